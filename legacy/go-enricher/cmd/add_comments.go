@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/genai"
@@ -23,6 +30,10 @@ If --dry-run=false, prompts for application.`,
 	RunE:    runAddComments,
 }
 
+// collectMetadata runs the collection phase of add-comments. Tests replace it to
+// interrupt a run at a known point.
+var collectMetadata = (*enricher.Service).CollectMetadata
+
 func runAddComments(cmd *cobra.Command, args []string) error {
 	cfg := getAppConfig()
 	ctx := cmd.Context()
@@ -34,96 +45,258 @@ func runAddComments(cmd *cobra.Command, args []string) error {
 
 	log.Println("INFO: Starting add-comments operation", "dialect:", cfg.Database.Dialect, "database:", cfg.Database.DBName, "dry-run:", cfg.DryRun)
 
+	// Parse enrichments before connecting, so a missing --enrichments fails fast.
+	enrichmentSet, err := parseEnrichments(cfg.EnrichmentsRaw, cfg.DryRun)
+	if err != nil {
+		return err
+	}
+	if enrichmentSet, err = excludeEnrichments(enrichmentSet, cfg.ExcludeEnrichmentsRaw); err != nil {
+		return err
+	}
+	if err := database.ValidateEnrichments(cfg.Database.Dialect, enrichmentSet); err != nil {
+		return &enricher.ErrInvalidInput{Msg: "--enrichments", Err: err}
+	}
+
+	if err := database.ValidateCommentEncoding(cfg.Database.CommentEncoding); err != nil {
+		return err
+	}
+	if err := database.ValidateEmbedSource(cfg.Database.EmbedSource); err != nil {
+		return err
+	}
+	if cfg.Database.MaxCommentParts < 0 {
+		return fmt.Errorf("invalid value for --max-comment-parts: %d. Must be 0 (no limit) or more", cfg.Database.MaxCommentParts)
+	}
+	if cfg.DryLLM && cfg.PrintPrompts == "" {
+		return fmt.Errorf("--dry-llm requires --print-prompts")
+	}
+	if cfg.PrintPrompts == utils.StdioPath && outputFile == utils.StdioPath {
+		return fmt.Errorf("--print-prompts - cannot be combined with --out_file -: both would write to stdout")
+	}
+	if cfg.LLMRPS < 0 {
+		return fmt.Errorf("invalid value for --llm-rps: %v. Must be 0 (no limit) or more", cfg.LLMRPS)
+	}
+	if err := validateLLMRetries(cfg); err != nil {
+		return err
+	}
+	if cfg.MaxDescriptionLength < 0 {
+		return fmt.Errorf("invalid value for --max-description-length: %d. Must be 0 (no limit) or more", cfg.MaxDescriptionLength)
+	}
+	if cfg.SlowQueryWarn < 0 {
+		return fmt.Errorf("invalid value for --slow-query-warn: %s. Must be 0 (no warning) or more", cfg.SlowQueryWarn)
+	}
+	skipDescriptionPatterns, err := cfg.SkipDescriptionPatterns()
+	if err != nil {
+		return err
+	}
+	if cfg.DedupeDescriptions && cfg.BatchDescriptions {
+		return fmt.Errorf("--dedupe-descriptions and --batch-descriptions cannot be used together")
+	}
+	if cfg.Interactive && cfg.TablesRaw != "" {
+		return fmt.Errorf("--interactive and --tables cannot be used together")
+	}
+
+	// Parse filters
+	tableFilters, columnEnrichments, err := utils.ParseTablesFlagWithEnrichments(cfg.TablesRaw)
+	if err != nil {
+		return fmt.Errorf("error parsing --tables flag: %w", err)
+	}
+	// The average is an extra query per column, so handlers only run it when asked to.
+	cfg.Database.ComputeAverage = requestsAverage(enrichmentSet, columnEnrichments)
+
 	// Setup Database Connection
 	dbAdapter, err := database.New(cfg.Database)
 	if err != nil {
-		return fmt.Errorf("failed to initialize database connection: %w", err)
+		return connectionError(cfg.Database, err)
 	}
 	defer dbAdapter.Close()
 
 	var llmClient genai.LLMClient
 	var llmErr error
-	if cfg.GeminiAPIKey != "" {
-		llmConfig := genai.Config{
-			APIKey: cfg.GeminiAPIKey,
-			Model:  cfg.Model,
-		}
+	if cfg.DryLLM {
+		log.Println("INFO: --dry-llm set. LLM prompts will be printed but not sent.")
+	} else if hasLLMCredentials(cfg) {
+		llmConfig := newLLMConfig(cfg)
+		llmConfig.DescriptionLanguage = cfg.DescriptionLanguage
 		llmClient, llmErr = genai.NewClient(ctx, llmConfig)
 		if llmErr != nil {
 			return fmt.Errorf("failed to initialize Gemini client: %w", llmErr)
 		}
+		if cfg.FallbackModel != "" {
+			llmConfig.Model = cfg.FallbackModel
+			fallbackClient, err := genai.NewClient(ctx, llmConfig)
+			if err != nil {
+				llmClient.Close()
+				return fmt.Errorf("failed to initialize Gemini client for --fallback-model: %w", err)
+			}
+			llmClient = genai.NewFallbackClient(llmClient, cfg.Model, fallbackClient, cfg.FallbackModel)
+		}
+		if cfg.LLMRPS > 0 {
+			llmClient = genai.NewRateLimitedClient(llmClient, cfg.LLMRPS)
+		}
 		defer llmClient.Close()
 		log.Println("INFO: LLM client initialized.")
 	} else {
 		log.Println("INFO: No Gemini API key provided. LLM-based enrichments (Description, PII check) will be skipped.")
 	}
+	if cfg.PrintPrompts != "" {
+		promptOut, closePromptOut, err := openPromptOutput(cfg, cfg.PrintPrompts)
+		if err != nil {
+			return err
+		}
+		defer closePromptOut()
+		llmClient = genai.NewPromptPrinter(promptOut, llmClient, cfg.DescriptionLanguage)
+	}
 
 	// Setup Enricher Service
-	enricherCfg := enricher.Config{MaskPII: appCfg.MaskPII}
-	svc := enricher.NewService(dbAdapter, llmClient, enricherCfg)
-
-	// Parse filters
-	tableFilters, err := utils.ParseTablesFlag(cfg.TablesRaw)
-	if err != nil {
-		return fmt.Errorf("error parsing --tables flag: %w", err)
+	enricherCfg := enricher.Config{
+		MaskPII:                     appCfg.MaskPII,
+		SkipEmptyTables:             appCfg.SkipEmptyTables,
+		BatchDescriptions:           appCfg.BatchDescriptions,
+		SlowQueryWarn:               appCfg.SlowQueryWarn,
+		MaxDescriptionLength:        appCfg.MaxDescriptionLength,
+		DedupeDescriptions:          appCfg.DedupeDescriptions,
+		PreserveExistingDescription: appCfg.PreserveExistingDescription,
+		SkipDescriptionPatterns:     skipDescriptionPatterns,
+		HumanizeNames:               appCfg.HumanizeNames,
+		StrictDescriptions:          appCfg.StrictDescriptions,
+		DescribeColumnRelationships: appCfg.DescribeColumnRelationships,
+		Explain:                     appCfg.ExplainFile != "",
+		CommentIncludeDataType:      appCfg.Database.CommentIncludeDataType,
 	}
+	if cfg.StatsCacheFile != "" {
+		cacheKey := fmt.Sprintf("%s as %s, example sample size %d, max distinct for examples %d, distinct collation %s", describeDatabase(cfg.Database), cfg.Database.User, cfg.Database.ExampleSampleSize, cfg.Database.MaxDistinctForExamples, cfg.Database.DistinctCollation)
+		if cfg.Database.ComputeAverage {
+			// Statistics cached without averages would leave them out of the comments.
+			cacheKey += ", with averages"
+		}
+		if enricherCfg.StatsCache, err = enricher.LoadStatsCache(cfg.StatsCacheFile, cacheKey); err != nil {
+			return err
+		}
+	}
+	svc := enricher.NewService(dbAdapter, llmClient, enricherCfg)
 
-	// Parse enrichments
-	enrichmentSet := make(map[string]bool)
-	if cfg.EnrichmentsRaw != "" {
-		enrichmentsList := strings.Split(strings.ReplaceAll(cfg.EnrichmentsRaw, " ", ""), ",")
-		for _, e := range enrichmentsList {
-			enrichmentSet[strings.TrimSpace(strings.ToLower(e))] = true
+	if cfg.Interactive {
+		if tableFilters, err = pickTables(ctx, dbAdapter, svc); err != nil {
+			return err
 		}
 	}
 
 	// Read context files
-	additionalContext, err := utils.ReadContextFiles(cfg.ContextFilesRaw)
+	additionalContext, err := utils.ReadContextFiles(cfg.ContextFilesRaw, cfg.SanitizeContext)
 	if err != nil {
 		return fmt.Errorf("failed to read context files specified via --context: %w", err)
 	}
 	if additionalContext != "" {
 		log.Printf("INFO: Loaded additional context from: %s", cfg.ContextFilesRaw)
 	}
+	glossary, err := utils.ReadGlossaryFile(cfg.GlossaryFile)
+	if err != nil {
+		return err
+	}
+	if len(glossary) > 0 {
+		log.Printf("INFO: Loaded %d glossary term(s) from: %s", len(glossary), cfg.GlossaryFile)
+	}
+	if cfg.ContextDir != "" {
+		info, statErr := os.Stat(cfg.ContextDir)
+		if statErr != nil || !info.IsDir() {
+			return fmt.Errorf("--context-dir '%s' is not a readable directory", cfg.ContextDir)
+		}
+		log.Printf("INFO: Loading per-table context files from: %s", cfg.ContextDir)
+	}
 
-	needsLLM := additionalContext != "" || enrichmentSet["description"]
+	generationParams := enricher.GenerateSQLParams{
+		TableFilters:      tableFilters,
+		ColumnEnrichments: columnEnrichments,
+		Enrichments:       enrichmentSet,
+		AdditionalContext: additionalContext,
+		ContextDir:        cfg.ContextDir,
+		Glossary:          glossary,
+		SanitizeContext:   cfg.SanitizeContext,
+	}
+	if cfg.DryRun {
+		// Estimated from the filtered tables and columns, before any LLM call is made.
+		est, err := svc.EstimateLLMUsage(ctx, generationParams)
+		if err != nil {
+			return fmt.Errorf("SQL generation failed: %w", err)
+		}
+		logLLMEstimate(est, cfg.Model)
+	}
+
+	// Glossary definitions and humanized names describe columns without the LLM.
+	needsLLM := additionalContext != "" || cfg.ContextDir != "" || (enrichmentSet["description"] && len(glossary) == 0 && !cfg.HumanizeNames)
 	if needsLLM {
 		if llmClient == nil {
 			requiredBy := ""
-			if additionalContext != "" || enrichmentSet["description"] {
+			if additionalContext != "" || cfg.ContextDir != "" || enrichmentSet["description"] {
 				requiredBy = " for Description enrichment"
 			}
 			errorMsg := fmt.Sprintf("LLM features (%s) requested/implied, but Gemini API key is missing", strings.TrimSpace(requiredBy))
 			log.Println("ERROR:", errorMsg)
-			return fmt.Errorf("%s. Set --gemini-api-key flag or GEMINI_API_KEY environment variable", errorMsg)
+			return &enricher.ErrLLMAuth{Msg: errorMsg, Err: fmt.Errorf("set --gemini-api-key flag or GEMINI_API_KEY environment variable")}
 		}
-		if err := llmClient.IsAPIKeyValid(ctx); err != nil {
-			return fmt.Errorf("Gemini API key validation failed: %w. Ensure the key is correct and has permissions", err)
+		if err := llmClient.CheckAccess(ctx); err != nil {
+			if cfg.UsesVertexAI() {
+				return &enricher.ErrLLMAuth{Msg: "Vertex AI access check failed. Ensure application default credentials are set up and can use the model in the --vertex-project project", Err: err}
+			}
+			return &enricher.ErrLLMAuth{Msg: "Gemini API key validation failed. Ensure the key is correct and has permissions", Err: err}
 		}
 	}
 
-	generationParams := enricher.GenerateSQLParams{
-		TableFilters:      tableFilters,
-		Enrichments:       enrichmentSet,
-		AdditionalContext: additionalContext,
+	snapshot, err := collectMetadata(svc, ctx, generationParams)
+	var interrupted *enricher.ErrCancelled
+	if errors.As(err, &interrupted) && snapshot != nil {
+		return writePartialResults(cfg, outputFile, svc.GenerateSQLFromSnapshot(snapshot), err)
 	}
-	sqlStatements, err := svc.GenerateCommentSQLs(ctx, generationParams)
 	if err != nil {
 		return fmt.Errorf("SQL generation failed: %w", err)
 	}
+	if enricherCfg.StatsCache != nil {
+		if err := enricherCfg.StatsCache.Save(); err != nil {
+			return err
+		}
+		log.Println("INFO: Column statistics cached in:", cfg.StatsCacheFile)
+	}
+	if cfg.CollectOut != "" {
+		snapshot.Dialect = cfg.Database.Dialect
+		snapshot.Database = cfg.Database.DBName
+		mode, err := cfg.OutputFileMode()
+		if err != nil {
+			return err
+		}
+		if err := enricher.WriteSnapshot(cfg.CollectOut, snapshot, mode); err != nil {
+			return err
+		}
+		if err := applyOutputMode(cfg, cfg.CollectOut); err != nil {
+			return err
+		}
+		log.Println("INFO: Collected metadata written to:", cfg.CollectOut)
+	}
+	sqlStatements := svc.GenerateSQLFromSnapshot(snapshot)
+	if cfg.ExplainFile != "" {
+		if err := writeExplanations(cfg, cfg.ExplainFile, enricher.Explanations(snapshot)); err != nil {
+			return err
+		}
+	}
 
 	if len(sqlStatements) == 0 {
 		log.Println("INFO: No SQL statements generated. This might be due to filters or lack of enrichable content meeting criteria.")
 		return nil
 	}
 
-	// Write SQL to File
+	// Write SQL to File, or stdout for piping into apply-comments --in_file -
 	fileContent := strings.Join(sqlStatements, "\n") + "\n"
-	writeErr := os.WriteFile(outputFile, []byte(fileContent), 0644)
-	if writeErr != nil {
-		return fmt.Errorf("failed to write output file '%s': %w", outputFile, writeErr)
+	if outputFile == utils.StdioPath {
+		if _, writeErr := os.Stdout.WriteString(fileContent); writeErr != nil {
+			return fmt.Errorf("failed to write SQL statements to stdout: %w", writeErr)
+		}
+		log.Println("INFO: SQL statements successfully written to stdout.")
+	} else {
+		writeErr := writeOutputFile(cfg, outputFile, []byte(fileContent))
+		if writeErr != nil {
+			return fmt.Errorf("failed to write output file '%s': %w", outputFile, writeErr)
+		}
+		log.Println("INFO: SQL statements successfully written to:", outputFile)
 	}
-	log.Println("INFO: SQL statements successfully written to:", outputFile)
 
 	if cfg.DryRun {
 		log.Println("INFO: Add comments operation completed in dry-run mode. Review the generated SQL file:", outputFile)
@@ -132,7 +305,7 @@ func runAddComments(cmd *cobra.Command, args []string) error {
 
 	// Dry run is false
 	if utils.ConfirmAction(fmt.Sprintf("apply %d generated SQL statements from '%s'", len(sqlStatements), outputFile)) {
-		log.Println("INFO: Applying SQL statements to the database...")
+		log.Println("INFO: Applying SQL statements to the database..")
 
 		if execErr := dbAdapter.ExecuteSQLStatements(ctx, sqlStatements); execErr != nil {
 			return fmt.Errorf("failed to execute SQL statements from '%s': %w. Review the file and database logs", outputFile, execErr)
@@ -146,11 +319,207 @@ func runAddComments(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// partialResultsHeader starts the SQL file written for an interrupted run. apply-comments
+// skips lines starting with "--".
+const partialResultsHeader = "-- Partial results: the run was interrupted before every table and column was processed.\n"
+
+// writePartialResults writes the SQL generated for the tables and columns finished
+// before an interrupt, so a long run stopped with Ctrl-C keeps its work. The SQL is
+// never applied. It returns the interrupt error for the exit code.
+func writePartialResults(cfg *config.AppConfig, outputFile string, sqlStatements []string, interrupted error) error {
+	if len(sqlStatements) == 0 {
+		log.Println("WARN: Interrupted before any comment was generated. No SQL file was written.")
+		return interrupted
+	}
+	fileContent := partialResultsHeader + strings.Join(sqlStatements, "\n") + "\n"
+	if outputFile == utils.StdioPath {
+		if _, err := os.Stdout.WriteString(fileContent); err != nil {
+			return fmt.Errorf("failed to write partial SQL statements to stdout: %w", err)
+		}
+	} else if err := writeOutputFile(cfg, outputFile, []byte(fileContent)); err != nil {
+		return fmt.Errorf("failed to write partial results to '%s': %w", outputFile, err)
+	}
+	log.Printf("WARN: Interrupted. Wrote %d SQL statement(s) for the tables and columns finished so far to %s. They were not applied; rerun to generate the rest.", len(sqlStatements), outputFile)
+	return interrupted
+}
+
+// writeExplanations writes the --explain report: JSON if the path ends in .json,
+// text otherwise.
+func writeExplanations(cfg *config.AppConfig, path string, explanations []*enricher.ColumnExplanation) error {
+	content := []byte(enricher.FormatExplanationsAsText(explanations))
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		encoded, err := json.MarshalIndent(explanations, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode --explain report as JSON: %w", err)
+		}
+		content = append(encoded, '\n')
+	}
+	if err := writeOutputFile(cfg, path, content); err != nil {
+		return fmt.Errorf("failed to write --explain report to '%s': %w", path, err)
+	}
+	log.Printf("INFO: Explanations for %d column(s) written to: %s", len(explanations), path)
+	return nil
+}
+
+// pickTables asks the user which tables and columns to enrich, for --interactive.
+// Columns are listed through svc, which keeps them for the run that follows.
+func pickTables(ctx context.Context, dbAdapter *database.DB, svc *enricher.Service) (map[string][]string, error) {
+	tables, err := dbAdapter.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("--interactive: the database has no tables to pick from")
+	}
+	return utils.PickTables(tables, func(table string) ([]string, error) {
+		return svc.ColumnNames(ctx, table)
+	})
+}
+
+// logLLMEstimate reports the LLM calls and rough cost of running the same
+// add-comments for real, as part of the dry-run summary.
+func logLLMEstimate(est enricher.LLMUsageEstimate, model string) {
+	if est.ForeignKeyColumns > 0 {
+		log.Printf("INFO: The run also checks the foreign keys of %d column(s), with a query reading both tables per reference (billed by bytes scanned on BigQuery).", est.ForeignKeyColumns)
+	}
+	if est.Calls() == 0 {
+		log.Println("INFO: Estimated LLM usage for this run: no LLM calls.")
+		return
+	}
+	cost := "unknown for model " + model
+	if dollars, ok := genai.EstimateCost(model, est.InputTokens, est.OutputTokens); ok {
+		cost = fmt.Sprintf("~$%.4f with %s", dollars, model)
+	}
+	log.Printf("INFO: Estimated LLM usage for this run: %d call(s) (%d description, %d PII check), ~%d input and ~%d output tokens, cost %s.",
+		est.Calls(), est.DescriptionCalls, est.PIICalls, est.InputTokens, est.OutputTokens, cost)
+}
+
+// parseEnrichments turns --enrichments into the set passed to the enricher, where an
+// empty set means every enrichment. Because that default can write a lot of metadata
+// into production comments, applying with --dry-run=false requires --enrichments to be
+// given explicitly, with "all" selecting every enrichment.
+func parseEnrichments(raw string, dryRun bool) (map[string]bool, error) {
+	enrichmentSet := make(map[string]bool)
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		if !dryRun {
+			return nil, fmt.Errorf("--enrichments is required with --dry-run=false; list the enrichments to apply (e.g. 'description,examples') or pass --enrichments all")
+		}
+		return enrichmentSet, nil
+	}
+	if strings.EqualFold(raw, "all") {
+		return enrichmentSet, nil
+	}
+	enrichmentsList := strings.Split(strings.ReplaceAll(raw, " ", ""), ",")
+	for _, e := range enrichmentsList {
+		enrichmentSet[strings.TrimSpace(strings.ToLower(e))] = true
+	}
+	return enrichmentSet, nil
+}
+
+// requestsAverage reports whether the average enrichment is listed in --enrichments
+// or in a --tables hint. It is not included by "all", since it costs another full
+// scan of every numeric column.
+func requestsAverage(enrichmentSet map[string]bool, columnEnrichments map[string]map[string][]string) bool {
+	if enrichmentSet["average"] {
+		return true
+	}
+	for _, columns := range columnEnrichments {
+		for _, hints := range columns {
+			for _, hint := range hints {
+				if hint == "average" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// excludeEnrichments removes the --exclude-enrichments names from an enrichment set.
+// An empty set stands for all enrichments, so the exclusions are recorded in it as
+// false; a listed set simply loses them, and may not lose all of them.
+func excludeEnrichments(enrichmentSet map[string]bool, excludeRaw string) (map[string]bool, error) {
+	if strings.TrimSpace(excludeRaw) == "" {
+		return enrichmentSet, nil
+	}
+	includesAll := len(enrichmentSet) == 0
+	result := make(map[string]bool, len(enrichmentSet))
+	for name, included := range enrichmentSet {
+		result[name] = included
+	}
+	for _, e := range strings.Split(excludeRaw, ",") {
+		name := strings.TrimSpace(strings.ToLower(e))
+		if name == "" {
+			continue
+		}
+		if includesAll {
+			result[name] = false
+		} else {
+			delete(result, name)
+		}
+	}
+	if !includesAll && len(result) == 0 {
+		return nil, fmt.Errorf("--exclude-enrichments removes every enrichment in --enrichments; nothing would be generated")
+	}
+	return result, nil
+}
+
+// openPromptOutput opens the --print-prompts destination, where "-" is stdout. The
+// prompts include sampled column values, so the file gets the --output-mode
+// permissions like the other generated files.
+func openPromptOutput(cfg *config.AppConfig, path string) (io.Writer, func(), error) {
+	if path == "-" {
+		return os.Stdout, func() {}, nil
+	}
+	f, err := createOutputFile(cfg, path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create --print-prompts file '%s': %w", path, err)
+	}
+	return f, func() { f.Close() }, nil
+}
+
 func init() {
-	addCommentsCmd.Flags().StringVarP(&appCfg.OutputFile, "out_file", "o", "", "File path to output generated SQL statements (defaults to <database>_comments.sql)")
-	addCommentsCmd.Flags().StringVar(&appCfg.TablesRaw, "tables", "", "Comma-separated list of tables/columns to include (e.g., 'table1[col1,col2],table2')")
-	addCommentsCmd.Flags().StringVar(&appCfg.EnrichmentsRaw, "enrichments", "", "Comma-separated list of enrichments to include (e.g., 'description,examples,distinct_values,foreign_keys'). If empty, all are included.")
+	addCommentsCmd.Flags().StringVarP(&appCfg.OutputFile, "out_file", "o", "", "File path to output generated SQL statements, or '-' for stdout (defaults to <database>_comments.sql)")
+	addCommentsCmd.Flags().StringVar(&appCfg.TablesRaw, "tables", "", "Comma-separated list of tables/columns to include (e.g., 'table1[col1,col2],table2'). A column may name its own enrichments, which replace --enrichments for it (e.g., 'orders[amount:examples+null_count;status:description]'). Use '-' to read the list from stdin.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.Interactive, "interactive", false, "List the database's tables, then the columns of each chosen table, and prompt for which to enrich instead of using --tables.")
+	addCommentsCmd.Flags().StringVar(&appCfg.EnrichmentsRaw, "enrichments", "", "Comma-separated list of enrichments to include (e.g., 'description,examples,distinct_values,foreign_keys'), or 'all'. If empty, all are included; required with --dry-run=false.")
+	addCommentsCmd.Flags().StringVar(&appCfg.ExcludeEnrichmentsRaw, "exclude-enrichments", "", "Comma-separated list of enrichments to leave out (e.g., 'examples'). With --enrichments empty or 'all', every other enrichment is included.")
 	addCommentsCmd.Flags().StringVar(&appCfg.ContextFilesRaw, "context", "", "Comma-separated list of context files for description generation.")
+	addCommentsCmd.Flags().StringVar(&appCfg.GlossaryFile, "glossary", "", "Business glossary file of 'term: definition' lines (or a JSON object). A column whose name matches a term, ignoring case and separators (created_at matches 'Created At'), gets the definition at the start of its description without an LLM call.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.SanitizeContext, "sanitize-context", false, "Redact likely secrets (private keys, API keys, tokens, passwords and credentials in connection URLs) from --context and --context-dir files before they are sent to the LLM.")
+	addCommentsCmd.Flags().StringVar(&appCfg.ContextDir, "context-dir", "", "Directory of per-table context files: <table>.md is used for the table and its columns, <table>.<column>.md for that column only.")
 	addCommentsCmd.Flags().StringVar(&appCfg.Model, "model", appCfg.Model, "Model to use for description/PII enrichment.")
+	addCommentsCmd.Flags().StringVar(&appCfg.Database.DistinctCollation, "distinct-collation", "default", "Collation of distinct value counts: 'default' uses the column's collation, 'binary' counts values differing only in case or accents separately.")
+	addCommentsCmd.Flags().IntVar(&appCfg.Database.ExampleSampleSize, "example-sample-size", appCfg.Database.ExampleSampleSize, "Number of distinct values to sample per column before picking representative examples spread across the sorted sample (0 uses the first 3 values found).")
+	addCommentsCmd.Flags().Int64Var(&appCfg.Database.MaxDistinctForExamples, "max-distinct-for-examples", 0, "Sample examples from the first rows found, without DISTINCT or ORDER BY, for columns with more distinct values than this (0 always samples distinct values). The distinct count is queried first, so this saves a full sort of high-cardinality columns.")
 	addCommentsCmd.Flags().BoolVar(&appCfg.MaskPII, "mask_pii", appCfg.MaskPII, "Enable PII masking using LLM-based detection (default: true). When false, skips LLM PII handling.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.SkipEmptyTables, "skip-empty-tables", false, "Skip tables where no columns are accessible instead of emitting only a table comment.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.BatchDescriptions, "batch-descriptions", false, "Generate column descriptions of a table with a single LLM call (per 50 columns for wide tables) instead of one call per column.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.DedupeDescriptions, "dedupe-descriptions", false, "Generate one description for columns that share a name and data type across tables, such as created_at, and reuse it instead of calling the LLM per column.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.PreserveExistingDescription, "preserve-existing-description", false, "Do not generate an LLM description for columns whose comment already has text outside the <gemini> block. Their metadata is still refreshed.")
+	addCommentsCmd.Flags().StringVar(&appCfg.SkipDescriptionPatternsRaw, "skip-description-patterns", "", "Comma-separated column name globs, e.g. 'is_*,*_id', for self-explanatory columns that get no LLM description. Matching ignores case; their metadata is still generated.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.HumanizeNames, "humanize-names", false, "Describe columns that get no LLM or glossary description with their humanized name, e.g. 'Created at' for created_at. Works without a Gemini API key.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.StrictDescriptions, "strict-descriptions", false, "Fail the run (exit code 4) if a column gets no description although one was requested, instead of leaving it out of the comment. Other enrichments are not checked.")
+	addCommentsCmd.Flags().StringVar(&appCfg.StatsCacheFile, "stats-cache", "", "Cache column statistics in this JSON file and reuse them for tables that have not changed since (PostgreSQL and SQL Server).")
+	addCommentsCmd.Flags().StringVar(&appCfg.ExplainFile, "explain", "", "Write a report to this file of what was done with each column and why: the enrichments that made it into its comment, whether the LLM was called, the PII decision for its examples, and why it was skipped. A .json path writes JSON, any other path text.")
+	addCommentsCmd.Flags().StringVar(&appCfg.CollectOut, "collect-out", "", "Also write the collected metadata (including LLM descriptions) to this JSON file, so SQL can be regenerated later without re-querying the database or the LLM.")
+	addCommentsCmd.Flags().StringVar(&appCfg.Database.CommentEncoding, "comment-encoding", "", "Charset of the database's comment columns ('utf8', 'latin1' or 'ascii'). Generated text is transliterated to fit, and characters that cannot be represented are replaced with '?'.")
+	addCommentsCmd.Flags().StringVar(&appCfg.Database.EmbedSource, "embed-source", "", "Record the database or environment the metadata was profiled against (e.g. 'prod') as a 'Source: <value>' entry in each generated comment. delete-comments removes it with the rest of the <gemini> block.")
+	addCommentsCmd.Flags().IntVar(&appCfg.Database.MaxCommentParts, "max-comment-parts", 0, "Keep at most this many parts in each column comment, by priority: description, foreign keys, examples, distinct values, null count, data type, then custom enrichments (0 keeps all).")
+	addCommentsCmd.Flags().BoolVar(&appCfg.Database.EmbedTimestamp, "embed-timestamp", false, "Record when each column's statistics were queried, as a 'Profiled: <UTC time>' entry in its comment, so readers can tell how current they are. Reruns replace it.")
+	addCommentsCmd.Flags().StringVar(&appCfg.Database.CommentStyle, "comment-style", "keyvalue", "How generated column metadata is written: 'keyvalue' for terse 'Distinct: 150 | Nulls: 5' parts, 'sentence' for readable prose such as 'This column has 150 distinct values and 5 nulls; examples include ...'.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.Database.EmbedProvenance, "embed-provenance", false, "Record in each generated comment which parts were inferred (description) and which were computed from the database (examples, statistics, foreign keys), as a 'Provenance: ...' entry.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.Database.CommentIncludeDataType, "comment-include-datatype", false, "Start each column comment with the column's data type, e.g. 'Type: varchar(255)', as the data_type enrichment does. The type is already known from the schema, so no query is run.")
+	addCommentsCmd.Flags().StringVar(&appCfg.FallbackModel, "fallback-model", "", "Model to retry a call with when --model hits its rate limit or quota (RESOURCE_EXHAUSTED), e.g. a cheaper flash model.")
+	addCommentsCmd.Flags().DurationVar(&appCfg.SlowQueryWarn, "slow-query-warn", 30*time.Second, "Warn about columns whose metadata queries (examples, distinct values, null count) take longer than this, e.g. '10s' (0 disables the warning).")
+	addCommentsCmd.Flags().Float64Var(&appCfg.LLMRPS, "llm-rps", 0, "Maximum Gemini requests per second across all concurrent workers (0 means no limit).")
+	addLLMRetryFlags(addCommentsCmd)
+	addCommentsCmd.Flags().BoolVar(&appCfg.StructuredOutput, "structured-output", false, "Ask Gemini for JSON responses that follow a response schema for descriptions and PII checks, instead of parsing tagged text. Falls back to tags for models that do not support it.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.DescribeColumnRelationships, "describe-column-relationships", false, "Also ask the LLM, in each table description prompt, for meaning that only emerges from a combination of columns (e.g. start_date and end_date forming a range) and add it to the table comment. Needs context, like descriptions.")
+	addCommentsCmd.Flags().StringVar(&appCfg.DescriptionLanguage, "description-language", "", "Language to write LLM-generated descriptions in, e.g. \"fr\" or \"Japanese\". Defaults to the model's choice, usually the language of the context.")
+	addCommentsCmd.Flags().IntVar(&appCfg.MaxDescriptionLength, "max-description-length", 0, "Truncate generated table and column descriptions to this many characters, ending them with \"...\" (0 means no limit).")
+	addCommentsCmd.Flags().StringVar(&appCfg.PrintPrompts, "print-prompts", "", "Write every rendered LLM prompt (descriptions and PII checks) to this file, or '-' for stdout.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.DryLLM, "dry-llm", false, "With --print-prompts, only print the prompts: no Gemini API calls are made, so descriptions are left empty and examples are not masked.")
+	addCommentsCmd.Flags().BoolVar(&appCfg.Database.CascadePartitions, "cascade-partitions", false, "Also emit column comment statements for every partition of a partitioned table (postgres only).")
 }
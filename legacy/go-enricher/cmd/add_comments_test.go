@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+	"github.com/spf13/cobra"
+)
+
+func TestParseEnrichments(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		dryRun      bool
+		expected    map[string]bool
+		expectedErr string
+	}{
+		{"dry run without enrichments", "", true, map[string]bool{}, ""},
+		{"apply without enrichments", "", false, nil, "--enrichments is required with --dry-run=false"},
+		{"apply with all", "all", false, map[string]bool{}, ""},
+		{"apply with ALL", " ALL ", false, map[string]bool{}, ""},
+		{"apply with explicit list", "Description, examples", false, map[string]bool{"description": true, "examples": true}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnrichments(tt.raw, tt.dryRun)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("parseEnrichments() error = %v, want error containing %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEnrichments() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseEnrichments() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExcludeEnrichments(t *testing.T) {
+	tests := []struct {
+		name        string
+		enrichments map[string]bool
+		exclude     string
+		expected    map[string]bool
+		expectedErr string
+	}{
+		{"nothing excluded", map[string]bool{}, "", map[string]bool{}, ""},
+		{"all minus excluded", map[string]bool{}, "Examples, null_count", map[string]bool{"examples": false, "null_count": false}, ""},
+		{"list minus excluded", map[string]bool{"description": true, "examples": true}, "examples", map[string]bool{"description": true}, ""},
+		{"excluding an unlisted enrichment", map[string]bool{"description": true}, "examples", map[string]bool{"description": true}, ""},
+		{"excluding the whole list", map[string]bool{"examples": true}, "examples", nil, "removes every enrichment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := excludeEnrichments(tt.enrichments, tt.exclude)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("excludeEnrichments() error = %v, want error containing %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("excludeEnrichments() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("excludeEnrichments() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRequestsAverage(t *testing.T) {
+	tests := []struct {
+		name              string
+		enrichments       map[string]bool
+		columnEnrichments map[string]map[string][]string
+		want              bool
+	}{
+		{"all", map[string]bool{}, nil, false},
+		{"listed", map[string]bool{"average": true, "description": true}, nil, true},
+		{"column hint", map[string]bool{"description": true}, map[string]map[string][]string{"orders": {"amount": {"examples", "average"}}}, true},
+		{"other hints", map[string]bool{}, map[string]map[string][]string{"orders": {"amount": {"examples"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requestsAverage(tt.enrichments, tt.columnEnrichments); got != tt.want {
+				t.Errorf("requestsAverage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritePartialResults(t *testing.T) {
+	snapshot, err := enricher.ReadSnapshot("testdata/metadata_snapshot.json")
+	if err != nil {
+		t.Fatalf("ReadSnapshot() error = %v", err)
+	}
+	// The run was interrupted before orders.user_id was collected.
+	snapshot.Columns = snapshot.Columns[:1]
+	sqlStatements, err := generateFromSnapshot(snapshot, config.DatabaseConfig{Dialect: "postgres"})
+	if err != nil {
+		t.Fatalf("generateFromSnapshot() error = %v", err)
+	}
+
+	outputFile := filepath.Join(t.TempDir(), "shop_comments.sql")
+	interrupted := &enricher.ErrCancelled{Msg: "metadata collection interrupted after 1 column(s)", Err: context.Canceled}
+	err = writePartialResults(config.NewAppConfig(), outputFile, sqlStatements, interrupted)
+	if ExitCode(err) != ExitInterrupted {
+		t.Errorf("writePartialResults() error = %v, want the interrupt error", err)
+	}
+
+	content, readErr := os.ReadFile(outputFile)
+	if readErr != nil {
+		t.Fatalf("partial results file not written: %v", readErr)
+	}
+	want := partialResultsHeader +
+		`COMMENT ON TABLE "orders" IS '<gemini>Customer orders</gemini>';` + "\n" +
+		`COMMENT ON COLUMN "orders"."id" IS '<gemini>Examples: [''1'', ''2''] | Order identifier</gemini>';` + "\n"
+	if string(content) != want {
+		t.Errorf("partial results file = %q, want %q", content, want)
+	}
+}
+
+func TestRunAddCommentsWritesPartialResultsWhenInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "shop.db")
+	db, err := sql.Open("sqlite", dbFile)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE orders (id INTEGER, note TEXT)`); err != nil {
+		t.Fatalf("creating the test table: %v", err)
+	}
+	db.Close()
+
+	defer func(cfg *config.AppConfig) { appCfg = cfg }(appCfg)
+	cfg := *appCfg // The flag defaults.
+	appCfg = &cfg
+	appCfg.Database.Dialect = "sqlite"
+	appCfg.Database.DBName = dbFile
+	appCfg.EnrichmentsRaw = "examples"
+	appCfg.OutputFile = filepath.Join(dir, "shop_comments.sql")
+
+	// The run is interrupted after orders.id was collected, before orders.note.
+	defer func(collect func(*enricher.Service, context.Context, enricher.GenerateSQLParams) (*enricher.MetadataSnapshot, error)) {
+		collectMetadata = collect
+	}(collectMetadata)
+	collectMetadata = func(*enricher.Service, context.Context, enricher.GenerateSQLParams) (*enricher.MetadataSnapshot, error) {
+		snapshot := &enricher.MetadataSnapshot{
+			Enrichments: map[string]bool{"examples": true},
+			Tables:      []*enricher.TableMetadata{{Table: "orders"}},
+			Columns:     []*enricher.ColumnMetadata{{Table: "orders", Column: "id", ExampleValues: []string{"1", "2"}}},
+		}
+		return snapshot, &enricher.ErrCancelled{Msg: "metadata collection interrupted after 1 column(s)", Err: context.Canceled}
+	}
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	err = runAddComments(cmd, nil)
+	if ExitCode(err) != ExitInterrupted {
+		t.Errorf("runAddComments() error = %v, want exit code %d", err, ExitInterrupted)
+	}
+
+	content, readErr := os.ReadFile(appCfg.OutputFile)
+	if readErr != nil {
+		t.Fatalf("partial results file not written: %v", readErr)
+	}
+	if !strings.HasPrefix(string(content), partialResultsHeader) {
+		t.Errorf("partial results file = %q, want it to start with the partial results header", content)
+	}
+	if !strings.Contains(string(content), `VALUES ('orders', 'id', '<gemini>Examples: [''1'', ''2'']</gemini>')`) {
+		t.Errorf("partial results file = %q, want the comment of orders.id", content)
+	}
+	if strings.Contains(string(content), "'note'") {
+		t.Errorf("partial results file = %q, want no comment for the uncollected orders.note", content)
+	}
+}
+
+func TestRunAddCommentsRejectsPromptsAndSQLOnStdout(t *testing.T) {
+	defer func(cfg *config.AppConfig) { appCfg = cfg }(appCfg)
+	cfg := *appCfg // The flag defaults.
+	appCfg = &cfg
+	appCfg.Database.Dialect = "sqlite"
+	appCfg.Database.DBName = filepath.Join(t.TempDir(), "never_opened.db")
+	appCfg.EnrichmentsRaw = "examples"
+	appCfg.PrintPrompts = "-"
+	appCfg.OutputFile = "-"
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	err := runAddComments(cmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "--print-prompts - cannot be combined with --out_file -") {
+		t.Errorf("runAddComments() error = %v, want --print-prompts - and --out_file - rejected", err)
+	}
+}
+
+func TestWritePartialResultsWithoutStatements(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "shop_comments.sql")
+	interrupted := &enricher.ErrCancelled{Msg: "metadata collection interrupted after 0 column(s)", Err: context.Canceled}
+	if err := writePartialResults(config.NewAppConfig(), outputFile, nil, interrupted); err != interrupted {
+		t.Errorf("writePartialResults() error = %v, want %v", err, interrupted)
+	}
+	if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
+		t.Errorf("writePartialResults() wrote a file without statements: %v", err)
+	}
+}
+
+func TestValidateEnrichmentsForDialect(t *testing.T) {
+	tests := []struct {
+		name        string
+		dialect     string
+		enrichments map[string]bool
+		wantErr     bool
+	}{
+		{"json_keys on postgres", "postgres", map[string]bool{"json_keys": true}, false},
+		{"json_keys on cockroach", "cockroach", map[string]bool{"json_keys": true, "examples": true}, false},
+		{"json_keys on mysql", "mysql", map[string]bool{"description": true, "json_keys": true}, true},
+		{"json_keys on bigquery", "bigquery", map[string]bool{"json_keys": true}, true},
+		{"all enrichments on mysql", "mysql", map[string]bool{}, false},
+		{"json_keys excluded on mysql", "mysql", map[string]bool{"json_keys": false}, false},
+		{"foreign keys on spanner", "spanner", map[string]bool{"foreign_keys": true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := database.ValidateEnrichments(tt.dialect, tt.enrichments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateEnrichments(%s, %v) error = %v, wantErr %v", tt.dialect, tt.enrichments, err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "json_keys are not supported for dialect "+tt.dialect) {
+				t.Errorf("ValidateEnrichments() error = %q, want it to name json_keys and %s", err, tt.dialect)
+			}
+		})
+	}
+}
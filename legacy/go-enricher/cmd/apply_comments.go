@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -31,13 +32,23 @@ func runApplyComments(cmd *cobra.Command, args []string) error {
 
 	log.Println("INFO: Starting apply-comments operation", "dialect:", cfg.Database.Dialect, "database:", cfg.Database.DBName, "input-file:", inputFile, "dry-run:", cfg.DryRun)
 
-	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
-		return fmt.Errorf("input file not found: %s", inputFile)
-	} else if err != nil {
-		return fmt.Errorf("error checking input file '%s': %w", inputFile, err)
+	protectedPattern, err := cfg.ProtectedPattern()
+	if err != nil {
+		return err
+	}
+	if protectedPattern != "" && !cfg.Force {
+		return fmt.Errorf("database '%s' matches protected pattern '%s': refusing to apply comments without --force", cfg.Database.DBName, protectedPattern)
+	}
+
+	if inputFile != utils.StdioPath {
+		if _, err := os.Stat(inputFile); os.IsNotExist(err) {
+			return fmt.Errorf("input file not found: %s", inputFile)
+		} else if err != nil {
+			return fmt.Errorf("error checking input file '%s': %w", inputFile, err)
+		}
 	}
 
-	content, readErr := os.ReadFile(inputFile)
+	content, readErr := utils.ReadInputFile(inputFile)
 	if readErr != nil {
 		return fmt.Errorf("failed to read input file '%s': %w", inputFile, readErr)
 	}
@@ -60,7 +71,7 @@ func runApplyComments(cmd *cobra.Command, args []string) error {
 	// Setup Database Connection
 	dbAdapter, err := database.New(cfg.Database)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return connectionError(cfg.Database, err)
 	}
 	defer dbAdapter.Close()
 	log.Println("INFO: Database connection established successfully.")
@@ -74,5 +85,5 @@ func runApplyComments(cmd *cobra.Command, args []string) error {
 }
 
 func init() {
-	applyCommentsCmd.Flags().StringVarP(&appCfg.InputFile, "in_file", "i", "", "Path to the input SQL file containing statements to apply (defaults to <database_name>_comments.sql)")
+	applyCommentsCmd.Flags().StringVarP(&appCfg.InputFile, "in_file", "i", "", "Path to the input SQL file containing statements to apply, or '-' to read them from stdin (defaults to <database_name>_comments.sql)")
 }
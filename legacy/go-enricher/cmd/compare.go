@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare table and column comments between two databases",
+	Long: `Connects to a source and a target database (e.g. staging and prod), retrieves the table and column comments of both,
+and writes the tables and columns whose comments differ as a diff: the source comment on a "-" line and the target comment on a "+" line.
+Connection details are given with --src-* and --dst-* flags instead of the global connection flags.`,
+	Example: `./db_schema_enricher compare --src-dialect postgres --src-host staging-db --src-username user --src-password pass --src-database shop --dst-dialect postgres --dst-host prod-db --dst-username user --dst-password pass --dst-database shop`,
+	// The global connection flags are not used, so validate the two connections instead.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if appCfg.NoColor {
+			utils.SetColorEnabled(false)
+		}
+		appCfg.CompareSource.IncludeTempTables = appCfg.Database.IncludeTempTables
+		appCfg.CompareTarget.IncludeTempTables = appCfg.Database.IncludeTempTables
+		if err := appCfg.CompareSource.Validate(); err != nil {
+			return &enricher.ErrInvalidInput{Msg: "source database configuration error (--src-*)", Err: err}
+		}
+		if err := appCfg.CompareTarget.Validate(); err != nil {
+			return &enricher.ErrInvalidInput{Msg: "target database configuration error (--dst-*)", Err: err}
+		}
+		return nil
+	},
+	RunE: runCompare,
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	cfg := getAppConfig()
+	ctx := cmd.Context()
+
+	sourceName := describeDatabase(cfg.CompareSource)
+	targetName := describeDatabase(cfg.CompareTarget)
+	outputFile := cfg.OutputFile
+	if outputFile == "" {
+		outputFile = fmt.Sprintf("%s_vs_%s_comments_diff.txt", cfg.CompareSource.DBName, cfg.CompareTarget.DBName)
+	}
+
+	log.Println("INFO: Starting compare operation", "source:", sourceName, "target:", targetName)
+
+	tableFilters, err := utils.ParseTablesFlag(cfg.TablesRaw)
+	if err != nil {
+		return fmt.Errorf("error parsing --tables flag: %w", err)
+	}
+	params := enricher.GetCommentsParams{TableFilters: tableFilters}
+
+	sourceComments, err := retrieveComments(ctx, cfg.CompareSource, params)
+	if err != nil {
+		return err
+	}
+	targetComments, err := retrieveComments(ctx, cfg.CompareTarget, params)
+	if err != nil {
+		return err
+	}
+
+	differences := enricher.CompareComments(sourceComments, targetComments)
+	log.Printf("INFO: Found %d table(s)/column(s) with different comments.", len(differences))
+
+	report := enricher.FormatCommentDifferencesAsText(differences, sourceName, targetName)
+	if writeErr := writeOutputFile(cfg, outputFile, []byte(report)); writeErr != nil {
+		return fmt.Errorf("failed to write comparison to file '%s': %w", outputFile, writeErr)
+	}
+	log.Println("INFO: Comparison successfully written to:", outputFile)
+	log.Println("INFO: Compare operation completed.")
+	return nil
+}
+
+// retrieveComments connects to a database and returns its table and column comments.
+func retrieveComments(ctx context.Context, dbCfg config.DatabaseConfig, params enricher.GetCommentsParams) ([]*enricher.ColumnComment, error) {
+	dbAdapter, err := database.New(dbCfg)
+	if err != nil {
+		return nil, connectionError(dbCfg, err)
+	}
+	defer dbAdapter.Close()
+
+	svc := enricher.NewService(dbAdapter, nil, enricher.Config{})
+	comments, err := svc.GetComments(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve comments from %s: %w", describeDatabase(dbCfg), err)
+	}
+	return comments, nil
+}
+
+// describeDatabase names a database in logs and diff headers, e.g. "postgres://staging-db/shop".
+func describeDatabase(dbCfg config.DatabaseConfig) string {
+	location := dbCfg.Host
+	switch {
+	case dbCfg.CloudSQLInstanceConnectionName != "":
+		location = dbCfg.CloudSQLInstanceConnectionName
+	case dbCfg.Dialect == "spanner":
+		location = dbCfg.Project + "/" + dbCfg.SpannerInstance
+	case dbCfg.Dialect == "bigquery":
+		location = dbCfg.Project
+	case dbCfg.Socket != "":
+		location = dbCfg.Socket
+	}
+	return fmt.Sprintf("%s://%s/%s", dbCfg.Dialect, location, dbCfg.DBName)
+}
+
+// addConnectionFlags registers the connection flags of one side of compare, e.g.
+// --src-host for prefix "src".
+func addConnectionFlags(cmd *cobra.Command, dbCfg *config.DatabaseConfig, prefix, label string) {
+	flag := func(name string) string { return prefix + "-" + name }
+	cmd.Flags().StringVar(&dbCfg.Dialect, flag("dialect"), "", fmt.Sprintf("Dialect of the %s database.", label))
+	cmd.Flags().StringVar(&dbCfg.Host, flag("host"), "", fmt.Sprintf("Host of the %s database (for non-Cloud SQL connections).", label))
+	cmd.Flags().IntVar(&dbCfg.Port, flag("port"), 0, fmt.Sprintf("Port of the %s database. Defaults to the dialect's standard port.", label))
+	cmd.Flags().StringVar(&dbCfg.Socket, flag("socket"), "", fmt.Sprintf("Unix socket of the %s database, instead of host and port (postgres and mysql).", label))
+	cmd.Flags().StringVar(&dbCfg.User, flag("username"), "", fmt.Sprintf("Username for the %s database.", label))
+	cmd.Flags().StringVar(&dbCfg.Password, flag("password"), "", fmt.Sprintf("Password for the %s database.", label))
+	cmd.Flags().StringVar(&dbCfg.DBName, flag("database"), "", fmt.Sprintf("Name of the %s database (the dataset for bigquery).", label))
+	cmd.Flags().StringVar(&dbCfg.Project, flag("project"), "", fmt.Sprintf("Google Cloud project of the %s database (bigquery and spanner).", label))
+	cmd.Flags().StringVar(&dbCfg.SpannerInstance, flag("spanner-instance"), "", fmt.Sprintf("Spanner instance ID of the %s database.", label))
+	cmd.Flags().StringVar(&dbCfg.CloudSQLInstanceConnectionName, flag("cloudsql-instance-connection-name"), "", fmt.Sprintf("Cloud SQL instance connection name of the %s database.", label))
+	cmd.Flags().BoolVar(&dbCfg.UsePrivateIP, flag("cloudsql-use-private-ip"), false, fmt.Sprintf("Use the private IP address for the %s Cloud SQL connection.", label))
+}
+
+func init() {
+	addConnectionFlags(compareCmd, &appCfg.CompareSource, "src", "source")
+	addConnectionFlags(compareCmd, &appCfg.CompareTarget, "dst", "target")
+	compareCmd.Flags().StringVar(&appCfg.TablesRaw, "tables", "", "Comma-separated list of tables/columns to compare (e.g., 'table1[col1,col2],table2')")
+	compareCmd.Flags().StringVarP(&appCfg.OutputFile, "out_file", "o", "", "Path to the output diff file (defaults to <src_database>_vs_<dst_database>_comments_diff.txt)")
+}
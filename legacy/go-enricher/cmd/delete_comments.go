@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 	"log"
-	"os"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
@@ -32,9 +31,14 @@ func runDeleteComments(cmd *cobra.Command, args []string) error {
 
 	log.Println("INFO: Starting delete-comments operation", "dialect:", cfg.Database.Dialect, "database:", cfg.Database.DBName, "dry-run:", cfg.DryRun)
 
+	scope := strings.ToLower(cfg.DeleteScope)
+	if scope != "all" && scope != "tables" && scope != "columns" {
+		return fmt.Errorf("invalid value for --scope: '%s'. Must be 'all', 'tables' or 'columns'", cfg.DeleteScope)
+	}
+
 	dbAdapter, err := database.New(cfg.Database)
 	if err != nil {
-		return fmt.Errorf("failed to initialize database connection: %w", err)
+		return connectionError(cfg.Database, err)
 	}
 	defer dbAdapter.Close()
 	log.Println("INFO: Database connection established successfully.")
@@ -48,19 +52,30 @@ func runDeleteComments(cmd *cobra.Command, args []string) error {
 	}
 	deleteParams := enricher.GenerateDeleteSQLParams{
 		TableFilters: tableFilters,
+		StripStats:   cfg.StripStats,
+		Scope:        scope,
 	}
-	sqlStatements, err := svc.GenerateDeleteCommentSQLs(ctx, deleteParams)
+	changes, err := svc.GenerateDeleteCommentChanges(ctx, deleteParams)
 	if err != nil {
 		return fmt.Errorf("failed to generate SQL for comment deletion: %w", err)
 	}
 
-	if len(sqlStatements) == 0 {
+	if len(changes) == 0 {
 		log.Println("INFO: No SQL statements generated for deletion. This might be due to filters or no tagged comments found matching the criteria.")
 		return nil
 	}
 
+	if cfg.ShowDiff {
+		fmt.Print(enricher.FormatCommentChangesAsText(changes))
+	}
+
+	sqlStatements := make([]string, len(changes))
+	for i, change := range changes {
+		sqlStatements[i] = change.SQL
+	}
+
 	fileContent := strings.Join(sqlStatements, "\n") + "\n"
-	writeErr := os.WriteFile(outputFile, []byte(fileContent), 0644)
+	writeErr := writeOutputFile(cfg, outputFile, []byte(fileContent))
 	if writeErr != nil {
 		return fmt.Errorf("failed to write output file '%s': %w", outputFile, writeErr)
 	}
@@ -73,7 +88,7 @@ func runDeleteComments(cmd *cobra.Command, args []string) error {
 
 	// Dry run is false
 	if utils.ConfirmAction(fmt.Sprintf("apply %d generated SQL statements for comment DELETION from '%s'", len(sqlStatements), outputFile)) {
-		log.Println("INFO: Applying SQL statements to the database...")
+		log.Println("INFO: Applying SQL statements to the database..")
 
 		if execErr := dbAdapter.ExecuteSQLStatements(ctx, sqlStatements); execErr != nil {
 			return fmt.Errorf("failed to execute SQL statements for comment deletion from '%s': %w. Review the file and database logs", outputFile, execErr)
@@ -89,5 +104,9 @@ func runDeleteComments(cmd *cobra.Command, args []string) error {
 
 func init() {
 	deleteCommentsCmd.Flags().StringVarP(&appCfg.OutputFile, "out_file", "o", "", "Path to the output SQL file (defaults to <database_name>_comments.sql)")
-	deleteCommentsCmd.Flags().StringVar(&appCfg.TablesRaw, "tables", "", "Comma-separated list of tables/columns to target for comment deletion (e.g., 'table1[col1],table2')")
+	deleteCommentsCmd.Flags().StringVar(&appCfg.TablesRaw, "tables", "", "Comma-separated list of tables/columns to target for comment deletion (e.g., 'table1[col1],table2'). Use '-' to read the list from stdin.")
+	deleteCommentsCmd.Flags().BoolVar(&appCfg.ShowDiff, "show-diff", false, "Print each targeted comment before and after its <gemini> tags are stripped.")
+	deleteCommentsCmd.Flags().BoolVar(&appCfg.StripStats, "strip-stats-on-delete", false, "Also remove statistics such as 'Distinct: N', 'Nulls: N' or 'Examples: [..]' that earlier versions wrote outside the <gemini> tags. Only ' | '-separated segments that are entirely a statistic are removed.")
+	deleteCommentsCmd.Flags().StringVar(&appCfg.DeleteScope, "scope", "all", "Which comments to remove: 'all', 'tables' (table comments only) or 'columns' (column comments only).")
+	deleteCommentsCmd.Flags().BoolVar(&appCfg.Database.CascadePartitions, "cascade-partitions", false, "Also emit column comment statements for every partition of a partitioned table (postgres only).")
 }
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+)
+
+// Exit codes returned by the tool, so that scripts and CI can tell failures apart.
+const (
+	ExitOK                = 0
+	ExitFailure           = 1 // Any error not covered below.
+	ExitConfigError       = 2 // Invalid flags or configuration.
+	ExitConnectionError   = 3 // The database could not be connected to.
+	ExitPartialEnrichment = 4 // Some tables or columns failed; see the logged errors.
+	ExitLLMAuthError      = 5 // The Gemini API key is missing, or the LLM credentials were rejected.
+	ExitInterrupted       = 6 // Stopped by SIGINT or SIGTERM; partial results may have been written.
+)
+
+// ExitCode maps an error returned by Execute to the process exit code. Errors are
+// classified by the typed errors of the enricher package they wrap.
+func ExitCode(err error) int {
+	var (
+		invalidInput *enricher.ErrInvalidInput
+		llmAuth      *enricher.ErrLLMAuth
+		partial      *enricher.ErrPartialEnrichment
+		connection   *enricher.ErrDatabaseConnection
+		cancelled    *enricher.ErrCancelled
+	)
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &invalidInput):
+		return ExitConfigError
+	case errors.As(err, &llmAuth):
+		return ExitLLMAuthError
+	case errors.As(err, &partial):
+		return ExitPartialEnrichment
+	case errors.As(err, &connection):
+		return ExitConnectionError
+	case errors.As(err, &cancelled):
+		return ExitInterrupted
+	default:
+		return ExitFailure
+	}
+}
+
+// connectionError wraps a failure to open the configured database.
+func connectionError(dbCfg config.DatabaseConfig, err error) error {
+	return &enricher.ErrDatabaseConnection{Msg: "failed to connect to " + dbCfg.Dialect + " database '" + dbCfg.DBName + "'", Err: err}
+}
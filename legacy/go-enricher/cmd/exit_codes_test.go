@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+)
+
+func TestExitCode(t *testing.T) {
+	connErr := connectionError(config.DatabaseConfig{Dialect: "postgres", DBName: "sales"}, errors.New("connection refused"))
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, ExitOK},
+		{"generic error", errors.New("failed to write output file"), ExitFailure},
+		{"config error", &enricher.ErrInvalidInput{Msg: "configuration validation failed", Err: errors.New("--dialect is required")}, ExitConfigError},
+		{"connection error", connErr, ExitConnectionError},
+		{"wrapped connection error", fmt.Errorf("delete comments: %w", connErr), ExitConnectionError},
+		{"partial enrichment", fmt.Errorf("SQL generation failed: %w", &enricher.ErrPartialEnrichment{Operation: "SQL generation", Errs: []error{errors.New("Table[orders] list columns: permission denied")}}), ExitPartialEnrichment},
+		{"partial enrichment of connection errors", &enricher.ErrPartialEnrichment{Operation: "SQL generation", Errs: []error{connErr}}, ExitPartialEnrichment},
+		{"interrupted", fmt.Errorf("add comments: %w", &enricher.ErrCancelled{Msg: "metadata collection interrupted after 3 column(s)", Err: context.Canceled}), ExitInterrupted},
+		{"LLM auth error", &enricher.ErrLLMAuth{Msg: "Gemini API key validation failed", Err: errors.New("API key not valid")}, ExitLLMAuthError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCodeForFlagErrors(t *testing.T) {
+	rootCmd.SetArgs([]string{"get-comments", "--no-such-flag"})
+	defer rootCmd.SetArgs(nil)
+
+	if got := ExitCode(Execute()); got != ExitConfigError {
+		t.Errorf("ExitCode() for an unknown flag = %d, want %d", got, ExitConfigError)
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate comment SQL from a saved metadata snapshot, without a database connection",
+	Long: `Reads a metadata snapshot written by add-comments --collect-out and generates the comment SQL for it offline.
+Neither the database nor the LLM is contacted, which makes it cheap to iterate on comment formatting or to render the same metadata for another dialect.
+Because existing comments cannot be read, the generated SQL replaces any comment already on a table or column.`,
+	Example: `./db_schema_enricher generate --in_file ./metadata.json --dialect postgres --enrichments "description,examples" --out_file ./mydb_comments.sql`,
+	// Offline generation needs no connection details, so the root command's validation is skipped.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if appCfg.NoColor {
+			utils.SetColorEnabled(false)
+		}
+		return nil
+	},
+	RunE: runGenerate,
+}
+
+func runGenerate(cmd *cobra.Command, args []string) error {
+	cfg := getAppConfig()
+
+	if cfg.InputFile == "" {
+		return fmt.Errorf("a metadata snapshot is required (--in_file)")
+	}
+	snapshot, err := enricher.ReadSnapshot(cfg.InputFile)
+	if err != nil {
+		return err
+	}
+
+	dialect := cfg.Database.Dialect
+	if dialect == "" {
+		dialect = snapshot.Dialect
+	}
+	if dialect == "" {
+		return fmt.Errorf("the snapshot does not record a dialect; set --dialect")
+	}
+	if cfg.Database.DBName == "" {
+		cfg.Database.DBName = snapshot.Database
+	}
+	if err := database.ValidateCommentEncoding(cfg.Database.CommentEncoding); err != nil {
+		return err
+	}
+	if err := database.ValidateEmbedSource(cfg.Database.EmbedSource); err != nil {
+		return err
+	}
+	if cfg.Database.MaxCommentParts < 0 {
+		return fmt.Errorf("invalid value for --max-comment-parts: %d. Must be 0 (no limit) or more", cfg.Database.MaxCommentParts)
+	}
+	cfg.Database.CommentStyle = strings.ToLower(cfg.Database.CommentStyle)
+	if style := cfg.Database.CommentStyle; style != "" && style != database.CommentStyleKeyValue && style != database.CommentStyleSentence {
+		return fmt.Errorf("invalid value for --comment-style: '%s'. Must be 'keyvalue' or 'sentence'", style)
+	}
+
+	outputFile := cfg.OutputFile
+	if outputFile == "" {
+		outputFile = cfg.GetDefaultOutputFile("generate")
+	}
+
+	log.Println("INFO: Starting generate operation", "dialect:", dialect, "snapshot:", cfg.InputFile)
+
+	if strings.TrimSpace(cfg.EnrichmentsRaw) != "" {
+		enrichmentSet, err := parseEnrichments(cfg.EnrichmentsRaw, true)
+		if err != nil {
+			return err
+		}
+		snapshot.Enrichments = enrichmentSet
+	}
+	if snapshot.Enrichments, err = excludeEnrichments(snapshot.Enrichments, cfg.ExcludeEnrichmentsRaw); err != nil {
+		return err
+	}
+
+	sqlStatements, err := generateFromSnapshot(snapshot, config.DatabaseConfig{
+		Dialect:                dialect,
+		CommentEncoding:        cfg.Database.CommentEncoding,
+		EmbedSource:            cfg.Database.EmbedSource,
+		LowercaseIdentifiers:   cfg.Database.LowercaseIdentifiers,
+		MaxCommentParts:        cfg.Database.MaxCommentParts,
+		EmbedProvenance:        cfg.Database.EmbedProvenance,
+		EmbedTimestamp:         cfg.Database.EmbedTimestamp,
+		CommentStyle:           cfg.Database.CommentStyle,
+		CommentIncludeDataType: cfg.Database.CommentIncludeDataType,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(sqlStatements) == 0 {
+		log.Println("INFO: No SQL statements generated. The snapshot may be empty or contain no enrichable content.")
+		return nil
+	}
+
+	fileContent := strings.Join(sqlStatements, "\n") + "\n"
+	if writeErr := writeOutputFile(cfg, outputFile, []byte(fileContent)); writeErr != nil {
+		return fmt.Errorf("failed to write output file '%s': %w", outputFile, writeErr)
+	}
+	log.Println("INFO: SQL statements successfully written to:", outputFile)
+	log.Println("INFO: Generate operation completed. Review the file and apply it with apply-comments.")
+	return nil
+}
+
+// generateFromSnapshot renders the comment SQL for a snapshot without connecting to a
+// database. Only the dialect, comment and identifier options of dbCfg are used.
+func generateFromSnapshot(snapshot *enricher.MetadataSnapshot, dbCfg config.DatabaseConfig) ([]string, error) {
+	dbAdapter, err := database.NewOffline(config.DatabaseConfig{
+		Dialect:                dbCfg.Dialect,
+		DBName:                 snapshot.Database,
+		UpdateExistingMode:     "overwrite",
+		CommentEncoding:        dbCfg.CommentEncoding,
+		EmbedSource:            dbCfg.EmbedSource,
+		LowercaseIdentifiers:   dbCfg.LowercaseIdentifiers,
+		MaxCommentParts:        dbCfg.MaxCommentParts,
+		EmbedProvenance:        dbCfg.EmbedProvenance,
+		EmbedTimestamp:         dbCfg.EmbedTimestamp,
+		CommentStyle:           dbCfg.CommentStyle,
+		CommentIncludeDataType: dbCfg.CommentIncludeDataType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	svc := enricher.NewService(dbAdapter, nil, enricher.Config{})
+	return svc.GenerateSQLFromSnapshot(snapshot), nil
+}
+
+func init() {
+	generateCmd.Flags().StringVarP(&appCfg.InputFile, "in_file", "i", "", "Path to the metadata snapshot written by add-comments --collect-out (required)")
+	generateCmd.Flags().StringVarP(&appCfg.OutputFile, "out_file", "o", "", "File path to output generated SQL statements (defaults to <database>_comments.sql)")
+	generateCmd.Flags().StringVar(&appCfg.Database.CommentEncoding, "comment-encoding", "", "Charset of the database's comment columns ('utf8', 'latin1' or 'ascii'). Generated text is transliterated to fit, and characters that cannot be represented are replaced with '?'.")
+	generateCmd.Flags().StringVar(&appCfg.Database.EmbedSource, "embed-source", "", "Record the database or environment the snapshot was collected from (e.g. 'prod') as a 'Source: <value>' entry in each generated comment.")
+	generateCmd.Flags().BoolVar(&appCfg.Database.EmbedTimestamp, "embed-timestamp", false, "Record when each column's statistics were collected, as a 'Profiled: <UTC time>' entry in its comment.")
+	generateCmd.Flags().StringVar(&appCfg.Database.CommentStyle, "comment-style", "keyvalue", "How generated column metadata is written: 'keyvalue' for terse 'Distinct: 150 | Nulls: 5' parts, 'sentence' for readable prose.")
+	generateCmd.Flags().BoolVar(&appCfg.Database.EmbedProvenance, "embed-provenance", false, "Record in each generated comment which parts were inferred (description) and which were computed from the database (examples, statistics, foreign keys), as a 'Provenance: ...' entry.")
+	generateCmd.Flags().BoolVar(&appCfg.Database.CommentIncludeDataType, "comment-include-datatype", false, "Start each column comment with the column's data type, e.g. 'Type: varchar(255)', as the data_type enrichment does.")
+	generateCmd.Flags().StringVar(&appCfg.EnrichmentsRaw, "enrichments", "", "Comma-separated list of enrichments to render, or 'all'. Defaults to the enrichments the snapshot was collected with.")
+	generateCmd.Flags().IntVar(&appCfg.Database.MaxCommentParts, "max-comment-parts", 0, "Keep at most this many parts in each column comment, by priority: description, foreign keys, examples, distinct values, null count, data type, then custom enrichments (0 keeps all).")
+	generateCmd.Flags().StringVar(&appCfg.ExcludeEnrichmentsRaw, "exclude-enrichments", "", "Comma-separated list of enrichments to leave out of the rendered comments (e.g., 'examples').")
+}
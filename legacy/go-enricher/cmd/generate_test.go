@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+)
+
+func TestGenerateFromSnapshot(t *testing.T) {
+	tests := []struct {
+		name        string
+		dialect     string
+		embedSource string
+		expected    []string
+	}{
+		{"postgres", "postgres", "", []string{
+			`COMMENT ON TABLE "orders" IS '<gemini>Customer orders</gemini>';`,
+			`COMMENT ON COLUMN "orders"."id" IS '<gemini>Examples: [''1'', ''2''] | Order identifier</gemini>';`,
+			`COMMENT ON COLUMN "orders"."user_id" IS '<gemini>Ordering user | Foreign Keys: ["users"."id" (Registered users)]</gemini>';`,
+		}},
+		{"mysql uses the collected column type", "mysql", "", []string{
+			"ALTER TABLE `orders` COMMENT = '<gemini>Customer orders</gemini>';",
+			"ALTER TABLE `orders` MODIFY COLUMN `id` int COMMENT '<gemini>Examples: [''1'', ''2''] | Order identifier</gemini>';",
+			"ALTER TABLE `orders` MODIFY COLUMN `user_id` int COMMENT '<gemini>Ordering user | Foreign Keys: [`users`.`id` (Registered users)]</gemini>';",
+		}},
+		{"postgres with embedded source", "postgres", "prod", []string{
+			`COMMENT ON TABLE "orders" IS '<gemini>Source: prod | Customer orders</gemini>';`,
+			`COMMENT ON COLUMN "orders"."id" IS '<gemini>Source: prod | Examples: [''1'', ''2''] | Order identifier</gemini>';`,
+			`COMMENT ON COLUMN "orders"."user_id" IS '<gemini>Source: prod | Ordering user | Foreign Keys: ["users"."id" (Registered users)]</gemini>';`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot, err := enricher.ReadSnapshot("testdata/metadata_snapshot.json")
+			if err != nil {
+				t.Fatalf("ReadSnapshot() error = %v", err)
+			}
+			got, err := generateFromSnapshot(snapshot, config.DatabaseConfig{Dialect: tt.dialect, EmbedSource: tt.embedSource})
+			if err != nil {
+				t.Fatalf("generateFromSnapshot() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("generateFromSnapshot() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
 	"github.com/spf13/cobra"
@@ -22,23 +25,41 @@ func runGetComments(cmd *cobra.Command, args []string) error {
 	cfg := getAppConfig()
 	ctx := cmd.Context()
 
+	format := strings.ToLower(cfg.ReportFormat)
+	if format != "text" && format != "json" {
+		return fmt.Errorf("invalid value for --format: '%s'. Must be 'text' or 'json'", cfg.ReportFormat)
+	}
+	if format == "json" && cfg.StreamComments {
+		return fmt.Errorf("--stream writes text only and cannot be combined with --format json")
+	}
+	if cfg.MaxConcurrency < 0 {
+		return fmt.Errorf("invalid value for --max-concurrency: %d. Must be 0 (no limit) or more", cfg.MaxConcurrency)
+	}
+
 	outputFile := cfg.OutputFile
 	if outputFile == "" {
 		outputFile = cfg.GetDefaultOutputFile("get-comments")
+		if format == "json" {
+			outputFile = strings.TrimSuffix(outputFile, ".txt") + ".json"
+		}
 	}
 
 	log.Println("INFO: Starting get-comments operation", "dialect:", cfg.Database.Dialect, "database:", cfg.Database.DBName)
 
 	dbAdapter, err := database.New(cfg.Database)
 	if err != nil {
-		return fmt.Errorf("failed to initialize database connection: %w", err)
+		return connectionError(cfg.Database, err)
 	}
 	defer dbAdapter.Close()
 	log.Println("INFO: Database connection established successfully.")
 
-	enricherCfg := enricher.Config{MaskPII: appCfg.MaskPII}
+	enricherCfg := enricher.Config{MaskPII: appCfg.MaskPII, MaxConcurrency: appCfg.MaxConcurrency}
 	svc := enricher.NewService(dbAdapter, nil, enricherCfg)
 
+	if appCfg.StreamComments {
+		return streamComments(ctx, cfg, svc, outputFile)
+	}
+
 	getParams := enricher.GetCommentsParams{}
 
 	comments, err := svc.GetComments(ctx, getParams)
@@ -57,9 +78,17 @@ func runGetComments(cmd *cobra.Command, args []string) error {
 
 	log.Printf("INFO: Retrieved %d comments.", len(comments))
 
-	formattedComments := enricher.FormatCommentsAsText(comments)
+	var content []byte
+	if format == "json" {
+		content, err = enricher.FormatCommentsAsJSON(comments)
+		if err != nil {
+			return fmt.Errorf("failed to encode comments as JSON: %w", err)
+		}
+	} else {
+		content = []byte(enricher.FormatCommentsAsText(comments))
+	}
 
-	writeErr := os.WriteFile(outputFile, []byte(formattedComments), 0644)
+	writeErr := writeOutputFile(cfg, outputFile, content)
 	if writeErr != nil {
 		return fmt.Errorf("failed to write comments to file '%s': %w", outputFile, writeErr)
 	}
@@ -69,6 +98,56 @@ func runGetComments(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// streamComments writes each table's comments to outputFile as soon as the table is
+// read, so that the comments of a very large schema are never all held in memory.
+// Tables appear in the order they were read rather than by name.
+func streamComments(ctx context.Context, cfg *config.AppConfig, svc *enricher.Service, outputFile string) error {
+	var out *os.File
+	count := 0
+	getParams := enricher.GetCommentsParams{OnTable: func(tableComments []*enricher.ColumnComment) error {
+		separator := "\n"
+		if out == nil {
+			f, err := createOutputFile(cfg, outputFile)
+			if err != nil {
+				return fmt.Errorf("failed to create file '%s': %w", outputFile, err)
+			}
+			out = f
+			separator = ""
+		}
+		if _, err := out.WriteString(separator + enricher.FormatCommentsAsText(tableComments)); err != nil {
+			return fmt.Errorf("failed to write comments to file '%s': %w", outputFile, err)
+		}
+		count += len(tableComments)
+		return nil
+	}}
+
+	_, err := svc.GetComments(ctx, getParams)
+	if out != nil {
+		if closeErr := out.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to write comments to file '%s': %w", outputFile, closeErr)
+		}
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed during comment retrieval: %v", err)
+		if count > 0 {
+			log.Printf("WARN: %d comments were written to %s before the error occurred.", count, outputFile)
+		}
+		return fmt.Errorf("failed to retrieve comments: %w", err)
+	}
+
+	if count == 0 {
+		log.Println("INFO: No comments found in the database (or matching the specified filters).")
+		return nil
+	}
+
+	log.Printf("INFO: Streamed %d comments to: %s", count, outputFile)
+	log.Println("INFO: Get comments operation completed.")
+	return nil
+}
+
 func init() {
 	getCommentsCmd.Flags().StringVarP(&appCfg.OutputFile, "out_file", "o", "", "Path to the output file to save the comments (defaults to <database_name>_comments.txt)")
+	getCommentsCmd.Flags().IntVar(&appCfg.MaxConcurrency, "max-concurrency", 0, "Maximum number of tables to read comments from at once (0 means no limit).")
+	getCommentsCmd.Flags().BoolVar(&appCfg.StreamComments, "stream", false, "Write each table's comments to the output file as soon as they are read, instead of all at once sorted by table.")
+	getCommentsCmd.Flags().StringVar(&appCfg.ReportFormat, "format", appCfg.ReportFormat, "Output format: 'text' or 'json'. JSON lists each comment with its table, column (empty for table comments) and parsed provenance.")
 }
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var listTablesCmd = &cobra.Command{
+	Use:   "list-tables",
+	Short: "Print the tables the other commands would process",
+	Long: `Connects to the database and prints the tables selected by --tables (all tables if omitted), one per line,
+optionally with the number of columns selected in each. Useful for building --tables filters and for checking
+connectivity and permissions before a run. Nothing is written to the database.`,
+	Example: `./db_schema_enricher list-tables --dialect postgres --host localhost --port 5432 --username user --password pass --database shop --column-counts --format json`,
+	RunE:    runListTables,
+}
+
+func runListTables(cmd *cobra.Command, args []string) error {
+	cfg := getAppConfig()
+	ctx := cmd.Context()
+
+	format := strings.ToLower(cfg.ReportFormat)
+	if format != "text" && format != "json" {
+		return fmt.Errorf("invalid value for --format: '%s'. Must be 'text' or 'json'", cfg.ReportFormat)
+	}
+
+	tableFilters, err := utils.ParseTablesFlag(cfg.TablesRaw)
+	if err != nil {
+		return fmt.Errorf("error parsing --tables flag: %w", err)
+	}
+
+	dbAdapter, err := database.New(cfg.Database)
+	if err != nil {
+		return connectionError(cfg.Database, err)
+	}
+	defer dbAdapter.Close()
+
+	svc := enricher.NewService(dbAdapter, nil, enricher.Config{})
+	listings, err := svc.ListTables(ctx, enricher.ListTablesParams{TableFilters: tableFilters, CountColumns: cfg.ColumnCounts})
+	if err != nil {
+		return err
+	}
+
+	var content []byte
+	if format == "json" {
+		content, err = json.MarshalIndent(listings, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode table list as JSON: %w", err)
+		}
+		content = append(content, '\n')
+	} else {
+		content = []byte(enricher.FormatTableListingAsText(listings))
+	}
+	if _, err := cmd.OutOrStdout().Write(content); err != nil {
+		return fmt.Errorf("failed to write table list: %w", err)
+	}
+
+	log.Printf("INFO: Listed %d table(s).", len(listings))
+	return nil
+}
+
+func init() {
+	listTablesCmd.Flags().StringVar(&appCfg.TablesRaw, "tables", "", "Comma-separated list of tables/columns to list (e.g., 'table1[col1,col2],table2'). Use '-' to read the list from stdin.")
+	listTablesCmd.Flags().BoolVar(&appCfg.ColumnCounts, "column-counts", false, "Also print the number of columns selected in each table.")
+	listTablesCmd.Flags().StringVar(&appCfg.ReportFormat, "format", appCfg.ReportFormat, "Output format: 'text' or 'json'.")
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/genai"
+	"github.com/spf13/cobra"
+)
+
+// addLLMRetryFlags adds the flags controlling how Gemini calls that were rate limited
+// or found the service unavailable are retried.
+func addLLMRetryFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&appCfg.LLMMaxRetries, "llm-max-retries", 3, "Times to retry a Gemini call that was rate limited (429) or found the service unavailable (503), waiting longer before each retry (0 disables retries).")
+	cmd.Flags().DurationVar(&appCfg.LLMRetryBackoff, "llm-retry-backoff", 2*time.Second, "Wait before the first retry of a Gemini call; it doubles for each further retry, up to 30s.")
+}
+
+// validateLLMRetries rejects invalid --llm-max-retries and --llm-retry-backoff values.
+func validateLLMRetries(cfg *config.AppConfig) error {
+	if cfg.LLMMaxRetries < 0 {
+		return fmt.Errorf("invalid value for --llm-max-retries: %d. Must be 0 (no retries) or more", cfg.LLMMaxRetries)
+	}
+	if cfg.LLMRetryBackoff <= 0 {
+		return fmt.Errorf("invalid value for --llm-retry-backoff: %s. Must be positive", cfg.LLMRetryBackoff)
+	}
+	return nil
+}
+
+// hasLLMCredentials reports whether an LLM client can be created: Vertex AI uses
+// application default credentials, while the Gemini API needs an API key.
+func hasLLMCredentials(cfg *config.AppConfig) bool {
+	return cfg.UsesVertexAI() || cfg.GeminiAPIKey != ""
+}
+
+// newLLMConfig returns the genai.Config for the configured provider, model and retries.
+func newLLMConfig(cfg *config.AppConfig) genai.Config {
+	return genai.Config{
+		Provider:         cfg.LLMProvider,
+		APIKey:           cfg.GeminiAPIKey,
+		Project:          cfg.VertexProject,
+		Location:         cfg.VertexLocation,
+		Model:            cfg.Model,
+		StructuredOutput: cfg.StructuredOutput,
+		MaxRetries:       llmMaxRetries(cfg),
+		InitialBackoff:   cfg.LLMRetryBackoff,
+	}
+}
+
+// llmMaxRetries returns --llm-max-retries as genai.Config.MaxRetries, where 0 means
+// the default and a negative number disables retries.
+func llmMaxRetries(cfg *config.AppConfig) int {
+	if cfg.LLMMaxRetries == 0 {
+		return -1
+	}
+	return cfg.LLMMaxRetries
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+)
+
+func TestLLMRetries(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxRetries     int
+		backoff        time.Duration
+		wantErr        bool
+		wantMaxRetries int
+	}{
+		{"default", 3, 2 * time.Second, false, 3},
+		{"no retries", 0, 2 * time.Second, false, -1},
+		{"negative retries", -1, 2 * time.Second, true, 0},
+		{"no backoff", 3, 0, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.AppConfig{LLMMaxRetries: tt.maxRetries, LLMRetryBackoff: tt.backoff}
+			err := validateLLMRetries(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateLLMRetries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if got := llmMaxRetries(cfg); got != tt.wantMaxRetries {
+					t.Errorf("llmMaxRetries() = %d, want %d", got, tt.wantMaxRetries)
+				}
+			}
+		})
+	}
+}
+
+func TestHasLLMCredentials(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.AppConfig
+		wanted bool
+	}{
+		{"gemini with API key", config.AppConfig{LLMProvider: "gemini", GeminiAPIKey: "key"}, true},
+		{"gemini without API key", config.AppConfig{LLMProvider: "gemini"}, false},
+		{"vertexai", config.AppConfig{LLMProvider: "vertexai", VertexProject: "my-project"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasLLMCredentials(&tt.cfg); got != tt.wanted {
+				t.Errorf("hasLLMCredentials() = %v, want %v", got, tt.wanted)
+			}
+		})
+	}
+}
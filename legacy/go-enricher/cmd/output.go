@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+)
+
+// writeOutputFile writes a generated file with the --output-mode permissions.
+func writeOutputFile(cfg *config.AppConfig, path string, content []byte) error {
+	mode, err := cfg.OutputFileMode()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, mode); err != nil {
+		return err
+	}
+	return applyOutputMode(cfg, path)
+}
+
+// createOutputFile creates or truncates a generated file with the --output-mode
+// permissions, for output that is written piece by piece.
+func createOutputFile(cfg *config.AppConfig, path string) (*os.File, error) {
+	mode, err := cfg.OutputFileMode()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyOutputMode(cfg, path); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// applyOutputMode sets an explicitly requested --output-mode on path. Creating a file
+// applies the umask and leaves an existing file's permissions alone, so without this
+// a file written earlier as 0644 would stay readable by everyone.
+func applyOutputMode(cfg *config.AppConfig, path string) error {
+	if cfg.OutputModeRaw == "" {
+		return nil
+	}
+	mode, err := cfg.OutputFileMode()
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("failed to set permissions of '%s': %w", path, err)
+	}
+	return nil
+}
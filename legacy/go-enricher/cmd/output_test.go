@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+)
+
+func TestWriteOutputFileMode(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.sql")
+	if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		mode string
+		want os.FileMode
+	}{
+		{"new file", filepath.Join(dir, "new.sql"), "0600", 0600},
+		{"existing file", existing, "0600", 0600},
+		{"group readable", filepath.Join(dir, "group.sql"), "0640", 0640},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewAppConfig()
+			cfg.OutputModeRaw = tt.mode
+			if err := writeOutputFile(cfg, tt.path, []byte("SELECT 1;\n")); err != nil {
+				t.Fatalf("writeOutputFile() unexpected error: %v", err)
+			}
+			info, err := os.Stat(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("file mode = %o, want %o", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("streamed file", func(t *testing.T) {
+		cfg := config.NewAppConfig()
+		cfg.OutputModeRaw = "0600"
+		path := filepath.Join(dir, "comments.txt")
+		f, err := createOutputFile(cfg, path)
+		if err != nil {
+			t.Fatalf("createOutputFile() unexpected error: %v", err)
+		}
+		f.Close()
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != 0600 {
+			t.Errorf("file mode = %o, want 600", got)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/genai"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var piiReportCmd = &cobra.Command{
+	Use:   "pii-report",
+	Short: "Report which columns likely contain PII without modifying comments",
+	Long: `Connects to the database, samples values for the selected columns and classifies each column as likely PII or not.
+Column names and sample values are checked against common PII patterns; when a Gemini API key is available
+or --llm-provider vertexai is used, columns not flagged by the patterns are also classified by the LLM. No comments are generated or applied.`,
+	Example: `./db_schema_enricher pii-report --dialect postgres --host localhost --port 5432 --username user --password pass --database crm --tables "customers,orders[email]" --format json`,
+	RunE:    runPIIReport,
+}
+
+func runPIIReport(cmd *cobra.Command, args []string) error {
+	cfg := getAppConfig()
+	ctx := cmd.Context()
+
+	format := strings.ToLower(cfg.ReportFormat)
+	if format != "text" && format != "json" {
+		return fmt.Errorf("invalid value for --format: '%s'. Must be 'text' or 'json'", cfg.ReportFormat)
+	}
+	if err := validateLLMRetries(cfg); err != nil {
+		return err
+	}
+
+	outputFile := cfg.OutputFile
+	if outputFile == "" {
+		outputFile = cfg.GetDefaultOutputFile("pii-report")
+		if format == "json" {
+			outputFile = strings.TrimSuffix(outputFile, ".txt") + ".json"
+		}
+	}
+
+	log.Println("INFO: Starting pii-report operation", "dialect:", cfg.Database.Dialect, "database:", cfg.Database.DBName)
+
+	dbAdapter, err := database.New(cfg.Database)
+	if err != nil {
+		return connectionError(cfg.Database, err)
+	}
+	defer dbAdapter.Close()
+
+	var llmClient genai.LLMClient
+	if hasLLMCredentials(cfg) {
+		llmClient, err = genai.NewClient(ctx, newLLMConfig(cfg))
+		if err != nil {
+			return fmt.Errorf("failed to initialize Gemini client: %w", err)
+		}
+		if cfg.LLMRPS > 0 {
+			llmClient = genai.NewRateLimitedClient(llmClient, cfg.LLMRPS)
+		}
+		defer llmClient.Close()
+		log.Println("INFO: LLM client initialized; columns not matched by PII patterns will be classified by the LLM.")
+	} else {
+		log.Println("INFO: No Gemini API key provided. Only pattern-based PII detection will be used.")
+	}
+
+	tableFilters, err := utils.ParseTablesFlag(cfg.TablesRaw)
+	if err != nil {
+		return fmt.Errorf("error parsing --tables flag: %w", err)
+	}
+
+	svc := enricher.NewService(dbAdapter, llmClient, enricher.Config{MaskPII: true})
+	findings, err := svc.GeneratePIIReport(ctx, enricher.PIIReportParams{TableFilters: tableFilters})
+	if err != nil {
+		return fmt.Errorf("PII classification failed: %w", err)
+	}
+
+	var content []byte
+	if format == "json" {
+		content, err = json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode PII report as JSON: %w", err)
+		}
+		content = append(content, '\n')
+	} else {
+		content = []byte(enricher.FormatPIIReportAsText(findings))
+	}
+
+	if writeErr := writeOutputFile(cfg, outputFile, content); writeErr != nil {
+		return fmt.Errorf("failed to write PII report to file '%s': %w", outputFile, writeErr)
+	}
+
+	log.Printf("INFO: PII report with %d flagged column(s) written to: %s", len(findings), outputFile)
+	return nil
+}
+
+func init() {
+	piiReportCmd.Flags().StringVarP(&appCfg.OutputFile, "out_file", "o", "", "Path to the output report file (defaults to <database_name>_pii_report.txt or .json)")
+	piiReportCmd.Flags().StringVar(&appCfg.TablesRaw, "tables", "", "Comma-separated list of tables/columns to classify (e.g., 'table1[col1,col2],table2'). Use '-' to read the list from stdin.")
+	piiReportCmd.Flags().StringVar(&appCfg.ReportFormat, "format", appCfg.ReportFormat, "Report format: 'text' or 'json'.")
+	piiReportCmd.Flags().StringVar(&appCfg.Model, "model", appCfg.Model, "Model to use for LLM-based PII classification.")
+	piiReportCmd.Flags().Float64Var(&appCfg.LLMRPS, "llm-rps", 0, "Maximum Gemini requests per second across all concurrent workers (0 means no limit).")
+	addLLMRetryFlags(piiReportCmd)
+	piiReportCmd.Flags().BoolVar(&appCfg.StructuredOutput, "structured-output", false, "Ask Gemini for JSON responses that follow a response schema instead of parsing tagged text. Falls back to tags for models that do not support it.")
+}
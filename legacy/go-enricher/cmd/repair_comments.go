@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var repairCommentsCmd = &cobra.Command{
+	Use:   "repair-comments",
+	Short: "Generate SQL to fix comments with malformed <gemini> tags",
+	Long: `Finds comments whose <gemini> tags are malformed, for example an unterminated tag left by an interrupted run, and generates SQL that rewrites them.
+Unterminated blocks are removed, since their metadata may be truncated; stray or nested tags are dropped and multiple blocks are merged into one.
+Outputs the SQL to a file. If --dry-run=false, prompts for application.`,
+	Example: `./db_schema_enricher repair-comments --dialect postgres --username user --password pass --database sales_db --out_file ./repair_sales_comments.sql --show-diff`,
+	RunE:    runRepairComments,
+}
+
+func runRepairComments(cmd *cobra.Command, args []string) error {
+	cfg := getAppConfig()
+	ctx := cmd.Context()
+
+	outputFile := cfg.OutputFile
+	if outputFile == "" {
+		outputFile = cfg.GetDefaultOutputFile("repair-comments")
+	}
+
+	log.Println("INFO: Starting repair-comments operation", "dialect:", cfg.Database.Dialect, "database:", cfg.Database.DBName, "dry-run:", cfg.DryRun)
+
+	dbAdapter, err := database.New(cfg.Database)
+	if err != nil {
+		return connectionError(cfg.Database, err)
+	}
+	defer dbAdapter.Close()
+	log.Println("INFO: Database connection established successfully.")
+
+	svc := enricher.NewService(dbAdapter, nil, enricher.Config{MaskPII: appCfg.MaskPII})
+
+	tableFilters, err := utils.ParseTablesFlag(cfg.TablesRaw)
+	if err != nil {
+		return fmt.Errorf("error parsing --tables flag: %w", err)
+	}
+	changes, err := svc.GenerateRepairCommentChanges(ctx, enricher.GenerateRepairSQLParams{TableFilters: tableFilters})
+	if err != nil {
+		return fmt.Errorf("failed to generate SQL for comment repair: %w", err)
+	}
+
+	if len(changes) == 0 {
+		log.Println("INFO: No malformed <gemini> tags found. Nothing to repair.")
+		return nil
+	}
+
+	if cfg.ShowDiff {
+		fmt.Print(enricher.FormatCommentChangesAsText(changes))
+	}
+
+	sqlStatements := make([]string, len(changes))
+	for i, change := range changes {
+		sqlStatements[i] = change.SQL
+	}
+
+	fileContent := strings.Join(sqlStatements, "\n") + "\n"
+	if writeErr := writeOutputFile(cfg, outputFile, []byte(fileContent)); writeErr != nil {
+		return fmt.Errorf("failed to write output file '%s': %w", outputFile, writeErr)
+	}
+	log.Println("INFO: SQL statements successfully written to:", outputFile)
+
+	if cfg.DryRun {
+		log.Println("INFO: Repair comments operation completed in dry-run mode. Review the generated SQL file:", outputFile)
+		return nil
+	}
+
+	if utils.ConfirmAction(fmt.Sprintf("apply %d generated SQL statements for comment REPAIR from '%s'", len(sqlStatements), outputFile)) {
+		log.Println("INFO: Applying SQL statements to the database..")
+
+		if execErr := dbAdapter.ExecuteSQLStatements(ctx, sqlStatements); execErr != nil {
+			return fmt.Errorf("failed to execute SQL statements for comment repair from '%s': %w. Review the file and database logs", outputFile, execErr)
+		}
+		log.Printf("INFO: Successfully applied %d SQL statements for comment repair.", len(sqlStatements))
+	} else {
+		log.Println("INFO: Comment repair aborted by user. Generated SQL statements remain in:", outputFile)
+	}
+
+	log.Println("INFO: Repair comments operation completed.")
+	return nil
+}
+
+func init() {
+	repairCommentsCmd.Flags().StringVarP(&appCfg.OutputFile, "out_file", "o", "", "Path to the output SQL file (defaults to <database_name>_comments.sql)")
+	repairCommentsCmd.Flags().StringVar(&appCfg.TablesRaw, "tables", "", "Comma-separated list of tables/columns to check for malformed tags (e.g., 'table1[col1],table2'). Use '-' to read the list from stdin.")
+	repairCommentsCmd.Flags().BoolVar(&appCfg.ShowDiff, "show-diff", false, "Print each malformed comment before and after repair.")
+	repairCommentsCmd.Flags().BoolVar(&appCfg.Database.CascadePartitions, "cascade-partitions", false, "Also emit column comment statements for every partition of a partitioned table (postgres only).")
+}
@@ -1,14 +1,25 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	_ "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/bigquery"
+	_ "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/clickhouse"
 	_ "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/mysql"
 	_ "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/postgres"
+	_ "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/spanner"
+	_ "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/sqlite"
 	_ "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/sqlserver"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/utils"
 
 	"github.com/spf13/cobra"
 )
@@ -23,42 +34,116 @@ var rootCmd = &cobra.Command{
 	Long: `db_schema_enricher is a CLI tool that helps enrich database schemas
 with metadata like column descriptions, example values, distinct values, null counts, and foreign key relationships.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if appCfg.NoColor {
+			utils.SetColorEnabled(false)
+		}
+		if appCfg.CloudSQLAutodetect {
+			if err := autodetectCloudSQLDialect(cmd.Context(), &appCfg.Database); err != nil {
+				log.Printf("ERROR: Cloud SQL dialect detection failed: %v", err)
+				return &enricher.ErrInvalidInput{Msg: "--cloudsql-autodetect failed", Err: err}
+			}
+		}
 		err := appCfg.LoadAndValidate()
 		if err != nil {
 			log.Printf("ERROR: Configuration validation failed: %v", err)
+			return &enricher.ErrInvalidInput{Msg: "configuration validation failed", Err: err}
 		}
-		return err
+		return nil
 	},
 }
 
+// autodetectCloudSQLDialect sets the dialect from the engine of the Cloud SQL instance
+// for --cloudsql-autodetect. An explicit --dialect must match the instance.
+func autodetectCloudSQLDialect(ctx context.Context, dbc *config.DatabaseConfig) error {
+	if dbc.CloudSQLInstanceConnectionName == "" {
+		return fmt.Errorf("--cloudsql-autodetect requires --cloudsql-instance-connection-name")
+	}
+	lookup, err := database.NewCloudSQLInstanceLookup(ctx)
+	if err != nil {
+		return err
+	}
+	dialect, err := database.DetectCloudSQLDialect(ctx, lookup, dbc.CloudSQLInstanceConnectionName)
+	if err != nil {
+		return err
+	}
+	if dbc.Dialect != "" && dbc.Dialect != dialect {
+		return fmt.Errorf("--dialect %s does not match Cloud SQL instance %s, which needs %s", dbc.Dialect, dbc.CloudSQLInstanceConnectionName, dialect)
+	}
+	log.Printf("INFO: Detected dialect %s for Cloud SQL instance %s.", dialect, dbc.CloudSQLInstanceConnectionName)
+	dbc.Dialect = dialect
+	return nil
+}
+
 func Execute() error {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	return rootCmd.Execute()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+	go func() {
+		select {
+		case sig := <-interrupts:
+			// A second interrupt gets the default behavior and ends the process at once.
+			signal.Stop(interrupts)
+			log.Printf("WARN: Received %s. Finishing the queries in flight and writing partial results; interrupt again to exit immediately.", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
+	// Flag parsing errors are configuration errors, like failed validation.
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &enricher.ErrInvalidInput{Msg: "invalid flags", Err: err}
+	})
+
 	// Global persistent flags
 	rootCmd.PersistentFlags().BoolVar(&appCfg.DryRun, "dry-run", appCfg.DryRun, "Preview changes without modifying the database.")
+	rootCmd.PersistentFlags().StringVar(&appCfg.ProtectRaw, "protect", "", "Comma-separated list of database name patterns (e.g. 'prod_*') that may only be run in dry-run mode unless --force is given.")
+	rootCmd.PersistentFlags().BoolVar(&appCfg.NoColor, "no-color", false, "Disable colored terminal output (also disabled automatically when stdout is not a terminal or NO_COLOR is set).")
+	rootCmd.PersistentFlags().BoolVar(&appCfg.Force, "force", false, "Allow applying changes to databases matching a --protect pattern.")
+	rootCmd.PersistentFlags().StringVar(&appCfg.OutputModeRaw, "output-mode", "", "Octal permissions of generated files such as the SQL file, reports and --collect-out snapshots (e.g. '0600'). Also applied to files that already exist. Defaults to 0644 for new files.")
 
 	// Database connection flags
-	rootCmd.PersistentFlags().StringVar(&appCfg.Database.Dialect, "dialect", "", fmt.Sprintf("Database dialect (%s) - MANDATORY", strings.Join([]string{"postgres", "mysql", "sqlserver", "cloudsqlpostgres", "cloudsqlmysql", "cloudsqlsqlserver"}, ", ")))
+	rootCmd.PersistentFlags().StringVar(&appCfg.Database.Dialect, "dialect", "", fmt.Sprintf("Database dialect (%s) - MANDATORY unless --cloudsql-autodetect is given", strings.Join([]string{"postgres", "mysql", "sqlserver", "cloudsqlpostgres", "cloudsqlmysql", "cloudsqlsqlserver", "bigquery", "spanner", "cockroach", "clickhouse", "sqlite"}, ", ")))
 	rootCmd.PersistentFlags().StringVar(&appCfg.Database.Host, "host", "", "Database host (for non-Cloud SQL connections).")
-	rootCmd.PersistentFlags().IntVar(&appCfg.Database.Port, "port", 0, "Database port (for non-Cloud SQL connections).")
+	rootCmd.PersistentFlags().IntVar(&appCfg.Database.Port, "port", 0, "Database port (for non-Cloud SQL connections). Defaults to 5432 (postgres), 3306 (mysql), 1433 (sqlserver), 26257 (cockroach) or 9000 (clickhouse).")
+	rootCmd.PersistentFlags().StringVar(&appCfg.Database.ReadHost, "read-host", "", "Read replica to collect metadata from; generated statements are still applied to --host (standard connections only).")
+	rootCmd.PersistentFlags().IntVar(&appCfg.Database.ReadPort, "read-port", 0, "Port of the --read-host replica. Defaults to --port.")
+	rootCmd.PersistentFlags().StringVar(&appCfg.Database.Socket, "socket", "", "Unix socket to connect through instead of --host/--port (postgres: socket directory, mysql: socket file).")
 	rootCmd.PersistentFlags().StringVar(&appCfg.Database.User, "username", "", "Database username.")
 	rootCmd.PersistentFlags().StringVar(&appCfg.Database.Password, "password", "", "Database password.")
-	rootCmd.PersistentFlags().StringVar(&appCfg.Database.DBName, "database", "", "Database name.")
+	rootCmd.PersistentFlags().StringVar(&appCfg.Database.DBName, "database", "", "Database name (the dataset for bigquery, the database file path for sqlite).")
+	rootCmd.PersistentFlags().StringVar(&appCfg.Database.Schema, "schema", "", "Schema to list tables from and write comments to instead of the connection's default schema (postgres, cloudsqlpostgres and cockroach) or of every schema, with dbo tables unprefixed (sqlserver, cloudsqlsqlserver). Generated statements name the schema.")
+	rootCmd.PersistentFlags().StringVar(&appCfg.Database.Project, "project", "", "Google Cloud project containing the dataset or instance (required for bigquery and spanner).")
+	rootCmd.PersistentFlags().StringVar(&appCfg.Database.SpannerInstance, "spanner-instance", "", "Spanner instance ID (required for spanner).")
 	rootCmd.PersistentFlags().StringVar(&appCfg.Database.CloudSQLInstanceConnectionName, "cloudsql-instance-connection-name", "", "Cloud SQL instance connection name (required for Cloud SQL).")
+	rootCmd.PersistentFlags().BoolVar(&appCfg.CloudSQLAutodetect, "cloudsql-autodetect", false, "Look up the engine of the --cloudsql-instance-connection-name instance with the Cloud SQL Admin API and pick cloudsqlpostgres, cloudsqlmysql or cloudsqlsqlserver, so --dialect can be left out. Uses Application Default Credentials.")
 	rootCmd.PersistentFlags().BoolVar(&appCfg.Database.UsePrivateIP, "cloudsql-use-private-ip", appCfg.Database.UsePrivateIP, "Use the private IP address for the Cloud SQL connection.")
+	rootCmd.PersistentFlags().StringVar(&appCfg.DriverParamsRaw, "driver-params", "", "Comma-separated key=value driver parameters merged into the connection string of standard postgres, mysql, sqlserver and cockroach connections (e.g. 'connect_timeout=5,application_name=enricher'). They override generated settings such as sslmode.")
 	rootCmd.PersistentFlags().StringVar(&appCfg.Database.UpdateExistingMode, "update_existing", appCfg.Database.UpdateExistingMode, "How to handle existing comments: 'overwrite' or 'append'.")
+	rootCmd.PersistentFlags().BoolVar(&appCfg.Database.IncludeTempTables, "include-temp-tables", false, "Also list temporary tables (postgres and cockroach tables in a pg_temp schema, sqlserver #temp tables), which are skipped by default.")
+	rootCmd.PersistentFlags().BoolVar(&appCfg.Database.LowercaseIdentifiers, "lowercase-identifiers", false, "Write lower-case table and column names unquoted in generated SQL (postgres and cockroach, which fold unquoted names to lower case). Names that need quoting to keep their case or that are reserved words stay quoted. By default every name is quoted, preserving its case.")
 
-	// Gemini API Key flag
-	rootCmd.PersistentFlags().StringVar(&appCfg.GeminiAPIKey, "gemini-api-key", "", "Gemini API key. Required for generating descriptions using additional context. Can also be set via the GEMINI_API_KEY environment variable.")
+	// LLM access flags
+	rootCmd.PersistentFlags().StringVar(&appCfg.GeminiAPIKey, "gemini-api-key", "", "Gemini API key. Required for generating descriptions using additional context with --llm-provider gemini. Can also be set via the GEMINI_API_KEY environment variable.")
+	rootCmd.PersistentFlags().StringVar(&appCfg.LLMProvider, "llm-provider", appCfg.LLMProvider, "Service to reach Gemini models through: 'gemini' (the Gemini API, with --gemini-api-key) or 'vertexai' (Vertex AI, with Application Default Credentials).")
+	rootCmd.PersistentFlags().StringVar(&appCfg.VertexProject, "vertex-project", "", "Google Cloud project to use Vertex AI in (required for --llm-provider vertexai). Can also be set via the GOOGLE_CLOUD_PROJECT environment variable.")
+	rootCmd.PersistentFlags().StringVar(&appCfg.VertexLocation, "vertex-location", appCfg.VertexLocation, "Google Cloud region to use Vertex AI in, e.g. 'us-central1' or 'europe-west4'.")
 
 	// Add subcommands
 	rootCmd.AddCommand(addCommentsCmd)
 	rootCmd.AddCommand(getCommentsCmd)
 	rootCmd.AddCommand(deleteCommentsCmd)
+	rootCmd.AddCommand(repairCommentsCmd)
+	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(applyCommentsCmd)
+	rootCmd.AddCommand(piiReportCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(listTablesCmd)
 }
 
 // GetAppConfig returns the application configuration.
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // DatabaseConfig holds database connection configuration
@@ -17,7 +22,47 @@ type DatabaseConfig struct {
 	SSLMode                        string
 	CloudSQLInstanceConnectionName string
 	UsePrivateIP                   bool
+	Socket                         string
+	Project                        string
+	SpannerInstance                string
 	UpdateExistingMode             string
+	ExampleSampleSize              int
+	CascadePartitions              bool
+	CommentEncoding                string            // Charset generated comment text is converted to; empty means UTF-8.
+	EmbedSource                    string            // Environment marker written into generated metadata, e.g. "prod".
+	LowercaseIdentifiers           bool              // Leave identifiers unquoted where the dialect's case folding keeps them unchanged.
+	DriverParams                   map[string]string // Extra driver parameters merged into the standard connection string.
+	MaxCommentParts                int               // Keep only this many highest-priority parts in column comments; 0 keeps all.
+	EmbedProvenance                bool              // Record which comment parts were inferred and which were computed from the database.
+	EmbedTimestamp                 bool              // Record when each column's statistics were queried.
+	DistinctCollation              string            // "binary" counts distinct values byte by byte; empty or "default" uses the column's collation.
+	CommentStyle                   string            // "sentence" renders generated metadata as prose; empty or "keyvalue" keeps the terse parts.
+	IncludeTempTables              bool              // List temporary tables, which are skipped by default.
+	CommentIncludeDataType         bool              // Add each column's data type to its comment, as if data_type were requested.
+	MaxDistinctForExamples         int64             // Sample examples without DISTINCT from columns with more distinct values; 0 always uses DISTINCT.
+	ReadHost                       string            // Read replica that metadata is collected from; statements are still applied through Host.
+	ReadPort                       int               // Port of ReadHost; 0 means Port.
+	Schema                         string            // Schema to enrich instead of the connection's default (current_schema() or dbo).
+	ComputeAverage                 bool              // Also query the average of numeric columns, for the average enrichment.
+}
+
+// DefaultPort returns the conventional port for a standard connection to the dialect,
+// or 0 if the dialect has none.
+func DefaultPort(dialect string) int {
+	switch dialect {
+	case "postgres":
+		return 5432
+	case "mysql":
+		return 3306
+	case "sqlserver":
+		return 1433
+	case "cockroach":
+		return 26257
+	case "clickhouse":
+		return 9000
+	default:
+		return 0
+	}
 }
 
 // Validate checks the database configuration for required fields based on dialect.
@@ -32,6 +77,11 @@ func (dbc *DatabaseConfig) Validate() error {
 		"cloudsqlmysql":     true,
 		"sqlserver":         true,
 		"cloudsqlsqlserver": true,
+		"bigquery":          true,
+		"spanner":           true,
+		"cockroach":         true,
+		"clickhouse":        true,
+		"sqlite":            true,
 	}
 	if !supportedDialects[dbc.Dialect] {
 		return fmt.Errorf("unsupported dialect: %s", dbc.Dialect)
@@ -40,7 +90,31 @@ func (dbc *DatabaseConfig) Validate() error {
 	// Validate connection details based on dialect type
 	isCloudSQL := strings.HasPrefix(dbc.Dialect, "cloudsql")
 
-	if isCloudSQL {
+	if dbc.Dialect == "bigquery" {
+		// BigQuery authenticates with Application Default Credentials; --database names the dataset.
+		if dbc.Project == "" {
+			return fmt.Errorf("Google Cloud project is required (--project) for dialect %s", dbc.Dialect)
+		}
+		if dbc.DBName == "" {
+			return fmt.Errorf("BigQuery dataset is required (--database)")
+		}
+	} else if dbc.Dialect == "spanner" {
+		// Spanner also uses Application Default Credentials.
+		if dbc.Project == "" {
+			return fmt.Errorf("Google Cloud project is required (--project) for dialect %s", dbc.Dialect)
+		}
+		if dbc.SpannerInstance == "" {
+			return fmt.Errorf("Spanner instance is required (--spanner-instance)")
+		}
+		if dbc.DBName == "" {
+			return fmt.Errorf("database name is required (--database)")
+		}
+	} else if dbc.Dialect == "sqlite" {
+		// SQLite reads a local file named by --database; there is no server or login.
+		if dbc.DBName == "" {
+			return fmt.Errorf("SQLite database file is required (--database)")
+		}
+	} else if isCloudSQL {
 		if dbc.CloudSQLInstanceConnectionName == "" {
 			return fmt.Errorf("Cloud SQL instance connection name is required (--cloudsql-instance-connection-name) for dialect %s", dbc.Dialect)
 		}
@@ -54,12 +128,18 @@ func (dbc *DatabaseConfig) Validate() error {
 			return fmt.Errorf("database name is required (--database)")
 		}
 	} else {
-		// Standard connection
-		if dbc.Host == "" {
-			return fmt.Errorf("database host is required (--host) for dialect %s", dbc.Dialect)
-		}
-		if dbc.Port == 0 {
-			return fmt.Errorf("database port is required (--port) for dialect %s", dbc.Dialect)
+		// Standard connection; a Unix socket replaces host and port.
+		if dbc.Socket != "" {
+			if dbc.Dialect != "postgres" && dbc.Dialect != "mysql" {
+				return fmt.Errorf("--socket is only supported for postgres and mysql, not %s", dbc.Dialect)
+			}
+		} else {
+			if dbc.Host == "" {
+				return fmt.Errorf("database host is required (--host or --socket) for dialect %s", dbc.Dialect)
+			}
+			if dbc.Port == 0 {
+				dbc.Port = DefaultPort(dbc.Dialect)
+			}
 		}
 		if dbc.User == "" {
 			return fmt.Errorf("database username is required (--username)")
@@ -72,6 +152,46 @@ func (dbc *DatabaseConfig) Validate() error {
 		}
 	}
 
+	schemaDialects := map[string]bool{"postgres": true, "cloudsqlpostgres": true, "cockroach": true, "sqlserver": true, "cloudsqlsqlserver": true}
+	if dbc.Schema != "" && !schemaDialects[dbc.Dialect] {
+		return fmt.Errorf("--schema is only supported for postgres, cloudsqlpostgres, cockroach, sqlserver and cloudsqlsqlserver, not %s", dbc.Dialect)
+	}
+
+	if dbc.ReadHost != "" {
+		if isCloudSQL || dbc.Dialect == "bigquery" || dbc.Dialect == "spanner" || dbc.Dialect == "sqlite" {
+			return fmt.Errorf("--read-host is only supported for standard host connections, not %s", dbc.Dialect)
+		}
+		if dbc.Socket != "" {
+			return fmt.Errorf("--read-host cannot be combined with --socket")
+		}
+		if dbc.ReadPort == 0 {
+			dbc.ReadPort = dbc.Port
+		}
+	} else if dbc.ReadPort != 0 {
+		return fmt.Errorf("--read-port requires --read-host")
+	}
+
+	if dbc.CascadePartitions && dbc.Dialect != "postgres" && dbc.Dialect != "cloudsqlpostgres" {
+		return fmt.Errorf("--cascade-partitions is only supported for postgres and cloudsqlpostgres, not %s", dbc.Dialect)
+	}
+
+	if dbc.ExampleSampleSize < 0 {
+		return fmt.Errorf("invalid value for --example-sample-size: %d. Must not be negative", dbc.ExampleSampleSize)
+	}
+	if dbc.MaxDistinctForExamples < 0 {
+		return fmt.Errorf("invalid value for --max-distinct-for-examples: %d. Must not be negative", dbc.MaxDistinctForExamples)
+	}
+
+	dbc.DistinctCollation = strings.ToLower(dbc.DistinctCollation)
+	if dbc.DistinctCollation != "" && dbc.DistinctCollation != "default" && dbc.DistinctCollation != "binary" {
+		return fmt.Errorf("invalid value for --distinct-collation: '%s'. Must be 'binary' or 'default'", dbc.DistinctCollation)
+	}
+
+	dbc.CommentStyle = strings.ToLower(dbc.CommentStyle)
+	if dbc.CommentStyle != "" && dbc.CommentStyle != "keyvalue" && dbc.CommentStyle != "sentence" {
+		return fmt.Errorf("invalid value for --comment-style: '%s'. Must be 'keyvalue' or 'sentence'", dbc.CommentStyle)
+	}
+
 	// Validate update_existing mode
 	dbc.UpdateExistingMode = strings.ToLower(dbc.UpdateExistingMode)
 	if dbc.UpdateExistingMode != "overwrite" && dbc.UpdateExistingMode != "append" {
@@ -81,47 +201,280 @@ func (dbc *DatabaseConfig) Validate() error {
 	return nil
 }
 
+// ReadReplica returns the configuration for connecting to the ReadHost replica, or
+// the configuration itself when there is no replica.
+func (dbc DatabaseConfig) ReadReplica() DatabaseConfig {
+	if dbc.ReadHost == "" {
+		return dbc
+	}
+	replica := dbc
+	replica.Host = dbc.ReadHost
+	replica.Port = dbc.ReadPort
+	if replica.Port == 0 {
+		replica.Port = dbc.Port
+	}
+	return replica
+}
+
 // AppConfig holds all configuration for the application, populated from flags/env vars.
 type AppConfig struct {
-	Database        DatabaseConfig
-	GeminiAPIKey    string
-	DryRun          bool
-	OutputFile      string
-	InputFile       string
-	TablesRaw       string
-	EnrichmentsRaw  string
-	ContextFilesRaw string
-	Model           string
-	MaskPII         bool
+	Database                    DatabaseConfig
+	CompareSource               DatabaseConfig // Source database of the compare command (--src-* flags).
+	CompareTarget               DatabaseConfig // Target database of the compare command (--dst-* flags).
+	GeminiAPIKey                string
+	LLMProvider                 string
+	VertexProject               string
+	VertexLocation              string
+	DryRun                      bool
+	OutputFile                  string
+	InputFile                   string
+	TablesRaw                   string
+	Interactive                 bool
+	EnrichmentsRaw              string
+	ExcludeEnrichmentsRaw       string
+	ContextFilesRaw             string
+	ContextDir                  string
+	GlossaryFile                string
+	Model                       string
+	MaskPII                     bool
+	SkipEmptyTables             bool
+	ProtectRaw                  string
+	Force                       bool
+	BatchDescriptions           bool
+	DedupeDescriptions          bool
+	NoColor                     bool
+	ReportFormat                string
+	ShowDiff                    bool
+	CollectOut                  string
+	StripStats                  bool
+	PrintPrompts                string
+	DryLLM                      bool
+	StructuredOutput            bool
+	DescriptionLanguage         string
+	MaxDescriptionLength        int
+	FallbackModel               string
+	LLMRPS                      float64
+	LLMMaxRetries               int
+	LLMRetryBackoff             time.Duration
+	SlowQueryWarn               time.Duration
+	DriverParamsRaw             string
+	PreserveExistingDescription bool
+	ColumnCounts                bool
+	StatsCacheFile              string
+	DeleteScope                 string
+	MaxConcurrency              int
+	StreamComments              bool
+	SkipDescriptionPatternsRaw  string
+	HumanizeNames               bool
+	StrictDescriptions          bool
+	CloudSQLAutodetect          bool
+	SanitizeContext             bool
+	DescribeColumnRelationships bool
+	OutputModeRaw               string
+	ExplainFile                 string
 }
 
 // NewAppConfig creates an AppConfig with default values.
 func NewAppConfig() *AppConfig {
 	return &AppConfig{
 		// Default values set here. They will be overridden by flags.
-		DryRun:  true,
-		MaskPII: true,
+		DryRun:       true,
+		MaskPII:      true,
+		ReportFormat: "text",
 		Database: DatabaseConfig{
 			SSLMode:            "disable",
 			UpdateExistingMode: "overwrite",
 		},
-		Model: "gemini-1.5-pro-002",
+		CompareSource:  DatabaseConfig{SSLMode: "disable"},
+		CompareTarget:  DatabaseConfig{SSLMode: "disable"},
+		Model:          "gemini-1.5-pro-002",
+		LLMProvider:    "gemini",
+		VertexLocation: "us-central1",
 	}
 }
 
-// LoadAndValidate populates the Gemini API key from environment if not set via flag,
-// and then validates the entire configuration.
+// LoadAndValidate populates the Gemini API key and Vertex AI project from environment
+// if not set via flag, and then validates the entire configuration.
 func (cfg *AppConfig) LoadAndValidate() error {
 	if cfg.GeminiAPIKey == "" {
 		cfg.GeminiAPIKey = os.Getenv("GEMINI_API_KEY")
 	}
+	if cfg.VertexProject == "" {
+		cfg.VertexProject = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if err := cfg.validateLLMProvider(); err != nil {
+		return err
+	}
 	// Validate Database config first
 	if err := cfg.Database.Validate(); err != nil {
 		return fmt.Errorf("database configuration error: %w", err)
 	}
+	if err := cfg.checkProtection(); err != nil {
+		return err
+	}
+	if cfg.TablesRaw == "-" {
+		if err := cfg.readTablesFrom(os.Stdin); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(cfg.DriverParamsRaw) != "" {
+		if !driverParamsDialects[cfg.Database.Dialect] {
+			return fmt.Errorf("--driver-params is only supported for standard postgres, mysql, sqlserver and cockroach connections, not %s", cfg.Database.Dialect)
+		}
+		params, err := ParseDriverParams(cfg.DriverParamsRaw)
+		if err != nil {
+			return err
+		}
+		cfg.Database.DriverParams = params
+	}
+	if _, err := cfg.OutputFileMode(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateLLMProvider checks --llm-provider and, for Vertex AI, that a project is known.
+// An empty provider means the Gemini API.
+func (cfg *AppConfig) validateLLMProvider() error {
+	cfg.LLMProvider = strings.ToLower(cfg.LLMProvider)
+	switch cfg.LLMProvider {
+	case "", "gemini":
+		return nil
+	case "vertexai":
+		if cfg.VertexProject == "" {
+			return fmt.Errorf("--llm-provider vertexai requires --vertex-project or the GOOGLE_CLOUD_PROJECT environment variable")
+		}
+		if cfg.VertexLocation == "" {
+			return fmt.Errorf("--llm-provider vertexai requires --vertex-location")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid value for --llm-provider: '%s'. Must be 'gemini' or 'vertexai'", cfg.LLMProvider)
+	}
+}
+
+// UsesVertexAI reports whether the LLM is reached through Vertex AI, which
+// authenticates with application default credentials instead of an API key.
+func (cfg *AppConfig) UsesVertexAI() bool {
+	return cfg.LLMProvider == "vertexai"
+}
+
+// readTablesFrom replaces a --tables value of "-" with the filter list read from in,
+// so that the tables of interest can be piped in from a query. Entries may be given
+// one per line or comma-separated; blank lines and lines starting with '#' are
+// ignored. Since stdin is used up, the apply confirmation cannot be answered and
+// --dry-run=false is rejected.
+func (cfg *AppConfig) readTablesFrom(in io.Reader) error {
+	if !cfg.DryRun {
+		return fmt.Errorf("--tables - reads the filter list from stdin, which is then not available to confirm applying; run with --dry-run and apply the generated file with apply-comments")
+	}
+	var entries []string
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, strings.TrimSuffix(line, ","))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read --tables from stdin: %w", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("--tables - read no tables from stdin")
+	}
+	cfg.TablesRaw = strings.Join(entries, ",")
 	return nil
 }
 
+// driverParamsDialects are the dialects whose standard connection strings take --driver-params.
+var driverParamsDialects = map[string]bool{"postgres": true, "mysql": true, "sqlserver": true, "cockroach": true}
+
+// ParseDriverParams parses a --driver-params value of comma-separated key=value pairs,
+// e.g. "connect_timeout=5,application_name=enricher". Keys and values are trimmed; a
+// value may be empty but a key may not.
+func ParseDriverParams(raw string) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --driver-params entry '%s': expected key=value", strings.TrimSpace(pair))
+		}
+		params[key] = strings.TrimSpace(value)
+	}
+	return params, nil
+}
+
+// ProtectedPattern returns the first --protect pattern matching the configured
+// database name, or an empty string if the database is not protected.
+func (cfg *AppConfig) ProtectedPattern() (string, error) {
+	for _, pattern := range strings.Split(cfg.ProtectRaw, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		matched, err := path.Match(pattern, cfg.Database.DBName)
+		if err != nil {
+			return "", fmt.Errorf("invalid --protect pattern '%s': %w", pattern, err)
+		}
+		if matched {
+			return pattern, nil
+		}
+	}
+	return "", nil
+}
+
+// SkipDescriptionPatterns returns the --skip-description-patterns globs, lower-cased
+// so that column names can be matched regardless of case.
+func (cfg *AppConfig) SkipDescriptionPatterns() ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(cfg.SkipDescriptionPatternsRaw, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid --skip-description-patterns pattern '%s': %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// DefaultOutputFileMode is the permission of generated files when --output-mode is not given.
+const DefaultOutputFileMode os.FileMode = 0644
+
+// OutputFileMode returns the permissions for generated files given by --output-mode
+// as an octal string such as 0600, or DefaultOutputFileMode when it is not set.
+func (cfg *AppConfig) OutputFileMode() (os.FileMode, error) {
+	raw := strings.TrimSpace(cfg.OutputModeRaw)
+	if raw == "" {
+		return DefaultOutputFileMode, nil
+	}
+	mode, err := strconv.ParseUint(raw, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid --output-mode '%s': expected octal permissions such as 0600", raw)
+	}
+	return os.FileMode(mode), nil
+}
+
+// checkProtection refuses to run with --dry-run=false against a protected
+// database unless --force is also given.
+func (cfg *AppConfig) checkProtection() error {
+	pattern, err := cfg.ProtectedPattern()
+	if err != nil {
+		return err
+	}
+	if pattern == "" || cfg.Force || cfg.DryRun {
+		return nil
+	}
+	return fmt.Errorf("database '%s' matches protected pattern '%s': refusing to run with --dry-run=false without --force", cfg.Database.DBName, pattern)
+}
+
 // GetDefaultOutputFile returns the default output file path based on DB name and command.
 func (cfg *AppConfig) GetDefaultOutputFile(commandName string) string {
 	dbName := "output"
@@ -132,6 +485,8 @@ func (cfg *AppConfig) GetDefaultOutputFile(commandName string) string {
 	switch commandName {
 	case "get-comments":
 		return fmt.Sprintf("%s_comments.txt", dbName)
+	case "pii-report":
+		return fmt.Sprintf("%s_pii_report.txt", dbName)
 	default:
 		return fmt.Sprintf("%s_comments.sql", dbName)
 	}
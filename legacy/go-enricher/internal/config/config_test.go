@@ -0,0 +1,605 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func newTestAppConfig(dbName string) *AppConfig {
+	cfg := NewAppConfig()
+	cfg.Database.Dialect = "postgres"
+	cfg.Database.Host = "localhost"
+	cfg.Database.Port = 5432
+	cfg.Database.User = "user"
+	cfg.Database.Password = "pass"
+	cfg.Database.DBName = dbName
+	return cfg
+}
+
+func TestLoadAndValidateProtectedDatabase(t *testing.T) {
+	tests := []struct {
+		name        string
+		dbName      string
+		protect     string
+		dryRun      bool
+		force       bool
+		expectedErr string
+	}{
+		{"protected with dry-run", "prod_sales", "prod_*", true, false, ""},
+		{"protected without dry-run", "prod_sales", "prod_*", false, false, "refusing to run with --dry-run=false without --force"},
+		{"protected without dry-run but forced", "prod_sales", "staging,prod_*", false, true, ""},
+		{"not protected", "dev_sales", "prod_*", false, false, ""},
+		{"invalid pattern", "prod_sales", "prod_[", true, false, "invalid --protect pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestAppConfig(tt.dbName)
+			cfg.ProtectRaw = tt.protect
+			cfg.DryRun = tt.dryRun
+			cfg.Force = tt.force
+
+			err := cfg.LoadAndValidate()
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("LoadAndValidate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("LoadAndValidate() error = %v, want error containing %q", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestProtectedPattern(t *testing.T) {
+	cfg := newTestAppConfig("prod_sales")
+	cfg.ProtectRaw = " staging_* , prod_* "
+
+	pattern, err := cfg.ProtectedPattern()
+	if err != nil {
+		t.Fatalf("ProtectedPattern() unexpected error: %v", err)
+	}
+	if pattern != "prod_*" {
+		t.Errorf("ProtectedPattern() = %q, want %q", pattern, "prod_*")
+	}
+
+	cfg.ProtectRaw = ""
+	if pattern, _ := cfg.ProtectedPattern(); pattern != "" {
+		t.Errorf("ProtectedPattern() with no patterns = %q, want empty", pattern)
+	}
+}
+
+func TestSkipDescriptionPatterns(t *testing.T) {
+	cfg := newTestAppConfig("sales")
+	cfg.SkipDescriptionPatternsRaw = " IS_* , *_id ,"
+
+	patterns, err := cfg.SkipDescriptionPatterns()
+	if err != nil {
+		t.Fatalf("SkipDescriptionPatterns() unexpected error: %v", err)
+	}
+	if got := strings.Join(patterns, ","); got != "is_*,*_id" {
+		t.Errorf("SkipDescriptionPatterns() = %q, want %q", got, "is_*,*_id")
+	}
+
+	cfg.SkipDescriptionPatternsRaw = "[id"
+	if _, err := cfg.SkipDescriptionPatterns(); err == nil || !strings.Contains(err.Error(), "--skip-description-patterns") {
+		t.Errorf("SkipDescriptionPatterns() with a malformed pattern error = %v, want --skip-description-patterns error", err)
+	}
+}
+
+func TestOutputFileMode(t *testing.T) {
+	tests := []struct {
+		raw         string
+		want        os.FileMode
+		expectedErr string
+	}{
+		{"", 0644, ""},
+		{"0600", 0600, ""},
+		{" 640 ", 0640, ""},
+		{"0o600", 0, "invalid --output-mode"},
+		{"0800", 0, "invalid --output-mode"},
+		{"01777", 0, "invalid --output-mode"},
+		{"rw-------", 0, "invalid --output-mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			cfg := newTestAppConfig("sales")
+			cfg.OutputModeRaw = tt.raw
+			got, err := cfg.OutputFileMode()
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("OutputFileMode() error = %v, want error containing %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OutputFileMode() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("OutputFileMode() = %o, want %o", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSocket(t *testing.T) {
+	tests := []struct {
+		name        string
+		dialect     string
+		socket      string
+		host        string
+		expectedErr string
+	}{
+		{"postgres socket without host", "postgres", "/var/run/postgresql", "", ""},
+		{"mysql socket without host", "mysql", "/var/run/mysqld/mysqld.sock", "", ""},
+		{"sqlserver socket rejected", "sqlserver", "/tmp/sql.sock", "", "--socket is only supported"},
+		{"no socket and no host", "postgres", "", "", "database host is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbc := DatabaseConfig{
+				Dialect:            tt.dialect,
+				Host:               tt.host,
+				Socket:             tt.socket,
+				User:               "user",
+				Password:           "pass",
+				DBName:             "db",
+				UpdateExistingMode: "overwrite",
+			}
+			err := dbc.Validate()
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidateDefaultPort(t *testing.T) {
+	tests := []struct {
+		dialect      string
+		port         int
+		expectedPort int
+	}{
+		{"postgres", 0, 5432},
+		{"mysql", 0, 3306},
+		{"sqlserver", 0, 1433},
+		{"cockroach", 0, 26257},
+		{"clickhouse", 0, 9000},
+		{"postgres", 6543, 6543},
+		{"cloudsqlpostgres", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s_%d", tt.dialect, tt.port), func(t *testing.T) {
+			dbc := DatabaseConfig{
+				Dialect:                        tt.dialect,
+				Host:                           "localhost",
+				Port:                           tt.port,
+				User:                           "user",
+				Password:                       "pass",
+				DBName:                         "db",
+				CloudSQLInstanceConnectionName: "project:region:instance",
+				UpdateExistingMode:             "overwrite",
+			}
+			if err := dbc.Validate(); err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+			if dbc.Port != tt.expectedPort {
+				t.Errorf("Port = %d, want %d", dbc.Port, tt.expectedPort)
+			}
+		})
+	}
+}
+
+func TestValidateReadHost(t *testing.T) {
+	tests := []struct {
+		name             string
+		dialect          string
+		socket           string
+		readHost         string
+		readPort         int
+		expectedReadPort int
+		expectedErr      string
+	}{
+		{"replica on the primary's port", "postgres", "", "replica", 0, 5432, ""},
+		{"replica on its own port", "mysql", "", "replica", 3307, 3307, ""},
+		{"cloud sql rejected", "cloudsqlpostgres", "", "replica", 0, 0, "--read-host is only supported for standard host connections"},
+		{"socket rejected", "postgres", "/var/run/postgresql", "replica", 0, 0, "--read-host cannot be combined with --socket"},
+		{"read port without read host", "postgres", "", "", 5433, 0, "--read-port requires --read-host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbc := DatabaseConfig{
+				Dialect:                        tt.dialect,
+				Host:                           "primary",
+				Port:                           DefaultPort(tt.dialect),
+				Socket:                         tt.socket,
+				ReadHost:                       tt.readHost,
+				ReadPort:                       tt.readPort,
+				User:                           "user",
+				Password:                       "pass",
+				DBName:                         "db",
+				CloudSQLInstanceConnectionName: "project:region:instance",
+				UpdateExistingMode:             "overwrite",
+			}
+			err := dbc.Validate()
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("Validate() error = %v, want error containing %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+			replica := dbc.ReadReplica()
+			if replica.Host != "replica" || replica.Port != tt.expectedReadPort {
+				t.Errorf("ReadReplica() connects to %s:%d, want replica:%d", replica.Host, replica.Port, tt.expectedReadPort)
+			}
+		})
+	}
+}
+
+func TestValidateBigQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		project     string
+		dataset     string
+		expectedErr string
+	}{
+		{"project and dataset", "my-project", "sales", ""},
+		{"missing project", "", "sales", "--project"},
+		{"missing dataset", "my-project", "", "BigQuery dataset is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbc := DatabaseConfig{
+				Dialect:            "bigquery",
+				Project:            tt.project,
+				DBName:             tt.dataset,
+				UpdateExistingMode: "overwrite",
+			}
+			err := dbc.Validate()
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidateSpanner(t *testing.T) {
+	tests := []struct {
+		name        string
+		project     string
+		instance    string
+		expectedErr string
+	}{
+		{"project and instance", "my-project", "my-instance", ""},
+		{"missing project", "", "my-instance", "--project"},
+		{"missing instance", "my-project", "", "--spanner-instance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbc := DatabaseConfig{
+				Dialect:            "spanner",
+				Project:            tt.project,
+				SpannerInstance:    tt.instance,
+				DBName:             "sales",
+				UpdateExistingMode: "overwrite",
+			}
+			err := dbc.Validate()
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidateSQLite(t *testing.T) {
+	tests := []struct {
+		name        string
+		dbName      string
+		readHost    string
+		expectedErr string
+	}{
+		{"file path only", "data/app.db", "", ""},
+		{"missing file", "", "", "--database"},
+		{"read replica", "data/app.db", "replica", "--read-host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbc := DatabaseConfig{
+				Dialect:            "sqlite",
+				DBName:             tt.dbName,
+				ReadHost:           tt.readHost,
+				UpdateExistingMode: "overwrite",
+			}
+			err := dbc.Validate()
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		dialect     string
+		expectedErr string
+	}{
+		{"postgres", ""},
+		{"cockroach", ""},
+		{"sqlserver", ""},
+		{"mysql", "--schema is only supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			dbc := DatabaseConfig{
+				Dialect:            tt.dialect,
+				Host:               "localhost",
+				User:               "user",
+				Password:           "pass",
+				DBName:             "db",
+				Schema:             "sales",
+				UpdateExistingMode: "overwrite",
+			}
+			err := dbc.Validate()
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidateCascadePartitions(t *testing.T) {
+	for _, dialect := range []string{"postgres", "mysql"} {
+		dbc := DatabaseConfig{
+			Dialect:            dialect,
+			Host:               "localhost",
+			User:               "user",
+			Password:           "pass",
+			DBName:             "db",
+			UpdateExistingMode: "overwrite",
+			CascadePartitions:  true,
+		}
+		err := dbc.Validate()
+		if dialect == "postgres" && err != nil {
+			t.Errorf("Validate() for postgres unexpected error: %v", err)
+		}
+		if dialect == "mysql" && (err == nil || !strings.Contains(err.Error(), "--cascade-partitions")) {
+			t.Errorf("Validate() for mysql error = %v, want --cascade-partitions error", err)
+		}
+	}
+}
+
+func TestValidateDistinctCollation(t *testing.T) {
+	for _, tt := range []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "default", want: "default"},
+		{value: "BINARY", want: "binary"},
+		{value: "nocase", wantErr: true},
+	} {
+		dbc := DatabaseConfig{
+			Dialect:            "postgres",
+			Host:               "localhost",
+			User:               "user",
+			Password:           "pass",
+			DBName:             "db",
+			UpdateExistingMode: "overwrite",
+			DistinctCollation:  tt.value,
+		}
+		err := dbc.Validate()
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "--distinct-collation") {
+				t.Errorf("Validate() with %q error = %v, want --distinct-collation error", tt.value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Validate() with %q unexpected error: %v", tt.value, err)
+		}
+		if dbc.DistinctCollation != tt.want {
+			t.Errorf("Validate() normalized %q to %q, want %q", tt.value, dbc.DistinctCollation, tt.want)
+		}
+	}
+}
+
+func TestValidateCommentStyle(t *testing.T) {
+	for _, tt := range []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "keyvalue", want: "keyvalue"},
+		{value: "Sentence", want: "sentence"},
+		{value: "prose", wantErr: true},
+	} {
+		dbc := DatabaseConfig{
+			Dialect:            "postgres",
+			Host:               "localhost",
+			User:               "user",
+			Password:           "pass",
+			DBName:             "db",
+			UpdateExistingMode: "overwrite",
+			CommentStyle:       tt.value,
+		}
+		err := dbc.Validate()
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "--comment-style") {
+				t.Errorf("Validate() with %q error = %v, want --comment-style error", tt.value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Validate() with %q unexpected error: %v", tt.value, err)
+		}
+		if dbc.CommentStyle != tt.want {
+			t.Errorf("Validate() normalized %q to %q, want %q", tt.value, dbc.CommentStyle, tt.want)
+		}
+	}
+}
+
+func TestLoadAndValidateDriverParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		dialect     string
+		raw         string
+		want        map[string]string
+		expectedErr string
+	}{
+		{"key value pairs", "postgres", "connect_timeout=5, application_name = enricher", map[string]string{"connect_timeout": "5", "application_name": "enricher"}, ""},
+		{"value with equals sign", "mysql", "sql_mode=a=b,", map[string]string{"sql_mode": "a=b"}, ""},
+		{"key with space", "sqlserver", "app name=enricher", map[string]string{"app name": "enricher"}, ""},
+		{"missing value separator", "postgres", "connect_timeout", nil, "expected key=value"},
+		{"empty key", "postgres", "=5", nil, "expected key=value"},
+		{"unsupported dialect", "cloudsqlpostgres", "connect_timeout=5", nil, "only supported for standard"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestAppConfig("sales")
+			cfg.Database.Dialect = tt.dialect
+			cfg.Database.CloudSQLInstanceConnectionName = "project:region:instance"
+			cfg.DriverParamsRaw = tt.raw
+
+			err := cfg.LoadAndValidate()
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("LoadAndValidate() error = %v, want error containing %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAndValidate() unexpected error: %v", err)
+			}
+			if fmt.Sprint(cfg.Database.DriverParams) != fmt.Sprint(tt.want) {
+				t.Errorf("DriverParams = %v, want %v", cfg.Database.DriverParams, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAndValidateLLMProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    string
+		project     string
+		envProject  string
+		location    string
+		wantProject string
+		expectedErr string
+	}{
+		{"gemini", "gemini", "", "", "us-central1", "", ""},
+		{"vertexai", "VertexAI", "my-project", "", "europe-west4", "my-project", ""},
+		{"vertexai project from environment", "vertexai", "", "env-project", "us-central1", "env-project", ""},
+		{"vertexai without project", "vertexai", "", "", "us-central1", "", "requires --vertex-project"},
+		{"vertexai without location", "vertexai", "my-project", "", "", "", "requires --vertex-location"},
+		{"unsupported provider", "openai", "", "", "us-central1", "", "invalid value for --llm-provider"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOOGLE_CLOUD_PROJECT", tt.envProject)
+			cfg := newTestAppConfig("sales")
+			cfg.LLMProvider = tt.provider
+			cfg.VertexProject = tt.project
+			cfg.VertexLocation = tt.location
+
+			err := cfg.LoadAndValidate()
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("LoadAndValidate() error = %v, want error containing %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAndValidate() unexpected error: %v", err)
+			}
+			if cfg.VertexProject != tt.wantProject {
+				t.Errorf("VertexProject = %q, want %q", cfg.VertexProject, tt.wantProject)
+			}
+		})
+	}
+}
+
+func TestReadTablesFromStdin(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		dryRun      bool
+		want        string
+		expectedErr string
+	}{
+		{"one entry per line", "orders[id,status]\n\n# from psql\ncustomers,\npublic.items\n", true, "orders[id,status],customers,public.items", ""},
+		{"comma-separated line", "orders, customers", true, "orders, customers", ""},
+		{"empty input", "\n# nothing\n", true, "", "read no tables"},
+		{"apply mode", "orders\n", false, "", "not available to confirm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestAppConfig("sales")
+			cfg.DryRun = tt.dryRun
+			cfg.TablesRaw = "-"
+
+			err := cfg.readTablesFrom(strings.NewReader(tt.input))
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("readTablesFrom() error = %v, want error containing %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readTablesFrom() unexpected error: %v", err)
+			}
+			if cfg.TablesRaw != tt.want {
+				t.Errorf("TablesRaw = %q, want %q", cfg.TablesRaw, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"strings"
+)
+
+// ColumnAverage runs query, which selects a column's average as a float, for the
+// average enrichment. It returns nil when the query fails, as it does for columns
+// that are not numeric, and when the column has no values to average.
+func ColumnAverage(ctx context.Context, db *DB, query string) *float64 {
+	var avg sql.NullFloat64
+	if err := db.Pool.QueryRowContext(ctx, query).Scan(&avg); err != nil {
+		return nil
+	}
+	if !avg.Valid || math.IsNaN(avg.Float64) || math.IsInf(avg.Float64, 0) {
+		return nil
+	}
+	return &avg.Float64
+}
+
+// numericTypes are the numeric type names AVG accepts, across the dialects: MySQL and
+// SQL Server, PostgreSQL and CockroachDB (including the internal int8/float8 names),
+// BigQuery and Spanner (INT64, FLOAT64) and ClickHouse (Int32, UInt64, Decimal64).
+var numericTypes = map[string]bool{
+	"tinyint": true, "smallint": true, "mediumint": true, "int": true, "integer": true, "bigint": true,
+	"decimal": true, "numeric": true, "dec": true, "fixed": true,
+	"float": true, "double": true, "real": true,
+	"money": true, "smallmoney": true,
+	"int2": true, "int4": true, "int8": true, "float4": true, "float8": true,
+	"int64": true, "float32": true, "float64": true, "bignumeric": true, "bigdecimal": true,
+	"int16": true, "int32": true, "int128": true, "int256": true,
+	"uint8": true, "uint16": true, "uint32": true, "uint64": true, "uint128": true, "uint256": true,
+	"decimal32": true, "decimal64": true, "decimal128": true, "decimal256": true,
+}
+
+// IsNumericType reports whether dataType, as read from the catalog, names a numeric
+// type. Precision, scale and modifiers such as "unsigned" are ignored, as are
+// ClickHouse's Nullable and LowCardinality wrappers, so "decimal(10,2)",
+// "int(11) unsigned", "double precision" and "Nullable(Int32)" are numeric.
+func IsNumericType(dataType string) bool {
+	name := strings.ToLower(strings.TrimSpace(dataType))
+	for _, wrapper := range []string{"nullable(", "lowcardinality("} {
+		name = strings.TrimPrefix(name, wrapper)
+	}
+	name, _, _ = strings.Cut(name, "(")
+	fields := strings.Fields(strings.TrimRight(name, ")"))
+	return len(fields) > 0 && numericTypes[fields[0]]
+}
@@ -0,0 +1,42 @@
+package database
+
+import "testing"
+
+func TestIsNumericType(t *testing.T) {
+	tests := map[string]bool{
+		"int":                true,
+		"int(11) unsigned":   true,
+		"DECIMAL(10,2)":      true,
+		"double precision":   true,
+		"money":              true,
+		"varchar(255)":       false,
+		"bit":                false,
+		"datetime":           false,
+		"enum('a','b')":      false,
+		"":                   false,
+		"interval":           false,
+		"bigint zerofill":    true,
+		"float unsigned":     true,
+		"tinytext":           false,
+		"smallmoney":         true,
+		"numeric":            true,
+		"year":               false,
+		"real":               true,
+		"mediumint unsigned": true,
+		"float8":             true,
+		"int8":               true,
+		"FLOAT64":            true,
+		"INT64":              true,
+		"STRING":             false,
+		"Int32":              true,
+		"UInt64":             true,
+		"Nullable(Float32)":  true,
+		"Decimal(18, 4)":     true,
+		"Nullable(DateTime)": false,
+	}
+	for dataType, want := range tests {
+		if got := IsNumericType(dataType); got != want {
+			t.Errorf("IsNumericType(%q) = %v, want %v", dataType, got, want)
+		}
+	}
+}
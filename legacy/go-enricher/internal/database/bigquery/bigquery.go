@@ -0,0 +1,461 @@
+package bigquery
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	bq "cloud.google.com/go/bigquery"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/sqladapter"
+)
+
+// bigqueryHandler treats a BigQuery dataset (--database) in a project (--project)
+// as the schema to enrich. Comments are stored in the description option of
+// tables and columns.
+type bigqueryHandler struct{}
+
+var _ database.DialectHandler = (*bigqueryHandler)(nil)
+
+func (h bigqueryHandler) CreateCloudSQLPool(cfg config.DatabaseConfig) (*sql.DB, error) {
+	return nil, fmt.Errorf("Cloud SQL connections are not supported for BigQuery")
+}
+
+// CreateStandardPool connects to BigQuery with Application Default Credentials.
+func (h bigqueryHandler) CreateStandardPool(cfg config.DatabaseConfig) (*sql.DB, error) {
+	client, err := bq.NewClient(context.Background(), cfg.Project)
+	if err != nil {
+		return nil, fmt.Errorf("bigquery.NewClient: %w", err)
+	}
+	return sqladapter.OpenDB("bigquery", &clientRunner{client: client, dataset: cfg.DBName}), nil
+}
+
+func (h bigqueryHandler) QuoteIdentifier(name string) string {
+	name = strings.ReplaceAll(name, `\`, `\\`)
+	name = strings.ReplaceAll(name, "`", "\\`")
+	return fmt.Sprintf("`%s`", name)
+}
+
+// qualifiedTable returns the dataset-qualified, quoted name of a table.
+func (h bigqueryHandler) qualifiedTable(db *database.DB, tableName string) string {
+	return fmt.Sprintf("%s.%s", h.QuoteIdentifier(db.Config.DBName), h.QuoteIdentifier(tableName))
+}
+
+// informationSchema returns the quoted name of a dataset-scoped INFORMATION_SCHEMA view.
+func (h bigqueryHandler) informationSchema(db *database.DB, view string) string {
+	return fmt.Sprintf("%s.INFORMATION_SCHEMA.%s", h.QuoteIdentifier(db.Config.DBName), view)
+}
+
+func (h bigqueryHandler) ListTables(db *database.DB) ([]string, error) {
+	query := fmt.Sprintf("SELECT table_name FROM %s WHERE table_type = 'BASE TABLE' ORDER BY table_name", h.informationSchema(db, "TABLES"))
+
+	rows, err := db.Pool.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var tableName sql.NullString
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, fmt.Errorf("error scanning table name: %w", err)
+		}
+		if !tableName.Valid {
+			log.Println("WARN: Skipping a table listed with a NULL name.")
+			continue
+		}
+		tables = append(tables, tableName.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating table rows: %w", err)
+	}
+	return tables, nil
+}
+
+func (h bigqueryHandler) ListColumns(db *database.DB, tableName string) ([]database.ColumnInfo, error) {
+	query := fmt.Sprintf(`
+		SELECT column_name, data_type
+		FROM %s
+		WHERE table_name = @p1
+		ORDER BY ordinal_position`, h.informationSchema(db, "COLUMNS"))
+
+	rows, err := db.Pool.Query(query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("error querying columns for table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var columns []database.ColumnInfo
+	for rows.Next() {
+		var name, dataType sql.NullString
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return nil, fmt.Errorf("error scanning column name and data type: %w", err)
+		}
+		if !name.Valid {
+			log.Printf("WARN: Skipping a column of %s listed with a NULL name.", tableName)
+			continue
+		}
+		columns = append(columns, database.ColumnInfo{Name: name.String, DataType: dataType.String})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column rows: %w", err)
+	}
+
+	return columns, nil
+}
+
+func (h bigqueryHandler) GetColumnMetadata(db *database.DB, tableName string, columnName string) (map[string]interface{}, error) {
+	quotedTable := h.qualifiedTable(db, tableName)
+	quotedColumn := h.QuoteIdentifier(columnName)
+	ctx := context.Background()
+
+	// Casting to STRING gives COUNT(DISTINCT) and ORDER BY a comparable type; columns that cannot be cast report -1.
+	distinctQuery := fmt.Sprintf("SELECT COUNT(DISTINCT CAST(%s AS STRING)) FROM %s", quotedColumn, quotedTable)
+	var distinctCount int64
+	err := db.Pool.QueryRowContext(ctx, distinctQuery).Scan(&distinctCount)
+	if err != nil {
+		log.Printf("WARN: Failed to get distinct count for %s.%s (may not be supported for this column type): %v. Reporting -1.", tableName, columnName, err)
+		distinctCount = -1
+	}
+
+	nullQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NULL", quotedTable, quotedColumn)
+	var nullCount int64
+	err = db.Pool.QueryRowContext(ctx, nullQuery).Scan(&nullCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get null count for %s.%s: %w", tableName, columnName, err)
+	}
+
+	exampleLimit := database.ExampleQueryLimit(db.Config.ExampleSampleSize)
+	exampleQuery := fmt.Sprintf("SELECT DISTINCT CAST(%s AS STRING) FROM %s WHERE %s IS NOT NULL ORDER BY 1 LIMIT %d",
+		quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	firstRows := database.SampleWithoutDistinct(db, distinctCount)
+	if firstRows {
+		exampleQuery = fmt.Sprintf("SELECT CAST(%s AS STRING) FROM %s WHERE %s IS NOT NULL LIMIT %d",
+			quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	}
+	rows, err := db.Pool.QueryContext(ctx, exampleQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get example values for %s.%s: %w", tableName, columnName, err)
+	}
+	defer rows.Close()
+
+	var examples []string
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("error scanning example value for %s.%s: %w", tableName, columnName, err)
+		}
+		if value.Valid {
+			examples = append(examples, value.String)
+		}
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating example values for %s.%s: %w", tableName, columnName, rows.Err())
+	}
+	if firstRows {
+		examples = database.UniqueValues(examples)
+	}
+	examples = database.SelectRepresentativeValues(examples, database.DefaultExampleCount)
+
+	metadata := map[string]interface{}{
+		"DistinctCount": distinctCount,
+		"NullCount":     nullCount,
+		"ExampleValues": examples,
+	}
+	// Only numeric columns are averaged, since the query is billed as a full scan.
+	if db.Config.ComputeAverage && database.IsNumericType(h.columnDataType(ctx, db, tableName, columnName)) {
+		avgQuery := fmt.Sprintf("SELECT CAST(AVG(%s) AS FLOAT64) FROM %s", quotedColumn, quotedTable)
+		if avg := database.ColumnAverage(ctx, db, avgQuery); avg != nil {
+			metadata["Average"] = *avg
+		}
+	}
+	return metadata, nil
+}
+
+// columnDataType returns the name of a column's type, or "" if it cannot be read.
+func (h bigqueryHandler) columnDataType(ctx context.Context, db *database.DB, tableName, columnName string) string {
+	query := fmt.Sprintf(`
+		SELECT data_type
+		FROM %s
+		WHERE table_name = @p1
+			AND column_name = @p2`, h.informationSchema(db, "COLUMNS"))
+	var dataType string
+	if err := db.Pool.QueryRowContext(ctx, query, tableName, columnName).Scan(&dataType); err != nil {
+		log.Printf("WARN: Failed to read the type of %s.%s: %v", tableName, columnName, err)
+		return ""
+	}
+	return dataType
+}
+
+// quoteBigQueryString returns value as a double-quoted GoogleSQL string literal.
+func quoteBigQueryString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return fmt.Sprintf(`"%s"`, value)
+}
+
+// formatExampleValues renders the examples as plain comment text. Values are not
+// escaped here: the whole comment is escaped once by quoteBigQueryString.
+func (h bigqueryHandler) formatExampleValues(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	cleaned := make([]string, len(values))
+	for i, v := range values {
+		trimmed := strings.ReplaceAll(v, "\n", " ")
+		if len(trimmed) > 100 {
+			trimmed = trimmed[:100] + "...[truncated]"
+		}
+		cleaned[i] = trimmed
+	}
+	return fmt.Sprintf("Examples: ['%s']", strings.Join(cleaned, "', '"))
+}
+
+// formatForeignKeys renders referenced columns with this dialect's identifier quoting.
+func (h bigqueryHandler) formatForeignKeys(foreignKeys []database.ForeignKeyReference) string {
+	return database.FormatForeignKeys(foreignKeys, h.QuoteIdentifier)
+}
+
+func (h bigqueryHandler) columnDescriptionSQL(db *database.DB, tableName, columnName, description string) string {
+	return fmt.Sprintf(
+		"ALTER TABLE %s ALTER COLUMN %s SET OPTIONS (description = %s);",
+		h.qualifiedTable(db, tableName),
+		h.QuoteIdentifier(columnName),
+		quoteBigQueryString(description),
+	)
+}
+
+func (h bigqueryHandler) tableDescriptionSQL(db *database.DB, tableName, description string) string {
+	return fmt.Sprintf(
+		"ALTER TABLE %s SET OPTIONS (description = %s);",
+		h.qualifiedTable(db, tableName),
+		quoteBigQueryString(description),
+	)
+}
+
+func (h bigqueryHandler) GenerateCommentSQL(db *database.DB, data *database.CommentData, enrichments map[string]bool) (string, error) {
+	if data == nil || data.TableName == "" || data.ColumnName == "" {
+		return "", fmt.Errorf("invalid input for GenerateCommentSQL")
+	}
+
+	formattedExamples := h.formatExampleValues(data.ExampleValues)
+	newMetadataComment := database.GenerateMetadataCommentString(data, enrichments, formattedExamples, h.formatForeignKeys(data.ForeignKeys))
+
+	existingComment, err := database.ExistingColumnComment(context.Background(), db, h, data.TableName, data.ColumnName)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("WARN: Failed to get existing column comment for %s.%s: %v. Proceeding as if empty.", data.TableName, data.ColumnName, err)
+		existingComment = ""
+	}
+
+	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
+	return h.columnDescriptionSQL(db, data.TableName, data.ColumnName, finalComment), nil
+}
+
+// GenerateRewriteCommentSQL returns the statement that replaces a column's comment
+// with rewrite(existing), or "" when that leaves the comment unchanged.
+func (h bigqueryHandler) GenerateRewriteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string, rewrite func(string) string) (string, error) {
+	if tableName == "" || columnName == "" {
+		return "", fmt.Errorf("table and column names cannot be empty for GenerateRewriteCommentSQL")
+	}
+
+	existingComment, err := database.ExistingColumnComment(ctx, db, h, tableName, columnName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get existing column comment for %s.%s before rewrite: %w", tableName, columnName, err)
+	}
+
+	finalComment := rewrite(existingComment)
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.columnDescriptionSQL(db, tableName, columnName, finalComment), nil
+}
+
+func (h bigqueryHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	return h.GenerateRewriteCommentSQL(ctx, db, tableName, columnName, database.StripMetadata)
+}
+
+// GetColumnComment reads the description option of a top-level column.
+func (h bigqueryHandler) GetColumnComment(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	query := fmt.Sprintf(`
+		SELECT description
+		FROM %s
+		WHERE table_name = @p1
+			AND column_name = @p2
+			AND field_path = column_name`, h.informationSchema(db, "COLUMN_FIELD_PATHS"))
+
+	var comment sql.NullString
+	err := db.Pool.QueryRowContext(ctx, query, tableName, columnName).Scan(&comment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		log.Printf("ERROR: Failed to retrieve column comment for %s.%s: %v", tableName, columnName, err)
+		return "", fmt.Errorf("failed to retrieve column comment for %s.%s: %w", tableName, columnName, err)
+	}
+
+	if comment.Valid {
+		return comment.String, nil
+	}
+	return "", nil
+}
+
+// GetAllColumnComments reads the description option of every top-level column of
+// a table in one query.
+func (h bigqueryHandler) GetAllColumnComments(ctx context.Context, db *database.DB, tableName string) (map[string]string, error) {
+	query := fmt.Sprintf(`
+		SELECT column_name, description
+		FROM %s
+		WHERE table_name = @p1
+			AND field_path = column_name`, h.informationSchema(db, "COLUMN_FIELD_PATHS"))
+
+	rows, err := db.Pool.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve column comments for table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	comments := make(map[string]string)
+	for rows.Next() {
+		var column string
+		var comment sql.NullString
+		if err := rows.Scan(&column, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column comment for table %s: %w", tableName, err)
+		}
+		if comment.Valid && comment.String != "" {
+			comments[column] = comment.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column comments for table %s: %w", tableName, err)
+	}
+	return comments, nil
+}
+
+func (h bigqueryHandler) GenerateTableCommentSQL(db *database.DB, data *database.TableCommentData, enrichments map[string]bool) (string, error) {
+	if data == nil || data.TableName == "" {
+		return "", fmt.Errorf("table comment data cannot be nil or empty")
+	}
+
+	newMetadataComment := database.GenerateTableMetadataCommentString(data, enrichments)
+
+	existingComment, err := database.ExistingTableComment(context.Background(), db, h, data.TableName)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("WARN: Failed to get existing table comment for %s: %v. Proceeding as if empty.", data.TableName, err)
+		existingComment = ""
+	}
+
+	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.tableDescriptionSQL(db, data.TableName, finalComment), nil
+}
+
+// GetTableComment reads the table's description option. TABLE_OPTIONS reports
+// option values as GoogleSQL literals, so the value is unquoted before use.
+func (h bigqueryHandler) GetTableComment(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	query := fmt.Sprintf(`
+		SELECT option_value
+		FROM %s
+		WHERE table_name = @p1
+			AND option_name = 'description'`, h.informationSchema(db, "TABLE_OPTIONS"))
+
+	var optionValue sql.NullString
+	err := db.Pool.QueryRowContext(ctx, query, tableName).Scan(&optionValue)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		log.Printf("ERROR: Failed to retrieve table comment for %s: %v", tableName, err)
+		return "", fmt.Errorf("failed to retrieve table comment for %s: %w", tableName, err)
+	}
+
+	if !optionValue.Valid {
+		return "", nil
+	}
+	comment, err := strconv.Unquote(optionValue.String)
+	if err != nil {
+		return optionValue.String, nil
+	}
+	return comment, nil
+}
+
+// GenerateRewriteTableCommentSQL is GenerateRewriteCommentSQL for the table comment.
+func (h bigqueryHandler) GenerateRewriteTableCommentSQL(ctx context.Context, db *database.DB, tableName string, rewrite func(string) string) (string, error) {
+	if tableName == "" {
+		return "", fmt.Errorf("table name cannot be empty for GenerateRewriteTableCommentSQL")
+	}
+
+	existingComment, err := h.GetTableComment(ctx, db, tableName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get existing table comment for %s before rewrite: %w", tableName, err)
+	}
+
+	finalComment := rewrite(existingComment)
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.tableDescriptionSQL(db, tableName, finalComment), nil
+}
+
+func (h bigqueryHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	return h.GenerateRewriteTableCommentSQL(ctx, db, tableName, database.StripMetadata)
+}
+
+// GetForeignKeys returns the unenforced foreign keys declared on the column.
+func (h bigqueryHandler) GetForeignKeys(db *database.DB, tableName string, columnName string) ([]database.ForeignKeyReference, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			ccu.table_name AS referenced_table,
+			ccu.column_name AS referenced_column,
+			tc.constraint_name
+		FROM %s tc
+		JOIN %s kcu
+			ON tc.constraint_name = kcu.constraint_name
+		JOIN %s ccu
+			ON ccu.constraint_name = tc.constraint_name
+		WHERE tc.constraint_type = 'FOREIGN KEY'
+			AND tc.table_name = @p1
+			AND kcu.column_name = @p2`,
+		h.informationSchema(db, "TABLE_CONSTRAINTS"),
+		h.informationSchema(db, "KEY_COLUMN_USAGE"),
+		h.informationSchema(db, "CONSTRAINT_COLUMN_USAGE"))
+
+	rows, err := db.Pool.Query(query, tableName, columnName)
+	if err != nil {
+		return nil, fmt.Errorf("error querying foreign keys for %s.%s: %w", tableName, columnName, err)
+	}
+	defer rows.Close()
+
+	var foreignKeys []database.ForeignKeyReference
+	for rows.Next() {
+		var fk database.ForeignKeyReference
+		if err := rows.Scan(&fk.ReferencedTable, &fk.ReferencedColumn, &fk.ConstraintName); err != nil {
+			return nil, fmt.Errorf("error scanning foreign key data for %s.%s: %w", tableName, columnName, err)
+		}
+		foreignKeys = append(foreignKeys, fk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign key rows for %s.%s: %w", tableName, columnName, err)
+	}
+
+	return foreignKeys, nil
+}
+
+// ForeignKeyMatchQuery implements database.ForeignKeyMatcher. BigQuery never enforces
+// foreign keys, so the share shows how far they hold.
+func (h bigqueryHandler) ForeignKeyMatchQuery(ctx context.Context, db *database.DB, tableName string, columnName string, fk database.ForeignKeyReference) string {
+	return database.ForeignKeyMatchSQL(h.qualifiedTable(db, tableName), h.QuoteIdentifier(columnName), h.qualifiedTable(db, fk.ReferencedTable), h.QuoteIdentifier(fk.ReferencedColumn))
+}
+
+func init() {
+	database.RegisterDialectHandler("bigquery", bigqueryHandler{})
+}
@@ -0,0 +1,384 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/sqladapter"
+)
+
+// fakeResult is a canned response for queries containing a given substring.
+type fakeResult struct {
+	match   string
+	columns []string
+	rows    [][]interface{}
+	err     error
+}
+
+// fakeRunner stands in for the BigQuery client, answering queries with the first
+// result whose match string appears in the query and recording every call.
+type fakeRunner struct {
+	results    []fakeResult
+	queries    []string
+	params     [][]interface{}
+	statements []string
+}
+
+func (f *fakeRunner) Query(ctx context.Context, query string, params []interface{}) ([]string, [][]interface{}, error) {
+	f.queries = append(f.queries, query)
+	f.params = append(f.params, params)
+	for _, r := range f.results {
+		if strings.Contains(query, r.match) {
+			return r.columns, r.rows, r.err
+		}
+	}
+	return nil, nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func (f *fakeRunner) Exec(ctx context.Context, statements []string) error {
+	f.statements = append(f.statements, statements...)
+	return nil
+}
+
+func (f *fakeRunner) Close() error { return nil }
+
+func newTestDB(runner *fakeRunner) *database.DB {
+	return &database.DB{
+		Pool:    sqladapter.OpenDB("bigquery", runner),
+		Handler: bigqueryHandler{},
+		Config: config.DatabaseConfig{
+			Dialect:            "bigquery",
+			Project:            "my-project",
+			DBName:             "sales",
+			UpdateExistingMode: "overwrite",
+		},
+	}
+}
+
+func TestBigQueryQuoteIdentifier(t *testing.T) {
+	h := bigqueryHandler{}
+	if got := h.QuoteIdentifier("orders"); got != "`orders`" {
+		t.Errorf("QuoteIdentifier() = %q, want %q", got, "`orders`")
+	}
+	if got := h.QuoteIdentifier("a`b"); got != "`a\\`b`" {
+		t.Errorf("QuoteIdentifier() = %q, want %q", got, "`a\\`b`")
+	}
+	if got := h.QuoteIdentifier(`a\`); got != "`a\\\\`" {
+		t.Errorf("QuoteIdentifier() = %q, want %q", got, "`a\\\\`")
+	}
+}
+
+func TestBigQueryListColumns(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{{
+		match:   "INFORMATION_SCHEMA.COLUMNS",
+		columns: []string{"column_name", "data_type"},
+		rows:    [][]interface{}{{"id", "INT64"}, {"email", "STRING"}},
+	}}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	columns, err := bigqueryHandler{}.ListColumns(db, "customers")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	want := []database.ColumnInfo{{Name: "id", DataType: "INT64"}, {Name: "email", DataType: "STRING"}}
+	if len(columns) != len(want) || columns[0] != want[0] || columns[1] != want[1] {
+		t.Errorf("ListColumns() = %v, want %v", columns, want)
+	}
+	if !strings.Contains(runner.queries[0], "`sales`.INFORMATION_SCHEMA.COLUMNS") {
+		t.Errorf("ListColumns() query not dataset-qualified: %s", runner.queries[0])
+	}
+	if want := []interface{}{"customers"}; !reflect.DeepEqual(runner.params[0], want) {
+		t.Errorf("ListColumns() params = %v, want %v", runner.params[0], want)
+	}
+}
+
+func TestBigQueryGetColumnMetadata(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{
+		{match: "COUNT(DISTINCT", columns: []string{"f0_"}, rows: [][]interface{}{{"3"}}},
+		{match: "IS NULL", columns: []string{"f0_"}, rows: [][]interface{}{{"1"}}},
+		{match: "SELECT DISTINCT", columns: []string{"f0_"}, rows: [][]interface{}{{"a"}, {"b"}, {nil}}},
+	}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	metadata, err := bigqueryHandler{}.GetColumnMetadata(db, "customers", "tier")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if metadata["DistinctCount"] != int64(3) || metadata["NullCount"] != int64(1) {
+		t.Errorf("GetColumnMetadata() counts = %v/%v, want 3/1", metadata["DistinctCount"], metadata["NullCount"])
+	}
+	if examples := metadata["ExampleValues"].([]string); len(examples) != 2 {
+		t.Errorf("GetColumnMetadata() examples = %v, want [a b]", examples)
+	}
+	exampleQuery := runner.queries[2]
+	if !strings.Contains(exampleQuery, "FROM `sales`.`customers`") || !strings.Contains(exampleQuery, "ORDER BY 1") {
+		t.Errorf("GetColumnMetadata() example query = %s", exampleQuery)
+	}
+}
+
+// TestBigQueryAverage checks the AVG query and the rendered average, and that a
+// column that is not numeric is not averaged.
+func TestBigQueryAverage(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{
+		{match: "SELECT data_type", columns: []string{"data_type"}, rows: [][]interface{}{{"NUMERIC"}}},
+		{match: "COUNT(DISTINCT", columns: []string{"f0_"}, rows: [][]interface{}{{"2"}}},
+		{match: "IS NULL", columns: []string{"f0_"}, rows: [][]interface{}{{"0"}}},
+		{match: "SELECT DISTINCT", columns: []string{"f0_"}, rows: [][]interface{}{{"10"}}},
+		{match: "AVG(`amount`)", columns: []string{"f0_"}, rows: [][]interface{}{{"12.3456"}}},
+		{match: "COLUMN_FIELD_PATHS", columns: []string{"description"}},
+	}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+	db.Config.ComputeAverage = true
+
+	metadata, err := bigqueryHandler{}.GetColumnMetadata(db, "orders", "amount")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if want := "SELECT CAST(AVG(`amount`) AS FLOAT64) FROM `sales`.`orders`"; runner.queries[4] != want {
+		t.Errorf("GetColumnMetadata() average query = %s, want %s", runner.queries[4], want)
+	}
+	avg, ok := metadata["Average"].(float64)
+	if !ok || avg != 12.3456 {
+		t.Fatalf("GetColumnMetadata() Average = %v, want 12.3456", metadata["Average"])
+	}
+
+	got, err := bigqueryHandler{}.GenerateCommentSQL(db, &database.CommentData{TableName: "orders", ColumnName: "amount", Average: &avg}, map[string]bool{"average": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	want := "ALTER TABLE `sales`.`orders` ALTER COLUMN `amount` SET OPTIONS (description = \"<gemini>Avg: 12.35</gemini>\");"
+	if got != want {
+		t.Errorf("GenerateCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+
+	runner.results[0].rows = [][]interface{}{{"STRING"}}
+	queries := len(runner.queries)
+	metadata, err = bigqueryHandler{}.GetColumnMetadata(db, "orders", "status")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error for a STRING column: %v", err)
+	}
+	if _, ok := metadata["Average"]; ok {
+		t.Errorf("GetColumnMetadata() Average = %v for a STRING column, want none", metadata["Average"])
+	}
+	for _, query := range runner.queries[queries:] {
+		if strings.Contains(query, "AVG(") {
+			t.Errorf("GetColumnMetadata() ran %s for a STRING column", query)
+		}
+	}
+}
+
+func TestBigQueryGenerateCommentSQL(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{{
+		match:   "COLUMN_FIELD_PATHS",
+		columns: []string{"description"},
+		rows:    [][]interface{}{{"Customer\nemail"}},
+	}}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	data := &database.CommentData{
+		TableName:     "customers",
+		ColumnName:    "email",
+		ExampleValues: []string{`a"b@example.com`},
+	}
+	got, err := bigqueryHandler{}.GenerateCommentSQL(db, data, map[string]bool{"examples": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	want := "ALTER TABLE `sales`.`customers` ALTER COLUMN `email` SET OPTIONS (description = \"Customer\\nemail <gemini>Examples: ['a\\\"b@example.com']</gemini>\");"
+	if got != want {
+		t.Errorf("GenerateCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBigQueryGetAllColumnComments(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{{
+		match:   "SELECT column_name, description",
+		columns: []string{"column_name", "description"},
+		rows:    [][]interface{}{{"id", nil}, {"email", "Customer email"}},
+	}}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	comments, err := db.GetAllColumnComments(context.Background(), "customers")
+	if err != nil {
+		t.Fatalf("GetAllColumnComments() unexpected error: %v", err)
+	}
+	if len(comments) != 1 || comments["email"] != "Customer email" {
+		t.Errorf("GetAllColumnComments() = %v, want map[email:Customer email]", comments)
+	}
+	if !strings.Contains(runner.queries[0], "`sales`.INFORMATION_SCHEMA.COLUMN_FIELD_PATHS") {
+		t.Errorf("GetAllColumnComments() query not dataset-qualified: %s", runner.queries[0])
+	}
+
+	data := &database.CommentData{TableName: "customers", ColumnName: "email", ExampleValues: []string{"a@example.com"}}
+	if _, err := db.GenerateCommentSQL(data, map[string]bool{"examples": true}); err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	if len(runner.queries) != 1 {
+		t.Errorf("expected the cached comments to be reused, got queries %v", runner.queries)
+	}
+}
+
+func TestBigQueryGetTableComment(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{{
+		match:   "TABLE_OPTIONS",
+		columns: []string{"option_value"},
+		rows:    [][]interface{}{{`"Customer \"master\" data"`}},
+	}}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	got, err := bigqueryHandler{}.GetTableComment(context.Background(), db, "customers")
+	if err != nil {
+		t.Fatalf("GetTableComment() unexpected error: %v", err)
+	}
+	if want := `Customer "master" data`; got != want {
+		t.Errorf("GetTableComment() = %q, want %q", got, want)
+	}
+}
+
+func TestBigQueryExecuteSQLStatements(t *testing.T) {
+	runner := &fakeRunner{}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	stmts := []string{"ALTER TABLE `sales`.`customers` SET OPTIONS (description = \"x\");"}
+	if err := db.ExecuteSQLStatements(context.Background(), stmts); err != nil {
+		t.Fatalf("ExecuteSQLStatements() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(runner.statements, stmts) {
+		t.Errorf("executed statements = %v, want %v", runner.statements, stmts)
+	}
+}
+
+func TestBigQueryDriverValue(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{nil, nil},
+		{"a", "a"},
+		{int64(3), int64(3)},
+		{1.5, 1.5},
+		{true, true},
+		{ts, ts},
+		{big.NewRat(12345, 100), "123.450000000"},
+		{[]interface{}{"a", "b"}, "[a b]"},
+	}
+	for _, tt := range tests {
+		if got := driverValue(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("driverValue(%#v) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBigQueryExecEachReportsAppliedStatements(t *testing.T) {
+	stmts := []string{"ALTER TABLE a SET OPTIONS ()", "ALTER TABLE b SET OPTIONS ()", "ALTER TABLE c SET OPTIONS ()"}
+	failOn := func(failing string) func(context.Context, string) error {
+		return func(ctx context.Context, stmt string) error {
+			if stmt == failing {
+				return errors.New("table not found")
+			}
+			return nil
+		}
+	}
+
+	err := execEach(context.Background(), stmts, failOn(stmts[1]))
+	var partial *database.PartialApplyError
+	if !errors.As(err, &partial) {
+		t.Fatalf("execEach() error = %v, want a PartialApplyError", err)
+	}
+	if !reflect.DeepEqual(partial.Applied, stmts[:1]) || partial.Failed != stmts[1] {
+		t.Errorf("PartialApplyError applied %v, failed %q; want %v, %q", partial.Applied, partial.Failed, stmts[:1], stmts[1])
+	}
+
+	err = execEach(context.Background(), stmts, failOn(stmts[0]))
+	if err == nil || errors.As(err, &partial) {
+		t.Errorf("execEach() error = %v, want a plain error when nothing was applied", err)
+	}
+}
+
+// TestBigQuerySpecialIdentifiersEndToEnd follows reserved-word and spaced column names
+// from ListColumns through the metadata queries to the generated comment.
+func TestBigQuerySpecialIdentifiersEndToEnd(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{
+		{match: "INFORMATION_SCHEMA.COLUMNS", columns: []string{"column_name", "data_type"}, rows: [][]interface{}{{"select", "STRING"}, {"first name", "STRING"}}},
+		{match: "COLUMN_FIELD_PATHS", columns: []string{"description"}},
+		{match: "COUNT(DISTINCT", columns: []string{"f0_"}, rows: [][]interface{}{{"1"}}},
+		{match: "IS NULL", columns: []string{"f0_"}, rows: [][]interface{}{{"0"}}},
+		{match: "SELECT DISTINCT", columns: []string{"f0_"}, rows: [][]interface{}{{"a"}}},
+	}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+	h := bigqueryHandler{}
+
+	columns, err := h.ListColumns(db, "order")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	for _, col := range columns {
+		runner.queries = nil
+		metadata, err := h.GetColumnMetadata(db, "order", col.Name)
+		if err != nil {
+			t.Fatalf("GetColumnMetadata(%q) unexpected error: %v", col.Name, err)
+		}
+		quoted := h.QuoteIdentifier(col.Name)
+		for _, q := range runner.queries {
+			if !strings.Contains(q, "FROM `sales`.`order`") || !strings.Contains(q, quoted) {
+				t.Errorf("GetColumnMetadata(%q) query not quoted: %s", col.Name, q)
+			}
+		}
+
+		got, err := h.GenerateCommentSQL(db, &database.CommentData{
+			TableName:     "order",
+			ColumnName:    col.Name,
+			ExampleValues: metadata["ExampleValues"].([]string),
+		}, map[string]bool{"examples": true})
+		if err != nil {
+			t.Fatalf("GenerateCommentSQL(%q) unexpected error: %v", col.Name, err)
+		}
+		want := "ALTER TABLE `sales`.`order` ALTER COLUMN " + quoted + " SET OPTIONS (description = \"<gemini>Examples: ['a']</gemini>\");"
+		if got != want {
+			t.Errorf("GenerateCommentSQL(%q) =\n%s\nwant\n%s", col.Name, got, want)
+		}
+	}
+}
+
+func TestBigQueryListSkipsNullNames(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{
+		{match: "INFORMATION_SCHEMA.TABLES", columns: []string{"table_name"}, rows: [][]interface{}{{"customers"}, {nil}}},
+		{match: "INFORMATION_SCHEMA.COLUMNS", columns: []string{"column_name", "data_type"}, rows: [][]interface{}{{"Id", "INT64"}, {nil, "STRING"}, {"Note", nil}}},
+	}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	tables, err := bigqueryHandler{}.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	if len(tables) != 1 || tables[0] != "customers" {
+		t.Errorf("ListTables() = %v, want [customers]", tables)
+	}
+
+	columns, err := bigqueryHandler{}.ListColumns(db, "customers")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	want := []database.ColumnInfo{{Name: "Id", DataType: "INT64"}, {Name: "Note"}}
+	if len(columns) != len(want) || columns[0] != want[0] || columns[1] != want[1] {
+		t.Errorf("ListColumns() = %v, want %v", columns, want)
+	}
+}
@@ -0,0 +1,114 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	bq "cloud.google.com/go/bigquery"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/sqladapter"
+	"google.golang.org/api/iterator"
+)
+
+// clientRunner runs statements through the BigQuery client library against a
+// default dataset, so the handler's queries can name tables without a project.
+type clientRunner struct {
+	client  *bq.Client
+	dataset string
+}
+
+var _ sqladapter.Runner = (*clientRunner)(nil)
+
+func (r *clientRunner) query(statement string, params []interface{}) *bq.Query {
+	q := r.client.Query(statement)
+	q.DefaultProjectID = r.client.Project()
+	q.DefaultDatasetID = r.dataset
+	for i, p := range params {
+		q.Parameters = append(q.Parameters, bq.QueryParameter{Name: fmt.Sprintf("p%d", i+1), Value: p})
+	}
+	return q
+}
+
+func (r *clientRunner) Query(ctx context.Context, query string, params []interface{}) ([]string, [][]interface{}, error) {
+	it, err := r.query(query, params).Read(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bigquery query failed: %w", err)
+	}
+
+	var values [][]interface{}
+	for {
+		var row []bq.Value
+		err := it.Next(&row)
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("bigquery query results failed: %w", err)
+		}
+		cells := make([]interface{}, len(row))
+		for i, v := range row {
+			cells[i] = driverValue(v)
+		}
+		values = append(values, cells)
+	}
+
+	// The schema is known once the iterator has fetched the first page.
+	columns := make([]string, len(it.Schema))
+	for i, field := range it.Schema {
+		columns[i] = field.Name
+	}
+	return columns, values, nil
+}
+
+// Exec runs the statements one after another. BigQuery DDL is not transactional,
+// so each statement takes effect as soon as its job completes.
+func (r *clientRunner) Exec(ctx context.Context, statements []string) error {
+	return execEach(ctx, statements, func(ctx context.Context, stmt string) error {
+		job, err := r.query(stmt, nil).Run(ctx)
+		if err != nil {
+			return err
+		}
+		status, err := job.Wait(ctx)
+		if err != nil {
+			return err
+		}
+		return status.Err()
+	})
+}
+
+// execEach runs the statements in order with run and stops at the first failure. A
+// failure after the first statement is a *database.PartialApplyError listing the
+// statements that were applied.
+func execEach(ctx context.Context, statements []string, run func(ctx context.Context, stmt string) error) error {
+	for i, stmt := range statements {
+		if err := run(ctx, stmt); err != nil {
+			err = fmt.Errorf("bigquery statement #%d failed: %w", i+1, err)
+			if i == 0 {
+				return err
+			}
+			return &database.PartialApplyError{Applied: statements[:i], Failed: stmt, Err: err}
+		}
+	}
+	return nil
+}
+
+func (r *clientRunner) Close() error {
+	return r.client.Close()
+}
+
+// driverValue converts a value read by the client into one database/sql can scan.
+// NUMERIC, DATE, ARRAY and the other types database/sql has no counterpart for
+// are passed as strings.
+func driverValue(v bq.Value) interface{} {
+	switch v := v.(type) {
+	case nil, bool, int64, float64, string, []byte, time.Time:
+		return v
+	case *big.Rat:
+		return bq.NumericString(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,437 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+// clickhouseHandler reads comments from system.columns and system.tables and writes
+// them with ALTER TABLE ... COMMENT COLUMN and ALTER TABLE ... MODIFY COMMENT.
+type clickhouseHandler struct{}
+
+var _ database.DialectHandler = (*clickhouseHandler)(nil)
+
+func (h clickhouseHandler) CreateCloudSQLPool(cfg config.DatabaseConfig) (*sql.DB, error) {
+	return nil, fmt.Errorf("Cloud SQL connections are not supported for ClickHouse")
+}
+
+// CreateStandardPool connects over the native protocol (port 9000 by default).
+func (h clickhouseHandler) CreateStandardPool(cfg config.DatabaseConfig) (*sql.DB, error) {
+	return clickhouse.OpenDB(&clickhouse.Options{
+		Addr: []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)},
+		Auth: clickhouse.Auth{
+			Database: cfg.DBName,
+			Username: cfg.User,
+			Password: cfg.Password,
+		},
+		ClientInfo: clickhouse.ClientInfo{
+			Products: []struct{ Name, Version string }{{Name: database.ApplicationName}},
+		},
+	}), nil
+}
+
+// QuoteIdentifier quotes a name with backticks, which ClickHouse escapes with a backslash.
+func (h clickhouseHandler) QuoteIdentifier(name string) string {
+	name = strings.ReplaceAll(name, `\`, `\\`)
+	name = strings.ReplaceAll(name, "`", "\\`")
+	return fmt.Sprintf("`%s`", name)
+}
+
+// quoteClickHouseString returns value as a single-quoted ClickHouse string literal.
+func quoteClickHouseString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return fmt.Sprintf("'%s'", value)
+}
+
+// ListTables lists the tables of the current database. Views, dictionaries and
+// the hidden .inner tables backing materialized views have no columns of their own
+// to describe, and temporary tables only exist in the session that created them.
+func (h clickhouseHandler) ListTables(db *database.DB) ([]string, error) {
+	query := `
+		SELECT name
+		FROM system.tables
+		WHERE database = currentDatabase()
+			AND is_temporary = 0
+			AND engine NOT IN ('View', 'MaterializedView', 'LiveView', 'WindowView', 'Dictionary')
+			AND NOT startsWith(name, '.inner')
+		ORDER BY name`
+
+	rows, err := db.Pool.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var tableName sql.NullString
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, fmt.Errorf("error scanning table name: %w", err)
+		}
+		if !tableName.Valid {
+			log.Println("WARN: Skipping a table listed with a NULL name.")
+			continue
+		}
+		tables = append(tables, tableName.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating table rows: %w", err)
+	}
+	return tables, nil
+}
+
+func (h clickhouseHandler) ListColumns(db *database.DB, tableName string) ([]database.ColumnInfo, error) {
+	query := `
+		SELECT name, type
+		FROM system.columns
+		WHERE database = currentDatabase()
+			AND table = ?
+		ORDER BY position`
+
+	rows, err := db.Pool.Query(query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("error querying columns for table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var columns []database.ColumnInfo
+	for rows.Next() {
+		var name, dataType sql.NullString
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return nil, fmt.Errorf("error scanning column name and data type: %w", err)
+		}
+		if !name.Valid {
+			log.Printf("WARN: Skipping a column of %s listed with a NULL name.", tableName)
+			continue
+		}
+		columns = append(columns, database.ColumnInfo{Name: name.String, DataType: dataType.String})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column rows: %w", err)
+	}
+
+	return columns, nil
+}
+
+func (h clickhouseHandler) GetColumnMetadata(db *database.DB, tableName string, columnName string) (map[string]interface{}, error) {
+	quotedTable := h.QuoteIdentifier(tableName)
+	quotedColumn := h.QuoteIdentifier(columnName)
+	ctx := context.Background()
+
+	// toString gives COUNT(DISTINCT) and ORDER BY a comparable type for every column type,
+	// including arrays, maps and tuples.
+	distinctQuery := fmt.Sprintf("SELECT COUNT(DISTINCT toString(%s)) FROM %s", quotedColumn, quotedTable)
+	var distinctCount int64
+	err := db.Pool.QueryRowContext(ctx, distinctQuery).Scan(&distinctCount)
+	if err != nil {
+		log.Printf("WARN: Failed to get distinct count for %s.%s: %v. Reporting -1.", tableName, columnName, err)
+		distinctCount = -1
+	}
+
+	nullQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NULL", quotedTable, quotedColumn)
+	var nullCount int64
+	err = db.Pool.QueryRowContext(ctx, nullQuery).Scan(&nullCount)
+	if err != nil {
+		if permErr := h.permissionError(db, tableName, err); permErr != nil {
+			return nil, permErr
+		}
+		return nil, fmt.Errorf("failed to get null count for %s.%s: %w", tableName, columnName, err)
+	}
+
+	exampleLimit := database.ExampleQueryLimit(db.Config.ExampleSampleSize)
+	exampleQuery := fmt.Sprintf("SELECT DISTINCT toString(%s) FROM %s WHERE %s IS NOT NULL ORDER BY 1 LIMIT %d",
+		quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	firstRows := database.SampleWithoutDistinct(db, distinctCount)
+	if firstRows {
+		exampleQuery = fmt.Sprintf("SELECT toString(%s) FROM %s WHERE %s IS NOT NULL LIMIT %d",
+			quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	}
+	rows, err := db.Pool.QueryContext(ctx, exampleQuery)
+	if err != nil {
+		if permErr := h.permissionError(db, tableName, err); permErr != nil {
+			return nil, permErr
+		}
+		return nil, fmt.Errorf("failed to get example values for %s.%s: %w", tableName, columnName, err)
+	}
+	defer rows.Close()
+
+	var examples []string
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("error scanning example value for %s.%s: %w", tableName, columnName, err)
+		}
+		if value.Valid {
+			examples = append(examples, value.String)
+		}
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating example values for %s.%s: %w", tableName, columnName, rows.Err())
+	}
+	if firstRows {
+		examples = database.UniqueValues(examples)
+	}
+	examples = database.SelectRepresentativeValues(examples, database.DefaultExampleCount)
+
+	metadata := map[string]interface{}{
+		"DistinctCount": distinctCount,
+		"NullCount":     nullCount,
+		"ExampleValues": examples,
+	}
+	// Only numeric columns are averaged, since the query is a full scan.
+	if db.Config.ComputeAverage && database.IsNumericType(h.columnDataType(ctx, db, tableName, columnName)) {
+		avgQuery := fmt.Sprintf("SELECT toFloat64(avg(%s)) FROM %s", quotedColumn, quotedTable)
+		if avg := database.ColumnAverage(ctx, db, avgQuery); avg != nil {
+			metadata["Average"] = *avg
+		}
+	}
+	return metadata, nil
+}
+
+// columnDataType returns the name of a column's type, or "" if it cannot be read.
+func (h clickhouseHandler) columnDataType(ctx context.Context, db *database.DB, tableName, columnName string) string {
+	query := `
+		SELECT type
+		FROM system.columns
+		WHERE database = currentDatabase()
+			AND table = ?
+			AND name = ?`
+	var dataType string
+	if err := db.Pool.QueryRowContext(ctx, query, tableName, columnName).Scan(&dataType); err != nil {
+		log.Printf("WARN: Failed to read the type of %s.%s: %v", tableName, columnName, err)
+		return ""
+	}
+	return dataType
+}
+
+// formatExampleValues renders the examples as plain comment text. Values are not
+// escaped here: the whole comment is escaped once by quoteClickHouseString.
+func (h clickhouseHandler) formatExampleValues(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	cleaned := make([]string, len(values))
+	for i, v := range values {
+		trimmed := strings.ReplaceAll(v, "\n", " ")
+		if len(trimmed) > 100 {
+			trimmed = trimmed[:100] + "...[truncated]"
+		}
+		cleaned[i] = trimmed
+	}
+	return fmt.Sprintf("Examples: ['%s']", strings.Join(cleaned, "', '"))
+}
+
+// formatForeignKeys renders referenced columns with this dialect's identifier quoting.
+func (h clickhouseHandler) formatForeignKeys(foreignKeys []database.ForeignKeyReference) string {
+	return database.FormatForeignKeys(foreignKeys, h.QuoteIdentifier)
+}
+
+// columnCommentSQL sets a column's comment. ClickHouse cannot remove a comment, so
+// an empty one is stored as an empty string.
+func (h clickhouseHandler) columnCommentSQL(tableName, columnName, comment string) string {
+	return fmt.Sprintf("ALTER TABLE %s COMMENT COLUMN %s %s;",
+		h.QuoteIdentifier(tableName), h.QuoteIdentifier(columnName), quoteClickHouseString(comment))
+}
+
+// tableCommentSQL sets a table's comment, which needs ClickHouse 23.9 or later for
+// MergeTree tables.
+func (h clickhouseHandler) tableCommentSQL(tableName, comment string) string {
+	return fmt.Sprintf("ALTER TABLE %s MODIFY COMMENT %s;", h.QuoteIdentifier(tableName), quoteClickHouseString(comment))
+}
+
+func (h clickhouseHandler) GenerateCommentSQL(db *database.DB, data *database.CommentData, enrichments map[string]bool) (string, error) {
+	if data == nil || data.TableName == "" || data.ColumnName == "" {
+		return "", fmt.Errorf("invalid input for GenerateCommentSQL")
+	}
+
+	formattedExamples := h.formatExampleValues(data.ExampleValues)
+	newMetadataComment := database.GenerateMetadataCommentString(data, enrichments, formattedExamples, h.formatForeignKeys(data.ForeignKeys))
+
+	existingComment, err := database.ExistingColumnComment(context.Background(), db, h, data.TableName, data.ColumnName)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("WARN: Failed to get existing column comment for %s.%s: %v. Proceeding as if empty.", data.TableName, data.ColumnName, err)
+		existingComment = ""
+	}
+
+	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
+	return h.columnCommentSQL(data.TableName, data.ColumnName, finalComment), nil
+}
+
+// GenerateRewriteCommentSQL returns the statement that replaces a column's comment
+// with rewrite(existing), or "" when that leaves the comment unchanged.
+func (h clickhouseHandler) GenerateRewriteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string, rewrite func(string) string) (string, error) {
+	if tableName == "" || columnName == "" {
+		return "", fmt.Errorf("table and column names cannot be empty for GenerateRewriteCommentSQL")
+	}
+
+	existingComment, err := database.ExistingColumnComment(ctx, db, h, tableName, columnName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get existing column comment for %s.%s before rewrite: %w", tableName, columnName, err)
+	}
+
+	// A deleted comment is stored as exactly '', not as leftover whitespace.
+	finalComment := strings.TrimSpace(rewrite(existingComment))
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.columnCommentSQL(tableName, columnName, finalComment), nil
+}
+
+func (h clickhouseHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	return h.GenerateRewriteCommentSQL(ctx, db, tableName, columnName, database.StripMetadata)
+}
+
+func (h clickhouseHandler) GetColumnComment(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	query := `
+		SELECT comment
+		FROM system.columns
+		WHERE database = currentDatabase()
+			AND table = ?
+			AND name = ?`
+
+	var comment sql.NullString
+	err := db.Pool.QueryRowContext(ctx, query, tableName, columnName).Scan(&comment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		log.Printf("ERROR: Failed to retrieve column comment for %s.%s: %v", tableName, columnName, err)
+		return "", fmt.Errorf("failed to retrieve column comment for %s.%s: %w", tableName, columnName, err)
+	}
+	if comment.Valid {
+		return comment.String, nil
+	}
+	return "", nil
+}
+
+// GetAllColumnComments reads the comments on every column of a table in one query.
+func (h clickhouseHandler) GetAllColumnComments(ctx context.Context, db *database.DB, tableName string) (map[string]string, error) {
+	query := `
+		SELECT name, comment
+		FROM system.columns
+		WHERE database = currentDatabase()
+			AND table = ?`
+
+	rows, err := db.Pool.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve column comments for table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	comments := make(map[string]string)
+	for rows.Next() {
+		var column string
+		var comment sql.NullString
+		if err := rows.Scan(&column, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column comment for table %s: %w", tableName, err)
+		}
+		if comment.Valid && comment.String != "" {
+			comments[column] = comment.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column comments for table %s: %w", tableName, err)
+	}
+	return comments, nil
+}
+
+func (h clickhouseHandler) GenerateTableCommentSQL(db *database.DB, data *database.TableCommentData, enrichments map[string]bool) (string, error) {
+	if data == nil || data.TableName == "" {
+		return "", fmt.Errorf("table comment data cannot be nil or empty")
+	}
+
+	newMetadataComment := database.GenerateTableMetadataCommentString(data, enrichments)
+
+	existingComment, err := database.ExistingTableComment(context.Background(), db, h, data.TableName)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("WARN: Failed to get existing table comment for %s: %v. Proceeding as if empty.", data.TableName, err)
+		existingComment = ""
+	}
+
+	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.tableCommentSQL(data.TableName, finalComment), nil
+}
+
+func (h clickhouseHandler) GetTableComment(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	query := `
+		SELECT comment
+		FROM system.tables
+		WHERE database = currentDatabase()
+			AND name = ?`
+
+	var comment sql.NullString
+	err := db.Pool.QueryRowContext(ctx, query, tableName).Scan(&comment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		log.Printf("ERROR: Failed to retrieve table comment for %s: %v", tableName, err)
+		return "", fmt.Errorf("failed to retrieve table comment for %s: %w", tableName, err)
+	}
+	if comment.Valid {
+		return comment.String, nil
+	}
+	return "", nil
+}
+
+// GenerateRewriteTableCommentSQL is GenerateRewriteCommentSQL for the table comment.
+func (h clickhouseHandler) GenerateRewriteTableCommentSQL(ctx context.Context, db *database.DB, tableName string, rewrite func(string) string) (string, error) {
+	if tableName == "" {
+		return "", fmt.Errorf("table name cannot be empty for GenerateRewriteTableCommentSQL")
+	}
+
+	existingComment, err := h.GetTableComment(ctx, db, tableName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get existing table comment for %s before rewrite: %w", tableName, err)
+	}
+
+	// As for columns, a deleted comment is stored as exactly ''.
+	finalComment := strings.TrimSpace(rewrite(existingComment))
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.tableCommentSQL(tableName, finalComment), nil
+}
+
+func (h clickhouseHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	return h.GenerateRewriteTableCommentSQL(ctx, db, tableName, database.StripMetadata)
+}
+
+// GetForeignKeys returns no references: ClickHouse has no foreign key constraints.
+func (h clickhouseHandler) GetForeignKeys(db *database.DB, tableName string, columnName string) ([]database.ForeignKeyReference, error) {
+	return nil, nil
+}
+
+// accessDeniedCode is ClickHouse's ACCESS_DENIED error code.
+const accessDeniedCode = 497
+
+// permissionError returns a database.PermissionError when err is ClickHouse's
+// access denied error, and nil otherwise.
+func (h clickhouseHandler) permissionError(db *database.DB, tableName string, err error) error {
+	var chErr *clickhouse.Exception
+	if errors.As(err, &chErr) && chErr.Code == accessDeniedCode {
+		return &database.PermissionError{
+			Table: tableName,
+			Grant: fmt.Sprintf("GRANT SELECT ON %s.%s TO %s;", h.QuoteIdentifier(db.Config.DBName), h.QuoteIdentifier(tableName), h.QuoteIdentifier(db.Config.User)),
+			Err:   err,
+		}
+	}
+	return nil
+}
+
+func init() {
+	database.RegisterDialectHandler("clickhouse", clickhouseHandler{})
+}
@@ -0,0 +1,258 @@
+package clickhouse
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+func newMockClickHouseDB(t *testing.T) (*database.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	db := &database.DB{
+		Pool:    mockDB,
+		Handler: clickhouseHandler{},
+		Config: config.DatabaseConfig{
+			Dialect:            "clickhouse",
+			DBName:             "analytics",
+			User:               "enricher",
+			UpdateExistingMode: "overwrite",
+		},
+	}
+	return db, mock
+}
+
+func TestClickHouseDialectRegistered(t *testing.T) {
+	handler, err := database.GetDialectHandler("clickhouse")
+	if err != nil {
+		t.Fatalf("GetDialectHandler(clickhouse) unexpected error: %v", err)
+	}
+	if _, ok := handler.(clickhouseHandler); !ok {
+		t.Errorf("GetDialectHandler(clickhouse) = %T, want clickhouseHandler", handler)
+	}
+}
+
+func TestClickHouseQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"events", "`events`"},
+		{"odd`name", "`odd\\`name`"},
+		{`back\slash`, "`back\\\\slash`"},
+	}
+	for _, tt := range tests {
+		if got := (clickhouseHandler{}).QuoteIdentifier(tt.name); got != tt.expected {
+			t.Errorf("QuoteIdentifier(%q) = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestClickHouseListTables(t *testing.T) {
+	db, mock := newMockClickHouseDB(t)
+	defer db.Pool.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM system.tables")).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("events").AddRow("sessions"))
+
+	tables, err := clickhouseHandler{}.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	if want := []string{"events", "sessions"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("ListTables() = %v, want %v", tables, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestClickHouseListColumns(t *testing.T) {
+	db, mock := newMockClickHouseDB(t)
+	defer db.Pool.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM system.columns")).WithArgs("events").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "type"}).
+			AddRow("event_id", "UUID").
+			AddRow("user_agent", "Nullable(String)"))
+
+	columns, err := clickhouseHandler{}.ListColumns(db, "events")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	want := []database.ColumnInfo{{Name: "event_id", DataType: "UUID"}, {Name: "user_agent", DataType: "Nullable(String)"}}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("ListColumns() = %v, want %v", columns, want)
+	}
+}
+
+func TestClickHouseGetColumnMetadata(t *testing.T) {
+	db, mock := newMockClickHouseDB(t)
+	defer db.Pool.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT toString(`country`)) FROM `events`")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(3)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `events` WHERE `country` IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(12)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT toString(`country`) FROM `events` WHERE `country` IS NOT NULL ORDER BY 1 LIMIT 3")).
+		WillReturnRows(sqlmock.NewRows([]string{"country"}).AddRow("DE").AddRow("FR").AddRow("US"))
+
+	metadata, err := clickhouseHandler{}.GetColumnMetadata(db, "events", "country")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if metadata["DistinctCount"] != int64(3) || metadata["NullCount"] != int64(12) {
+		t.Errorf("Expected DistinctCount 3 and NullCount 12, got %v and %v", metadata["DistinctCount"], metadata["NullCount"])
+	}
+	if ev := metadata["ExampleValues"]; !reflect.DeepEqual(ev, []string{"DE", "FR", "US"}) {
+		t.Errorf("Expected ExampleValues [DE FR US], got %v", ev)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestClickHouseGetColumnMetadataPermissionDenied(t *testing.T) {
+	db, mock := newMockClickHouseDB(t)
+	defer db.Pool.Close()
+
+	denied := &clickhouse.Exception{Code: accessDeniedCode, Message: "enricher: Not enough privileges"}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT")).WillReturnError(denied)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*)")).WillReturnError(denied)
+
+	_, err := clickhouseHandler{}.GetColumnMetadata(db, "events", "country")
+	permErr, ok := err.(*database.PermissionError)
+	if !ok {
+		t.Fatalf("GetColumnMetadata() error = %v, want a *database.PermissionError", err)
+	}
+	if want := "GRANT SELECT ON `analytics`.`events` TO `enricher`;"; permErr.Grant != want {
+		t.Errorf("Grant = %q, want %q", permErr.Grant, want)
+	}
+}
+
+// TestClickHouseAverage checks the avg query and the rendered average, and that a
+// column that is not numeric is not averaged.
+func TestClickHouseAverage(t *testing.T) {
+	db, mock := newMockClickHouseDB(t)
+	defer db.Pool.Close()
+	db.Config.ComputeAverage = true
+
+	expectStatistics := func(column string) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT toString(`" + column + "`)) FROM `events`")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `events` WHERE `" + column + "` IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT toString(`" + column + "`) FROM `events`")).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("10"))
+	}
+
+	expectType := func(column, dataType string) {
+		mock.ExpectQuery(`SELECT type\s+FROM system.columns`).WithArgs("events", column).
+			WillReturnRows(sqlmock.NewRows([]string{"type"}).AddRow(dataType))
+	}
+
+	expectStatistics("duration_ms")
+	expectType("duration_ms", "Nullable(UInt32)")
+	mock.ExpectQuery("^" + regexp.QuoteMeta("SELECT toFloat64(avg(`duration_ms`)) FROM `events`") + "$").WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(12.3456))
+	metadata, err := clickhouseHandler{}.GetColumnMetadata(db, "events", "duration_ms")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	avg, ok := metadata["Average"].(float64)
+	if !ok || avg != 12.3456 {
+		t.Fatalf("GetColumnMetadata() Average = %v, want 12.3456", metadata["Average"])
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT comment")).WithArgs("events", "duration_ms").
+		WillReturnRows(sqlmock.NewRows([]string{"comment"}).AddRow(""))
+	got, err := clickhouseHandler{}.GenerateCommentSQL(db, &database.CommentData{TableName: "events", ColumnName: "duration_ms", Average: &avg}, map[string]bool{"average": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	if want := "ALTER TABLE `events` COMMENT COLUMN `duration_ms` '<gemini>Avg: 12.35</gemini>';"; got != want {
+		t.Errorf("GenerateCommentSQL() = %q, want %q", got, want)
+	}
+
+	expectStatistics("country")
+	expectType("country", "LowCardinality(String)")
+	metadata, err = clickhouseHandler{}.GetColumnMetadata(db, "events", "country")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error for a String column: %v", err)
+	}
+	if _, ok := metadata["Average"]; ok {
+		t.Errorf("GetColumnMetadata() Average = %v for a String column, want none", metadata["Average"])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestClickHouseGenerateCommentSQL(t *testing.T) {
+	db, mock := newMockClickHouseDB(t)
+	defer db.Pool.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT comment")).WithArgs("events", "country").
+		WillReturnRows(sqlmock.NewRows([]string{"comment"}).AddRow(""))
+
+	data := &database.CommentData{
+		TableName:     "events",
+		ColumnName:    "country",
+		Description:   "Visitor's country",
+		ExampleValues: []string{"DE", "US"},
+	}
+	got, err := clickhouseHandler{}.GenerateCommentSQL(db, data, map[string]bool{"description": true, "examples": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	want := "ALTER TABLE `events` COMMENT COLUMN `country` '<gemini>Examples: [\\'DE\\', \\'US\\'] | Visitor\\'s country</gemini>';"
+	if got != want {
+		t.Errorf("GenerateCommentSQL() = %q, want %q", got, want)
+	}
+}
+
+func TestClickHouseGenerateTableCommentSQL(t *testing.T) {
+	db, mock := newMockClickHouseDB(t)
+	defer db.Pool.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM system.tables")).WithArgs("events").
+		WillReturnRows(sqlmock.NewRows([]string{"comment"}).AddRow(""))
+
+	got, err := clickhouseHandler{}.GenerateTableCommentSQL(db, &database.TableCommentData{TableName: "events", Description: "Page views"}, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateTableCommentSQL() unexpected error: %v", err)
+	}
+	if want := "ALTER TABLE `events` MODIFY COMMENT '<gemini>Page views</gemini>';"; got != want {
+		t.Errorf("GenerateTableCommentSQL() = %q, want %q", got, want)
+	}
+}
+
+func TestClickHouseGenerateDeleteCommentSQL(t *testing.T) {
+	db, mock := newMockClickHouseDB(t)
+	defer db.Pool.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT comment")).WithArgs("events", "country").
+		WillReturnRows(sqlmock.NewRows([]string{"comment"}).AddRow("ISO code <gemini>Examples: ['DE']</gemini>"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT comment")).WithArgs("events", "plain").
+		WillReturnRows(sqlmock.NewRows([]string{"comment"}).AddRow("User text only"))
+
+	got, err := clickhouseHandler{}.GenerateDeleteCommentSQL(context.Background(), db, "events", "country")
+	if err != nil {
+		t.Fatalf("GenerateDeleteCommentSQL() unexpected error: %v", err)
+	}
+	if want := "ALTER TABLE `events` COMMENT COLUMN `country` 'ISO code';"; got != want {
+		t.Errorf("GenerateDeleteCommentSQL() = %q, want %q", got, want)
+	}
+
+	got, err = clickhouseHandler{}.GenerateDeleteCommentSQL(context.Background(), db, "events", "plain")
+	if err != nil {
+		t.Fatalf("GenerateDeleteCommentSQL() unexpected error: %v", err)
+	}
+	if got != "" {
+		t.Errorf("GenerateDeleteCommentSQL() without generated metadata = %q, want no statement", got)
+	}
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sqladmin "google.golang.org/api/sqladmin/v1"
+)
+
+// CloudSQLInstanceLookup reads the settings of a Cloud SQL instance. It is the seam
+// between --cloudsql-autodetect and the Cloud SQL Admin API, so tests can substitute
+// canned instances.
+type CloudSQLInstanceLookup interface {
+	// DatabaseVersion returns the instance's engine version, e.g. "POSTGRES_15",
+	// "MYSQL_8_0" or "SQLSERVER_2019_STANDARD".
+	DatabaseVersion(ctx context.Context, project, instance string) (string, error)
+}
+
+// adminAPILookup looks up instances with the Cloud SQL Admin API, authenticating with
+// Application Default Credentials.
+type adminAPILookup struct {
+	svc *sqladmin.Service
+}
+
+// NewCloudSQLInstanceLookup returns a lookup backed by the Cloud SQL Admin API.
+func NewCloudSQLInstanceLookup(ctx context.Context) (CloudSQLInstanceLookup, error) {
+	svc, err := sqladmin.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud SQL Admin client: %w", err)
+	}
+	return &adminAPILookup{svc: svc}, nil
+}
+
+func (l *adminAPILookup) DatabaseVersion(ctx context.Context, project, instance string) (string, error) {
+	inst, err := l.svc.Instances.Get(project, instance).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	return inst.DatabaseVersion, nil
+}
+
+// cloudSQLDialects maps the engine prefix of a Cloud SQL database version to the
+// dialect that connects to it.
+var cloudSQLDialects = []struct{ prefix, dialect string }{
+	{"POSTGRES_", "cloudsqlpostgres"},
+	{"MYSQL_", "cloudsqlmysql"},
+	{"SQLSERVER_", "cloudsqlsqlserver"},
+}
+
+// DetectCloudSQLDialect returns the dialect of the Cloud SQL instance named by a
+// "project:region:instance" connection name, for --cloudsql-autodetect. The Cloud
+// SQL connector picks the port itself, so the engine is all that needs detecting.
+// The name is split on its last two colons, since domain-scoped project IDs such as
+// "example.com:project" contain one themselves.
+func DetectCloudSQLDialect(ctx context.Context, lookup CloudSQLInstanceLookup, connectionName string) (string, error) {
+	rest, instance, _ := cutLast(connectionName, ":")
+	project, region, ok := cutLast(rest, ":")
+	if !ok || project == "" || region == "" || instance == "" {
+		return "", fmt.Errorf("invalid Cloud SQL instance connection name '%s'. Expected 'project:region:instance'", connectionName)
+	}
+	version, err := lookup.DatabaseVersion(ctx, project, instance)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up Cloud SQL instance %s: %w", connectionName, err)
+	}
+	for _, d := range cloudSQLDialects {
+		if strings.HasPrefix(version, d.prefix) {
+			return d.dialect, nil
+		}
+	}
+	return "", fmt.Errorf("Cloud SQL instance %s runs unsupported database version '%s'", connectionName, version)
+}
+
+// cutLast slices s around the last instance of sep, like strings.Cut does around the
+// first.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeInstanceLookup serves database versions keyed by "project/instance".
+type fakeInstanceLookup struct {
+	versions map[string]string
+	calls    []string
+}
+
+func (f *fakeInstanceLookup) DatabaseVersion(ctx context.Context, project, instance string) (string, error) {
+	f.calls = append(f.calls, project+"/"+instance)
+	version, ok := f.versions[project+"/"+instance]
+	if !ok {
+		return "", errors.New("googleapi: Error 404: The Cloud SQL instance does not exist")
+	}
+	return version, nil
+}
+
+func TestDetectCloudSQLDialect(t *testing.T) {
+	lookup := &fakeInstanceLookup{versions: map[string]string{
+		"shop/orders-pg":             "POSTGRES_15",
+		"shop/orders-mysql":          "MYSQL_8_0_31",
+		"shop/orders-mssql":          "SQLSERVER_2019_STANDARD",
+		"shop/legacy":                "ORACLE_19",
+		"example.com:shop/orders-pg": "POSTGRES_16",
+	}}
+	tests := []struct {
+		connectionName string
+		want           string
+		wantErr        bool
+	}{
+		{"shop:us-central1:orders-pg", "cloudsqlpostgres", false},
+		{"shop:us-central1:orders-mysql", "cloudsqlmysql", false},
+		{"shop:europe-west1:orders-mssql", "cloudsqlsqlserver", false},
+		{"shop:us-central1:legacy", "", true},
+		{"shop:us-central1:missing", "", true},
+		{"example.com:shop:us-central1:orders-pg", "cloudsqlpostgres", false},
+		{"orders-pg", "", true},
+		{"shop:orders-pg", "", true},
+		{"shop::orders-pg", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.connectionName, func(t *testing.T) {
+			got, err := DetectCloudSQLDialect(context.Background(), lookup, tt.connectionName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectCloudSQLDialect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DetectCloudSQLDialect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	// The malformed connection names are rejected before any lookup.
+	if len(lookup.calls) != 6 {
+		t.Errorf("DatabaseVersion() called %d times, want 6: %v", len(lookup.calls), lookup.calls)
+	}
+}
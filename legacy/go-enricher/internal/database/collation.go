@@ -0,0 +1,30 @@
+package database
+
+import (
+	"context"
+	"log"
+)
+
+// Values of --distinct-collation.
+const (
+	DistinctCollationDefault = "default" // Count with the column's own collation.
+	DistinctCollationBinary  = "binary"  // Count values that differ only in case or accents as distinct.
+)
+
+// DistinctCount runs a distinct count query. With --distinct-collation binary it runs
+// binaryQuery instead, which compares values byte by byte, and falls back to query if
+// that fails, as it does for types without a collation where both count the same.
+// The fallback is logged for column, which names the column as table.column.
+// Dialects that already compare binary pass an empty binaryQuery.
+func DistinctCount(ctx context.Context, db *DB, column, query, binaryQuery string) (int64, error) {
+	var count int64
+	if db.Config.DistinctCollation == DistinctCollationBinary && binaryQuery != "" {
+		err := db.Pool.QueryRowContext(ctx, binaryQuery).Scan(&count)
+		if err == nil {
+			return count, nil
+		}
+		log.Printf("WARN: Column[%s] Binary distinct count failed: %v. Counting with the column's own collation instead (--distinct-collation).", column, err)
+	}
+	err := db.Pool.QueryRowContext(ctx, query).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Values of --comment-style.
+const (
+	CommentStyleKeyValue = "keyvalue" // Terse "Distinct: 150 | Nulls: 5" parts.
+	CommentStyleSentence = "sentence" // Readable sentences, e.g. "This column has 150 distinct values and 5 nulls."
+)
+
+// sentenceMetadata renders the kept comment parts as prose. The statistics are
+// combined into one sentence; the description comes first and custom enrichments
+// last, as in the key-value style.
+func sentenceMetadata(parts []commentPart, data *CommentData) string {
+	texts := make(map[string]string, len(parts))
+	for _, p := range parts {
+		texts[p.enrichment] = p.text
+	}
+
+	var sentences []string
+	if description, ok := texts["description"]; ok {
+		sentences = append(sentences, asSentence(description))
+	}
+
+	if dataType, ok := texts["data_type"]; ok {
+		sentences = append(sentences, fmt.Sprintf("Its data type is %s.", strings.TrimPrefix(dataType, "Type: ")))
+	}
+
+	var counts []string
+	if _, ok := texts["distinct_values"]; ok {
+		counts = append(counts, countPhrase(data.DistinctCount, "distinct value", "distinct values"))
+	}
+	if _, ok := texts["null_count"]; ok {
+		counts = append(counts, countPhrase(data.NullCount, "null", "nulls"))
+	}
+	examples, hasExamples := texts["examples"]
+	examples = strings.TrimSuffix(strings.TrimPrefix(examples, "Examples: ["), "]")
+	switch {
+	case len(counts) > 0 && hasExamples:
+		sentences = append(sentences, fmt.Sprintf("This column has %s; examples include %s.", strings.Join(counts, " and "), examples))
+	case len(counts) > 0:
+		sentences = append(sentences, fmt.Sprintf("This column has %s.", strings.Join(counts, " and ")))
+	case hasExamples:
+		sentences = append(sentences, fmt.Sprintf("Examples include %s.", examples))
+	}
+
+	if _, ok := texts["average"]; ok {
+		sentences = append(sentences, fmt.Sprintf("Its values average %.2f.", *data.Average))
+	}
+	if keys, ok := texts["json_keys"]; ok {
+		sentences = append(sentences, fmt.Sprintf("Its JSON objects have the keys %s.", strings.TrimPrefix(keys, "Keys: ")))
+	}
+	if foreignKeys, ok := texts["foreign_keys"]; ok {
+		sentences = append(sentences, fmt.Sprintf("It references %s.", strings.TrimSuffix(strings.TrimPrefix(foreignKeys, "Foreign Keys: ["), "]")))
+	}
+	for _, p := range parts {
+		if _, builtIn := commentPartPriority[p.enrichment]; !builtIn {
+			sentences = append(sentences, asSentence(p.text))
+		}
+	}
+	return strings.Join(sentences, " ")
+}
+
+// asSentence ends text with a period unless it already ends a sentence.
+func asSentence(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasSuffix(text, ".") || strings.HasSuffix(text, "!") || strings.HasSuffix(text, "?") {
+		return text
+	}
+	return text + "."
+}
+
+// countPhrase renders n with the singular or plural noun, or "no" for zero.
+func countPhrase(n int64, singular, plural string) string {
+	switch n {
+	case 0:
+		return "no " + plural
+	case 1:
+		return "1 " + singular
+	default:
+		return fmt.Sprintf("%d %s", n, plural)
+	}
+}
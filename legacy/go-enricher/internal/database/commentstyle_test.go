@@ -0,0 +1,80 @@
+package database
+
+import "testing"
+
+func TestCommentStyles(t *testing.T) {
+	all := map[string]bool{}
+	examples := "Examples: ['paid', 'shipped']"
+	foreignKeys := "Foreign Keys: [customers.id (Customers)]"
+
+	tests := []struct {
+		name        string
+		data        *CommentData
+		enrichments map[string]bool
+		examples    string
+		foreignKeys string
+		want        string
+	}{
+		{
+			name:     "key-value",
+			data:     &CommentData{DistinctCount: 150, NullCount: 5, Description: "Order status"},
+			examples: examples,
+			want:     "Examples: ['paid', 'shipped'] | Distinct: 150 | Nulls: 5 | Order status",
+		},
+		{
+			name:        "sentence with every part",
+			data:        &CommentData{DistinctCount: 150, NullCount: 5, Description: "Order status.", Custom: map[string]string{"pattern": "lowercase"}, Style: CommentStyleSentence},
+			examples:    examples,
+			foreignKeys: foreignKeys,
+			want:        "Order status. This column has 150 distinct values and 5 nulls; examples include 'paid', 'shipped'. It references customers.id (Customers). pattern: lowercase.",
+		},
+		{
+			name:        "sentence ends the description",
+			data:        &CommentData{Description: "Order status", Style: CommentStyleSentence},
+			enrichments: map[string]bool{"description": true},
+			want:        "Order status.",
+		},
+		{
+			name:        "sentence with singular and zero counts",
+			data:        &CommentData{DistinctCount: 1, NullCount: 0, Style: CommentStyleSentence},
+			enrichments: map[string]bool{"distinct_values": true, "null_count": true},
+			want:        "This column has 1 distinct value and no nulls.",
+		},
+		{
+			name:        "sentence with examples only",
+			data:        &CommentData{Style: CommentStyleSentence},
+			enrichments: map[string]bool{"examples": true},
+			examples:    examples,
+			want:        "Examples include 'paid', 'shipped'.",
+		},
+		{
+			name:        "sentence with data type",
+			data:        &CommentData{ColumnDataType: "text", Description: "Order status", Style: CommentStyleSentence},
+			enrichments: map[string]bool{"description": true, "data_type": true},
+			want:        "Order status. Its data type is text.",
+		},
+		{
+			name:        "sentence with average",
+			data:        &CommentData{NullCount: 5, Average: func() *float64 { v := 12.5; return &v }(), Style: CommentStyleSentence},
+			enrichments: map[string]bool{"null_count": true, "average": true},
+			want:        "This column has 5 nulls. Its values average 12.50.",
+		},
+		{
+			name:        "sentence keeps markers as key-value entries",
+			data:        &CommentData{NullCount: 5, Source: "prod", Provenance: true, Style: CommentStyleSentence},
+			enrichments: map[string]bool{"null_count": true},
+			want:        "Source: prod | This column has 5 nulls. | Provenance: computed=null_count",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enrichments := tt.enrichments
+			if enrichments == nil {
+				enrichments = all
+			}
+			if got := GenerateMetadataCommentString(tt.data, enrichments, tt.examples, tt.foreignKeys); got != tt.want {
+				t.Errorf("GenerateMetadataCommentString() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,30 +3,38 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
 )
 
 // DBAdapter defines the interface for database operations needed by the enricher.
 type DBAdapter interface {
-	ListTables() ([]string, error)
-	ListColumns(tableName string) ([]ColumnInfo, error)
+	ListTables(ctx context.Context) ([]string, error)
+	ListColumns(ctx context.Context, tableName string) ([]ColumnInfo, error)
 	GetColumnMetadata(tableName string, columnName string) (map[string]interface{}, error)
 	GetColumnComment(ctx context.Context, tableName string, columnName string) (string, error)
+	GetAllColumnComments(ctx context.Context, tableName string) (map[string]string, error)
 	GetTableComment(ctx context.Context, tableName string) (string, error)
 	GenerateCommentSQL(data *CommentData, enrichments map[string]bool) (string, error)
 	GenerateTableCommentSQL(data *TableCommentData, enrichments map[string]bool) (string, error)
 	GenerateDeleteCommentSQL(ctx context.Context, tableName string, columnName string) (string, error)
 	GenerateDeleteTableCommentSQL(ctx context.Context, tableName string) (string, error)
+	GenerateRewriteCommentSQL(ctx context.Context, tableName string, columnName string, rewrite func(string) string) (string, error)
+	GenerateRewriteTableCommentSQL(ctx context.Context, tableName string, rewrite func(string) string) (string, error)
 	ExecuteSQLStatements(ctx context.Context, sqlStatements []string) error
 	Ping(ctx context.Context) error
 	Close() error
 	GetConfig() config.DatabaseConfig
-	GetForeignKeys(tableName, columnName string) ([]ForeignKeyReference, error)
+	GetForeignKeys(ctx context.Context, tableName, columnName string) ([]ForeignKeyReference, error)
+	TableVersion(ctx context.Context, tableName string) (string, error)
+	JSONKeys(ctx context.Context, tableName string, columnName string) ([]string, error)
 }
 
 var _ DBAdapter = (*DB)(nil)
@@ -36,19 +44,51 @@ type DB struct {
 	Pool    *sql.DB
 	Handler DialectHandler
 	Config  config.DatabaseConfig
+
+	// WritePool, if set, is the primary that statements are applied to, while Pool
+	// is a read replica (--read-host) that all metadata is read from.
+	WritePool *sql.DB
+
+	// columnComments caches GetAllColumnComments results by table so that
+	// per-column lookups during a run don't each cost a round trip.
+	commentsMu     sync.Mutex
+	columnComments map[string]map[string]string
+
+	offline bool // Created by NewOffline; there is no Pool to query.
 }
 
 // ColumnInfo holds basic information about a database column.
 type ColumnInfo struct {
 	Name     string
 	DataType string
+	// BaseType is the type a domain-typed column is based on, e.g. jsonb for a domain
+	// over jsonb, whose DataType names the domain. It is empty for other columns.
+	BaseType string
+	// StatsUnsupported is set for columns whose values cannot be meaningfully sampled
+	// or counted, such as composite-typed columns. They get no statistics.
+	StatsUnsupported bool
+}
+
+// ResolvedType returns the column's BaseType if it has one, and its DataType
+// otherwise, for checks that depend on how the values are stored.
+func (c ColumnInfo) ResolvedType() string {
+	if c.BaseType != "" {
+		return c.BaseType
+	}
+	return c.DataType
 }
 
 // ForeignKeyReference holds information about a foreign key relationship.
 type ForeignKeyReference struct {
-	ReferencedTable  string
-	ReferencedColumn string
-	ConstraintName   string
+	ReferencedTable  string `json:"referenced_table"`
+	ReferencedColumn string `json:"referenced_column"`
+	ConstraintName   string `json:"constraint_name,omitempty"`
+	// ReferencedTableDescription is a short description of ReferencedTable,
+	// filled in by the enricher when one is known.
+	ReferencedTableDescription string `json:"referenced_table_description,omitempty"`
+	// MatchRatio is the fraction of the column's non-null values found in
+	// ReferencedColumn; nil if the dialect cannot check it or the column has no values.
+	MatchRatio *float64 `json:"match_ratio,omitempty"`
 }
 
 // CommentData holds information needed to generate a column comment.
@@ -61,14 +101,30 @@ type CommentData struct {
 	NullCount      int64
 	Description    string
 	ForeignKeys    []ForeignKeyReference
+	Custom         map[string]string // Output of custom enrichments, keyed by enrichment name.
+	Source         string            // Environment marker from --embed-source; set by DB.GenerateCommentSQL.
+	MaxParts       int               // Limit from --max-comment-parts, 0 for no limit; set by DB.GenerateCommentSQL.
+	Provenance     bool              // Record where each part came from (--embed-provenance); set by DB.GenerateCommentSQL.
+	ProfiledAt     time.Time         // When the statistics were queried; zero if they were not.
+	EmbedTimestamp bool              // Record ProfiledAt in the comment (--embed-timestamp); set by DB.GenerateCommentSQL.
+	Style          string            // CommentStyleSentence renders prose (--comment-style); set by DB.GenerateCommentSQL.
+	JSONKeys       []string          // Top-level keys of the objects in a JSON column.
+	IncludeType    bool              // Render ColumnDataType even if data_type is not requested (--comment-include-datatype); set by DB.GenerateCommentSQL.
+	Average        *float64          // Average of a numeric column; nil if it is not numeric or was not queried.
 }
 
 // TableCommentData holds information needed to generate a table comment.
 type TableCommentData struct {
 	TableName   string
 	Description string
+	Source      string // Environment marker from --embed-source; set by DB.GenerateTableCommentSQL.
+	Provenance  bool   // Record where the description came from (--embed-provenance); set by DB.GenerateTableCommentSQL.
 }
 
+// ApplicationName identifies this tool's connections to DBAs, e.g. in pg_stat_activity
+// or sys.dm_exec_sessions. Handlers set it on every connection they open.
+const ApplicationName = "db_schema_enricher"
+
 var (
 	dialectHandlers = make(map[string]DialectHandler)
 	mu              sync.RWMutex
@@ -93,13 +149,41 @@ func GetDialectHandler(dialect string) (DialectHandler, error) {
 	return handler, nil
 }
 
+// New connects to the database in cfg. With cfg.ReadHost set, it also connects to
+// that read replica, which then serves every query while statements are applied
+// to the primary.
 func New(cfg config.DatabaseConfig) (*DB, error) {
 	handler, err := GetDialectHandler(cfg.Dialect)
 	if err != nil {
 		return nil, err
 	}
 
+	pool, err := openPool(handler, cfg)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{
+		Pool:    pool,
+		Handler: handler,
+		Config:  cfg,
+	}
+	if cfg.ReadHost == "" {
+		return db, nil
+	}
+
+	readPool, err := openPool(handler, cfg.ReadReplica())
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("read replica %s: %w", cfg.ReadHost, err)
+	}
+	db.Pool, db.WritePool = readPool, pool
+	return db, nil
+}
+
+// openPool creates the connection pool for cfg and checks that it connects.
+func openPool(handler DialectHandler, cfg config.DatabaseConfig) (*sql.DB, error) {
 	var pool *sql.DB
+	var err error
 	if strings.HasPrefix(cfg.Dialect, "cloudsql") {
 		pool, err = handler.CreateCloudSQLPool(cfg)
 	} else {
@@ -115,14 +199,29 @@ func New(cfg config.DatabaseConfig) (*DB, error) {
 		pool.Close()
 		return nil, fmt.Errorf("failed to connect to database (ping failed) for dialect %s: %w", cfg.Dialect, err)
 	}
+	return pool, nil
+}
 
+// NewOffline returns a DB for the dialect in cfg that has no connection. It can only
+// generate comment SQL: existing comments are treated as empty, and statements that
+// would normally depend on the database state use their "no comment yet" form.
+func NewOffline(cfg config.DatabaseConfig) (*DB, error) {
+	handler, err := GetDialectHandler(cfg.Dialect)
+	if err != nil {
+		return nil, err
+	}
 	return &DB{
-		Pool:    pool,
 		Handler: handler,
 		Config:  cfg,
+		offline: true,
 	}, nil
 }
 
+// Offline reports whether the DB was created with NewOffline.
+func (db *DB) Offline() bool {
+	return db.offline
+}
+
 func (db *DB) GetConfig() config.DatabaseConfig {
 	return db.Config
 }
@@ -131,10 +230,20 @@ func (db *DB) Ping(ctx context.Context) error {
 	if db.Pool == nil {
 		return fmt.Errorf("database connection pool is not initialized")
 	}
+	if db.WritePool != nil {
+		if err := db.WritePool.PingContext(ctx); err != nil {
+			return err
+		}
+	}
 	return db.Pool.PingContext(ctx)
 }
 
 func (db *DB) Close() error {
+	if db.WritePool != nil {
+		if err := db.WritePool.Close(); err != nil {
+			log.Printf("WARN: Failed to close the primary connection pool: %v", err)
+		}
+	}
 	if db.Pool != nil {
 		return db.Pool.Close()
 	}
@@ -142,18 +251,33 @@ func (db *DB) Close() error {
 	return nil
 }
 
-func (db *DB) ListTables() ([]string, error) {
+// writePool returns the pool that statements are applied through: the primary
+// when metadata is read from a replica, and the only pool otherwise.
+func (db *DB) writePool() *sql.DB {
+	if db.WritePool != nil {
+		return db.WritePool
+	}
+	return db.Pool
+}
+
+// ListTables lists the tables of the database, retrying if the connection drops.
+func (db *DB) ListTables(ctx context.Context) ([]string, error) {
 	if db.Handler == nil {
 		return nil, fmt.Errorf("dialect handler not initialized")
 	}
-	return db.Handler.ListTables(db)
+	return retryOnConnLoss(ctx, "listing tables", func() ([]string, error) {
+		return db.Handler.ListTables(db)
+	})
 }
 
-func (db *DB) ListColumns(tableName string) ([]ColumnInfo, error) {
+// ListColumns lists the columns of a table, retrying if the connection drops.
+func (db *DB) ListColumns(ctx context.Context, tableName string) ([]ColumnInfo, error) {
 	if db.Handler == nil {
 		return nil, fmt.Errorf("dialect handler not initialized")
 	}
-	return db.Handler.ListColumns(db, tableName)
+	return retryOnConnLoss(ctx, fmt.Sprintf("listing columns of %s", tableName), func() ([]ColumnInfo, error) {
+		return db.Handler.ListColumns(db, tableName)
+	})
 }
 
 func (db *DB) GetColumnMetadata(tableName string, columnName string) (map[string]interface{}, error) {
@@ -163,11 +287,77 @@ func (db *DB) GetColumnMetadata(tableName string, columnName string) (map[string
 	return db.Handler.GetColumnMetadata(db, tableName, columnName)
 }
 
+// GetColumnComment returns the existing comment on a column. It is answered from
+// the cache when GetAllColumnComments has already been called for the table, and
+// with a single-column query otherwise.
 func (db *DB) GetColumnComment(ctx context.Context, tableName string, columnName string) (string, error) {
 	if db.Handler == nil {
 		return "", fmt.Errorf("dialect handler not initialized")
 	}
-	return db.Handler.GetColumnComment(ctx, db, tableName, columnName)
+	return ExistingColumnComment(ctx, db, db.Handler, tableName, columnName)
+}
+
+// ExistingColumnComment returns the cached comment for a column when its table
+// has been loaded with GetAllColumnComments, and asks the handler otherwise.
+// Handlers use it when merging with or deleting existing comments.
+func ExistingColumnComment(ctx context.Context, db *DB, h DialectHandler, tableName string, columnName string) (string, error) {
+	db.commentsMu.Lock()
+	comments, ok := db.columnComments[tableName]
+	db.commentsMu.Unlock()
+	if ok {
+		return comments[columnName], nil
+	}
+	if db.Offline() {
+		return "", nil
+	}
+	return h.GetColumnComment(ctx, db, tableName, columnName)
+}
+
+// ExistingTableComment returns the existing comment on a table, or "" for an
+// offline DB. Handlers use it when merging with existing comments.
+func ExistingTableComment(ctx context.Context, db *DB, h DialectHandler, tableName string) (string, error) {
+	if db.Offline() {
+		return "", nil
+	}
+	return h.GetTableComment(ctx, db, tableName)
+}
+
+// GetAllColumnComments returns the existing comments on every column of a table,
+// keyed by column name, fetching them in one query the first time a table is
+// requested. Columns without a comment are absent from the map.
+func (db *DB) GetAllColumnComments(ctx context.Context, tableName string) (map[string]string, error) {
+	if db.Handler == nil {
+		return nil, fmt.Errorf("dialect handler not initialized")
+	}
+	db.commentsMu.Lock()
+	comments, ok := db.columnComments[tableName]
+	db.commentsMu.Unlock()
+	if ok {
+		return comments, nil
+	}
+
+	comments, err := db.Handler.GetAllColumnComments(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if comments == nil {
+		comments = map[string]string{}
+	}
+	db.commentsMu.Lock()
+	if db.columnComments == nil {
+		db.columnComments = make(map[string]map[string]string)
+	}
+	db.columnComments[tableName] = comments
+	db.commentsMu.Unlock()
+	return comments, nil
+}
+
+// invalidateCommentCache drops cached column comments after statements that may
+// have changed them.
+func (db *DB) invalidateCommentCache() {
+	db.commentsMu.Lock()
+	db.columnComments = nil
+	db.commentsMu.Unlock()
 }
 
 func (db *DB) GetTableComment(ctx context.Context, tableName string) (string, error) {
@@ -181,6 +371,17 @@ func (db *DB) GenerateCommentSQL(data *CommentData, enrichments map[string]bool)
 	if db.Handler == nil {
 		return "", fmt.Errorf("dialect handler not initialized")
 	}
+	data = encodeCommentData(data, db.Config.CommentEncoding)
+	if data != nil && (db.Config.EmbedSource != "" || db.Config.MaxCommentParts > 0 || db.Config.EmbedProvenance || db.Config.EmbedTimestamp || db.Config.CommentStyle != "" || db.Config.CommentIncludeDataType) {
+		configured := *data
+		configured.Source = db.Config.EmbedSource
+		configured.MaxParts = db.Config.MaxCommentParts
+		configured.Provenance = db.Config.EmbedProvenance
+		configured.EmbedTimestamp = db.Config.EmbedTimestamp
+		configured.Style = db.Config.CommentStyle
+		configured.IncludeType = db.Config.CommentIncludeDataType
+		data = &configured
+	}
 	return db.Handler.GenerateCommentSQL(db, data, enrichments)
 }
 
@@ -188,6 +389,13 @@ func (db *DB) GenerateTableCommentSQL(data *TableCommentData, enrichments map[st
 	if db.Handler == nil {
 		return "", fmt.Errorf("dialect handler not initialized")
 	}
+	data = encodeTableCommentData(data, db.Config.CommentEncoding)
+	if data != nil && (db.Config.EmbedSource != "" || db.Config.EmbedProvenance) {
+		configured := *data
+		configured.Source = db.Config.EmbedSource
+		configured.Provenance = db.Config.EmbedProvenance
+		data = &configured
+	}
 	return db.Handler.GenerateTableCommentSQL(db, data, enrichments)
 }
 
@@ -205,16 +413,70 @@ func (db *DB) GenerateDeleteTableCommentSQL(ctx context.Context, tableName strin
 	return db.Handler.GenerateDeleteTableCommentSQL(ctx, db, tableName)
 }
 
+func (db *DB) GenerateRewriteCommentSQL(ctx context.Context, tableName string, columnName string, rewrite func(string) string) (string, error) {
+	if db.Handler == nil {
+		return "", fmt.Errorf("dialect handler not initialized")
+	}
+	return db.Handler.GenerateRewriteCommentSQL(ctx, db, tableName, columnName, rewrite)
+}
+
+func (db *DB) GenerateRewriteTableCommentSQL(ctx context.Context, tableName string, rewrite func(string) string) (string, error) {
+	if db.Handler == nil {
+		return "", fmt.Errorf("dialect handler not initialized")
+	}
+	return db.Handler.GenerateRewriteTableCommentSQL(ctx, db, tableName, rewrite)
+}
+
+// maxConnRetries is how many times retryOnConnLoss retries a call that failed because
+// the connection dropped, waiting connRetryBackoff and then twice as long before each
+// retry.
+const maxConnRetries = 2
+
+var connRetryBackoff = time.Second
+
+// retryOnConnLoss calls fn, and calls it again, on a new connection from the pool, if
+// it fails because the connection was lost. Other errors are returned at once. what
+// describes the call in the log.
+func retryOnConnLoss[T any](ctx context.Context, what string, fn func() (T, error)) (T, error) {
+	backoff := connRetryBackoff
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil || attempt > maxConnRetries || !IsTransientConnError(err) {
+			return result, err
+		}
+		log.Printf("WARN: Connection lost while %s: %v. Retrying on a new connection (retry %d/%d) in %s...", what, err, attempt, maxConnRetries, backoff)
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// ExecuteSQLStatements applies the statements in one transaction, on the primary
+// when metadata is read from a replica. If the connection drops, the rolled-back
+// transaction is replayed on a new connection from the pool; comment statements set
+// a value, so replaying them is safe.
 func (db *DB) ExecuteSQLStatements(ctx context.Context, sqlStatements []string) error {
-	if db.Pool == nil {
+	if db.writePool() == nil {
 		return fmt.Errorf("database connection pool is not initialized")
 	}
 	if len(sqlStatements) == 0 {
 		log.Println("INFO: No SQL statements provided to ExecuteSQLStatements.")
 		return nil
 	}
+	defer db.invalidateCommentCache()
 
-	tx, err := db.Pool.BeginTx(ctx, nil)
+	_, err := retryOnConnLoss(ctx, fmt.Sprintf("applying %d statements", len(sqlStatements)), func() (struct{}, error) {
+		return struct{}{}, db.executeInTransaction(ctx, sqlStatements)
+	})
+	return err
+}
+
+func (db *DB) executeInTransaction(ctx context.Context, sqlStatements []string) error {
+	tx, err := db.writePool().BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -233,17 +495,143 @@ func (db *DB) ExecuteSQLStatements(ctx context.Context, sqlStatements []string)
 	}
 
 	if err = tx.Commit(); err != nil {
+		var partial *PartialApplyError
+		if errors.As(err, &partial) {
+			log.Printf("ERROR: %d of %d statements were applied before the failure and are not rolled back:\n%s", len(partial.Applied), len(sqlStatements), strings.Join(partial.Applied, "\n"))
+		}
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-// GetForeignKeys retrieves foreign key references for a specific column.
-func (db *DB) GetForeignKeys(tableName, columnName string) ([]ForeignKeyReference, error) {
-	return db.Handler.GetForeignKeys(db, tableName, columnName)
+// GetForeignKeys retrieves foreign key references for a specific column, with the
+// share of its values that match each reference when the dialect can check it (see
+// ForeignKeyMatcher).
+func (db *DB) GetForeignKeys(ctx context.Context, tableName, columnName string) ([]ForeignKeyReference, error) {
+	foreignKeys, err := db.Handler.GetForeignKeys(db, tableName, columnName)
+	if err != nil {
+		return nil, err
+	}
+	matcher, ok := db.Handler.(ForeignKeyMatcher)
+	if !ok || db.Offline() {
+		return foreignKeys, nil
+	}
+	for i, fk := range foreignKeys {
+		ratio, err := foreignKeyMatchRatio(ctx, db, matcher.ForeignKeyMatchQuery(ctx, db, tableName, columnName, fk))
+		if err != nil {
+			log.Printf("WARN: Column[%s.%s] Failed to check the values referencing %s.%s: %v", tableName, columnName, fk.ReferencedTable, fk.ReferencedColumn, err)
+			continue
+		}
+		foreignKeys[i].MatchRatio = ratio
+	}
+	return foreignKeys, nil
+}
+
+// ForeignKeyMatcher is implemented by dialect handlers that can check how many of a
+// column's values exist in the column its foreign key references. That is not
+// guaranteed where constraints are unenforced or were added without validation.
+// ForeignKeyMatchQuery returns the query, usually built with ForeignKeyMatchSQL.
+type ForeignKeyMatcher interface {
+	ForeignKeyMatchQuery(ctx context.Context, db *DB, tableName string, columnName string, fk ForeignKeyReference) string
+}
+
+// ForeignKeyMatchSQL returns a query counting a column's non-null values and how many
+// of them are found in the referenced column. The referenced values are deduplicated
+// first, so a reference to a column that is not unique does not inflate the count.
+// All names must already be quoted.
+func ForeignKeyMatchSQL(quotedTable, quotedColumn, quotedReferencedTable, quotedReferencedColumn string) string {
+	return fmt.Sprintf("SELECT COUNT(*), COUNT(r.referenced_value) FROM %s c LEFT JOIN (SELECT DISTINCT %s AS referenced_value FROM %s) r ON c.%s = r.referenced_value WHERE c.%s IS NOT NULL",
+		quotedTable, quotedReferencedColumn, quotedReferencedTable, quotedColumn, quotedColumn)
 }
 
+// foreignKeyMatchRatio runs a query from ForeignKeyMatchSQL and returns the share of
+// values that matched, or nil when the column has no non-null values.
+func foreignKeyMatchRatio(ctx context.Context, db *DB, query string) (*float64, error) {
+	var total, matched int64
+	if err := db.Pool.QueryRowContext(ctx, query).Scan(&total, &matched); err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, nil
+	}
+	ratio := float64(matched) / float64(total)
+	return &ratio, nil
+}
+
+// TableVersion returns a value that changes whenever the table's data may have
+// changed, or "" when the dialect cannot tell (see TableVersioner).
+func (db *DB) TableVersion(ctx context.Context, tableName string) (string, error) {
+	versioner, ok := db.Handler.(TableVersioner)
+	if !ok || db.Offline() {
+		return "", nil
+	}
+	return versioner.TableVersion(ctx, db, tableName)
+}
+
+// TableVersioner is implemented by dialect handlers whose engine exposes a table
+// change counter, so column statistics collected earlier can be reused for tables
+// that have not changed since. An empty version means unknown.
+type TableVersioner interface {
+	TableVersion(ctx context.Context, db *DB, tableName string) (string, error)
+}
+
+// JSONKeys returns the top-level keys found in the objects of a JSON column, or nil
+// when the dialect cannot list them (see JSONKeySampler).
+func (db *DB) JSONKeys(ctx context.Context, tableName string, columnName string) ([]string, error) {
+	sampler, ok := db.Handler.(JSONKeySampler)
+	if !ok || db.Offline() {
+		return nil, nil
+	}
+	return sampler.JSONKeys(ctx, db, tableName, columnName)
+}
+
+// JSONKeySampler is implemented by dialect handlers that can list the keys of JSON
+// objects stored in a column, for the json_keys enrichment. Only the first
+// JSONKeySampleRows non-null values are read, and at most MaxJSONKeys keys returned.
+type JSONKeySampler interface {
+	JSONKeys(ctx context.Context, db *DB, tableName string, columnName string) ([]string, error)
+}
+
+// Capabilities reports, for the built-in enrichments that only some dialects can
+// collect, whether the dialect's handler supports them. Enrichments not listed are
+// supported by every dialect.
+func Capabilities(dialect string) (map[string]bool, error) {
+	handler, err := GetDialectHandler(dialect)
+	if err != nil {
+		return nil, err
+	}
+	_, jsonKeys := handler.(JSONKeySampler)
+	return map[string]bool{"json_keys": jsonKeys}, nil
+}
+
+// ValidateEnrichments rejects enrichments requested by name that the dialect cannot
+// collect, so the run fails up front instead of leaving them out column by column.
+// Enrichments included only through "all" are skipped where unsupported, as before.
+func ValidateEnrichments(dialect string, enrichments map[string]bool) error {
+	capabilities, err := Capabilities(dialect)
+	if err != nil {
+		return err
+	}
+	var unsupported []string
+	for name, requested := range enrichments {
+		if supported, limited := capabilities[name]; requested && limited && !supported {
+			unsupported = append(unsupported, name)
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	sort.Strings(unsupported)
+	return fmt.Errorf("enrichment(s) %s are not supported for dialect %s", strings.Join(unsupported, ", "), dialect)
+}
+
+// JSONKeySampleRows is how many values of a JSON column are read for its keys.
+const JSONKeySampleRows = 1000
+
+// MaxJSONKeys is how many keys of a JSON column are listed in its comment.
+const MaxJSONKeys = 20
+
 // DialectHandler interface remains the same
 type DialectHandler interface {
 	CreateCloudSQLPool(cfg config.DatabaseConfig) (*sql.DB, error)
@@ -254,9 +642,12 @@ type DialectHandler interface {
 	GetForeignKeys(db *DB, tableName string, columnName string) ([]ForeignKeyReference, error)
 	GetColumnMetadata(db *DB, tableName string, columnName string) (map[string]interface{}, error)
 	GetColumnComment(ctx context.Context, db *DB, tableName string, columnName string) (string, error)
+	GetAllColumnComments(ctx context.Context, db *DB, tableName string) (map[string]string, error)
 	GetTableComment(ctx context.Context, db *DB, tableName string) (string, error)
 	GenerateCommentSQL(db *DB, data *CommentData, enrichments map[string]bool) (string, error)
 	GenerateTableCommentSQL(db *DB, data *TableCommentData, enrichments map[string]bool) (string, error)
 	GenerateDeleteCommentSQL(ctx context.Context, db *DB, tableName string, columnName string) (string, error)
 	GenerateDeleteTableCommentSQL(ctx context.Context, db *DB, tableName string) (string, error)
+	GenerateRewriteCommentSQL(ctx context.Context, db *DB, tableName string, columnName string, rewrite func(string) string) (string, error)
+	GenerateRewriteTableCommentSQL(ctx context.Context, db *DB, tableName string, rewrite func(string) string) (string, error)
 }
@@ -5,8 +5,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
@@ -21,6 +25,7 @@ type mockDialectHandler struct {
 	listColumnsFn              func(db *DB, tableName string) ([]ColumnInfo, error)
 	getColumnMetadataFn        func(db *DB, tableName string, columnName string) (map[string]interface{}, error)
 	getColumnCommentFn         func(ctx context.Context, db *DB, tableName string, columnName string) (string, error)
+	getAllColumnCommentsFn     func(ctx context.Context, db *DB, tableName string) (map[string]string, error)
 	getTableCommentFn          func(ctx context.Context, db *DB, tableName string) (string, error)
 	genCommentSQLFn            func(db *DB, data *CommentData, enrichments map[string]bool) (string, error)
 	genTableCommentSQLFn       func(db *DB, data *TableCommentData, enrichments map[string]bool) (string, error)
@@ -32,11 +37,14 @@ type mockDialectHandler struct {
 	listTablesCalls               int
 	listColumnsCalls              int
 	getColumnCommentCalls         int
+	getAllColumnCommentsCalls     int
 	getTableCommentCalls          int
 	genCommentSQLCalls            int
 	genTableCommentSQLCalls       int
 	genDeleteCommentSQLCalls      int
 	genDeleteTableCommentSQLCalls int
+	genRewriteCommentSQLCalls     int
+	genRewriteTableCommentSQLCalls int
 	getColumnMetadataCalls        int
 }
 
@@ -103,6 +111,16 @@ func (m *mockDialectHandler) GetColumnComment(ctx context.Context, db *DB, table
 	return "mock comment", nil
 }
 
+func (m *mockDialectHandler) GetAllColumnComments(ctx context.Context, db *DB, tableName string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.getAllColumnCommentsCalls++
+	if m.getAllColumnCommentsFn != nil {
+		return m.getAllColumnCommentsFn(ctx, db, tableName)
+	}
+	return map[string]string{"c1": "mock comment"}, nil
+}
+
 func (m *mockDialectHandler) GetTableComment(ctx context.Context, db *DB, tableName string) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -153,6 +171,20 @@ func (m *mockDialectHandler) GenerateDeleteTableCommentSQL(ctx context.Context,
 	return "DELETE TABLE COMMENT mock", nil
 }
 
+func (m *mockDialectHandler) GenerateRewriteCommentSQL(ctx context.Context, db *DB, tableName string, columnName string, rewrite func(string) string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.genRewriteCommentSQLCalls++
+	return "REWRITE COMMENT mock", nil
+}
+
+func (m *mockDialectHandler) GenerateRewriteTableCommentSQL(ctx context.Context, db *DB, tableName string, rewrite func(string) string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.genRewriteTableCommentSQLCalls++
+	return "REWRITE TABLE COMMENT mock", nil
+}
+
 
 func (m *mockDialectHandler) GetForeignKeys(db *DB, tableName string, columnName string) ([]ForeignKeyReference, error) {
 	m.mu.Lock()
@@ -182,11 +214,14 @@ func (m *mockDialectHandler) Reset() {
 	m.listColumnsCalls = 0
 	m.getColumnMetadataCalls = 0
 	m.getColumnCommentCalls = 0
+	m.getAllColumnCommentsCalls = 0
 	m.getTableCommentCalls = 0
 	m.genCommentSQLCalls = 0
 	m.genTableCommentSQLCalls = 0
 	m.genDeleteCommentSQLCalls = 0
 	m.genDeleteTableCommentSQLCalls = 0
+	m.genRewriteCommentSQLCalls = 0
+	m.genRewriteTableCommentSQLCalls = 0
 }
 
 func TestRegisterAndGetDialectHandler(t *testing.T) {
@@ -274,8 +309,8 @@ func TestDBMethodsDelegateToHandler(t *testing.T) {
 		dbMethodCall  func() error // Function to call the DB method
 		expectedCalls *int         // Pointer to the mock handler's call counter
 	}{
-		{"ListTables", func() error { _, err := db.ListTables(); return err }, &mockHandler.listTablesCalls},
-		{"ListColumns", func() error { _, err := db.ListColumns("t1"); return err }, &mockHandler.listColumnsCalls},
+		{"ListTables", func() error { _, err := db.ListTables(context.Background()); return err }, &mockHandler.listTablesCalls},
+		{"ListColumns", func() error { _, err := db.ListColumns(context.Background(), "t1"); return err }, &mockHandler.listColumnsCalls},
 		{"GetColumnMetadata", func() error { _, err := db.GetColumnMetadata("t1", "c1"); return err }, &mockHandler.getColumnMetadataCalls},
 		{"GetColumnComment", func() error { _, err := db.GetColumnComment(ctx, "t1", "c1"); return err }, &mockHandler.getColumnCommentCalls},
 		{"GetTableComment", func() error { _, err := db.GetTableComment(ctx, "t1"); return err }, &mockHandler.getTableCommentCalls},
@@ -283,6 +318,8 @@ func TestDBMethodsDelegateToHandler(t *testing.T) {
 		{"GenerateTableCommentSQL", func() error { _, err := db.GenerateTableCommentSQL(&TableCommentData{}, nil); return err }, &mockHandler.genTableCommentSQLCalls},
 		{"GenerateDeleteCommentSQL", func() error { _, err := db.GenerateDeleteCommentSQL(ctx, "t1", "c1"); return err }, &mockHandler.genDeleteCommentSQLCalls},
 		{"GenerateDeleteTableCommentSQL", func() error { _, err := db.GenerateDeleteTableCommentSQL(ctx, "t1"); return err }, &mockHandler.genDeleteTableCommentSQLCalls},
+		{"GenerateRewriteCommentSQL", func() error { _, err := db.GenerateRewriteCommentSQL(ctx, "t1", "c1", RepairComment); return err }, &mockHandler.genRewriteCommentSQLCalls},
+		{"GenerateRewriteTableCommentSQL", func() error { _, err := db.GenerateRewriteTableCommentSQL(ctx, "t1", RepairComment); return err }, &mockHandler.genRewriteTableCommentSQLCalls},
 	}
 
 	for _, tt := range tests {
@@ -319,8 +356,168 @@ func TestDBMethodsDelegateToHandler(t *testing.T) {
 	}
 }
 
+func TestColumnCommentCache(t *testing.T) {
+	mockHandler := &mockDialectHandler{
+		getAllColumnCommentsFn: func(ctx context.Context, db *DB, tableName string) (map[string]string, error) {
+			return map[string]string{"email": "Login email"}, nil
+		},
+	}
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() failed: %v", err)
+	}
+	db := &DB{Pool: mockDB, Handler: mockHandler}
+	defer db.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		comments, err := db.GetAllColumnComments(ctx, "users")
+		if err != nil {
+			t.Fatalf("GetAllColumnComments() unexpected error: %v", err)
+		}
+		if comments["email"] != "Login email" {
+			t.Errorf("GetAllColumnComments() = %v, want email comment", comments)
+		}
+	}
+	if mockHandler.getAllColumnCommentsCalls != 1 {
+		t.Errorf("expected one bulk comment query, got %d", mockHandler.getAllColumnCommentsCalls)
+	}
+
+	// Per-column lookups for a cached table are served without querying.
+	for column, want := range map[string]string{"email": "Login email", "id": ""} {
+		got, err := db.GetColumnComment(ctx, "users", column)
+		if err != nil {
+			t.Fatalf("GetColumnComment() unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("GetColumnComment(%q) = %q, want %q", column, got, want)
+		}
+	}
+	if mockHandler.getColumnCommentCalls != 0 {
+		t.Errorf("expected cached lookups, got %d GetColumnComment calls", mockHandler.getColumnCommentCalls)
+	}
+
+	// Other tables still fall back to the handler.
+	if _, err := db.GetColumnComment(ctx, "orders", "id"); err != nil {
+		t.Fatalf("GetColumnComment() unexpected error: %v", err)
+	}
+	if mockHandler.getColumnCommentCalls != 1 {
+		t.Errorf("expected uncached table to query the handler, got %d calls", mockHandler.getColumnCommentCalls)
+	}
+
+	// Applying statements invalidates the cache.
+	mock.ExpectBegin()
+	mock.ExpectExec("COMMENT ON").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	if err := db.ExecuteSQLStatements(ctx, []string{"COMMENT ON COLUMN users.email IS 'x';"}); err != nil {
+		t.Fatalf("ExecuteSQLStatements() unexpected error: %v", err)
+	}
+	if _, err := db.GetAllColumnComments(ctx, "users"); err != nil {
+		t.Fatalf("GetAllColumnComments() unexpected error: %v", err)
+	}
+	if mockHandler.getAllColumnCommentsCalls != 2 {
+		t.Errorf("expected a fresh bulk query after applying statements, got %d", mockHandler.getAllColumnCommentsCalls)
+	}
+}
+
+func TestListTablesAndColumnsRetryTransientErrors(t *testing.T) {
+	defer func(backoff time.Duration) { connRetryBackoff = backoff }(connRetryBackoff)
+	connRetryBackoff = 0
+	connReset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+
+	mockHandler := &mockDialectHandler{}
+	mockHandler.listTablesFn = func(db *DB) ([]string, error) {
+		if mockHandler.listTablesCalls == 1 {
+			return nil, connReset
+		}
+		return []string{"orders"}, nil
+	}
+	mockHandler.listColumnsFn = func(db *DB, tableName string) ([]ColumnInfo, error) {
+		if mockHandler.listColumnsCalls == 1 {
+			return nil, connReset
+		}
+		return []ColumnInfo{{Name: "total", DataType: "int"}}, nil
+	}
+	db, _ := newTestDBWithMockHandler(t, mockHandler)
+	defer db.Close()
+
+	tables, err := db.ListTables(context.Background())
+	if err != nil || len(tables) != 1 || tables[0] != "orders" {
+		t.Errorf("ListTables() = %v, %v; want [orders], nil", tables, err)
+	}
+	if mockHandler.listTablesCalls != 2 {
+		t.Errorf("ListTables() called the handler %d times, want 2", mockHandler.listTablesCalls)
+	}
+	columns, err := db.ListColumns(context.Background(), "orders")
+	if err != nil || len(columns) != 1 || columns[0].Name != "total" {
+		t.Errorf("ListColumns() = %v, %v; want [total], nil", columns, err)
+	}
+	if mockHandler.listColumnsCalls != 2 {
+		t.Errorf("ListColumns() called the handler %d times, want 2", mockHandler.listColumnsCalls)
+	}
+}
+
+func TestListColumnsDoesNotRetryOtherErrors(t *testing.T) {
+	mockHandler := &mockDialectHandler{}
+	mockHandler.listColumnsFn = func(db *DB, tableName string) ([]ColumnInfo, error) {
+		return nil, errors.New("permission denied")
+	}
+	db, _ := newTestDBWithMockHandler(t, mockHandler)
+	defer db.Close()
+
+	if _, err := db.ListColumns(context.Background(), "orders"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("ListColumns() error = %v, want permission denied", err)
+	}
+	if mockHandler.listColumnsCalls != 1 {
+		t.Errorf("ListColumns() called the handler %d times, want 1", mockHandler.listColumnsCalls)
+	}
+}
+
+func TestListTablesGivesUpAfterRetries(t *testing.T) {
+	defer func(backoff time.Duration) { connRetryBackoff = backoff }(connRetryBackoff)
+	connRetryBackoff = 0
+
+	mockHandler := &mockDialectHandler{}
+	mockHandler.listTablesFn = func(db *DB) ([]string, error) {
+		return nil, syscall.ECONNRESET
+	}
+	db, _ := newTestDBWithMockHandler(t, mockHandler)
+	defer db.Close()
+
+	if _, err := db.ListTables(context.Background()); !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("ListTables() error = %v, want ECONNRESET", err)
+	}
+	if mockHandler.listTablesCalls != maxConnRetries+1 {
+		t.Errorf("ListTables() called the handler %d times, want %d", mockHandler.listTablesCalls, maxConnRetries+1)
+	}
+}
+
+func TestListTablesStopsRetryingWhenCancelled(t *testing.T) {
+	defer func(backoff time.Duration) { connRetryBackoff = backoff }(connRetryBackoff)
+	connRetryBackoff = time.Hour
+
+	mockHandler := &mockDialectHandler{}
+	mockHandler.listTablesFn = func(db *DB) ([]string, error) {
+		return nil, syscall.ECONNRESET
+	}
+	db, _ := newTestDBWithMockHandler(t, mockHandler)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.ListTables(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListTables() error = %v, want context.Canceled", err)
+	}
+	if mockHandler.listTablesCalls != 1 {
+		t.Errorf("ListTables() called the handler %d times, want 1", mockHandler.listTablesCalls)
+	}
+}
+
 func TestExecuteSQLStatements(t *testing.T) {
 	ctx := context.Background()
+	defer func(backoff time.Duration) { connRetryBackoff = backoff }(connRetryBackoff)
+	connRetryBackoff = 0
+	connReset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
 
 	tests := []struct {
 		name          string
@@ -385,6 +582,33 @@ func TestExecuteSQLStatements(t *testing.T) {
 			},
 			expectedError: true,
 		},
+		{
+			name:          "Connection reset succeeds on retry",
+			sqlStatements: []string{"SELECT 1;", "SELECT 2;"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("SELECT 1;").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("SELECT 2;").WillReturnError(connReset)
+				mock.ExpectRollback()
+				mock.ExpectBegin()
+				mock.ExpectExec("SELECT 1;").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("SELECT 2;").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			expectedError: false,
+		},
+		{
+			name:          "Connection reset on every attempt",
+			sqlStatements: []string{"SELECT 1;"},
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				for i := 0; i <= maxConnRetries; i++ {
+					mock.ExpectBegin()
+					mock.ExpectExec("SELECT 1;").WillReturnError(connReset)
+					mock.ExpectRollback()
+				}
+			},
+			expectedError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -412,3 +636,99 @@ func TestExecuteSQLStatements(t *testing.T) {
 		})
 	}
 }
+
+func TestNewWithReadReplica(t *testing.T) {
+	primaryDb, primary, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	replicaDb, replica, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	handler := &mockDialectHandler{
+		createStandardPoolFn: func(cfg config.DatabaseConfig) (*sql.DB, error) {
+			switch {
+			case cfg.Host == "primary.internal" && cfg.Port == 5432:
+				return primaryDb, nil
+			case cfg.Host == "replica.internal" && cfg.Port == 5433:
+				return replicaDb, nil
+			}
+			return nil, fmt.Errorf("unexpected connection to %s:%d", cfg.Host, cfg.Port)
+		},
+		listTablesFn: func(db *DB) ([]string, error) {
+			var name string
+			if err := db.Pool.QueryRow("SELECT name FROM tables").Scan(&name); err != nil {
+				return nil, err
+			}
+			return []string{name}, nil
+		},
+	}
+	RegisterDialectHandler("replicatest", handler)
+	defer func() {
+		mu.Lock()
+		delete(dialectHandlers, "replicatest")
+		mu.Unlock()
+	}()
+
+	// Each mock fails any statement it does not expect, so a read sent to the
+	// primary or a write sent to the replica fails the test.
+	replica.ExpectQuery("SELECT name FROM tables").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("orders"))
+	primary.ExpectBegin()
+	primary.ExpectExec("COMMENT ON orders").WillReturnResult(sqlmock.NewResult(0, 0))
+	primary.ExpectCommit()
+	primary.ExpectClose()
+	replica.ExpectClose()
+
+	db, err := New(config.DatabaseConfig{Dialect: "replicatest", Host: "primary.internal", Port: 5432, ReadHost: "replica.internal", ReadPort: 5433})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	tables, err := db.ListTables(context.Background())
+	if err != nil || len(tables) != 1 || tables[0] != "orders" {
+		t.Errorf("ListTables() = %v, %v, want [orders] from the replica", tables, err)
+	}
+	if err := db.ExecuteSQLStatements(context.Background(), []string{"COMMENT ON orders"}); err != nil {
+		t.Errorf("ExecuteSQLStatements() unexpected error: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("Close() unexpected error: %v", err)
+	}
+
+	if err := primary.ExpectationsWereMet(); err != nil {
+		t.Errorf("primary: there were unfulfilled expectations: %s", err)
+	}
+	if err := replica.ExpectationsWereMet(); err != nil {
+		t.Errorf("replica: there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestNewClosesPrimaryWhenReplicaFails(t *testing.T) {
+	primaryDb, primary, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	handler := &mockDialectHandler{
+		createStandardPoolFn: func(cfg config.DatabaseConfig) (*sql.DB, error) {
+			if cfg.Host == "replica.internal" {
+				return nil, errors.New("connection refused")
+			}
+			return primaryDb, nil
+		},
+	}
+	RegisterDialectHandler("replicatest", handler)
+	defer func() {
+		mu.Lock()
+		delete(dialectHandlers, "replicatest")
+		mu.Unlock()
+	}()
+	primary.ExpectClose()
+
+	_, err = New(config.DatabaseConfig{Dialect: "replicatest", Host: "primary.internal", Port: 5432, ReadHost: "replica.internal", ReadPort: 5432})
+	if err == nil || !strings.Contains(err.Error(), "read replica replica.internal") {
+		t.Errorf("New() error = %v, want a read replica error", err)
+	}
+	if err := primary.ExpectationsWereMet(); err != nil {
+		t.Errorf("primary was not closed: %s", err)
+	}
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Comment encodings accepted by --comment-encoding. The empty string means UTF-8.
+const (
+	CommentEncodingUTF8   = "utf8"
+	CommentEncodingLatin1 = "latin1"
+	CommentEncodingASCII  = "ascii"
+)
+
+// commentEncodingLimits holds the highest code point each encoding can represent.
+var commentEncodingLimits = map[string]rune{
+	CommentEncodingUTF8:   unicode.MaxRune,
+	CommentEncodingLatin1: 0xFF,
+	CommentEncodingASCII:  0x7F,
+}
+
+// punctuationFallbacks are the ASCII spellings used for common typographic
+// characters that have no equivalent in the target charset.
+var punctuationFallbacks = map[rune]string{
+	'‘': "'", '’': "'", '‚': "'", '“': `"`, '”': `"`, '„': `"`,
+	'–': "-", '—': "-", '−': "-", '…': "...", '•': "*", '\u00a0': " ",
+}
+
+// ValidateCommentEncoding checks a --comment-encoding value.
+func ValidateCommentEncoding(encoding string) error {
+	if encoding == "" {
+		return nil
+	}
+	if _, ok := commentEncodingLimits[strings.ToLower(encoding)]; !ok {
+		return fmt.Errorf("invalid value for --comment-encoding: '%s'. Must be 'utf8', 'latin1' or 'ascii'", encoding)
+	}
+	return nil
+}
+
+// EncodeComment converts generated comment text so that it can be stored in a
+// column of the given charset. Text is NFC-normalized; characters the charset
+// cannot hold are transliterated by dropping accents or using an ASCII spelling,
+// and replaced with '?' as a last resort, in which case lossy is true.
+func EncodeComment(text string, encoding string) (encoded string, lossy bool) {
+	limit, ok := commentEncodingLimits[strings.ToLower(encoding)]
+	if !ok || limit == unicode.MaxRune {
+		return text, false
+	}
+	text = norm.NFC.String(text)
+
+	var sb strings.Builder
+	for _, r := range text {
+		if r <= limit {
+			sb.WriteRune(r)
+			continue
+		}
+		if fallback, ok := punctuationFallbacks[r]; ok {
+			sb.WriteString(fallback)
+			continue
+		}
+		if base, ok := stripAccents(r, limit); ok {
+			sb.WriteString(base)
+			continue
+		}
+		sb.WriteRune('?')
+		lossy = true
+	}
+	return sb.String(), lossy
+}
+
+// stripAccents decomposes r and drops its combining marks, succeeding only when
+// what remains fits within limit.
+func stripAccents(r rune, limit rune) (string, bool) {
+	var sb strings.Builder
+	for _, d := range norm.NFD.String(string(r)) {
+		if unicode.Is(unicode.Mn, d) {
+			continue
+		}
+		if d > limit {
+			return "", false
+		}
+		sb.WriteRune(d)
+	}
+	return sb.String(), sb.Len() > 0
+}
+
+// encodeCommentData returns a copy of data whose generated text is converted to
+// encoding, warning when characters had to be replaced.
+func encodeCommentData(data *CommentData, encoding string) *CommentData {
+	if data == nil || encoding == "" {
+		return data
+	}
+	encoded := *data
+	anyLossy := false
+	encode := func(s string) string {
+		out, lossy := EncodeComment(s, encoding)
+		anyLossy = anyLossy || lossy
+		return out
+	}
+
+	encoded.Description = encode(data.Description)
+	if data.ExampleValues != nil {
+		encoded.ExampleValues = make([]string, len(data.ExampleValues))
+		for i, v := range data.ExampleValues {
+			encoded.ExampleValues[i] = encode(v)
+		}
+	}
+	if data.ForeignKeys != nil {
+		encoded.ForeignKeys = make([]ForeignKeyReference, len(data.ForeignKeys))
+		for i, fk := range data.ForeignKeys {
+			fk.ReferencedTableDescription = encode(fk.ReferencedTableDescription)
+			encoded.ForeignKeys[i] = fk
+		}
+	}
+	if data.Custom != nil {
+		encoded.Custom = make(map[string]string, len(data.Custom))
+		for name, v := range data.Custom {
+			encoded.Custom[name] = encode(v)
+		}
+	}
+	if anyLossy {
+		log.Printf("WARN: Column[%s.%s] Comment contains characters that cannot be represented in %s; they were replaced with '?'.", data.TableName, data.ColumnName, encoding)
+	}
+	return &encoded
+}
+
+// encodeTableCommentData is encodeCommentData for table comments.
+func encodeTableCommentData(data *TableCommentData, encoding string) *TableCommentData {
+	if data == nil || encoding == "" {
+		return data
+	}
+	encoded := *data
+	var lossy bool
+	encoded.Description, lossy = EncodeComment(data.Description, encoding)
+	if lossy {
+		log.Printf("WARN: Table[%s] Comment contains characters that cannot be represented in %s; they were replaced with '?'.", data.TableName, encoding)
+	}
+	return &encoded
+}
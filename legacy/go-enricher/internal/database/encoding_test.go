@@ -0,0 +1,92 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+)
+
+func TestEncodeComment(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		encoding  string
+		expected  string
+		wantLossy bool
+	}{
+		{"utf8 keeps everything", "Café 東京 “quoted”", "utf8", "Café 東京 “quoted”", false},
+		{"empty encoding keeps everything", "Café 東京", "", "Café 東京", false},
+		{"latin1 keeps accents", "Café in São Paulo", "latin1", "Café in São Paulo", false},
+		{"latin1 normalizes decomposed accents", "Cafe\u0301", "latin1", "Caf\u00e9", false},
+		{"latin1 strips accents it cannot hold", "Łódź and Dvořák", "latin1", "?ódz and Dvorák", true},
+		{"latin1 replaces punctuation", "“Net” total – in €…", "latin1", `"Net" total - in ?...`, true},
+		{"ascii strips accents", "Crème brûlée, naïve", "ascii", "Creme brulee, naive", false},
+		{"ascii replaces multibyte text", "Tokyo 東京 office", "ascii", "Tokyo ?? office", true},
+		{"encoding name is case-insensitive", "Crème", "ASCII", "Creme", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, lossy := EncodeComment(tt.text, tt.encoding)
+			if got != tt.expected || lossy != tt.wantLossy {
+				t.Errorf("EncodeComment(%q, %q) = %q, %v; want %q, %v", tt.text, tt.encoding, got, lossy, tt.expected, tt.wantLossy)
+			}
+		})
+	}
+}
+
+func TestValidateCommentEncoding(t *testing.T) {
+	for _, encoding := range []string{"", "utf8", "latin1", "ASCII"} {
+		if err := ValidateCommentEncoding(encoding); err != nil {
+			t.Errorf("ValidateCommentEncoding(%q) unexpected error: %v", encoding, err)
+		}
+	}
+	if err := ValidateCommentEncoding("ebcdic"); err == nil {
+		t.Error("ValidateCommentEncoding(\"ebcdic\") expected error, got nil")
+	}
+}
+
+func TestGenerateCommentSQLEncodesComment(t *testing.T) {
+	var column *CommentData
+	var table *TableCommentData
+	handler := &mockDialectHandler{
+		genCommentSQLFn: func(db *DB, data *CommentData, enrichments map[string]bool) (string, error) {
+			column = data
+			return "", nil
+		},
+		genTableCommentSQLFn: func(db *DB, data *TableCommentData, enrichments map[string]bool) (string, error) {
+			table = data
+			return "", nil
+		},
+	}
+	db := &DB{Handler: handler, Config: config.DatabaseConfig{CommentEncoding: "ascii"}}
+
+	data := &CommentData{
+		TableName:     "cafés",
+		ColumnName:    "naïve",
+		Description:   "Crème brûlée",
+		ExampleValues: []string{"Zürich"},
+		ForeignKeys:   []ForeignKeyReference{{ReferencedTable: "villes", ReferencedTableDescription: "Ville française"}},
+		Custom:        map[string]string{"owner": "José"},
+	}
+	if _, err := db.GenerateCommentSQL(data, nil); err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	if _, err := db.GenerateTableCommentSQL(&TableCommentData{TableName: "cafés", Description: "Cafés à Paris"}, nil); err != nil {
+		t.Fatalf("GenerateTableCommentSQL() unexpected error: %v", err)
+	}
+
+	if column.Description != "Creme brulee" || column.ExampleValues[0] != "Zurich" ||
+		column.ForeignKeys[0].ReferencedTableDescription != "Ville francaise" || column.Custom["owner"] != "Jose" {
+		t.Errorf("GenerateCommentSQL() passed %+v, want generated text converted to ascii", column)
+	}
+	if column.TableName != "cafés" || column.ColumnName != "naïve" {
+		t.Errorf("GenerateCommentSQL() changed identifiers to %s.%s", column.TableName, column.ColumnName)
+	}
+	if data.Description != "Crème brûlée" {
+		t.Errorf("GenerateCommentSQL() modified the caller's data: %q", data.Description)
+	}
+	if table.Description != "Cafes a Paris" {
+		t.Errorf("GenerateTableCommentSQL() description = %q, want %q", table.Description, "Cafes a Paris")
+	}
+}
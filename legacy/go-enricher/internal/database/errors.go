@@ -0,0 +1,56 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+)
+
+// PermissionError reports that a metadata query was rejected because the
+// connected user lacks the privileges needed to read a table.
+type PermissionError struct {
+	Table string
+	Grant string // Suggested statement that would grant the missing privilege.
+	Err   error
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("insufficient privileges on table %s: %v. Ask an administrator to run: %s", e.Table, e.Err, e.Grant)
+}
+
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// PartialApplyError reports that applying statements failed after some of them had
+// already taken effect, on databases whose DDL is not transactional, such as BigQuery.
+// The applied statements are not rolled back.
+type PartialApplyError struct {
+	Applied []string
+	Failed  string
+	Err     error
+}
+
+func (e *PartialApplyError) Error() string {
+	return fmt.Sprintf("%d statement(s) were applied before %q failed: %v", len(e.Applied), e.Failed, e.Err)
+}
+
+func (e *PartialApplyError) Unwrap() error {
+	return e.Err
+}
+
+// IsTransientConnError reports whether err means the connection was lost, so the same
+// statements or queries may succeed on a new one.
+func IsTransientConnError(err error) bool {
+	for _, transient := range []error{driver.ErrBadConn, sql.ErrConnDone, io.EOF, io.ErrUnexpectedEOF, syscall.ECONNRESET, syscall.ECONNABORTED, syscall.EPIPE} {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -1,7 +1,13 @@
 package database
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
 	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
 )
 
 // TestForeignKeyReference verifies the ForeignKeyReference struct is properly defined
@@ -62,3 +68,73 @@ func TestDBAdapterInterface(t *testing.T) {
 	// Test passes if compilation succeeds
 	t.Log("DB struct successfully implements DBAdapter interface with GetForeignKeys method")
 }
+
+// matchingDialectHandler adds ForeignKeyMatcher to the mock handler.
+type matchingDialectHandler struct {
+	*mockDialectHandler
+}
+
+func (h matchingDialectHandler) ForeignKeyMatchQuery(ctx context.Context, db *DB, tableName string, columnName string, fk ForeignKeyReference) string {
+	return ForeignKeyMatchSQL(tableName, columnName, fk.ReferencedTable, fk.ReferencedColumn)
+}
+
+func TestGetForeignKeysMatchRatio(t *testing.T) {
+	matchQuery := regexp.QuoteMeta("SELECT COUNT(*), COUNT(r.referenced_value) FROM orders c LEFT JOIN (SELECT DISTINCT id AS referenced_value FROM users) r ON c.user_id = r.referenced_value WHERE c.user_id IS NOT NULL")
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+		want   string
+	}{
+		{"ratio", func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(matchQuery).WillReturnRows(sqlmock.NewRows([]string{"total", "matched"}).AddRow(200, 197))
+		}, "98.50%"},
+		{"no values", func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(matchQuery).WillReturnRows(sqlmock.NewRows([]string{"total", "matched"}).AddRow(0, 0))
+		}, ""},
+		{"query fails", func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(matchQuery).WillReturnError(errors.New("permission denied"))
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := matchingDialectHandler{&mockDialectHandler{
+				getForeignKeysFn: func(db *DB, tableName string, columnName string) ([]ForeignKeyReference, error) {
+					return []ForeignKeyReference{{ReferencedTable: "users", ReferencedColumn: "id"}}, nil
+				},
+			}}
+			db, mock := newTestDBWithMockHandler(t, handler)
+			defer db.Close()
+			mock.MatchExpectationsInOrder(false) // The helper also expects a ping.
+			tt.expect(mock)
+
+			fks, err := db.GetForeignKeys(context.Background(), "orders", "user_id")
+			if err != nil {
+				t.Fatalf("GetForeignKeys() unexpected error: %v", err)
+			}
+			got := ""
+			if fks[0].MatchRatio != nil {
+				got = fmt.Sprintf("%.2f%%", *fks[0].MatchRatio*100)
+			}
+			if got != tt.want {
+				t.Errorf("GetForeignKeys() match ratio = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetForeignKeysWithoutMatcher(t *testing.T) {
+	db, _ := newTestDBWithMockHandler(t, &mockDialectHandler{
+		getForeignKeysFn: func(db *DB, tableName string, columnName string) ([]ForeignKeyReference, error) {
+			return []ForeignKeyReference{{ReferencedTable: "users", ReferencedColumn: "id"}}, nil
+		},
+	})
+	defer db.Close()
+
+	fks, err := db.GetForeignKeys(context.Background(), "orders", "user_id")
+	if err != nil {
+		t.Fatalf("GetForeignKeys() unexpected error: %v", err)
+	}
+	if fks[0].MatchRatio != nil {
+		t.Errorf("GetForeignKeys() match ratio = %v, want nil for a handler without ForeignKeyMatcher", *fks[0].MatchRatio)
+	}
+}
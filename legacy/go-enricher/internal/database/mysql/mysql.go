@@ -3,9 +3,11 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/url"
 	"strings"
 
 	"cloud.google.com/go/cloudsqlconn"
@@ -77,18 +79,41 @@ func (h mysqlHandler) CreateCloudSQLPool(cfg config.DatabaseConfig) (*sql.DB, er
 		DBName:               dbName,
 		AllowNativePasswords: true,
 		ParseTime:            true,
+		ConnectionAttributes: programNameAttribute,
 	}
 
-	dbPool, err := sql.Open("mysql", mysqlCfg.FormatDSN())
+	// A connector keeps ConnectionAttributes, which FormatDSN leaves out.
+	connector, err := mysql.NewConnector(&mysqlCfg)
 	if err != nil {
 		mysql.DeregisterDialContext(network)
 		d.Close()
-		return nil, fmt.Errorf("sql.Open failed for CloudSQL MySQL: %w", err)
+		return nil, fmt.Errorf("mysql.NewConnector failed for CloudSQL MySQL: %w", err)
 	}
-	return dbPool, nil
+	return sql.OpenDB(connector), nil
 }
 
 func (h mysqlHandler) CreateStandardPool(cfg config.DatabaseConfig) (*sql.DB, error) {
+	connStr, err := standardDSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dbPool, err := sql.Open("mysql", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open (standard mysql): %w", err)
+	}
+	return dbPool, nil
+}
+
+// programNameAttribute is the connection attribute that names this tool in
+// performance_schema.session_connect_attrs.
+var programNameAttribute = "program_name:" + database.ApplicationName
+
+// standardDSN builds the DSN for a direct connection, using the Unix socket when one is configured.
+// --driver-params are parsed as DSN parameters, so known ones such as timeout or charset
+// set the driver option and override the generated values. The DSN is assembled as a
+// string because FormatDSN leaves out connectionAttributes.
+func standardDSN(cfg config.DatabaseConfig) (string, error) {
 	mysqlCfg := mysql.Config{
 		User:                 cfg.User,
 		Passwd:               cfg.Password,
@@ -98,13 +123,29 @@ func (h mysqlHandler) CreateStandardPool(cfg config.DatabaseConfig) (*sql.DB, er
 		AllowNativePasswords: true,
 		ParseTime:            true,
 	}
-	connStr := mysqlCfg.FormatDSN()
+	if cfg.Socket != "" {
+		mysqlCfg.Net = "unix"
+		mysqlCfg.Addr = cfg.Socket
+	}
+	dsn := mysqlCfg.FormatDSN()
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	dsn += separator + "connectionAttributes=" + url.QueryEscape(programNameAttribute)
+	if len(cfg.DriverParams) == 0 {
+		return dsn, nil
+	}
 
-	dbPool, err := sql.Open("mysql", connStr)
-	if err != nil {
-		return nil, fmt.Errorf("sql.Open (standard mysql): %w", err)
+	params := url.Values{}
+	for key, value := range cfg.DriverParams {
+		params.Set(key, value)
 	}
-	return dbPool, nil
+	dsn += "&" + params.Encode()
+	if _, err := mysql.ParseDSN(dsn); err != nil {
+		return "", fmt.Errorf("invalid --driver-params for mysql: %w", err)
+	}
+	return dsn, nil
 }
 
 func (h mysqlHandler) QuoteIdentifier(name string) string {
@@ -123,11 +164,15 @@ func (h mysqlHandler) ListTables(db *database.DB) ([]string, error) {
 
 	var tables []string
 	for rows.Next() {
-		var tableName string
+		var tableName sql.NullString
 		if err := rows.Scan(&tableName); err != nil {
 			return nil, fmt.Errorf("error scanning table name: %w", err)
 		}
-		tables = append(tables, tableName)
+		if !tableName.Valid {
+			log.Println("WARN: Skipping a table listed with a NULL name.")
+			continue
+		}
+		tables = append(tables, tableName.String)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating table rows: %w", err)
@@ -151,11 +196,15 @@ func (h mysqlHandler) ListColumns(db *database.DB, tableName string) ([]database
 
 	var columns []database.ColumnInfo
 	for rows.Next() {
-		var colInfo database.ColumnInfo
-		if err := rows.Scan(&colInfo.Name, &colInfo.DataType); err != nil {
+		var name, dataType sql.NullString
+		if err := rows.Scan(&name, &dataType); err != nil {
 			return nil, fmt.Errorf("error scanning column name and data type: %w", err)
 		}
-		columns = append(columns, colInfo)
+		if !name.Valid {
+			log.Printf("WARN: Skipping a column of %s listed with a NULL name.", tableName)
+			continue
+		}
+		columns = append(columns, database.ColumnInfo{Name: name.String, DataType: dataType.String})
 	}
 
 	if err := rows.Err(); err != nil {
@@ -171,8 +220,10 @@ func (h mysqlHandler) GetColumnMetadata(db *database.DB, tableName string, colum
 	ctx := context.Background()
 
 	distinctQuery := fmt.Sprintf("SELECT COUNT(DISTINCT %s) FROM %s", quotedColumn, quotedTable)
-	var distinctCount int64
-	err := db.Pool.QueryRowContext(ctx, distinctQuery).Scan(&distinctCount)
+	// A COLLATE clause must name a collation of the column's character set, so the
+	// binary count casts to BINARY instead.
+	binaryDistinctQuery := fmt.Sprintf("SELECT COUNT(DISTINCT CAST(%s AS BINARY)) FROM %s", quotedColumn, quotedTable)
+	distinctCount, err := database.DistinctCount(ctx, db, tableName+"."+columnName, distinctQuery, binaryDistinctQuery)
 	if err != nil {
 		log.Printf("WARN: Failed to get distinct count for %s.%s (may require specific privileges or type): %v. Reporting -1.", tableName, columnName, err)
 		distinctCount = -1
@@ -182,13 +233,32 @@ func (h mysqlHandler) GetColumnMetadata(db *database.DB, tableName string, colum
 	var nullCount int64
 	err = db.Pool.QueryRowContext(ctx, nullQuery).Scan(&nullCount)
 	if err != nil {
+		if permErr := h.permissionError(db, tableName, err); permErr != nil {
+			return nil, permErr
+		}
 		return nil, fmt.Errorf("failed to get null count for %s.%s: %w", tableName, columnName, err)
 	}
 
-	exampleQuery := fmt.Sprintf("SELECT DISTINCT CAST(%s AS CHAR) FROM %s WHERE %s IS NOT NULL LIMIT 3",
-		quotedColumn, quotedTable, quotedColumn)
+	// Ordering by the CHAR cast keeps the sample stable across runs and works for every column type.
+	exampleLimit := database.ExampleQueryLimit(db.Config.ExampleSampleSize)
+	exampleQuery := fmt.Sprintf("SELECT DISTINCT CAST(%s AS CHAR) FROM %s WHERE %s IS NOT NULL ORDER BY 1 LIMIT %d",
+		quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	firstRows := database.SampleWithoutDistinct(db, distinctCount)
+	if firstRows {
+		exampleQuery = fmt.Sprintf("SELECT CAST(%s AS CHAR) FROM %s WHERE %s IS NOT NULL LIMIT %d",
+			quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	}
 	rows, err := db.Pool.QueryContext(ctx, exampleQuery)
+	if err != nil && !firstRows && h.permissionError(db, tableName, err) == nil {
+		log.Printf("WARN: Ordered example query failed for %s.%s: %v. Retrying without ORDER BY.", tableName, columnName, err)
+		unorderedQuery := fmt.Sprintf("SELECT DISTINCT CAST(%s AS CHAR) FROM %s WHERE %s IS NOT NULL LIMIT %d",
+			quotedColumn, quotedTable, quotedColumn, exampleLimit)
+		rows, err = db.Pool.QueryContext(ctx, unorderedQuery)
+	}
 	if err != nil {
+		if permErr := h.permissionError(db, tableName, err); permErr != nil {
+			return nil, permErr
+		}
 		return nil, fmt.Errorf("failed to get example values for %s.%s: %w", tableName, columnName, err)
 	}
 	defer rows.Close()
@@ -206,12 +276,26 @@ func (h mysqlHandler) GetColumnMetadata(db *database.DB, tableName string, colum
 	if rows.Err() != nil {
 		return nil, fmt.Errorf("error iterating example values for %s.%s: %w", tableName, columnName, rows.Err())
 	}
+	if firstRows {
+		examples = database.UniqueValues(examples)
+	}
+	examples = database.SelectRepresentativeValues(examples, database.DefaultExampleCount)
 
-	return map[string]interface{}{
+	metadata := map[string]interface{}{
 		"DistinctCount": distinctCount,
 		"NullCount":     nullCount,
 		"ExampleValues": examples,
-	}, nil
+	}
+	// MySQL averages strings as numbers, so only numeric columns are averaged.
+	if db.Config.ComputeAverage {
+		if dataType, err := h.getColumnDataType(ctx, db, tableName, columnName); err == nil && database.IsNumericType(dataType) {
+			avgQuery := fmt.Sprintf("SELECT AVG(%s) FROM %s", quotedColumn, quotedTable)
+			if avg := database.ColumnAverage(ctx, db, avgQuery); avg != nil {
+				metadata["Average"] = *avg
+			}
+		}
+	}
+	return metadata, nil
 }
 
 func escapeMySQLString(value string) string {
@@ -236,15 +320,20 @@ func (h mysqlHandler) formatExampleValues(values []string) string {
 	return fmt.Sprintf("Examples: [%s]", strings.Join(quoted, ", "))
 }
 
+// formatForeignKeys renders referenced columns with this dialect's identifier quoting.
+func (h mysqlHandler) formatForeignKeys(foreignKeys []database.ForeignKeyReference) string {
+	return database.FormatForeignKeys(foreignKeys, h.QuoteIdentifier)
+}
+
 func (h mysqlHandler) GenerateCommentSQL(db *database.DB, data *database.CommentData, enrichments map[string]bool) (string, error) {
 	if data == nil || data.TableName == "" || data.ColumnName == "" {
 		return "", fmt.Errorf("invalid input for GenerateCommentSQL")
 	}
 
 	formattedExamples := h.formatExampleValues(data.ExampleValues)
-	newMetadataComment := database.GenerateMetadataCommentString(data, enrichments, formattedExamples)
+	newMetadataComment := database.GenerateMetadataCommentString(data, enrichments, formattedExamples, h.formatForeignKeys(data.ForeignKeys))
 
-	existingComment, err := h.GetColumnComment(context.Background(), db, data.TableName, data.ColumnName)
+	existingComment, err := database.ExistingColumnComment(context.Background(), db, h, data.TableName, data.ColumnName)
 	if err != nil && err != sql.ErrNoRows {
 		log.Printf("WARN: Failed to get existing column comment for %s.%s: %v. Proceeding as if empty.", data.TableName, data.ColumnName, err)
 		existingComment = ""
@@ -252,9 +341,13 @@ func (h mysqlHandler) GenerateCommentSQL(db *database.DB, data *database.Comment
 
 	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
 
-	columnDataType, err := h.getColumnDataType(context.Background(), db, data.TableName, data.ColumnName)
-	if err != nil {
-		return "", fmt.Errorf("failed to get column data type for %s.%s: %w", data.TableName, data.ColumnName, err)
+	// Offline, rely on the collected type; ListColumns reports the full COLUMN_TYPE.
+	columnDataType := data.ColumnDataType
+	if !db.Offline() {
+		columnDataType, err = h.getColumnDataType(context.Background(), db, data.TableName, data.ColumnName)
+		if err != nil {
+			return "", fmt.Errorf("failed to get column data type for %s.%s: %w", data.TableName, data.ColumnName, err)
+		}
 	}
 	if columnDataType == "" {
 		return "", fmt.Errorf("could not determine data type for column %s.%s, cannot generate comment SQL", data.TableName, data.ColumnName)
@@ -270,20 +363,24 @@ func (h mysqlHandler) GenerateCommentSQL(db *database.DB, data *database.Comment
 	), nil
 }
 
-func (h mysqlHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+// GenerateRewriteCommentSQL returns the statement that replaces a column's comment
+// with rewrite(existing), or "" when that leaves the comment unchanged.
+func (h mysqlHandler) GenerateRewriteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string, rewrite func(string) string) (string, error) {
 	if tableName == "" || columnName == "" {
-		return "", fmt.Errorf("table and column names cannot be empty for GenerateDeleteCommentSQL")
+		return "", fmt.Errorf("table and column names cannot be empty for GenerateRewriteCommentSQL")
 	}
 
-	existingComment, err := h.GetColumnComment(ctx, db, tableName, columnName)
+	existingComment, err := database.ExistingColumnComment(ctx, db, h, tableName, columnName)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", nil
 		}
-		return "", fmt.Errorf("failed to get existing column comment for %s.%s before delete: %w", tableName, columnName, err)
+		return "", fmt.Errorf("failed to get existing column comment for %s.%s before rewrite: %w", tableName, columnName, err)
 	}
 
-	finalComment := database.MergeComments(existingComment, "", "")
+	// MySQL cannot remove a comment, only set it to ''. Trimming makes sure a deleted
+	// comment is stored as exactly that, not as leftover whitespace.
+	finalComment := strings.TrimSpace(rewrite(existingComment))
 
 	if finalComment == strings.TrimSpace(existingComment) {
 		return "", nil
@@ -291,10 +388,10 @@ func (h mysqlHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database
 
 	columnDataType, err := h.getColumnDataType(ctx, db, tableName, columnName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get column data type for deleting comment on %s.%s: %w", tableName, columnName, err)
+		return "", fmt.Errorf("failed to get column data type for rewriting comment on %s.%s: %w", tableName, columnName, err)
 	}
 	if columnDataType == "" {
-		return "", fmt.Errorf("could not determine data type for column %s.%s, cannot generate delete comment SQL", tableName, columnName)
+		return "", fmt.Errorf("could not determine data type for column %s.%s, cannot generate comment SQL", tableName, columnName)
 	}
 
 	quotedComment := fmt.Sprintf("'%s'", escapeMySQLString(finalComment))
@@ -307,6 +404,10 @@ func (h mysqlHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database
 	), nil
 }
 
+func (h mysqlHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	return h.GenerateRewriteCommentSQL(ctx, db, tableName, columnName, database.StripMetadata)
+}
+
 func (h mysqlHandler) GetColumnComment(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
 	query := `
 		  SELECT COLUMN_COMMENT
@@ -333,6 +434,38 @@ func (h mysqlHandler) GetColumnComment(ctx context.Context, db *database.DB, tab
 	return "", nil
 }
 
+// GetAllColumnComments reads the comments on every column of a table in one query.
+func (h mysqlHandler) GetAllColumnComments(ctx context.Context, db *database.DB, tableName string) (map[string]string, error) {
+	query := `
+		  SELECT COLUMN_NAME, COLUMN_COMMENT
+		  FROM information_schema.COLUMNS
+		  WHERE TABLE_SCHEMA = DATABASE()
+			AND TABLE_NAME = ?;
+	  `
+
+	rows, err := db.Pool.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve column comments for table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	comments := make(map[string]string)
+	for rows.Next() {
+		var column string
+		var comment sql.NullString
+		if err := rows.Scan(&column, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column comment for table %s: %w", tableName, err)
+		}
+		if comment.Valid && comment.String != "" {
+			comments[column] = comment.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column comments for table %s: %w", tableName, err)
+	}
+	return comments, nil
+}
+
 func (h mysqlHandler) getColumnDataType(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
 	query := `
 		  SELECT COLUMN_TYPE
@@ -362,7 +495,7 @@ func (h mysqlHandler) GenerateTableCommentSQL(db *database.DB, data *database.Ta
 
 	newMetadataComment := database.GenerateTableMetadataCommentString(data, enrichments)
 
-	existingComment, err := h.GetTableComment(context.Background(), db, data.TableName)
+	existingComment, err := database.ExistingTableComment(context.Background(), db, h, data.TableName)
 	if err != nil && err != sql.ErrNoRows {
 		log.Printf("WARN: Failed to get existing table comment for %s: %v. Proceeding as if empty.", data.TableName, err)
 		existingComment = ""
@@ -406,9 +539,10 @@ func (h mysqlHandler) GetTableComment(ctx context.Context, db *database.DB, tabl
 	return "", nil
 }
 
-func (h mysqlHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *database.DB, tableName string) (string, error) {
+// GenerateRewriteTableCommentSQL is GenerateRewriteCommentSQL for the table comment.
+func (h mysqlHandler) GenerateRewriteTableCommentSQL(ctx context.Context, db *database.DB, tableName string, rewrite func(string) string) (string, error) {
 	if tableName == "" {
-		return "", fmt.Errorf("table name cannot be empty for GenerateDeleteTableCommentSQL")
+		return "", fmt.Errorf("table name cannot be empty for GenerateRewriteTableCommentSQL")
 	}
 
 	existingComment, err := h.GetTableComment(ctx, db, tableName)
@@ -416,10 +550,11 @@ func (h mysqlHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *dat
 		if err == sql.ErrNoRows {
 			return "", nil
 		}
-		return "", fmt.Errorf("failed to get existing table comment for %s before delete: %w", tableName, err)
+		return "", fmt.Errorf("failed to get existing table comment for %s before rewrite: %w", tableName, err)
 	}
 
-	finalComment := database.MergeComments(existingComment, "", "")
+	// As for columns, a deleted comment is stored as exactly ''.
+	finalComment := strings.TrimSpace(rewrite(existingComment))
 
 	if finalComment == strings.TrimSpace(existingComment) {
 		return "", nil
@@ -433,6 +568,10 @@ func (h mysqlHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *dat
 	), nil
 }
 
+func (h mysqlHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	return h.GenerateRewriteTableCommentSQL(ctx, db, tableName, database.StripMetadata)
+}
+
 func (h mysqlHandler) GetForeignKeys(db *database.DB, tableName string, columnName string) ([]database.ForeignKeyReference, error) {
 	query := `
 		SELECT
@@ -467,6 +606,26 @@ func (h mysqlHandler) GetForeignKeys(db *database.DB, tableName string, columnNa
 	return foreignKeys, nil
 }
 
+// ForeignKeyMatchQuery implements database.ForeignKeyMatcher. Rows written with
+// foreign_key_checks disabled may not match.
+func (h mysqlHandler) ForeignKeyMatchQuery(ctx context.Context, db *database.DB, tableName string, columnName string, fk database.ForeignKeyReference) string {
+	return database.ForeignKeyMatchSQL(h.QuoteIdentifier(tableName), h.QuoteIdentifier(columnName), h.QuoteIdentifier(fk.ReferencedTable), h.QuoteIdentifier(fk.ReferencedColumn))
+}
+
+// permissionError returns a database.PermissionError when err is MySQL's
+// table or column access denied error (1142 / 1143), and nil otherwise.
+func (h mysqlHandler) permissionError(db *database.DB, tableName string, err error) error {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && (myErr.Number == 1142 || myErr.Number == 1143) {
+		return &database.PermissionError{
+			Table: tableName,
+			Grant: fmt.Sprintf("GRANT SELECT ON %s.%s TO '%s'@'%%';", h.QuoteIdentifier(db.Config.DBName), h.QuoteIdentifier(tableName), escapeMySQLString(db.Config.User)),
+			Err:   err,
+		}
+	}
+	return nil
+}
+
 func init() {
 	database.RegisterDialectHandler("mysql", mysqlHandler{})
 	database.RegisterDialectHandler("cloudsqlmysql", mysqlHandler{})
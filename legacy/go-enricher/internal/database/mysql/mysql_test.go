@@ -1,11 +1,17 @@
 package mysql
 
 import (
+	"context"
 	"errors"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/go-sql-driver/mysql"
 )
 
 func TestMySQLGetForeignKeys(t *testing.T) {
@@ -124,3 +130,416 @@ func TestMySQLGetForeignKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestMySQLGetColumnMetadataPermissionDenied(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{DBName: "hr", User: "enricher"}}
+	handler := mysqlHandler{}
+
+	permErr := &mysql.MySQLError{Number: 1142, Message: "SELECT command denied to user 'enricher'@'%' for table 'payroll'"}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT `salary`) FROM `payroll`")).WillReturnError(permErr)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `payroll` WHERE `salary` IS NULL")).WillReturnError(permErr)
+
+	_, err = handler.GetColumnMetadata(db, "payroll", "salary")
+	var pe *database.PermissionError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected *database.PermissionError, got: %v", err)
+	}
+	wantGrant := "GRANT SELECT ON `hr`.`payroll` TO 'enricher'@'%';"
+	if pe.Grant != wantGrant {
+		t.Errorf("Expected grant %q, got %q", wantGrant, pe.Grant)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMySQLStandardDSN(t *testing.T) {
+	tcpDSN, err := standardDSN(config.DatabaseConfig{Host: "localhost", Port: 3306, User: "u", Password: "p", DBName: "db"})
+	if err != nil {
+		t.Fatalf("standardDSN() unexpected error: %v", err)
+	}
+	tcpCfg, err := mysql.ParseDSN(tcpDSN)
+	if err != nil {
+		t.Fatalf("Failed to parse TCP DSN %q: %v", tcpDSN, err)
+	}
+	if tcpCfg.Net != "tcp" || tcpCfg.Addr != "localhost:3306" {
+		t.Errorf("Expected tcp localhost:3306, got %s %s", tcpCfg.Net, tcpCfg.Addr)
+	}
+	if tcpCfg.ConnectionAttributes != "program_name:db_schema_enricher" {
+		t.Errorf("Expected program_name connection attribute, got %q", tcpCfg.ConnectionAttributes)
+	}
+
+	socketDSN, err := standardDSN(config.DatabaseConfig{Socket: "/var/run/mysqld/mysqld.sock", User: "u", Password: "p", DBName: "db"})
+	if err != nil {
+		t.Fatalf("standardDSN() unexpected error: %v", err)
+	}
+	socketCfg, err := mysql.ParseDSN(socketDSN)
+	if err != nil {
+		t.Fatalf("Failed to parse socket DSN %q: %v", socketDSN, err)
+	}
+	if socketCfg.Net != "unix" || socketCfg.Addr != "/var/run/mysqld/mysqld.sock" {
+		t.Errorf("Expected unix /var/run/mysqld/mysqld.sock, got %s %s", socketCfg.Net, socketCfg.Addr)
+	}
+	if socketCfg.DBName != "db" || socketCfg.User != "u" {
+		t.Errorf("Expected user u and database db, got %s and %s", socketCfg.User, socketCfg.DBName)
+	}
+}
+
+func TestMySQLStandardDSNDriverParams(t *testing.T) {
+	dsn, err := standardDSN(config.DatabaseConfig{Host: "localhost", Port: 3306, User: "u", Password: "p", DBName: "db",
+		DriverParams: map[string]string{"timeout": "5s", "charset": "utf8mb4", "sql_mode": "'ANSI_QUOTES'"}})
+	if err != nil {
+		t.Fatalf("standardDSN() unexpected error: %v", err)
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("Failed to parse DSN %q: %v", dsn, err)
+	}
+	if cfg.Timeout != 5*time.Second {
+		t.Errorf("Expected timeout 5s, got %v", cfg.Timeout)
+	}
+	if !strings.Contains(dsn, "charset=utf8mb4") {
+		t.Errorf("Expected charset=utf8mb4 in DSN %q", dsn)
+	}
+	if cfg.Params["sql_mode"] != "'ANSI_QUOTES'" {
+		t.Errorf("Expected sql_mode system variable, got params %v", cfg.Params)
+	}
+	if !cfg.ParseTime || cfg.DBName != "db" || cfg.ConnectionAttributes != "program_name:db_schema_enricher" {
+		t.Errorf("Expected generated options to be kept, got parseTime=%v database=%s attributes=%s", cfg.ParseTime, cfg.DBName, cfg.ConnectionAttributes)
+	}
+
+	if _, err := standardDSN(config.DatabaseConfig{Host: "localhost", Port: 3306, DriverParams: map[string]string{"timeout": "soon"}}); err == nil {
+		t.Error("standardDSN() with an invalid timeout expected error, got nil")
+	}
+}
+
+func TestMySQLFormatForeignKeys(t *testing.T) {
+	handler := mysqlHandler{}
+	got := handler.formatForeignKeys([]database.ForeignKeyReference{{ReferencedTable: "users", ReferencedColumn: "id"}})
+	want := "Foreign Keys: [`users`.`id`]"
+	if got != want {
+		t.Errorf("formatForeignKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestMySQLGetColumnMetadataOrdersExamples(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB}
+	handler := mysqlHandler{}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT `city`) FROM `stores`")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(3)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `stores` WHERE `city` IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT CAST(`city` AS CHAR) FROM `stores` WHERE `city` IS NOT NULL ORDER BY 1 LIMIT 3")).
+		WillReturnRows(sqlmock.NewRows([]string{"city"}).AddRow("Austin").AddRow("Boston").AddRow("Chicago"))
+
+	metadata, err := handler.GetColumnMetadata(db, "stores", "city")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	ev, ok := metadata["ExampleValues"].([]string)
+	if !ok || len(ev) != 3 || ev[0] != "Austin" || ev[1] != "Boston" || ev[2] != "Chicago" {
+		t.Errorf("Expected sorted ExampleValues [Austin Boston Chicago], got %v", metadata["ExampleValues"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMySQLGetColumnMetadataHighCardinality(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{MaxDistinctForExamples: 1000}}
+	handler := mysqlHandler{}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT `session_id`) FROM `events`")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2000000)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `events` WHERE `session_id` IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery("^" + regexp.QuoteMeta("SELECT CAST(`session_id` AS CHAR) FROM `events` WHERE `session_id` IS NOT NULL LIMIT 3") + "$").
+		WillReturnRows(sqlmock.NewRows([]string{"session_id"}).AddRow("s-9").AddRow("s-9").AddRow("s-1"))
+
+	metadata, err := handler.GetColumnMetadata(db, "events", "session_id")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	ev, ok := metadata["ExampleValues"].([]string)
+	if !ok || len(ev) != 2 || ev[0] != "s-1" || ev[1] != "s-9" {
+		t.Errorf("Expected ExampleValues without repeats [s-1 s-9], got %v", metadata["ExampleValues"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMySQLGetColumnMetadataBinaryDistinctCollation(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{DistinctCollation: database.DistinctCollationBinary}}
+	handler := mysqlHandler{}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT CAST(`code` AS BINARY)) FROM `products`")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(4)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `products` WHERE `code` IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT CAST(`code` AS CHAR) FROM `products`")).WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow("A").AddRow("a"))
+
+	metadata, err := handler.GetColumnMetadata(db, "products", "code")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if metadata["DistinctCount"] != int64(4) {
+		t.Errorf("Expected DistinctCount 4, got %v", metadata["DistinctCount"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// TestMySQLAverage checks that the average is only queried for numeric columns, since
+// MySQL would average strings as numbers, and that it is rendered in the comment.
+func TestMySQLAverage(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{UpdateExistingMode: "overwrite", ComputeAverage: true}}
+	handler := mysqlHandler{}
+
+	expectStatistics := func(column, table string) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT `" + column + "`) FROM `" + table + "`")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `" + table + "` WHERE `" + column + "` IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT CAST(`" + column + "` AS CHAR) FROM `" + table + "`")).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("10"))
+	}
+
+	expectStatistics("amount", "orders")
+	mock.ExpectQuery(`SELECT COLUMN_TYPE\s+FROM information_schema.COLUMNS`).WithArgs("orders", "amount").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_TYPE"}).AddRow("decimal(10,2) unsigned"))
+	mock.ExpectQuery("^" + regexp.QuoteMeta("SELECT AVG(`amount`) FROM `orders`") + "$").WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(12.3456))
+	metadata, err := handler.GetColumnMetadata(db, "orders", "amount")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	avg, ok := metadata["Average"].(float64)
+	if !ok || avg != 12.3456 {
+		t.Fatalf("GetColumnMetadata() Average = %v, want 12.3456", metadata["Average"])
+	}
+
+	mock.ExpectQuery(`SELECT COLUMN_COMMENT\s+FROM information_schema.COLUMNS`).WithArgs("orders", "amount").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_COMMENT"}).AddRow(""))
+	mock.ExpectQuery(`SELECT COLUMN_TYPE\s+FROM information_schema.COLUMNS`).WithArgs("orders", "amount").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_TYPE"}).AddRow("decimal(10,2) unsigned"))
+	got, err := handler.GenerateCommentSQL(db, &database.CommentData{TableName: "orders", ColumnName: "amount", Average: &avg}, map[string]bool{"average": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	want := "ALTER TABLE `orders` MODIFY COLUMN `amount` decimal(10,2) unsigned COMMENT '<gemini>Avg: 12.35</gemini>';"
+	if got != want {
+		t.Errorf("GenerateCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+
+	// A string column gets no AVG query and no average.
+	expectStatistics("status", "orders")
+	mock.ExpectQuery(`SELECT COLUMN_TYPE\s+FROM information_schema.COLUMNS`).WithArgs("orders", "status").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_TYPE"}).AddRow("varchar(20)"))
+	metadata, err = handler.GetColumnMetadata(db, "orders", "status")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if _, ok := metadata["Average"]; ok {
+		t.Errorf("GetColumnMetadata() Average = %v for a varchar column, want none", metadata["Average"])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMySQLGetAllColumnComments(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Handler: mysqlHandler{}}
+
+	mock.ExpectQuery(`SELECT COLUMN_NAME, COLUMN_COMMENT\s+FROM information_schema\.COLUMNS`).WithArgs("stores").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_COMMENT"}).AddRow("id", "").AddRow("city", "Store city"))
+
+	comments, err := db.GetAllColumnComments(context.Background(), "stores")
+	if err != nil {
+		t.Fatalf("GetAllColumnComments() unexpected error: %v", err)
+	}
+	if len(comments) != 1 || comments["city"] != "Store city" {
+		t.Errorf("GetAllColumnComments() = %v, want map[city:Store city]", comments)
+	}
+
+	// The per-column lookup is served from the cache without another query.
+	comment, err := db.GetColumnComment(context.Background(), "stores", "city")
+	if err != nil || comment != "Store city" {
+		t.Errorf("GetColumnComment() = %q, %v, want %q", comment, err, "Store city")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// TestMySQLSpecialIdentifiersEndToEnd follows reserved-word and quoted column names
+// from ListColumns through the metadata queries to the generated comment.
+func TestMySQLSpecialIdentifiersEndToEnd(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{UpdateExistingMode: "overwrite"}}
+	handler := mysqlHandler{}
+	enrichments := map[string]bool{"examples": true, "distinct_values": true}
+
+	mock.ExpectQuery(`SELECT COLUMN_NAME, COLUMN_TYPE\s+FROM information_schema.COLUMNS`).WithArgs("order").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_TYPE"}).AddRow("select", "varchar(20)").AddRow("my `odd` col", "int"))
+
+	columns, err := handler.ListColumns(db, "order")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		quoted string
+		want   string
+	}{
+		{"`select`", "ALTER TABLE `order` MODIFY COLUMN `select` varchar(20) COMMENT '<gemini>Examples: [''a''] | Distinct: 1</gemini>';"},
+		{"`my ``odd`` col`", "ALTER TABLE `order` MODIFY COLUMN `my ``odd`` col` int COMMENT '<gemini>Examples: [''a''] | Distinct: 1</gemini>';"},
+	}
+	if len(columns) != len(tests) {
+		t.Fatalf("ListColumns() returned %d columns, want %d", len(columns), len(tests))
+	}
+	for i, tt := range tests {
+		col := columns[i]
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT " + tt.quoted + ") FROM `order`")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `order` WHERE " + tt.quoted + " IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT CAST(" + tt.quoted + " AS CHAR) FROM `order` WHERE " + tt.quoted + " IS NOT NULL ORDER BY 1 LIMIT 3")).
+			WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("a"))
+		metadata, err := handler.GetColumnMetadata(db, "order", col.Name)
+		if err != nil {
+			t.Fatalf("GetColumnMetadata(%q) unexpected error: %v", col.Name, err)
+		}
+
+		mock.ExpectQuery(`SELECT COLUMN_COMMENT\s+FROM information_schema.COLUMNS`).WithArgs("order", col.Name).
+			WillReturnRows(sqlmock.NewRows([]string{"COLUMN_COMMENT"}).AddRow(""))
+		mock.ExpectQuery(`SELECT COLUMN_TYPE\s+FROM information_schema.COLUMNS`).WithArgs("order", col.Name).
+			WillReturnRows(sqlmock.NewRows([]string{"COLUMN_TYPE"}).AddRow(col.DataType))
+		got, err := handler.GenerateCommentSQL(db, &database.CommentData{
+			TableName:     "order",
+			ColumnName:    col.Name,
+			ExampleValues: metadata["ExampleValues"].([]string),
+			DistinctCount: metadata["DistinctCount"].(int64),
+		}, enrichments)
+		if err != nil {
+			t.Fatalf("GenerateCommentSQL(%q) unexpected error: %v", col.Name, err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateCommentSQL(%q) =\n%s\nwant\n%s", col.Name, got, tt.want)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// TestMySQLDeleteCommentLeavesEmptyComment checks that deleting a comment that held
+// only metadata stores an empty string, as MySQL has no way to remove a comment.
+func TestMySQLDeleteCommentLeavesEmptyComment(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB}
+	handler := mysqlHandler{}
+
+	mock.ExpectQuery(`SELECT COLUMN_COMMENT\s+FROM information_schema.COLUMNS`).WithArgs("orders", "status").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_COMMENT"}).AddRow("  \n<gemini>Distinct: 3</gemini>  "))
+	mock.ExpectQuery(`SELECT COLUMN_TYPE\s+FROM information_schema.COLUMNS`).WithArgs("orders", "status").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_TYPE"}).AddRow("varchar(20)"))
+	got, err := handler.GenerateDeleteCommentSQL(context.Background(), db, "orders", "status")
+	if err != nil {
+		t.Fatalf("GenerateDeleteCommentSQL() unexpected error: %v", err)
+	}
+	if want := "ALTER TABLE `orders` MODIFY COLUMN `status` varchar(20) COMMENT '';"; got != want {
+		t.Errorf("GenerateDeleteCommentSQL() = %q, want %q", got, want)
+	}
+
+	mock.ExpectQuery(`SELECT TABLE_COMMENT\s+FROM information_schema.TABLES`).WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_COMMENT"}).AddRow(" <gemini>Rows: 10</gemini>\n"))
+	got, err = handler.GenerateDeleteTableCommentSQL(context.Background(), db, "orders")
+	if err != nil {
+		t.Fatalf("GenerateDeleteTableCommentSQL() unexpected error: %v", err)
+	}
+	if want := "ALTER TABLE `orders` COMMENT = '';"; got != want {
+		t.Errorf("GenerateDeleteTableCommentSQL() = %q, want %q", got, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMySQLListSkipsNullNames(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB}
+	handler := mysqlHandler{}
+
+	mock.ExpectQuery(`SELECT TABLE_NAME FROM information_schema.TABLES`).
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME"}).AddRow("orders").AddRow(nil).AddRow("users"))
+	tables, err := handler.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	if len(tables) != 2 || tables[0] != "orders" || tables[1] != "users" {
+		t.Errorf("ListTables() = %v, want [orders users]", tables)
+	}
+
+	mock.ExpectQuery(`SELECT COLUMN_NAME, COLUMN_TYPE\s+FROM information_schema.COLUMNS`).WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_TYPE"}).
+			AddRow("id", "int").
+			AddRow(nil, "varchar(20)").
+			AddRow("note", nil))
+	columns, err := handler.ListColumns(db, "orders")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	want := []database.ColumnInfo{{Name: "id", DataType: "int"}, {Name: "note"}}
+	if len(columns) != len(want) || columns[0] != want[0] || columns[1] != want[1] {
+		t.Errorf("ListColumns() = %v, want %v", columns, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+// CockroachDB supports COMMENT ON (v20.1+), so comments are written exactly as for
+// Postgres. Only the catalog queries below differ.
+
+// cockroachListColumnsQuery skips hidden columns such as the implicit rowid primary key.
+const cockroachListColumnsQuery = `
+		SELECT column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		AND table_name = $1
+		AND is_hidden = 'NO'
+		ORDER BY ordinal_position;`
+
+// cockroachColumnCommentQuery reads comments through col_description. CockroachDB
+// materializes pg_catalog tables on every scan, which makes the Postgres join
+// expensive. $1 is the quoted table name, qualified with --schema when one is set,
+// resolved with regclass.
+const cockroachColumnCommentQuery = `
+		SELECT col_description(a.attrelid, a.attnum)
+		FROM pg_catalog.pg_attribute a
+		WHERE a.attrelid = $1::regclass
+		  AND a.attname = $2;
+	`
+
+// cockroachAllColumnCommentsQuery is the bulk form of cockroachColumnCommentQuery.
+const cockroachAllColumnCommentsQuery = `
+		SELECT a.attname, col_description(a.attrelid, a.attnum)
+		FROM pg_catalog.pg_attribute a
+		WHERE a.attrelid = $1::regclass
+		  AND a.attnum > 0;
+	`
+
+// cockroachTableCommentQuery is the regclass form of the table comment lookup.
+const cockroachTableCommentQuery = `
+		SELECT obj_description($1::regclass::oid, 'pg_class');
+	`
+
+// cockroachHistoricalRead makes metadata sampling a historical read, so the
+// scans don't contend with foreground writes.
+const cockroachHistoricalRead = "AS OF SYSTEM TIME '-10s'"
+
+// cockroachMetadataSource returns the FROM source for metadata sampling queries:
+// a historical read of the table when one is possible, or the live table when the
+// table is too new (or its data too old) for one.
+func (h postgresHandler) cockroachMetadataSource(ctx context.Context, db *database.DB, tableName string) string {
+	quotedTable := h.qualifiedTable(db, tableName)
+	historical := fmt.Sprintf("%s %s", quotedTable, cockroachHistoricalRead)
+
+	probe := fmt.Sprintf("SELECT 1 FROM %s LIMIT 1", historical)
+	rows, err := db.Pool.QueryContext(ctx, probe)
+	if err != nil {
+		log.Printf("WARN: Historical read of %s failed: %v. Sampling the live table instead.", tableName, err)
+		return quotedTable
+	}
+	rows.Close()
+	return historical
+}
+
+func init() {
+	database.RegisterDialectHandler("cockroach", postgresHandler{cockroach: true})
+}
@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+func newMockCockroachDB(t *testing.T) (*database.DB, sqlmock.Sqlmock, *postgresHandler) {
+	t.Helper()
+	mockDb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	handler := postgresHandler{cockroach: true}
+	db := &database.DB{
+		Pool:    mockDb,
+		Handler: &handler,
+		Config: config.DatabaseConfig{
+			Dialect:            "cockroach",
+			UpdateExistingMode: "overwrite",
+		},
+	}
+	return db, mock, &handler
+}
+
+func TestCockroachListColumnsSkipsHidden(t *testing.T) {
+	db, mock, handler := newMockCockroachDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM information_schema\.columns.*AND is_hidden = 'NO'`).
+		WithArgs("users").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name", "data_type"}).AddRow("id", "bigint").AddRow("email", "text"))
+
+	columns, err := handler.ListColumns(db, "users")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	if len(columns) != 2 || columns[0].Name != "id" || columns[1].Name != "email" {
+		t.Errorf("ListColumns() = %v, want [id email]", columns)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCockroachGetColumnComment(t *testing.T) {
+	db, mock, handler := newMockCockroachDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT col_description(a.attrelid, a.attnum)`)).
+		WithArgs(`"Users"`, "email").
+		WillReturnRows(sqlmock.NewRows([]string{"col_description"}).AddRow("Login email"))
+
+	comment, err := handler.GetColumnComment(context.Background(), db, "Users", "email")
+	if err != nil {
+		t.Fatalf("GetColumnComment() unexpected error: %v", err)
+	}
+	if comment != "Login email" {
+		t.Errorf("GetColumnComment() = %q, want %q", comment, "Login email")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCockroachGetTableComment(t *testing.T) {
+	db, mock, handler := newMockCockroachDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT obj_description($1::regclass::oid, 'pg_class')`)).
+		WithArgs(`"users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"obj_description"}).AddRow("Registered users"))
+
+	comment, err := handler.GetTableComment(context.Background(), db, "users")
+	if err != nil {
+		t.Fatalf("GetTableComment() unexpected error: %v", err)
+	}
+	if comment != "Registered users" {
+		t.Errorf("GetTableComment() = %q, want %q", comment, "Registered users")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCockroachGetColumnMetadata(t *testing.T) {
+	tests := []struct {
+		name         string
+		probeErr     error
+		expectedFrom string
+	}{
+		{"historical read", nil, `"users" AS OF SYSTEM TIME '-10s'`},
+		{"table too new for historical read", errors.New("relation \"users\" does not exist"), `"users"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, handler := newMockCockroachDB(t)
+			defer db.Close()
+
+			probe := mock.ExpectQuery(regexp.QuoteMeta(`SELECT 1 FROM "users" AS OF SYSTEM TIME '-10s' LIMIT 1`))
+			if tt.probeErr != nil {
+				probe.WillReturnError(tt.probeErr)
+			} else {
+				probe.WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+			}
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT "email"::text) FROM ` + tt.expectedFrom)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM ` + tt.expectedFrom + ` WHERE "email" IS NULL`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT "email"::text FROM ` + tt.expectedFrom + ` WHERE "email" IS NOT NULL ORDER BY 1 LIMIT 3`)).
+				WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("a@example.com").AddRow("b@example.com"))
+
+			metadata, err := handler.GetColumnMetadata(db, "users", "email")
+			if err != nil {
+				t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+			}
+			if metadata["DistinctCount"] != int64(2) {
+				t.Errorf("DistinctCount = %v, want 2", metadata["DistinctCount"])
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestCockroachGetAllColumnComments(t *testing.T) {
+	db, mock, handler := newMockCockroachDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT a.attname, col_description(a.attrelid, a.attnum)`)).
+		WithArgs(`"Users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"attname", "col_description"}).AddRow("id", nil).AddRow("email", "Login email"))
+
+	comments, err := handler.GetAllColumnComments(context.Background(), db, "Users")
+	if err != nil {
+		t.Fatalf("GetAllColumnComments() unexpected error: %v", err)
+	}
+	if len(comments) != 1 || comments["email"] != "Login email" {
+		t.Errorf("GetAllColumnComments() = %v, want map[email:Login email]", comments)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCockroachForeignKeyMatchQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		probeErr error
+		want     string
+	}{
+		{"historical read", nil, `SELECT COUNT(*), COUNT(r.referenced_value) FROM "orders" c LEFT JOIN (SELECT DISTINCT "id" AS referenced_value FROM "users") r ON c."user_id" = r.referenced_value AS OF SYSTEM TIME '-10s' WHERE c."user_id" IS NOT NULL`},
+		{"referenced table too new for historical read", errors.New("relation \"users\" does not exist"), `SELECT COUNT(*), COUNT(r.referenced_value) FROM "orders" c LEFT JOIN (SELECT DISTINCT "id" AS referenced_value FROM "users") r ON c."user_id" = r.referenced_value WHERE c."user_id" IS NOT NULL`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, handler := newMockCockroachDB(t)
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT 1 FROM "orders" AS OF SYSTEM TIME '-10s' LIMIT 1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+			probe := mock.ExpectQuery(regexp.QuoteMeta(`SELECT 1 FROM "users" AS OF SYSTEM TIME '-10s' LIMIT 1`))
+			if tt.probeErr != nil {
+				probe.WillReturnError(tt.probeErr)
+			} else {
+				probe.WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+			}
+
+			got := handler.ForeignKeyMatchQuery(context.Background(), db, "orders", "user_id", database.ForeignKeyReference{ReferencedTable: "users", ReferencedColumn: "id"})
+			if got != tt.want {
+				t.Errorf("ForeignKeyMatchQuery() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
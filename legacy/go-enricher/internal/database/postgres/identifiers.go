@@ -0,0 +1,74 @@
+package postgres
+
+import "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+
+// reservedKeywords are the keywords PostgreSQL does not accept as bare identifiers
+// everywhere (reserved, type/function-name and column-name categories). quote_ident
+// quotes the same set.
+var reservedKeywords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true,
+	"as": true, "asc": true, "asymmetric": true, "authorization": true, "between": true,
+	"bigint": true, "binary": true, "bit": true, "boolean": true, "both": true, "case": true,
+	"cast": true, "char": true, "character": true, "check": true, "coalesce": true,
+	"collate": true, "collation": true, "column": true, "concurrently": true,
+	"constraint": true, "create": true, "cross": true, "current_catalog": true,
+	"current_date": true, "current_role": true, "current_schema": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "dec": true, "decimal": true,
+	"default": true, "deferrable": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "exists": true, "extract": true, "false": true,
+	"fetch": true, "float": true, "for": true, "foreign": true, "freeze": true, "from": true,
+	"full": true, "grant": true, "greatest": true, "group": true, "grouping": true,
+	"having": true, "ilike": true, "in": true, "initially": true, "inner": true, "inout": true,
+	"int": true, "integer": true, "intersect": true, "interval": true, "into": true, "is": true,
+	"isnull": true, "join": true, "lateral": true, "leading": true, "least": true, "left": true,
+	"like": true, "limit": true, "localtime": true, "localtimestamp": true, "national": true,
+	"natural": true, "nchar": true, "none": true, "normalize": true, "not": true,
+	"notnull": true, "null": true, "nullif": true, "numeric": true, "offset": true, "on": true,
+	"only": true, "or": true, "order": true, "out": true, "outer": true, "overlaps": true,
+	"overlay": true, "placing": true, "position": true, "precision": true, "primary": true,
+	"real": true, "references": true, "returning": true, "right": true, "row": true,
+	"select": true, "session_user": true, "setof": true, "similar": true, "smallint": true,
+	"some": true, "substring": true, "symmetric": true, "system_user": true, "table": true,
+	"tablesample": true, "then": true, "time": true, "timestamp": true, "to": true,
+	"trailing": true, "treat": true, "trim": true, "true": true, "union": true, "unique": true,
+	"user": true, "using": true, "values": true, "varchar": true, "variadic": true,
+	"verbose": true, "when": true, "where": true, "window": true, "with": true,
+}
+
+// foldsToItself reports whether name can be written unquoted and still refer to name:
+// it is a lower-case identifier that PostgreSQL's case folding leaves unchanged and
+// not a reserved keyword.
+func foldsToItself(name string) bool {
+	if name == "" || reservedKeywords[name] {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r == '_':
+		case i > 0 && (r >= '0' && r <= '9' || r == '$'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// sqlIdentifier returns name as written in generated COMMENT statements. Names are
+// quoted, preserving their case, unless --lowercase-identifiers is set and the name
+// reads the same unquoted. Mixed-case names stay quoted either way, since folding
+// them would point the statement at a different object.
+func (h postgresHandler) sqlIdentifier(db *database.DB, name string) string {
+	if db.Config.LowercaseIdentifiers && foldsToItself(name) {
+		return name
+	}
+	return h.QuoteIdentifier(name)
+}
+
+// sqlTable returns tableName as written in generated COMMENT statements, qualified
+// with --schema when one is set.
+func (h postgresHandler) sqlTable(db *database.DB, tableName string) string {
+	if db.Config.Schema == "" {
+		return h.sqlIdentifier(db, tableName)
+	}
+	return h.sqlIdentifier(db, db.Config.Schema) + "." + h.sqlIdentifier(db, tableName)
+}
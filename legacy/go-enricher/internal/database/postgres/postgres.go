@@ -3,20 +3,27 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"sort"
 	"strings"
 
 	"cloud.google.com/go/cloudsqlconn"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/lib/pq"
 )
 
-type postgresHandler struct{}
+// postgresHandler serves Postgres and, with cockroach set, CockroachDB, which
+// speaks the same protocol but differs in some catalog queries.
+type postgresHandler struct {
+	cockroach bool
+}
 
 var _ database.DialectHandler = (*postgresHandler)(nil)
 
@@ -50,7 +57,7 @@ func (h postgresHandler) CreateCloudSQLPool(cfg config.DatabaseConfig) (*sql.DB,
 		return nil, fmt.Errorf("missing required CloudSQL connection parameter (user, pass, db, instance)")
 	}
 
-	dsn := fmt.Sprintf("user=%s password=%s database=%s", dbUser, dbPwd, dbName)
+	dsn := fmt.Sprintf("user=%s password=%s database=%s application_name=%s", dbUser, dbPwd, dbName, database.ApplicationName)
 	pgxCfg, err := pgx.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("pgx.ParseConfig failed: %w", err)
@@ -80,14 +87,7 @@ func (h postgresHandler) CreateCloudSQLPool(cfg config.DatabaseConfig) (*sql.DB,
 }
 
 func (h postgresHandler) CreateStandardPool(cfg config.DatabaseConfig) (*sql.DB, error) {
-	sslmode := cfg.SSLMode
-	if sslmode == "" {
-		sslmode = "disable"
-	}
-	connStr := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, sslmode,
-	)
+	connStr := standardConnString(cfg)
 
 	dbPool, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -96,20 +96,88 @@ func (h postgresHandler) CreateStandardPool(cfg config.DatabaseConfig) (*sql.DB,
 	return dbPool, nil
 }
 
+// standardConnString builds the connection string for a direct connection.
+// With a socket, host is the socket directory and the port is only included if set.
+// --driver-params come last, so they override the generated settings (e.g. sslmode or
+// application_name).
+func standardConnString(cfg config.DatabaseConfig) string {
+	sslmode := cfg.SSLMode
+	if sslmode == "" {
+		sslmode = "disable"
+	}
+	var connStr string
+	if cfg.Socket != "" {
+		connStr = fmt.Sprintf("host=%s", cfg.Socket)
+		if cfg.Port != 0 {
+			connStr += fmt.Sprintf(" port=%d", cfg.Port)
+		}
+		connStr = fmt.Sprintf("%s user=%s password=%s dbname=%s sslmode=%s", connStr, cfg.User, cfg.Password, cfg.DBName, sslmode)
+	} else {
+		connStr = fmt.Sprintf(
+			"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, sslmode,
+		)
+	}
+	connStr += " application_name=" + database.ApplicationName
+
+	keys := make([]string, 0, len(cfg.DriverParams))
+	for key := range cfg.DriverParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		connStr += fmt.Sprintf(" %s=%s", key, quoteConnValue(cfg.DriverParams[key]))
+	}
+	return connStr
+}
+
+// quoteConnValue single-quotes a connection string value that is empty or contains
+// spaces, quotes or backslashes, as libpq requires.
+func quoteConnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
 func (h postgresHandler) QuoteIdentifier(name string) string {
 	name = strings.Replace(name, `"`, `""`, -1)
 	return fmt.Sprintf(`"%s"`, name)
 }
 
-func (h postgresHandler) ListTables(db *database.DB) ([]string, error) {
-	query := `
-		SELECT table_name
+// qualifiedTable returns the quoted table name for metadata queries, qualified with
+// --schema when one is set.
+func (h postgresHandler) qualifiedTable(db *database.DB, tableName string) string {
+	if db.Config.Schema == "" {
+		return h.QuoteIdentifier(tableName)
+	}
+	return h.QuoteIdentifier(db.Config.Schema) + "." + h.QuoteIdentifier(tableName)
+}
+
+// inSchema points a catalog query at --schema when one is set, replacing its
+// current_schema() filter with a parameter that follows args. Without --schema the
+// query and args are returned unchanged.
+func inSchema(db *database.DB, query string, args ...interface{}) (string, []interface{}) {
+	if db.Config.Schema == "" {
+		return query, args
+	}
+	placeholder := fmt.Sprintf("$%d", len(args)+1)
+	return strings.ReplaceAll(query, "current_schema()", placeholder), append(args, db.Config.Schema)
+}
+
+// listTablesQuery lists the tables of the current schema, or of --schema through
+// inSchema. Temporary tables are reported as LOCAL TEMPORARY and only appear when
+// pg_temp is the current schema, e.g. with search_path set to pg_temp.
+const listTablesQuery = `
+		SELECT table_name, table_type
 		FROM information_schema.tables
 		WHERE table_schema = current_schema()
-		AND table_type = 'BASE TABLE'
+		AND table_type IN ('BASE TABLE', 'LOCAL TEMPORARY')
 		ORDER BY table_name;`
 
-	rows, err := db.Pool.Query(query)
+func (h postgresHandler) ListTables(db *database.DB) ([]string, error) {
+	query, args := inSchema(db, listTablesQuery)
+	rows, err := db.Pool.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying tables: %w", err)
 	}
@@ -117,11 +185,19 @@ func (h postgresHandler) ListTables(db *database.DB) ([]string, error) {
 
 	var tables []string
 	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
+		var tableName, tableType sql.NullString
+		if err := rows.Scan(&tableName, &tableType); err != nil {
 			return nil, fmt.Errorf("error scanning table name: %w", err)
 		}
-		tables = append(tables, tableName)
+		if !tableName.Valid {
+			log.Println("WARN: Skipping a table listed with a NULL name.")
+			continue
+		}
+		if tableType.String == "LOCAL TEMPORARY" && !db.Config.IncludeTempTables {
+			log.Printf("INFO: Skipping temporary table %s (use --include-temp-tables to list it).", tableName.String)
+			continue
+		}
+		tables = append(tables, tableName.String)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -132,14 +208,13 @@ func (h postgresHandler) ListTables(db *database.DB) ([]string, error) {
 }
 
 func (h postgresHandler) ListColumns(db *database.DB, tableName string) ([]database.ColumnInfo, error) {
-	query := `
-		SELECT column_name, data_type
-		FROM information_schema.columns
-		WHERE table_schema = current_schema()
-		AND table_name = $1
-		ORDER BY ordinal_position;`
+	query := listColumnsQuery
+	if h.cockroach {
+		query = cockroachListColumnsQuery
+	}
 
-	rows, err := db.Pool.Query(query, tableName)
+	query, args := inSchema(db, query, tableName)
+	rows, err := db.Pool.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying columns for table %s: %w", tableName, err)
 	}
@@ -147,10 +222,23 @@ func (h postgresHandler) ListColumns(db *database.DB, tableName string) ([]datab
 
 	var columns []database.ColumnInfo
 	for rows.Next() {
-		var colInfo database.ColumnInfo
-		if err := rows.Scan(&colInfo.Name, &colInfo.DataType); err != nil {
+		var name, dataType, udtName sql.NullString
+		var domainName, typeKind string
+		if h.cockroach {
+			if err := rows.Scan(&name, &dataType); err != nil {
+				return nil, fmt.Errorf("error scanning column name and data type: %w", err)
+			}
+		} else if err := rows.Scan(&name, &dataType, &domainName, &udtName, &typeKind); err != nil {
 			return nil, fmt.Errorf("error scanning column name and data type: %w", err)
 		}
+		if !name.Valid {
+			log.Printf("WARN: Skipping a column of %s listed with a NULL name.", tableName)
+			continue
+		}
+		colInfo := database.ColumnInfo{Name: name.String, DataType: dataType.String}
+		if !h.cockroach {
+			colInfo.DataType, colInfo.BaseType, colInfo.StatsUnsupported = resolveColumnType(dataType.String, domainName, udtName.String, typeKind)
+		}
 		columns = append(columns, colInfo)
 	}
 
@@ -161,15 +249,61 @@ func (h postgresHandler) ListColumns(db *database.DB, tableName string) ([]datab
 	return columns, nil
 }
 
+// listColumnsQuery lists the columns of a table with what is needed to resolve
+// user-defined types: the domain, the underlying type (udt_name, which information_schema
+// reports after resolving domains) and its pg_type.typtype kind.
+const listColumnsQuery = `
+		SELECT c.column_name, c.data_type, COALESCE(c.domain_name, ''), c.udt_name, COALESCE(t.typtype::text, '')
+		FROM information_schema.columns c
+		LEFT JOIN pg_catalog.pg_type t
+			ON t.typname = c.udt_name
+			AND t.typnamespace = (SELECT n.oid FROM pg_catalog.pg_namespace n WHERE n.nspname = c.udt_schema)
+		WHERE c.table_schema = current_schema()
+		AND c.table_name = $1
+		ORDER BY c.ordinal_position;`
+
+// resolveColumnType names a column's type for prompts and reports. information_schema
+// reports user-defined types as USER-DEFINED, so the type's own name is used with its
+// kind, and columns of a domain name the domain and the type it is based on, which is
+// also returned as baseType. Composite values are whole rows, so statistics are not
+// gathered for them.
+func resolveColumnType(dataType, domainName, udtName, typeKind string) (resolved, baseType string, statsUnsupported bool) {
+	resolved = dataType
+	if dataType == "USER-DEFINED" {
+		resolved = udtName
+		switch typeKind {
+		case "e":
+			resolved += " (enum)"
+		case "c":
+			resolved += " (composite)"
+		case "r":
+			resolved += " (range)"
+		}
+	}
+	if domainName != "" {
+		baseType = resolved
+		resolved = fmt.Sprintf("%s (domain over %s)", domainName, resolved)
+	}
+	return resolved, baseType, typeKind == "c"
+}
+
 func (h postgresHandler) GetColumnMetadata(db *database.DB, tableName string, columnName string) (map[string]interface{}, error) {
-	quotedTable := h.QuoteIdentifier(tableName)
+	quotedTable := h.qualifiedTable(db, tableName)
 	quotedColumn := h.QuoteIdentifier(columnName)
 
 	ctx := context.Background()
+	if h.cockroach {
+		quotedTable = h.cockroachMetadataSource(ctx, db, tableName)
+	}
 
 	distinctQuery := fmt.Sprintf("SELECT COUNT(DISTINCT %s::text) FROM %s", quotedColumn, quotedTable)
-	var distinctCount int64
-	err := db.Pool.QueryRowContext(ctx, distinctQuery).Scan(&distinctCount)
+	// CockroachDB compares strings binary unless a column has an explicit collation,
+	// which the text cast drops.
+	var binaryDistinctQuery string
+	if !h.cockroach {
+		binaryDistinctQuery = fmt.Sprintf(`SELECT COUNT(DISTINCT %s::text COLLATE "C") FROM %s`, quotedColumn, quotedTable)
+	}
+	distinctCount, err := database.DistinctCount(ctx, db, tableName+"."+columnName, distinctQuery, binaryDistinctQuery)
 	if err != nil {
 		log.Printf("WARN: Failed to get distinct count for %s.%s: %v. Reporting -1.", tableName, columnName, err)
 		distinctCount = -1
@@ -179,13 +313,32 @@ func (h postgresHandler) GetColumnMetadata(db *database.DB, tableName string, co
 	var nullCount int64
 	err = db.Pool.QueryRowContext(ctx, nullQuery).Scan(&nullCount)
 	if err != nil {
+		if permErr := h.permissionError(db, tableName, err); permErr != nil {
+			return nil, permErr
+		}
 		return nil, fmt.Errorf("failed to get null count for %s.%s: %w", tableName, columnName, err)
 	}
 
-	exampleQuery := fmt.Sprintf("SELECT DISTINCT %s::text FROM %s WHERE %s IS NOT NULL LIMIT 3",
-		quotedColumn, quotedTable, quotedColumn)
+	// Ordering by the text cast keeps the sample stable across runs and works for every column type.
+	exampleLimit := database.ExampleQueryLimit(db.Config.ExampleSampleSize)
+	exampleQuery := fmt.Sprintf("SELECT DISTINCT %s::text FROM %s WHERE %s IS NOT NULL ORDER BY 1 LIMIT %d",
+		quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	firstRows := database.SampleWithoutDistinct(db, distinctCount)
+	if firstRows {
+		exampleQuery = fmt.Sprintf("SELECT %s::text FROM %s WHERE %s IS NOT NULL LIMIT %d",
+			quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	}
 	rows, err := db.Pool.QueryContext(ctx, exampleQuery)
+	if err != nil && !firstRows && h.permissionError(db, tableName, err) == nil {
+		log.Printf("WARN: Ordered example query failed for %s.%s: %v. Retrying without ORDER BY.", tableName, columnName, err)
+		unorderedQuery := fmt.Sprintf("SELECT DISTINCT %s::text FROM %s WHERE %s IS NOT NULL LIMIT %d",
+			quotedColumn, quotedTable, quotedColumn, exampleLimit)
+		rows, err = db.Pool.QueryContext(ctx, unorderedQuery)
+	}
 	if err != nil {
+		if permErr := h.permissionError(db, tableName, err); permErr != nil {
+			return nil, permErr
+		}
 		return nil, fmt.Errorf("failed to get example values for %s.%s: %w", tableName, columnName, err)
 	}
 	defer rows.Close()
@@ -203,12 +356,42 @@ func (h postgresHandler) GetColumnMetadata(db *database.DB, tableName string, co
 	if rows.Err() != nil {
 		return nil, fmt.Errorf("error iterating example values for %s.%s: %w", tableName, columnName, rows.Err())
 	}
+	if firstRows {
+		examples = database.UniqueValues(examples)
+	}
+	examples = database.SelectRepresentativeValues(examples, database.DefaultExampleCount)
 
-	return map[string]interface{}{
+	metadata := map[string]interface{}{
 		"DistinctCount": distinctCount,
 		"NullCount":     nullCount,
 		"ExampleValues": examples,
-	}, nil
+	}
+	// Only numeric columns are averaged: AVG of an interval would fail the float8 cast,
+	// and the query is a full scan.
+	if db.Config.ComputeAverage && database.IsNumericType(h.columnDataType(ctx, db, tableName, columnName)) {
+		avgQuery := fmt.Sprintf("SELECT AVG(%s)::float8 FROM %s", quotedColumn, quotedTable)
+		if avg := database.ColumnAverage(ctx, db, avgQuery); avg != nil {
+			metadata["Average"] = *avg
+		}
+	}
+	return metadata, nil
+}
+
+// columnDataType returns the name of a column's type, with domains resolved to their
+// base type, or "" if it cannot be read.
+func (h postgresHandler) columnDataType(ctx context.Context, db *database.DB, tableName, columnName string) string {
+	query, args := inSchema(db, `
+		SELECT data_type
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		AND table_name = $1
+		AND column_name = $2;`, tableName, columnName)
+	var dataType string
+	if err := db.Pool.QueryRowContext(ctx, query, args...).Scan(&dataType); err != nil {
+		log.Printf("WARN: Failed to read the type of %s.%s: %v", tableName, columnName, err)
+		return ""
+	}
+	return dataType
 }
 
 func (h postgresHandler) formatExampleValues(values []string) string {
@@ -226,15 +409,20 @@ func (h postgresHandler) formatExampleValues(values []string) string {
 	return fmt.Sprintf("Examples: [%s]", strings.Join(quoted, ", "))
 }
 
+// formatForeignKeys renders referenced columns with this dialect's identifier quoting.
+func (h postgresHandler) formatForeignKeys(foreignKeys []database.ForeignKeyReference) string {
+	return database.FormatForeignKeys(foreignKeys, h.QuoteIdentifier)
+}
+
 func (h postgresHandler) GenerateCommentSQL(db *database.DB, data *database.CommentData, enrichments map[string]bool) (string, error) {
 	if data == nil || data.TableName == "" || data.ColumnName == "" {
 		return "", fmt.Errorf("invalid input for GenerateCommentSQL")
 	}
 
 	formattedExamples := h.formatExampleValues(data.ExampleValues)
-	newMetadataComment := database.GenerateMetadataCommentString(data, enrichments, formattedExamples) // Use database.Generate...
+	newMetadataComment := database.GenerateMetadataCommentString(data, enrichments, formattedExamples, h.formatForeignKeys(data.ForeignKeys)) // Use database.Generate...
 
-	existingComment, err := h.GetColumnComment(context.Background(), db, data.TableName, data.ColumnName)
+	existingComment, err := database.ExistingColumnComment(context.Background(), db, h, data.TableName, data.ColumnName)
 	if err != nil && err != sql.ErrNoRows {
 		log.Printf("WARN: Failed to get existing column comment for %s.%s: %v. Proceeding as if empty.", data.TableName, data.ColumnName, err)
 		existingComment = ""
@@ -242,41 +430,126 @@ func (h postgresHandler) GenerateCommentSQL(db *database.DB, data *database.Comm
 
 	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode) // Use database.Merge...
 
-	quotedComment := pq.QuoteLiteral(finalComment)
-	return fmt.Sprintf(
-		"COMMENT ON COLUMN %s.%s IS %s;",
-		h.QuoteIdentifier(data.TableName),
-		h.QuoteIdentifier(data.ColumnName),
-		quotedComment,
-	), nil
+	return h.columnCommentSQL(context.Background(), db, data.TableName, data.ColumnName, finalComment, func(existing string) string {
+		return database.MergeComments(existing, newMetadataComment, db.Config.UpdateExistingMode)
+	})
 }
 
-func (h postgresHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+// GenerateRewriteCommentSQL returns the statement that replaces a column's comment
+// with rewrite(existing), or "" when that leaves the comment unchanged.
+func (h postgresHandler) GenerateRewriteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string, rewrite func(string) string) (string, error) {
 	if tableName == "" || columnName == "" {
-		return "", fmt.Errorf("table and column names cannot be empty for GenerateDeleteCommentSQL")
+		return "", fmt.Errorf("table and column names cannot be empty for GenerateRewriteCommentSQL")
 	}
 
-	existingComment, err := h.GetColumnComment(ctx, db, tableName, columnName)
+	existingComment, err := database.ExistingColumnComment(ctx, db, h, tableName, columnName)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", nil
 		}
-		return "", fmt.Errorf("failed to get existing column comment for %s.%s before delete: %w", tableName, columnName, err)
+		return "", fmt.Errorf("failed to get existing column comment for %s.%s before rewrite: %w", tableName, columnName, err)
 	}
 
-	finalComment := database.MergeComments(existingComment, "", "")
+	finalComment := rewrite(existingComment)
 
 	if finalComment == strings.TrimSpace(existingComment) {
 		return "", nil
 	}
 
-	quotedComment := pq.QuoteLiteral(finalComment)
-	return fmt.Sprintf(
+	return h.columnCommentSQL(ctx, db, tableName, columnName, finalComment, rewrite)
+}
+
+func (h postgresHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	return h.GenerateRewriteCommentSQL(ctx, db, tableName, columnName, database.StripMetadata)
+}
+
+// columnCommentSQL returns the COMMENT ON COLUMN statement for the column. With
+// --cascade-partitions, it is followed by one statement per partition of the
+// table (one per line), since comments on a partitioned table are not inherited.
+// Each partition's own comment is passed through update, and partitions whose
+// comment that leaves unchanged are skipped.
+func (h postgresHandler) columnCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string, comment string, update func(existing string) string) (string, error) {
+	statements := []string{fmt.Sprintf(
 		"COMMENT ON COLUMN %s.%s IS %s;",
-		h.QuoteIdentifier(tableName),
-		h.QuoteIdentifier(columnName),
-		quotedComment,
-	), nil
+		h.sqlTable(db, tableName),
+		h.sqlIdentifier(db, columnName),
+		pq.QuoteLiteral(comment),
+	)}
+
+	if db.Config.CascadePartitions {
+		partitions, err := h.listPartitions(ctx, db, tableName, columnName)
+		if err != nil {
+			return "", err
+		}
+		for _, partition := range partitions {
+			partitionComment := update(partition.comment)
+			if partitionComment == strings.TrimSpace(partition.comment) {
+				continue
+			}
+			statements = append(statements, fmt.Sprintf(
+				"COMMENT ON COLUMN %s.%s IS %s;",
+				partition.name,
+				h.sqlIdentifier(db, columnName),
+				pq.QuoteLiteral(partitionComment),
+			))
+		}
+	}
+	return strings.Join(statements, "\n"), nil
+}
+
+// partitionColumn is a partition, by its schema-qualified name as written in
+// generated SQL, and the current comment of one of its columns.
+type partitionColumn struct {
+	name    string
+	comment string
+}
+
+// listPartitions returns all partitions below tableName, including sub-partitions,
+// with their comment on columnName, or nil if the table is not partitioned.
+func (h postgresHandler) listPartitions(ctx context.Context, db *database.DB, tableName string, columnName string) ([]partitionColumn, error) {
+	query := `
+		WITH RECURSIVE partitions(oid) AS (
+			SELECT i.inhrelid
+			FROM pg_catalog.pg_inherits i
+			JOIN pg_catalog.pg_class p ON i.inhparent = p.oid
+			JOIN pg_catalog.pg_namespace n ON p.relnamespace = n.oid
+			WHERE n.nspname = current_schema()
+			  AND p.relname = $1
+			UNION ALL
+			SELECT i.inhrelid
+			FROM pg_catalog.pg_inherits i
+			JOIN partitions pt ON i.inhparent = pt.oid
+		)
+		SELECT n.nspname, c.relname, col_description(c.oid, a.attnum)
+		FROM partitions pt
+		JOIN pg_catalog.pg_class c ON c.oid = pt.oid
+		JOIN pg_catalog.pg_namespace n ON c.relnamespace = n.oid
+		LEFT JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attname = $2 AND NOT a.attisdropped
+		ORDER BY n.nspname, c.relname;`
+
+	query, args := inSchema(db, query, tableName, columnName)
+	rows, err := db.Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying partitions of table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var partitions []partitionColumn
+	for rows.Next() {
+		var schemaName, partitionName string
+		var comment sql.NullString
+		if err := rows.Scan(&schemaName, &partitionName, &comment); err != nil {
+			return nil, fmt.Errorf("error scanning partition of table %s: %w", tableName, err)
+		}
+		partitions = append(partitions, partitionColumn{
+			name:    fmt.Sprintf("%s.%s", h.sqlIdentifier(db, schemaName), h.sqlIdentifier(db, partitionName)),
+			comment: comment.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating partitions of table %s: %w", tableName, err)
+	}
+	return partitions, nil
 }
 
 func (h postgresHandler) GetColumnComment(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
@@ -290,8 +563,14 @@ func (h postgresHandler) GetColumnComment(ctx context.Context, db *database.DB,
 		  AND c.relname = $1
 		  AND a.attname = $2;
 	`
+	args := []interface{}{tableName, columnName}
+	if h.cockroach {
+		query = cockroachColumnCommentQuery
+		args = []interface{}{h.qualifiedTable(db, tableName), columnName}
+	}
+	query, args = inSchema(db, query, args...)
 	var comment sql.NullString
-	err := db.Pool.QueryRowContext(ctx, query, tableName, columnName).Scan(&comment)
+	err := db.Pool.QueryRowContext(ctx, query, args...).Scan(&comment)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -303,6 +582,47 @@ func (h postgresHandler) GetColumnComment(ctx context.Context, db *database.DB,
 	return comment.String, nil
 }
 
+// GetAllColumnComments reads the comments on every column of a table in one query.
+func (h postgresHandler) GetAllColumnComments(ctx context.Context, db *database.DB, tableName string) (map[string]string, error) {
+	query := `
+		SELECT a.attname, d.description
+		FROM pg_catalog.pg_description d
+		JOIN pg_catalog.pg_class c ON d.objoid = c.oid
+		JOIN pg_catalog.pg_namespace n ON c.relnamespace = n.oid
+		JOIN pg_catalog.pg_attribute a ON d.objoid = a.attrelid AND d.objsubid = a.attnum
+		WHERE n.nspname = current_schema()
+		  AND c.relname = $1
+		  AND a.attnum > 0;
+	`
+	args := []interface{}{tableName}
+	if h.cockroach {
+		query = cockroachAllColumnCommentsQuery
+		args = []interface{}{h.qualifiedTable(db, tableName)}
+	}
+	query, args = inSchema(db, query, args...)
+	rows, err := db.Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve column comments for table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	comments := make(map[string]string)
+	for rows.Next() {
+		var column string
+		var comment sql.NullString
+		if err := rows.Scan(&column, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column comment for table %s: %w", tableName, err)
+		}
+		if comment.Valid && comment.String != "" {
+			comments[column] = comment.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column comments for table %s: %w", tableName, err)
+	}
+	return comments, nil
+}
+
 func (h postgresHandler) GenerateTableCommentSQL(db *database.DB, data *database.TableCommentData, enrichments map[string]bool) (string, error) {
 	if data == nil || data.TableName == "" {
 		return "", fmt.Errorf("invalid input for GenerateTableCommentSQL")
@@ -310,7 +630,7 @@ func (h postgresHandler) GenerateTableCommentSQL(db *database.DB, data *database
 
 	newMetadataComment := database.GenerateTableMetadataCommentString(data, enrichments) // Use database.Generate...
 
-	existingComment, err := h.GetTableComment(context.Background(), db, data.TableName)
+	existingComment, err := database.ExistingTableComment(context.Background(), db, h, data.TableName)
 	if err != nil && err != sql.ErrNoRows {
 		log.Printf("WARN: Failed to get existing table comment for %s: %v. Proceeding as if empty.", data.TableName, err)
 		existingComment = ""
@@ -325,11 +645,73 @@ func (h postgresHandler) GenerateTableCommentSQL(db *database.DB, data *database
 	quotedComment := pq.QuoteLiteral(finalComment)
 	return fmt.Sprintf(
 		"COMMENT ON TABLE %s IS %s;",
-		h.QuoteIdentifier(data.TableName),
+		h.sqlTable(db, data.TableName),
 		quotedComment,
 	), nil
 }
 
+// tableVersionQuery combines the table's storage file, which TRUNCATE and VACUUM FULL
+// replace, with its row change counters.
+const tableVersionQuery = `
+		SELECT c.relfilenode::text || ':' || s.n_tup_ins || ':' || s.n_tup_upd || ':' || s.n_tup_del
+		FROM pg_catalog.pg_stat_user_tables s
+		JOIN pg_catalog.pg_class c ON c.oid = s.relid
+		WHERE s.schemaname = current_schema()
+		AND s.relname = $1;`
+
+// TableVersion implements database.TableVersioner. The counters are reported by the
+// statistics collector, which can lag a transaction by a moment, and restart when
+// statistics are reset; either way the version changes and statistics are recollected.
+// CockroachDB has no equivalent counters.
+func (h postgresHandler) TableVersion(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	if h.cockroach {
+		return "", nil
+	}
+	var version sql.NullString
+	query, args := inSchema(db, tableVersionQuery, tableName)
+	err := db.Pool.QueryRowContext(ctx, query, args...).Scan(&version)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read change counters of table %s: %w", tableName, err)
+	}
+	return version.String, nil
+}
+
+// JSONKeys implements database.JSONKeySampler for json and jsonb columns. Values that
+// are arrays or scalars have no keys and are skipped, since jsonb_object_keys rejects them.
+func (h postgresHandler) JSONKeys(ctx context.Context, db *database.DB, tableName string, columnName string) ([]string, error) {
+	quotedTable := h.qualifiedTable(db, tableName)
+	if h.cockroach {
+		quotedTable = h.cockroachMetadataSource(ctx, db, tableName)
+	}
+	quotedColumn := h.QuoteIdentifier(columnName)
+	query := fmt.Sprintf(`SELECT DISTINCT jsonb_object_keys(v) FROM (SELECT %s::jsonb AS v FROM %s WHERE %s IS NOT NULL LIMIT %d) AS sample WHERE jsonb_typeof(v) = 'object' ORDER BY 1 LIMIT %d`,
+		quotedColumn, quotedTable, quotedColumn, database.JSONKeySampleRows, database.MaxJSONKeys)
+	rows, err := db.Pool.QueryContext(ctx, query)
+	if err != nil {
+		if permErr := h.permissionError(db, tableName, err); permErr != nil {
+			return nil, permErr
+		}
+		return nil, fmt.Errorf("failed to get JSON keys for %s.%s: %w", tableName, columnName, err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("error scanning JSON key for %s.%s: %w", tableName, columnName, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating JSON keys for %s.%s: %w", tableName, columnName, err)
+	}
+	return keys, nil
+}
+
 func (h postgresHandler) GetTableComment(ctx context.Context, db *database.DB, tableName string) (string, error) {
 	query := `
         SELECT pg_catalog.obj_description(c.oid, 'pg_class')
@@ -338,8 +720,14 @@ func (h postgresHandler) GetTableComment(ctx context.Context, db *database.DB, t
         WHERE n.nspname = current_schema()
           AND c.relname = $1;
     `
+	args := []interface{}{tableName}
+	if h.cockroach {
+		query = cockroachTableCommentQuery
+		args = []interface{}{h.qualifiedTable(db, tableName)}
+	}
+	query, args = inSchema(db, query, args...)
 	var comment sql.NullString
-	err := db.Pool.QueryRowContext(ctx, query, tableName).Scan(&comment)
+	err := db.Pool.QueryRowContext(ctx, query, args...).Scan(&comment)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", nil
@@ -350,9 +738,10 @@ func (h postgresHandler) GetTableComment(ctx context.Context, db *database.DB, t
 	return comment.String, nil
 }
 
-func (h postgresHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *database.DB, tableName string) (string, error) {
+// GenerateRewriteTableCommentSQL is GenerateRewriteCommentSQL for the table comment.
+func (h postgresHandler) GenerateRewriteTableCommentSQL(ctx context.Context, db *database.DB, tableName string, rewrite func(string) string) (string, error) {
 	if tableName == "" {
-		return "", fmt.Errorf("table name cannot be empty for GenerateDeleteTableCommentSQL")
+		return "", fmt.Errorf("table name cannot be empty for GenerateRewriteTableCommentSQL")
 	}
 
 	existingComment, err := h.GetTableComment(ctx, db, tableName)
@@ -360,10 +749,10 @@ func (h postgresHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *
 		if err == sql.ErrNoRows {
 			return "", nil
 		}
-		return "", fmt.Errorf("failed to get existing table comment for %s before delete: %w", tableName, err)
+		return "", fmt.Errorf("failed to get existing table comment for %s before rewrite: %w", tableName, err)
 	}
 
-	finalComment := database.MergeComments(existingComment, "", "")
+	finalComment := rewrite(existingComment)
 
 	if finalComment == strings.TrimSpace(existingComment) {
 		return "", nil
@@ -372,11 +761,15 @@ func (h postgresHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *
 	quotedComment := pq.QuoteLiteral(finalComment)
 	return fmt.Sprintf(
 		"COMMENT ON TABLE %s IS %s;",
-		h.QuoteIdentifier(tableName),
+		h.sqlTable(db, tableName),
 		quotedComment,
 	), nil
 }
 
+func (h postgresHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	return h.GenerateRewriteTableCommentSQL(ctx, db, tableName, database.StripMetadata)
+}
+
 func (h postgresHandler) GetForeignKeys(db *database.DB, tableName string, columnName string) ([]database.ForeignKeyReference, error) {
 	query := `
 		SELECT
@@ -395,7 +788,8 @@ func (h postgresHandler) GetForeignKeys(db *database.DB, tableName string, colum
 		    AND kcu.column_name = $2
 		    AND tc.table_schema = current_schema()`
 
-	rows, err := db.Pool.Query(query, tableName, columnName)
+	query, args := inSchema(db, query, tableName, columnName)
+	rows, err := db.Pool.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying foreign keys for table %s, column %s: %w", tableName, columnName, err)
 	}
@@ -417,6 +811,36 @@ func (h postgresHandler) GetForeignKeys(db *database.DB, tableName string, colum
 	return foreignKeys, nil
 }
 
+// ForeignKeyMatchQuery implements database.ForeignKeyMatcher. The referenced table is
+// in the same schema, as GetForeignKeys only follows those. On CockroachDB the query
+// is a historical read, like the other metadata queries, when both tables allow one.
+func (h postgresHandler) ForeignKeyMatchQuery(ctx context.Context, db *database.DB, tableName string, columnName string, fk database.ForeignKeyReference) string {
+	quotedTable := h.qualifiedTable(db, tableName)
+	quotedReferencedTable := h.qualifiedTable(db, fk.ReferencedTable)
+	query := database.ForeignKeyMatchSQL(quotedTable, h.QuoteIdentifier(columnName), quotedReferencedTable, h.QuoteIdentifier(fk.ReferencedColumn))
+	if h.cockroach && h.cockroachMetadataSource(ctx, db, tableName) != quotedTable && h.cockroachMetadataSource(ctx, db, fk.ReferencedTable) != quotedReferencedTable {
+		// AS OF SYSTEM TIME applies to the whole statement and follows its FROM clause,
+		// so it goes after the join rather than after a table.
+		query = strings.Replace(query, " WHERE ", " "+cockroachHistoricalRead+" WHERE ", 1)
+	}
+	return query
+}
+
+// permissionError returns a database.PermissionError when err is Postgres'
+// insufficient_privilege (SQLSTATE 42501), and nil otherwise.
+func (h postgresHandler) permissionError(db *database.DB, tableName string, err error) error {
+	var pqErr *pq.Error
+	var pgErr *pgconn.PgError
+	if (errors.As(err, &pqErr) && pqErr.Code == "42501") || (errors.As(err, &pgErr) && pgErr.Code == "42501") {
+		return &database.PermissionError{
+			Table: tableName,
+			Grant: fmt.Sprintf("GRANT SELECT ON %s TO %s;", h.qualifiedTable(db, tableName), h.QuoteIdentifier(db.Config.User)),
+			Err:   err,
+		}
+	}
+	return nil
+}
+
 func init() {
 	database.RegisterDialectHandler("postgres", postgresHandler{})
 	database.RegisterDialectHandler("cloudsqlpostgres", postgresHandler{})
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -57,23 +58,111 @@ func TestPostgresQuoteIdentifier(t *testing.T) {
 	}
 }
 
-func TestPostgresListTables(t *testing.T) {
+func TestPostgresLowercaseIdentifiers(t *testing.T) {
+	tests := []struct {
+		name          string
+		lowercase     bool
+		table, column string
+		want          string
+	}{
+		{"Preserve case quotes lower-case names", false, "orders", "status", `COMMENT ON COLUMN "orders"."status" IS '<gemini>Order status</gemini>';`},
+		{"Preserve case quotes mixed-case names", false, "Orders", "Status", `COMMENT ON COLUMN "Orders"."Status" IS '<gemini>Order status</gemini>';`},
+		{"Lowercase leaves folded names unquoted", true, "orders", "status_2", `COMMENT ON COLUMN orders.status_2 IS '<gemini>Order status</gemini>';`},
+		{"Lowercase keeps mixed-case names quoted", true, "Orders", "status", `COMMENT ON COLUMN "Orders".status IS '<gemini>Order status</gemini>';`},
+		{"Lowercase keeps reserved words quoted", true, "user", "order", `COMMENT ON COLUMN "user"."order" IS '<gemini>Order status</gemini>';`},
+		{"Lowercase keeps special characters quoted", true, "orders", "2nd status", `COMMENT ON COLUMN orders."2nd status" IS '<gemini>Order status</gemini>';`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, handler := newMockPostgresDB(t)
+			defer db.Close()
+			db.Config.LowercaseIdentifiers = tt.lowercase
+
+			mock.ExpectQuery(`SELECT description\s+FROM pg_catalog\.pg_description`).
+				WithArgs(tt.table, tt.column).
+				WillReturnRows(sqlmock.NewRows([]string{"description"}))
+
+			data := &database.CommentData{TableName: tt.table, ColumnName: tt.column, Description: "Order status"}
+			got, err := handler.GenerateCommentSQL(db, data, map[string]bool{"description": true})
+			if err != nil {
+				t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GenerateCommentSQL() = %s, want %s", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %s", err)
+			}
+		})
+	}
+
 	db, mock, handler := newMockPostgresDB(t)
 	defer db.Close()
+	db.Config.LowercaseIdentifiers = true
+	mock.ExpectQuery(`SELECT pg_catalog\.obj_description`).
+		WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"obj_description"}))
+	got, err := handler.GenerateTableCommentSQL(db, &database.TableCommentData{TableName: "orders", Description: "Customer orders"}, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateTableCommentSQL() unexpected error: %v", err)
+	}
+	if want := `COMMENT ON TABLE orders IS '<gemini>Customer orders</gemini>';`; got != want {
+		t.Errorf("GenerateTableCommentSQL() = %s, want %s", got, want)
+	}
+}
 
-	query := `
-		SELECT table_name
-		FROM information_schema.tables
-		WHERE table_schema = current_schema()
-		AND table_type = 'BASE TABLE'
-		ORDER BY table_name;`
+func TestPostgresStandardConnString(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.DatabaseConfig
+		want string
+	}{
+		{
+			name: "TCP",
+			cfg:  config.DatabaseConfig{Host: "localhost", Port: 5432, User: "u", Password: "p", DBName: "db"},
+			want: "host=localhost port=5432 user=u password=p dbname=db sslmode=disable application_name=db_schema_enricher",
+		},
+		{
+			name: "Socket without port",
+			cfg:  config.DatabaseConfig{Socket: "/var/run/postgresql", User: "u", Password: "p", DBName: "db"},
+			want: "host=/var/run/postgresql user=u password=p dbname=db sslmode=disable application_name=db_schema_enricher",
+		},
+		{
+			name: "Socket with port",
+			cfg:  config.DatabaseConfig{Socket: "/tmp", Port: 5433, User: "u", Password: "p", DBName: "db", SSLMode: "require"},
+			want: "host=/tmp port=5433 user=u password=p dbname=db sslmode=require application_name=db_schema_enricher",
+		},
+		{
+			name: "Driver params",
+			cfg: config.DatabaseConfig{Host: "localhost", Port: 5432, User: "u", Password: "p", DBName: "db", DriverParams: map[string]string{
+				"connect_timeout":  "5",
+				"application_name": "db enricher",
+				"sslmode":          "verify-full",
+				"options":          `-c search_path='a\b'`,
+			}},
+			want: `host=localhost port=5432 user=u password=p dbname=db sslmode=disable application_name=db_schema_enricher application_name='db enricher' connect_timeout=5 options='-c search_path=\'a\\b\'' sslmode=verify-full`,
+		},
+	}
 
-	expectedQuery := regexp.QuoteMeta(query)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := standardConnString(tt.cfg); got != tt.want {
+				t.Errorf("standardConnString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresListTables(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+
+	expectedQuery := regexp.QuoteMeta(listTablesQuery)
 
 	t.Run("Success", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"table_name"}).
-			AddRow("users").
-			AddRow("products")
+		rows := sqlmock.NewRows([]string{"table_name", "table_type"}).
+			AddRow("users", "BASE TABLE").
+			AddRow("products", "BASE TABLE")
 		mock.ExpectQuery(expectedQuery).WillReturnRows(rows)
 
 		tables, err := handler.ListTables(db)
@@ -99,15 +188,63 @@ func TestPostgresListTables(t *testing.T) {
 		}
 	})
 
+	t.Run("NULL Name Skipped", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"table_name", "table_type"}).
+			AddRow("users", "BASE TABLE").
+			AddRow(nil, "BASE TABLE").
+			AddRow("products", "BASE TABLE")
+		mock.ExpectQuery(expectedQuery).WillReturnRows(rows)
+
+		tables, err := handler.ListTables(db)
+		if err != nil {
+			t.Fatalf("ListTables() unexpected error: %v", err)
+		}
+		if len(tables) != 2 || tables[0] != "users" || tables[1] != "products" {
+			t.Errorf("ListTables() got %v, want [users products]", tables)
+		}
+	})
+
+	t.Run("Temporary Tables Skipped", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"table_name", "table_type"}).
+			AddRow("scratch", "LOCAL TEMPORARY").
+			AddRow("users", "BASE TABLE")
+		mock.ExpectQuery(expectedQuery).WillReturnRows(rows)
+
+		tables, err := handler.ListTables(db)
+		if err != nil {
+			t.Fatalf("ListTables() unexpected error: %v", err)
+		}
+		if len(tables) != 1 || tables[0] != "users" {
+			t.Errorf("ListTables() got %v, want [users]", tables)
+		}
+	})
+
+	t.Run("Temporary Tables Included", func(t *testing.T) {
+		db.Config.IncludeTempTables = true
+		defer func() { db.Config.IncludeTempTables = false }()
+		rows := sqlmock.NewRows([]string{"table_name", "table_type"}).
+			AddRow("scratch", "LOCAL TEMPORARY").
+			AddRow("users", "BASE TABLE")
+		mock.ExpectQuery(expectedQuery).WillReturnRows(rows)
+
+		tables, err := handler.ListTables(db)
+		if err != nil {
+			t.Fatalf("ListTables() unexpected error: %v", err)
+		}
+		if len(tables) != 2 || tables[0] != "scratch" || tables[1] != "users" {
+			t.Errorf("ListTables() got %v, want [scratch users]", tables)
+		}
+	})
+
 	t.Run("Scan Error", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"table_name"}).
-			AddRow("users").
-			AddRow(nil) // Simulate a scan error
+		rows := sqlmock.NewRows([]string{"table_name", "table_type"}).
+			AddRow("users", "BASE TABLE").
+			RowError(0, errors.New("connection reset"))
 		mock.ExpectQuery(expectedQuery).WillReturnRows(rows)
 
 		_, err := handler.ListTables(db)
 		if err == nil {
-			t.Fatalf("ListTables() expected scan error, got nil")
+			t.Fatalf("ListTables() expected error, got nil")
 		}
 	})
 
@@ -121,18 +258,15 @@ func TestPostgresListColumns(t *testing.T) {
 	defer db.Close()
 	tableName := "users"
 
-	query := `
-		SELECT column_name, data_type
-		FROM information_schema.columns
-		WHERE table_schema = current_schema()
-		AND table_name = $1
-		ORDER BY ordinal_position;`
-	expectedQuery := regexp.QuoteMeta(query)
+	expectedQuery := regexp.QuoteMeta(listColumnsQuery)
+	columnRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"column_name", "data_type", "domain_name", "udt_name", "typtype"})
+	}
 
 	t.Run("Success", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"column_name", "data_type"}).
-			AddRow("id", "integer").
-			AddRow("email", "character varying")
+		rows := columnRows().
+			AddRow("id", "integer", "", "int4", "b").
+			AddRow("email", "character varying", "", "varchar", "b")
 		mock.ExpectQuery(expectedQuery).WithArgs(tableName).WillReturnRows(rows)
 
 		cols, err := handler.ListColumns(db, tableName)
@@ -154,6 +288,63 @@ func TestPostgresListColumns(t *testing.T) {
 		}
 	})
 
+	t.Run("Domains and user-defined types", func(t *testing.T) {
+		rows := columnRows().
+			AddRow("contact", "text", "email_address", "text", "b").
+			AddRow("mood", "USER-DEFINED", "", "mood", "e").
+			AddRow("shipping", "USER-DEFINED", "", "address", "c").
+			AddRow("billing", "USER-DEFINED", "billing_address", "address", "c").
+			AddRow("stay", "USER-DEFINED", "", "daterange", "r")
+		mock.ExpectQuery(expectedQuery).WithArgs(tableName).WillReturnRows(rows)
+
+		cols, err := handler.ListColumns(db, tableName)
+		if err != nil {
+			t.Fatalf("ListColumns() unexpected error: %v", err)
+		}
+
+		expectedCols := []database.ColumnInfo{
+			{Name: "contact", DataType: "email_address (domain over text)", BaseType: "text"},
+			{Name: "mood", DataType: "mood (enum)"},
+			{Name: "shipping", DataType: "address (composite)", StatsUnsupported: true},
+			{Name: "billing", DataType: "billing_address (domain over address (composite))", BaseType: "address (composite)", StatsUnsupported: true},
+			{Name: "stay", DataType: "daterange (range)"},
+		}
+		if len(cols) != len(expectedCols) {
+			t.Fatalf("ListColumns() got %d columns, want %d", len(cols), len(expectedCols))
+		}
+		for i := range cols {
+			if cols[i] != expectedCols[i] {
+				t.Errorf("ListColumns() col %d got %+v, want %+v", i, cols[i], expectedCols[i])
+			}
+		}
+	})
+
+	t.Run("NULL Name Skipped", func(t *testing.T) {
+		rows := columnRows().
+			AddRow("id", "integer", "", "int4", "b").
+			AddRow(nil, "text", "", "text", "b").
+			AddRow("note", nil, "", nil, "")
+		mock.ExpectQuery(expectedQuery).WithArgs(tableName).WillReturnRows(rows)
+
+		cols, err := handler.ListColumns(db, tableName)
+		if err != nil {
+			t.Fatalf("ListColumns() unexpected error: %v", err)
+		}
+
+		expectedCols := []database.ColumnInfo{
+			{Name: "id", DataType: "integer"},
+			{Name: "note", DataType: ""},
+		}
+		if len(cols) != len(expectedCols) {
+			t.Fatalf("ListColumns() got %d columns, want %d", len(cols), len(expectedCols))
+		}
+		for i := range cols {
+			if cols[i] != expectedCols[i] {
+				t.Errorf("ListColumns() col %d got %+v, want %+v", i, cols[i], expectedCols[i])
+			}
+		}
+	})
+
 	t.Run("Query Error", func(t *testing.T) {
 		dbError := errors.New("table not found")
 		mock.ExpectQuery(expectedQuery).WithArgs(tableName).WillReturnError(dbError)
@@ -278,7 +469,7 @@ func TestPostgresGenerateCommentSQL(t *testing.T) {
 			t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
 		}
 
-		expectedMetadata := "User Email | Examples: 'test@example.com', 'another''email@test.co' | Distinct: 150 | Nulls: 5"
+		expectedMetadata := "Examples: ['test@example.com', 'another''email@test.co'] | Distinct: 150 | Nulls: 5 | User Email"
 		expectedFinalComment := fmt.Sprintf("<gemini>%s</gemini>", expectedMetadata)
 		expectedSQL := fmt.Sprintf(`COMMENT ON COLUMN "users"."email" IS %s;`, pq.QuoteLiteral(expectedFinalComment))
 
@@ -306,7 +497,7 @@ func TestPostgresGenerateCommentSQL(t *testing.T) {
 			t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
 		}
 
-		expectedMetadata := "User Email | Examples: 'test@example.com', 'another''email@test.co' | Distinct: 150 | Nulls: 5"
+		expectedMetadata := "Examples: ['test@example.com', 'another''email@test.co'] | Distinct: 150 | Nulls: 5 | User Email"
 		expectedFinalComment := fmt.Sprintf("Old user comment <gemini>%s</gemini>", expectedMetadata) // Overwrites <gemini> content
 		expectedSQL := fmt.Sprintf(`COMMENT ON COLUMN "users"."email" IS %s;`, pq.QuoteLiteral(expectedFinalComment))
 
@@ -334,7 +525,7 @@ func TestPostgresGenerateCommentSQL(t *testing.T) {
 			t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
 		}
 
-		expectedMetadata := "User Email | Examples: 'test@example.com', 'another''email@test.co' | Distinct: 150 | Nulls: 5"
+		expectedMetadata := "Examples: ['test@example.com', 'another''email@test.co'] | Distinct: 150 | Nulls: 5 | User Email"
 		expectedFinalComment := fmt.Sprintf("Old user comment <gemini>Old Data | %s</gemini>", expectedMetadata) // Appends to <gemini> content
 		expectedSQL := fmt.Sprintf(`COMMENT ON COLUMN "users"."email" IS %s;`, pq.QuoteLiteral(expectedFinalComment))
 
@@ -362,7 +553,7 @@ func TestPostgresGenerateCommentSQL(t *testing.T) {
 		}
 
 		// Expects the new comment structure as if existing was empty
-		expectedMetadata := "User Email | Examples: 'test@example.com', 'another''email@test.co' | Distinct: 150 | Nulls: 5"
+		expectedMetadata := "Examples: ['test@example.com', 'another''email@test.co'] | Distinct: 150 | Nulls: 5 | User Email"
 		expectedFinalComment := fmt.Sprintf("<gemini>%s</gemini>", expectedMetadata)
 		expectedSQL := fmt.Sprintf(`COMMENT ON COLUMN "users"."email" IS %s;`, pq.QuoteLiteral(expectedFinalComment))
 
@@ -511,7 +702,8 @@ func TestPostgresGetColumnMetadata(t *testing.T) {
 
 	distinctQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT COUNT(DISTINCT %s::text) FROM %s`, handler.QuoteIdentifier(columnName), handler.QuoteIdentifier(tableName)))
 	nullQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s IS NULL`, handler.QuoteIdentifier(tableName), handler.QuoteIdentifier(columnName)))
-	exampleQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT DISTINCT %s::text FROM %s WHERE %s IS NOT NULL LIMIT 3`, handler.QuoteIdentifier(columnName), handler.QuoteIdentifier(tableName), handler.QuoteIdentifier(columnName)))
+	exampleQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT DISTINCT %s::text FROM %s WHERE %s IS NOT NULL ORDER BY 1 LIMIT 3`, handler.QuoteIdentifier(columnName), handler.QuoteIdentifier(tableName), handler.QuoteIdentifier(columnName)))
+	unorderedExampleQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT DISTINCT %s::text FROM %s WHERE %s IS NOT NULL LIMIT 3`, handler.QuoteIdentifier(columnName), handler.QuoteIdentifier(tableName), handler.QuoteIdentifier(columnName)))
 
 	t.Run("Success", func(t *testing.T) {
 		mock.ExpectQuery(distinctQuery).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(50)))
@@ -575,6 +767,7 @@ func TestPostgresGetColumnMetadata(t *testing.T) {
 		mock.ExpectQuery(distinctQuery).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(10)))
 		mock.ExpectQuery(nullQuery).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
 		mock.ExpectQuery(exampleQuery).WillReturnError(errors.New("example fetch error"))
+		mock.ExpectQuery(unorderedExampleQuery).WillReturnError(errors.New("example fetch error"))
 
 		_, err := handler.GetColumnMetadata(db, tableName, columnName)
 		if err == nil {
@@ -588,6 +781,236 @@ func TestPostgresGetColumnMetadata(t *testing.T) {
 	}
 }
 
+func TestPostgresGetColumnMetadataWideSample(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+	db.Config.ExampleSampleSize = 10
+	tableName := "orders"
+	columnName := "status"
+
+	distinctQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT COUNT(DISTINCT %s::text) FROM %s`, handler.QuoteIdentifier(columnName), handler.QuoteIdentifier(tableName)))
+	nullQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s IS NULL`, handler.QuoteIdentifier(tableName), handler.QuoteIdentifier(columnName)))
+	exampleQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT DISTINCT %s::text FROM %s WHERE %s IS NOT NULL ORDER BY 1 LIMIT 10`, handler.QuoteIdentifier(columnName), handler.QuoteIdentifier(tableName), handler.QuoteIdentifier(columnName)))
+
+	exampleRows := sqlmock.NewRows([]string{"status"})
+	for _, v := range []string{"a01", "a02", "a03", "a04", "a05", "a06", "a07", "a08", "a09", "a10"} {
+		exampleRows.AddRow(v)
+	}
+	mock.ExpectQuery(distinctQuery).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(500)))
+	mock.ExpectQuery(nullQuery).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(exampleQuery).WillReturnRows(exampleRows)
+
+	metadata, err := handler.GetColumnMetadata(db, tableName, columnName)
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+
+	ev, ok := metadata["ExampleValues"].([]string)
+	if !ok || len(ev) != 3 || ev[0] != "a01" || ev[1] != "a05" || ev[2] != "a10" {
+		t.Errorf("Expected representative ExampleValues [a01 a05 a10], got %v (%T)", metadata["ExampleValues"], metadata["ExampleValues"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostgresGetColumnMetadataHighCardinality(t *testing.T) {
+	tests := []struct {
+		name          string
+		distinctCount int64
+		exampleQuery  string
+	}{
+		{"above the threshold reads the first rows", 5000, `SELECT "email"::text FROM "users" WHERE "email" IS NOT NULL LIMIT 3`},
+		{"at the threshold keeps DISTINCT", 1000, `SELECT DISTINCT "email"::text FROM "users" WHERE "email" IS NOT NULL ORDER BY 1 LIMIT 3`},
+		{"unknown count keeps DISTINCT", -1, `SELECT DISTINCT "email"::text FROM "users" WHERE "email" IS NOT NULL ORDER BY 1 LIMIT 3`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, handler := newMockPostgresDB(t)
+			defer db.Close()
+			db.Config.MaxDistinctForExamples = 1000
+
+			distinct := mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT "email"::text) FROM "users"`))
+			if tt.distinctCount < 0 {
+				distinct.WillReturnError(fmt.Errorf("could not identify an equality operator"))
+			} else {
+				distinct.WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.distinctCount))
+			}
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM "users" WHERE "email" IS NULL`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+			mock.ExpectQuery("^" + regexp.QuoteMeta(tt.exampleQuery) + "$").
+				WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("c@example.com").AddRow("a@example.com").AddRow("c@example.com"))
+
+			metadata, err := handler.GetColumnMetadata(db, "users", "email")
+			if err != nil {
+				t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+			}
+			if metadata["DistinctCount"] != tt.distinctCount {
+				t.Errorf("Expected DistinctCount %d, got %v", tt.distinctCount, metadata["DistinctCount"])
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestPostgresGetColumnMetadataHighCardinalityDropsRepeats(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+	db.Config.MaxDistinctForExamples = 10
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT "email"::text) FROM "users"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(5000)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM "users" WHERE "email" IS NULL`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "email"::text FROM "users" WHERE "email" IS NOT NULL LIMIT 3`)).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("c@example.com").AddRow("a@example.com").AddRow("c@example.com"))
+
+	metadata, err := handler.GetColumnMetadata(db, "users", "email")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	ev, ok := metadata["ExampleValues"].([]string)
+	if !ok || len(ev) != 2 || ev[0] != "a@example.com" || ev[1] != "c@example.com" {
+		t.Errorf("Expected ExampleValues without repeats [a@example.com c@example.com], got %v", metadata["ExampleValues"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostgresGetColumnMetadataBinaryDistinctCollation(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+	db.Config.DistinctCollation = database.DistinctCollationBinary
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT "code"::text COLLATE "C") FROM "products"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(4)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM "products" WHERE "code" IS NULL`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT "code"::text FROM "products"`)).WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow("A").AddRow("a"))
+
+	metadata, err := handler.GetColumnMetadata(db, "products", "code")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if metadata["DistinctCount"] != int64(4) {
+		t.Errorf("Expected DistinctCount 4, got %v", metadata["DistinctCount"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostgresGetColumnMetadataPermissionDenied(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+	db.Config.User = "enricher"
+	tableName := "payroll"
+	columnName := "salary"
+
+	distinctQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT COUNT(DISTINCT %s::text) FROM %s`, handler.QuoteIdentifier(columnName), handler.QuoteIdentifier(tableName)))
+	nullQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s IS NULL`, handler.QuoteIdentifier(tableName), handler.QuoteIdentifier(columnName)))
+	permErr := &pq.Error{Code: "42501", Message: "permission denied for table payroll"}
+	mock.ExpectQuery(distinctQuery).WillReturnError(permErr)
+	mock.ExpectQuery(nullQuery).WillReturnError(permErr)
+
+	_, err := handler.GetColumnMetadata(db, tableName, columnName)
+	var pe *database.PermissionError
+	if !errors.As(err, &pe) {
+		t.Fatalf("GetColumnMetadata() error = %v, want *database.PermissionError", err)
+	}
+	if pe.Table != tableName {
+		t.Errorf("PermissionError.Table = %q, want %q", pe.Table, tableName)
+	}
+	wantGrant := `GRANT SELECT ON "payroll" TO "enricher";`
+	if pe.Grant != wantGrant {
+		t.Errorf("PermissionError.Grant = %q, want %q", pe.Grant, wantGrant)
+	}
+	if !errors.Is(err, permErr) {
+		t.Errorf("PermissionError should unwrap to the driver error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// TestPostgresAverage checks the AVG query and the rendered average, and that a
+// column that is not numeric is not averaged.
+func TestPostgresAverage(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+	db.Config.ComputeAverage = true
+
+	expectStatistics := func(column string) {
+		mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT COUNT(DISTINCT "%s"::text) FROM "orders"`, column))).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+		mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT COUNT(*) FROM "orders" WHERE "%s" IS NULL`, column))).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT DISTINCT "%s"::text FROM "orders"`, column))).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("10"))
+	}
+
+	expectType := func(column, dataType string) {
+		mock.ExpectQuery(`SELECT data_type\s+FROM information_schema.columns`).WithArgs("orders", column).
+			WillReturnRows(sqlmock.NewRows([]string{"data_type"}).AddRow(dataType))
+	}
+
+	expectStatistics("amount")
+	expectType("amount", "numeric")
+	mock.ExpectQuery("^" + regexp.QuoteMeta(`SELECT AVG("amount")::float8 FROM "orders"`) + "$").WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(12.3456))
+	metadata, err := handler.GetColumnMetadata(db, "orders", "amount")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	avg, ok := metadata["Average"].(float64)
+	if !ok || avg != 12.3456 {
+		t.Fatalf("GetColumnMetadata() Average = %v, want 12.3456", metadata["Average"])
+	}
+
+	mock.ExpectQuery(`SELECT description\s+FROM pg_catalog.pg_description`).WithArgs("orders", "amount").
+		WillReturnRows(sqlmock.NewRows([]string{"description"}))
+	got, err := handler.GenerateCommentSQL(db, &database.CommentData{TableName: "orders", ColumnName: "amount", Average: &avg}, map[string]bool{"average": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	want := `COMMENT ON COLUMN "orders"."amount" IS '<gemini>Avg: 12.35</gemini>';`
+	if got != want {
+		t.Errorf("GenerateCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+
+	expectStatistics("status")
+	expectType("status", "text")
+	metadata, err = handler.GetColumnMetadata(db, "orders", "status")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error for a text column: %v", err)
+	}
+	if _, ok := metadata["Average"]; ok {
+		t.Errorf("GetColumnMetadata() Average = %v for a text column, want none", metadata["Average"])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostgresJSONKeys(t *testing.T) {
+	db, mock, _ := newMockPostgresDB(t)
+	defer db.Close()
+
+	// Only object values are expanded; arrays and scalars in the sample are filtered out.
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT jsonb_object_keys(v) FROM (SELECT "attributes"::jsonb AS v FROM "products" WHERE "attributes" IS NOT NULL LIMIT 1000) AS sample WHERE jsonb_typeof(v) = 'object' ORDER BY 1 LIMIT 20`)).
+		WillReturnRows(sqlmock.NewRows([]string{"jsonb_object_keys"}).AddRow("id").AddRow("name").AddRow("tags"))
+
+	keys, err := db.JSONKeys(context.Background(), "products", "attributes")
+	if err != nil {
+		t.Fatalf("JSONKeys() unexpected error: %v", err)
+	}
+	if got, want := strings.Join(keys, ","), "id,name,tags"; got != want {
+		t.Errorf("JSONKeys() = %v, want %s", keys, want)
+	}
+	metadata := database.GenerateMetadataCommentString(&database.CommentData{JSONKeys: keys}, map[string]bool{"json_keys": true}, "", "")
+	if want := "Keys: id, name, tags"; metadata != want {
+		t.Errorf("GenerateMetadataCommentString() = %q, want %q", metadata, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestPostgresFormatExampleValues(t *testing.T) {
 	handler := postgresHandler{}
 
@@ -597,13 +1020,13 @@ func TestPostgresFormatExampleValues(t *testing.T) {
 		want   string
 	}{
 		{"No values", []string{}, ""},
-		{"Single value", []string{"abc"}, "Examples: 'abc'"},
-		{"Multiple values", []string{"abc", "123", "def"}, "Examples: 'abc', '123', 'def'"},
-		{"Value with single quote", []string{"it's"}, "Examples: 'it''s'"},
-		{"Value with backslash", []string{`a\b`}, `Examples:  E'a\\b'`}, // pq handles this with E''
-		{"Mixed values", []string{"a", "b'c", `d\e`}, `Examples: 'a', 'b''c',  E'd\\e'`},
-		{"Empty string value", []string{""}, "Examples: ''"},
-		{"Mixed with empty", []string{"a", "", "b"}, "Examples: 'a', '', 'b'"},
+		{"Single value", []string{"abc"}, "Examples: ['abc']"},
+		{"Multiple values", []string{"abc", "123", "def"}, "Examples: ['abc', '123', 'def']"},
+		{"Value with single quote", []string{"it's"}, "Examples: ['it''s']"},
+		{"Value with backslash", []string{`a\b`}, `Examples: [ E'a\\b']`}, // pq handles this with E''
+		{"Mixed values", []string{"a", "b'c", `d\e`}, `Examples: ['a', 'b''c',  E'd\\e']`},
+		{"Empty string value", []string{""}, "Examples: ['']"},
+		{"Mixed with empty", []string{"a", "", "b"}, "Examples: ['a', '', 'b']"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1024,3 +1447,257 @@ func TestPostgresGetForeignKeys(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestPostgresFormatForeignKeys(t *testing.T) {
+	handler := postgresHandler{}
+	got := handler.formatForeignKeys([]database.ForeignKeyReference{{ReferencedTable: "users", ReferencedColumn: "id"}})
+	want := `Foreign Keys: ["users"."id"]`
+	if got != want {
+		t.Errorf("formatForeignKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestPostgresForeignKeyMatchQuery(t *testing.T) {
+	handler := postgresHandler{}
+	db := &database.DB{Config: config.DatabaseConfig{Schema: "sales"}}
+	got := handler.ForeignKeyMatchQuery(context.Background(), db, "orders", "customer_id", database.ForeignKeyReference{ReferencedTable: "customers", ReferencedColumn: "id"})
+	want := `SELECT COUNT(*), COUNT(r.referenced_value) FROM "sales"."orders" c LEFT JOIN (SELECT DISTINCT "id" AS referenced_value FROM "sales"."customers") r ON c."customer_id" = r.referenced_value WHERE c."customer_id" IS NOT NULL`
+	if got != want {
+		t.Errorf("ForeignKeyMatchQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestPostgresGenerateCommentSQLCascadePartitions(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+	db.Config.CascadePartitions = true
+
+	mock.ExpectQuery(`SELECT description\s+FROM pg_catalog\.pg_description`).
+		WithArgs("measurements", "reading").
+		WillReturnRows(sqlmock.NewRows([]string{"description"}))
+	mock.ExpectQuery(`WITH RECURSIVE partitions`).
+		WithArgs("measurements", "reading").
+		WillReturnRows(sqlmock.NewRows([]string{"nspname", "relname", "col_description"}).
+			AddRow("public", "measurements_2024", nil).
+			AddRow("public", "measurements_2025_q1", nil).
+			AddRow("archive", "measurements_2023", "Calibrated before 2024"))
+
+	data := &database.CommentData{TableName: "measurements", ColumnName: "reading", Description: "Sensor reading"}
+	got, err := handler.GenerateCommentSQL(db, data, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+
+	comment := pq.QuoteLiteral("<gemini>Sensor reading</gemini>")
+	archived := pq.QuoteLiteral("Calibrated before 2024 <gemini>Sensor reading</gemini>")
+	want := `COMMENT ON COLUMN "measurements"."reading" IS ` + comment + ";\n" +
+		`COMMENT ON COLUMN "public"."measurements_2024"."reading" IS ` + comment + ";\n" +
+		`COMMENT ON COLUMN "public"."measurements_2025_q1"."reading" IS ` + comment + ";\n" +
+		`COMMENT ON COLUMN "archive"."measurements_2023"."reading" IS ` + archived + ";"
+	if got != want {
+		t.Errorf("GenerateCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(got, "Calibrated before 2024") {
+		t.Errorf("GenerateCommentSQL() dropped the partition's own comment:\n%s", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+// TestPostgresDeleteCommentSQLCascadePartitionsKeepsOwnComments checks that deleting
+// the metadata only rewrites partitions whose own comment has a <gemini> block.
+func TestPostgresDeleteCommentSQLCascadePartitionsKeepsOwnComments(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+	db.Config.CascadePartitions = true
+
+	mock.ExpectQuery(`SELECT description\s+FROM pg_catalog\.pg_description`).
+		WithArgs("measurements", "reading").
+		WillReturnRows(sqlmock.NewRows([]string{"description"}).AddRow("Reading <gemini>Sensor reading</gemini>"))
+	mock.ExpectQuery(`WITH RECURSIVE partitions`).
+		WithArgs("measurements", "reading").
+		WillReturnRows(sqlmock.NewRows([]string{"nspname", "relname", "col_description"}).
+			AddRow("public", "measurements_2024", "Reading <gemini>Sensor reading</gemini>").
+			AddRow("archive", "measurements_2023", "Calibrated before 2024"))
+
+	got, err := handler.GenerateDeleteCommentSQL(context.Background(), db, "measurements", "reading")
+	if err != nil {
+		t.Fatalf("GenerateDeleteCommentSQL() unexpected error: %v", err)
+	}
+	want := `COMMENT ON COLUMN "measurements"."reading" IS 'Reading';` + "\n" +
+		`COMMENT ON COLUMN "public"."measurements_2024"."reading" IS 'Reading';`
+	if got != want {
+		t.Errorf("GenerateDeleteCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostgresGetAllColumnComments(t *testing.T) {
+	db, mock, _ := newMockPostgresDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT a\.attname, d\.description\s+FROM pg_catalog\.pg_description d`).
+		WithArgs("users").
+		WillReturnRows(sqlmock.NewRows([]string{"attname", "description"}).
+			AddRow("email", "Login email").
+			AddRow("id", nil))
+
+	comments, err := db.GetAllColumnComments(context.Background(), "users")
+	if err != nil {
+		t.Fatalf("GetAllColumnComments() unexpected error: %v", err)
+	}
+	if len(comments) != 1 || comments["email"] != "Login email" {
+		t.Errorf("GetAllColumnComments() = %v, want map[email:Login email]", comments)
+	}
+
+	// GenerateCommentSQL merges with the cached comment instead of querying it again.
+	got, err := db.GenerateCommentSQL(&database.CommentData{TableName: "users", ColumnName: "email", Description: "User email"}, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	if !strings.Contains(got, "Login email <gemini>") {
+		t.Errorf("GenerateCommentSQL() = %s, want the existing comment preserved", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostgresGenerateRewriteCommentSQLRepairsTags(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT description\s+FROM pg_catalog\.pg_description`).
+		WithArgs("users", "email").
+		WillReturnRows(sqlmock.NewRows([]string{"description"}).AddRow("Login email <gemini>Examples: 'a@exa"))
+
+	sqlStmt, err := handler.GenerateRewriteCommentSQL(context.Background(), db, "users", "email", database.RepairComment)
+	if err != nil {
+		t.Fatalf("GenerateRewriteCommentSQL() unexpected error: %v", err)
+	}
+	if want := `COMMENT ON COLUMN "users"."email" IS 'Login email';`; sqlStmt != want {
+		t.Errorf("GenerateRewriteCommentSQL() = %s, want %s", sqlStmt, want)
+	}
+
+	// A well-formed comment needs no statement.
+	mock.ExpectQuery(`SELECT description\s+FROM pg_catalog\.pg_description`).
+		WithArgs("users", "id").
+		WillReturnRows(sqlmock.NewRows([]string{"description"}).AddRow("Key <gemini>Distinct: 10</gemini>"))
+	sqlStmt, err = handler.GenerateRewriteCommentSQL(context.Background(), db, "users", "id", database.RepairComment)
+	if err != nil || sqlStmt != "" {
+		t.Errorf("GenerateRewriteCommentSQL() = %q, %v, want no statement", sqlStmt, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+// TestPostgresSpecialIdentifiersEndToEnd follows reserved-word and quoted column names
+// from ListColumns through the metadata queries to the generated comment.
+func TestPostgresSpecialIdentifiersEndToEnd(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+	enrichments := map[string]bool{"examples": true, "distinct_values": true}
+
+	mock.ExpectQuery(`SELECT c.column_name, c.data_type, .*\s+FROM information_schema.columns c`).WithArgs("order").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name", "data_type", "domain_name", "udt_name", "typtype"}).
+			AddRow("select", "text", "", "text", "b").AddRow(`my "odd" col`, "text", "", "text", "b"))
+
+	columns, err := handler.ListColumns(db, "order")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+
+	quoted := map[string]string{"select": `"select"`, `my "odd" col`: `"my ""odd"" col"`}
+	want := map[string]string{
+		"select":       `COMMENT ON COLUMN "order"."select" IS '<gemini>Examples: [''a''] | Distinct: 1</gemini>';`,
+		`my "odd" col`: `COMMENT ON COLUMN "order"."my ""odd"" col" IS '<gemini>Examples: [''a''] | Distinct: 1</gemini>';`,
+	}
+	for _, col := range columns {
+		q := quoted[col.Name]
+		mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT COUNT(DISTINCT %s::text) FROM "order"`, q))).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+		mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT COUNT(*) FROM "order" WHERE %s IS NULL`, q))).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT DISTINCT %s::text FROM "order" WHERE %s IS NOT NULL ORDER BY 1 LIMIT 3`, q, q))).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("a"))
+		metadata, err := handler.GetColumnMetadata(db, "order", col.Name)
+		if err != nil {
+			t.Fatalf("GetColumnMetadata(%q) unexpected error: %v", col.Name, err)
+		}
+
+		mock.ExpectQuery(`SELECT description\s+FROM pg_catalog.pg_description`).WithArgs("order", col.Name).
+			WillReturnRows(sqlmock.NewRows([]string{"description"}))
+		got, err := handler.GenerateCommentSQL(db, &database.CommentData{
+			TableName:     "order",
+			ColumnName:    col.Name,
+			ExampleValues: metadata["ExampleValues"].([]string),
+			DistinctCount: metadata["DistinctCount"].(int64),
+			NullCount:     metadata["NullCount"].(int64),
+		}, enrichments)
+		if err != nil {
+			t.Fatalf("GenerateCommentSQL(%q) unexpected error: %v", col.Name, err)
+		}
+		if got != want[col.Name] {
+			t.Errorf("GenerateCommentSQL(%q) =\n%s\nwant\n%s", col.Name, got, want[col.Name])
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// TestPostgresSchema checks that --schema replaces current_schema() in catalog
+// queries and qualifies the sampled table and the generated statements.
+func TestPostgresSchema(t *testing.T) {
+	db, mock, handler := newMockPostgresDB(t)
+	defer db.Close()
+	db.Config.Schema = "sales"
+
+	mock.ExpectQuery(`FROM information_schema\.tables\s+WHERE table_schema = \$1`).WithArgs("sales").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "table_type"}).AddRow("orders", "BASE TABLE"))
+	tables, err := handler.ListTables(db)
+	if err != nil || len(tables) != 1 || tables[0] != "orders" {
+		t.Fatalf("ListTables() = %v, %v, want [orders]", tables, err)
+	}
+
+	mock.ExpectQuery(`WHERE c\.table_schema = \$2\s+AND c\.table_name = \$1`).WithArgs("orders", "sales").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name", "data_type", "domain_name", "udt_name", "typtype"}).
+			AddRow("status", "text", "", "text", "b"))
+	if _, err := handler.ListColumns(db, "orders"); err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT "status"::text) FROM "sales"."orders"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM "sales"."orders" WHERE "status" IS NULL`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT "status"::text FROM "sales"."orders" WHERE "status" IS NOT NULL ORDER BY 1 LIMIT 3`)).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("new").AddRow("paid"))
+	if _, err := handler.GetColumnMetadata(db, "orders", "status"); err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+
+	mock.ExpectQuery(`WHERE n\.nspname = \$3\s+AND c\.relname = \$1\s+AND a\.attname = \$2`).WithArgs("orders", "status", "sales").
+		WillReturnRows(sqlmock.NewRows([]string{"description"}))
+	got, err := handler.GenerateCommentSQL(db, &database.CommentData{TableName: "orders", ColumnName: "status", Description: "Order state"}, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	if want := `COMMENT ON COLUMN "sales"."orders"."status" IS '<gemini>Order state</gemini>';`; got != want {
+		t.Errorf("GenerateCommentSQL() = %s, want %s", got, want)
+	}
+
+	mock.ExpectQuery(`WHERE n\.nspname = \$2\s+AND c\.relname = \$1`).WithArgs("orders", "sales").
+		WillReturnRows(sqlmock.NewRows([]string{"obj_description"}).AddRow(nil))
+	got, err = handler.GenerateTableCommentSQL(db, &database.TableCommentData{TableName: "orders", Description: "Customer orders"}, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateTableCommentSQL() unexpected error: %v", err)
+	}
+	if want := `COMMENT ON TABLE "sales"."orders" IS '<gemini>Customer orders</gemini>';`; got != want {
+		t.Errorf("GenerateTableCommentSQL() = %s, want %s", got, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// profiledPrefix starts the metadata segment that records when a column's statistics
+// were queried, enabled with --embed-timestamp.
+const profiledPrefix = "Profiled: "
+
+// withProfiled prepends the profiling time to generated metadata, in UTC. Empty
+// metadata stays empty, and a zero time, for columns whose statistics were not
+// queried, adds nothing.
+func withProfiled(metadata string, profiledAt time.Time) string {
+	if metadata == "" || profiledAt.IsZero() {
+		return metadata
+	}
+	return profiledPrefix + profiledAt.UTC().Format(time.RFC3339) + " | " + metadata
+}
+
+// withoutStaleProfiled removes the profiling times from existing metadata when the
+// new metadata records one, so that appending to a comment keeps only the latest.
+func withoutStaleProfiled(existingMetadata, newMetadata string) string {
+	if !strings.Contains(newMetadata, profiledPrefix) {
+		return existingMetadata
+	}
+	var kept []string
+	for _, segment := range strings.Split(existingMetadata, " | ") {
+		if !strings.HasPrefix(strings.TrimSpace(segment), profiledPrefix) {
+			kept = append(kept, segment)
+		}
+	}
+	return strings.Join(kept, " | ")
+}
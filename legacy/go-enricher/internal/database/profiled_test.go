@@ -0,0 +1,56 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEmbedTimestampInMetadata(t *testing.T) {
+	enrichments := map[string]bool{"description": true, "null_count": true}
+	profiledAt := time.Date(2025, 1, 1, 1, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	data := &CommentData{Description: "Order total", NullCount: 3, ProfiledAt: profiledAt, EmbedTimestamp: true, Source: "prod"}
+	if got, want := GenerateMetadataCommentString(data, enrichments, "", ""), "Source: prod | Profiled: 2025-01-01T00:00:00Z | Nulls: 3 | Order total"; got != want {
+		t.Errorf("GenerateMetadataCommentString() = %q, want %q", got, want)
+	}
+
+	data.EmbedTimestamp = false
+	if got, want := GenerateMetadataCommentString(data, enrichments, "", ""), "Source: prod | Nulls: 3 | Order total"; got != want {
+		t.Errorf("GenerateMetadataCommentString() without --embed-timestamp = %q, want %q", got, want)
+	}
+
+	// Columns whose statistics were not queried have no time to record.
+	data = &CommentData{Description: "Order total", EmbedTimestamp: true}
+	if got, want := GenerateMetadataCommentString(data, map[string]bool{"description": true}, "", ""), "Order total"; got != want {
+		t.Errorf("GenerateMetadataCommentString() without a profiling time = %q, want %q", got, want)
+	}
+}
+
+func TestEmbeddedTimestampOnRerun(t *testing.T) {
+	existing := "Order total <gemini>Profiled: 2025-01-01T00:00:00Z | Nulls: 3</gemini>"
+	rerun := "Profiled: 2025-02-01T00:00:00Z | Nulls: 4"
+
+	tests := []struct {
+		mode     string
+		metadata string
+		want     string
+	}{
+		{"overwrite", rerun, "Order total <gemini>Profiled: 2025-02-01T00:00:00Z | Nulls: 4</gemini>"},
+		{"append", rerun, "Order total <gemini>Nulls: 3 | Profiled: 2025-02-01T00:00:00Z | Nulls: 4</gemini>"},
+		// Without a new time, appending keeps the existing one.
+		{"append", "Nulls checked", "Order total <gemini>Profiled: 2025-01-01T00:00:00Z | Nulls: 3 | Nulls checked</gemini>"},
+	}
+	for _, tt := range tests {
+		if got := MergeComments(existing, tt.metadata, tt.mode); got != tt.want {
+			t.Errorf("MergeComments(%q, %q) = %q, want %q", tt.mode, tt.metadata, got, tt.want)
+		}
+	}
+
+	// delete-comments removes the time with the rest of the block.
+	if got := StripMetadata(existing); got != "Order total" {
+		t.Errorf("StripMetadata() = %q, want %q", got, "Order total")
+	}
+	if got := StripMetadataAndStats(existing); got != "Order total" {
+		t.Errorf("StripMetadataAndStats() = %q, want %q", got, "Order total")
+	}
+}
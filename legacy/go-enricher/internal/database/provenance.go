@@ -0,0 +1,86 @@
+package database
+
+import "strings"
+
+// provenancePrefix starts the metadata segment that records where each part of the
+// generated metadata came from, enabled with --embed-provenance.
+const provenancePrefix = "Provenance: "
+
+// Provenance kinds recorded for the parts of a comment.
+const (
+	ProvenanceInferred = "inferred" // Written by the LLM or taken from the glossary.
+	ProvenanceComputed = "computed" // Queried from the database.
+	ProvenanceCustom   = "custom"   // Produced by a custom enrichment.
+)
+
+// provenanceKinds lists the kinds in the order they are written.
+var provenanceKinds = []string{ProvenanceInferred, ProvenanceComputed, ProvenanceCustom}
+
+// enrichmentProvenance returns the provenance kind of a comment part.
+func enrichmentProvenance(enrichment string) string {
+	switch enrichment {
+	case "description":
+		return ProvenanceInferred
+	case "examples", "distinct_values", "null_count", "average", "json_keys", "foreign_keys", "data_type":
+		return ProvenanceComputed
+	default:
+		return ProvenanceCustom
+	}
+}
+
+// provenanceSegment renders the provenance of the given enrichments, e.g.
+// "Provenance: inferred=description; computed=examples,null_count". It returns an
+// empty string for no enrichments.
+func provenanceSegment(enrichments []string) string {
+	byKind := make(map[string][]string)
+	for _, enrichment := range enrichments {
+		kind := enrichmentProvenance(enrichment)
+		byKind[kind] = append(byKind[kind], enrichment)
+	}
+	var groups []string
+	for _, kind := range provenanceKinds {
+		if len(byKind[kind]) > 0 {
+			groups = append(groups, kind+"="+strings.Join(byKind[kind], ","))
+		}
+	}
+	if len(groups) == 0 {
+		return ""
+	}
+	return provenancePrefix + strings.Join(groups, "; ")
+}
+
+// withProvenance appends the provenance segment of enrichments to generated metadata.
+// Empty metadata stays empty.
+func withProvenance(metadata string, enrichments []string) string {
+	segment := provenanceSegment(enrichments)
+	if metadata == "" || segment == "" {
+		return metadata
+	}
+	return metadata + " | " + segment
+}
+
+// ParseProvenance returns the provenance recorded in a comment's <gemini> block, keyed
+// by enrichment name, or nil if the comment has none.
+func ParseProvenance(comment string) map[string]string {
+	_, metadata, _ := SplitComment(comment)
+	for _, segment := range strings.Split(metadata, "|") {
+		segment = strings.TrimSpace(segment)
+		if !strings.HasPrefix(segment, provenancePrefix) {
+			continue
+		}
+		provenance := make(map[string]string)
+		for _, group := range strings.Split(strings.TrimPrefix(segment, provenancePrefix), ";") {
+			kind, enrichments, ok := strings.Cut(strings.TrimSpace(group), "=")
+			if !ok {
+				continue
+			}
+			for _, enrichment := range strings.Split(enrichments, ",") {
+				if enrichment = strings.TrimSpace(enrichment); enrichment != "" {
+					provenance[enrichment] = kind
+				}
+			}
+		}
+		return provenance
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmbedProvenanceInMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     *CommentData
+		examples string
+		want     string
+	}{
+		{
+			name:     "inferred and computed parts",
+			data:     &CommentData{Description: "Order total", NullCount: 0, DistinctCount: -1, Provenance: true},
+			examples: "Examples: [12]",
+			want:     "Examples: [12] | Nulls: 0 | Order total | Provenance: inferred=description; computed=examples,null_count",
+		},
+		{
+			name: "custom enrichment",
+			data: &CommentData{DistinctCount: -1, Custom: map[string]string{"tier": "gold"}, Provenance: true, Source: "prod"},
+			want: "Source: prod | Nulls: 0 | tier: gold | Provenance: computed=null_count; custom=tier",
+		},
+		{
+			name: "only kept parts",
+			data: &CommentData{Description: "Order total", DistinctCount: 4, Provenance: true, MaxParts: 1},
+			want: "Order total | Provenance: inferred=description",
+		},
+		{
+			name: "off by default",
+			data: &CommentData{Description: "Order total", DistinctCount: -1},
+			want: "Nulls: 0 | Order total",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenerateMetadataCommentString(tt.data, map[string]bool{}, tt.examples, ""); got != tt.want {
+				t.Errorf("GenerateMetadataCommentString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	table := GenerateTableMetadataCommentString(&TableCommentData{Description: "Orders", Provenance: true}, map[string]bool{})
+	if want := "Orders | Provenance: inferred=description"; table != want {
+		t.Errorf("GenerateTableMetadataCommentString() = %q, want %q", table, want)
+	}
+	if got := GenerateMetadataCommentString(&CommentData{DistinctCount: -1, Provenance: true}, map[string]bool{"description": true}, "", ""); got != "" {
+		t.Errorf("GenerateMetadataCommentString() with only provenance = %q, want empty", got)
+	}
+}
+
+func TestParseProvenance(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    map[string]string
+	}{
+		{
+			name:    "generated comment",
+			comment: "Order total <gemini>Source: prod | Examples: [12] | Order total | Provenance: inferred=description; computed=examples</gemini>",
+			want:    map[string]string{"description": ProvenanceInferred, "examples": ProvenanceComputed},
+		},
+		{"no provenance", "Order total <gemini>Examples: [12]</gemini>", nil},
+		{"marker outside metadata is user text", "Provenance: inferred=description", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseProvenance(tt.comment); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseProvenance(%q) = %v, want %v", tt.comment, got, tt.want)
+			}
+		})
+	}
+}
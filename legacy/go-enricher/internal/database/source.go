@@ -0,0 +1,31 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sourcePrefix starts the metadata segment that records which database or environment
+// the metadata was profiled against, set with --embed-source.
+const sourcePrefix = "Source: "
+
+// ValidateEmbedSource checks an --embed-source value. The marker is written inside the
+// <gemini> block, so it cannot contain tag or separator characters.
+func ValidateEmbedSource(source string) error {
+	if source == "" {
+		return nil
+	}
+	if strings.TrimSpace(source) != source || strings.ContainsAny(source, "<>|\n") {
+		return fmt.Errorf("invalid value for --embed-source: '%s'. It cannot contain '<', '>', '|', line breaks or surrounding spaces", source)
+	}
+	return nil
+}
+
+// withSource prepends the source marker to generated metadata. Empty metadata stays
+// empty, so a marker never creates a comment on its own.
+func withSource(metadata, source string) string {
+	if metadata == "" || source == "" {
+		return metadata
+	}
+	return sourcePrefix + source + " | " + metadata
+}
@@ -0,0 +1,59 @@
+package database
+
+import "testing"
+
+func TestValidateEmbedSource(t *testing.T) {
+	for _, source := range []string{"", "prod", "eu-staging", "Reporting replica"} {
+		if err := ValidateEmbedSource(source); err != nil {
+			t.Errorf("ValidateEmbedSource(%q) unexpected error: %v", source, err)
+		}
+	}
+	for _, source := range []string{" prod", "a|b", "<gemini>", "prod\nstaging"} {
+		if err := ValidateEmbedSource(source); err == nil {
+			t.Errorf("ValidateEmbedSource(%q) expected an error", source)
+		}
+	}
+}
+
+func TestEmbedSourceInMetadata(t *testing.T) {
+	enrichments := map[string]bool{"description": true}
+
+	column := GenerateMetadataCommentString(&CommentData{Description: "Order total", Source: "prod"}, enrichments, "", "")
+	if want := "Source: prod | Order total"; column != want {
+		t.Errorf("GenerateMetadataCommentString() = %q, want %q", column, want)
+	}
+	table := GenerateTableMetadataCommentString(&TableCommentData{Description: "Orders", Source: "prod"}, enrichments)
+	if want := "Source: prod | Orders"; table != want {
+		t.Errorf("GenerateTableMetadataCommentString() = %q, want %q", table, want)
+	}
+
+	// A source on its own does not create a comment.
+	if got := GenerateMetadataCommentString(&CommentData{Source: "prod"}, enrichments, "", ""); got != "" {
+		t.Errorf("GenerateMetadataCommentString() with only a source = %q, want empty", got)
+	}
+	if got := GenerateTableMetadataCommentString(&TableCommentData{Source: "prod"}, enrichments); got != "" {
+		t.Errorf("GenerateTableMetadataCommentString() with only a source = %q, want empty", got)
+	}
+}
+
+func TestStripEmbeddedSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    string
+	}{
+		{"With source", "Order total <gemini>Source: prod | Examples: [12]</gemini>", "Order total"},
+		{"Without source", "Order total <gemini>Examples: [12]</gemini>", "Order total"},
+		{"Source only", "<gemini>Source: staging</gemini>", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripMetadata(tt.comment); got != tt.want {
+				t.Errorf("StripMetadata(%q) = %q, want %q", tt.comment, got, tt.want)
+			}
+			if got := StripMetadataAndStats(tt.comment); got != tt.want {
+				t.Errorf("StripMetadataAndStats(%q) = %q, want %q", tt.comment, got, tt.want)
+			}
+		})
+	}
+}
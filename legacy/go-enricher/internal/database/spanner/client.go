@@ -0,0 +1,104 @@
+package spanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/sqladapter"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// clientRunner runs statements through the Spanner client library, whose session
+// pool serves concurrent queries and replaces sessions the server has deleted.
+type clientRunner struct {
+	client *sp.Client
+}
+
+var _ sqladapter.Runner = (*clientRunner)(nil)
+
+func (r *clientRunner) Query(ctx context.Context, query string, params []interface{}) ([]string, [][]interface{}, error) {
+	stmt := sp.Statement{SQL: query}
+	if len(params) > 0 {
+		stmt.Params = make(map[string]interface{}, len(params))
+		for i, p := range params {
+			stmt.Params[fmt.Sprintf("p%d", i+1)] = p
+		}
+	}
+
+	iter := r.client.Single().Query(ctx, stmt)
+	var values [][]interface{}
+	err := iter.Do(func(row *sp.Row) error {
+		cells := make([]interface{}, row.Size())
+		for i := range cells {
+			var col sp.GenericColumnValue
+			if err := row.Column(i, &col); err != nil {
+				return err
+			}
+			v, err := columnValue(col)
+			if err != nil {
+				return err
+			}
+			cells[i] = v
+		}
+		values = append(values, cells)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("spanner query failed: %w", err)
+	}
+
+	var columns []string
+	if iter.Metadata != nil && iter.Metadata.RowType != nil {
+		for _, field := range iter.Metadata.RowType.Fields {
+			columns = append(columns, field.Name)
+		}
+	}
+	return columns, values, nil
+}
+
+// Exec runs the statements as one batch DML request in a read-write transaction,
+// which the client retries if Spanner aborts it.
+func (r *clientRunner) Exec(ctx context.Context, statements []string) error {
+	batch := make([]sp.Statement, len(statements))
+	for i, stmt := range statements {
+		batch[i] = sp.Statement{SQL: stmt}
+	}
+	_, err := r.client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *sp.ReadWriteTransaction) error {
+		_, err := txn.BatchUpdate(ctx, batch)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("spanner batch DML failed: %w", err)
+	}
+	return nil
+}
+
+func (r *clientRunner) Close() error {
+	r.client.Close()
+	return nil
+}
+
+// columnValue converts a column read by the client into a value database/sql can
+// scan. Spanner encodes INT64, NUMERIC, DATE and TIMESTAMP values as strings,
+// which database/sql converts to the scan destination type. Arrays and structs are
+// passed as their JSON encoding.
+func columnValue(col sp.GenericColumnValue) (interface{}, error) {
+	switch v := col.Value.GetKind().(type) {
+	case nil, *structpb.Value_NullValue:
+		return nil, nil
+	case *structpb.Value_StringValue:
+		return v.StringValue, nil
+	case *structpb.Value_NumberValue:
+		return v.NumberValue, nil
+	case *structpb.Value_BoolValue:
+		return v.BoolValue, nil
+	default:
+		encoded, err := json.Marshal(col.Value.AsInterface())
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode spanner value: %w", err)
+		}
+		return string(encoded), nil
+	}
+}
@@ -0,0 +1,433 @@
+package spanner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/sqladapter"
+)
+
+// CommentsTable is the table comments are stored in. Spanner has no DDL for
+// table or column descriptions, so the handler upserts rows keyed by table and
+// column name (an empty ColumnName holds the table comment). It must be created
+// once per database with CommentsTableDDL.
+const CommentsTable = "DbContextComments"
+
+// CommentsTableDDL creates CommentsTable.
+const CommentsTableDDL = "CREATE TABLE DbContextComments (TableName STRING(MAX) NOT NULL, ColumnName STRING(MAX) NOT NULL, Comment STRING(MAX)) PRIMARY KEY (TableName, ColumnName)"
+
+type spannerHandler struct{}
+
+var _ database.DialectHandler = (*spannerHandler)(nil)
+
+func (h spannerHandler) CreateCloudSQLPool(cfg config.DatabaseConfig) (*sql.DB, error) {
+	return nil, fmt.Errorf("Cloud SQL connections are not supported for Spanner")
+}
+
+// CreateStandardPool connects to Spanner with Application Default Credentials.
+func (h spannerHandler) CreateStandardPool(cfg config.DatabaseConfig) (*sql.DB, error) {
+	databasePath := fmt.Sprintf("projects/%s/instances/%s/databases/%s", cfg.Project, cfg.SpannerInstance, cfg.DBName)
+	client, err := sp.NewClient(context.Background(), databasePath)
+	if err != nil {
+		return nil, fmt.Errorf("spanner.NewClient: %w", err)
+	}
+	return sqladapter.OpenDB("spanner", &clientRunner{client: client}), nil
+}
+
+func (h spannerHandler) QuoteIdentifier(name string) string {
+	name = strings.ReplaceAll(name, `\`, `\\`)
+	name = strings.ReplaceAll(name, "`", "\\`")
+	return fmt.Sprintf("`%s`", name)
+}
+
+func (h spannerHandler) ListTables(db *database.DB) ([]string, error) {
+	query := "SELECT table_name FROM INFORMATION_SCHEMA.TABLES WHERE table_schema = '' AND table_type = 'BASE TABLE' AND table_name != @p1 ORDER BY table_name"
+
+	rows, err := db.Pool.Query(query, CommentsTable)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var tableName sql.NullString
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, fmt.Errorf("error scanning table name: %w", err)
+		}
+		if !tableName.Valid {
+			log.Println("WARN: Skipping a table listed with a NULL name.")
+			continue
+		}
+		tables = append(tables, tableName.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating table rows: %w", err)
+	}
+	return tables, nil
+}
+
+func (h spannerHandler) ListColumns(db *database.DB, tableName string) ([]database.ColumnInfo, error) {
+	query := `
+		SELECT column_name, spanner_type
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE table_schema = ''
+			AND table_name = @p1
+		ORDER BY ordinal_position`
+
+	rows, err := db.Pool.Query(query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("error querying columns for table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var columns []database.ColumnInfo
+	for rows.Next() {
+		var name, dataType sql.NullString
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return nil, fmt.Errorf("error scanning column name and data type: %w", err)
+		}
+		if !name.Valid {
+			log.Printf("WARN: Skipping a column of %s listed with a NULL name.", tableName)
+			continue
+		}
+		columns = append(columns, database.ColumnInfo{Name: name.String, DataType: dataType.String})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column rows: %w", err)
+	}
+
+	return columns, nil
+}
+
+func (h spannerHandler) GetColumnMetadata(db *database.DB, tableName string, columnName string) (map[string]interface{}, error) {
+	quotedTable := h.QuoteIdentifier(tableName)
+	quotedColumn := h.QuoteIdentifier(columnName)
+	ctx := context.Background()
+
+	// Casting to STRING gives COUNT(DISTINCT) and ORDER BY a comparable type; columns that cannot be cast report -1.
+	distinctQuery := fmt.Sprintf("SELECT COUNT(DISTINCT CAST(%s AS STRING)) FROM %s", quotedColumn, quotedTable)
+	var distinctCount int64
+	err := db.Pool.QueryRowContext(ctx, distinctQuery).Scan(&distinctCount)
+	if err != nil {
+		log.Printf("WARN: Failed to get distinct count for %s.%s (may not be supported for this column type): %v. Reporting -1.", tableName, columnName, err)
+		distinctCount = -1
+	}
+
+	nullQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NULL", quotedTable, quotedColumn)
+	var nullCount int64
+	err = db.Pool.QueryRowContext(ctx, nullQuery).Scan(&nullCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get null count for %s.%s: %w", tableName, columnName, err)
+	}
+
+	exampleLimit := database.ExampleQueryLimit(db.Config.ExampleSampleSize)
+	exampleQuery := fmt.Sprintf("SELECT DISTINCT CAST(%s AS STRING) FROM %s WHERE %s IS NOT NULL ORDER BY 1 LIMIT %d",
+		quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	firstRows := database.SampleWithoutDistinct(db, distinctCount)
+	if firstRows {
+		exampleQuery = fmt.Sprintf("SELECT CAST(%s AS STRING) FROM %s WHERE %s IS NOT NULL LIMIT %d",
+			quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	}
+	rows, err := db.Pool.QueryContext(ctx, exampleQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get example values for %s.%s: %w", tableName, columnName, err)
+	}
+	defer rows.Close()
+
+	var examples []string
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("error scanning example value for %s.%s: %w", tableName, columnName, err)
+		}
+		if value.Valid {
+			examples = append(examples, value.String)
+		}
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating example values for %s.%s: %w", tableName, columnName, rows.Err())
+	}
+	if firstRows {
+		examples = database.UniqueValues(examples)
+	}
+	examples = database.SelectRepresentativeValues(examples, database.DefaultExampleCount)
+
+	metadata := map[string]interface{}{
+		"DistinctCount": distinctCount,
+		"NullCount":     nullCount,
+		"ExampleValues": examples,
+	}
+	// Only numeric columns are averaged, since the query is a full scan.
+	if db.Config.ComputeAverage && database.IsNumericType(h.columnDataType(ctx, db, tableName, columnName)) {
+		avgQuery := fmt.Sprintf("SELECT CAST(AVG(%s) AS FLOAT64) FROM %s", quotedColumn, quotedTable)
+		if avg := database.ColumnAverage(ctx, db, avgQuery); avg != nil {
+			metadata["Average"] = *avg
+		}
+	}
+	return metadata, nil
+}
+
+// columnDataType returns the name of a column's type, or "" if it cannot be read.
+func (h spannerHandler) columnDataType(ctx context.Context, db *database.DB, tableName, columnName string) string {
+	query := `
+		SELECT spanner_type
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE table_schema = ''
+			AND table_name = @p1
+			AND column_name = @p2`
+	var dataType string
+	if err := db.Pool.QueryRowContext(ctx, query, tableName, columnName).Scan(&dataType); err != nil {
+		log.Printf("WARN: Failed to read the type of %s.%s: %v", tableName, columnName, err)
+		return ""
+	}
+	return dataType
+}
+
+// quoteSpannerString returns value as a double-quoted GoogleSQL string literal.
+func quoteSpannerString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return fmt.Sprintf(`"%s"`, value)
+}
+
+// formatExampleValues renders the examples as plain comment text. Values are not
+// escaped here: the whole comment is escaped once by quoteSpannerString.
+func (h spannerHandler) formatExampleValues(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	cleaned := make([]string, len(values))
+	for i, v := range values {
+		trimmed := strings.ReplaceAll(v, "\n", " ")
+		if len(trimmed) > 100 {
+			trimmed = trimmed[:100] + "...[truncated]"
+		}
+		cleaned[i] = trimmed
+	}
+	return fmt.Sprintf("Examples: ['%s']", strings.Join(cleaned, "', '"))
+}
+
+// formatForeignKeys renders referenced columns with this dialect's identifier quoting.
+func (h spannerHandler) formatForeignKeys(foreignKeys []database.ForeignKeyReference) string {
+	return database.FormatForeignKeys(foreignKeys, h.QuoteIdentifier)
+}
+
+// commentSQL upserts the comment row for a column (or the table, when columnName
+// is empty), deleting the row instead when the comment is empty.
+func (h spannerHandler) commentSQL(tableName, columnName, comment string) string {
+	if comment == "" {
+		return fmt.Sprintf("DELETE FROM %s WHERE TableName = %s AND ColumnName = %s;",
+			CommentsTable, quoteSpannerString(tableName), quoteSpannerString(columnName))
+	}
+	return fmt.Sprintf("INSERT OR UPDATE INTO %s (TableName, ColumnName, Comment) VALUES (%s, %s, %s);",
+		CommentsTable, quoteSpannerString(tableName), quoteSpannerString(columnName), quoteSpannerString(comment))
+}
+
+// getComment reads a row from CommentsTable.
+func (h spannerHandler) getComment(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	query := fmt.Sprintf("SELECT Comment FROM %s WHERE TableName = @p1 AND ColumnName = @p2", CommentsTable)
+
+	var comment sql.NullString
+	err := db.Pool.QueryRowContext(ctx, query, tableName, columnName).Scan(&comment)
+	if err != nil {
+		return "", err
+	}
+	if comment.Valid {
+		return comment.String, nil
+	}
+	return "", nil
+}
+
+func (h spannerHandler) GenerateCommentSQL(db *database.DB, data *database.CommentData, enrichments map[string]bool) (string, error) {
+	if data == nil || data.TableName == "" || data.ColumnName == "" {
+		return "", fmt.Errorf("invalid input for GenerateCommentSQL")
+	}
+
+	formattedExamples := h.formatExampleValues(data.ExampleValues)
+	newMetadataComment := database.GenerateMetadataCommentString(data, enrichments, formattedExamples, h.formatForeignKeys(data.ForeignKeys))
+
+	existingComment, err := database.ExistingColumnComment(context.Background(), db, h, data.TableName, data.ColumnName)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("WARN: Failed to get existing column comment for %s.%s: %v. Proceeding as if empty.", data.TableName, data.ColumnName, err)
+		existingComment = ""
+	}
+
+	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
+	return h.commentSQL(data.TableName, data.ColumnName, finalComment), nil
+}
+
+// GenerateRewriteCommentSQL returns the statement that replaces a column's comment
+// with rewrite(existing), or "" when that leaves the comment unchanged.
+func (h spannerHandler) GenerateRewriteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string, rewrite func(string) string) (string, error) {
+	if tableName == "" || columnName == "" {
+		return "", fmt.Errorf("table and column names cannot be empty for GenerateRewriteCommentSQL")
+	}
+
+	existingComment, err := database.ExistingColumnComment(ctx, db, h, tableName, columnName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get existing column comment for %s.%s before rewrite: %w", tableName, columnName, err)
+	}
+
+	finalComment := rewrite(existingComment)
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.commentSQL(tableName, columnName, finalComment), nil
+}
+
+func (h spannerHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	return h.GenerateRewriteCommentSQL(ctx, db, tableName, columnName, database.StripMetadata)
+}
+
+func (h spannerHandler) GetColumnComment(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	comment, err := h.getComment(ctx, db, tableName, columnName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		log.Printf("ERROR: Failed to retrieve column comment for %s.%s (has %s been created?): %v", tableName, columnName, CommentsTable, err)
+		return "", fmt.Errorf("failed to retrieve column comment for %s.%s: %w", tableName, columnName, err)
+	}
+	return comment, nil
+}
+
+// GetAllColumnComments reads the stored comments for every column of a table in
+// one query. Table comments, stored with an empty ColumnName, are skipped.
+func (h spannerHandler) GetAllColumnComments(ctx context.Context, db *database.DB, tableName string) (map[string]string, error) {
+	query := fmt.Sprintf("SELECT ColumnName, Comment FROM %s WHERE TableName = @p1 AND ColumnName != ''", CommentsTable)
+
+	rows, err := db.Pool.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve column comments for table %s (has %s been created?): %w", tableName, CommentsTable, err)
+	}
+	defer rows.Close()
+
+	comments := make(map[string]string)
+	for rows.Next() {
+		var column string
+		var comment sql.NullString
+		if err := rows.Scan(&column, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column comment for table %s: %w", tableName, err)
+		}
+		if comment.Valid && comment.String != "" {
+			comments[column] = comment.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column comments for table %s: %w", tableName, err)
+	}
+	return comments, nil
+}
+
+func (h spannerHandler) GenerateTableCommentSQL(db *database.DB, data *database.TableCommentData, enrichments map[string]bool) (string, error) {
+	if data == nil || data.TableName == "" {
+		return "", fmt.Errorf("table comment data cannot be nil or empty")
+	}
+
+	newMetadataComment := database.GenerateTableMetadataCommentString(data, enrichments)
+
+	existingComment, err := database.ExistingTableComment(context.Background(), db, h, data.TableName)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("WARN: Failed to get existing table comment for %s: %v. Proceeding as if empty.", data.TableName, err)
+		existingComment = ""
+	}
+
+	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.commentSQL(data.TableName, "", finalComment), nil
+}
+
+func (h spannerHandler) GetTableComment(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	comment, err := h.getComment(ctx, db, tableName, "")
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		log.Printf("ERROR: Failed to retrieve table comment for %s (has %s been created?): %v", tableName, CommentsTable, err)
+		return "", fmt.Errorf("failed to retrieve table comment for %s: %w", tableName, err)
+	}
+	return comment, nil
+}
+
+// GenerateRewriteTableCommentSQL is GenerateRewriteCommentSQL for the table comment.
+func (h spannerHandler) GenerateRewriteTableCommentSQL(ctx context.Context, db *database.DB, tableName string, rewrite func(string) string) (string, error) {
+	if tableName == "" {
+		return "", fmt.Errorf("table name cannot be empty for GenerateRewriteTableCommentSQL")
+	}
+
+	existingComment, err := h.GetTableComment(ctx, db, tableName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get existing table comment for %s before rewrite: %w", tableName, err)
+	}
+
+	finalComment := rewrite(existingComment)
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.commentSQL(tableName, "", finalComment), nil
+}
+
+func (h spannerHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	return h.GenerateRewriteTableCommentSQL(ctx, db, tableName, database.StripMetadata)
+}
+
+func (h spannerHandler) GetForeignKeys(db *database.DB, tableName string, columnName string) ([]database.ForeignKeyReference, error) {
+	query := `
+		SELECT
+			ccu.table_name AS referenced_table,
+			ccu.column_name AS referenced_column,
+			tc.constraint_name
+		FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+		JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+			ON tc.constraint_name = kcu.constraint_name
+			AND tc.table_schema = kcu.table_schema
+		JOIN INFORMATION_SCHEMA.CONSTRAINT_COLUMN_USAGE ccu
+			ON ccu.constraint_name = tc.constraint_name
+			AND ccu.table_schema = tc.table_schema
+		WHERE tc.constraint_type = 'FOREIGN KEY'
+			AND tc.table_schema = ''
+			AND tc.table_name = @p1
+			AND kcu.column_name = @p2`
+
+	rows, err := db.Pool.Query(query, tableName, columnName)
+	if err != nil {
+		return nil, fmt.Errorf("error querying foreign keys for %s.%s: %w", tableName, columnName, err)
+	}
+	defer rows.Close()
+
+	var foreignKeys []database.ForeignKeyReference
+	for rows.Next() {
+		var fk database.ForeignKeyReference
+		if err := rows.Scan(&fk.ReferencedTable, &fk.ReferencedColumn, &fk.ConstraintName); err != nil {
+			return nil, fmt.Errorf("error scanning foreign key data for %s.%s: %w", tableName, columnName, err)
+		}
+		foreignKeys = append(foreignKeys, fk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign key rows for %s.%s: %w", tableName, columnName, err)
+	}
+
+	return foreignKeys, nil
+}
+
+// ForeignKeyMatchQuery implements database.ForeignKeyMatcher. NOT ENFORCED foreign
+// keys may not match.
+func (h spannerHandler) ForeignKeyMatchQuery(ctx context.Context, db *database.DB, tableName string, columnName string, fk database.ForeignKeyReference) string {
+	return database.ForeignKeyMatchSQL(h.QuoteIdentifier(tableName), h.QuoteIdentifier(columnName), h.QuoteIdentifier(fk.ReferencedTable), h.QuoteIdentifier(fk.ReferencedColumn))
+}
+
+func init() {
+	database.RegisterDialectHandler("spanner", spannerHandler{})
+}
@@ -0,0 +1,397 @@
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/sqladapter"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeResult is a canned response for queries containing a given substring.
+type fakeResult struct {
+	match   string
+	columns []string
+	rows    [][]interface{}
+	err     error
+}
+
+// fakeRunner stands in for the Spanner API, answering queries with the first
+// result whose match string appears in the query and recording every call.
+type fakeRunner struct {
+	results []fakeResult
+	queries []string
+	params  [][]interface{}
+	batches [][]string
+}
+
+func (f *fakeRunner) Query(ctx context.Context, query string, params []interface{}) ([]string, [][]interface{}, error) {
+	f.queries = append(f.queries, query)
+	f.params = append(f.params, params)
+	for _, r := range f.results {
+		if strings.Contains(query, r.match) {
+			return r.columns, r.rows, r.err
+		}
+	}
+	return nil, nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func (f *fakeRunner) Exec(ctx context.Context, statements []string) error {
+	f.batches = append(f.batches, statements)
+	return nil
+}
+
+func (f *fakeRunner) Close() error { return nil }
+
+func newTestDB(runner *fakeRunner) *database.DB {
+	return &database.DB{
+		Pool:    sqladapter.OpenDB("spanner", runner),
+		Handler: spannerHandler{},
+		Config: config.DatabaseConfig{
+			Dialect:            "spanner",
+			Project:            "my-project",
+			SpannerInstance:    "my-instance",
+			DBName:             "sales",
+			UpdateExistingMode: "overwrite",
+		},
+	}
+}
+
+func TestSpannerListTablesExcludesCommentsTable(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{{
+		match:   "INFORMATION_SCHEMA.TABLES",
+		columns: []string{"table_name"},
+		rows:    [][]interface{}{{"Customers"}, {"Orders"}},
+	}}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	tables, err := spannerHandler{}.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	if want := []string{"Customers", "Orders"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("ListTables() = %v, want %v", tables, want)
+	}
+	if want := []interface{}{CommentsTable}; !reflect.DeepEqual(runner.params[0], want) {
+		t.Errorf("ListTables() params = %v, want %v", runner.params[0], want)
+	}
+}
+
+func TestSpannerListColumns(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{{
+		match:   "INFORMATION_SCHEMA.COLUMNS",
+		columns: []string{"column_name", "spanner_type"},
+		rows:    [][]interface{}{{"CustomerId", "INT64"}, {"Email", "STRING(MAX)"}},
+	}}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	columns, err := spannerHandler{}.ListColumns(db, "Customers")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	want := []database.ColumnInfo{{Name: "CustomerId", DataType: "INT64"}, {Name: "Email", DataType: "STRING(MAX)"}}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("ListColumns() = %v, want %v", columns, want)
+	}
+}
+
+func TestSpannerGetColumnMetadata(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{
+		{match: "COUNT(DISTINCT", columns: []string{""}, rows: [][]interface{}{{"2"}}},
+		{match: "IS NULL", columns: []string{""}, rows: [][]interface{}{{"0"}}},
+		{match: "SELECT DISTINCT", columns: []string{""}, rows: [][]interface{}{{"gold"}, {"silver"}}},
+	}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	metadata, err := spannerHandler{}.GetColumnMetadata(db, "Customers", "Tier")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if metadata["DistinctCount"] != int64(2) || metadata["NullCount"] != int64(0) {
+		t.Errorf("GetColumnMetadata() counts = %v/%v, want 2/0", metadata["DistinctCount"], metadata["NullCount"])
+	}
+	if want := []string{"gold", "silver"}; !reflect.DeepEqual(metadata["ExampleValues"], want) {
+		t.Errorf("GetColumnMetadata() examples = %v, want %v", metadata["ExampleValues"], want)
+	}
+	if !strings.Contains(runner.queries[2], "FROM `Customers`") {
+		t.Errorf("GetColumnMetadata() example query = %s", runner.queries[2])
+	}
+}
+
+// TestSpannerAverage checks the AVG query and the rendered average, and that a
+// column that is not numeric is not averaged.
+func TestSpannerAverage(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{
+		{match: "spanner_type", columns: []string{""}, rows: [][]interface{}{{"INT64"}}},
+		{match: "COUNT(DISTINCT", columns: []string{""}, rows: [][]interface{}{{"2"}}},
+		{match: "IS NULL", columns: []string{""}, rows: [][]interface{}{{"0"}}},
+		{match: "SELECT DISTINCT", columns: []string{""}, rows: [][]interface{}{{"10"}}},
+		{match: "AVG(`Amount`)", columns: []string{""}, rows: [][]interface{}{{"12.3456"}}},
+		{match: CommentsTable, columns: []string{"Comment"}},
+	}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+	db.Config.ComputeAverage = true
+
+	metadata, err := spannerHandler{}.GetColumnMetadata(db, "Orders", "Amount")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if want := "SELECT CAST(AVG(`Amount`) AS FLOAT64) FROM `Orders`"; runner.queries[4] != want {
+		t.Errorf("GetColumnMetadata() average query = %s, want %s", runner.queries[4], want)
+	}
+	avg, ok := metadata["Average"].(float64)
+	if !ok || avg != 12.3456 {
+		t.Fatalf("GetColumnMetadata() Average = %v, want 12.3456", metadata["Average"])
+	}
+
+	got, err := spannerHandler{}.GenerateCommentSQL(db, &database.CommentData{TableName: "Orders", ColumnName: "Amount", Average: &avg}, map[string]bool{"average": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	want := `INSERT OR UPDATE INTO DbContextComments (TableName, ColumnName, Comment) VALUES ("Orders", "Amount", "<gemini>Avg: 12.35</gemini>");`
+	if got != want {
+		t.Errorf("GenerateCommentSQL() = %s, want %s", got, want)
+	}
+
+	runner.results[0].rows = [][]interface{}{{"STRING(MAX)"}}
+	queries := len(runner.queries)
+	metadata, err = spannerHandler{}.GetColumnMetadata(db, "Orders", "Tier")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error for a STRING column: %v", err)
+	}
+	if _, ok := metadata["Average"]; ok {
+		t.Errorf("GetColumnMetadata() Average = %v for a STRING column, want none", metadata["Average"])
+	}
+	for _, query := range runner.queries[queries:] {
+		if strings.Contains(query, "AVG(") {
+			t.Errorf("GetColumnMetadata() ran %s for a STRING column", query)
+		}
+	}
+}
+
+func TestSpannerGenerateCommentSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing [][]interface{}
+		want     string
+	}{
+		{
+			name:     "no existing comment",
+			existing: nil,
+			want:     `INSERT OR UPDATE INTO DbContextComments (TableName, ColumnName, Comment) VALUES ("Customers", "Email", "<gemini>Examples: ['a\"b@example.com']</gemini>");`,
+		},
+		{
+			name:     "keeps user comment",
+			existing: [][]interface{}{{"Login email"}},
+			want:     `INSERT OR UPDATE INTO DbContextComments (TableName, ColumnName, Comment) VALUES ("Customers", "Email", "Login email <gemini>Examples: ['a\"b@example.com']</gemini>");`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fakeRunner{results: []fakeResult{{
+				match:   CommentsTable,
+				columns: []string{"Comment"},
+				rows:    tt.existing,
+			}}}
+			db := newTestDB(runner)
+			defer db.Pool.Close()
+
+			data := &database.CommentData{
+				TableName:     "Customers",
+				ColumnName:    "Email",
+				ExampleValues: []string{`a"b@example.com`},
+			}
+			got, err := spannerHandler{}.GenerateCommentSQL(db, data, map[string]bool{"examples": true})
+			if err != nil {
+				t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GenerateCommentSQL() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if want := []interface{}{"Customers", "Email"}; !reflect.DeepEqual(runner.params[0], want) {
+				t.Errorf("comment lookup params = %v, want %v", runner.params[0], want)
+			}
+		})
+	}
+}
+
+func TestSpannerGetAllColumnComments(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{{
+		match:   "SELECT ColumnName, Comment FROM " + CommentsTable,
+		columns: []string{"ColumnName", "Comment"},
+		rows:    [][]interface{}{{"Email", "Login email"}, {"Id", nil}},
+	}}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	comments, err := db.GetAllColumnComments(context.Background(), "Customers")
+	if err != nil {
+		t.Fatalf("GetAllColumnComments() unexpected error: %v", err)
+	}
+	if want := map[string]string{"Email": "Login email"}; !reflect.DeepEqual(comments, want) {
+		t.Errorf("GetAllColumnComments() = %v, want %v", comments, want)
+	}
+
+	data := &database.CommentData{TableName: "Customers", ColumnName: "Email", ExampleValues: []string{"a@example.com"}}
+	if _, err := db.GenerateCommentSQL(data, map[string]bool{"examples": true}); err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	if len(runner.queries) != 1 {
+		t.Errorf("expected the cached comments to be reused, got queries %v", runner.queries)
+	}
+}
+
+func TestSpannerGenerateDeleteTableCommentSQL(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{{
+		match:   CommentsTable,
+		columns: []string{"Comment"},
+		rows:    [][]interface{}{{"<gemini>Description: Customers</gemini>"}},
+	}}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	got, err := spannerHandler{}.GenerateDeleteTableCommentSQL(context.Background(), db, "Customers")
+	if err != nil {
+		t.Fatalf("GenerateDeleteTableCommentSQL() unexpected error: %v", err)
+	}
+	want := `DELETE FROM DbContextComments WHERE TableName = "Customers" AND ColumnName = "";`
+	if got != want {
+		t.Errorf("GenerateDeleteTableCommentSQL() = %s, want %s", got, want)
+	}
+}
+
+func TestSpannerExecuteSQLStatementsBatchesTransaction(t *testing.T) {
+	runner := &fakeRunner{}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	stmts := []string{
+		`INSERT OR UPDATE INTO DbContextComments (TableName, ColumnName, Comment) VALUES ("A", "x", "1");`,
+		`INSERT OR UPDATE INTO DbContextComments (TableName, ColumnName, Comment) VALUES ("A", "y", "2");`,
+	}
+	if err := db.ExecuteSQLStatements(context.Background(), stmts); err != nil {
+		t.Fatalf("ExecuteSQLStatements() unexpected error: %v", err)
+	}
+	if want := [][]string{stmts}; !reflect.DeepEqual(runner.batches, want) {
+		t.Errorf("DML batches = %v, want %v", runner.batches, want)
+	}
+}
+
+func TestSpannerQuoteIdentifier(t *testing.T) {
+	h := spannerHandler{}
+	tests := map[string]string{
+		"Orders": "`Orders`",
+		"a`b":    "`a\\`b`",
+		`a\`:     "`a\\\\`",
+	}
+	for name, want := range tests {
+		if got := h.QuoteIdentifier(name); got != want {
+			t.Errorf("QuoteIdentifier(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSpannerColumnValue(t *testing.T) {
+	tests := []struct {
+		in   *structpb.Value
+		want interface{}
+	}{
+		{structpb.NewNullValue(), nil},
+		{structpb.NewStringValue("42"), "42"},
+		{structpb.NewNumberValue(1.5), 1.5},
+		{structpb.NewBoolValue(true), true},
+		{structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("a")}}), `["a"]`},
+	}
+	for _, tt := range tests {
+		got, err := columnValue(sp.GenericColumnValue{Value: tt.in})
+		if err != nil {
+			t.Fatalf("columnValue(%v) unexpected error: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("columnValue(%v) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestSpannerSpecialIdentifiersEndToEnd follows reserved-word column names from
+// ListColumns through the metadata queries to the generated comment.
+func TestSpannerSpecialIdentifiersEndToEnd(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{
+		{match: "INFORMATION_SCHEMA.COLUMNS", columns: []string{"column_name", "spanner_type"}, rows: [][]interface{}{{"Select", "STRING(MAX)"}, {"Order", "INT64"}}},
+		{match: CommentsTable, columns: []string{"Comment"}},
+		{match: "COUNT(DISTINCT", columns: []string{""}, rows: [][]interface{}{{"1"}}},
+		{match: "IS NULL", columns: []string{""}, rows: [][]interface{}{{"0"}}},
+		{match: "SELECT DISTINCT", columns: []string{""}, rows: [][]interface{}{{"a"}}},
+	}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+	h := spannerHandler{}
+
+	columns, err := h.ListColumns(db, "Group")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	for _, col := range columns {
+		runner.queries = nil
+		metadata, err := h.GetColumnMetadata(db, "Group", col.Name)
+		if err != nil {
+			t.Fatalf("GetColumnMetadata(%q) unexpected error: %v", col.Name, err)
+		}
+		quoted := h.QuoteIdentifier(col.Name)
+		for _, q := range runner.queries {
+			if !strings.Contains(q, "FROM `Group`") || !strings.Contains(q, quoted) {
+				t.Errorf("GetColumnMetadata(%q) query not quoted: %s", col.Name, q)
+			}
+		}
+
+		got, err := h.GenerateCommentSQL(db, &database.CommentData{
+			TableName:     "Group",
+			ColumnName:    col.Name,
+			ExampleValues: metadata["ExampleValues"].([]string),
+		}, map[string]bool{"examples": true})
+		if err != nil {
+			t.Fatalf("GenerateCommentSQL(%q) unexpected error: %v", col.Name, err)
+		}
+		want := `INSERT OR UPDATE INTO DbContextComments (TableName, ColumnName, Comment) VALUES ("Group", "` + col.Name + `", "<gemini>Examples: ['a']</gemini>");`
+		if got != want {
+			t.Errorf("GenerateCommentSQL(%q) =\n%s\nwant\n%s", col.Name, got, want)
+		}
+	}
+}
+
+func TestSpannerListSkipsNullNames(t *testing.T) {
+	runner := &fakeRunner{results: []fakeResult{
+		{match: "INFORMATION_SCHEMA.TABLES", columns: []string{"table_name"}, rows: [][]interface{}{{"Customers"}, {nil}}},
+		{match: "INFORMATION_SCHEMA.COLUMNS", columns: []string{"column_name", "spanner_type"}, rows: [][]interface{}{{"Id", "INT64"}, {nil, "STRING"}, {"Note", nil}}},
+	}}
+	db := newTestDB(runner)
+	defer db.Pool.Close()
+
+	tables, err := spannerHandler{}.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	if len(tables) != 1 || tables[0] != "Customers" {
+		t.Errorf("ListTables() = %v, want [Customers]", tables)
+	}
+
+	columns, err := spannerHandler{}.ListColumns(db, "Customers")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	want := []database.ColumnInfo{{Name: "Id", DataType: "INT64"}, {Name: "Note"}}
+	if len(columns) != len(want) || columns[0] != want[0] || columns[1] != want[1] {
+		t.Errorf("ListColumns() = %v, want %v", columns, want)
+	}
+}
@@ -0,0 +1,175 @@
+// Package sqladapter exposes databases reached through a Go client library, such
+// as BigQuery and Spanner, as a *sql.DB so their dialect handlers can use db.Pool
+// like the handlers backed by a database/sql driver.
+package sqladapter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+)
+
+// Runner executes statements through a database's client library. It is the seam
+// between the adapter and the client, so tests can substitute canned results.
+type Runner interface {
+	// Query runs a read-only query. params bind to @p1, @p2, ... in order.
+	Query(ctx context.Context, query string, params []interface{}) ([]string, [][]interface{}, error)
+	// Exec runs the statements in order, in a single transaction where the
+	// database supports one.
+	Exec(ctx context.Context, statements []string) error
+	Close() error
+}
+
+// OpenDB returns a *sql.DB whose connections send their statements to runner.
+// name prefixes the adapter's errors. Closing the *sql.DB closes runner.
+func OpenDB(name string, runner Runner) *sql.DB {
+	return sql.OpenDB(&connector{name: name, runner: runner})
+}
+
+type connector struct {
+	name   string
+	runner Runner
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &conn{name: c.name, runner: c.runner}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return adapterDriver{name: c.name}
+}
+
+// Close is called by sql.DB.Close and releases the runner's client.
+func (c *connector) Close() error {
+	return c.runner.Close()
+}
+
+type adapterDriver struct {
+	name string
+}
+
+func (d adapterDriver) Open(name string) (driver.Conn, error) {
+	return nil, fmt.Errorf("%s: connections must be opened through a connector", d.name)
+}
+
+type conn struct {
+	name   string
+	runner Runner
+	tx     *tx
+}
+
+var (
+	_ driver.QueryerContext = (*conn)(nil)
+	_ driver.ExecerContext  = (*conn)(nil)
+	_ driver.Pinger         = (*conn)(nil)
+	_ driver.ConnBeginTx    = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("%s: prepared statements are not supported", c.name)
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a transaction whose statements are buffered and passed to
+// Runner.Exec together on Commit, with ctx.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, fmt.Errorf("%s: transaction already in progress", c.name)
+	}
+	c.tx = &tx{ctx: ctx, conn: c}
+	return c.tx, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	_, _, err := c.runner.Query(ctx, "SELECT 1", nil)
+	return err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	params := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("%s: named arguments are not supported, use @p1, @p2, ...", c.name)
+		}
+		params[i] = arg.Value
+	}
+	columns, values, err := c.runner.Query(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: columns, values: values}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%s: parameters are not supported for statements", c.name)
+	}
+	if c.tx != nil {
+		c.tx.statements = append(c.tx.statements, query)
+		return driver.RowsAffected(0), nil
+	}
+	if err := c.runner.Exec(ctx, []string{query}); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type tx struct {
+	ctx        context.Context
+	conn       *conn
+	statements []string
+}
+
+func (t *tx) Commit() error {
+	t.conn.tx = nil
+	if len(t.statements) == 0 {
+		return nil
+	}
+	return t.conn.runner.Exec(t.ctx, t.statements)
+}
+
+func (t *tx) Rollback() error {
+	t.conn.tx = nil
+	return nil
+}
+
+// rows serves a fully materialized result set. Values the runner could not map
+// to a database/sql type are expected to be strings, which database/sql
+// converts to the scan destination type.
+type rows struct {
+	columns []string
+	values  [][]interface{}
+	pos     int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+	for i := range dest {
+		if i < len(r.values[r.pos]) {
+			dest[i] = r.values[r.pos][i]
+		} else {
+			dest[i] = nil
+		}
+	}
+	r.pos++
+	return nil
+}
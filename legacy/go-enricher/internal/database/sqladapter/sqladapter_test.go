@@ -0,0 +1,179 @@
+package sqladapter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeRunner answers every query with the same result and records every call.
+type fakeRunner struct {
+	columns []string
+	values  [][]interface{}
+	err     error
+
+	queries []string
+	params  [][]interface{}
+	execs   [][]string
+	execCtx context.Context
+	closed  bool
+}
+
+func (f *fakeRunner) Query(ctx context.Context, query string, params []interface{}) ([]string, [][]interface{}, error) {
+	f.queries = append(f.queries, query)
+	f.params = append(f.params, params)
+	return f.columns, f.values, f.err
+}
+
+func (f *fakeRunner) Exec(ctx context.Context, statements []string) error {
+	f.execs = append(f.execs, statements)
+	f.execCtx = ctx
+	return f.err
+}
+
+func (f *fakeRunner) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestQueryBindsParametersAndScansRows(t *testing.T) {
+	runner := &fakeRunner{
+		columns: []string{"name", "total"},
+		values:  [][]interface{}{{"a", "3"}, {nil, int64(4)}},
+	}
+	db := OpenDB("test", runner)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT name, total FROM t WHERE x = @p1 AND y = @p2", "x", int64(2))
+	if err != nil {
+		t.Fatalf("Query() unexpected error: %v", err)
+	}
+	defer rows.Close()
+
+	type row struct {
+		name  *string
+		total int
+	}
+	var got []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.name, &r.total); err != nil {
+			t.Fatalf("Scan() unexpected error: %v", err)
+		}
+		got = append(got, r)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err() = %v", err)
+	}
+	if len(got) != 2 || got[0].name == nil || *got[0].name != "a" || got[0].total != 3 || got[1].name != nil || got[1].total != 4 {
+		t.Errorf("scanned rows = %+v, want [{a 3} {<nil> 4}]", got)
+	}
+	if want := []interface{}{"x", int64(2)}; !reflect.DeepEqual(runner.params[0], want) {
+		t.Errorf("params = %v, want %v", runner.params[0], want)
+	}
+}
+
+func TestTransactionBuffersStatementsUntilCommit(t *testing.T) {
+	runner := &fakeRunner{}
+	db := OpenDB("test", runner)
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() unexpected error: %v", err)
+	}
+	for _, stmt := range []string{"UPDATE a", "UPDATE b"} {
+		if _, err := tx.Exec(stmt); err != nil {
+			t.Fatalf("Exec(%q) unexpected error: %v", stmt, err)
+		}
+	}
+	if len(runner.execs) != 0 {
+		t.Fatalf("statements executed before Commit: %v", runner.execs)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() unexpected error: %v", err)
+	}
+	if want := [][]string{{"UPDATE a", "UPDATE b"}}; !reflect.DeepEqual(runner.execs, want) {
+		t.Errorf("executed batches = %v, want %v", runner.execs, want)
+	}
+}
+
+func TestRollbackDiscardsStatements(t *testing.T) {
+	runner := &fakeRunner{}
+	db := OpenDB("test", runner)
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() unexpected error: %v", err)
+	}
+	if _, err := tx.Exec("UPDATE a"); err != nil {
+		t.Fatalf("Exec() unexpected error: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() unexpected error: %v", err)
+	}
+	if len(runner.execs) != 0 {
+		t.Errorf("executed batches = %v, want none", runner.execs)
+	}
+}
+
+func TestCommitReturnsExecError(t *testing.T) {
+	runner := &fakeRunner{err: errors.New("boom")}
+	db := OpenDB("test", runner)
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() unexpected error: %v", err)
+	}
+	if _, err := tx.Exec("UPDATE a"); err != nil {
+		t.Fatalf("Exec() unexpected error: %v", err)
+	}
+	if err := tx.Commit(); err == nil || err.Error() != "boom" {
+		t.Errorf("Commit() error = %v, want boom", err)
+	}
+}
+
+func TestCommitExecutesWithTransactionContext(t *testing.T) {
+	runner := &fakeRunner{}
+	db := OpenDB("test", runner)
+	defer db.Close()
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "run")
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx() unexpected error: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE a"); err != nil {
+		t.Fatalf("ExecContext() unexpected error: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() unexpected error: %v", err)
+	}
+	if runner.execCtx == nil || runner.execCtx.Value(key{}) != "run" {
+		t.Error("Commit() did not pass the transaction's context to Runner.Exec")
+	}
+}
+
+func TestExecRejectsParameters(t *testing.T) {
+	db := OpenDB("test", &fakeRunner{})
+	defer db.Close()
+
+	if _, err := db.Exec("UPDATE a SET x = @p1", "x"); err == nil {
+		t.Error("Exec() with parameters succeeded, want an error")
+	}
+}
+
+func TestCloseClosesRunner(t *testing.T) {
+	runner := &fakeRunner{}
+	db := OpenDB("test", runner)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	if !runner.closed {
+		t.Error("Close() did not close the runner")
+	}
+}
@@ -0,0 +1,438 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	_ "modernc.org/sqlite"
+)
+
+// commentsTable stores table and column comments, which SQLite has no syntax for.
+// Table comments are stored with an empty column_name. The generated statements
+// create it when it does not exist yet, and ListTables leaves it out.
+const commentsTable = "_db_context_comments"
+
+const createCommentsTableSQL = `CREATE TABLE IF NOT EXISTS "` + commentsTable + `" (table_name TEXT NOT NULL, column_name TEXT NOT NULL DEFAULT '', comment TEXT NOT NULL, PRIMARY KEY (table_name, column_name));`
+
+// sqliteHandler keeps comments in the commentsTable side table of the database file.
+type sqliteHandler struct{}
+
+var _ database.DialectHandler = (*sqliteHandler)(nil)
+
+func (h sqliteHandler) CreateCloudSQLPool(cfg config.DatabaseConfig) (*sql.DB, error) {
+	return nil, fmt.Errorf("Cloud SQL connections are not supported for SQLite")
+}
+
+// CreateStandardPool opens the database file named by --database. A missing file is
+// an error rather than a new, empty database.
+func (h sqliteHandler) CreateStandardPool(cfg config.DatabaseConfig) (*sql.DB, error) {
+	if _, err := os.Stat(cfg.DBName); err != nil {
+		return nil, fmt.Errorf("cannot open SQLite database file '%s': %w", cfg.DBName, err)
+	}
+	return sql.Open("sqlite", cfg.DBName)
+}
+
+// QuoteIdentifier quotes a name with double quotes, doubling any inside it.
+func (h sqliteHandler) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteSQLiteString returns value as a single-quoted SQLite string literal.
+func quoteSQLiteString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// ListTables lists the tables of the database, without SQLite's internal tables and
+// the comments side table.
+func (h sqliteHandler) ListTables(db *database.DB) ([]string, error) {
+	query := `
+		SELECT name
+		FROM sqlite_master
+		WHERE type = 'table'
+			AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+			AND name != ?
+		ORDER BY name`
+
+	rows, err := db.Pool.Query(query, commentsTable)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var tableName sql.NullString
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, fmt.Errorf("error scanning table name: %w", err)
+		}
+		if !tableName.Valid {
+			log.Println("WARN: Skipping a table listed with a NULL name.")
+			continue
+		}
+		tables = append(tables, tableName.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating table rows: %w", err)
+	}
+	return tables, nil
+}
+
+// ListColumns lists a table's columns with their declared types, which are empty
+// for columns declared without one, such as x in CREATE TABLE t(x).
+func (h sqliteHandler) ListColumns(db *database.DB, tableName string) ([]database.ColumnInfo, error) {
+	query := `SELECT name, type FROM pragma_table_info(?) ORDER BY cid`
+
+	rows, err := db.Pool.Query(query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("error querying columns for table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var columns []database.ColumnInfo
+	for rows.Next() {
+		var name, dataType sql.NullString
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return nil, fmt.Errorf("error scanning column name and data type: %w", err)
+		}
+		if !name.Valid {
+			log.Printf("WARN: Skipping a column of %s listed with a NULL name.", tableName)
+			continue
+		}
+		columns = append(columns, database.ColumnInfo{Name: name.String, DataType: dataType.String})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column rows: %w", err)
+	}
+
+	return columns, nil
+}
+
+func (h sqliteHandler) GetColumnMetadata(db *database.DB, tableName string, columnName string) (map[string]interface{}, error) {
+	quotedTable := h.QuoteIdentifier(tableName)
+	quotedColumn := h.QuoteIdentifier(columnName)
+	ctx := context.Background()
+
+	distinctQuery := fmt.Sprintf("SELECT COUNT(DISTINCT %s) FROM %s", quotedColumn, quotedTable)
+	binaryDistinctQuery := fmt.Sprintf("SELECT COUNT(DISTINCT %s COLLATE BINARY) FROM %s", quotedColumn, quotedTable)
+	distinctCount, err := database.DistinctCount(ctx, db, tableName+"."+columnName, distinctQuery, binaryDistinctQuery)
+	if err != nil {
+		log.Printf("WARN: Failed to get distinct count for %s.%s: %v. Reporting -1.", tableName, columnName, err)
+		distinctCount = -1
+	}
+
+	nullQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NULL", quotedTable, quotedColumn)
+	var nullCount int64
+	if err := db.Pool.QueryRowContext(ctx, nullQuery).Scan(&nullCount); err != nil {
+		return nil, fmt.Errorf("failed to get null count for %s.%s: %w", tableName, columnName, err)
+	}
+
+	// Values are cast to TEXT, since SQLite lets a column hold values of any type.
+	exampleLimit := database.ExampleQueryLimit(db.Config.ExampleSampleSize)
+	exampleQuery := fmt.Sprintf("SELECT DISTINCT CAST(%s AS TEXT) FROM %s WHERE %s IS NOT NULL ORDER BY 1 LIMIT %d",
+		quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	firstRows := database.SampleWithoutDistinct(db, distinctCount)
+	if firstRows {
+		exampleQuery = fmt.Sprintf("SELECT CAST(%s AS TEXT) FROM %s WHERE %s IS NOT NULL LIMIT %d",
+			quotedColumn, quotedTable, quotedColumn, exampleLimit)
+	}
+	rows, err := db.Pool.QueryContext(ctx, exampleQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get example values for %s.%s: %w", tableName, columnName, err)
+	}
+	defer rows.Close()
+
+	var examples []string
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("error scanning example value for %s.%s: %w", tableName, columnName, err)
+		}
+		if value.Valid {
+			examples = append(examples, value.String)
+		}
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating example values for %s.%s: %w", tableName, columnName, rows.Err())
+	}
+	if firstRows {
+		examples = database.UniqueValues(examples)
+	}
+	examples = database.SelectRepresentativeValues(examples, database.DefaultExampleCount)
+
+	metadata := map[string]interface{}{
+		"DistinctCount": distinctCount,
+		"NullCount":     nullCount,
+		"ExampleValues": examples,
+	}
+	// SQLite averages text as numbers, so only values stored as numbers are averaged;
+	// a column holding none gets no average.
+	if db.Config.ComputeAverage {
+		avgQuery := fmt.Sprintf("SELECT AVG(%s) FROM %s WHERE typeof(%s) IN ('integer', 'real')", quotedColumn, quotedTable, quotedColumn)
+		if avg := database.ColumnAverage(ctx, db, avgQuery); avg != nil {
+			metadata["Average"] = *avg
+		}
+	}
+	return metadata, nil
+}
+
+// formatExampleValues renders the examples as plain comment text. Values are not
+// escaped here: the whole comment is escaped once by quoteSQLiteString.
+func (h sqliteHandler) formatExampleValues(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	cleaned := make([]string, len(values))
+	for i, v := range values {
+		trimmed := strings.ReplaceAll(v, "\n", " ")
+		if len(trimmed) > 100 {
+			trimmed = trimmed[:100] + "...[truncated]"
+		}
+		cleaned[i] = trimmed
+	}
+	return fmt.Sprintf("Examples: ['%s']", strings.Join(cleaned, "', '"))
+}
+
+// formatForeignKeys renders referenced columns with this dialect's identifier quoting.
+func (h sqliteHandler) formatForeignKeys(foreignKeys []database.ForeignKeyReference) string {
+	return database.FormatForeignKeys(foreignKeys, h.QuoteIdentifier)
+}
+
+// commentsTableExists reports whether the comments side table has been created. An
+// offline DB is treated as a database without it.
+func (h sqliteHandler) commentsTableExists(ctx context.Context, db *database.DB) (bool, error) {
+	if db.Offline() {
+		return false, nil
+	}
+	var count int
+	err := db.Pool.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, commentsTable).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up the %s table: %w", commentsTable, err)
+	}
+	return count > 0, nil
+}
+
+// commentSQL stores comment for a column, or for the table when columnName is
+// empty, creating the side table first if it does not exist yet. An empty comment
+// deletes the stored one; it returns "" when there is none to delete.
+func (h sqliteHandler) commentSQL(ctx context.Context, db *database.DB, tableName, columnName, comment string) (string, error) {
+	exists, err := h.commentsTableExists(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	if comment == "" {
+		if !exists {
+			return "", nil
+		}
+		return fmt.Sprintf("DELETE FROM %s WHERE table_name = %s AND column_name = %s;",
+			h.QuoteIdentifier(commentsTable), quoteSQLiteString(tableName), quoteSQLiteString(columnName)), nil
+	}
+	upsert := fmt.Sprintf("INSERT INTO %s (table_name, column_name, comment) VALUES (%s, %s, %s) ON CONFLICT (table_name, column_name) DO UPDATE SET comment = excluded.comment;",
+		h.QuoteIdentifier(commentsTable), quoteSQLiteString(tableName), quoteSQLiteString(columnName), quoteSQLiteString(comment))
+	if !exists {
+		return createCommentsTableSQL + "\n" + upsert, nil
+	}
+	return upsert, nil
+}
+
+// storedComment returns the comment stored for a column, or for the table when
+// columnName is empty, and "" when there is none.
+func (h sqliteHandler) storedComment(ctx context.Context, db *database.DB, tableName, columnName string) (string, error) {
+	exists, err := h.commentsTableExists(ctx, db)
+	if err != nil || !exists {
+		return "", err
+	}
+	query := fmt.Sprintf("SELECT comment FROM %s WHERE table_name = ? AND column_name = ?", h.QuoteIdentifier(commentsTable))
+	var comment string
+	err = db.Pool.QueryRowContext(ctx, query, tableName, columnName).Scan(&comment)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve comment for %s: %w", strings.TrimSuffix(tableName+"."+columnName, "."), err)
+	}
+	return comment, nil
+}
+
+func (h sqliteHandler) GenerateCommentSQL(db *database.DB, data *database.CommentData, enrichments map[string]bool) (string, error) {
+	if data == nil || data.TableName == "" || data.ColumnName == "" {
+		return "", fmt.Errorf("invalid input for GenerateCommentSQL")
+	}
+
+	formattedExamples := h.formatExampleValues(data.ExampleValues)
+	newMetadataComment := database.GenerateMetadataCommentString(data, enrichments, formattedExamples, h.formatForeignKeys(data.ForeignKeys))
+
+	existingComment, err := database.ExistingColumnComment(context.Background(), db, h, data.TableName, data.ColumnName)
+	if err != nil {
+		log.Printf("WARN: Failed to get existing column comment for %s.%s: %v. Proceeding as if empty.", data.TableName, data.ColumnName, err)
+		existingComment = ""
+	}
+
+	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
+	return h.commentSQL(context.Background(), db, data.TableName, data.ColumnName, finalComment)
+}
+
+// GenerateRewriteCommentSQL returns the statement that replaces a column's comment
+// with rewrite(existing), or "" when that leaves the comment unchanged.
+func (h sqliteHandler) GenerateRewriteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string, rewrite func(string) string) (string, error) {
+	if tableName == "" || columnName == "" {
+		return "", fmt.Errorf("table and column names cannot be empty for GenerateRewriteCommentSQL")
+	}
+
+	existingComment, err := database.ExistingColumnComment(ctx, db, h, tableName, columnName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get existing column comment for %s.%s before rewrite: %w", tableName, columnName, err)
+	}
+
+	finalComment := strings.TrimSpace(rewrite(existingComment))
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.commentSQL(ctx, db, tableName, columnName, finalComment)
+}
+
+func (h sqliteHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	return h.GenerateRewriteCommentSQL(ctx, db, tableName, columnName, database.StripMetadata)
+}
+
+func (h sqliteHandler) GetColumnComment(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	return h.storedComment(ctx, db, tableName, columnName)
+}
+
+// GetAllColumnComments reads the comments stored for every column of a table in one query.
+func (h sqliteHandler) GetAllColumnComments(ctx context.Context, db *database.DB, tableName string) (map[string]string, error) {
+	comments := make(map[string]string)
+	exists, err := h.commentsTableExists(ctx, db)
+	if err != nil || !exists {
+		return comments, err
+	}
+	query := fmt.Sprintf("SELECT column_name, comment FROM %s WHERE table_name = ? AND column_name != ''", h.QuoteIdentifier(commentsTable))
+
+	rows, err := db.Pool.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve column comments for table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var column, comment string
+		if err := rows.Scan(&column, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column comment for table %s: %w", tableName, err)
+		}
+		if comment != "" {
+			comments[column] = comment
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column comments for table %s: %w", tableName, err)
+	}
+	return comments, nil
+}
+
+func (h sqliteHandler) GenerateTableCommentSQL(db *database.DB, data *database.TableCommentData, enrichments map[string]bool) (string, error) {
+	if data == nil || data.TableName == "" {
+		return "", fmt.Errorf("table comment data cannot be nil or empty")
+	}
+
+	newMetadataComment := database.GenerateTableMetadataCommentString(data, enrichments)
+
+	existingComment, err := database.ExistingTableComment(context.Background(), db, h, data.TableName)
+	if err != nil {
+		log.Printf("WARN: Failed to get existing table comment for %s: %v. Proceeding as if empty.", data.TableName, err)
+		existingComment = ""
+	}
+
+	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.commentSQL(context.Background(), db, data.TableName, "", finalComment)
+}
+
+func (h sqliteHandler) GetTableComment(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	return h.storedComment(ctx, db, tableName, "")
+}
+
+// GenerateRewriteTableCommentSQL is GenerateRewriteCommentSQL for the table comment.
+func (h sqliteHandler) GenerateRewriteTableCommentSQL(ctx context.Context, db *database.DB, tableName string, rewrite func(string) string) (string, error) {
+	if tableName == "" {
+		return "", fmt.Errorf("table name cannot be empty for GenerateRewriteTableCommentSQL")
+	}
+
+	existingComment, err := h.GetTableComment(ctx, db, tableName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get existing table comment for %s before rewrite: %w", tableName, err)
+	}
+
+	finalComment := strings.TrimSpace(rewrite(existingComment))
+	if finalComment == strings.TrimSpace(existingComment) {
+		return "", nil
+	}
+	return h.commentSQL(ctx, db, tableName, "", finalComment)
+}
+
+func (h sqliteHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	return h.GenerateRewriteTableCommentSQL(ctx, db, tableName, database.StripMetadata)
+}
+
+// GetForeignKeys reads the column's foreign keys with pragma_foreign_key_list. A
+// reference without a column names the parent table's primary key, which is looked
+// up in turn. SQLite does not name foreign key constraints.
+func (h sqliteHandler) GetForeignKeys(db *database.DB, tableName string, columnName string) ([]database.ForeignKeyReference, error) {
+	query := `SELECT "table", "to", seq FROM pragma_foreign_key_list(?) WHERE "from" = ? ORDER BY id, seq`
+
+	rows, err := db.Pool.Query(query, tableName, columnName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query foreign keys for %s.%s: %w", tableName, columnName, err)
+	}
+	defer rows.Close()
+
+	type reference struct {
+		table  string
+		column sql.NullString
+		seq    int
+	}
+	var references []reference
+	for rows.Next() {
+		var ref reference
+		if err := rows.Scan(&ref.table, &ref.column, &ref.seq); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key row for %s.%s: %w", tableName, columnName, err)
+		}
+		references = append(references, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign key rows for %s.%s: %w", tableName, columnName, err)
+	}
+	rows.Close()
+
+	var fks []database.ForeignKeyReference
+	for _, ref := range references {
+		referencedColumn := ref.column.String
+		if !ref.column.Valid || referencedColumn == "" {
+			// The seq-th column of a composite key references the seq-th primary key column.
+			pkQuery := `SELECT name FROM pragma_table_info(?) WHERE pk = ?`
+			if err := db.Pool.QueryRow(pkQuery, ref.table, ref.seq+1).Scan(&referencedColumn); err != nil {
+				log.Printf("WARN: Column[%s.%s] Failed to find the primary key of %s that it references: %v", tableName, columnName, ref.table, err)
+				continue
+			}
+		}
+		fks = append(fks, database.ForeignKeyReference{ReferencedTable: ref.table, ReferencedColumn: referencedColumn})
+	}
+	return fks, nil
+}
+
+// ForeignKeyMatchQuery implements database.ForeignKeyMatcher. Foreign keys are only
+// enforced with PRAGMA foreign_keys on, so rows may not match.
+func (h sqliteHandler) ForeignKeyMatchQuery(ctx context.Context, db *database.DB, tableName string, columnName string, fk database.ForeignKeyReference) string {
+	return database.ForeignKeyMatchSQL(h.QuoteIdentifier(tableName), h.QuoteIdentifier(columnName), h.QuoteIdentifier(fk.ReferencedTable), h.QuoteIdentifier(fk.ReferencedColumn))
+}
+
+func init() {
+	database.RegisterDialectHandler("sqlite", sqliteHandler{})
+}
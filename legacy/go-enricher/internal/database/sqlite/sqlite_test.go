@@ -0,0 +1,319 @@
+package sqlite
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+func newMockSQLiteDB(t *testing.T) (*database.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	db := &database.DB{
+		Pool:    mockDB,
+		Handler: sqliteHandler{},
+		Config: config.DatabaseConfig{
+			Dialect:            "sqlite",
+			DBName:             "shop.db",
+			UpdateExistingMode: "overwrite",
+		},
+	}
+	return db, mock
+}
+
+// expectCommentsTable expects the lookup of the comments side table.
+func expectCommentsTable(mock sqlmock.Sqlmock, exists bool) {
+	count := 0
+	if exists {
+		count = 1
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM sqlite_master WHERE type = 'table' AND name = ?")).WithArgs(commentsTable).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+func TestSQLiteDialectRegistered(t *testing.T) {
+	handler, err := database.GetDialectHandler("sqlite")
+	if err != nil {
+		t.Fatalf("GetDialectHandler(sqlite) unexpected error: %v", err)
+	}
+	if _, ok := handler.(sqliteHandler); !ok {
+		t.Errorf("GetDialectHandler(sqlite) = %T, want sqliteHandler", handler)
+	}
+}
+
+func TestSQLiteQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"orders", `"orders"`},
+		{`odd"name`, `"odd""name"`},
+	}
+	for _, tt := range tests {
+		if got := (sqliteHandler{}).QuoteIdentifier(tt.name); got != tt.expected {
+			t.Errorf("QuoteIdentifier(%q) = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestSQLiteCreateStandardPoolMissingFile(t *testing.T) {
+	_, err := sqliteHandler{}.CreateStandardPool(config.DatabaseConfig{Dialect: "sqlite", DBName: t.TempDir() + "/missing.db"})
+	if err == nil {
+		t.Fatal("CreateStandardPool() with a missing file succeeded, want an error")
+	}
+}
+
+func TestSQLiteListTables(t *testing.T) {
+	db, mock := newMockSQLiteDB(t)
+	defer db.Pool.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM sqlite_master")).WithArgs(commentsTable).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("customers").AddRow("orders"))
+
+	tables, err := sqliteHandler{}.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	if want := []string{"customers", "orders"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("ListTables() = %v, want %v", tables, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLiteListColumns(t *testing.T) {
+	db, mock := newMockSQLiteDB(t)
+	defer db.Pool.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM pragma_table_info(?)")).WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "type"}).
+			AddRow("id", "INTEGER").
+			AddRow("notes", "").
+			AddRow("payload", nil))
+
+	columns, err := sqliteHandler{}.ListColumns(db, "orders")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	want := []database.ColumnInfo{{Name: "id", DataType: "INTEGER"}, {Name: "notes", DataType: ""}, {Name: "payload", DataType: ""}}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("ListColumns() = %v, want %v", columns, want)
+	}
+}
+
+func TestSQLiteGetColumnMetadata(t *testing.T) {
+	db, mock := newMockSQLiteDB(t)
+	defer db.Pool.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT "status") FROM "orders"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(3)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM "orders" WHERE "status" IS NULL`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT CAST("status" AS TEXT) FROM "orders" WHERE "status" IS NOT NULL ORDER BY 1 LIMIT 3`)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("new").AddRow("paid").AddRow("shipped"))
+
+	metadata, err := sqliteHandler{}.GetColumnMetadata(db, "orders", "status")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if metadata["DistinctCount"] != int64(3) || metadata["NullCount"] != int64(2) {
+		t.Errorf("Expected DistinctCount 3 and NullCount 2, got %v and %v", metadata["DistinctCount"], metadata["NullCount"])
+	}
+	if ev := metadata["ExampleValues"]; !reflect.DeepEqual(ev, []string{"new", "paid", "shipped"}) {
+		t.Errorf("Expected ExampleValues [new paid shipped], got %v", ev)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// TestSQLiteAverage checks that only values stored as numbers are averaged, and that
+// the average is rendered in the comment.
+func TestSQLiteAverage(t *testing.T) {
+	db, mock := newMockSQLiteDB(t)
+	defer db.Pool.Close()
+	db.Config.ComputeAverage = true
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT "amount") FROM "orders"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM "orders" WHERE "amount" IS NULL`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT CAST("amount" AS TEXT) FROM "orders"`)).WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow("10"))
+	mock.ExpectQuery("^" + regexp.QuoteMeta(`SELECT AVG("amount") FROM "orders" WHERE typeof("amount") IN ('integer', 'real')`) + "$").
+		WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(12.3456))
+
+	metadata, err := sqliteHandler{}.GetColumnMetadata(db, "orders", "amount")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	avg, ok := metadata["Average"].(float64)
+	if !ok || avg != 12.3456 {
+		t.Fatalf("GetColumnMetadata() Average = %v, want 12.3456", metadata["Average"])
+	}
+
+	expectCommentsTable(mock, false)
+	expectCommentsTable(mock, false)
+	got, err := sqliteHandler{}.GenerateCommentSQL(db, &database.CommentData{TableName: "orders", ColumnName: "amount", Average: &avg}, map[string]bool{"average": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	if want := `VALUES ('orders', 'amount', '<gemini>Avg: 12.35</gemini>')`; !strings.Contains(got, want) {
+		t.Errorf("GenerateCommentSQL() = %q, want it to contain %q", got, want)
+	}
+
+	// A column holding no numbers averages to NULL and gets no average.
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT "status") FROM "orders"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM "orders" WHERE "status" IS NULL`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT CAST("status" AS TEXT) FROM "orders"`)).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("paid"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT AVG("status") FROM "orders"`)).WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(nil))
+	metadata, err = sqliteHandler{}.GetColumnMetadata(db, "orders", "status")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if _, ok := metadata["Average"]; ok {
+		t.Errorf("GetColumnMetadata() Average = %v for a text column, want none", metadata["Average"])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLiteGenerateCommentSQL(t *testing.T) {
+	data := &database.CommentData{
+		TableName:     "orders",
+		ColumnName:    "status",
+		Description:   "Order's state",
+		ExampleValues: []string{"new", "paid"},
+	}
+	enrichments := map[string]bool{"description": true, "examples": true}
+	upsert := `INSERT INTO "_db_context_comments" (table_name, column_name, comment) VALUES ('orders', 'status', '<gemini>Examples: [''new'', ''paid''] | Order''s state</gemini>') ON CONFLICT (table_name, column_name) DO UPDATE SET comment = excluded.comment;`
+
+	t.Run("creates the comments table", func(t *testing.T) {
+		db, mock := newMockSQLiteDB(t)
+		defer db.Pool.Close()
+		expectCommentsTable(mock, false)
+		expectCommentsTable(mock, false)
+
+		got, err := sqliteHandler{}.GenerateCommentSQL(db, data, enrichments)
+		if err != nil {
+			t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+		}
+		if want := createCommentsTableSQL + "\n" + upsert; got != want {
+			t.Errorf("GenerateCommentSQL() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("existing comments table", func(t *testing.T) {
+		db, mock := newMockSQLiteDB(t)
+		defer db.Pool.Close()
+		expectCommentsTable(mock, true)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT comment FROM")).WithArgs("orders", "status").
+			WillReturnRows(sqlmock.NewRows([]string{"comment"}))
+		expectCommentsTable(mock, true)
+
+		got, err := sqliteHandler{}.GenerateCommentSQL(db, data, enrichments)
+		if err != nil {
+			t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+		}
+		if got != upsert {
+			t.Errorf("GenerateCommentSQL() = %q, want %q", got, upsert)
+		}
+	})
+}
+
+func TestSQLiteGenerateTableCommentSQL(t *testing.T) {
+	db, mock := newMockSQLiteDB(t)
+	defer db.Pool.Close()
+
+	expectCommentsTable(mock, true)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT comment FROM")).WithArgs("orders", "").
+		WillReturnRows(sqlmock.NewRows([]string{"comment"}).AddRow("Orders"))
+	expectCommentsTable(mock, true)
+
+	got, err := sqliteHandler{}.GenerateTableCommentSQL(db, &database.TableCommentData{TableName: "orders", Description: "Customer orders"}, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateTableCommentSQL() unexpected error: %v", err)
+	}
+	want := `INSERT INTO "_db_context_comments" (table_name, column_name, comment) VALUES ('orders', '', 'Orders <gemini>Customer orders</gemini>') ON CONFLICT (table_name, column_name) DO UPDATE SET comment = excluded.comment;`
+	if got != want {
+		t.Errorf("GenerateTableCommentSQL() = %q, want %q", got, want)
+	}
+}
+
+func TestSQLiteGenerateDeleteCommentSQL(t *testing.T) {
+	db, mock := newMockSQLiteDB(t)
+	defer db.Pool.Close()
+
+	expectCommentsTable(mock, true)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT comment FROM")).WithArgs("orders", "status").
+		WillReturnRows(sqlmock.NewRows([]string{"comment"}).AddRow("<gemini>Examples: ['new']</gemini>"))
+	expectCommentsTable(mock, true)
+	expectCommentsTable(mock, true)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT comment FROM")).WithArgs("orders", "notes").
+		WillReturnRows(sqlmock.NewRows([]string{"comment"}).AddRow("User text only"))
+
+	got, err := sqliteHandler{}.GenerateDeleteCommentSQL(context.Background(), db, "orders", "status")
+	if err != nil {
+		t.Fatalf("GenerateDeleteCommentSQL() unexpected error: %v", err)
+	}
+	if want := `DELETE FROM "_db_context_comments" WHERE table_name = 'orders' AND column_name = 'status';`; got != want {
+		t.Errorf("GenerateDeleteCommentSQL() = %q, want %q", got, want)
+	}
+
+	got, err = sqliteHandler{}.GenerateDeleteCommentSQL(context.Background(), db, "orders", "notes")
+	if err != nil {
+		t.Fatalf("GenerateDeleteCommentSQL() unexpected error: %v", err)
+	}
+	if got != "" {
+		t.Errorf("GenerateDeleteCommentSQL() without generated metadata = %q, want no statement", got)
+	}
+}
+
+func TestSQLiteGetColumnCommentWithoutCommentsTable(t *testing.T) {
+	db, mock := newMockSQLiteDB(t)
+	defer db.Pool.Close()
+
+	expectCommentsTable(mock, false)
+
+	got, err := sqliteHandler{}.GetColumnComment(context.Background(), db, "orders", "status")
+	if err != nil {
+		t.Fatalf("GetColumnComment() unexpected error: %v", err)
+	}
+	if got != "" {
+		t.Errorf("GetColumnComment() = %q, want empty", got)
+	}
+}
+
+func TestSQLiteGetForeignKeys(t *testing.T) {
+	db, mock := newMockSQLiteDB(t)
+	defer db.Pool.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM pragma_foreign_key_list(?)")).WithArgs("orders", "customer_id").
+		WillReturnRows(sqlmock.NewRows([]string{"table", "to", "seq"}).
+			AddRow("customers", "id", 0).
+			AddRow("accounts", nil, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT name FROM pragma_table_info(?) WHERE pk = ?")).WithArgs("accounts", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("account_id"))
+
+	fks, err := sqliteHandler{}.GetForeignKeys(db, "orders", "customer_id")
+	if err != nil {
+		t.Fatalf("GetForeignKeys() unexpected error: %v", err)
+	}
+	want := []database.ForeignKeyReference{
+		{ReferencedTable: "customers", ReferencedColumn: "id"},
+		{ReferencedTable: "accounts", ReferencedColumn: "account_id"},
+	}
+	if !reflect.DeepEqual(fks, want) {
+		t.Errorf("GetForeignKeys() = %v, want %v", fks, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package sqlserver
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -74,6 +75,7 @@ func (h sqlServerHandler) CreateCloudSQLPool(cfg config.DatabaseConfig) (*sql.DB
 
 	query := url.Values{}
 	query.Add("database", dbName)
+	query.Add("app name", database.ApplicationName)
 	u := &url.URL{
 		Scheme:   "sqlserver",
 		User:     url.UserPassword(dbUser, dbPwd),
@@ -98,27 +100,32 @@ func (h sqlServerHandler) CreateCloudSQLPool(cfg config.DatabaseConfig) (*sql.DB
 }
 
 func (h sqlServerHandler) CreateStandardPool(cfg config.DatabaseConfig) (*sql.DB, error) {
-	port := cfg.Port
-	if port == 0 {
-		port = 1433
+	connStr := standardConnURL(cfg)
+	dbPool, err := sql.Open("sqlserver", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open (standard sqlserver): %w", err)
 	}
+	return dbPool, nil
+}
 
+// standardConnURL builds the sqlserver:// URL for a direct connection. --driver-params
+// become query parameters (e.g. "app name" or "connection timeout") and override the
+// generated ones.
+func standardConnURL(cfg config.DatabaseConfig) string {
 	query := url.Values{}
-	query.Add("database", cfg.DBName)
+	query.Set("database", cfg.DBName)
+	query.Set("app name", database.ApplicationName)
+	for key, value := range cfg.DriverParams {
+		query.Set(key, value)
+	}
 
 	u := &url.URL{
 		Scheme:   "sqlserver",
 		User:     url.UserPassword(cfg.User, cfg.Password),
-		Host:     fmt.Sprintf("%s:%d", cfg.Host, port),
+		Host:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
 		RawQuery: query.Encode(),
 	}
-
-	connStr := u.String()
-	dbPool, err := sql.Open("sqlserver", connStr)
-	if err != nil {
-		return nil, fmt.Errorf("sql.Open (standard sqlserver): %w", err)
-	}
-	return dbPool, nil
+	return u.String()
 }
 
 func (h sqlServerHandler) QuoteIdentifier(name string) string {
@@ -126,14 +133,59 @@ func (h sqlServerHandler) QuoteIdentifier(name string) string {
 	return fmt.Sprintf("[%s]", name)
 }
 
+// defaultSchema is the schema whose tables are listed without a schema prefix when
+// --schema is not set.
+const defaultSchema = "dbo"
+
+// tableSchema returns the schema of tables named without a schema prefix: --schema,
+// or dbo.
+func tableSchema(db *database.DB) string {
+	if db.Config.Schema != "" {
+		return db.Config.Schema
+	}
+	return defaultSchema
+}
+
+// splitTableName returns the schema and name of a table as listed by ListTables:
+// tables in the tableSchema are listed by name and tables in other schemas as
+// schema.table.
+func splitTableName(db *database.DB, tableName string) (schema, table string) {
+	if schema, table, ok := strings.Cut(tableName, "."); ok {
+		return schema, table
+	}
+	return tableSchema(db), tableName
+}
+
+// qualifiedTableName is the inverse of splitTableName. A table of the tableSchema
+// with a dot in its name keeps the prefix so that it splits back correctly.
+func qualifiedTableName(db *database.DB, schema, table string) string {
+	if schema == tableSchema(db) && !strings.Contains(table, ".") {
+		return table
+	}
+	return schema + "." + table
+}
+
+// ListTables lists the base tables of every schema the login can see, or of --schema
+// only when it is set. Tables outside the tableSchema are returned as schema.table.
 func (h sqlServerHandler) ListTables(db *database.DB) ([]string, error) {
 	query := `
-		  SELECT TABLE_NAME
+		  SELECT TABLE_SCHEMA, TABLE_NAME
+		  FROM INFORMATION_SCHEMA.TABLES
+		  WHERE TABLE_TYPE = 'BASE TABLE' AND TABLE_CATALOG = DB_NAME()
+		  ORDER BY TABLE_SCHEMA, TABLE_NAME;
+		  `
+	var args []interface{}
+	if db.Config.Schema != "" {
+		query = `
+		  SELECT TABLE_SCHEMA, TABLE_NAME
 		  FROM INFORMATION_SCHEMA.TABLES
-		  WHERE TABLE_TYPE = 'BASE TABLE' AND TABLE_CATALOG = DB_NAME() AND TABLE_SCHEMA = 'dbo'
+		  WHERE TABLE_TYPE = 'BASE TABLE' AND TABLE_CATALOG = DB_NAME()
+			AND TABLE_SCHEMA = @p1
 		  ORDER BY TABLE_NAME;
 		  `
-	rows, err := db.Pool.Query(query)
+		args = append(args, sql.Named("p1", db.Config.Schema))
+	}
+	rows, err := db.Pool.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying tables: %w", err)
 	}
@@ -141,11 +193,20 @@ func (h sqlServerHandler) ListTables(db *database.DB) ([]string, error) {
 
 	var tables []string
 	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
+		var schemaName, tableName sql.NullString
+		if err := rows.Scan(&schemaName, &tableName); err != nil {
 			return nil, fmt.Errorf("error scanning table name: %w", err)
 		}
-		tables = append(tables, tableName)
+		if !schemaName.Valid || !tableName.Valid {
+			log.Println("WARN: Skipping a table listed with a NULL schema or name.")
+			continue
+		}
+		// #local and ##global temporary tables are listed when connected to tempdb.
+		if strings.HasPrefix(tableName.String, "#") && !db.Config.IncludeTempTables {
+			log.Printf("INFO: Skipping temporary table %s (use --include-temp-tables to list it).", tableName.String)
+			continue
+		}
+		tables = append(tables, qualifiedTableName(db, schemaName.String, tableName.String))
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating table rows: %w", err)
@@ -154,16 +215,17 @@ func (h sqlServerHandler) ListTables(db *database.DB) ([]string, error) {
 }
 
 func (h sqlServerHandler) ListColumns(db *database.DB, tableName string) ([]database.ColumnInfo, error) {
+	schemaName, name := splitTableName(db, tableName)
 	query := `
 		  SELECT COLUMN_NAME, DATA_TYPE
 		  FROM INFORMATION_SCHEMA.COLUMNS
 		  WHERE TABLE_CATALOG = DB_NAME()
-			AND TABLE_SCHEMA = 'dbo'
+			AND TABLE_SCHEMA = @p2
 			AND TABLE_NAME = @p1
 		  ORDER BY ORDINAL_POSITION;
 		  `
 
-	rows, err := db.Pool.Query(query, sql.Named("p1", tableName))
+	rows, err := db.Pool.Query(query, sql.Named("p1", name), sql.Named("p2", schemaName))
 	if err != nil {
 		return nil, fmt.Errorf("error querying columns for table %s: %w", tableName, err)
 	}
@@ -171,11 +233,15 @@ func (h sqlServerHandler) ListColumns(db *database.DB, tableName string) ([]data
 
 	var columns []database.ColumnInfo
 	for rows.Next() {
-		var colInfo database.ColumnInfo
-		if err := rows.Scan(&colInfo.Name, &colInfo.DataType); err != nil {
+		var name, dataType sql.NullString
+		if err := rows.Scan(&name, &dataType); err != nil {
 			return nil, fmt.Errorf("error scanning column details: %w", err)
 		}
-		columns = append(columns, colInfo)
+		if !name.Valid {
+			log.Printf("WARN: Skipping a column of %s listed with a NULL name.", tableName)
+			continue
+		}
+		columns = append(columns, database.ColumnInfo{Name: name.String, DataType: dataType.String})
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating column rows: %w", err)
@@ -184,19 +250,20 @@ func (h sqlServerHandler) ListColumns(db *database.DB, tableName string) ([]data
 }
 
 func (h sqlServerHandler) GetColumnMetadata(db *database.DB, tableName string, columnName string) (map[string]interface{}, error) {
-	schemaName := "dbo"
+	schemaName, name := splitTableName(db, tableName)
 	quotedSchema := h.QuoteIdentifier(schemaName)
-	quotedTable := h.QuoteIdentifier(tableName)
+	quotedTable := h.QuoteIdentifier(name)
 	quotedColumn := h.QuoteIdentifier(columnName)
 	fullQuotedTable := fmt.Sprintf("%s.%s", quotedSchema, quotedTable)
 
 	ctx := context.Background()
 
 	distinctQuery := fmt.Sprintf("SELECT COUNT_BIG(DISTINCT %s) FROM %s", quotedColumn, fullQuotedTable)
-	var distinctCount int64
-	err := db.Pool.QueryRowContext(ctx, distinctQuery).Scan(&distinctCount)
+	// COLLATE only applies to character columns; others fall back to distinctQuery.
+	binaryDistinctQuery := fmt.Sprintf("SELECT COUNT_BIG(DISTINCT %s COLLATE Latin1_General_BIN2) FROM %s", quotedColumn, fullQuotedTable)
+	distinctCount, err := database.DistinctCount(ctx, db, tableName+"."+columnName, distinctQuery, binaryDistinctQuery)
 	if err != nil {
-		log.Printf("WARN: Failed to get distinct count for %s.%s.%s (type may not support DISTINCT): %v. Reporting -1.", schemaName, tableName, columnName, err)
+		log.Printf("WARN: Failed to get distinct count for %s.%s.%s (type may not support DISTINCT): %v. Reporting -1.", schemaName, name, columnName, err)
 		distinctCount = -1
 	}
 
@@ -204,14 +271,96 @@ func (h sqlServerHandler) GetColumnMetadata(db *database.DB, tableName string, c
 	var nullCount int64
 	err = db.Pool.QueryRowContext(ctx, nullQuery).Scan(&nullCount)
 	if err != nil {
+		if permErr := h.permissionError(db, tableName, err); permErr != nil {
+			return nil, permErr
+		}
 		return nil, fmt.Errorf("failed to get null count for %s.%s: %w", tableName, columnName, err)
 	}
 
-	exampleQuery := fmt.Sprintf("SELECT DISTINCT TOP (@p1) CAST(%s AS NVARCHAR(MAX)) FROM %s WHERE %s IS NOT NULL",
-		quotedColumn, fullQuotedTable, quotedColumn)
-	rows, err := db.Pool.QueryContext(ctx, exampleQuery, sql.Named("p1", 3))
+	examples, err := h.exampleValues(ctx, db, tableName, columnName, distinctCount)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{
+		"DistinctCount": distinctCount,
+		"NullCount":     nullCount,
+		"ExampleValues": examples,
+	}
+	// Only numeric columns are averaged: the FLOAT cast would also convert numeric
+	// strings, and AVG of an integer column would round to an integer without it.
+	if db.Config.ComputeAverage && database.IsNumericType(h.columnDataType(ctx, db, schemaName, name, columnName)) {
+		avgQuery := fmt.Sprintf("SELECT AVG(CAST(%s AS FLOAT)) FROM %s", quotedColumn, fullQuotedTable)
+		if avg := database.ColumnAverage(ctx, db, avgQuery); avg != nil {
+			metadata["Average"] = *avg
+		}
+	}
+	return metadata, nil
+}
+
+// incompatibleTypeErrors are the errors SQL Server raises when a column's values cannot
+// be converted to NVARCHAR or compared, e.g. for geography, image or xml columns.
+var incompatibleTypeErrors = map[int32]bool{
+	306:  true, // The text, ntext, and image data types cannot be compared or sorted.
+	420:  true, // The text, ntext, and image data types cannot be used in an ORDER BY clause.
+	421:  true, // The data type cannot be selected as DISTINCT because it is not comparable.
+	529:  true, // Explicit conversion from data type to nvarchar(max) is not allowed.
+	8116: true, // Argument data type is invalid for argument of function.
+}
+
+// clrTypes are the CLR types whose values convert to text with their ToString() method,
+// rather than with CAST.
+var clrTypes = map[string]bool{"geography": true, "geometry": true, "hierarchyid": true}
+
+func isIncompatibleTypeError(err error) bool {
+	var msErr mssql.Error
+	return errors.As(err, &msErr) && incompatibleTypeErrors[msErr.Number]
+}
+
+// exampleValues samples a column's distinct values as text, or its first rows for a
+// column with more than --max-distinct-for-examples distinct values. Columns whose
+// type cannot be converted to text are skipped with a warning, so that they keep
+// their other statistics.
+func (h sqlServerHandler) exampleValues(ctx context.Context, db *database.DB, tableName, columnName string, distinctCount int64) ([]string, error) {
+	schemaName, name := splitTableName(db, tableName)
+	fullQuotedTable := fmt.Sprintf("%s.%s", h.QuoteIdentifier(schemaName), h.QuoteIdentifier(name))
+	quotedColumn := h.QuoteIdentifier(columnName)
+	asText := fmt.Sprintf("CAST(%s AS NVARCHAR(MAX))", quotedColumn)
+
+	// Ordering by the NVARCHAR cast keeps the sample stable across runs; types that
+	// cannot be ordered fall back to the unordered query.
+	exampleLimit := sql.Named("p1", database.ExampleQueryLimit(db.Config.ExampleSampleSize))
+	selectTop := "SELECT DISTINCT TOP (@p1)"
+	exampleQuery := fmt.Sprintf("%s %s FROM %s WHERE %s IS NOT NULL ORDER BY 1",
+		selectTop, asText, fullQuotedTable, quotedColumn)
+	firstRows := database.SampleWithoutDistinct(db, distinctCount)
+	if firstRows {
+		selectTop = "SELECT TOP (@p1)"
+		exampleQuery = fmt.Sprintf("%s %s FROM %s WHERE %s IS NOT NULL",
+			selectTop, asText, fullQuotedTable, quotedColumn)
+	}
+	rows, err := db.Pool.QueryContext(ctx, exampleQuery, exampleLimit)
+	if err != nil && !firstRows && h.permissionError(db, tableName, err) == nil {
+		log.Printf("WARN: Ordered example query failed for %s.%s.%s: %v. Retrying without ORDER BY.", schemaName, name, columnName, err)
+		exampleQuery = fmt.Sprintf("%s %s FROM %s WHERE %s IS NOT NULL",
+			selectTop, asText, fullQuotedTable, quotedColumn)
+		rows, err = db.Pool.QueryContext(ctx, exampleQuery, exampleLimit)
+	}
+	if err != nil && isIncompatibleTypeError(err) {
+		dataType := h.columnDataType(ctx, db, schemaName, name, columnName)
+		if !clrTypes[dataType] {
+			log.Printf("WARN: Values of %s.%s.%s (type %s) cannot be converted to text: %v. Skipping example values.", schemaName, name, columnName, dataType, err)
+			return nil, nil
+		}
+		exampleQuery = fmt.Sprintf("%s %s.ToString() FROM %s WHERE %s IS NOT NULL",
+			selectTop, quotedColumn, fullQuotedTable, quotedColumn)
+		rows, err = db.Pool.QueryContext(ctx, exampleQuery, exampleLimit)
+	}
 	if err != nil {
 		log.Printf("ERROR executing example query [%s]: %v", exampleQuery, err)
+		if permErr := h.permissionError(db, tableName, err); permErr != nil {
+			return nil, permErr
+		}
 		return nil, fmt.Errorf("failed to get example values for %s.%s: %w", tableName, columnName, err)
 	}
 	defer rows.Close()
@@ -229,12 +378,25 @@ func (h sqlServerHandler) GetColumnMetadata(db *database.DB, tableName string, c
 	if rows.Err() != nil {
 		return nil, fmt.Errorf("error iterating example values for %s.%s: %w", tableName, columnName, rows.Err())
 	}
+	if firstRows {
+		examples = database.UniqueValues(examples)
+	}
+	return database.SelectRepresentativeValues(examples, database.DefaultExampleCount), nil
+}
 
-	return map[string]interface{}{
-		"DistinctCount": distinctCount,
-		"NullCount":     nullCount,
-		"ExampleValues": examples,
-	}, nil
+// columnDataType returns the name of a column's type, or "" if it cannot be read.
+func (h sqlServerHandler) columnDataType(ctx context.Context, db *database.DB, schemaName, tableName, columnName string) string {
+	query := `
+		SELECT DATA_TYPE
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = @p1 AND TABLE_NAME = @p2 AND COLUMN_NAME = @p3;`
+	var dataType string
+	err := db.Pool.QueryRowContext(ctx, query, sql.Named("p1", schemaName), sql.Named("p2", tableName), sql.Named("p3", columnName)).Scan(&dataType)
+	if err != nil {
+		log.Printf("WARN: Failed to read the type of %s.%s.%s: %v", schemaName, tableName, columnName, err)
+		return ""
+	}
+	return strings.ToLower(dataType)
 }
 
 func escapeSQLServerString(value string) string {
@@ -245,42 +407,74 @@ func escapeAndQuoteSQLServerString(value string) string {
 	return fmt.Sprintf("N'%s'", escapeSQLServerString(value))
 }
 
+// formatExampleValues renders the examples as plain comment text. Values are not
+// SQL-escaped here: the whole comment is escaped exactly once by
+// escapeAndQuoteSQLServerString when it is embedded in the statement.
 func (h sqlServerHandler) formatExampleValues(values []string) string {
 	if len(values) == 0 {
 		return ""
 	}
-	escaped := make([]string, len(values))
+	cleaned := make([]string, len(values))
 	for i, v := range values {
 		trimmed := strings.ReplaceAll(v, "\n", " ")
 		if len(trimmed) > 100 {
 			trimmed = trimmed[:100] + "...[truncated]"
 		}
-		escaped[i] = escapeSQLServerString(trimmed)
+		cleaned[i] = trimmed
+	}
+	return fmt.Sprintf("Examples: ['%s']", strings.Join(cleaned, "', '"))
+}
+
+// formatForeignKeys renders referenced columns with this dialect's identifier quoting.
+func (h sqlServerHandler) formatForeignKeys(db *database.DB, foreignKeys []database.ForeignKeyReference) string {
+	// Referenced tables outside the tableSchema are schema.table, so the parts are quoted here.
+	quoted := make([]database.ForeignKeyReference, len(foreignKeys))
+	for i, fk := range foreignKeys {
+		fk.ReferencedTable = h.quoteTableName(db, fk.ReferencedTable)
+		fk.ReferencedColumn = h.QuoteIdentifier(fk.ReferencedColumn)
+		quoted[i] = fk
+	}
+	return database.FormatForeignKeys(quoted, func(name string) string { return name })
+}
+
+// quoteTableName quotes a table name as listed by ListTables, keeping the schema of
+// tables outside the tableSchema.
+func (h sqlServerHandler) quoteTableName(db *database.DB, tableName string) string {
+	schemaName, name := splitTableName(db, tableName)
+	if name == tableName {
+		return h.QuoteIdentifier(name)
 	}
-	return fmt.Sprintf("Example Values: ['%s']", strings.Join(escaped, "', '"))
+	return h.QuoteIdentifier(schemaName) + "." + h.QuoteIdentifier(name)
 }
 
 func (h sqlServerHandler) GenerateCommentSQL(db *database.DB, data *database.CommentData, enrichments map[string]bool) (string, error) {
 	if data == nil || data.TableName == "" || data.ColumnName == "" {
 		return "", fmt.Errorf("invalid input for GenerateCommentSQL")
 	}
-	schemaName := "dbo"
+	schemaName, name := splitTableName(db, data.TableName)
 
 	formattedExamples := h.formatExampleValues(data.ExampleValues)
-	newMetadataComment := database.GenerateMetadataCommentString(data, enrichments, formattedExamples)
+	newMetadataComment := database.GenerateMetadataCommentString(data, enrichments, formattedExamples, h.formatForeignKeys(db, data.ForeignKeys))
 
-	existingComment, _ := h.GetColumnComment(context.Background(), db, data.TableName, data.ColumnName)
+	existingComment, _ := database.ExistingColumnComment(context.Background(), db, h, data.TableName, data.ColumnName)
 
 	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
 
-	propertyExists, checkErr := h.checkExtendedPropertyExists(context.Background(), db, schemaName, data.TableName, data.ColumnName)
+	if db.Offline() {
+		if finalComment == "" {
+			return "", nil
+		}
+		return upsertExtendedPropertySQL(finalComment, schemaName, name, data.ColumnName), nil
+	}
+
+	propertyExists, checkErr := h.checkExtendedPropertyExists(context.Background(), db, schemaName, name, data.ColumnName)
 	if checkErr != nil {
-		return "", fmt.Errorf("failed to check existing property for %s.%s.%s: %w", schemaName, data.TableName, data.ColumnName, checkErr)
+		return "", fmt.Errorf("failed to check existing property for %s.%s.%s: %w", schemaName, name, data.ColumnName, checkErr)
 	}
 
 	var sqlStmt string
 	quotedSchema := escapeAndQuoteSQLServerString(schemaName)
-	quotedTable := escapeAndQuoteSQLServerString(data.TableName)
+	quotedTable := escapeAndQuoteSQLServerString(name)
 	quotedColumn := escapeAndQuoteSQLServerString(data.ColumnName)
 	quotedCommentValue := escapeAndQuoteSQLServerString(finalComment)
 
@@ -307,34 +501,36 @@ func (h sqlServerHandler) GenerateCommentSQL(db *database.DB, data *database.Com
 	return strings.TrimSpace(sqlStmt), nil
 }
 
-func (h sqlServerHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+// GenerateRewriteCommentSQL returns the statement that replaces a column's comment
+// with rewrite(existing), or "" when that leaves the comment unchanged.
+func (h sqlServerHandler) GenerateRewriteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string, rewrite func(string) string) (string, error) {
 	if tableName == "" || columnName == "" {
-		return "", fmt.Errorf("table and column names cannot be empty for GenerateDeleteCommentSQL")
+		return "", fmt.Errorf("table and column names cannot be empty for GenerateRewriteCommentSQL")
 	}
-	schemaName := "dbo"
+	schemaName, name := splitTableName(db, tableName)
 
-	propertyExists, checkErr := h.checkExtendedPropertyExists(ctx, db, schemaName, tableName, columnName)
+	propertyExists, checkErr := h.checkExtendedPropertyExists(ctx, db, schemaName, name, columnName)
 	if checkErr != nil {
-		return "", fmt.Errorf("failed to check existing property for delete %s.%s.%s: %w", schemaName, tableName, columnName, checkErr)
+		return "", fmt.Errorf("failed to check existing property for rewrite %s.%s.%s: %w", schemaName, name, columnName, checkErr)
 	}
 	if !propertyExists {
 		return "", nil
 	}
 
-	existingComment, err := h.GetColumnComment(ctx, db, tableName, columnName)
+	existingComment, err := database.ExistingColumnComment(ctx, db, h, tableName, columnName)
 	if err != nil {
-		log.Printf("WARN: Property MS_Description exists for %s.%s.%s but failed to get value: %v", schemaName, tableName, columnName, err)
+		log.Printf("WARN: Property MS_Description exists for %s.%s.%s but failed to get value: %v", schemaName, name, columnName, err)
 		existingComment = ""
 	}
 
-	finalComment := database.MergeComments(existingComment, "", "")
+	finalComment := rewrite(existingComment)
 
 	if finalComment == strings.TrimSpace(existingComment) {
 		return "", nil
 	}
 
 	quotedSchema := escapeAndQuoteSQLServerString(schemaName)
-	quotedTable := escapeAndQuoteSQLServerString(tableName)
+	quotedTable := escapeAndQuoteSQLServerString(name)
 	quotedColumn := escapeAndQuoteSQLServerString(columnName)
 	quotedCommentValue := escapeAndQuoteSQLServerString(finalComment)
 
@@ -348,8 +544,12 @@ func (h sqlServerHandler) GenerateDeleteCommentSQL(ctx context.Context, db *data
 	return strings.TrimSpace(sqlStmt), nil
 }
 
+func (h sqlServerHandler) GenerateDeleteCommentSQL(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
+	return h.GenerateRewriteCommentSQL(ctx, db, tableName, columnName, database.StripMetadata)
+}
+
 func (h sqlServerHandler) GetColumnComment(ctx context.Context, db *database.DB, tableName string, columnName string) (string, error) {
-	schemaName := "dbo"
+	schemaName, name := splitTableName(db, tableName)
 	query := `
 		  SELECT CAST(p.value AS NVARCHAR(MAX))
 		  FROM sys.extended_properties AS p
@@ -366,7 +566,7 @@ func (h sqlServerHandler) GetColumnComment(ctx context.Context, db *database.DB,
 	var comment sql.NullString
 	err := db.Pool.QueryRowContext(ctx, query,
 		sql.Named("p1", schemaName),
-		sql.Named("p2", tableName),
+		sql.Named("p2", name),
 		sql.Named("p3", columnName),
 	).Scan(&comment)
 
@@ -374,8 +574,8 @@ func (h sqlServerHandler) GetColumnComment(ctx context.Context, db *database.DB,
 		if err == sql.ErrNoRows {
 			return "", nil
 		}
-		log.Printf("ERROR: Failed to retrieve column comment for %s.%s.%s: %v", schemaName, tableName, columnName, err)
-		return "", fmt.Errorf("failed to retrieve column comment for %s.%s.%s: %w", schemaName, tableName, columnName, err)
+		log.Printf("ERROR: Failed to retrieve column comment for %s.%s.%s: %v", schemaName, name, columnName, err)
+		return "", fmt.Errorf("failed to retrieve column comment for %s.%s.%s: %w", schemaName, name, columnName, err)
 	}
 
 	if comment.Valid {
@@ -384,15 +584,56 @@ func (h sqlServerHandler) GetColumnComment(ctx context.Context, db *database.DB,
 	return "", nil
 }
 
+// GetAllColumnComments reads the MS_Description of every column of a table in one query.
+func (h sqlServerHandler) GetAllColumnComments(ctx context.Context, db *database.DB, tableName string) (map[string]string, error) {
+	schemaName, name := splitTableName(db, tableName)
+	query := `
+		  SELECT c.name, CAST(p.value AS NVARCHAR(MAX))
+		  FROM sys.extended_properties AS p
+		  INNER JOIN sys.tables AS t ON p.major_id = t.object_id
+		  INNER JOIN sys.columns AS c ON p.major_id = c.object_id AND p.minor_id = c.column_id
+		  INNER JOIN sys.schemas AS s ON t.schema_id = s.schema_id
+		  WHERE p.class = 1
+			AND p.name = N'MS_Description'
+			AND s.name = @p1
+			AND t.name = @p2;
+	  `
+
+	rows, err := db.Pool.QueryContext(ctx, query,
+		sql.Named("p1", schemaName),
+		sql.Named("p2", name),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve column comments for %s.%s: %w", schemaName, name, err)
+	}
+	defer rows.Close()
+
+	comments := make(map[string]string)
+	for rows.Next() {
+		var column string
+		var comment sql.NullString
+		if err := rows.Scan(&column, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column comment for %s.%s: %w", schemaName, name, err)
+		}
+		if comment.Valid && comment.String != "" {
+			comments[column] = comment.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column comments for %s.%s: %w", schemaName, name, err)
+	}
+	return comments, nil
+}
+
 func (h sqlServerHandler) GenerateTableCommentSQL(db *database.DB, data *database.TableCommentData, enrichments map[string]bool) (string, error) {
 	if data == nil || data.TableName == "" {
 		return "", fmt.Errorf("table comment data cannot be nil or empty")
 	}
-	schemaName := "dbo"
+	schemaName, name := splitTableName(db, data.TableName)
 
 	newMetadataComment := database.GenerateTableMetadataCommentString(data, enrichments)
 
-	existingComment, _ := h.GetTableComment(context.Background(), db, data.TableName)
+	existingComment, _ := database.ExistingTableComment(context.Background(), db, h, data.TableName)
 
 	finalComment := database.MergeComments(existingComment, newMetadataComment, db.Config.UpdateExistingMode)
 
@@ -400,14 +641,18 @@ func (h sqlServerHandler) GenerateTableCommentSQL(db *database.DB, data *databas
 		return "", nil
 	}
 
-	propertyExists, checkErr := h.checkExtendedPropertyExists(context.Background(), db, schemaName, data.TableName, "")
+	if db.Offline() {
+		return upsertExtendedPropertySQL(finalComment, schemaName, name, ""), nil
+	}
+
+	propertyExists, checkErr := h.checkExtendedPropertyExists(context.Background(), db, schemaName, name, "")
 	if checkErr != nil {
-		return "", fmt.Errorf("failed to check existing property for table %s.%s: %w", schemaName, data.TableName, checkErr)
+		return "", fmt.Errorf("failed to check existing property for table %s.%s: %w", schemaName, name, checkErr)
 	}
 
 	var sqlStmt string
 	quotedSchema := escapeAndQuoteSQLServerString(schemaName)
-	quotedTable := escapeAndQuoteSQLServerString(data.TableName)
+	quotedTable := escapeAndQuoteSQLServerString(name)
 	quotedCommentValue := escapeAndQuoteSQLServerString(finalComment)
 
 	if !propertyExists {
@@ -426,7 +671,7 @@ func (h sqlServerHandler) GenerateTableCommentSQL(db *database.DB, data *databas
 }
 
 func (h sqlServerHandler) GetTableComment(ctx context.Context, db *database.DB, tableName string) (string, error) {
-	schemaName := "dbo"
+	schemaName, name := splitTableName(db, tableName)
 	query := `
 		  SELECT CAST(p.value AS NVARCHAR(MAX))
 		  FROM sys.extended_properties AS p
@@ -441,15 +686,15 @@ func (h sqlServerHandler) GetTableComment(ctx context.Context, db *database.DB,
 	var comment sql.NullString
 	err := db.Pool.QueryRowContext(ctx, query,
 		sql.Named("p1", schemaName),
-		sql.Named("p2", tableName),
+		sql.Named("p2", name),
 	).Scan(&comment)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", nil
 		}
-		log.Printf("ERROR: Failed to retrieve table comment for %s.%s: %v", schemaName, tableName, err)
-		return "", fmt.Errorf("failed to retrieve table comment for %s.%s: %w", schemaName, tableName, err)
+		log.Printf("ERROR: Failed to retrieve table comment for %s.%s: %v", schemaName, name, err)
+		return "", fmt.Errorf("failed to retrieve table comment for %s.%s: %w", schemaName, name, err)
 	}
 
 	if comment.Valid {
@@ -458,15 +703,16 @@ func (h sqlServerHandler) GetTableComment(ctx context.Context, db *database.DB,
 	return "", nil
 }
 
-func (h sqlServerHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *database.DB, tableName string) (string, error) {
+// GenerateRewriteTableCommentSQL is GenerateRewriteCommentSQL for the table comment.
+func (h sqlServerHandler) GenerateRewriteTableCommentSQL(ctx context.Context, db *database.DB, tableName string, rewrite func(string) string) (string, error) {
 	if tableName == "" {
-		return "", fmt.Errorf("table name cannot be empty for GenerateDeleteTableCommentSQL")
+		return "", fmt.Errorf("table name cannot be empty for GenerateRewriteTableCommentSQL")
 	}
-	schemaName := "dbo"
+	schemaName, name := splitTableName(db, tableName)
 
-	propertyExists, checkErr := h.checkExtendedPropertyExists(ctx, db, schemaName, tableName, "")
+	propertyExists, checkErr := h.checkExtendedPropertyExists(ctx, db, schemaName, name, "")
 	if checkErr != nil {
-		return "", fmt.Errorf("failed to check existing property for delete table %s.%s: %w", schemaName, tableName, checkErr)
+		return "", fmt.Errorf("failed to check existing property for rewrite table %s.%s: %w", schemaName, name, checkErr)
 	}
 	if !propertyExists {
 		return "", nil
@@ -474,18 +720,18 @@ func (h sqlServerHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db
 
 	existingComment, err := h.GetTableComment(ctx, db, tableName)
 	if err != nil {
-		log.Printf("WARN: Property MS_Description exists for table %s.%s but failed to get value: %v", schemaName, tableName, err)
+		log.Printf("WARN: Property MS_Description exists for table %s.%s but failed to get value: %v", schemaName, name, err)
 		existingComment = ""
 	}
 
-	finalComment := database.MergeComments(existingComment, "", "")
+	finalComment := rewrite(existingComment)
 
 	if finalComment == strings.TrimSpace(existingComment) {
 		return "", nil
 	}
 
 	quotedSchema := escapeAndQuoteSQLServerString(schemaName)
-	quotedTable := escapeAndQuoteSQLServerString(tableName)
+	quotedTable := escapeAndQuoteSQLServerString(name)
 	quotedCommentValue := escapeAndQuoteSQLServerString(finalComment)
 
 	sqlStmt := fmt.Sprintf(
@@ -495,7 +741,33 @@ func (h sqlServerHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db
 	return strings.TrimSpace(sqlStmt), nil
 }
 
+func (h sqlServerHandler) GenerateDeleteTableCommentSQL(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	return h.GenerateRewriteTableCommentSQL(ctx, db, tableName, database.StripMetadata)
+}
+
+// upsertExtendedPropertySQL returns one statement that updates the MS_Description of a
+// table, or of its column if columnName is set, when it exists and adds it otherwise.
+// Offline generation cannot look up which of the two applies.
+func upsertExtendedPropertySQL(comment, schemaName, tableName, columnName string) string {
+	quotedSchema := escapeAndQuoteSQLServerString(schemaName)
+	quotedTable := escapeAndQuoteSQLServerString(tableName)
+	levels := fmt.Sprintf(`@level0type=N'SCHEMA', @level0name=%s, @level1type=N'TABLE', @level1name=%s`, quotedSchema, quotedTable)
+	listArgs := fmt.Sprintf(`N'SCHEMA', %s, N'TABLE', %s, NULL, NULL`, quotedSchema, quotedTable)
+	if columnName != "" {
+		quotedColumn := escapeAndQuoteSQLServerString(columnName)
+		levels += fmt.Sprintf(`, @level2type=N'COLUMN', @level2name=%s`, quotedColumn)
+		listArgs = fmt.Sprintf(`N'SCHEMA', %s, N'TABLE', %s, N'COLUMN', %s`, quotedSchema, quotedTable, quotedColumn)
+	}
+	value := escapeAndQuoteSQLServerString(comment)
+	return fmt.Sprintf(
+		`IF EXISTS (SELECT 1 FROM sys.fn_listextendedproperty(N'MS_Description', %s)) EXEC sp_updateextendedproperty @name=N'MS_Description', @value=%s, %s; ELSE EXEC sp_addextendedproperty @name=N'MS_Description', @value=%s, %s;`,
+		listArgs, value, levels, value, levels)
+}
+
 func (h sqlServerHandler) checkExtendedPropertyExists(ctx context.Context, db *database.DB, schemaName, tableName, columnName string) (bool, error) {
+	if db.Offline() {
+		return false, nil // There is nothing to rewrite offline.
+	}
 	var query string
 	params := []interface{}{sql.Named("p1", schemaName), sql.Named("p2", tableName)}
 
@@ -537,9 +809,42 @@ func (h sqlServerHandler) checkExtendedPropertyExists(ctx context.Context, db *d
 	}
 	return true, nil
 }
+
+// TableVersion implements database.TableVersioner with the table's modify_date
+// (schema changes), its row count and the last update recorded in
+// sys.dm_db_index_usage_stats. That last update is forgotten when the server
+// restarts, so no version is reported until the table is written to again.
+func (h sqlServerHandler) TableVersion(ctx context.Context, db *database.DB, tableName string) (string, error) {
+	schemaName, name := splitTableName(db, tableName)
+	query := `
+		SELECT CONVERT(VARCHAR(33), t.modify_date, 126),
+			(SELECT SUM(p.rows) FROM sys.partitions AS p WHERE p.object_id = t.object_id AND p.index_id IN (0, 1)),
+			(SELECT CONVERT(VARCHAR(33), MAX(u.last_user_update), 126) FROM sys.dm_db_index_usage_stats AS u
+				WHERE u.database_id = DB_ID() AND u.object_id = t.object_id)
+		FROM sys.tables AS t
+		INNER JOIN sys.schemas AS s ON t.schema_id = s.schema_id
+		WHERE s.name = @p1 AND t.name = @p2;`
+
+	var modified, lastUpdate sql.NullString
+	var rowCount sql.NullInt64
+	err := db.Pool.QueryRowContext(ctx, query, sql.Named("p1", schemaName), sql.Named("p2", name)).Scan(&modified, &rowCount, &lastUpdate)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read change information of table %s.%s: %w", schemaName, name, err)
+	}
+	if !lastUpdate.Valid {
+		return "", nil
+	}
+	return fmt.Sprintf("%s:%d:%s", modified.String, rowCount.Int64, lastUpdate.String), nil
+}
+
 func (h sqlServerHandler) GetForeignKeys(db *database.DB, tableName string, columnName string) ([]database.ForeignKeyReference, error) {
+	schemaName, name := splitTableName(db, tableName)
 	query := `
 		SELECT
+			rs.name as referenced_schema,
 			rt.name as referenced_table,
 			rc.name as referenced_column,
 			fk.name as constraint_name
@@ -550,11 +855,12 @@ func (h sqlServerHandler) GetForeignKeys(db *database.DB, tableName string, colu
 		INNER JOIN sys.tables rt ON fkc.referenced_object_id = rt.object_id
 		INNER JOIN sys.columns rc ON fkc.referenced_object_id = rc.object_id AND fkc.referenced_column_id = rc.column_id
 		INNER JOIN sys.schemas s ON t.schema_id = s.schema_id
-		WHERE s.name = 'dbo'
+		INNER JOIN sys.schemas rs ON rt.schema_id = rs.schema_id
+		WHERE s.name = @p3
 			AND t.name = @p1
 			AND c.name = @p2`
 
-	rows, err := db.Pool.Query(query, sql.Named("p1", tableName), sql.Named("p2", columnName))
+	rows, err := db.Pool.Query(query, sql.Named("p1", name), sql.Named("p2", columnName), sql.Named("p3", schemaName))
 	if err != nil {
 		return nil, fmt.Errorf("error querying foreign keys for %s.%s: %w", tableName, columnName, err)
 	}
@@ -563,9 +869,11 @@ func (h sqlServerHandler) GetForeignKeys(db *database.DB, tableName string, colu
 	var foreignKeys []database.ForeignKeyReference
 	for rows.Next() {
 		var fk database.ForeignKeyReference
-		if err := rows.Scan(&fk.ReferencedTable, &fk.ReferencedColumn, &fk.ConstraintName); err != nil {
+		var referencedSchema string
+		if err := rows.Scan(&referencedSchema, &fk.ReferencedTable, &fk.ReferencedColumn, &fk.ConstraintName); err != nil {
 			return nil, fmt.Errorf("error scanning foreign key data for %s.%s: %w", tableName, columnName, err)
 		}
+		fk.ReferencedTable = qualifiedTableName(db, referencedSchema, fk.ReferencedTable)
 		foreignKeys = append(foreignKeys, fk)
 	}
 
@@ -576,6 +884,31 @@ func (h sqlServerHandler) GetForeignKeys(db *database.DB, tableName string, colu
 	return foreignKeys, nil
 }
 
+// ForeignKeyMatchQuery implements database.ForeignKeyMatcher. Constraints created or
+// re-enabled WITH NOCHECK may not match.
+func (h sqlServerHandler) ForeignKeyMatchQuery(ctx context.Context, db *database.DB, tableName string, columnName string, fk database.ForeignKeyReference) string {
+	fullQuotedTable := func(tableName string) string {
+		schemaName, name := splitTableName(db, tableName)
+		return fmt.Sprintf("%s.%s", h.QuoteIdentifier(schemaName), h.QuoteIdentifier(name))
+	}
+	return database.ForeignKeyMatchSQL(fullQuotedTable(tableName), h.QuoteIdentifier(columnName), fullQuotedTable(fk.ReferencedTable), h.QuoteIdentifier(fk.ReferencedColumn))
+}
+
+// permissionError returns a database.PermissionError when err is SQL Server's
+// SELECT permission denied error (229), and nil otherwise.
+func (h sqlServerHandler) permissionError(db *database.DB, tableName string, err error) error {
+	var msErr mssql.Error
+	if errors.As(err, &msErr) && msErr.Number == 229 {
+		schemaName, name := splitTableName(db, tableName)
+		return &database.PermissionError{
+			Table: tableName,
+			Grant: fmt.Sprintf("GRANT SELECT ON %s.%s TO %s;", h.QuoteIdentifier(schemaName), h.QuoteIdentifier(name), h.QuoteIdentifier(db.Config.User)),
+			Err:   err,
+		}
+	}
+	return nil
+}
+
 func init() {
 	database.RegisterDialectHandler("sqlserver", sqlServerHandler{})
 	database.RegisterDialectHandler("cloudsqlsqlserver", sqlServerHandler{})
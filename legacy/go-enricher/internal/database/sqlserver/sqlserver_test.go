@@ -1,12 +1,21 @@
 package sqlserver
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"errors"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	mssql "github.com/denisenkom/go-mssqldb"
 )
 
 func TestSQLServerGetForeignKeys(t *testing.T) {
@@ -31,9 +40,9 @@ func TestSQLServerGetForeignKeys(t *testing.T) {
 			},
 			expectedError: "",
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"referenced_table", "referenced_column", "constraint_name"}).
-					AddRow("customers", "id", "FK_orders_customer_id")
-				mock.ExpectQuery(`SELECT\s+rt\.name as referenced_table,\s+rc\.name as referenced_column,\s+fk\.name as constraint_name\s+FROM sys\.foreign_keys fk`).WithArgs(sql.Named("p1", "orders"), sql.Named("p2", "customer_id")).WillReturnRows(rows)
+				rows := sqlmock.NewRows([]string{"referenced_schema", "referenced_table", "referenced_column", "constraint_name"}).
+					AddRow("dbo", "customers", "id", "FK_orders_customer_id")
+				mock.ExpectQuery(`SELECT\s+rs\.name as referenced_schema,\s+rt\.name as referenced_table,\s+rc\.name as referenced_column,\s+fk\.name as constraint_name\s+FROM sys\.foreign_keys fk`).WithArgs(sql.Named("p1", "orders"), sql.Named("p2", "customer_id"), sql.Named("p3", "dbo")).WillReturnRows(rows)
 			},
 		},
 		{
@@ -43,8 +52,8 @@ func TestSQLServerGetForeignKeys(t *testing.T) {
 			expectedFKs:   []database.ForeignKeyReference{},
 			expectedError: "",
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"referenced_table", "referenced_column", "constraint_name"})
-				mock.ExpectQuery(`SELECT\s+rt\.name as referenced_table,\s+rc\.name as referenced_column,\s+fk\.name as constraint_name\s+FROM sys\.foreign_keys fk`).WithArgs(sql.Named("p1", "standalone_table"), sql.Named("p2", "id")).WillReturnRows(rows)
+				rows := sqlmock.NewRows([]string{"referenced_schema", "referenced_table", "referenced_column", "constraint_name"})
+				mock.ExpectQuery(`SELECT\s+rs\.name as referenced_schema,\s+rt\.name as referenced_table,\s+rc\.name as referenced_column,\s+fk\.name as constraint_name\s+FROM sys\.foreign_keys fk`).WithArgs(sql.Named("p1", "standalone_table"), sql.Named("p2", "id"), sql.Named("p3", "dbo")).WillReturnRows(rows)
 			},
 		},
 		{
@@ -54,7 +63,7 @@ func TestSQLServerGetForeignKeys(t *testing.T) {
 			expectedFKs:   nil,
 			expectedError: "error querying foreign keys for test_table.test_column",
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT\s+rt\.name as referenced_table,\s+rc\.name as referenced_column,\s+fk\.name as constraint_name\s+FROM sys\.foreign_keys fk`).WithArgs(sql.Named("p1", "test_table"), sql.Named("p2", "test_column")).WillReturnError(errors.New("database connection failed"))
+				mock.ExpectQuery(`SELECT\s+rs\.name as referenced_schema,\s+rt\.name as referenced_table,\s+rc\.name as referenced_column,\s+fk\.name as constraint_name\s+FROM sys\.foreign_keys fk`).WithArgs(sql.Named("p1", "test_table"), sql.Named("p2", "test_column"), sql.Named("p3", "dbo")).WillReturnError(errors.New("database connection failed"))
 			},
 		},
 		{
@@ -64,9 +73,9 @@ func TestSQLServerGetForeignKeys(t *testing.T) {
 			expectedFKs:   nil,
 			expectedError: "error scanning foreign key data for test_table.test_column",
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"referenced_table", "referenced_column", "constraint_name"}).
-					AddRow(nil, "id", "FK_test") // nil value will cause scan error
-				mock.ExpectQuery(`SELECT\s+rt\.name as referenced_table,\s+rc\.name as referenced_column,\s+fk\.name as constraint_name\s+FROM sys\.foreign_keys fk`).WithArgs(sql.Named("p1", "test_table"), sql.Named("p2", "test_column")).WillReturnRows(rows)
+				rows := sqlmock.NewRows([]string{"referenced_schema", "referenced_table", "referenced_column", "constraint_name"}).
+					AddRow("dbo", nil, "id", "FK_test") // nil value will cause scan error
+				mock.ExpectQuery(`SELECT\s+rs\.name as referenced_schema,\s+rt\.name as referenced_table,\s+rc\.name as referenced_column,\s+fk\.name as constraint_name\s+FROM sys\.foreign_keys fk`).WithArgs(sql.Named("p1", "test_table"), sql.Named("p2", "test_column"), sql.Named("p3", "dbo")).WillReturnRows(rows)
 			},
 		},
 	}
@@ -125,3 +134,924 @@ func TestSQLServerGetForeignKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLServerGetColumnMetadataPermissionDenied(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{User: "enricher"}}
+	handler := sqlServerHandler{}
+
+	permErr := mssql.Error{Number: 229, Message: "The SELECT permission was denied on the object 'payroll'"}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [salary]) FROM [dbo].[payroll]")).WillReturnError(permErr)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [dbo].[payroll] WHERE [salary] IS NULL")).WillReturnError(permErr)
+
+	_, err = handler.GetColumnMetadata(db, "payroll", "salary")
+	var pe *database.PermissionError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected *database.PermissionError, got: %v", err)
+	}
+	wantGrant := "GRANT SELECT ON [dbo].[payroll] TO [enricher];"
+	if pe.Grant != wantGrant {
+		t.Errorf("Expected grant %q, got %q", wantGrant, pe.Grant)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerStandardConnURLDriverParams(t *testing.T) {
+	connStr := standardConnURL(config.DatabaseConfig{Host: "db.example.com", Port: 1433, User: "u", Password: "p@ss", DBName: "sales",
+		DriverParams: map[string]string{"app name": "db-enricher", "connection timeout": "30"}})
+
+	u, err := url.Parse(connStr)
+	if err != nil {
+		t.Fatalf("Failed to parse connection URL %q: %v", connStr, err)
+	}
+	if u.Host != "db.example.com:1433" {
+		t.Errorf("Expected host db.example.com:1433, got %s", u.Host)
+	}
+	if password, _ := u.User.Password(); password != "p@ss" {
+		t.Errorf("Expected password p@ss, got %s", password)
+	}
+	query := u.Query()
+	for key, want := range map[string]string{"database": "sales", "app name": "db-enricher", "connection timeout": "30"} {
+		if got := query.Get(key); got != want {
+			t.Errorf("Expected %s=%s, got %q in %q", key, want, got, connStr)
+		}
+	}
+}
+
+func TestSQLServerStandardConnURLAppName(t *testing.T) {
+	u, err := url.Parse(standardConnURL(config.DatabaseConfig{Host: "localhost", Port: 1433, User: "u", Password: "p", DBName: "sales"}))
+	if err != nil {
+		t.Fatalf("Failed to parse connection URL: %v", err)
+	}
+	if got := u.Query().Get("app name"); got != "db_schema_enricher" {
+		t.Errorf("Expected app name db_schema_enricher, got %q", got)
+	}
+}
+
+func TestEscapeAndQuoteSQLServerString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"Plain", "abc", "N'abc'"},
+		{"Single quote", "O'Brien", "N'O''Brien'"},
+		{"Unicode prefix lookalike", "N'abc'", "N'N''abc'''"},
+		{"Brackets", "[dbo].[x]", "N'[dbo].[x]'"},
+		{"Empty", "", "N''"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeAndQuoteSQLServerString(tt.value); got != tt.want {
+				t.Errorf("escapeAndQuoteSQLServerString(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLServerFormatExampleValues(t *testing.T) {
+	handler := sqlServerHandler{}
+
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{"No values", []string{}, ""},
+		{"Quotes are left for the final escape", []string{"O'Brien"}, "Examples: ['O'Brien']"},
+		{"Mixed", []string{"N'x", "[a]", "line\nbreak"}, "Examples: ['N'x', '[a]', 'line break']"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := handler.formatExampleValues(tt.values); got != tt.want {
+				t.Errorf("formatExampleValues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLServerGenerateCommentSQLEscapesOnce(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{UpdateExistingMode: "overwrite"}}
+	handler := sqlServerHandler{}
+
+	mock.ExpectQuery(`SELECT CAST\(p\.value AS NVARCHAR\(MAX\)\)`).WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT 1\s+FROM sys\.extended_properties`).WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+
+	data := &database.CommentData{
+		TableName:     "customers",
+		ColumnName:    "last_name",
+		ExampleValues: []string{"O'Brien", "N'x", "[a]"},
+	}
+	got, err := handler.GenerateCommentSQL(db, data, map[string]bool{"examples": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+
+	want := `EXEC sp_addextendedproperty @name=N'MS_Description', @value=N'<gemini>Examples: [''O''Brien'', ''N''x'', ''[a]'']</gemini>', @level0type=N'SCHEMA', @level0name=N'dbo', @level1type=N'TABLE', @level1name=N'customers', @level2type=N'COLUMN', @level2name=N'last_name';`
+	if got != want {
+		t.Errorf("GenerateCommentSQL() mismatch:\ngot:  %s\nwant: %s", got, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerFormatForeignKeys(t *testing.T) {
+	handler := sqlServerHandler{}
+	got := handler.formatForeignKeys(&database.DB{}, []database.ForeignKeyReference{{ReferencedTable: "users", ReferencedColumn: "id"}})
+	want := "Foreign Keys: [[users].[id]]"
+	if got != want {
+		t.Errorf("formatForeignKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestSQLServerForeignKeyMatchQuery(t *testing.T) {
+	handler := sqlServerHandler{}
+	got := handler.ForeignKeyMatchQuery(context.Background(), &database.DB{}, "orders", "customer_id", database.ForeignKeyReference{ReferencedTable: "crm.customers", ReferencedColumn: "id"})
+	want := "SELECT COUNT(*), COUNT(r.referenced_value) FROM [dbo].[orders] c LEFT JOIN (SELECT DISTINCT [id] AS referenced_value FROM [crm].[customers]) r ON c.[customer_id] = r.referenced_value WHERE c.[customer_id] IS NOT NULL"
+	if got != want {
+		t.Errorf("ForeignKeyMatchQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestSQLServerGetColumnMetadataOrdersExamples(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB}
+	handler := sqlServerHandler{}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [city]) FROM [dbo].[stores]")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(3)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [dbo].[stores] WHERE [city] IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT TOP (@p1) CAST([city] AS NVARCHAR(MAX)) FROM [dbo].[stores] WHERE [city] IS NOT NULL ORDER BY 1")).
+		WithArgs(sql.Named("p1", 3)).
+		WillReturnRows(sqlmock.NewRows([]string{"city"}).AddRow("Austin").AddRow("Boston").AddRow("Chicago"))
+
+	metadata, err := handler.GetColumnMetadata(db, "stores", "city")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	ev, ok := metadata["ExampleValues"].([]string)
+	if !ok || len(ev) != 3 || ev[0] != "Austin" || ev[1] != "Boston" || ev[2] != "Chicago" {
+		t.Errorf("Expected sorted ExampleValues [Austin Boston Chicago], got %v", metadata["ExampleValues"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerGetColumnMetadataHighCardinality(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{MaxDistinctForExamples: 1000}}
+	handler := sqlServerHandler{}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [session_id]) FROM [dbo].[events]")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2000000)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [dbo].[events] WHERE [session_id] IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery("^" + regexp.QuoteMeta("SELECT TOP (@p1) CAST([session_id] AS NVARCHAR(MAX)) FROM [dbo].[events] WHERE [session_id] IS NOT NULL") + "$").
+		WithArgs(sql.Named("p1", 3)).
+		WillReturnRows(sqlmock.NewRows([]string{"session_id"}).AddRow("s-9").AddRow("s-4").AddRow("s-1"))
+
+	metadata, err := handler.GetColumnMetadata(db, "events", "session_id")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	ev, ok := metadata["ExampleValues"].([]string)
+	if !ok || len(ev) != 3 {
+		t.Errorf("Expected 3 ExampleValues, got %v", metadata["ExampleValues"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerGetColumnMetadataUnorderableFallback(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB}
+	handler := sqlServerHandler{}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [shape]) FROM [dbo].[parcels]")).WillReturnError(errors.New("type not comparable"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [dbo].[parcels] WHERE [shape] IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta("IS NOT NULL ORDER BY 1")).WillReturnError(errors.New("The type cannot be used in ORDER BY"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT TOP (@p1) CAST([shape] AS NVARCHAR(MAX)) FROM [dbo].[parcels] WHERE [shape] IS NOT NULL")).
+		WillReturnRows(sqlmock.NewRows([]string{"shape"}).AddRow("POINT (1 2)"))
+
+	metadata, err := handler.GetColumnMetadata(db, "parcels", "shape")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if ev, ok := metadata["ExampleValues"].([]string); !ok || len(ev) != 1 || ev[0] != "POINT (1 2)" {
+		t.Errorf("Expected ExampleValues [POINT (1 2)], got %v", metadata["ExampleValues"])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerGetColumnMetadataIncompatibleTypes(t *testing.T) {
+	conversionErr := mssql.Error{Number: 529, Message: "Explicit conversion from data type image to nvarchar(max) is not allowed."}
+
+	t.Run("image column is skipped", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Failed to create mock database: %v", err)
+		}
+		defer mockDB.Close()
+		db := &database.DB{Pool: mockDB}
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [scan]) FROM [dbo].[documents]")).WillReturnError(mssql.Error{Number: 421, Message: "The image data type cannot be selected as DISTINCT because it is not comparable."})
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [dbo].[documents] WHERE [scan] IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(4)))
+		mock.ExpectQuery(regexp.QuoteMeta("IS NOT NULL ORDER BY 1")).WillReturnError(conversionErr)
+		mock.ExpectQuery(regexp.QuoteMeta("[scan] IS NOT NULL")).WillReturnError(conversionErr)
+		mock.ExpectQuery("FROM INFORMATION_SCHEMA.COLUMNS").
+			WithArgs(sql.Named("p1", "dbo"), sql.Named("p2", "documents"), sql.Named("p3", "scan")).
+			WillReturnRows(sqlmock.NewRows([]string{"DATA_TYPE"}).AddRow("image"))
+
+		metadata, err := sqlServerHandler{}.GetColumnMetadata(db, "documents", "scan")
+		if err != nil {
+			t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+		}
+		if ev := metadata["ExampleValues"].([]string); len(ev) != 0 {
+			t.Errorf("Expected no ExampleValues, got %v", ev)
+		}
+		if metadata["NullCount"] != int64(4) || metadata["DistinctCount"] != int64(-1) {
+			t.Errorf("Expected NullCount 4 and DistinctCount -1, got %v and %v", metadata["NullCount"], metadata["DistinctCount"])
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("geography column is sampled as text", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Failed to create mock database: %v", err)
+		}
+		defer mockDB.Close()
+		db := &database.DB{Pool: mockDB}
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [location]) FROM [sales].[stores]")).WillReturnError(errors.New("not comparable"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [sales].[stores] WHERE [location] IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		mock.ExpectQuery(regexp.QuoteMeta("IS NOT NULL ORDER BY 1")).WillReturnError(conversionErr)
+		mock.ExpectQuery(regexp.QuoteMeta("[location] IS NOT NULL")).WillReturnError(conversionErr)
+		mock.ExpectQuery("FROM INFORMATION_SCHEMA.COLUMNS").WillReturnRows(sqlmock.NewRows([]string{"DATA_TYPE"}).AddRow("geography"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT TOP (@p1) [location].ToString() FROM [sales].[stores] WHERE [location] IS NOT NULL")).
+			WillReturnRows(sqlmock.NewRows([]string{"location"}).AddRow("POINT (-97.74 30.27)"))
+
+		metadata, err := sqlServerHandler{}.GetColumnMetadata(db, "sales.stores", "location")
+		if err != nil {
+			t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+		}
+		if ev, ok := metadata["ExampleValues"].([]string); !ok || len(ev) != 1 || ev[0] != "POINT (-97.74 30.27)" {
+			t.Errorf("Expected ExampleValues [POINT (-97.74 30.27)], got %v", metadata["ExampleValues"])
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestSQLServerGetColumnMetadataBinaryDistinctCollation(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		column    string
+		binaryErr error
+		want      int64
+	}{
+		{name: "character column", column: "code", want: 4},
+		// COLLATE is rejected for other types, which are counted as usual.
+		{name: "integer column", column: "qty", binaryErr: mssql.Error{Number: 447, Message: "Expression type int is invalid for COLLATE clause."}, want: 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Failed to create mock database: %v", err)
+			}
+			defer mockDB.Close()
+
+			db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{DistinctCollation: database.DistinctCollationBinary}}
+			handler := sqlServerHandler{}
+
+			binaryQuery := mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [" + tt.column + "] COLLATE Latin1_General_BIN2) FROM [dbo].[products]"))
+			if tt.binaryErr != nil {
+				binaryQuery.WillReturnError(tt.binaryErr)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [" + tt.column + "]) FROM [dbo].[products]")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.want))
+			} else {
+				binaryQuery.WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.want))
+			}
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [dbo].[products] WHERE [" + tt.column + "] IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+			mock.ExpectQuery(regexp.QuoteMeta("IS NOT NULL ORDER BY 1")).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("1"))
+
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			metadata, err := handler.GetColumnMetadata(db, "products", tt.column)
+			if err != nil {
+				t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+			}
+			if metadata["DistinctCount"] != tt.want {
+				t.Errorf("Expected DistinctCount %d, got %v", tt.want, metadata["DistinctCount"])
+			}
+			warned := strings.Contains(logs.String(), "WARN: Column[products."+tt.column+"] Binary distinct count failed")
+			if warned != (tt.binaryErr != nil) {
+				t.Errorf("fallback warning logged = %v, want %v; logs:\n%s", warned, tt.binaryErr != nil, logs.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+// TestSQLServerAverage checks that only numeric columns are averaged, as FLOAT so an
+// integer column's average is not rounded, and that the average is rendered.
+func TestSQLServerAverage(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{UpdateExistingMode: "overwrite", ComputeAverage: true}}
+	handler := sqlServerHandler{}
+
+	expectStatistics := func(column, dataType string) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [" + column + "]) FROM [dbo].[orders]")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [dbo].[orders] WHERE [" + column + "] IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		mock.ExpectQuery(regexp.QuoteMeta("IS NOT NULL ORDER BY 1")).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("10"))
+		mock.ExpectQuery(`SELECT DATA_TYPE\s+FROM INFORMATION_SCHEMA.COLUMNS`).
+			WithArgs(sql.Named("p1", "dbo"), sql.Named("p2", "orders"), sql.Named("p3", column)).
+			WillReturnRows(sqlmock.NewRows([]string{"DATA_TYPE"}).AddRow(dataType))
+	}
+
+	expectStatistics("quantity", "int")
+	mock.ExpectQuery("^" + regexp.QuoteMeta("SELECT AVG(CAST([quantity] AS FLOAT)) FROM [dbo].[orders]") + "$").WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(12.3456))
+	metadata, err := handler.GetColumnMetadata(db, "orders", "quantity")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	avg, ok := metadata["Average"].(float64)
+	if !ok || avg != 12.3456 {
+		t.Fatalf("GetColumnMetadata() Average = %v, want 12.3456", metadata["Average"])
+	}
+
+	mock.ExpectQuery(`SELECT CAST\(p\.value AS NVARCHAR\(MAX\)\)`).WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT 1\s+FROM sys\.extended_properties`).WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+	got, err := handler.GenerateCommentSQL(db, &database.CommentData{TableName: "orders", ColumnName: "quantity", Average: &avg}, map[string]bool{"average": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	want := `EXEC sp_addextendedproperty @name=N'MS_Description', @value=N'<gemini>Avg: 12.35</gemini>', @level0type=N'SCHEMA', @level0name=N'dbo', @level1type=N'TABLE', @level1name=N'orders', @level2type=N'COLUMN', @level2name=N'quantity';`
+	if got != want {
+		t.Errorf("GenerateCommentSQL() mismatch:\ngot:  %s\nwant: %s", got, want)
+	}
+
+	// A varchar column gets no AVG query, even if its values would convert to FLOAT.
+	expectStatistics("status", "varchar")
+	metadata, err = handler.GetColumnMetadata(db, "orders", "status")
+	if err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+	if _, ok := metadata["Average"]; ok {
+		t.Errorf("GetColumnMetadata() Average = %v for a varchar column, want none", metadata["Average"])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerGetAllColumnComments(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Handler: sqlServerHandler{}}
+
+	mock.ExpectQuery(`SELECT c\.name, CAST\(p\.value AS NVARCHAR\(MAX\)\)`).
+		WithArgs(sql.Named("p1", "dbo"), sql.Named("p2", "stores")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "value"}).AddRow("city", "Store city <gemini>Examples: 'Austin'</gemini>"))
+
+	comments, err := db.GetAllColumnComments(context.Background(), "stores")
+	if err != nil {
+		t.Fatalf("GetAllColumnComments() unexpected error: %v", err)
+	}
+	if len(comments) != 1 || comments["city"] != "Store city <gemini>Examples: 'Austin'</gemini>" {
+		t.Errorf("GetAllColumnComments() = %v", comments)
+	}
+
+	// Delete generation reads the existing comment from the cache; only the
+	// property existence check still queries.
+	mock.ExpectQuery(`SELECT 1\s+FROM sys\.extended_properties`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(1))
+	sqlStmt, err := db.GenerateDeleteCommentSQL(context.Background(), "stores", "city")
+	if err != nil {
+		t.Fatalf("GenerateDeleteCommentSQL() unexpected error: %v", err)
+	}
+	if sqlStmt == "" {
+		t.Errorf("GenerateDeleteCommentSQL() returned no statement for a column with gemini metadata")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// TestSQLServerSpecialIdentifiersEndToEnd follows reserved-word and bracketed column
+// names from ListColumns through the metadata queries to the generated comment.
+func TestSQLServerSpecialIdentifiersEndToEnd(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{UpdateExistingMode: "overwrite"}}
+	handler := sqlServerHandler{}
+	enrichments := map[string]bool{"examples": true, "distinct_values": true}
+
+	mock.ExpectQuery(`SELECT COLUMN_NAME, DATA_TYPE\s+FROM INFORMATION_SCHEMA.COLUMNS`).WithArgs(sql.Named("p1", "order"), sql.Named("p2", "dbo")).
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "DATA_TYPE"}).AddRow("select", "nvarchar").AddRow("my [odd] col's", "int"))
+
+	columns, err := handler.ListColumns(db, "order")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		quoted string
+		want   string
+	}{
+		{"[select]", `EXEC sp_addextendedproperty @name=N'MS_Description', @value=N'<gemini>Examples: [''a''] | Distinct: 1</gemini>', @level0type=N'SCHEMA', @level0name=N'dbo', @level1type=N'TABLE', @level1name=N'order', @level2type=N'COLUMN', @level2name=N'select';`},
+		{"[my [odd]] col's]", `EXEC sp_addextendedproperty @name=N'MS_Description', @value=N'<gemini>Examples: [''a''] | Distinct: 1</gemini>', @level0type=N'SCHEMA', @level0name=N'dbo', @level1type=N'TABLE', @level1name=N'order', @level2type=N'COLUMN', @level2name=N'my [odd] col''s';`},
+	}
+	if len(columns) != len(tests) {
+		t.Fatalf("ListColumns() returned %d columns, want %d", len(columns), len(tests))
+	}
+	for i, tt := range tests {
+		col := columns[i]
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT " + tt.quoted + ") FROM [dbo].[order]")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [dbo].[order] WHERE " + tt.quoted + " IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT TOP (@p1) CAST(" + tt.quoted + " AS NVARCHAR(MAX)) FROM [dbo].[order] WHERE " + tt.quoted + " IS NOT NULL ORDER BY 1")).
+			WithArgs(sql.Named("p1", 3)).
+			WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("a"))
+		metadata, err := handler.GetColumnMetadata(db, "order", col.Name)
+		if err != nil {
+			t.Fatalf("GetColumnMetadata(%q) unexpected error: %v", col.Name, err)
+		}
+
+		mock.ExpectQuery(`SELECT CAST\(p\.value AS NVARCHAR\(MAX\)\)`).
+			WithArgs(sql.Named("p1", "dbo"), sql.Named("p2", "order"), sql.Named("p3", col.Name)).
+			WillReturnRows(sqlmock.NewRows([]string{"value"}))
+		mock.ExpectQuery(`SELECT 1\s+FROM sys\.extended_properties`).WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+		got, err := handler.GenerateCommentSQL(db, &database.CommentData{
+			TableName:     "order",
+			ColumnName:    col.Name,
+			ExampleValues: metadata["ExampleValues"].([]string),
+			DistinctCount: metadata["DistinctCount"].(int64),
+		}, enrichments)
+		if err != nil {
+			t.Fatalf("GenerateCommentSQL(%q) unexpected error: %v", col.Name, err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateCommentSQL(%q) =\n%s\nwant\n%s", col.Name, got, tt.want)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerListTablesQualifiesOtherSchemas(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB}
+	handler := sqlServerHandler{}
+
+	mock.ExpectQuery(`SELECT TABLE_SCHEMA, TABLE_NAME\s+FROM INFORMATION_SCHEMA.TABLES`).
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_SCHEMA", "TABLE_NAME"}).
+			AddRow("dbo", "orders").
+			AddRow("dbo", "v1.archive").
+			AddRow("sales", "customers"))
+
+	tables, err := handler.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	want := []string{"orders", "dbo.v1.archive", "sales.customers"}
+	if len(tables) != len(want) {
+		t.Fatalf("ListTables() = %v, want %v", tables, want)
+	}
+	for i, table := range tables {
+		if table != want[i] {
+			t.Errorf("ListTables()[%d] = %q, want %q", i, table, want[i])
+		}
+	}
+
+	for _, tt := range []struct{ listed, schema, table string }{
+		{"orders", "dbo", "orders"},
+		{"dbo.v1.archive", "dbo", "v1.archive"},
+		{"sales.customers", "sales", "customers"},
+	} {
+		schema, table := splitTableName(db, tt.listed)
+		if schema != tt.schema || table != tt.table {
+			t.Errorf("splitTableName(%q) = (%q, %q), want (%q, %q)", tt.listed, schema, table, tt.schema, tt.table)
+		}
+		if got := qualifiedTableName(db, schema, table); got != tt.listed {
+			t.Errorf("qualifiedTableName(%q, %q) = %q, want %q", schema, table, got, tt.listed)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerListSkipsNullNames(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB}
+	handler := sqlServerHandler{}
+
+	mock.ExpectQuery(`SELECT TABLE_SCHEMA, TABLE_NAME\s+FROM INFORMATION_SCHEMA.TABLES`).
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_SCHEMA", "TABLE_NAME"}).
+			AddRow("dbo", "orders").
+			AddRow(nil, "ghost").
+			AddRow("sales", nil).
+			AddRow("sales", "customers"))
+	tables, err := handler.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	if len(tables) != 2 || tables[0] != "orders" || tables[1] != "sales.customers" {
+		t.Errorf("ListTables() = %v, want [orders sales.customers]", tables)
+	}
+
+	mock.ExpectQuery(`SELECT COLUMN_NAME, DATA_TYPE\s+FROM INFORMATION_SCHEMA.COLUMNS`).
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "DATA_TYPE"}).
+			AddRow("id", "int").
+			AddRow(nil, "nvarchar").
+			AddRow("note", nil))
+	columns, err := handler.ListColumns(db, "orders")
+	if err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+	want := []database.ColumnInfo{{Name: "id", DataType: "int"}, {Name: "note"}}
+	if len(columns) != len(want) || columns[0] != want[0] || columns[1] != want[1] {
+		t.Errorf("ListColumns() = %v, want %v", columns, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerListTablesSkipsTempTables(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB}
+	handler := sqlServerHandler{}
+	tableRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"TABLE_SCHEMA", "TABLE_NAME"}).
+			AddRow("dbo", "#scratch_____________000000000001").
+			AddRow("dbo", "##shared").
+			AddRow("dbo", "orders")
+	}
+
+	mock.ExpectQuery(`SELECT TABLE_SCHEMA, TABLE_NAME\s+FROM INFORMATION_SCHEMA.TABLES`).WillReturnRows(tableRows())
+	tables, err := handler.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	if len(tables) != 1 || tables[0] != "orders" {
+		t.Errorf("ListTables() = %v, want [orders]", tables)
+	}
+
+	db.Config.IncludeTempTables = true
+	mock.ExpectQuery(`SELECT TABLE_SCHEMA, TABLE_NAME\s+FROM INFORMATION_SCHEMA.TABLES`).WillReturnRows(tableRows())
+	tables, err = handler.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	if len(tables) != 3 {
+		t.Errorf("ListTables() with IncludeTempTables = %v, want all 3 tables", tables)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerNonDboSchema(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{UpdateExistingMode: "overwrite"}}
+	handler := sqlServerHandler{}
+
+	mock.ExpectQuery(`SELECT COLUMN_NAME, DATA_TYPE\s+FROM INFORMATION_SCHEMA.COLUMNS`).
+		WithArgs(sql.Named("p1", "orders"), sql.Named("p2", "sales")).
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "DATA_TYPE"}).AddRow("status", "nvarchar"))
+	if _, err := handler.ListColumns(db, "sales.orders"); err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [status]) FROM [sales].[orders]")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [sales].[orders] WHERE [status] IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT TOP (@p1) CAST([status] AS NVARCHAR(MAX)) FROM [sales].[orders]")).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("open"))
+	if _, err := handler.GetColumnMetadata(db, "sales.orders", "status"); err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT CAST\(p\.value AS NVARCHAR\(MAX\)\)`).
+		WithArgs(sql.Named("p1", "sales"), sql.Named("p2", "orders"), sql.Named("p3", "status")).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("Order state <gemini>Distinct: 1</gemini>"))
+	mock.ExpectQuery(`SELECT 1\s+FROM sys\.extended_properties`).
+		WithArgs(sql.Named("p1", "sales"), sql.Named("p2", "orders"), sql.Named("p3", "status")).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(1))
+	got, err := handler.GenerateCommentSQL(db, &database.CommentData{TableName: "sales.orders", ColumnName: "status", DistinctCount: 2}, map[string]bool{"distinct_values": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	want := `EXEC sp_updateextendedproperty @name=N'MS_Description', @value=N'Order state <gemini>Distinct: 2</gemini>', @level0type=N'SCHEMA', @level0name=N'sales', @level1type=N'TABLE', @level1name=N'orders', @level2type=N'COLUMN', @level2name=N'status';`
+	if got != want {
+		t.Errorf("GenerateCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+
+	mock.ExpectQuery(`SELECT CAST\(p\.value AS NVARCHAR\(MAX\)\)\s+FROM sys\.extended_properties AS p\s+INNER JOIN sys\.tables AS t ON p\.major_id = t\.object_id\s+INNER JOIN sys\.schemas`).
+		WithArgs(sql.Named("p1", "sales"), sql.Named("p2", "orders")).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT 1\s+FROM sys\.extended_properties`).
+		WithArgs(sql.Named("p1", "sales"), sql.Named("p2", "orders")).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+	got, err = handler.GenerateTableCommentSQL(db, &database.TableCommentData{TableName: "sales.orders", Description: "Orders"}, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateTableCommentSQL() unexpected error: %v", err)
+	}
+	want = `EXEC sp_addextendedproperty @name=N'MS_Description', @value=N'<gemini>Orders</gemini>', @level0type=N'SCHEMA', @level0name=N'sales', @level1type=N'TABLE', @level1name=N'orders';`
+	if got != want {
+		t.Errorf("GenerateTableCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// TestSQLServerSchemaFlag checks that --schema limits ListTables to that schema,
+// whose tables are then named without a prefix and written to with its name as
+// @level0name.
+func TestSQLServerSchemaFlag(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{UpdateExistingMode: "overwrite", Schema: "hr"}}
+	handler := sqlServerHandler{}
+
+	mock.ExpectQuery(`SELECT TABLE_SCHEMA, TABLE_NAME\s+FROM INFORMATION_SCHEMA.TABLES\s+WHERE .*\s+AND TABLE_SCHEMA = @p1`).
+		WithArgs(sql.Named("p1", "hr")).
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_SCHEMA", "TABLE_NAME"}).AddRow("hr", "employees"))
+	tables, err := handler.ListTables(db)
+	if err != nil {
+		t.Fatalf("ListTables() unexpected error: %v", err)
+	}
+	if len(tables) != 1 || tables[0] != "employees" {
+		t.Fatalf("ListTables() = %v, want [employees]", tables)
+	}
+
+	mock.ExpectQuery(`SELECT COLUMN_NAME, DATA_TYPE\s+FROM INFORMATION_SCHEMA.COLUMNS`).
+		WithArgs(sql.Named("p1", "employees"), sql.Named("p2", "hr")).
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "DATA_TYPE"}).AddRow("title", "nvarchar"))
+	if _, err := handler.ListColumns(db, "employees"); err != nil {
+		t.Fatalf("ListColumns() unexpected error: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(DISTINCT [title]) FROM [hr].[employees]")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT_BIG(*) FROM [hr].[employees] WHERE [title] IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT TOP (@p1) CAST([title] AS NVARCHAR(MAX)) FROM [hr].[employees]")).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("Engineer"))
+	if _, err := handler.GetColumnMetadata(db, "employees", "title"); err != nil {
+		t.Fatalf("GetColumnMetadata() unexpected error: %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT CAST\(p\.value AS NVARCHAR\(MAX\)\)`).
+		WithArgs(sql.Named("p1", "hr"), sql.Named("p2", "employees"), sql.Named("p3", "title")).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT 1\s+FROM sys\.extended_properties`).
+		WithArgs(sql.Named("p1", "hr"), sql.Named("p2", "employees"), sql.Named("p3", "title")).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+	got, err := handler.GenerateCommentSQL(db, &database.CommentData{TableName: "employees", ColumnName: "title", Description: "Job title"}, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	want := `EXEC sp_addextendedproperty @name=N'MS_Description', @value=N'<gemini>Job title</gemini>', @level0type=N'SCHEMA', @level0name=N'hr', @level1type=N'TABLE', @level1name=N'employees', @level2type=N'COLUMN', @level2name=N'title';`
+	if got != want {
+		t.Errorf("GenerateCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+
+	mock.ExpectQuery(`SELECT CAST\(p\.value AS NVARCHAR\(MAX\)\)`).
+		WithArgs(sql.Named("p1", "hr"), sql.Named("p2", "employees")).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("Staff"))
+	mock.ExpectQuery(`SELECT 1\s+FROM sys\.extended_properties`).
+		WithArgs(sql.Named("p1", "hr"), sql.Named("p2", "employees")).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(1))
+	got, err = handler.GenerateTableCommentSQL(db, &database.TableCommentData{TableName: "employees", Description: "Employees"}, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateTableCommentSQL() unexpected error: %v", err)
+	}
+	want = `EXEC sp_updateextendedproperty @name=N'MS_Description', @value=N'Staff <gemini>Employees</gemini>', @level0type=N'SCHEMA', @level0name=N'hr', @level1type=N'TABLE', @level1name=N'employees';`
+	if got != want {
+		t.Errorf("GenerateTableCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// TestSQLServerOfflineCommentSQLUpdatesExistingProperty checks that offline generation,
+// which cannot look up whether a property already exists, emits SQL that updates an
+// existing MS_Description instead of failing to add it a second time.
+func TestSQLServerOfflineCommentSQLUpdatesExistingProperty(t *testing.T) {
+	db, err := database.NewOffline(config.DatabaseConfig{Dialect: "sqlserver", UpdateExistingMode: "overwrite"})
+	if err != nil {
+		t.Fatalf("NewOffline() error: %v", err)
+	}
+	handler := sqlServerHandler{}
+
+	got, err := handler.GenerateCommentSQL(db, &database.CommentData{TableName: "sales.orders", ColumnName: "status", DistinctCount: 2}, map[string]bool{"distinct_values": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	want := `IF EXISTS (SELECT 1 FROM sys.fn_listextendedproperty(N'MS_Description', N'SCHEMA', N'sales', N'TABLE', N'orders', N'COLUMN', N'status')) ` +
+		`EXEC sp_updateextendedproperty @name=N'MS_Description', @value=N'<gemini>Distinct: 2</gemini>', @level0type=N'SCHEMA', @level0name=N'sales', @level1type=N'TABLE', @level1name=N'orders', @level2type=N'COLUMN', @level2name=N'status'; ` +
+		`ELSE EXEC sp_addextendedproperty @name=N'MS_Description', @value=N'<gemini>Distinct: 2</gemini>', @level0type=N'SCHEMA', @level0name=N'sales', @level1type=N'TABLE', @level1name=N'orders', @level2type=N'COLUMN', @level2name=N'status';`
+	if got != want {
+		t.Errorf("GenerateCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+
+	got, err = handler.GenerateTableCommentSQL(db, &database.TableCommentData{TableName: "sales.orders", Description: "Orders"}, map[string]bool{"description": true})
+	if err != nil {
+		t.Fatalf("GenerateTableCommentSQL() unexpected error: %v", err)
+	}
+	want = `IF EXISTS (SELECT 1 FROM sys.fn_listextendedproperty(N'MS_Description', N'SCHEMA', N'sales', N'TABLE', N'orders', NULL, NULL)) ` +
+		`EXEC sp_updateextendedproperty @name=N'MS_Description', @value=N'<gemini>Orders</gemini>', @level0type=N'SCHEMA', @level0name=N'sales', @level1type=N'TABLE', @level1name=N'orders'; ` +
+		`ELSE EXEC sp_addextendedproperty @name=N'MS_Description', @value=N'<gemini>Orders</gemini>', @level0type=N'SCHEMA', @level0name=N'sales', @level1type=N'TABLE', @level1name=N'orders';`
+	if got != want {
+		t.Errorf("GenerateTableCommentSQL() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestSQLServerColumnNamedLikeItsTable checks that the table's own MS_Description
+// (minor_id 0) is not taken for the comment of a column with the table's name.
+func TestSQLServerColumnNamedLikeItsTable(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB, Config: config.DatabaseConfig{UpdateExistingMode: "overwrite"}}
+	handler := sqlServerHandler{}
+	columnProperty := `INNER JOIN sys\.columns AS c ON p\.major_id = c\.object_id AND p\.minor_id = c\.column_id`
+
+	mock.ExpectQuery(`SELECT CAST\(p\.value AS NVARCHAR\(MAX\)\).*` + columnProperty).
+		WithArgs(sql.Named("p1", "dbo"), sql.Named("p2", "status"), sql.Named("p3", "status")).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery(`SELECT 1\s+FROM sys\.extended_properties.*` + columnProperty).
+		WithArgs(sql.Named("p1", "dbo"), sql.Named("p2", "status"), sql.Named("p3", "status")).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+	got, err := handler.GenerateCommentSQL(db, &database.CommentData{TableName: "status", ColumnName: "status", NullCount: 1}, map[string]bool{"null_count": true})
+	if err != nil {
+		t.Fatalf("GenerateCommentSQL() unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(got, "EXEC sp_addextendedproperty") || !strings.Contains(got, "@level2type=N'COLUMN', @level2name=N'status'") {
+		t.Errorf("GenerateCommentSQL() = %s, want a new column-level property", got)
+	}
+
+	mock.ExpectQuery(`SELECT CAST\(p\.value AS NVARCHAR\(MAX\)\).*p\.minor_id = 0`).
+		WithArgs(sql.Named("p1", "dbo"), sql.Named("p2", "status")).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("Lookup of order states"))
+	comment, err := handler.GetTableComment(context.Background(), db, "status")
+	if err != nil || comment != "Lookup of order states" {
+		t.Errorf("GetTableComment() = %q, %v", comment, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerForeignKeyToOtherSchema(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB}
+	handler := sqlServerHandler{}
+
+	mock.ExpectQuery(`SELECT\s+rs\.name as referenced_schema`).
+		WithArgs(sql.Named("p1", "orders"), sql.Named("p2", "customer_id"), sql.Named("p3", "sales")).
+		WillReturnRows(sqlmock.NewRows([]string{"referenced_schema", "referenced_table", "referenced_column", "constraint_name"}).
+			AddRow("crm", "customers", "id", "FK_orders_customers"))
+
+	fks, err := handler.GetForeignKeys(db, "sales.orders", "customer_id")
+	if err != nil {
+		t.Fatalf("GetForeignKeys() unexpected error: %v", err)
+	}
+	if len(fks) != 1 || fks[0].ReferencedTable != "crm.customers" {
+		t.Fatalf("GetForeignKeys() = %+v, want a reference to crm.customers", fks)
+	}
+	if got, want := handler.formatForeignKeys(db, fks), "Foreign Keys: [[crm].[customers].[id]]"; got != want {
+		t.Errorf("formatForeignKeys() = %q, want %q", got, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSQLServerTableVersion(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := &database.DB{Pool: mockDB}
+	handler := sqlServerHandler{}
+	columns := []string{"modify_date", "rows", "last_user_update"}
+
+	mock.ExpectQuery(`FROM sys.tables AS t`).
+		WithArgs(sql.Named("p1", "sales"), sql.Named("p2", "orders")).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("2026-01-05T10:00:00", 120, "2026-03-01T08:30:00"))
+	version, err := handler.TableVersion(context.Background(), db, "sales.orders")
+	if err != nil {
+		t.Fatalf("TableVersion() unexpected error: %v", err)
+	}
+	if want := "2026-01-05T10:00:00:120:2026-03-01T08:30:00"; version != want {
+		t.Errorf("TableVersion() = %q, want %q", version, want)
+	}
+
+	// Without a recorded update, e.g. after a server restart, the version is unknown.
+	mock.ExpectQuery(`FROM sys.tables AS t`).
+		WithArgs(sql.Named("p1", "dbo"), sql.Named("p2", "orders")).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("2026-01-05T10:00:00", 120, nil))
+	version, err = handler.TableVersion(context.Background(), db, "orders")
+	if err != nil {
+		t.Fatalf("TableVersion() unexpected error: %v", err)
+	}
+	if version != "" {
+		t.Errorf("TableVersion() = %q, want unknown", version)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
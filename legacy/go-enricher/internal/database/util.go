@@ -2,6 +2,8 @@ package database
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -10,50 +12,222 @@ const (
 	EndTag   = "</gemini>"
 )
 
+// DefaultExampleCount is the number of example values rendered in a column comment.
+const DefaultExampleCount = 3
+
+// ExampleQueryLimit returns how many distinct values the example query should fetch.
+// A sample size larger than DefaultExampleCount widens the sample so that
+// SelectRepresentativeValues can pick from more than the first few rows found.
+func ExampleQueryLimit(sampleSize int) int {
+	if sampleSize > DefaultExampleCount {
+		return sampleSize
+	}
+	return DefaultExampleCount
+}
+
+// SampleWithoutDistinct reports whether the example query for a column with
+// distinctCount distinct values should read the first rows found instead of its
+// distinct values, for --max-distinct-for-examples. With that many values the
+// first rows are nearly always distinct, and DISTINCT would process the whole column
+// for the few examples kept. An unknown count (-1) keeps DISTINCT.
+func SampleWithoutDistinct(db *DB, distinctCount int64) bool {
+	return db.Config.MaxDistinctForExamples > 0 && distinctCount > db.Config.MaxDistinctForExamples
+}
+
+// UniqueValues returns values without repeats, keeping the first occurrence of each.
+// Examples sampled without DISTINCT go through it.
+func UniqueValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := values[:0:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// SelectRepresentativeValues picks up to n values spread evenly across the sorted sample,
+// and returns them sorted. The first and last sorted values are always included when n > 1.
+func SelectRepresentativeValues(values []string, n int) []string {
+	if n <= 0 {
+		return []string{}
+	}
+	sorted := make([]string, len(values))
+	copy(sorted, values)
+	sort.Strings(sorted)
+	if len(sorted) <= n {
+		return sorted
+	}
+
+	if n == 1 {
+		return []string{sorted[len(sorted)/2]}
+	}
+	selected := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		selected = append(selected, sorted[i*(len(sorted)-1)/(n-1)])
+	}
+	return selected
+}
+
 // isEnrichmentRequested checks if a specific enrichment is requested.
-// If the enrichments map is empty, all are considered requested.
+// If the enrichments map includes none (it is empty or only lists exclusions as
+// false), all enrichments except the excluded ones are considered requested.
 func isEnrichmentRequested(enrichment string, enrichments map[string]bool) bool {
-	if len(enrichments) == 0 {
-		return true
+	if requested, listed := enrichments[strings.ToLower(enrichment)]; listed {
+		return requested
+	}
+	for _, included := range enrichments {
+		if included {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatForeignKeys renders foreign key references using the dialect's identifier quoting,
+// each followed by the percentage of values that match it when that was checked.
+func FormatForeignKeys(foreignKeys []ForeignKeyReference, quoteIdentifier func(string) string) string {
+	if len(foreignKeys) == 0 {
+		return ""
+	}
+	fkStrings := make([]string, len(foreignKeys))
+	for i, fk := range foreignKeys {
+		fkStrings[i] = fmt.Sprintf("%s.%s", quoteIdentifier(fk.ReferencedTable), quoteIdentifier(fk.ReferencedColumn))
+		if fk.MatchRatio != nil {
+			fkStrings[i] += fmt.Sprintf(" (%.2f%%)", *fk.MatchRatio*100)
+		}
+		if fk.ReferencedTableDescription != "" {
+			fkStrings[i] += fmt.Sprintf(" (%s)", fk.ReferencedTableDescription)
+		}
+	}
+	return fmt.Sprintf("Foreign Keys: [%s]", strings.Join(fkStrings, ", "))
+}
+
+// commentPartPriority ranks the built-in enrichments for --max-comment-parts: the
+// description first, then relationships and examples, then the statistics. Custom
+// enrichments rank below all of them.
+var commentPartPriority = map[string]int{
+	"description":     0,
+	"foreign_keys":    1,
+	"examples":        2,
+	"json_keys":       3,
+	"distinct_values": 4,
+	"null_count":      5,
+	"average":         6,
+	"data_type":       7,
+}
+
+// commentPart is one enrichment's text in a column comment.
+type commentPart struct {
+	enrichment string
+	text       string
+}
+
+// limitCommentParts keeps the max highest-priority parts, in their original order.
+// A max of 0 or less keeps every part.
+func limitCommentParts(parts []commentPart, max int) []commentPart {
+	if max <= 0 || len(parts) <= max {
+		return parts
+	}
+	rank := func(p commentPart) int {
+		if priority, ok := commentPartPriority[p.enrichment]; ok {
+			return priority
+		}
+		return len(commentPartPriority)
+	}
+	byPriority := make([]int, len(parts))
+	for i := range parts {
+		byPriority[i] = i
+	}
+	sort.SliceStable(byPriority, func(i, j int) bool {
+		return rank(parts[byPriority[i]]) < rank(parts[byPriority[j]])
+	})
+	keep := make(map[int]bool, max)
+	for _, i := range byPriority[:max] {
+		keep[i] = true
+	}
+	limited := make([]commentPart, 0, max)
+	for i, p := range parts {
+		if keep[i] {
+			limited = append(limited, p)
+		}
 	}
-	return enrichments[strings.ToLower(enrichment)]
+	return limited
 }
 
 // generateMetadataCommentString constructs the metadata portion of the column comment.
-// It takes the pre-formatted example string as input.
-func GenerateMetadataCommentString(data *CommentData, enrichments map[string]bool, formattedExamples string) string {
+// It takes the pre-formatted example and foreign key strings as input. The data type
+// is only added when data_type is listed explicitly or IncludeType is set, so that
+// "all enrichments" keeps its established comments.
+func GenerateMetadataCommentString(data *CommentData, enrichments map[string]bool, formattedExamples string, formattedForeignKeys string) string {
 	if data == nil {
 		return ""
 	}
 
-	var commentParts []string
+	var commentParts []commentPart
 	isReq := func(e string) bool { return isEnrichmentRequested(e, enrichments) }
+	add := func(enrichment, text string) {
+		commentParts = append(commentParts, commentPart{enrichment: enrichment, text: text})
+	}
 
+	if (enrichments["data_type"] || data.IncludeType) && data.ColumnDataType != "" {
+		add("data_type", "Type: "+data.ColumnDataType)
+	}
 	if isReq("examples") && formattedExamples != "" {
-		commentParts = append(commentParts, formattedExamples)
+		add("examples", formattedExamples)
 	}
 	if isReq("distinct_values") && data.DistinctCount >= 0 {
-		commentParts = append(commentParts, fmt.Sprintf("Distinct Values: %d", data.DistinctCount))
+		add("distinct_values", fmt.Sprintf("Distinct: %d", data.DistinctCount))
 	}
 	if isReq("null_count") {
-		commentParts = append(commentParts, fmt.Sprintf("Null Count: %d |", data.NullCount))
+		add("null_count", fmt.Sprintf("Nulls: %d", data.NullCount))
+	}
+	// Like data_type, the average is only added when it is requested by name.
+	if enrichments["average"] && data.Average != nil {
+		add("average", fmt.Sprintf("Avg: %.2f", *data.Average))
+	}
+	if isReq("json_keys") && len(data.JSONKeys) > 0 {
+		add("json_keys", "Keys: "+strings.Join(data.JSONKeys, ", "))
 	}
 	if isReq("description") && data.Description != "" {
-		commentParts = append(commentParts, data.Description)
+		add("description", data.Description)
 	}
 	// Add foreign key information to comment
-	if isReq("foreign_keys") && len(data.ForeignKeys) > 0 {
-		var fkStrings []string
-		for _, fk := range data.ForeignKeys {
-			fkStrings = append(fkStrings, fmt.Sprintf(`\"%s\".\"%s\"`, fk.ReferencedTable, fk.ReferencedColumn))
+	if isReq("foreign_keys") && formattedForeignKeys != "" {
+		add("foreign_keys", formattedForeignKeys)
+	}
+	// Custom enrichments follow the built-in ones, in name order for stable comments.
+	customNames := make([]string, 0, len(data.Custom))
+	for name := range data.Custom {
+		customNames = append(customNames, name)
+	}
+	sort.Strings(customNames)
+	for _, name := range customNames {
+		if isReq(name) && data.Custom[name] != "" {
+			add(name, fmt.Sprintf("%s: %s", name, data.Custom[name]))
 		}
-		commentParts = append(commentParts, fmt.Sprintf("Foreign Keys: [%s]", strings.Join(fkStrings, ", ")))
 	}
 
-	if len(commentParts) == 0 {
-		return ""
+	kept := limitCommentParts(commentParts, data.MaxParts)
+	texts := make([]string, 0, len(kept))
+	keptEnrichments := make([]string, 0, len(kept))
+	for _, p := range kept {
+		texts = append(texts, p.text)
+		keptEnrichments = append(keptEnrichments, p.enrichment)
+	}
+	metadata := strings.Join(texts, " | ")
+	if data.Style == CommentStyleSentence {
+		metadata = sentenceMetadata(kept, data)
+	}
+	if data.Provenance {
+		metadata = withProvenance(metadata, keptEnrichments)
+	}
+	if data.EmbedTimestamp {
+		metadata = withProfiled(metadata, data.ProfiledAt)
 	}
-	return strings.Join(commentParts, " | ")
+	return withSource(metadata, data.Source)
 }
 
 // generateTableMetadataCommentString constructs the metadata portion of the table comment.
@@ -61,7 +235,121 @@ func GenerateTableMetadataCommentString(data *TableCommentData, enrichments map[
 	if data == nil || data.Description == "" || !isEnrichmentRequested("description", enrichments) {
 		return ""
 	}
-	return data.Description
+	metadata := data.Description
+	if data.Provenance {
+		metadata = withProvenance(metadata, []string{"description"})
+	}
+	return withSource(metadata, data.Source)
+}
+
+// StripMetadata removes the <gemini> block from a comment, keeping the user's text.
+func StripMetadata(comment string) string {
+	return MergeComments(comment, "", "")
+}
+
+// SplitComment separates a comment into the user's text outside <gemini> tags and
+// the metadata inside them. Unterminated blocks are dropped, since their metadata
+// may be truncated; stray end tags and nested start tags are ignored. Several
+// blocks are joined with " | ". ok reports whether the tags were well formed:
+// none at all, or a single start tag followed by a single end tag.
+func SplitComment(comment string) (user string, metadata string, ok bool) {
+	var userParts, metadataParts []string
+	var current strings.Builder
+	inside := false
+	rest := comment
+	for rest != "" {
+		start := strings.Index(rest, StartTag)
+		end := strings.Index(rest, EndTag)
+		next, isStart := -1, false
+		switch {
+		case start == -1 && end == -1:
+		case end == -1 || (start != -1 && start < end):
+			next, isStart = start, true
+		default:
+			next = end
+		}
+		if next == -1 {
+			current.WriteString(rest)
+			break
+		}
+		current.WriteString(rest[:next])
+		if isStart {
+			rest = rest[next+len(StartTag):]
+			if !inside {
+				userParts = append(userParts, current.String())
+				current.Reset()
+				inside = true
+			}
+			continue
+		}
+		rest = rest[next+len(EndTag):]
+		if inside {
+			metadataParts = append(metadataParts, current.String())
+			current.Reset()
+			inside = false
+		}
+	}
+	if !inside {
+		userParts = append(userParts, current.String())
+	}
+
+	startCount := strings.Count(comment, StartTag)
+	endCount := strings.Count(comment, EndTag)
+	ok = (startCount == 0 && endCount == 0) ||
+		(startCount == 1 && endCount == 1 && strings.Index(comment, StartTag) < strings.Index(comment, EndTag))
+
+	return joinNonEmpty(userParts, " "), joinNonEmpty(metadataParts, " | "), ok
+}
+
+// RepairComment rewrites a comment with malformed <gemini> tags as the user's text
+// followed by a single well-formed block. Well-formed comments are returned as is.
+func RepairComment(comment string) string {
+	user, metadata, ok := SplitComment(comment)
+	if ok {
+		return comment
+	}
+	if metadata == "" {
+		return user
+	}
+	if user == "" {
+		return StartTag + metadata + EndTag
+	}
+	return user + " " + StartTag + metadata + EndTag
+}
+
+// statSegmentPattern matches a whole " | "-separated segment holding a statistic that
+// earlier versions of the tool wrote outside the <gemini> tags, such as "Distinct: 12",
+// "Null Count: 0" or "Examples: ['a', 'b']".
+var statSegmentPattern = regexp.MustCompile(`^(?:(?:Distinct|Distinct Values|Nulls|Null Count): \d+|Examples: \[.*\])$`)
+
+// StripMetadataAndStats removes the <gemini> block like StripMetadata and also drops
+// statistics left in the user's text by earlier versions of the tool. Only segments
+// between " | " separators that consist entirely of a statistic are removed, so prose
+// that merely mentions one, like "Distinct: 3 per customer", is kept. The user's text
+// is returned unchanged when it holds no such segment.
+func StripMetadataAndStats(comment string) string {
+	user := StripMetadata(comment)
+	segments := strings.Split(user, "|")
+	kept := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if !statSegmentPattern.MatchString(strings.TrimSpace(segment)) {
+			kept = append(kept, segment)
+		}
+	}
+	if len(kept) == len(segments) {
+		return user
+	}
+	return joinNonEmpty(kept, " | ")
+}
+
+func joinNonEmpty(parts []string, sep string) string {
+	var kept []string
+	for _, part := range parts {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			kept = append(kept, trimmed)
+		}
+	}
+	return strings.Join(kept, sep)
 }
 
 // mergeComments combines an existing comment with new metadata, handling tags.
@@ -105,7 +393,7 @@ func MergeComments(existingComment string, newMetadataComment string, updateExis
 		suffix := strings.TrimSpace(existingComment[endIndex+len(EndTag):])
 
 		if updateExistingMode == "append" {
-			currentGeminiComment := strings.TrimSpace(existingComment[startIndex+len(StartTag) : endIndex])
+			currentGeminiComment := withoutStaleProfiled(strings.TrimSpace(existingComment[startIndex+len(StartTag):endIndex]), newMetadataComment)
 			appendedMetadata := currentGeminiComment
 			if appendedMetadata != "" && newMetadataComment != "" {
 				appendedMetadata += " | " + newMetadataComment
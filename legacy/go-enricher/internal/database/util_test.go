@@ -1,8 +1,11 @@
 package database
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
 )
 
 func TestIsEnrichmentRequested(t *testing.T) {
@@ -18,6 +21,8 @@ func TestIsEnrichmentRequested(t *testing.T) {
 		{"Specific not requested", "examples", map[string]bool{"description": true}, false},
 		{"Case insensitivity", "NULL_COUNT", map[string]bool{"null_count": true}, true},
 		{"Not present in map", "foobar", map[string]bool{"description": true}, false},
+		{"All but excluded: excluded", "examples", map[string]bool{"examples": false}, false},
+		{"All but excluded: other requested", "null_count", map[string]bool{"examples": false}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -30,18 +35,19 @@ func TestIsEnrichmentRequested(t *testing.T) {
 
 func TestGenerateMetadataCommentString(t *testing.T) {
 	tests := []struct {
-		name              string
-		data              *CommentData
-		enrichments       map[string]bool
-		formattedExamples string
-		want              string
+		name                 string
+		data                 *CommentData
+		enrichments          map[string]bool
+		formattedExamples    string
+		formattedForeignKeys string
+		want                 string
 	}{
 		{
 			name:              "All enrichments, full data",
 			data:              &CommentData{Description: "Desc", DistinctCount: 10, NullCount: 5},
 			enrichments:       map[string]bool{}, // All
 			formattedExamples: "Examples: ['a', 'b']",
-			want:              "Desc | Examples: ['a', 'b'] | Distinct: 10 | Nulls: 5",
+			want:              "Examples: ['a', 'b'] | Distinct: 10 | Nulls: 5 | Desc",
 		},
 		{
 			name:              "Only description requested",
@@ -62,14 +68,14 @@ func TestGenerateMetadataCommentString(t *testing.T) {
 			data:              &CommentData{Description: "Desc", DistinctCount: 0, NullCount: 5},
 			enrichments:       map[string]bool{},
 			formattedExamples: "",
-			want:              "Desc | Distinct: 0 | Nulls: 5",
+			want:              "Distinct: 0 | Nulls: 5 | Desc",
 		},
 		{
 			name:              "Distinct count is negative (error indicator)",
 			data:              &CommentData{Description: "Desc", DistinctCount: -1, NullCount: 5},
 			enrichments:       map[string]bool{},
 			formattedExamples: "",
-			want:              "Desc | Nulls: 5", // Distinct shouldn't be added if < 0
+			want:              "Nulls: 5 | Desc", // Distinct shouldn't be added if < 0
 		},
 		{
 			name:              "No description provided",
@@ -83,7 +89,7 @@ func TestGenerateMetadataCommentString(t *testing.T) {
 			data:              &CommentData{Description: "Desc", DistinctCount: 10, NullCount: 5},
 			enrichments:       map[string]bool{},
 			formattedExamples: "",
-			want:              "Desc | Distinct: 10 | Nulls: 5",
+			want:              "Distinct: 10 | Nulls: 5 | Desc",
 		},
 		{
 			name:              "No relevant data provided",
@@ -99,6 +105,13 @@ func TestGenerateMetadataCommentString(t *testing.T) {
 			formattedExamples: "Ex",
 			want:              "",
 		},
+		{
+			name:                 "Foreign keys requested",
+			data:                 &CommentData{DistinctCount: -1},
+			enrichments:          map[string]bool{"foreign_keys": true},
+			formattedForeignKeys: `Foreign Keys: ["users"."id"]`,
+			want:                 `Foreign Keys: ["users"."id"]`,
+		},
 		{
 			name:              "Nil data",
 			data:              nil,
@@ -109,13 +122,120 @@ func TestGenerateMetadataCommentString(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := GenerateMetadataCommentString(tt.data, tt.enrichments, tt.formattedExamples); got != tt.want {
+			if got := GenerateMetadataCommentString(tt.data, tt.enrichments, tt.formattedExamples, tt.formattedForeignKeys); got != tt.want {
+				t.Errorf("GenerateMetadataCommentString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateMetadataCommentStringMaxParts(t *testing.T) {
+	examples := "Examples: ['a']"
+	foreignKeys := "Foreign Keys: [customers.id]"
+	tests := []struct {
+		name     string
+		maxParts int
+		source   string
+		want     string
+	}{
+		{"no limit", 0, "", "Examples: ['a'] | Distinct: 10 | Nulls: 5 | Desc | Foreign Keys: [customers.id] | tier: gold"},
+		{"description only", 1, "", "Desc"},
+		{"description and foreign keys", 2, "", "Desc | Foreign Keys: [customers.id]"},
+		{"keeps comment order", 3, "", "Examples: ['a'] | Desc | Foreign Keys: [customers.id]"},
+		{"stats before custom", 5, "", "Examples: ['a'] | Distinct: 10 | Nulls: 5 | Desc | Foreign Keys: [customers.id]"},
+		{"limit above part count", 10, "", "Examples: ['a'] | Distinct: 10 | Nulls: 5 | Desc | Foreign Keys: [customers.id] | tier: gold"},
+		{"source is not a part", 1, "prod", "Source: prod | Desc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &CommentData{
+				Description:   "Desc",
+				DistinctCount: 10,
+				NullCount:     5,
+				Custom:        map[string]string{"tier": "gold"},
+				Source:        tt.source,
+				MaxParts:      tt.maxParts,
+			}
+			if got := GenerateMetadataCommentString(data, map[string]bool{}, examples, foreignKeys); got != tt.want {
 				t.Errorf("GenerateMetadataCommentString() = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestGenerateMetadataCommentStringDataType(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        *CommentData
+		enrichments map[string]bool
+		want        string
+	}{
+		{"requested", &CommentData{ColumnDataType: "varchar(255)", Description: "Desc"}, map[string]bool{"data_type": true, "description": true}, "Type: varchar(255) | Desc"},
+		{"not part of all", &CommentData{ColumnDataType: "varchar(255)", Description: "Desc", DistinctCount: -1}, map[string]bool{"null_count": false}, "Desc"},
+		{"included by option", &CommentData{ColumnDataType: "varchar(255)", Description: "Desc", IncludeType: true}, map[string]bool{"description": true}, "Type: varchar(255) | Desc"},
+		{"unknown type", &CommentData{Description: "Desc"}, map[string]bool{"data_type": true, "description": true}, "Desc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenerateMetadataCommentString(tt.data, tt.enrichments, "", ""); got != tt.want {
+				t.Errorf("GenerateMetadataCommentString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateMetadataCommentStringAverage(t *testing.T) {
+	avg := 12.3456
+	tests := []struct {
+		name        string
+		data        *CommentData
+		enrichments map[string]bool
+		want        string
+	}{
+		{"rounded to two decimals", &CommentData{Average: &avg, Description: "Desc"}, map[string]bool{"average": true, "description": true}, "Avg: 12.35 | Desc"},
+		{"after the statistics", &CommentData{Average: &avg, DistinctCount: 3}, map[string]bool{"distinct_values": true, "average": true}, "Distinct: 3 | Avg: 12.35"},
+		{"not part of all", &CommentData{Average: &avg, Description: "Desc", DistinctCount: -1}, map[string]bool{"null_count": false}, "Desc"},
+		{"not numeric", &CommentData{Description: "Desc"}, map[string]bool{"average": true, "description": true}, "Desc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenerateMetadataCommentString(tt.data, tt.enrichments, "", ""); got != tt.want {
+				t.Errorf("GenerateMetadataCommentString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatForeignKeys(t *testing.T) {
+	quote := func(name string) string { return "<" + name + ">" }
+	fks := []ForeignKeyReference{
+		{ReferencedTable: "users", ReferencedColumn: "id"},
+		{ReferencedTable: "accounts", ReferencedColumn: "uid"},
+	}
+
+	if got := FormatForeignKeys(nil, quote); got != "" {
+		t.Errorf("FormatForeignKeys(nil) = %q, want empty", got)
+	}
+	want := "Foreign Keys: [<users>.<id>, <accounts>.<uid>]"
+	if got := FormatForeignKeys(fks, quote); got != want {
+		t.Errorf("FormatForeignKeys() = %q, want %q", got, want)
+	}
+
+	fks[0].ReferencedTableDescription = "the user accounts table"
+	want = "Foreign Keys: [<users>.<id> (the user accounts table), <accounts>.<uid>]"
+	if got := FormatForeignKeys(fks, quote); got != want {
+		t.Errorf("FormatForeignKeys() with description = %q, want %q", got, want)
+	}
+
+	ratio := 0.985
+	fks[0].MatchRatio = &ratio
+	want = "Foreign Keys: [<users>.<id> (98.50%) (the user accounts table), <accounts>.<uid>]"
+	if got := FormatForeignKeys(fks, quote); got != want {
+		t.Errorf("FormatForeignKeys() with match ratio = %q, want %q", got, want)
+	}
+}
+
 func TestGenerateTableMetadataCommentString(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -212,3 +332,161 @@ func TestMergeComments(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitComment(t *testing.T) {
+	tests := []struct {
+		name         string
+		comment      string
+		wantUser     string
+		wantMetadata string
+		wantOK       bool
+	}{
+		{"No tags", "User comment", "User comment", "", true},
+		{"Well formed", "Prefix <gemini>Data</gemini> Suffix", "Prefix Suffix", "Data", true},
+		{"Unterminated", "User <gemini>Examples: 'a", "User", "", false},
+		{"Stray end tag", "User A</gemini> B", "User A B", "", false},
+		{"End before start", "X </gemini>A<gemini> Y", "X A", "", false},
+		{"Nested start", "<gemini>A <gemini>B</gemini>", "", "A B", false},
+		{"Two blocks", "P <gemini>A</gemini> M <gemini>B</gemini> S", "P M S", "A | B", false},
+		{"Complete block then unterminated", "<gemini>A</gemini> U <gemini>B", "U", "A", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, metadata, ok := SplitComment(tt.comment)
+			if user != tt.wantUser || metadata != tt.wantMetadata || ok != tt.wantOK {
+				t.Errorf("SplitComment(%q) = (%q, %q, %v), want (%q, %q, %v)", tt.comment, user, metadata, ok, tt.wantUser, tt.wantMetadata, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRepairComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    string
+	}{
+		{"Well formed is unchanged", " Prefix <gemini>Data</gemini> Suffix ", " Prefix <gemini>Data</gemini> Suffix "},
+		{"No tags is unchanged", "User comment", "User comment"},
+		{"Unterminated block is removed", "User <gemini>Examples: 'a", "User"},
+		{"Unterminated only", "<gemini>Examples: 'a", ""},
+		{"Stray end tag is dropped", "User A</gemini> B", "User A B"},
+		{"Nested start is flattened", "<gemini>A <gemini>B</gemini>", "<gemini>A B</gemini>"},
+		{"Blocks are merged after the user text", "P <gemini>A</gemini> M <gemini>B</gemini> S", "P M S <gemini>A | B</gemini>"},
+		{"Complete block kept, unterminated dropped", "<gemini>A</gemini> U <gemini>B", "U <gemini>A</gemini>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RepairComment(tt.comment); got != tt.want {
+				t.Errorf("RepairComment(%q) = %q, want %q", tt.comment, got, tt.want)
+			}
+			if _, _, ok := SplitComment(RepairComment(tt.comment)); !ok {
+				t.Errorf("RepairComment(%q) is still malformed", tt.comment)
+			}
+		})
+	}
+}
+
+func TestStripMetadataAndStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    string
+	}{
+		{"Tags only", "Order total <gemini>Examples: [12]</gemini>", "Order total"},
+		{"Legacy stats outside tags", "Order total | Distinct: 12 | Nulls: 0 | Examples: ['a', 'b'] <gemini>Null Count: 0</gemini>", "Order total"},
+		{"Distinct Values and Null Count names", "Distinct Values: 3 | Null Count: 1 | Status of the order", "Status of the order"},
+		{"Stats only", "Distinct: 12 | Nulls: 0", ""},
+		{"Prose mentioning a stat is kept", "Distinct: 3 per customer | Nulls: none expected", "Distinct: 3 per customer | Nulls: none expected"},
+		{"Stat inside a sentence is kept", "Examples: [a, b] are shown in the UI", "Examples: [a, b] are shown in the UI"},
+		{"Prose with pipes is unchanged", "A|B  |C", "A|B  |C"},
+		{"Lowercase is not a tool stat", "distinct: 12", "distinct: 12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripMetadataAndStats(tt.comment); got != tt.want {
+				t.Errorf("StripMetadataAndStats(%q) = %q, want %q", tt.comment, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExampleQueryLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleSize int
+		want       int
+	}{
+		{"Unset uses default", 0, DefaultExampleCount},
+		{"Smaller than default uses default", 2, DefaultExampleCount},
+		{"Wider sample", 50, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExampleQueryLimit(tt.sampleSize); got != tt.want {
+				t.Errorf("ExampleQueryLimit(%d) = %d, want %d", tt.sampleSize, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleWithoutDistinct(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxDistinct   int64
+		distinctCount int64
+		want          bool
+	}{
+		{"Guard disabled", 0, 1000000, false},
+		{"Above the threshold", 1000, 1001, true},
+		{"At the threshold", 1000, 1000, false},
+		{"Unknown count", 1000, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &DB{Config: config.DatabaseConfig{MaxDistinctForExamples: tt.maxDistinct}}
+			if got := SampleWithoutDistinct(db, tt.distinctCount); got != tt.want {
+				t.Errorf("SampleWithoutDistinct(%d) with threshold %d = %v, want %v", tt.distinctCount, tt.maxDistinct, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueValues(t *testing.T) {
+	got := UniqueValues([]string{"b", "a", "b", "c", "a"})
+	if want := []string{"b", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UniqueValues() = %v, want %v", got, want)
+	}
+}
+
+func TestSelectRepresentativeValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		n      int
+		want   []string
+	}{
+		{"Fewer values than requested are all kept, sorted", []string{"b", "a"}, 3, []string{"a", "b"}},
+		{"Exactly n values are all kept, sorted", []string{"c", "a", "b"}, 3, []string{"a", "b", "c"}},
+		{"Spread across sorted sample", []string{"j", "a", "e", "c", "h", "b", "g", "d", "i", "f"}, 3, []string{"a", "e", "j"}},
+		{"Spread includes both ends", []string{"10", "11", "12", "13", "14"}, 2, []string{"10", "14"}},
+		{"Single value picks the median", []string{"c", "a", "b", "e", "d"}, 1, []string{"c"}},
+		{"Zero requested", []string{"a", "b"}, 0, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectRepresentativeValues(tt.values, tt.n)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") || len(got) != len(tt.want) {
+				t.Errorf("SelectRepresentativeValues(%v, %d) = %v, want %v", tt.values, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectRepresentativeValuesDoesNotReorderInput(t *testing.T) {
+	values := []string{"z", "m", "a", "q", "b"}
+	SelectRepresentativeValues(values, 3)
+	if strings.Join(values, ",") != "z,m,a,q,b" {
+		t.Errorf("SelectRepresentativeValues() modified its input: %v", values)
+	}
+}
@@ -0,0 +1,73 @@
+package enricher
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CommentDifference is a table or column whose comment differs between two databases.
+// Column is empty for table comments; an empty comment means the object has none.
+type CommentDifference struct {
+	Table         string `json:"table"`
+	Column        string `json:"column"`
+	SourceComment string `json:"source_comment"`
+	TargetComment string `json:"target_comment"`
+}
+
+// CompareComments returns the tables and columns whose comments differ between the
+// comments retrieved from a source and a target database, ordered like GetComments.
+// Surrounding whitespace is ignored.
+func CompareComments(source, target []*ColumnComment) []*CommentDifference {
+	type key struct{ table, column string }
+	targetComments := make(map[key]string, len(target))
+	for _, c := range target {
+		targetComments[key{c.Table, c.Column}] = strings.TrimSpace(c.Comment)
+	}
+
+	var differences []*CommentDifference
+	for _, c := range source {
+		k := key{c.Table, c.Column}
+		sourceComment := strings.TrimSpace(c.Comment)
+		targetComment := targetComments[k]
+		delete(targetComments, k)
+		if sourceComment != targetComment {
+			differences = append(differences, &CommentDifference{Table: c.Table, Column: c.Column, SourceComment: sourceComment, TargetComment: targetComment})
+		}
+	}
+	for k, targetComment := range targetComments {
+		if targetComment != "" {
+			differences = append(differences, &CommentDifference{Table: k.table, Column: k.column, TargetComment: targetComment})
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		if differences[i].Table != differences[j].Table {
+			return differences[i].Table < differences[j].Table
+		}
+		return differences[i].Column < differences[j].Column
+	})
+	return differences
+}
+
+// FormatCommentDifferencesAsText renders differences as a diff, one object per block,
+// with the source comment on a "-" line and the target comment on a "+" line.
+func FormatCommentDifferencesAsText(differences []*CommentDifference, sourceName, targetName string) string {
+	if len(differences) == 0 {
+		return fmt.Sprintf("No comment differences between %s and %s.\n", sourceName, targetName)
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", sourceName, targetName))
+	for _, d := range differences {
+		buffer.WriteString("\n")
+		if d.Column == "" {
+			buffer.WriteString(fmt.Sprintf("%s (table)\n", d.Table))
+		} else {
+			buffer.WriteString(fmt.Sprintf("%s.%s\n", d.Table, d.Column))
+		}
+		buffer.WriteString(fmt.Sprintf("- %s\n", displayComment(d.SourceComment)))
+		buffer.WriteString(fmt.Sprintf("+ %s\n", displayComment(d.TargetComment)))
+	}
+	return buffer.String()
+}
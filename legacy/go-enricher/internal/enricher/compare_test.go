@@ -0,0 +1,67 @@
+package enricher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+func TestCompareCommentsAcrossDatabases(t *testing.T) {
+	columns := []database.ColumnInfo{
+		{Name: "id", DataType: "int"},
+		{Name: "status", DataType: "text"},
+		{Name: "total", DataType: "int"},
+	}
+
+	staging := &MockDBAdapter{}
+	staging.On("ListTables").Return([]string{"orders"}, nil)
+	staging.On("GetTableComment", "orders").Return("Customer orders", nil)
+	staging.On("ListColumns", "orders").Return(columns, nil)
+	staging.On("GetAllColumnComments", "orders").Return(map[string]string{
+		"id":     "Order identifier",
+		"status": "Lifecycle state <gemini>Examples: ['open']</gemini>",
+	}, nil)
+
+	prod := &MockDBAdapter{}
+	prod.On("ListTables").Return([]string{"orders"}, nil)
+	prod.On("GetTableComment", "orders").Return("Customer orders ", nil)
+	prod.On("ListColumns", "orders").Return(columns, nil)
+	prod.On("GetAllColumnComments", "orders").Return(map[string]string{
+		"id":    "Order identifier",
+		"total": "Order total in cents",
+	}, nil)
+
+	ctx := context.Background()
+	source, err := NewService(staging, nil, Config{}).GetComments(ctx, GetCommentsParams{})
+	assert.NoError(t, err)
+	target, err := NewService(prod, nil, Config{}).GetComments(ctx, GetCommentsParams{})
+	assert.NoError(t, err)
+
+	differences := CompareComments(source, target)
+
+	assert.Equal(t, []*CommentDifference{
+		{Table: "orders", Column: "status", SourceComment: "Lifecycle state <gemini>Examples: ['open']</gemini>"},
+		{Table: "orders", Column: "total", TargetComment: "Order total in cents"},
+	}, differences)
+	assert.Equal(t, `--- staging
++++ prod
+
+orders.status
+- Lifecycle state <gemini>Examples: ['open']</gemini>
++ (no comment)
+
+orders.total
+- (no comment)
++ Order total in cents
+`, FormatCommentDifferencesAsText(differences, "staging", "prod"))
+}
+
+func TestCompareCommentsWithoutDifferences(t *testing.T) {
+	comments := []*ColumnComment{{Table: "orders", Comment: "Customer orders"}}
+	differences := CompareComments(comments, comments)
+	assert.Empty(t, differences)
+	assert.Equal(t, "No comment differences between staging and prod.\n", FormatCommentDifferencesAsText(differences, "staging", "prod"))
+}
@@ -0,0 +1,58 @@
+package enricher
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// sharedDescriptions lets columns that share a name and data type, such as
+// created_at across many tables, reuse one LLM-generated description. The first
+// column of a group makes the call; the others wait for its result. Columns are only
+// grouped when their description context is the same too, so a column with its own
+// context files is still described on its own.
+type sharedDescriptions struct {
+	mu      sync.Mutex
+	results map[string]*sharedDescription
+}
+
+type sharedDescription struct {
+	done        chan struct{}
+	description string
+	err         error
+}
+
+func newSharedDescriptions() *sharedDescriptions {
+	return &sharedDescriptions{results: make(map[string]*sharedDescription)}
+}
+
+// descriptionGroupKey identifies the columns that can share a description.
+func descriptionGroupKey(column, dataType string) string {
+	return strings.ToLower(column) + "\x00" + strings.ToLower(dataType)
+}
+
+// describe returns the description for the group of key and knowledgeContext,
+// calling generate only if no other column of the group has. shared reports whether
+// the description came from another column.
+func (d *sharedDescriptions) describe(ctx context.Context, key, knowledgeContext string, generate func() (string, error)) (description string, shared bool, err error) {
+	key += "\x00" + knowledgeContext
+	d.mu.Lock()
+	result, ok := d.results[key]
+	if !ok {
+		result = &sharedDescription{done: make(chan struct{})}
+		d.results[key] = result
+	}
+	d.mu.Unlock()
+
+	if !ok {
+		result.description, result.err = generate()
+		close(result.done)
+		return result.description, false, result.err
+	}
+	select {
+	case <-result.done:
+	case <-ctx.Done():
+		return "", true, ctx.Err()
+	}
+	return result.description, true, result.err
+}
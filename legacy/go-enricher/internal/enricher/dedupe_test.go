@@ -0,0 +1,96 @@
+package enricher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+func TestCollectMetadataDedupesDescriptions(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	llm := &fakeLLMClient{columnDescriptions: map[string]string{
+		"created_at": "When the row was created",
+		"id":         "Row identifier",
+	}}
+	service := NewService(mockAdapter, llm, Config{DedupeDescriptions: true})
+
+	tables := []string{"customers", "invoices", "orders"}
+	mockAdapter.On("ListTables").Return(tables, nil)
+	for _, table := range tables {
+		mockAdapter.On("GetAllColumnComments", table).Return(map[string]string{}, nil)
+	}
+	mockAdapter.On("ListColumns", "customers").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "integer"},
+		{Name: "created_at", DataType: "timestamp"},
+	}, nil)
+	mockAdapter.On("ListColumns", "invoices").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "uuid"},
+		{Name: "created_at", DataType: "timestamp"},
+	}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "integer"},
+		{Name: "created_at", DataType: "TIMESTAMP"},
+	}, nil)
+
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+		Enrichments:       map[string]bool{"description": true},
+		AdditionalContext: "shop docs",
+	})
+
+	assert.NoError(t, err)
+	// 3 table descriptions, one for created_at, one for integer ids and one for the uuid id.
+	assert.Equal(t, 6, llm.singleCalls)
+	for _, col := range snapshot.Columns {
+		if col.Column == "id" {
+			assert.Equal(t, "Row identifier", col.Description, col.Table)
+		} else {
+			assert.Equal(t, "When the row was created", col.Description, col.Table)
+		}
+	}
+	assert.Len(t, snapshot.Columns, 6)
+}
+
+func TestSharedDescriptionsKeepsContextsApart(t *testing.T) {
+	shared := newSharedDescriptions()
+	key := descriptionGroupKey("status", "text")
+	calls := 0
+	generate := func(desc string) func() (string, error) {
+		return func() (string, error) {
+			calls++
+			return desc, nil
+		}
+	}
+
+	first, wasShared, err := shared.describe(context.Background(), key, "orders docs", generate("Order status"))
+	assert.NoError(t, err)
+	assert.False(t, wasShared)
+	assert.Equal(t, "Order status", first)
+
+	again, wasShared, err := shared.describe(context.Background(), key, "orders docs", generate("unused"))
+	assert.NoError(t, err)
+	assert.True(t, wasShared)
+	assert.Equal(t, "Order status", again)
+
+	other, wasShared, err := shared.describe(context.Background(), key, "tickets docs", generate("Ticket status"))
+	assert.NoError(t, err)
+	assert.False(t, wasShared)
+	assert.Equal(t, "Ticket status", other)
+	assert.Equal(t, 2, calls)
+}
+
+func TestEstimateLLMUsageWithDedupe(t *testing.T) {
+	mockDB := &MockDBAdapter{}
+	mockDB.On("ListTables").Return([]string{"customers", "orders"}, nil)
+	mockDB.On("ListColumns", "customers").Return([]database.ColumnInfo{{Name: "created_at", DataType: "timestamp"}, {Name: "id", DataType: "integer"}}, nil)
+	mockDB.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "created_at", DataType: "timestamp"}, {Name: "id", DataType: "uuid"}}, nil)
+	params := GenerateSQLParams{Enrichments: map[string]bool{"description": true}, AdditionalContext: "docs"}
+
+	est, err := NewService(mockDB, nil, Config{DedupeDescriptions: true}).EstimateLLMUsage(context.Background(), params)
+	assert.NoError(t, err)
+
+	// 2 table descriptions, one for created_at and one per id type.
+	assert.Equal(t, 5, est.DescriptionCalls)
+}
@@ -3,26 +3,55 @@ package enricher
 import (
 	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"path"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/genai"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/utils"
 )
 
 type Service struct {
 	dbAdapter database.DBAdapter
 	llmClient genai.LLMClient
 	config    Config
+
+	columnsMu sync.Mutex
+	columns   map[string][]database.ColumnInfo // ListColumns results by table, for the life of the Service.
+
+	// collected, if set, is called with each column CollectMetadata adds to its
+	// snapshot, so tests can interrupt a run at a known point.
+	collected func(table, column string)
 }
 
 type Config struct {
-	MaskPII bool
+	MaskPII                     bool
+	SkipEmptyTables             bool
+	BatchDescriptions           bool          // Describe all columns of a table with one LLM call.
+	SlowQueryWarn               time.Duration // Warn about columns whose metadata queries take longer; 0 disables the warning.
+	MaxDescriptionLength        int           // Truncate descriptions to this many characters; 0 means no limit.
+	DedupeDescriptions          bool          // Describe columns sharing a name, data type and context with one LLM call.
+	PreserveExistingDescription bool          // Skip LLM descriptions for columns whose comment has user text outside the <gemini> block.
+	StatsCache                  *StatsCache   // If set, serves statistics of tables unchanged since they were cached, and caches new ones.
+	MaxConcurrency              int           // Limit how many tables GetComments reads at once; 0 means no limit.
+	SkipDescriptionPatterns     []string      // Lower-cased globs such as "is_*"; matching columns get no LLM description.
+	HumanizeNames               bool          // Describe columns that get no other description with their humanized name.
+	StrictDescriptions          bool          // Fail the run for columns that get no requested description instead of leaving it out.
+	DescribeColumnRelationships bool          // Ask for relationships between columns in the table description prompt.
+	Explain                     bool          // Record a ColumnExplanation of what was done with each column, and why.
+	CommentIncludeDataType      bool          // Comments start with the data type (--comment-include-datatype), for Explain.
 }
+
 func NewService(db database.DBAdapter, llm genai.LLMClient, cfg Config) *Service {
 	return &Service{
 		dbAdapter: db,
@@ -31,79 +60,227 @@ func NewService(db database.DBAdapter, llm genai.LLMClient, cfg Config) *Service
 	}
 }
 
+// workerSlots returns a semaphore with Config.MaxConcurrency slots, or nil when the
+// number of concurrent table workers is not limited.
+func (s *Service) workerSlots() chan struct{} {
+	if s.config.MaxConcurrency <= 0 {
+		return nil
+	}
+	return make(chan struct{}, s.config.MaxConcurrency)
+}
+
+// listColumns returns the columns of a table, asking the adapter only the first
+// time a table is seen. Failed lookups are not cached.
+func (s *Service) listColumns(ctx context.Context, table string) ([]database.ColumnInfo, error) {
+	s.columnsMu.Lock()
+	columns, ok := s.columns[table]
+	s.columnsMu.Unlock()
+	if ok {
+		return columns, nil
+	}
+
+	columns, err := s.dbAdapter.ListColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	s.columnsMu.Lock()
+	if s.columns == nil {
+		s.columns = make(map[string][]database.ColumnInfo)
+	}
+	s.columns[table] = columns
+	s.columnsMu.Unlock()
+	return columns, nil
+}
+
 type GenerateSQLParams struct {
 	TableFilters      map[string][]string
+	ColumnEnrichments map[string]map[string][]string // Per-column --tables hints, keyed like TableFilters; they replace Enrichments for that column.
 	Enrichments       map[string]bool
 	AdditionalContext string
+	ContextDir        string            // Directory of <table>.md and <table>.<column>.md context files.
+	Glossary          map[string]string // Definitions keyed by utils.NormalizeGlossaryTerm, prepended to matching column descriptions.
+	SanitizeContext   bool              // Redact likely secrets from the ContextDir files before they reach the LLM.
 }
 
 func (s *Service) GenerateCommentSQLs(ctx context.Context, params GenerateSQLParams) ([]string, error) {
 	startTime := time.Now()
 	log.Println("INFO: Starting metadata collection and SQL comment generation...")
 
-	tables, err := s.dbAdapter.ListTables()
+	snapshot, err := s.CollectMetadata(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	allSQLs := s.GenerateSQLFromSnapshot(snapshot)
+
+	log.Printf("INFO: SQL comment generation completed in %s. Generated %d statements.", time.Since(startTime), len(allSQLs))
+	return allSQLs, nil
+}
+
+// CollectMetadata is the collection phase of GenerateCommentSQLs: it queries the
+// database and the LLM for every filtered table and column and returns the result
+// as a snapshot, without generating any SQL. If ctx is cancelled, no further tables
+// or columns are started, and the ones finished before are returned with an
+// *ErrCancelled, so an interrupted run can still write its partial results.
+func (s *Service) CollectMetadata(ctx context.Context, params GenerateSQLParams) (*MetadataSnapshot, error) {
+	snapshot := &MetadataSnapshot{
+		Version:     SnapshotVersion,
+		Enrichments: params.Enrichments,
+		Tables:      []*TableMetadata{},
+		Columns:     []*ColumnMetadata{},
+	}
+
+	tables, err := s.dbAdapter.ListTables(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tables: %w", err)
 	}
 
+	var schema string
+	params.TableFilters, schema = s.unqualifiedTableFilters(params.TableFilters)
+	params.ColumnEnrichments = unqualifyEntries(params.ColumnEnrichments, schema)
 	filteredTables := filterTables(tables, params.TableFilters)
 	if len(filteredTables) == 0 {
-		log.Println("INFO: No tables match the provided filters (--tables).")
-		return []string{}, nil
+		log.Println(noTablesMessage(tables, "comment generation"))
+		return snapshot, nil
 	}
 
-	var orderedSQLs []OrderedSQL
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	errorChannel := make(chan error, len(filteredTables)*5) // Buffer size can be adjusted
 
 	log.Printf("INFO: Processing %d filtered table(s)...", len(filteredTables))
 
+	described := newTableDescriptions(filteredTables)
+	var shared *sharedDescriptions
+	if s.config.DedupeDescriptions {
+		shared = newSharedDescriptions()
+	}
+
 	for _, tableName := range filteredTables {
 		wg.Add(1)
 		go func(table string) {
 			defer wg.Done()
+			defer described.publish(table, "") // Unblocks waiting columns if the table is skipped.
+			if ctx.Err() != nil {
+				return
+			}
 			tableLogPrefix := fmt.Sprintf("Table[%s]", table)
 
+			columnInfos, listColErr := s.listColumns(ctx, table)
+			if listColErr != nil {
+				log.Printf("ERROR: %s Failed to list columns: %v", tableLogPrefix, listColErr)
+				errorChannel <- fmt.Errorf("%s list columns: %w", tableLogPrefix, listColErr)
+				return
+			}
+			if len(columnInfos) == 0 {
+				if s.config.SkipEmptyTables {
+					log.Printf("WARN: %s No accessible columns found (check SELECT privileges). Skipping table (--skip-empty-tables).", tableLogPrefix)
+					return
+				}
+				log.Printf("WARN: %s No accessible columns found (check SELECT privileges). Only the table comment will be generated.", tableLogPrefix)
+			} else {
+				s.preloadColumnComments(ctx, table, tableLogPrefix)
+			}
+			if s.config.StatsCache != nil {
+				s.refreshTableVersion(ctx, table, tableLogPrefix)
+			}
+
 			tableMetadata := &TableMetadata{Table: table}
 			if s.llmClient != nil && isEnrichmentRequested("description", params.Enrichments) {
-				desc, descErr := s.llmClient.GenerateDescription(ctx, "table", table, "", params.AdditionalContext)
+				objectType, tableContext := "table", descriptionContext(params, table)
+				if relationshipsContext := columnRelationshipsContext(table, columnInfos, tableContext); s.config.DescribeColumnRelationships && relationshipsContext != "" {
+					objectType, tableContext = "table_with_relationships", relationshipsContext
+				}
+				desc, descErr := s.llmClient.GenerateDescription(ctx, objectType, table, "", tableContext)
 				if descErr != nil {
 					log.Printf("WARN: %s Failed to generate table description via LLM: %v", tableLogPrefix, descErr)
-				} else if desc != "" {
-					tableMetadata.Description = desc
+					desc = ""
 				}
+				// The limit is for the description; the relationships are kept whole.
+				desc, relationships := splitColumnRelationships(desc)
+				tableMetadata.Description = withColumnRelationships(s.limitDescription(tableLogPrefix, desc), relationships)
 			}
-
-			tableCommentData := &database.TableCommentData{
-				TableName:   tableMetadata.Table,
-				Description: tableMetadata.Description,
-			}
-			tableSQL, genTableErr := s.dbAdapter.GenerateTableCommentSQL(tableCommentData, params.Enrichments)
-			if genTableErr != nil {
-				log.Printf("WARN: %s Failed to generate table comment SQL: %v", tableLogPrefix, genTableErr)
-			} else if tableSQL != "" {
-				mu.Lock()
-				orderedSQLs = append(orderedSQLs, OrderedSQL{SQL: tableSQL, Table: table, IsTableComment: true})
-				mu.Unlock()
+			described.publish(table, tableMetadata.Description)
+			if ctx.Err() != nil {
+				return // The description call may have been cut short.
 			}
 
-			columnInfos, listColErr := s.dbAdapter.ListColumns(table)
-			if listColErr != nil {
-				log.Printf("ERROR: %s Failed to list columns: %v", tableLogPrefix, listColErr)
-				errorChannel <- fmt.Errorf("%s list columns: %w", tableLogPrefix, listColErr)
-				return
-			}
+			mu.Lock()
+			snapshot.Tables = append(snapshot.Tables, tableMetadata)
+			mu.Unlock()
+
 			filteredColumnInfos := filterColumns(table, columnInfos, params.TableFilters)
+			preserved := s.columnsWithUserDescriptions(ctx, table, filteredColumnInfos, tableLogPrefix)
+			conventional := s.columnsMatchingSkipPatterns(filteredColumnInfos, params.Glossary)
+			batchedDescriptions := s.generateBatchedDescriptions(ctx, table, tableMetadata.Description, withoutColumns(withoutColumns(filteredColumnInfos, preserved), conventional), params)
 
 			var colWg sync.WaitGroup
 			for _, colInfo := range filteredColumnInfos {
 				colWg.Add(1)
 				go func(ci database.ColumnInfo) {
 					defer colWg.Done()
+					if ctx.Err() != nil {
+						return
+					}
 					colLogPrefix := fmt.Sprintf("Column[%s.%s]", table, ci.Name)
+					explanation := s.explain(table, ci.Name)
+					enrichments, hinted := columnEnrichments(table, ci.Name, params)
+					if hinted {
+						explanation.because("enrichments set by its --tables hint")
+					}
+					if ci.StatsUnsupported {
+						log.Printf("INFO: %s Values of type %s cannot be sampled or counted meaningfully. Skipping examples, distinct values and null count.", colLogPrefix, ci.DataType)
+						explanation.because("type unsupported: values of type %s cannot be sampled or counted", ci.DataType)
+						enrichments, hinted = withoutStatistics(enrichments), true
+					}
+					if preserved[ci.Name] && isEnrichmentRequested("description", enrichments) {
+						log.Printf("INFO: %s Keeping the existing description (--preserve-existing-description). Only metadata is generated.", colLogPrefix)
+						explanation.because("existing description kept (--preserve-existing-description)")
+						enrichments, hinted = withoutEnrichments(enrichments, "description"), true
+					}
+					if conventional[ci.Name] && isEnrichmentRequested("description", enrichments) {
+						log.Printf("INFO: %s Name matches --skip-description-patterns. Skipping its LLM description.", colLogPrefix)
+						explanation.because("no LLM description: name matches --skip-description-patterns")
+						enrichments, hinted = withoutEnrichments(enrichments, "description"), true
+					}
 
-					columnMetadata, colMetaErr := s.collectColumnDBMetadata(ctx, table, ci, params.Enrichments)
+					// A glossary definition is a description that needs no LLM call.
+					definition := params.Glossary[utils.NormalizeGlossaryTerm(ci.Name)]
+					var descContext string
+					descriptionAvailable := definition != "" || s.config.HumanizeNames
+					if s.llmClient != nil && batchedDescriptions != nil {
+						descriptionAvailable = descriptionAvailable || batchedDescriptions[ci.Name] != ""
+					} else if s.llmClient != nil && isEnrichmentRequested("description", enrichments) {
+						descContext = descriptionContext(params, table, ci.Name)
+						if shared == nil {
+							// Shared descriptions serve columns of several tables, so they leave the table out.
+							descContext = withTableDescription(descContext, table, tableMetadata.Description)
+						}
+						descriptionAvailable = descriptionAvailable || descContext != ""
+						if descContext == "" {
+							explanation.because("no LLM description: no --context or --context-dir context for it")
+						}
+					} else if s.llmClient == nil && isEnrichmentRequested("description", enrichments) {
+						explanation.because("no LLM description: no Gemini API key")
+					}
+					if !canEnrichColumn(enrichments, descriptionAvailable) && !(isJSONColumn(ci) && isEnrichmentRequested("json_keys", enrichments)) {
+						log.Printf("INFO: %s None of the requested enrichments can add to this column's comment. Skipping its queries and LLM calls.", colLogPrefix)
+						columnMetadata := &ColumnMetadata{Table: table, Column: ci.Name, DataType: ci.DataType, Explanation: explanation}
+						explanation.skip("none of the requested enrichments can add to its comment")
+						explanation.decidePII(piiNotChecked)
+						if err := s.checkStrictDescription(columnMetadata, enrichments); err != nil {
+							log.Printf("ERROR: %s %v", colLogPrefix, err)
+							errorChannel <- fmt.Errorf("%s %w", colLogPrefix, err)
+						}
+						if hinted {
+							columnMetadata.Enrichments = enrichments
+						}
+						mu.Lock()
+						snapshot.Columns = append(snapshot.Columns, columnMetadata)
+						mu.Unlock()
+						return
+					}
+
+					columnMetadata, colMetaErr := s.collectColumnDBMetadata(ctx, table, ci, enrichments)
 					if colMetaErr != nil {
 						log.Printf("ERROR: %s Failed to collect DB metadata: %v", colLogPrefix, colMetaErr)
 						errorChannel <- fmt.Errorf("%s collect DB meta: %w", colLogPrefix, colMetaErr)
@@ -111,22 +288,36 @@ func (s *Service) GenerateCommentSQLs(ctx context.Context, params GenerateSQLPar
 					}
 					if s.llmClient != nil {
 						// PII Check / Example Synthesis
-						if isEnrichmentRequested("examples", params.Enrichments) && len(columnMetadata.ExampleValues) > 0 {
+						if isEnrichmentRequested("examples", enrichments) && len(columnMetadata.ExampleValues) > 0 {
 						processedExamples, wasSynthesized, piiErr := s.llmClient.GenerateSyntheticExamples(ctx, ci.Name, table, ci.DataType, columnMetadata.ExampleValues, s.config.MaskPII)
 
+							if s.config.MaskPII {
+								explanation.calledLLM()
+							}
 							if piiErr != nil {
 								log.Printf("WARN: %s Failed to process example values with LLM: %v. Using original examples.", colLogPrefix, piiErr)
+								explanation.decidePII(piiFailed)
 							} else {
 								if wasSynthesized {
 									log.Printf("INFO: %s Used synthetic examples (PII detected/suspected).", colLogPrefix)
+									explanation.decidePII(piiSynthesized)
+								} else if s.config.MaskPII {
+									explanation.decidePII(piiKept)
+								} else {
+									explanation.decidePII(piiNoMasking)
 								}
 								columnMetadata.ExampleValues = processedExamples
 							}
 						}
 
 						// Description Generation
-						if isEnrichmentRequested("description", params.Enrichments) {
-							desc, descErr := s.llmClient.GenerateDescription(ctx, "column", ci.Name, table, params.AdditionalContext)
+						wantsDescription := isEnrichmentRequested("description", enrichments)
+						if wantsDescription && batchedDescriptions != nil {
+							columnMetadata.Description = batchedDescriptions[ci.Name]
+							explanation.calledLLM()
+						} else if wantsDescription && descContext != "" {
+							explanation.calledLLM()
+							desc, descErr := s.describeColumn(ctx, shared, table, ci, descContext, colLogPrefix)
 							if descErr != nil {
 								log.Printf("WARN: %s Failed to generate column description via LLM: %v", colLogPrefix, descErr)
 							} else if desc != "" {
@@ -135,23 +326,38 @@ func (s *Service) GenerateCommentSQLs(ctx context.Context, params GenerateSQLPar
 						}
 					}
 
-					commentData := &database.CommentData{
-						TableName:      columnMetadata.Table,
-						ColumnName:     columnMetadata.Column,
-						ColumnDataType: columnMetadata.DataType,
-						ExampleValues:  columnMetadata.ExampleValues,
-						DistinctCount:  columnMetadata.DistinctCount,
-						NullCount:      columnMetadata.NullCount,
-						Description:    columnMetadata.Description,
-						ForeignKeys:    columnMetadata.ForeignKeys,
+					if definition != "" && isEnrichmentRequested("description", enrichments) {
+						columnMetadata.Description = strings.TrimSpace(definition + " " + columnMetadata.Description)
+						explanation.because("description starts with its --glossary definition")
 					}
-					sql, genErr := s.dbAdapter.GenerateCommentSQL(commentData, params.Enrichments)
-					if genErr != nil {
-						log.Printf("WARN: %s Failed to generate comment SQL: %v", colLogPrefix, genErr)
-					} else if sql != "" {
-						mu.Lock()
-						orderedSQLs = append(orderedSQLs, OrderedSQL{SQL: sql, Table: table, Column: ci.Name, IsTableComment: false})
-						mu.Unlock()
+					if columnMetadata.Description == "" && s.config.HumanizeNames && isEnrichmentRequested("description", enrichments) {
+						columnMetadata.Description = humanizeName(ci.Name)
+						explanation.because("description humanized from its name (--humanize-names)")
+					}
+					columnMetadata.Description = s.limitDescription(colLogPrefix, columnMetadata.Description)
+					if err := s.checkStrictDescription(columnMetadata, enrichments); err != nil {
+						log.Printf("ERROR: %s %v", colLogPrefix, err)
+						errorChannel <- fmt.Errorf("%s %w", colLogPrefix, err)
+					}
+
+					if len(columnMetadata.ForeignKeys) > 0 {
+						s.describeReferences(ctx, described, columnMetadata.ForeignKeys)
+					}
+					columnMetadata.Custom = s.collectCustomEnrichments(ctx, table, ci.Name, enrichments)
+					if hinted {
+						columnMetadata.Enrichments = enrichments
+					}
+					explanation.finish(columnMetadata, enrichments, s.config.CommentIncludeDataType)
+					columnMetadata.Explanation = explanation
+					if ctx.Err() != nil {
+						return // Its LLM calls may have been cut short; leave it to a rerun.
+					}
+
+					mu.Lock()
+					snapshot.Columns = append(snapshot.Columns, columnMetadata)
+					mu.Unlock()
+					if s.collected != nil {
+						s.collected(table, ci.Name)
 					}
 				}(colInfo)
 			}
@@ -163,24 +369,244 @@ func (s *Service) GenerateCommentSQLs(ctx context.Context, params GenerateSQLPar
 	wg.Wait()
 	close(errorChannel)
 
+	if ctx.Err() != nil {
+		// Failures after the interrupt are mostly cancelled calls; the partial
+		// snapshot is what matters now.
+		snapshot.sort()
+		return snapshot, &ErrCancelled{Msg: fmt.Sprintf("metadata collection interrupted after %d column(s)", len(snapshot.Columns)), Err: ctx.Err()}
+	}
+
 	var allErrors []error
 	for err := range errorChannel {
 		allErrors = append(allErrors, err)
 	}
 	if len(allErrors) > 0 {
-		errorMessages := make([]string, len(allErrors))
-		for i, e := range allErrors {
-			errorMessages[i] = e.Error()
-		}
-		return nil, fmt.Errorf("encountered %d error(s) during SQL generation:\n- %s",
-			len(allErrors), strings.Join(errorMessages, "\n- "))
+		return nil, &ErrPartialEnrichment{Operation: "SQL generation", Errs: allErrors}
 	}
 
+	snapshot.sort()
+	return snapshot, nil
+}
+
+// GenerateSQLFromSnapshot is the generation phase of GenerateCommentSQLs: it turns a
+// snapshot into comment SQL using the enrichments recorded in it. Only existing
+// comments are read from the database; statements that fail to generate are
+// logged and skipped.
+func (s *Service) GenerateSQLFromSnapshot(snapshot *MetadataSnapshot) []string {
+	var orderedSQLs []OrderedSQL
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for _, tableMetadata := range snapshot.Tables {
+		wg.Add(1)
+		go func(tm *TableMetadata) {
+			defer wg.Done()
+			tableCommentData := &database.TableCommentData{
+				TableName:   tm.Table,
+				Description: tm.Description,
+			}
+			tableSQL, genTableErr := s.dbAdapter.GenerateTableCommentSQL(tableCommentData, snapshot.Enrichments)
+			if genTableErr != nil {
+				log.Printf("WARN: Table[%s] Failed to generate table comment SQL: %v", tm.Table, genTableErr)
+			} else if tableSQL != "" {
+				mu.Lock()
+				orderedSQLs = append(orderedSQLs, OrderedSQL{SQL: tableSQL, Table: tm.Table, IsTableComment: true})
+				mu.Unlock()
+			}
+		}(tableMetadata)
+	}
+
+	for _, columnMetadata := range snapshot.Columns {
+		wg.Add(1)
+		go func(cm *ColumnMetadata) {
+			defer wg.Done()
+			commentData := &database.CommentData{
+				TableName:      cm.Table,
+				ColumnName:     cm.Column,
+				ColumnDataType: cm.DataType,
+				ExampleValues:  cm.ExampleValues,
+				DistinctCount:  cm.DistinctCount,
+				NullCount:      cm.NullCount,
+				Average:        cm.Average,
+				Description:    cm.Description,
+				ForeignKeys:    cm.ForeignKeys,
+				Custom:         cm.Custom,
+				ProfiledAt:     cm.ProfiledAt,
+				JSONKeys:       cm.JSONKeys,
+			}
+			enrichments := snapshot.Enrichments
+			if cm.Enrichments != nil {
+				enrichments = cm.Enrichments
+			}
+			sql, genErr := s.dbAdapter.GenerateCommentSQL(commentData, enrichments)
+			if genErr != nil {
+				log.Printf("WARN: Column[%s.%s] Failed to generate comment SQL: %v", cm.Table, cm.Column, genErr)
+				cm.Explanation.skip("failed to generate its comment SQL: %v", genErr)
+			} else if sql == "" {
+				if cm.Explanation != nil && !cm.Explanation.Skipped {
+					cm.Explanation.skip("no change: no comment statement was generated")
+				}
+			} else {
+				mu.Lock()
+				orderedSQLs = append(orderedSQLs, OrderedSQL{SQL: sql, Table: cm.Table, Column: cm.Column, IsTableComment: false})
+				mu.Unlock()
+			}
+		}(columnMetadata)
+	}
+
+	wg.Wait()
+
 	sortSQLs(orderedSQLs)
-	allSQLs := extractSQL(orderedSQLs)
+	return extractSQL(orderedSQLs)
+}
 
-	log.Printf("INFO: SQL comment generation completed in %s. Generated %d statements.", time.Since(startTime), len(allSQLs))
-	return allSQLs, nil
+// Wide tables are described in chunks so that a single prompt never lists more than
+// maxColumnsPerDescriptionBatch columns, and the --context-dir files gathered for one
+// prompt are capped at maxScopedContextBytes.
+const (
+	maxColumnsPerDescriptionBatch = 50
+	maxScopedContextBytes         = 64 * 1024
+)
+
+// generateBatchedDescriptions describes the columns of a table with one LLM call per
+// chunk of maxColumnsPerDescriptionBatch columns when BatchDescriptions is enabled.
+// A nil result means columns are described one call at a time. If the LLM is still
+// rate limited after its retries, the descriptions gathered so far are returned and
+// the remaining columns are left undescribed rather than retried per column.
+func (s *Service) generateBatchedDescriptions(ctx context.Context, table, tableDescription string, columns []database.ColumnInfo, params GenerateSQLParams) map[string]string {
+	if !s.config.BatchDescriptions || s.llmClient == nil || len(columns) == 0 || !isEnrichmentRequested("description", params.Enrichments) {
+		return nil
+	}
+	columnNames := make([]string, len(columns))
+	for i, ci := range columns {
+		columnNames[i] = ci.Name
+	}
+	if len(columnNames) > maxColumnsPerDescriptionBatch {
+		log.Printf("INFO: Table[%s] has %d columns; describing them in batches of %d.", table, len(columnNames), maxColumnsPerDescriptionBatch)
+	}
+	descriptions := make(map[string]string, len(columnNames))
+	for start := 0; start < len(columnNames); start += maxColumnsPerDescriptionBatch {
+		end := start + maxColumnsPerDescriptionBatch
+		if end > len(columnNames) {
+			end = len(columnNames)
+		}
+		chunk := columnNames[start:end]
+		chunkContext := withTableDescription(descriptionContext(params, table, chunk...), table, tableDescription)
+		chunkDescriptions, err := s.llmClient.GenerateColumnDescriptions(ctx, table, chunk, chunkContext)
+		if err != nil {
+			if genai.IsRateLimitError(err) {
+				// One call per column would only make the rate limiting worse.
+				log.Printf("WARN: Table[%s] Batched column descriptions are still rate limited after retries: %v. Leaving %d column(s) undescribed.", table, err, len(columnNames)-start)
+				return descriptions
+			}
+			log.Printf("WARN: Table[%s] Failed to generate batched column descriptions via LLM: %v. Falling back to per-column calls.", table, err)
+			return nil
+		}
+		for name, desc := range chunkDescriptions {
+			descriptions[name] = desc
+		}
+	}
+	return descriptions
+}
+
+// describeColumn generates a column description, sharing it with the other columns
+// of the same name and type when shared is not nil (--dedupe-descriptions).
+func (s *Service) describeColumn(ctx context.Context, shared *sharedDescriptions, table string, ci database.ColumnInfo, descContext, logPrefix string) (string, error) {
+	generate := func() (string, error) {
+		return s.llmClient.GenerateDescription(ctx, "column", ci.Name, table, descContext)
+	}
+	if shared == nil {
+		return generate()
+	}
+	desc, wasShared, err := shared.describe(ctx, descriptionGroupKey(ci.Name, ci.DataType), descContext, generate)
+	if wasShared && err == nil && desc != "" {
+		log.Printf("INFO: %s Reused the description generated for another %s column of type %s (--dedupe-descriptions).", logPrefix, ci.Name, ci.DataType)
+	}
+	return desc, err
+}
+
+// withTableDescription adds the description generated for a table to the knowledge
+// context of its columns, so that their descriptions can build on it. Without
+// context nothing is described, so an empty context stays empty.
+func withTableDescription(knowledgeContext, table, description string) string {
+	if knowledgeContext == "" || description == "" {
+		return knowledgeContext
+	}
+	return fmt.Sprintf("%s\n\nDescription of table %s: %s\n", strings.TrimRight(knowledgeContext, "\n"), table, description)
+}
+
+// columnRelationshipsContext returns the knowledge context for a table description
+// that also notes meaning only emerging from a combination of its columns, such as
+// start_date and end_date forming a range, for --describe-column-relationships: the
+// table's context followed by its columns. Like descriptions, relationships are only
+// looked for in a non-empty knowledge context, so it returns "" without one, or
+// without two columns to relate.
+func columnRelationshipsContext(table string, columns []database.ColumnInfo, knowledgeContext string) string {
+	if knowledgeContext == "" || len(columns) < 2 {
+		return ""
+	}
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = fmt.Sprintf("%s (%s)", c.Name, c.DataType)
+	}
+	return fmt.Sprintf("%s\n\n-- Columns of table %s --\n%s\n", strings.TrimRight(knowledgeContext, "\n"), table, strings.Join(names, ", "))
+}
+
+// splitColumnRelationships splits a table description into the description itself
+// and the column relationships it ends with, if any.
+func splitColumnRelationships(description string) (string, string) {
+	i := strings.Index(description, genai.ColumnRelationshipsPrefix)
+	if i < 0 {
+		return strings.TrimSpace(description), ""
+	}
+	return strings.TrimSpace(description[:i]), strings.TrimSpace(description[i+len(genai.ColumnRelationshipsPrefix):])
+}
+
+// withColumnRelationships appends the column relationships of a table to its
+// description, so they are stored in the table comment.
+func withColumnRelationships(description, relationships string) string {
+	if relationships == "" {
+		return description
+	}
+	relationships = genai.ColumnRelationshipsPrefix + " " + relationships
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return relationships
+	}
+	if !strings.HasSuffix(description, ".") {
+		description += "."
+	}
+	return description + " " + relationships
+}
+
+// descriptionContext returns the knowledge context for describing a table or its
+// columns: the --context files followed by the --context-dir files for the table
+// and each given column. Missing files are skipped, and scoped files that would take
+// the context past maxScopedContextBytes are left out with a warning.
+func descriptionContext(params GenerateSQLParams, table string, columns ...string) string {
+	if params.ContextDir == "" {
+		return params.AdditionalContext
+	}
+	var sb strings.Builder
+	sb.WriteString(params.AdditionalContext)
+	scopedBytes, skipped := 0, 0
+	for _, column := range append([]string{""}, columns...) {
+		scoped, err := utils.ReadScopedContextFile(params.ContextDir, table, column, params.SanitizeContext)
+		if err != nil {
+			log.Printf("WARN: Table[%s] %v. Continuing without it.", table, err)
+			continue
+		}
+		if scopedBytes+len(scoped) > maxScopedContextBytes {
+			skipped++
+			continue
+		}
+		scopedBytes += len(scoped)
+		sb.WriteString(scoped)
+	}
+	if skipped > 0 {
+		log.Printf("WARN: Table[%s] Context files exceed %d bytes; left out %d of them.", table, maxScopedContextBytes, skipped)
+	}
+	return sb.String()
 }
 
 func (s *Service) collectColumnDBMetadata(ctx context.Context, tableName string, colInfo database.ColumnInfo, enrichments map[string]bool) (*ColumnMetadata, error) {
@@ -191,18 +617,30 @@ func (s *Service) collectColumnDBMetadata(ctx context.Context, tableName string,
 		DataType: colInfo.DataType,
 	}
 
+	// Foreign keys are looked up separately, so they alone do not need the statistics query.
+	// Like data_type, the average is only collected when it is requested by name.
 	needsDBQuery := isEnrichmentRequested("examples", enrichments) ||
 		isEnrichmentRequested("distinct_values", enrichments) ||
 		isEnrichmentRequested("null_count", enrichments) ||
-		isEnrichmentRequested("foreign_keys", enrichments)
-
-	if !needsDBQuery {
-		return metadata, nil
-	}
-
-	dbMetadata, err := s.dbAdapter.GetColumnMetadata(tableName, colInfo.Name)
-	if err != nil {
-		return nil, fmt.Errorf("get column DB metadata for %s.%s: %w", tableName, colInfo.Name, err)
+		enrichments["average"]
+
+	dbMetadata := map[string]interface{}{}
+	if cached, profiledAt, ok := s.config.StatsCache.lookup(tableName, colInfo.Name); ok && needsDBQuery {
+		dbMetadata = cached
+		metadata.ProfiledAt = profiledAt
+	} else if needsDBQuery {
+		var err error
+		queryStart := time.Now()
+		dbMetadata, err = s.dbAdapter.GetColumnMetadata(tableName, colInfo.Name)
+		if elapsed := time.Since(queryStart); s.config.SlowQueryWarn > 0 && elapsed > s.config.SlowQueryWarn {
+			log.Printf("WARN: Column[%s.%s] Metadata queries took %s (over --slow-query-warn %s). This is often an unindexed, high-cardinality text column; consider leaving it out with --tables or dropping examples/distinct_values for it.",
+				tableName, colInfo.Name, elapsed.Round(time.Millisecond), s.config.SlowQueryWarn)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get column DB metadata for %s.%s: %w", tableName, colInfo.Name, err)
+		}
+		metadata.ProfiledAt = queryStart.UTC().Truncate(time.Second)
+		s.config.StatsCache.store(tableName, colInfo.Name, dbMetadata, metadata.ProfiledAt)
 	}
 
 	if isEnrichmentRequested("examples", enrichments) {
@@ -227,10 +665,29 @@ func (s *Service) collectColumnDBMetadata(ctx context.Context, tableName string,
 		}
 	}
 
+	if enrichments["average"] {
+		if avgRaw, ok := dbMetadata["Average"]; ok {
+			if avg, okCast := avgRaw.(float64); okCast {
+				metadata.Average = &avg
+			} else {
+				log.Printf("WARN: Column[%s.%s] Unexpected type for Average from DB: %T", tableName, colInfo.Name, avgRaw)
+			}
+		}
+	}
+
+	if isEnrichmentRequested("json_keys", enrichments) && isJSONColumn(colInfo) {
+		keys, err := s.dbAdapter.JSONKeys(ctx, tableName, colInfo.Name)
+		if err != nil {
+			log.Printf("WARN: Column[%s.%s] Failed to get JSON keys: %v", tableName, colInfo.Name, err)
+		} else {
+			metadata.JSONKeys = keys
+		}
+	}
+
 	// Add foreign key collection
 	needsForeignKeys := isEnrichmentRequested("foreign_keys", enrichments)
 	if needsForeignKeys {
-		foreignKeys, fkErr := s.dbAdapter.GetForeignKeys(tableName, colInfo.Name)
+		foreignKeys, fkErr := s.foreignKeys(ctx, tableName, colInfo.Name)
 		if fkErr != nil {
 			log.Printf("WARN: Column[%s.%s] Failed to get foreign keys: %v", tableName, colInfo.Name, fkErr)
 		} else {
@@ -243,29 +700,113 @@ func (s *Service) collectColumnDBMetadata(ctx context.Context, tableName string,
 
 type GenerateDeleteSQLParams struct {
 	TableFilters map[string][]string
+	StripStats   bool   // Also remove statistics earlier versions left outside the <gemini> tags.
+	Scope        string // "tables" or "columns" deletes only comments of that level; empty or "all" deletes both.
 }
 
 func (s *Service) GenerateDeleteCommentSQLs(ctx context.Context, params GenerateDeleteSQLParams) ([]string, error) {
+	changes, err := s.GenerateDeleteCommentChanges(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	allSQLs := make([]string, len(changes))
+	for i, change := range changes {
+		allSQLs[i] = change.SQL
+	}
+	return allSQLs, nil
+}
+
+// GenerateDeleteCommentChanges generates the statements that strip <gemini> tags,
+// paired with each target's comment before and after the strip.
+func (s *Service) GenerateDeleteCommentChanges(ctx context.Context, params GenerateDeleteSQLParams) ([]*CommentChange, error) {
+	rw := commentRewrite{
+		action:    "deletion",
+		tableSQL:  s.dbAdapter.GenerateDeleteTableCommentSQL,
+		columnSQL: s.dbAdapter.GenerateDeleteCommentSQL,
+		rewrite:   database.StripMetadata,
+	}
+	if params.StripStats {
+		rw = commentRewrite{
+			action: "deletion",
+			tableSQL: func(ctx context.Context, table string) (string, error) {
+				return s.dbAdapter.GenerateRewriteTableCommentSQL(ctx, table, database.StripMetadataAndStats)
+			},
+			columnSQL: func(ctx context.Context, table, column string) (string, error) {
+				return s.dbAdapter.GenerateRewriteCommentSQL(ctx, table, column, database.StripMetadataAndStats)
+			},
+			rewrite: database.StripMetadataAndStats,
+		}
+	}
+	switch params.Scope {
+	case "tables":
+		rw.columnSQL = nil
+	case "columns":
+		rw.tableSQL = nil
+	}
+	return s.generateCommentChanges(ctx, params.TableFilters, rw)
+}
+
+type GenerateRepairSQLParams struct {
+	TableFilters map[string][]string
+}
+
+// GenerateRepairCommentChanges generates statements that fix comments whose
+// <gemini> tags were left malformed, for example by an interrupted run.
+func (s *Service) GenerateRepairCommentChanges(ctx context.Context, params GenerateRepairSQLParams) ([]*CommentChange, error) {
+	return s.generateCommentChanges(ctx, params.TableFilters, commentRewrite{
+		action: "repair",
+		tableSQL: func(ctx context.Context, table string) (string, error) {
+			return s.dbAdapter.GenerateRewriteTableCommentSQL(ctx, table, database.RepairComment)
+		},
+		columnSQL: func(ctx context.Context, table, column string) (string, error) {
+			return s.dbAdapter.GenerateRewriteCommentSQL(ctx, table, column, database.RepairComment)
+		},
+		rewrite: database.RepairComment,
+	})
+}
+
+// commentRewrite describes a bulk change to existing comments: the statements for
+// each table and column, and the rewrite they apply, used to report the result.
+// A nil tableSQL or columnSQL leaves the comments of that level alone.
+type commentRewrite struct {
+	action    string // Used in log messages, e.g. "deletion".
+	tableSQL  func(ctx context.Context, table string) (string, error)
+	columnSQL func(ctx context.Context, table, column string) (string, error)
+	rewrite   func(comment string) string
+}
+
+func (rw commentRewrite) change(table, column, before, sql string) *CommentChange {
+	return &CommentChange{
+		Table:  table,
+		Column: column,
+		Before: before,
+		After:  rw.rewrite(before),
+		SQL:    sql,
+	}
+}
+
+func (s *Service) generateCommentChanges(ctx context.Context, tableFilters map[string][]string, rw commentRewrite) ([]*CommentChange, error) {
 	startTime := time.Now()
-	log.Println("INFO: Starting SQL comment deletion generation...")
+	log.Printf("INFO: Starting SQL comment %s generation...", rw.action)
 
-	tables, err := s.dbAdapter.ListTables()
+	tables, err := s.dbAdapter.ListTables(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tables: %w", err)
 	}
 
-	filteredTables := filterTables(tables, params.TableFilters)
+	tableFilters, _ = s.unqualifiedTableFilters(tableFilters)
+	filteredTables := filterTables(tables, tableFilters)
 	if len(filteredTables) == 0 {
-		log.Println("INFO: No tables match the provided filters (--tables) for deletion.")
-		return []string{}, nil
+		log.Println(noTablesMessage(tables, rw.action))
+		return []*CommentChange{}, nil
 	}
 
-	var orderedSQLs []OrderedSQL
+	var changes []*CommentChange
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	errorChannel := make(chan error, len(filteredTables)*5)
 
-	log.Printf("INFO: Processing %d filtered table(s) for deletion...", len(filteredTables))
+	log.Printf("INFO: Processing %d filtered table(s) for %s...", len(filteredTables), rw.action)
 
 	for _, tableName := range filteredTables {
 		wg.Add(1)
@@ -273,23 +814,33 @@ func (s *Service) GenerateDeleteCommentSQLs(ctx context.Context, params Generate
 			defer wg.Done()
 			tableLogPrefix := fmt.Sprintf("Table[%s]", table)
 
-			// Direct call, no retry
-			tableSQL, genTableErr := s.dbAdapter.GenerateDeleteTableCommentSQL(ctx, table)
-			if genTableErr != nil {
-				log.Printf("WARN: %s Failed to generate delete table comment SQL: %v", tableLogPrefix, genTableErr)
-			} else if tableSQL != "" {
-				mu.Lock()
-				orderedSQLs = append(orderedSQLs, OrderedSQL{SQL: tableSQL, Table: table, IsTableComment: true})
-				mu.Unlock()
+			if rw.tableSQL != nil {
+				// Direct call, no retry
+				tableSQL, genTableErr := rw.tableSQL(ctx, table)
+				if genTableErr != nil {
+					log.Printf("WARN: %s Failed to generate table comment %s SQL: %v", tableLogPrefix, rw.action, genTableErr)
+				} else if tableSQL != "" {
+					before, err := s.dbAdapter.GetTableComment(ctx, table)
+					if err != nil {
+						log.Printf("WARN: %s Failed to read table comment for diff: %v", tableLogPrefix, err)
+					}
+					mu.Lock()
+					changes = append(changes, rw.change(table, "", before, tableSQL))
+					mu.Unlock()
+				}
+			}
+			if rw.columnSQL == nil {
+				return
 			}
 
-			columnInfos, listColErr := s.dbAdapter.ListColumns(table)
+			columnInfos, listColErr := s.listColumns(ctx, table)
 			if listColErr != nil {
-				log.Printf("ERROR: %s Failed to list columns for delete: %v", tableLogPrefix, listColErr)
-				errorChannel <- fmt.Errorf("%s list columns delete: %w", tableLogPrefix, listColErr)
+				log.Printf("ERROR: %s Failed to list columns for %s: %v", tableLogPrefix, rw.action, listColErr)
+				errorChannel <- fmt.Errorf("%s list columns %s: %w", tableLogPrefix, rw.action, listColErr)
 				return
 			}
-			filteredColumnInfos := filterColumns(table, columnInfos, params.TableFilters)
+			filteredColumnInfos := filterColumns(table, columnInfos, tableFilters)
+			s.preloadColumnComments(ctx, table, tableLogPrefix)
 
 			var colWg sync.WaitGroup
 			for _, colInfo := range filteredColumnInfos {
@@ -299,12 +850,17 @@ func (s *Service) GenerateDeleteCommentSQLs(ctx context.Context, params Generate
 					colLogPrefix := fmt.Sprintf("Column[%s.%s]", table, ci.Name)
 
 					// Direct call, no retry
-					sql, genErr := s.dbAdapter.GenerateDeleteCommentSQL(ctx, table, ci.Name)
+					sql, genErr := rw.columnSQL(ctx, table, ci.Name)
 					if genErr != nil {
-						log.Printf("WARN: %s Failed to generate delete comment SQL: %v", colLogPrefix, genErr)
+						log.Printf("WARN: %s Failed to generate comment %s SQL: %v", colLogPrefix, rw.action, genErr)
 					} else if sql != "" {
+						// Served from the comments preloaded above.
+						before, err := s.dbAdapter.GetColumnComment(ctx, table, ci.Name)
+						if err != nil {
+							log.Printf("WARN: %s Failed to read column comment for diff: %v", colLogPrefix, err)
+						}
 						mu.Lock()
-						orderedSQLs = append(orderedSQLs, OrderedSQL{SQL: sql, Table: table, Column: ci.Name, IsTableComment: false})
+						changes = append(changes, rw.change(table, ci.Name, before, sql))
 						mu.Unlock()
 					}
 				}(colInfo)
@@ -322,72 +878,200 @@ func (s *Service) GenerateDeleteCommentSQLs(ctx context.Context, params Generate
 		allErrors = append(allErrors, err)
 	}
 	if len(allErrors) > 0 {
-		errorMessages := make([]string, len(allErrors))
-		for i, e := range allErrors {
-			errorMessages[i] = e.Error()
-		}
-		return nil, fmt.Errorf("encountered %d error(s) during delete SQL generation:\n- %s",
-			len(allErrors), strings.Join(errorMessages, "\n- "))
+		return nil, &ErrPartialEnrichment{Operation: rw.action + " SQL generation", Errs: allErrors}
 	}
 
-	sortSQLs(orderedSQLs)
-	allSQLs := extractSQL(orderedSQLs)
+	sortChanges(changes)
 
-	if len(allSQLs) == 0 {
-		log.Println("INFO: No SQL statements generated for deleting comments (no matching tables/columns or no relevant tags found).")
+	if len(changes) == 0 {
+		log.Printf("INFO: No SQL statements generated for comment %s (no matching tables/columns or no relevant tags found).", rw.action)
 	} else {
-		log.Printf("INFO: Generated %d SQL statements for deleting comments.", len(allSQLs))
+		log.Printf("INFO: Generated %d SQL statements for comment %s.", len(changes), rw.action)
 	}
-	log.Println("INFO: SQL comment deletion generation completed in:", time.Since(startTime))
-	return allSQLs, nil
+	log.Printf("INFO: SQL comment %s generation completed in: %s", rw.action, time.Since(startTime))
+	return changes, nil
+}
+
+// columnsWithUserDescriptions returns the columns whose existing comment has user
+// text outside the <gemini> block, when PreserveExistingDescription is set. Comments
+// are read through the adapter's cache, so preloadColumnComments should run first.
+func (s *Service) columnsWithUserDescriptions(ctx context.Context, table string, columns []database.ColumnInfo, tableLogPrefix string) map[string]bool {
+	if !s.config.PreserveExistingDescription {
+		return nil
+	}
+	preserved := make(map[string]bool)
+	for _, ci := range columns {
+		comment, err := s.dbAdapter.GetColumnComment(ctx, table, ci.Name)
+		if err != nil {
+			log.Printf("WARN: %s Failed to read the existing comment of column %s: %v. Describing it anyway.", tableLogPrefix, ci.Name, err)
+			continue
+		}
+		if user, _, _ := database.SplitComment(comment); user != "" {
+			preserved[ci.Name] = true
+		}
+	}
+	return preserved
+}
+
+// columnsMatchingSkipPatterns returns the columns whose names match one of the
+// SkipDescriptionPatterns, ignoring case. A column with a glossary definition still
+// gets that, since it needs no LLM call.
+func (s *Service) columnsMatchingSkipPatterns(columns []database.ColumnInfo, glossary map[string]string) map[string]bool {
+	if len(s.config.SkipDescriptionPatterns) == 0 {
+		return nil
+	}
+	conventional := make(map[string]bool)
+	for _, ci := range columns {
+		if glossary[utils.NormalizeGlossaryTerm(ci.Name)] != "" {
+			continue
+		}
+		name := strings.ToLower(ci.Name)
+		for _, pattern := range s.config.SkipDescriptionPatterns {
+			if matched, _ := path.Match(pattern, name); matched {
+				conventional[ci.Name] = true
+				break
+			}
+		}
+	}
+	return conventional
+}
+
+// withoutColumns returns columns minus those named in excluded.
+func withoutColumns(columns []database.ColumnInfo, excluded map[string]bool) []database.ColumnInfo {
+	if len(excluded) == 0 {
+		return columns
+	}
+	kept := make([]database.ColumnInfo, 0, len(columns))
+	for _, ci := range columns {
+		if !excluded[ci.Name] {
+			kept = append(kept, ci)
+		}
+	}
+	return kept
+}
+
+// preloadColumnComments fetches a table's existing column comments in one query
+// so that the per-column SQL generation that follows is served from the adapter's
+// cache. On failure each column falls back to its own lookup.
+func (s *Service) preloadColumnComments(ctx context.Context, table, tableLogPrefix string) {
+	if _, err := s.dbAdapter.GetAllColumnComments(ctx, table); err != nil {
+		log.Printf("WARN: %s Failed to preload column comments, reading them per column: %v", tableLogPrefix, err)
+	}
+}
+
+// readColumnComments returns the existing comments of a table's columns, keyed by
+// column name. They are read in one query, or column by column if that fails. A
+// column whose comment cannot be read is left out, and the first such error is
+// returned along with the comments that were read.
+func (s *Service) readColumnComments(ctx context.Context, table string, columns []database.ColumnInfo, tableLogPrefix string) (map[string]string, error) {
+	comments, err := s.dbAdapter.GetAllColumnComments(ctx, table)
+	if err == nil {
+		return comments, nil
+	}
+	log.Printf("WARN: %s Failed to get column comments in one query, reading them per column: %v", tableLogPrefix, err)
+
+	comments = make(map[string]string, len(columns))
+	var firstErr error
+	for _, ci := range columns {
+		comment, err := s.dbAdapter.GetColumnComment(ctx, table, ci.Name)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			log.Printf("ERROR: %s Column[%s] Failed to get column comment: %v", tableLogPrefix, ci.Name, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("column %s: %w", ci.Name, err)
+			}
+			continue
+		}
+		comments[ci.Name] = comment
+	}
+	return comments, firstErr
 }
 
 type GetCommentsParams struct {
 	TableFilters map[string][]string
+	// OnTable, if set, receives each table's comments, sorted, as soon as the table
+	// is read, and GetComments returns none itself. It is never called concurrently,
+	// and an error from it stops the retrieval.
+	OnTable func(tableComments []*ColumnComment) error
 }
 
 func (s *Service) GetComments(ctx context.Context, params GetCommentsParams) ([]*ColumnComment, error) {
 	startTime := time.Now()
 	log.Println("INFO: Starting comment retrieval...")
 
-	tables, err := s.dbAdapter.ListTables()
+	tables, err := s.dbAdapter.ListTables(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tables: %w", err)
 	}
 
+	params.TableFilters, _ = s.unqualifiedTableFilters(params.TableFilters)
 	filteredTables := filterTables(tables, params.TableFilters)
 	if len(filteredTables) == 0 {
-		log.Println("INFO: No tables match the provided filters (--tables) for retrieval.")
+		log.Println(noTablesMessage(tables, "retrieval"))
 		return []*ColumnComment{}, nil
 	}
 
 	var allComments []*ColumnComment
+	var commentCount int
+	var streamErr error
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	errorChannel := make(chan error, len(filteredTables)*5)
+	slots := s.workerSlots()
 
 	log.Printf("INFO: Retrieving comments for %d filtered table(s)...", len(filteredTables))
 
 	for _, tableName := range filteredTables {
+		if slots != nil {
+			slots <- struct{}{}
+		}
 		wg.Add(1)
 		go func(table string) {
 			defer wg.Done()
+			if slots != nil {
+				defer func() { <-slots }()
+			}
 			tableLogPrefix := fmt.Sprintf("Table[%s]", table)
+			var tableComments []*ColumnComment
+			defer func() {
+				if len(tableComments) == 0 {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				commentCount += len(tableComments)
+				if params.OnTable == nil {
+					allComments = append(allComments, tableComments...)
+					return
+				}
+				if streamErr == nil {
+					sortComments(tableComments)
+					streamErr = params.OnTable(tableComments)
+				}
+			}()
+
+			mu.Lock()
+			stopped := streamErr != nil
+			mu.Unlock()
+			if stopped {
+				return
+			}
 
 			tableComment, err := s.dbAdapter.GetTableComment(ctx, table)
 			if err != nil {
 				log.Printf("WARN: %s Failed to get table comment: %v", tableLogPrefix, err)
 			} else if tableComment != "" {
-				mu.Lock()
-				allComments = append(allComments, &ColumnComment{
-					Table:   table,
-					Column:  "",
-					Comment: tableComment,
+				tableComments = append(tableComments, &ColumnComment{
+					Table:      table,
+					Column:     "",
+					Comment:    tableComment,
+					Provenance: database.ParseProvenance(tableComment),
 				})
-				mu.Unlock()
 			}
 
-			columnInfos, listColErr := s.dbAdapter.ListColumns(table)
+			columnInfos, listColErr := s.listColumns(ctx, table)
 			if listColErr != nil {
 				log.Printf("ERROR: %s Failed to list columns for get comments: %v", tableLogPrefix, listColErr)
 				errorChannel <- fmt.Errorf("%s list columns get: %w", tableLogPrefix, listColErr)
@@ -395,29 +1079,20 @@ func (s *Service) GetComments(ctx context.Context, params GetCommentsParams) ([]
 			}
 			filteredColumnInfos := filterColumns(table, columnInfos, params.TableFilters)
 
-			var colWg sync.WaitGroup
-			for _, colInfo := range filteredColumnInfos {
-				colWg.Add(1)
-				go func(ci database.ColumnInfo) {
-					defer colWg.Done()
-					colLogPrefix := fmt.Sprintf("Column[%s.%s]", table, ci.Name)
-
-					// Direct call, no retry
-					comment, err := s.dbAdapter.GetColumnComment(ctx, table, ci.Name)
-					if err != nil {
-						log.Printf("WARN: %s Failed to get column comment: %v", colLogPrefix, err)
-					} else if comment != "" {
-						mu.Lock()
-						allComments = append(allComments, &ColumnComment{
-							Table:   table,
-							Column:  ci.Name,
-							Comment: comment,
-						})
-						mu.Unlock()
-					}
-				}(colInfo)
+			columnComments, err := s.readColumnComments(ctx, table, filteredColumnInfos, tableLogPrefix)
+			if err != nil {
+				errorChannel <- fmt.Errorf("%s get column comments: %w", tableLogPrefix, err)
+			}
+			for _, ci := range filteredColumnInfos {
+				if comment := columnComments[ci.Name]; comment != "" {
+					tableComments = append(tableComments, &ColumnComment{
+						Table:      table,
+						Column:     ci.Name,
+						Comment:    comment,
+						Provenance: database.ParseProvenance(comment),
+					})
+				}
 			}
-			colWg.Wait()
 
 		}(tableName)
 	}
@@ -425,50 +1100,130 @@ func (s *Service) GetComments(ctx context.Context, params GetCommentsParams) ([]
 	wg.Wait()
 	close(errorChannel)
 
+	if streamErr != nil {
+		return nil, fmt.Errorf("failed to stream comments: %w", streamErr)
+	}
+
 	var allErrors []error
 	for err := range errorChannel {
 		allErrors = append(allErrors, err)
 	}
 	if len(allErrors) > 0 {
-		errorMessages := make([]string, len(allErrors))
-		for i, e := range allErrors {
-			errorMessages[i] = e.Error()
-		}
-		aggError := fmt.Errorf("encountered %d error(s) during comment retrieval:\n- %s",
-			len(allErrors), strings.Join(errorMessages, "\n- "))
 		sortComments(allComments)
-		return allComments, aggError
+		return allComments, &ErrPartialEnrichment{Operation: "comment retrieval", Errs: allErrors}
 	}
 
 	sortComments(allComments)
 
-	log.Printf("INFO: Comment retrieval completed in %s. Found %d comments.", time.Since(startTime), len(allComments))
+	log.Printf("INFO: Comment retrieval completed in %s. Found %d comments.", time.Since(startTime), commentCount)
 	return allComments, nil
 }
 
+// noTablesMessage explains why there is nothing to process for purpose: either the
+// database has no tables in the connection's default schema, or the --tables filters
+// excluded all of them.
+func noTablesMessage(tables []string, purpose string) string {
+	if len(tables) == 0 {
+		return fmt.Sprintf("INFO: The database has no tables in the connection's default schema. Nothing to do for %s.", purpose)
+	}
+	return fmt.Sprintf("INFO: None of the %d table(s) in the database match the provided filters (--tables) for %s.", len(tables), purpose)
+}
+
 func filterTables(allTables []string, tableFilters map[string][]string) []string {
 	if len(tableFilters) == 0 {
 		return allTables
 	}
 	filtered := make([]string, 0, len(tableFilters))
-	allowed := make(map[string]bool)
-	for table := range tableFilters {
-		allowed[table] = true
-	}
+	matched := make(map[string]bool)
 	for _, table := range allTables {
-		if allowed[table] {
+		if entry, ok := tableFilterEntry(table, tableFilters); ok {
 			filtered = append(filtered, table)
+			matched[entry] = true
+		}
+	}
+	for entry := range tableFilters {
+		if schema, _ := utils.SplitQualifiedTable(entry); schema != "" && !matched[entry] {
+			log.Printf("WARN: --tables entry '%s' did not match any table. Tables are only listed from the connection's default schema.", entry)
 		}
 	}
 	sort.Strings(filtered)
 	return filtered
 }
 
+// listedSchema returns the schema whose tables ListTables lists by their bare name:
+// --schema if set, otherwise the dialect's default schema, or "" if it has none.
+// For postgres this assumes the default search_path.
+func listedSchema(cfg config.DatabaseConfig) string {
+	if cfg.Schema != "" {
+		return cfg.Schema
+	}
+	switch cfg.Dialect {
+	case "postgres", "cloudsqlpostgres", "cockroach":
+		return "public"
+	case "sqlserver", "cloudsqlsqlserver":
+		return "dbo"
+	case "mysql", "cloudsqlmysql", "clickhouse":
+		return cfg.DBName
+	}
+	return ""
+}
+
+// unqualifiedTableFilters returns the --tables filters with entries qualified by the
+// listed schema rewritten to the bare names that ListTables returns, so that
+// sales.orders with --schema sales selects the table orders. The database config is
+// only read when an entry is qualified.
+func (s *Service) unqualifiedTableFilters(tableFilters map[string][]string) (map[string][]string, string) {
+	for entry := range tableFilters {
+		if schema, _ := utils.SplitQualifiedTable(entry); schema != "" {
+			schema = listedSchema(s.dbAdapter.GetConfig())
+			return unqualifyEntries(tableFilters, schema), schema
+		}
+	}
+	return tableFilters, ""
+}
+
+// unqualifyEntries strips schema from the keys of entries qualified with it. An
+// unqualified entry for the same table takes precedence.
+func unqualifyEntries[V any](entries map[string]V, schema string) map[string]V {
+	if schema == "" || len(entries) == 0 {
+		return entries
+	}
+	result := make(map[string]V, len(entries))
+	for entry, value := range entries {
+		if entrySchema, name := utils.SplitQualifiedTable(entry); entrySchema == schema {
+			if _, ok := entries[name]; !ok {
+				result[name] = value
+			}
+			continue
+		}
+		result[entry] = value
+	}
+	return result
+}
+
+// tableFilterEntry returns the --tables entry that selects a listed table. Entries
+// match on schema and table name, and an unqualified entry matches the table in
+// any schema. An exact entry wins over an unqualified one.
+func tableFilterEntry(table string, tableFilters map[string][]string) (string, bool) {
+	if _, ok := tableFilters[table]; ok {
+		return table, true
+	}
+	schema, name := utils.SplitQualifiedTable(table)
+	if schema == "" {
+		return "", false
+	}
+	if _, ok := tableFilters[name]; ok {
+		return name, true
+	}
+	return "", false
+}
+
 func filterColumns(tableName string, allColumns []database.ColumnInfo, tableFilters map[string][]string) []database.ColumnInfo {
 	if len(tableFilters) == 0 {
 		return allColumns
 	}
-	specificColumnFilters, tableIncluded := tableFilters[tableName]
+	entry, tableIncluded := tableFilterEntry(tableName, tableFilters)
+	specificColumnFilters := tableFilters[entry]
 	if !tableIncluded || len(specificColumnFilters) == 0 {
 		return allColumns
 	}
@@ -488,11 +1243,144 @@ func filterColumns(tableName string, allColumns []database.ColumnInfo, tableFilt
 	return filtered
 }
 
-func isEnrichmentRequested(enrichment string, enrichments map[string]bool) bool {
-	if len(enrichments) == 0 {
+// columnEnrichments returns the enrichments to apply to a column: its --tables hint
+// if it has one, otherwise the run's enrichments. hinted reports which it was.
+func columnEnrichments(table, column string, params GenerateSQLParams) (enrichments map[string]bool, hinted bool) {
+	entry, ok := tableFilterEntry(table, params.TableFilters)
+	if !ok {
+		return params.Enrichments, false
+	}
+	hints, ok := params.ColumnEnrichments[entry][column]
+	if !ok {
+		return params.Enrichments, false
+	}
+	enrichments = make(map[string]bool, len(hints))
+	for _, hint := range hints {
+		enrichments[hint] = true
+	}
+	return enrichments, true
+}
+
+// limitDescription truncates a description to Config.MaxDescriptionLength, since the
+// model does not always keep to the word limit in the prompt.
+func (s *Service) limitDescription(logPrefix, description string) string {
+	truncated := truncateDescription(description, s.config.MaxDescriptionLength)
+	if truncated != description {
+		log.Printf("INFO: %s Description truncated from %d to %d characters (--max-description-length).", logPrefix, len([]rune(description)), len([]rune(truncated)))
+	}
+	return truncated
+}
+
+// truncateDescription shortens description to at most maxLength characters, cutting
+// at the last word boundary that leaves room for a "..." ellipsis. A maxLength of 0
+// or less leaves the description unchanged.
+func truncateDescription(description string, maxLength int) string {
+	const ellipsis = "..."
+	runes := []rune(description)
+	if maxLength <= 0 || len(runes) <= maxLength {
+		return description
+	}
+	if maxLength <= len(ellipsis) {
+		return string(runes[:maxLength])
+	}
+	cut := string(runes[:maxLength-len(ellipsis)])
+	if i := strings.LastIndexAny(cut, " \t\n"); i > 0 && !unicode.IsSpace(runes[maxLength-len(ellipsis)]) {
+		cut = cut[:i]
+	}
+	cut = strings.TrimRightFunc(cut, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+	return cut + ellipsis
+}
+
+// withoutStatistics returns a copy of enrichments that excludes the enrichments
+// queried from column values: examples, distinct values, null count, average and
+// JSON keys.
+func withoutStatistics(enrichments map[string]bool) map[string]bool {
+	return withoutEnrichments(enrichments, "examples", "distinct_values", "null_count", "average", "json_keys")
+}
+
+// isJSONColumn reports whether a column holds JSON documents whose keys the
+// json_keys enrichment can list, also through a domain over a JSON type.
+func isJSONColumn(ci database.ColumnInfo) bool {
+	switch strings.ToLower(ci.ResolvedType()) {
+	case "json", "jsonb":
 		return true
 	}
-	return enrichments[strings.ToLower(enrichment)]
+	return false
+}
+
+// withoutEnrichments returns a copy of enrichments that excludes names.
+func withoutEnrichments(enrichments map[string]bool, names ...string) map[string]bool {
+	reduced := make(map[string]bool, len(enrichments)+len(names))
+	inclusive := false
+	for name, requested := range enrichments {
+		reduced[name] = requested
+		inclusive = inclusive || requested
+	}
+	for _, name := range names {
+		reduced[name] = false
+	}
+	if !inclusive {
+		return reduced
+	}
+	for _, requested := range reduced {
+		if requested {
+			return reduced
+		}
+	}
+	// Only excluded enrichments were requested. Excluding every other enrichment keeps
+	// the map from reading as "all but these" now that none of it is included.
+	for name := range builtinEnrichments {
+		reduced[name] = false
+	}
+	enrichmentsMu.RLock()
+	defer enrichmentsMu.RUnlock()
+	for name := range customEnrichments {
+		reduced[name] = false
+	}
+	return reduced
+}
+
+// canEnrichColumn reports whether any of a column's enrichments can add to its comment.
+// Statistics and foreign keys always can, as can a requested average and requested
+// custom enrichments; a description only when one is available from the batched call
+// or can be generated from knowledge context. Columns that fail this would only get
+// an empty comment.
+func canEnrichColumn(enrichments map[string]bool, descriptionAvailable bool) bool {
+	for _, e := range []string{"examples", "distinct_values", "null_count", "foreign_keys"} {
+		if isEnrichmentRequested(e, enrichments) {
+			return true
+		}
+	}
+	if enrichments["average"] || len(requestedCustomEnrichments(enrichments)) > 0 {
+		return true
+	}
+	return descriptionAvailable && isEnrichmentRequested("description", enrichments)
+}
+
+// checkStrictDescription returns an error for a column whose requested description
+// came out empty when StrictDescriptions is set. Descriptions dropped on purpose, such
+// as for --skip-description-patterns, are no longer requested and pass.
+func (s *Service) checkStrictDescription(cm *ColumnMetadata, enrichments map[string]bool) error {
+	if !s.config.StrictDescriptions || cm.Description != "" || !isEnrichmentRequested("description", enrichments) {
+		return nil
+	}
+	return errors.New("no description was generated (--strict-descriptions)")
+}
+
+// isEnrichmentRequested mirrors the database package: a map that includes no
+// enrichment requests all of them except those excluded with false.
+func isEnrichmentRequested(enrichment string, enrichments map[string]bool) bool {
+	if requested, listed := enrichments[strings.ToLower(enrichment)]; listed {
+		return requested
+	}
+	for _, included := range enrichments {
+		if included {
+			return false
+		}
+	}
+	return true
 }
 
 func safeConvertToInt64(value interface{}) int64 {
@@ -533,6 +1421,18 @@ func extractSQL(orderedSQLs []OrderedSQL) []string {
 	return allSQLs
 }
 
+func sortChanges(changes []*CommentChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Table != changes[j].Table {
+			return changes[i].Table < changes[j].Table
+		}
+		if (changes[i].Column == "") != (changes[j].Column == "") {
+			return changes[i].Column == "" // Table comments first
+		}
+		return changes[i].Column < changes[j].Column
+	})
+}
+
 func sortComments(comments []*ColumnComment) {
 	sort.Slice(comments, func(i, j int) bool {
 		if comments[i].Table != comments[j].Table {
@@ -551,19 +1451,25 @@ func sortComments(comments []*ColumnComment) {
 // --- Types ---
 
 type ColumnMetadata struct {
-	Table         string
-	Column        string
-	DataType      string
-	ExampleValues []string
-	DistinctCount int64
-	NullCount     int64
-	Description   string
-	ForeignKeys   []database.ForeignKeyReference
+	Table         string                         `json:"table"`
+	Column        string                         `json:"column"`
+	DataType      string                         `json:"data_type"`
+	ExampleValues []string                       `json:"example_values,omitempty"`
+	DistinctCount int64                          `json:"distinct_count,omitempty"`
+	NullCount     int64                          `json:"null_count,omitempty"`
+	Average       *float64                       `json:"average,omitempty"`
+	JSONKeys      []string                       `json:"json_keys,omitempty"`
+	Description   string                         `json:"description,omitempty"`
+	ForeignKeys   []database.ForeignKeyReference `json:"foreign_keys,omitempty"`
+	Custom        map[string]string              `json:"custom,omitempty"`      // Output of custom enrichments, keyed by enrichment name.
+	Enrichments   map[string]bool                `json:"enrichments,omitempty"` // Set when a --tables hint replaced the run's enrichments for this column.
+	ProfiledAt    time.Time                      `json:"profiled_at,omitzero"`  // When the statistics were queried, possibly by an earlier run (--stats-cache).
+	Explanation   *ColumnExplanation             `json:"-"`                     // Set with Config.Explain; not part of snapshots.
 }
 
 type TableMetadata struct {
-	Table       string
-	Description string
+	Table       string `json:"table"`
+	Description string `json:"description,omitempty"`
 }
 
 type OrderedSQL struct {
@@ -574,9 +1480,49 @@ type OrderedSQL struct {
 }
 
 type ColumnComment struct {
-	Table   string `json:"table"`
-	Column  string `json:"column"`
-	Comment string `json:"comment"`
+	Table      string            `json:"table"`
+	Column     string            `json:"column"`
+	Comment    string            `json:"comment"`
+	Provenance map[string]string `json:"provenance,omitempty"` // Parsed from a --embed-provenance marker, keyed by enrichment.
+}
+
+// CommentChange is a generated statement together with the target's comment
+// before and after it is applied. Column is empty for table comments.
+type CommentChange struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+	SQL    string `json:"sql"`
+}
+
+// FormatCommentChangesAsText renders changes as a review diff, one target per
+// block, with the old comment on a "-" line and the new one on a "+" line.
+func FormatCommentChangesAsText(changes []*CommentChange) string {
+	if len(changes) == 0 {
+		return "No comments will change.\n"
+	}
+	var buffer bytes.Buffer
+	for i, change := range changes {
+		if i > 0 {
+			buffer.WriteString("\n")
+		}
+		if change.Column == "" {
+			buffer.WriteString(fmt.Sprintf("%s (table)\n", change.Table))
+		} else {
+			buffer.WriteString(fmt.Sprintf("%s.%s\n", change.Table, change.Column))
+		}
+		buffer.WriteString(fmt.Sprintf("- %s\n", displayComment(change.Before)))
+		buffer.WriteString(fmt.Sprintf("+ %s\n", displayComment(change.After)))
+	}
+	return buffer.String()
+}
+
+func displayComment(comment string) string {
+	if comment == "" {
+		return "(no comment)"
+	}
+	return comment
 }
 
 func FormatCommentsAsText(comments []*ColumnComment) string {
@@ -603,3 +1549,15 @@ func FormatCommentsAsText(comments []*ColumnComment) string {
 	}
 	return buffer.String()
 }
+
+// FormatCommentsAsJSON renders comments as an indented JSON array, ordered by table
+// with each table's comment before its columns. Table comments have an empty column.
+func FormatCommentsAsJSON(comments []*ColumnComment) ([]byte, error) {
+	sorted := append(make([]*ColumnComment, 0, len(comments)), comments...)
+	sortComments(sorted)
+	content, err := json.MarshalIndent(sorted, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
@@ -2,11 +2,14 @@ package enricher
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
 	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
 )
 
@@ -15,17 +18,17 @@ type MockDBAdapter struct {
 	mock.Mock
 }
 
-func (m *MockDBAdapter) GetForeignKeys(tableName, columnName string) ([]database.ForeignKeyReference, error) {
+func (m *MockDBAdapter) GetForeignKeys(ctx context.Context, tableName, columnName string) ([]database.ForeignKeyReference, error) {
 	args := m.Called(tableName, columnName)
 	return args.Get(0).([]database.ForeignKeyReference), args.Error(1)
 }
 
-func (m *MockDBAdapter) GetColumns(tableName string) ([]database.ColumnInfo, error) {
+func (m *MockDBAdapter) ListColumns(ctx context.Context, tableName string) ([]database.ColumnInfo, error) {
 	args := m.Called(tableName)
 	return args.Get(0).([]database.ColumnInfo), args.Error(1)
 }
 
-func (m *MockDBAdapter) GetTables() ([]string, error) {
+func (m *MockDBAdapter) ListTables(ctx context.Context) ([]string, error) {
 	args := m.Called()
 	return args.Get(0).([]string), args.Error(1)
 }
@@ -35,16 +38,78 @@ func (m *MockDBAdapter) GetColumnMetadata(tableName, columnName string) (map[str
 	return args.Get(0).(map[string]interface{}), args.Error(1)
 }
 
+func (m *MockDBAdapter) GetColumnComment(ctx context.Context, tableName, columnName string) (string, error) {
+	args := m.Called(tableName, columnName)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDBAdapter) GetAllColumnComments(ctx context.Context, tableName string) (map[string]string, error) {
+	args := m.Called(tableName)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (m *MockDBAdapter) GetTableComment(ctx context.Context, tableName string) (string, error) {
+	args := m.Called(tableName)
+	return args.String(0), args.Error(1)
+}
+
 func (m *MockDBAdapter) GenerateCommentSQL(data *database.CommentData, enrichments map[string]bool) (string, error) {
 	args := m.Called(data, enrichments)
 	return args.Get(0).(string), args.Error(1)
 }
 
-func (m *MockDBAdapter) GenerateDeleteCommentSQL(tableName, columnName string) (string, error) {
+func (m *MockDBAdapter) GenerateTableCommentSQL(data *database.TableCommentData, enrichments map[string]bool) (string, error) {
+	args := m.Called(data, enrichments)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDBAdapter) GenerateDeleteCommentSQL(ctx context.Context, tableName, columnName string) (string, error) {
 	args := m.Called(tableName, columnName)
 	return args.Get(0).(string), args.Error(1)
 }
 
+func (m *MockDBAdapter) GenerateDeleteTableCommentSQL(ctx context.Context, tableName string) (string, error) {
+	args := m.Called(tableName)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDBAdapter) GenerateRewriteCommentSQL(ctx context.Context, tableName, columnName string, rewrite func(string) string) (string, error) {
+	args := m.Called(tableName, columnName)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDBAdapter) GenerateRewriteTableCommentSQL(ctx context.Context, tableName string, rewrite func(string) string) (string, error) {
+	args := m.Called(tableName)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDBAdapter) ExecuteSQLStatements(ctx context.Context, sqlStatements []string) error {
+	args := m.Called(sqlStatements)
+	return args.Error(0)
+}
+
+func (m *MockDBAdapter) TableVersion(ctx context.Context, tableName string) (string, error) {
+	args := m.Called(tableName)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDBAdapter) JSONKeys(ctx context.Context, tableName, columnName string) ([]string, error) {
+	args := m.Called(tableName, columnName)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockDBAdapter) Ping(ctx context.Context) error {
+	return m.Called().Error(0)
+}
+
+func (m *MockDBAdapter) Close() error {
+	return m.Called().Error(0)
+}
+
+func (m *MockDBAdapter) GetConfig() config.DatabaseConfig {
+	return m.Called().Get(0).(config.DatabaseConfig)
+}
+
 func TestCollectColumnDBMetadataWithForeignKeys(t *testing.T) {
 	tests := []struct {
 		name                string
@@ -101,8 +166,10 @@ func TestCollectColumnDBMetadataWithForeignKeys(t *testing.T) {
 				mockAdapter.On("GetForeignKeys", "orders", "user_id").Return(tt.expectedForeignKeys, tt.foreignKeyError)
 			}
 
-			// Mock GetColumnMetadata for other enrichments
-			mockAdapter.On("GetColumnMetadata", "orders", "user_id").Return(map[string]interface{}{}, nil)
+			// Only the other enrichments need GetColumnMetadata.
+			if !tt.expectForeignKeys {
+				mockAdapter.On("GetColumnMetadata", "orders", "user_id").Return(map[string]interface{}{}, nil)
+			}
 
 			// Test data
 			colInfo := database.ColumnInfo{
@@ -128,6 +195,9 @@ func TestCollectColumnDBMetadataWithForeignKeys(t *testing.T) {
 
 			// Verify mock expectations
 			mockAdapter.AssertExpectations(t)
+			if tt.expectForeignKeys {
+				mockAdapter.AssertNotCalled(t, "GetColumnMetadata", "orders", "user_id")
+			}
 		})
 	}
 }
@@ -150,7 +220,6 @@ func TestForeignKeyIntegrationWithCommentData(t *testing.T) {
 
 	// Setup expectations
 	mockAdapter.On("GetForeignKeys", "orders", "user_id").Return(expectedForeignKeys, nil)
-	mockAdapter.On("GetColumnMetadata", "orders", "user_id").Return(map[string]interface{}{}, nil)
 
 	// Test data
 	colInfo := database.ColumnInfo{
@@ -188,3 +257,58 @@ func TestForeignKeyIntegrationWithCommentData(t *testing.T) {
 	// Verify mock expectations
 	mockAdapter.AssertExpectations(t)
 }
+
+func TestGenerateCommentSQLsDescribesReferencedTables(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	llm := &fakeLLMClient{tableDescriptions: map[string]string{
+		"users": "The user accounts table. One row per sign-up.",
+	}}
+	service := NewService(mockAdapter, llm, Config{})
+	enrichments := map[string]bool{"description": true, "foreign_keys": true}
+
+	mockAdapter.On("ListTables").Return([]string{"orders", "users"}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "user_id", DataType: "int"},
+		{Name: "product_id", DataType: "int"},
+	}, nil)
+	mockAdapter.On("ListColumns", "users").Return([]database.ColumnInfo{{Name: "id", DataType: "int"}}, nil)
+	mockAdapter.On("GetAllColumnComments", mock.Anything).Return(map[string]string{}, nil)
+	mockAdapter.On("GetForeignKeys", "orders", "user_id").Return([]database.ForeignKeyReference{
+		{ReferencedTable: "users", ReferencedColumn: "id"},
+	}, nil)
+	// products is not part of the run, so its existing comment is used.
+	mockAdapter.On("GetForeignKeys", "orders", "product_id").Return([]database.ForeignKeyReference{
+		{ReferencedTable: "products", ReferencedColumn: "id"},
+	}, nil)
+	mockAdapter.On("GetTableComment", "products").Return("<gemini>Catalog of products for sale</gemini>", nil)
+	mockAdapter.On("GetForeignKeys", "users", "id").Return([]database.ForeignKeyReference{}, nil)
+	mockAdapter.On("GetColumnMetadata", mock.Anything, mock.Anything).Return(map[string]interface{}{}, nil)
+	mockAdapter.On("GenerateTableCommentSQL", mock.Anything, enrichments).Return("", nil)
+
+	var mu sync.Mutex
+	described := map[string]string{}
+	mockAdapter.On("GenerateCommentSQL", mock.Anything, enrichments).Run(func(args mock.Arguments) {
+		data := args.Get(0).(*database.CommentData)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, fk := range data.ForeignKeys {
+			described[data.ColumnName] = fk.ReferencedTableDescription
+		}
+	}).Return("", nil)
+
+	_, err := service.GenerateCommentSQLs(context.Background(), GenerateSQLParams{Enrichments: enrichments})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"user_id":    "The user accounts table",
+		"product_id": "Catalog of products for sale",
+	}, described)
+	mockAdapter.AssertNotCalled(t, "GetTableComment", "users")
+}
+
+func TestShortDescription(t *testing.T) {
+	assert.Equal(t, "The user accounts table", shortDescription(" The user accounts table. One row per sign-up. "))
+	assert.Equal(t, "Orders", shortDescription("Orders."))
+	long := strings.Repeat("a", 100)
+	assert.Equal(t, strings.Repeat("a", 77)+"...", shortDescription(long))
+}
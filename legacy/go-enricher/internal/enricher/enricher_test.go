@@ -0,0 +1,1410 @@
+package enricher
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/api/googleapi"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/genai"
+)
+
+func TestGenerateCommentSQLsWithEmptyColumns(t *testing.T) {
+	tests := []struct {
+		name            string
+		skipEmptyTables bool
+		expectedSQLs    []string
+	}{
+		{
+			name:            "empty_table_kept_by_default",
+			skipEmptyTables: false,
+			expectedSQLs:    []string{"COMMENT ON TABLE hidden IS 'x';"},
+		},
+		{
+			name:            "empty_table_skipped",
+			skipEmptyTables: true,
+			expectedSQLs:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAdapter := &MockDBAdapter{}
+			service := NewService(mockAdapter, nil, Config{SkipEmptyTables: tt.skipEmptyTables})
+
+			mockAdapter.On("ListTables").Return([]string{"hidden"}, nil)
+			mockAdapter.On("ListColumns", "hidden").Return([]database.ColumnInfo{}, nil)
+			if !tt.skipEmptyTables {
+				mockAdapter.On("GenerateTableCommentSQL", mock.Anything, mock.Anything).Return("COMMENT ON TABLE hidden IS 'x';", nil)
+			}
+
+			sqls, err := service.GenerateCommentSQLs(context.Background(), GenerateSQLParams{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedSQLs, sqls)
+			mockAdapter.AssertExpectations(t)
+			if tt.skipEmptyTables {
+				mockAdapter.AssertNotCalled(t, "GenerateTableCommentSQL", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// fakeLLMClient returns canned descriptions and counts the calls it receives.
+type fakeLLMClient struct {
+	mu                 sync.Mutex
+	columnDescriptions map[string]string
+	batchCalls         int
+	singleCalls        int
+	contexts           map[string]string // knowledgeContext per "parent.name" of GenerateDescription calls
+	tableDescriptions  map[string]string
+	batchColumns       [][]string // columnNames of each GenerateColumnDescriptions call
+	batchContexts      []string   // knowledgeContext of each GenerateColumnDescriptions call
+	batchErr           error
+	relationships      map[string]string   // Column relationships per table, for "table_with_relationships" calls.
+	relationshipsCtx   map[string]string   // knowledgeContext per table of "table_with_relationships" calls.
+	synthetic          map[string][]string // Examples returned as synthesized, per column.
+}
+
+func (f *fakeLLMClient) GenerateDescription(ctx context.Context, objectType, objectName, parentName, knowledgeContext string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.singleCalls++
+	if objectType == "table_with_relationships" {
+		if f.relationshipsCtx != nil {
+			f.relationshipsCtx[objectName] = knowledgeContext
+		}
+		description := f.tableDescriptions[objectName]
+		if notes := f.relationships[objectName]; notes != "" {
+			description += " " + genai.ColumnRelationshipsPrefix + " " + notes
+		}
+		return description, nil
+	}
+	if f.contexts != nil {
+		f.contexts[parentName+"."+objectName] = knowledgeContext
+	}
+	if objectType == "table" {
+		return f.tableDescriptions[objectName], nil
+	}
+	return f.columnDescriptions[objectName], nil
+}
+
+func (f *fakeLLMClient) GenerateColumnDescriptions(ctx context.Context, tableName string, columnNames []string, knowledgeContext string) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batchCalls++
+	f.batchColumns = append(f.batchColumns, columnNames)
+	f.batchContexts = append(f.batchContexts, knowledgeContext)
+	if f.batchErr != nil {
+		return nil, f.batchErr
+	}
+	return f.columnDescriptions, nil
+}
+
+func (f *fakeLLMClient) GenerateSyntheticExamples(ctx context.Context, columnName, tableName, dataType string, originalExamples []string, maskPII bool) ([]string, bool, error) {
+	if synthetic, ok := f.synthetic[columnName]; ok && maskPII {
+		return synthetic, true, nil
+	}
+	return originalExamples, false, nil
+}
+
+func (f *fakeLLMClient) CheckAccess(ctx context.Context) error { return nil }
+
+func (f *fakeLLMClient) Close() error { return nil }
+
+func TestGenerateCommentSQLsWithBatchedDescriptions(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	llm := &fakeLLMClient{columnDescriptions: map[string]string{
+		"id":    "Order identifier",
+		"total": "Order total in cents",
+	}}
+	service := NewService(mockAdapter, llm, Config{BatchDescriptions: true})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "int"},
+		{Name: "note", DataType: "text"},
+		{Name: "total", DataType: "int"},
+	}, nil)
+	mockAdapter.On("GenerateTableCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+	for _, col := range []struct{ name, desc string }{{"id", "Order identifier"}, {"note", ""}, {"total", "Order total in cents"}} {
+		col := col
+		mockAdapter.On("GenerateCommentSQL", mock.MatchedBy(func(d *database.CommentData) bool {
+			return d.ColumnName == col.name && d.Description == col.desc
+		}), mock.Anything).Return("COMMENT "+col.name+";", nil)
+	}
+
+	sqls, err := service.GenerateCommentSQLs(context.Background(), GenerateSQLParams{
+		Enrichments:       map[string]bool{"description": true},
+		AdditionalContext: "orders docs",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"COMMENT id;", "COMMENT note;", "COMMENT total;"}, sqls)
+	assert.Equal(t, 1, llm.batchCalls)
+	// Only the table description is requested one call at a time.
+	assert.Equal(t, 1, llm.singleCalls)
+	mockAdapter.AssertExpectations(t)
+	mockAdapter.AssertNotCalled(t, "GetColumnMetadata", mock.Anything, mock.Anything)
+}
+
+func TestGenerateBatchedDescriptionsErrors(t *testing.T) {
+	columns := []database.ColumnInfo{{Name: "id", DataType: "int"}, {Name: "total", DataType: "int"}}
+	params := GenerateSQLParams{Enrichments: map[string]bool{"description": true}}
+
+	t.Run("rate_limited_does_not_fall_back", func(t *testing.T) {
+		llm := &fakeLLMClient{batchErr: fmt.Errorf("Gemini API call failed after 3 retries due to rate limits: %w", &googleapi.Error{Code: 429})}
+		service := NewService(&MockDBAdapter{}, llm, Config{BatchDescriptions: true})
+
+		got := service.generateBatchedDescriptions(context.Background(), "orders", "", columns, params)
+		assert.NotNil(t, got)
+		assert.Empty(t, got)
+		assert.Equal(t, 1, llm.batchCalls)
+	})
+
+	t.Run("other_error_falls_back_to_per_column", func(t *testing.T) {
+		llm := &fakeLLMClient{batchErr: errors.New("could not extract column descriptions")}
+		service := NewService(&MockDBAdapter{}, llm, Config{BatchDescriptions: true})
+
+		assert.Nil(t, service.generateBatchedDescriptions(context.Background(), "orders", "", columns, params))
+	})
+}
+
+func TestGenerateCommentSQLsBatchesWideTables(t *testing.T) {
+	const numColumns = 200
+	dir := t.TempDir()
+	columns := make([]database.ColumnInfo, numColumns)
+	descriptions := make(map[string]string, numColumns)
+	for i := range columns {
+		name := fmt.Sprintf("c%03d", i)
+		columns[i] = database.ColumnInfo{Name: name, DataType: "int"}
+		descriptions[name] = "Column " + name
+		// 200 x 1 KiB of column context is well past the per-prompt budget.
+		if err := os.WriteFile(filepath.Join(dir, "wide."+name+".md"), bytes.Repeat([]byte("x"), 1024), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mockAdapter := &MockDBAdapter{}
+	llm := &fakeLLMClient{columnDescriptions: descriptions}
+	service := NewService(mockAdapter, llm, Config{BatchDescriptions: true})
+
+	mockAdapter.On("ListTables").Return([]string{"wide"}, nil)
+	mockAdapter.On("GetAllColumnComments", "wide").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "wide").Return(columns, nil)
+	mockAdapter.On("GenerateTableCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+	mockAdapter.On("GenerateCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+		Enrichments:       map[string]bool{"description": true},
+		AdditionalContext: "global docs",
+		ContextDir:        dir,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, numColumns/maxColumnsPerDescriptionBatch, llm.batchCalls)
+	described := 0
+	for i, names := range llm.batchColumns {
+		assert.LessOrEqual(t, len(names), maxColumnsPerDescriptionBatch)
+		assert.LessOrEqual(t, len(llm.batchContexts[i]), len("global docs")+maxScopedContextBytes)
+		assert.True(t, strings.HasPrefix(llm.batchContexts[i], "global docs"))
+		described += len(names)
+	}
+	assert.Equal(t, numColumns, described)
+
+	assert.Len(t, snapshot.Columns, numColumns)
+	for _, col := range snapshot.Columns {
+		assert.Equal(t, "Column "+col.Column, col.Description)
+	}
+}
+
+// clearProfiledAt zeroes the times at which column statistics were queried, which
+// depend on the clock, so collected columns can be compared with expected ones.
+func clearProfiledAt(columns []*ColumnMetadata) []*ColumnMetadata {
+	for _, col := range columns {
+		col.ProfiledAt = time.Time{}
+	}
+	return columns
+}
+
+func TestPerColumnEnrichments(t *testing.T) {
+	collectAdapter := &MockDBAdapter{}
+	llm := &fakeLLMClient{columnDescriptions: map[string]string{"amount": "Not requested", "status": "Order status"}}
+	service := NewService(collectAdapter, llm, Config{})
+
+	collectAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	collectAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	collectAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "amount", DataType: "int"},
+		{Name: "note", DataType: "text"},
+		{Name: "status", DataType: "text"},
+	}, nil)
+	collectAdapter.On("GetColumnMetadata", "orders", "amount").Return(map[string]interface{}{"ExampleValues": []string{"10", "20"}, "DistinctCount": 2}, nil)
+	collectAdapter.On("GetColumnMetadata", "orders", "note").Return(map[string]interface{}{"ExampleValues": []string{"gift"}, "DistinctCount": 5}, nil)
+
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+		TableFilters: map[string][]string{"orders": {"amount", "note", "status"}},
+		ColumnEnrichments: map[string]map[string][]string{
+			"orders": {"amount": {"examples"}, "status": {"description"}},
+		},
+		Enrichments:       map[string]bool{"distinct_values": true},
+		AdditionalContext: "orders docs",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []*ColumnMetadata{
+		{Table: "orders", Column: "amount", DataType: "int", ExampleValues: []string{"10", "20"}, Enrichments: map[string]bool{"examples": true}},
+		{Table: "orders", Column: "note", DataType: "text", DistinctCount: 5},
+		{Table: "orders", Column: "status", DataType: "text", Description: "Order status", Enrichments: map[string]bool{"description": true}},
+	}, clearProfiledAt(snapshot.Columns))
+	// status only asked for a description, so the database is not queried for it.
+	collectAdapter.AssertNotCalled(t, "GetColumnMetadata", "orders", "status")
+
+	generateAdapter := &MockDBAdapter{}
+	generateAdapter.On("GenerateTableCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+	for column, enrichments := range map[string]map[string]bool{
+		"amount": {"examples": true},
+		"note":   {"distinct_values": true},
+		"status": {"description": true},
+	} {
+		column := column
+		generateAdapter.On("GenerateCommentSQL", mock.MatchedBy(func(d *database.CommentData) bool {
+			return d.ColumnName == column
+		}), enrichments).Return("COMMENT "+column+";", nil)
+	}
+	sqls := NewService(generateAdapter, nil, Config{}).GenerateSQLFromSnapshot(snapshot)
+	assert.Equal(t, []string{"COMMENT amount;", "COMMENT note;", "COMMENT status;"}, sqls)
+	generateAdapter.AssertExpectations(t)
+}
+
+func TestCollectMetadataSkipsColumnsWithoutEnrichableData(t *testing.T) {
+	tests := []struct {
+		name              string
+		enrichments       map[string]bool
+		additionalContext string
+		expectedColumn    *ColumnMetadata
+		expectDescribed   bool
+	}{
+		{
+			name:           "description_without_context_skipped",
+			enrichments:    map[string]bool{"description": true},
+			expectedColumn: &ColumnMetadata{Table: "orders", Column: "status", DataType: "text"},
+		},
+		{
+			name:              "description_with_context_generated",
+			enrichments:       map[string]bool{"description": true},
+			additionalContext: "orders docs",
+			expectedColumn:    &ColumnMetadata{Table: "orders", Column: "status", DataType: "text", Description: "Order status"},
+			expectDescribed:   true,
+		},
+		{
+			name:           "foreign_keys_without_statistics_query",
+			enrichments:    map[string]bool{"description": true, "foreign_keys": true},
+			expectedColumn: &ColumnMetadata{Table: "orders", Column: "status", DataType: "text", ForeignKeys: []database.ForeignKeyReference{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAdapter := &MockDBAdapter{}
+			llm := &fakeLLMClient{columnDescriptions: map[string]string{"status": "Order status"}, contexts: map[string]string{}}
+			service := NewService(mockAdapter, llm, Config{})
+
+			mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+			mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+			mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "status", DataType: "text"}}, nil)
+			mockAdapter.On("GetForeignKeys", "orders", "status").Return([]database.ForeignKeyReference{}, nil)
+
+			snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+				Enrichments:       tt.enrichments,
+				AdditionalContext: tt.additionalContext,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, []*ColumnMetadata{tt.expectedColumn}, snapshot.Columns)
+			_, described := llm.contexts["orders.status"]
+			assert.Equal(t, tt.expectDescribed, described)
+			mockAdapter.AssertNotCalled(t, "GetColumnMetadata", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCollectMetadataWithExcludedEnrichments(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "amount", DataType: "int"}}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "amount").Return(map[string]interface{}{"ExampleValues": []string{"10"}, "DistinctCount": 4, "NullCount": 1}, nil)
+
+	// All enrichments except examples and foreign keys.
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+		Enrichments: map[string]bool{"examples": false, "foreign_keys": false},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnMetadata{
+		{Table: "orders", Column: "amount", DataType: "int", DistinctCount: 4, NullCount: 1},
+	}, clearProfiledAt(snapshot.Columns))
+	mockAdapter.AssertNotCalled(t, "GetForeignKeys", mock.Anything, mock.Anything)
+}
+
+func TestCollectMetadataReportsPartialEnrichment(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"customers", "orders"}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo(nil), errors.New("permission denied"))
+	mockAdapter.On("ListColumns", "customers").Return([]database.ColumnInfo{{Name: "id", DataType: "integer"}}, nil)
+	mockAdapter.On("GetAllColumnComments", "customers").Return(map[string]string{}, nil)
+	mockAdapter.On("GetColumnMetadata", "customers", "id").Return(map[string]interface{}{"NullCount": int64(0)}, nil)
+
+	_, err := service.CollectMetadata(context.Background(), GenerateSQLParams{Enrichments: map[string]bool{"null_count": true}})
+
+	var partial *ErrPartialEnrichment
+	if assert.ErrorAs(t, err, &partial) {
+		assert.Len(t, partial.Errs, 1)
+		assert.Contains(t, err.Error(), "encountered 1 error(s) during SQL generation")
+		assert.Contains(t, err.Error(), "Table[orders]")
+	}
+}
+
+func TestCollectMetadataStrictDescriptions(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			mockAdapter := &MockDBAdapter{}
+			llm := &fakeLLMClient{columnDescriptions: map[string]string{"id": "Order identifier."}}
+			service := NewService(mockAdapter, llm, Config{StrictDescriptions: strict})
+
+			mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+			mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+			mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+				{Name: "id", DataType: "int"},
+				{Name: "notes", DataType: "text"},
+			}, nil)
+
+			snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+				Enrichments:       map[string]bool{"description": true},
+				AdditionalContext: "orders docs",
+			})
+
+			if !strict {
+				assert.NoError(t, err)
+				assert.Len(t, snapshot.Columns, 2)
+				return
+			}
+			var partial *ErrPartialEnrichment
+			if assert.ErrorAs(t, err, &partial) {
+				assert.Len(t, partial.Errs, 1)
+				assert.Contains(t, err.Error(), "Column[orders.notes] no description was generated")
+			}
+		})
+	}
+}
+
+func TestCollectMetadataColumnRelationships(t *testing.T) {
+	tests := []struct {
+		name          string
+		relationships bool
+		notes         string
+		maxLength     int
+		expected      string
+	}{
+		{"disabled", false, "start_date and end_date form the booking period.", 0, "Hotel room bookings"},
+		{"notes appended", true, "start_date and end_date form the booking period.", 0, "Hotel room bookings. Column relationships: start_date and end_date form the booking period."},
+		{"no notable relationships", true, "", 0, "Hotel room bookings"},
+		{"limit applied before the notes", true, "start_date and end_date form the booking period.", 10, "Hotel... Column relationships: start_date and end_date form the booking period."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAdapter := &MockDBAdapter{}
+			llm := &fakeLLMClient{
+				tableDescriptions: map[string]string{"bookings": "Hotel room bookings"},
+				relationships:     map[string]string{"bookings": tt.notes},
+				relationshipsCtx:  map[string]string{},
+			}
+			service := NewService(mockAdapter, llm, Config{DescribeColumnRelationships: tt.relationships, MaxDescriptionLength: tt.maxLength})
+
+			mockAdapter.On("ListTables").Return([]string{"bookings"}, nil)
+			mockAdapter.On("GetAllColumnComments", "bookings").Return(map[string]string{}, nil)
+			mockAdapter.On("ListColumns", "bookings").Return([]database.ColumnInfo{
+				{Name: "start_date", DataType: "date"},
+				{Name: "end_date", DataType: "date"},
+			}, nil)
+
+			snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+				Enrichments:       map[string]bool{"description": true},
+				AdditionalContext: "bookings docs",
+			})
+
+			assert.NoError(t, err)
+			if assert.Len(t, snapshot.Tables, 1) {
+				assert.Equal(t, tt.expected, snapshot.Tables[0].Description)
+			}
+			if !tt.relationships {
+				assert.Empty(t, llm.relationshipsCtx)
+				return
+			}
+			// The prompt gets the table's columns along with the context.
+			assert.Contains(t, llm.relationshipsCtx["bookings"], "bookings docs")
+			assert.Contains(t, llm.relationshipsCtx["bookings"], "start_date (date), end_date (date)")
+			// One call for the table and one per column: the relationships need no call of their own.
+			assert.Equal(t, 3, llm.singleCalls)
+		})
+	}
+}
+
+// blockingLLMClient holds the description of the column blockAt until the run is
+// cancelled, as an LLM call in flight when an interrupt arrives would.
+type blockingLLMClient struct {
+	*fakeLLMClient
+	blockAt string
+}
+
+func (c *blockingLLMClient) GenerateDescription(ctx context.Context, objectType, objectName, parentName, knowledgeContext string) (string, error) {
+	if objectName == c.blockAt {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return c.fakeLLMClient.GenerateDescription(ctx, objectType, objectName, parentName, knowledgeContext)
+}
+
+func TestCollectMetadataReturnsPartialSnapshotWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mockAdapter := &MockDBAdapter{}
+	llm := &blockingLLMClient{
+		fakeLLMClient: &fakeLLMClient{columnDescriptions: map[string]string{"id": "Order identifier."}},
+		blockAt:       "notes",
+	}
+	service := NewService(mockAdapter, llm, Config{})
+	// Interrupt once the first column is finished.
+	service.collected = func(table, column string) {
+		if column == "id" {
+			cancel()
+		}
+	}
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "int"},
+		{Name: "notes", DataType: "text"},
+	}, nil)
+
+	snapshot, err := service.CollectMetadata(ctx, GenerateSQLParams{
+		Enrichments:       map[string]bool{"description": true},
+		AdditionalContext: "orders docs",
+	})
+
+	var cancelled *ErrCancelled
+	assert.ErrorAs(t, err, &cancelled)
+	if assert.NotNil(t, snapshot) && assert.Len(t, snapshot.Columns, 1) {
+		// The finished column is kept whole; the interrupted one is left out.
+		assert.Equal(t, "id", snapshot.Columns[0].Column)
+		assert.Equal(t, "Order identifier.", snapshot.Columns[0].Description)
+	}
+}
+
+func TestCollectMetadataAppliesGlossary(t *testing.T) {
+	glossary := map[string]string{"createdat": "When the record was created, in UTC."}
+	tests := []struct {
+		name     string
+		llm      *fakeLLMClient
+		context  string
+		expected string
+	}{
+		{"without LLM", nil, "", "When the record was created, in UTC."},
+		{"prepended to LLM description", &fakeLLMClient{columnDescriptions: map[string]string{"created_at": "Set on insert."}}, "orders docs", "When the record was created, in UTC. Set on insert."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAdapter := &MockDBAdapter{}
+			var service *Service
+			if tt.llm != nil {
+				service = NewService(mockAdapter, tt.llm, Config{})
+			} else {
+				service = NewService(mockAdapter, nil, Config{})
+			}
+
+			mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+			mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+			mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+				{Name: "created_at", DataType: "timestamp"},
+				{Name: "status", DataType: "text"},
+			}, nil)
+
+			snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+				Enrichments:       map[string]bool{"description": true},
+				AdditionalContext: tt.context,
+				Glossary:          glossary,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, snapshot.Columns[0].Description)
+			assert.Equal(t, "status", snapshot.Columns[1].Column)
+			assert.Empty(t, snapshot.Columns[1].Description)
+		})
+	}
+}
+
+func TestGenerateCommentSQLsWithContextDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"orders.md":       "Orders placed in the web shop.",
+		"orders.total.md": "Total in cents, including tax.",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mockAdapter := &MockDBAdapter{}
+	llm := &fakeLLMClient{contexts: map[string]string{}}
+	service := NewService(mockAdapter, llm, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "status", DataType: "text"},
+		{Name: "total", DataType: "int"},
+	}, nil)
+	mockAdapter.On("GenerateTableCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+	mockAdapter.On("GenerateCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+
+	_, err := service.GenerateCommentSQLs(context.Background(), GenerateSQLParams{
+		Enrichments:       map[string]bool{"description": true},
+		AdditionalContext: "global docs",
+		ContextDir:        dir,
+	})
+	assert.NoError(t, err)
+
+	tableContext := llm.contexts[".orders"]
+	assert.True(t, strings.HasPrefix(tableContext, "global docs"))
+	assert.Contains(t, tableContext, files["orders.md"])
+	assert.NotContains(t, tableContext, files["orders.total.md"])
+
+	// status has no column file, which is not an error; it still gets the table file.
+	assert.Contains(t, llm.contexts["orders.status"], files["orders.md"])
+	assert.NotContains(t, llm.contexts["orders.status"], files["orders.total.md"])
+	assert.Contains(t, llm.contexts["orders.total"], files["orders.md"])
+	assert.Contains(t, llm.contexts["orders.total"], files["orders.total.md"])
+}
+
+func TestColumnDescriptionsSeeTableDescription(t *testing.T) {
+	for _, batch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batch=%t", batch), func(t *testing.T) {
+			mockAdapter := &MockDBAdapter{}
+			llm := &fakeLLMClient{
+				contexts:           map[string]string{},
+				tableDescriptions:  map[string]string{"orders": "Orders placed in the web shop"},
+				columnDescriptions: map[string]string{"status": "Fulfilment state of the order"},
+			}
+			service := NewService(mockAdapter, llm, Config{BatchDescriptions: batch})
+
+			mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+			mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+			mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "status", DataType: "text"}}, nil)
+
+			_, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+				Enrichments:       map[string]bool{"description": true},
+				AdditionalContext: "shop docs",
+			})
+			assert.NoError(t, err)
+
+			// The table is described first, without its own description in the context.
+			assert.Equal(t, "shop docs", llm.contexts[".orders"])
+			columnContext := llm.contexts["orders.status"]
+			if batch {
+				assert.Len(t, llm.batchContexts, 1)
+				columnContext = llm.batchContexts[0]
+			}
+			assert.Equal(t, "shop docs\n\nDescription of table orders: Orders placed in the web shop\n", columnContext)
+		})
+	}
+}
+
+func TestWithTableDescription(t *testing.T) {
+	assert.Equal(t, "", withTableDescription("", "orders", "Orders"), "no context means nothing is described")
+	assert.Equal(t, "docs\n", withTableDescription("docs\n", "orders", ""))
+	assert.Equal(t, "docs\n\nDescription of table orders: Orders\n", withTableDescription("docs\n", "orders", "Orders"))
+}
+
+func TestGetCommentsReadsColumnCommentsInBulk(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetTableComment", "orders").Return("Customer orders", nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "int"},
+		{Name: "total", DataType: "int"},
+	}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{"total": "Order total in cents"}, nil)
+
+	comments, err := service.GetComments(context.Background(), GetCommentsParams{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnComment{
+		{Table: "orders", Comment: "Customer orders"},
+		{Table: "orders", Column: "total", Comment: "Order total in cents"},
+	}, comments)
+	mockAdapter.AssertNotCalled(t, "GetColumnComment", mock.Anything, mock.Anything)
+	mockAdapter.AssertExpectations(t)
+}
+
+func TestGetCommentsFallsBackToPerColumnReads(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetTableComment", "orders").Return("", nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "int"},
+		{Name: "total", DataType: "int"},
+	}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string(nil), errors.New("permission denied for pg_description"))
+	mockAdapter.On("GetColumnComment", "orders", "id").Return("", sql.ErrNoRows)
+	mockAdapter.On("GetColumnComment", "orders", "total").Return("Order total in cents", nil)
+
+	comments, err := service.GetComments(context.Background(), GetCommentsParams{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnComment{
+		{Table: "orders", Column: "total", Comment: "Order total in cents"},
+	}, comments)
+	mockAdapter.AssertExpectations(t)
+}
+
+func TestGetCommentsParsesProvenance(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	metadata := database.GenerateMetadataCommentString(&database.CommentData{
+		Description:   "Order total in cents",
+		DistinctCount: 42,
+		Provenance:    true,
+	}, map[string]bool{"description": true, "distinct_values": true}, "", "")
+	stored := database.MergeComments("Totals", metadata, "overwrite")
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetTableComment", "orders").Return("", nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "total", DataType: "int"}}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{"total": stored}, nil)
+
+	comments, err := service.GetComments(context.Background(), GetCommentsParams{})
+
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, stored, comments[0].Comment)
+		assert.Equal(t, map[string]string{
+			"description":     database.ProvenanceInferred,
+			"distinct_values": database.ProvenanceComputed,
+		}, comments[0].Provenance)
+	}
+}
+
+func TestGetCommentsBoundsConcurrency(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{MaxConcurrency: 2})
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	tables := []string{"a", "b", "c", "d", "e", "f"}
+	mockAdapter.On("ListTables").Return(tables, nil)
+	mockAdapter.On("GetTableComment", mock.Anything).Return("", nil).Run(func(mock.Arguments) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	})
+	mockAdapter.On("ListColumns", mock.Anything).Return([]database.ColumnInfo{{Name: "id", DataType: "int"}}, nil)
+	mockAdapter.On("GetAllColumnComments", mock.Anything).Return(map[string]string{"id": "Key"}, nil)
+
+	comments, err := service.GetComments(context.Background(), GetCommentsParams{})
+
+	assert.NoError(t, err)
+	assert.Len(t, comments, len(tables))
+	assert.LessOrEqual(t, maxInFlight, 2)
+	mockAdapter.AssertNumberOfCalls(t, "GetTableComment", len(tables))
+}
+
+func TestGetCommentsStreamsTables(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{MaxConcurrency: 1})
+
+	mockAdapter.On("ListTables").Return([]string{"orders", "customers"}, nil)
+	mockAdapter.On("GetTableComment", "orders").Return("Customer orders", nil)
+	mockAdapter.On("GetTableComment", "customers").Return("", nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "total", DataType: "int"}, {Name: "id", DataType: "int"}}, nil)
+	mockAdapter.On("ListColumns", "customers").Return([]database.ColumnInfo{{Name: "email", DataType: "text"}}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{"id": "Key", "total": "Total"}, nil)
+	mockAdapter.On("GetAllColumnComments", "customers").Return(map[string]string{}, nil)
+
+	t.Run("each table is streamed sorted", func(t *testing.T) {
+		var streamed [][]*ColumnComment
+		comments, err := service.GetComments(context.Background(), GetCommentsParams{OnTable: func(tableComments []*ColumnComment) error {
+			streamed = append(streamed, tableComments)
+			return nil
+		}})
+
+		assert.NoError(t, err)
+		assert.Empty(t, comments)
+		assert.Equal(t, [][]*ColumnComment{{
+			{Table: "orders", Comment: "Customer orders"},
+			{Table: "orders", Column: "id", Comment: "Key"},
+			{Table: "orders", Column: "total", Comment: "Total"},
+		}}, streamed, "tables without comments are not streamed")
+	})
+
+	t.Run("a failing writer stops the retrieval", func(t *testing.T) {
+		_, err := service.GetComments(context.Background(), GetCommentsParams{OnTable: func([]*ColumnComment) error {
+			return errors.New("disk full")
+		}})
+		assert.ErrorContains(t, err, "disk full")
+	})
+}
+
+func TestListColumnsIsCachedPerTable(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders", "customers"}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "total", DataType: "int"}}, nil).Once()
+	mockAdapter.On("ListColumns", "customers").Return([]database.ColumnInfo{{Name: "email", DataType: "text"}}, nil).Once()
+	mockAdapter.On("GetAllColumnComments", mock.Anything).Return(map[string]string{}, nil)
+	mockAdapter.On("GetColumnMetadata", mock.Anything, mock.Anything).Return(map[string]interface{}{}, nil)
+	mockAdapter.On("GetTableComment", mock.Anything).Return("", nil)
+	mockAdapter.On("GenerateTableCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+	mockAdapter.On("GenerateCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+
+	_, err := service.GenerateCommentSQLs(context.Background(), GenerateSQLParams{Enrichments: map[string]bool{"examples": true}})
+	assert.NoError(t, err)
+	_, err = service.GetComments(context.Background(), GetCommentsParams{})
+	assert.NoError(t, err)
+	_, err = service.GeneratePIIReport(context.Background(), PIIReportParams{})
+	assert.NoError(t, err)
+
+	mockAdapter.AssertNumberOfCalls(t, "ListColumns", 2)
+	mockAdapter.AssertExpectations(t)
+}
+
+func TestGenerateDeleteCommentChanges(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GenerateDeleteTableCommentSQL", "orders").Return("COMMENT ON TABLE orders IS NULL;", nil)
+	mockAdapter.On("GetTableComment", "orders").Return("<gemini>Customer orders</gemini>", nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "note", DataType: "text"},
+		{Name: "total", DataType: "int"},
+	}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{
+		"note":  "Free-form note",
+		"total": "Order total <gemini>Examples: '12'</gemini> in cents",
+	}, nil)
+	// note has no <gemini> tags, so the handler generates nothing for it.
+	mockAdapter.On("GenerateDeleteCommentSQL", "orders", "note").Return("", nil)
+	mockAdapter.On("GenerateDeleteCommentSQL", "orders", "total").Return("COMMENT ON COLUMN orders.total IS 'Order total in cents';", nil)
+	mockAdapter.On("GetColumnComment", "orders", "total").Return("Order total <gemini>Examples: '12'</gemini> in cents", nil)
+
+	changes, err := service.GenerateDeleteCommentChanges(context.Background(), GenerateDeleteSQLParams{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*CommentChange{
+		{Table: "orders", Before: "<gemini>Customer orders</gemini>", After: "", SQL: "COMMENT ON TABLE orders IS NULL;"},
+		{Table: "orders", Column: "total", Before: "Order total <gemini>Examples: '12'</gemini> in cents", After: "Order total in cents", SQL: "COMMENT ON COLUMN orders.total IS 'Order total in cents';"},
+	}, changes)
+	mockAdapter.AssertNotCalled(t, "GetColumnComment", "orders", "note")
+	mockAdapter.AssertExpectations(t)
+}
+
+func TestGenerateDeleteCommentChangesStripStats(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GenerateRewriteTableCommentSQL", "orders").Return("", nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "total", DataType: "int"}}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{
+		"total": "Order total | Distinct: 12 <gemini>Examples: '12'</gemini>",
+	}, nil)
+	mockAdapter.On("GenerateRewriteCommentSQL", "orders", "total").Return("COMMENT ON COLUMN orders.total IS 'Order total';", nil)
+	mockAdapter.On("GetColumnComment", "orders", "total").Return("Order total | Distinct: 12 <gemini>Examples: '12'</gemini>", nil)
+
+	changes, err := service.GenerateDeleteCommentChanges(context.Background(), GenerateDeleteSQLParams{StripStats: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*CommentChange{
+		{Table: "orders", Column: "total", Before: "Order total | Distinct: 12 <gemini>Examples: '12'</gemini>", After: "Order total", SQL: "COMMENT ON COLUMN orders.total IS 'Order total';"},
+	}, changes)
+	mockAdapter.AssertNotCalled(t, "GenerateDeleteCommentSQL", mock.Anything, mock.Anything)
+	mockAdapter.AssertExpectations(t)
+}
+
+func TestGenerateDeleteCommentChangesScope(t *testing.T) {
+	newAdapter := func() *MockDBAdapter {
+		mockAdapter := &MockDBAdapter{}
+		mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+		mockAdapter.On("GenerateDeleteTableCommentSQL", "orders").Return("COMMENT ON TABLE orders IS NULL;", nil)
+		mockAdapter.On("GetTableComment", "orders").Return("<gemini>Customer orders</gemini>", nil)
+		mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "total", DataType: "int"}}, nil)
+		mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{"total": "<gemini>Examples: '12'</gemini>"}, nil)
+		mockAdapter.On("GenerateDeleteCommentSQL", "orders", "total").Return("COMMENT ON COLUMN orders.total IS NULL;", nil)
+		mockAdapter.On("GetColumnComment", "orders", "total").Return("<gemini>Examples: '12'</gemini>", nil)
+		return mockAdapter
+	}
+	tableChange := &CommentChange{Table: "orders", Before: "<gemini>Customer orders</gemini>", After: "", SQL: "COMMENT ON TABLE orders IS NULL;"}
+	columnChange := &CommentChange{Table: "orders", Column: "total", Before: "<gemini>Examples: '12'</gemini>", After: "", SQL: "COMMENT ON COLUMN orders.total IS NULL;"}
+
+	t.Run("all", func(t *testing.T) {
+		changes, err := NewService(newAdapter(), nil, Config{}).GenerateDeleteCommentChanges(context.Background(), GenerateDeleteSQLParams{Scope: "all"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*CommentChange{tableChange, columnChange}, changes)
+	})
+
+	t.Run("tables", func(t *testing.T) {
+		mockAdapter := newAdapter()
+		changes, err := NewService(mockAdapter, nil, Config{}).GenerateDeleteCommentChanges(context.Background(), GenerateDeleteSQLParams{Scope: "tables"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*CommentChange{tableChange}, changes)
+		mockAdapter.AssertNotCalled(t, "ListColumns", "orders")
+		mockAdapter.AssertNotCalled(t, "GenerateDeleteCommentSQL", mock.Anything, mock.Anything)
+	})
+
+	t.Run("columns", func(t *testing.T) {
+		mockAdapter := newAdapter()
+		changes, err := NewService(mockAdapter, nil, Config{}).GenerateDeleteCommentChanges(context.Background(), GenerateDeleteSQLParams{Scope: "columns"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*CommentChange{columnChange}, changes)
+		mockAdapter.AssertNotCalled(t, "GenerateDeleteTableCommentSQL", mock.Anything)
+	})
+}
+
+func TestFormatCommentChangesAsText(t *testing.T) {
+	changes := []*CommentChange{
+		{Table: "orders", Before: "<gemini>Customer orders</gemini>", After: ""},
+		{Table: "orders", Column: "total", Before: "Total <gemini>Examples: '12'</gemini>", After: "Total"},
+	}
+	want := "orders (table)\n- <gemini>Customer orders</gemini>\n+ (no comment)\n\norders.total\n- Total <gemini>Examples: '12'</gemini>\n+ Total\n"
+	assert.Equal(t, want, FormatCommentChangesAsText(changes))
+	assert.Equal(t, "No comments will change.\n", FormatCommentChangesAsText(nil))
+}
+
+func TestFormatCommentsAsJSON(t *testing.T) {
+	comments := []*ColumnComment{
+		{Table: "orders", Column: "total", Comment: "Order total", Provenance: map[string]string{"description": "inferred"}},
+		{Table: "customers", Column: "id", Comment: "Customer key"},
+		{Table: "orders", Comment: "Customer orders"},
+	}
+	got, err := FormatCommentsAsJSON(comments)
+	assert.NoError(t, err)
+	want := `[
+  {
+    "table": "customers",
+    "column": "id",
+    "comment": "Customer key"
+  },
+  {
+    "table": "orders",
+    "column": "",
+    "comment": "Customer orders"
+  },
+  {
+    "table": "orders",
+    "column": "total",
+    "comment": "Order total",
+    "provenance": {
+      "description": "inferred"
+    }
+  }
+]
+`
+	assert.Equal(t, want, string(got))
+	assert.Equal(t, "orders", comments[0].Table, "the caller's slice is left in its order")
+
+	empty, err := FormatCommentsAsJSON(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]\n", string(empty))
+}
+
+func TestGenerateRepairCommentChanges(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GenerateRewriteTableCommentSQL", "orders").Return("", nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "note", DataType: "text"},
+		{Name: "total", DataType: "int"},
+	}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{
+		"note":  "Free-form <gemini>Description: a note</gemini>",
+		"total": "Order total <gemini>Examples: '1",
+	}, nil)
+	mockAdapter.On("GenerateRewriteCommentSQL", "orders", "note").Return("", nil)
+	mockAdapter.On("GenerateRewriteCommentSQL", "orders", "total").Return("COMMENT ON COLUMN orders.total IS 'Order total';", nil)
+	mockAdapter.On("GetColumnComment", "orders", "total").Return("Order total <gemini>Examples: '1", nil)
+
+	changes, err := service.GenerateRepairCommentChanges(context.Background(), GenerateRepairSQLParams{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*CommentChange{
+		{Table: "orders", Column: "total", Before: "Order total <gemini>Examples: '1", After: "Order total", SQL: "COMMENT ON COLUMN orders.total IS 'Order total';"},
+	}, changes)
+	mockAdapter.AssertExpectations(t)
+}
+
+func TestNoTablesToProcess(t *testing.T) {
+	tests := []struct {
+		name         string
+		tables       []string
+		tableFilters map[string][]string
+		wantMessage  string
+	}{
+		{
+			name:        "empty_database",
+			tables:      []string{},
+			wantMessage: "INFO: The database has no tables in the connection's default schema. Nothing to do for retrieval.",
+		},
+		{
+			name:         "empty_database_with_filters",
+			tables:       []string{},
+			tableFilters: map[string][]string{"orders": nil},
+			wantMessage:  "INFO: The database has no tables in the connection's default schema. Nothing to do for retrieval.",
+		},
+		{
+			name:         "filtered_to_empty",
+			tables:       []string{"customers", "orders"},
+			tableFilters: map[string][]string{"invoices": nil},
+			wantMessage:  "INFO: None of the 2 table(s) in the database match the provided filters (--tables) for retrieval.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantMessage, noTablesMessage(tt.tables, "retrieval"))
+
+			mockAdapter := &MockDBAdapter{}
+			service := NewService(mockAdapter, nil, Config{})
+			mockAdapter.On("ListTables").Return(tt.tables, nil)
+
+			sqls, err := service.GenerateCommentSQLs(context.Background(), GenerateSQLParams{TableFilters: tt.tableFilters})
+			assert.NoError(t, err)
+			assert.Empty(t, sqls)
+
+			deleteSQLs, err := service.GenerateDeleteCommentSQLs(context.Background(), GenerateDeleteSQLParams{TableFilters: tt.tableFilters})
+			assert.NoError(t, err)
+			assert.Empty(t, deleteSQLs)
+
+			comments, err := service.GetComments(context.Background(), GetCommentsParams{TableFilters: tt.tableFilters})
+			assert.NoError(t, err)
+			assert.Empty(t, comments)
+
+			mockAdapter.AssertNotCalled(t, "ListColumns", mock.Anything)
+			mockAdapter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFilterTablesSchemaQualified(t *testing.T) {
+	allTables := []string{"analytics.orders", "orders", "sales.orders", "users"}
+	columns := []database.ColumnInfo{{Name: "id"}, {Name: "total"}}
+
+	tests := []struct {
+		name            string
+		filters         map[string][]string
+		expectedTables  []string
+		expectedColumns map[string][]database.ColumnInfo
+	}{
+		{
+			name:           "qualified entry matches only its schema",
+			filters:        map[string][]string{"analytics.orders": {"id"}},
+			expectedTables: []string{"analytics.orders"},
+			expectedColumns: map[string][]database.ColumnInfo{
+				"analytics.orders": {{Name: "id"}},
+			},
+		},
+		{
+			name:           "bare entry matches the table in any schema",
+			filters:        map[string][]string{"orders": {"total"}},
+			expectedTables: []string{"analytics.orders", "orders", "sales.orders"},
+			expectedColumns: map[string][]database.ColumnInfo{
+				"orders":       {{Name: "total"}},
+				"sales.orders": {{Name: "total"}},
+			},
+		},
+		{
+			name:           "qualified entry wins over bare entry",
+			filters:        map[string][]string{"orders": {"total"}, "sales.orders": {"id"}},
+			expectedTables: []string{"analytics.orders", "orders", "sales.orders"},
+			expectedColumns: map[string][]database.ColumnInfo{
+				"analytics.orders": {{Name: "total"}},
+				"sales.orders":     {{Name: "id"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedTables, filterTables(allTables, tt.filters))
+			for table, expected := range tt.expectedColumns {
+				assert.Equal(t, expected, filterColumns(table, columns, tt.filters), table)
+			}
+		})
+	}
+}
+
+func TestListTablesMatchesPostgresSchemaQualifiedEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		filters map[string][]string
+	}{
+		{name: "entry qualified with --schema", schema: "sales", filters: map[string][]string{"sales.orders": {"id"}, "archive.customers": nil}},
+		{name: "entry qualified with the default schema", filters: map[string][]string{"public.orders": {"id"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAdapter := &MockDBAdapter{}
+			service := NewService(mockAdapter, nil, Config{})
+
+			mockAdapter.On("GetConfig").Return(config.DatabaseConfig{Dialect: "postgres", Schema: tt.schema})
+			mockAdapter.On("ListTables").Return([]string{"customers", "orders"}, nil)
+			mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+				{Name: "id", DataType: "int"},
+				{Name: "total", DataType: "int"},
+			}, nil)
+
+			listings, err := service.ListTables(context.Background(), ListTablesParams{TableFilters: tt.filters, CountColumns: true})
+
+			assert.NoError(t, err)
+			one := 1
+			assert.Equal(t, []TableListing{{Table: "orders", ColumnCount: &one}}, listings)
+		})
+	}
+}
+
+func TestCollectMetadataWarnsAboutSlowColumnQueries(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{SlowQueryWarn: 20 * time.Millisecond})
+
+	mockAdapter.On("ListTables").Return([]string{"events"}, nil)
+	mockAdapter.On("GetAllColumnComments", "events").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "events").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "int"},
+		{Name: "payload", DataType: "text"},
+	}, nil)
+	mockAdapter.On("GetColumnMetadata", "events", "id").Return(map[string]interface{}{"NullCount": int64(0)}, nil)
+	mockAdapter.On("GetColumnMetadata", "events", "payload").Return(map[string]interface{}{"NullCount": int64(0)}, nil).After(60 * time.Millisecond)
+
+	_, err := service.CollectMetadata(context.Background(), GenerateSQLParams{Enrichments: map[string]bool{"null_count": true}})
+
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), "WARN: Column[events.payload] Metadata queries took")
+	assert.Contains(t, logs.String(), "over --slow-query-warn 20ms")
+	assert.NotContains(t, logs.String(), "Column[events.id] Metadata queries took")
+}
+
+func TestCollectMetadataSkipsStatisticsForUnsupportedTypes(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "integer"},
+		{Name: "contact", DataType: "email_address (domain over text)"},
+		{Name: "shipping", DataType: "address (composite)", StatsUnsupported: true},
+	}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "id").Return(map[string]interface{}{"NullCount": int64(0)}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "contact").Return(map[string]interface{}{"NullCount": int64(2)}, nil)
+
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{Enrichments: map[string]bool{"null_count": true}})
+
+	assert.NoError(t, err)
+	mockAdapter.AssertNotCalled(t, "GetColumnMetadata", "orders", "shipping")
+	for _, col := range snapshot.Columns {
+		if col.Column == "contact" {
+			assert.Equal(t, int64(2), col.NullCount)
+			assert.Equal(t, "email_address (domain over text)", col.DataType)
+		}
+	}
+}
+
+func TestCollectMetadataListsJSONKeys(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"products"}, nil)
+	mockAdapter.On("GetAllColumnComments", "products").Return(map[string]string{}, nil)
+	mockAdapter.On("GetTableComment", "products").Return("", nil)
+	mockAdapter.On("ListColumns", "products").Return([]database.ColumnInfo{
+		{Name: "attributes", DataType: "jsonb"},
+		{Name: "name", DataType: "text"},
+		{Name: "settings", DataType: "product_settings (domain over json)", BaseType: "json"},
+	}, nil)
+	mockAdapter.On("JSONKeys", "products", "attributes").Return([]string{"id", "name", "tags"}, nil)
+	mockAdapter.On("JSONKeys", "products", "settings").Return([]string{"theme"}, nil)
+	mockAdapter.On("GenerateTableCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+	mockAdapter.On("GenerateCommentSQL", mock.MatchedBy(func(data *database.CommentData) bool {
+		return data.ColumnName == "attributes"
+	}), mock.Anything).Return("COMMENT ON attributes", nil)
+
+	enrichments := map[string]bool{"json_keys": true}
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{Enrichments: enrichments})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnMetadata{
+		{Table: "products", Column: "attributes", DataType: "jsonb", JSONKeys: []string{"id", "name", "tags"}},
+		{Table: "products", Column: "name", DataType: "text"},
+		{Table: "products", Column: "settings", DataType: "product_settings (domain over json)", JSONKeys: []string{"theme"}},
+	}, snapshot.Columns)
+	mockAdapter.AssertNotCalled(t, "JSONKeys", "products", "name")
+	mockAdapter.AssertNotCalled(t, "GetColumnMetadata", mock.Anything, mock.Anything)
+
+	snapshot.Columns = snapshot.Columns[:1]
+	assert.Equal(t, []string{"COMMENT ON attributes"}, service.GenerateSQLFromSnapshot(snapshot))
+	mockAdapter.AssertCalled(t, "GenerateCommentSQL", &database.CommentData{
+		TableName:      "products",
+		ColumnName:     "attributes",
+		ColumnDataType: "jsonb",
+		JSONKeys:       []string{"id", "name", "tags"},
+	}, enrichments)
+}
+
+func TestCollectMetadataAverage(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("GetTableComment", "orders").Return("", nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "amount", DataType: "numeric"},
+		{Name: "status", DataType: "text"},
+	}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "amount").Return(map[string]interface{}{"DistinctCount": int64(2), "NullCount": int64(0), "Average": 12.3456}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "status").Return(map[string]interface{}{"DistinctCount": int64(3), "NullCount": int64(1)}, nil)
+	mockAdapter.On("GenerateTableCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+	mockAdapter.On("GenerateCommentSQL", mock.Anything, mock.Anything).Return("COMMENT ON amount", nil)
+
+	enrichments := map[string]bool{"average": true}
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{Enrichments: enrichments})
+
+	assert.NoError(t, err)
+	avg := 12.3456
+	assert.Equal(t, &avg, snapshot.Columns[0].Average)
+	assert.Nil(t, snapshot.Columns[1].Average)
+
+	snapshot.Columns = snapshot.Columns[:1]
+	service.GenerateSQLFromSnapshot(snapshot)
+	mockAdapter.AssertCalled(t, "GenerateCommentSQL", mock.MatchedBy(func(data *database.CommentData) bool {
+		return data.ColumnName == "amount" && data.Average != nil && *data.Average == avg
+	}), enrichments)
+}
+
+func TestWithoutStatistics(t *testing.T) {
+	tests := []struct {
+		name        string
+		enrichments map[string]bool
+		requested   []string
+		skipped     []string
+	}{
+		{"all enrichments", map[string]bool{}, []string{"description", "foreign_keys"}, []string{"examples", "distinct_values", "null_count", "json_keys"}},
+		{"average", map[string]bool{"average": true, "description": true}, []string{"description"}, []string{"average"}},
+		{"exclusions", map[string]bool{"description": false}, []string{"foreign_keys"}, []string{"description", "examples", "null_count"}},
+		{"mixed inclusions", map[string]bool{"null_count": true, "description": true}, []string{"description"}, []string{"null_count", "foreign_keys"}},
+		{"statistics only", map[string]bool{"examples": true, "null_count": true}, nil, []string{"description", "foreign_keys", "examples", "null_count"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reduced := withoutStatistics(tt.enrichments)
+			for _, name := range tt.requested {
+				assert.True(t, isEnrichmentRequested(name, reduced), name)
+			}
+			for _, name := range tt.skipped {
+				assert.False(t, isEnrichmentRequested(name, reduced), name)
+			}
+		})
+	}
+}
+
+func TestTruncateDescription(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		maxLength   int
+		want        string
+	}{
+		{"no limit", "Order total in cents, including tax.", 0, "Order total in cents, including tax."},
+		{"within limit", "Order total", 11, "Order total"},
+		{"cut at word boundary", "Order total in cents, including tax and shipping.", 30, "Order total in cents..."},
+		{"word ends at cut", "Order total in cents and more", 23, "Order total in cents..."},
+		{"single long word", "Supercalifragilistic", 10, "Superca..."},
+		{"multibyte characters", "Prix total de la commande, taxes comprises", 20, "Prix total de la..."},
+		{"limit below ellipsis", "Order total", 2, "Or"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateDescription(tt.description, tt.maxLength)
+			assert.Equal(t, tt.want, got)
+			if tt.maxLength > 0 {
+				assert.LessOrEqual(t, len([]rune(got)), tt.maxLength)
+			}
+		})
+	}
+}
+
+func TestCollectMetadataTruncatesLongDescriptions(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	llm := &fakeLLMClient{
+		tableDescriptions:  map[string]string{"orders": "Orders placed in the web shop"},
+		columnDescriptions: map[string]string{"total": "The total amount charged for the order in cents, including tax, shipping and any discounts applied at checkout."},
+	}
+	service := NewService(mockAdapter, llm, Config{MaxDescriptionLength: 41})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "total", DataType: "int"}}, nil)
+
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+		Enrichments:       map[string]bool{"description": true},
+		AdditionalContext: "orders docs",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Orders placed in the web shop", snapshot.Tables[0].Description)
+	assert.Equal(t, "The total amount charged for the order...", snapshot.Columns[0].Description)
+}
+
+func TestCollectMetadataPreservesExistingDescriptions(t *testing.T) {
+	for _, batch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batch=%v", batch), func(t *testing.T) {
+			mockAdapter := &MockDBAdapter{}
+			llm := &fakeLLMClient{columnDescriptions: map[string]string{
+				"id":     "Order identifier",
+				"status": "Generated status description",
+			}}
+			service := NewService(mockAdapter, llm, Config{PreserveExistingDescription: true, BatchDescriptions: batch})
+
+			mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+			mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+			mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+				{Name: "id", DataType: "int"},
+				{Name: "status", DataType: "text"},
+			}, nil)
+			mockAdapter.On("GetColumnComment", "orders", "id").Return("<gemini>Null Count: 0</gemini>", nil)
+			mockAdapter.On("GetColumnComment", "orders", "status").Return("Lifecycle state, see the ops runbook. <gemini>Null Count: 0</gemini>", nil)
+			mockAdapter.On("GetColumnMetadata", "orders", "id").Return(map[string]interface{}{"NullCount": int64(0)}, nil)
+			mockAdapter.On("GetColumnMetadata", "orders", "status").Return(map[string]interface{}{"NullCount": int64(3)}, nil)
+
+			snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+				Enrichments:       map[string]bool{"description": true, "null_count": true},
+				AdditionalContext: "orders docs",
+			})
+
+			assert.NoError(t, err)
+			for _, col := range snapshot.Columns {
+				switch col.Column {
+				case "id":
+					assert.Equal(t, "Order identifier", col.Description)
+				case "status":
+					assert.Empty(t, col.Description)
+					assert.Equal(t, int64(3), col.NullCount)
+					assert.False(t, isEnrichmentRequested("description", col.Enrichments))
+				}
+			}
+			if batch {
+				assert.Equal(t, [][]string{{"id"}}, llm.batchColumns)
+			} else {
+				// The table and orders.id; orders.status keeps its description.
+				assert.Equal(t, 2, llm.singleCalls)
+			}
+		})
+	}
+}
+
+func TestCollectMetadataSkipsDescriptionPatterns(t *testing.T) {
+	for _, batch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batch=%v", batch), func(t *testing.T) {
+			mockAdapter := &MockDBAdapter{}
+			llm := &fakeLLMClient{columnDescriptions: map[string]string{
+				"is_active":   "Whether the customer is active",
+				"Customer_ID": "Customer reference",
+				"status":      "Order status",
+				"idle_since":  "When the customer went idle",
+			}}
+			service := NewService(mockAdapter, llm, Config{SkipDescriptionPatterns: []string{"is_*", "*_id"}, BatchDescriptions: batch})
+
+			mockAdapter.On("ListTables").Return([]string{"customers"}, nil)
+			mockAdapter.On("GetAllColumnComments", "customers").Return(map[string]string{}, nil)
+			mockAdapter.On("ListColumns", "customers").Return([]database.ColumnInfo{
+				{Name: "is_active", DataType: "boolean"},
+				{Name: "Customer_ID", DataType: "int"},
+				{Name: "status", DataType: "text"},
+				{Name: "idle_since", DataType: "timestamp"},
+			}, nil)
+			mockAdapter.On("GetColumnMetadata", "customers", mock.Anything).Return(map[string]interface{}{"NullCount": int64(0)}, nil)
+
+			snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+				Enrichments:       map[string]bool{"description": true, "null_count": true},
+				AdditionalContext: "customer docs",
+			})
+
+			assert.NoError(t, err)
+			descriptions := map[string]string{}
+			for _, col := range snapshot.Columns {
+				descriptions[col.Column] = col.Description
+			}
+			assert.Equal(t, map[string]string{
+				"is_active":   "",
+				"Customer_ID": "",
+				"status":      "Order status",
+				"idle_since":  "When the customer went idle",
+			}, descriptions)
+			if batch {
+				assert.Equal(t, [][]string{{"status", "idle_since"}}, llm.batchColumns)
+			} else {
+				// The table, status and idle_since.
+				assert.Equal(t, 3, llm.singleCalls)
+			}
+			mockAdapter.AssertNumberOfCalls(t, "GetColumnMetadata", 4)
+		})
+	}
+}
+
+func TestColumnsMatchingSkipPatternsKeepsGlossaryDefinitions(t *testing.T) {
+	service := NewService(&MockDBAdapter{}, nil, Config{SkipDescriptionPatterns: []string{"*_id"}})
+	columns := []database.ColumnInfo{{Name: "customer_id"}, {Name: "order_id"}, {Name: "status"}}
+	glossary := map[string]string{"orderid": "The order's number"}
+
+	assert.Equal(t, map[string]bool{"customer_id": true}, service.columnsMatchingSkipPatterns(columns, glossary))
+}
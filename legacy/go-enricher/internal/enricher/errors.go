@@ -18,6 +18,7 @@ package enricher
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // ErrDatabaseConnection represents errors that occur during database connection attempts
@@ -51,7 +52,7 @@ type ErrCancelled struct {
 }
 
 func (e *ErrDatabaseConnection) Error() string {
-	return fmt.Sprintf("database connection error: %s: %w", e.Msg, e.Err)
+	return fmt.Sprintf("database connection error: %s: %v", e.Msg, e.Err)
 }
 
 func (e *ErrDatabaseConnection) Unwrap() error {
@@ -59,7 +60,7 @@ func (e *ErrDatabaseConnection) Unwrap() error {
 }
 
 func (e *ErrQueryExecution) Error() string {
-	return fmt.Sprintf("query execution error: %s: %w", e.Msg, e.Err)
+	return fmt.Sprintf("query execution error: %s: %v", e.Msg, e.Err)
 }
 
 func (e *ErrQueryExecution) Unwrap() error {
@@ -67,7 +68,7 @@ func (e *ErrQueryExecution) Unwrap() error {
 }
 
 func (e *ErrInvalidInput) Error() string {
-	return fmt.Sprintf("invalid input error: %s: %w", e.Msg, e.Err)
+	return fmt.Sprintf("invalid input error: %s: %v", e.Msg, e.Err)
 }
 
 func (e *ErrInvalidInput) Unwrap() error {
@@ -75,7 +76,7 @@ func (e *ErrInvalidInput) Unwrap() error {
 }
 
 func (e *ErrTimeout) Error() string {
-	return fmt.Sprintf("timeout error: %s: %w", e.Msg, e.Err)
+	return fmt.Sprintf("timeout error: %s: %v", e.Msg, e.Err)
 }
 
 func (e *ErrTimeout) Unwrap() error {
@@ -83,9 +84,43 @@ func (e *ErrTimeout) Unwrap() error {
 }
 
 func (e *ErrCancelled) Error() string {
-	return fmt.Sprintf("operation cancelled: %s: %w", e.Msg, e.Err)
+	return fmt.Sprintf("operation cancelled: %s: %v", e.Msg, e.Err)
 }
 
 func (e *ErrCancelled) Unwrap() error {
 	return errors.Unwrap(e.Err)
 }
+
+// ErrLLMAuth represents errors authenticating with the LLM provider, such as a missing
+// or rejected API key.
+type ErrLLMAuth struct {
+	Msg string
+	Err error
+}
+
+func (e *ErrLLMAuth) Error() string {
+	return fmt.Sprintf("LLM authentication error: %s: %v", e.Msg, e.Err)
+}
+
+func (e *ErrLLMAuth) Unwrap() error {
+	return e.Err
+}
+
+// ErrPartialEnrichment represents a run in which some tables or columns failed while
+// the others were processed. Errs holds the individual failures.
+type ErrPartialEnrichment struct {
+	Operation string
+	Errs      []error
+}
+
+func (e *ErrPartialEnrichment) Error() string {
+	messages := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("encountered %d error(s) during %s:\n- %s", len(e.Errs), e.Operation, strings.Join(messages, "\n- "))
+}
+
+func (e *ErrPartialEnrichment) Unwrap() []error {
+	return e.Errs
+}
@@ -0,0 +1,112 @@
+package enricher
+
+import (
+	"context"
+	"fmt"
+)
+
+// Rough token sizes of the prompts and responses of each kind of LLM call, used to
+// estimate usage before running for real. Context passed with description prompts
+// is counted on top, at about four bytes per token.
+const (
+	estimatedPromptTokens            = 400
+	estimatedDescriptionOutputTokens = 60
+	estimatedPIIOutputTokens         = 120
+	estimatedBytesPerToken           = 4
+)
+
+// LLMUsageEstimate is the approximate number of LLM calls and tokens a run makes.
+// ForeignKeyColumns counts the columns whose foreign keys are also checked: each
+// reference found runs a match ratio query, not an LLM call, that reads both tables,
+// which BigQuery bills by bytes scanned.
+type LLMUsageEstimate struct {
+	DescriptionCalls  int
+	PIICalls          int
+	InputTokens       int64
+	OutputTokens      int64
+	ForeignKeyColumns int
+}
+
+// Calls returns the total number of LLM calls in the estimate.
+func (e LLMUsageEstimate) Calls() int {
+	return e.DescriptionCalls + e.PIICalls
+}
+
+// EstimateLLMUsage estimates the LLM calls and tokens that collecting metadata with
+// params would take. It only lists the tables and columns the filters select, so it
+// can be reported before any statistics are queried or any LLM call is made: one
+// description call per table, one per column when there is context to describe it
+// from (or one per batch of columns with BatchDescriptions), and one PII check per
+// column whose examples are requested. DescribeColumnRelationships asks for the
+// relationships in the table's own call. Columns end up with no examples, and need no PII check,
+// when they hold no values, so that count is an upper bound. With
+// DedupeDescriptions, columns sharing a name and data type count once. Per-column
+// --tables hints are honored. Calls are counted whether or not the service has an
+// LLM client, so the estimate also covers runs with --dry-llm.
+func (s *Service) EstimateLLMUsage(ctx context.Context, params GenerateSQLParams) (LLMUsageEstimate, error) {
+	var est LLMUsageEstimate
+	tables, err := s.dbAdapter.ListTables(ctx)
+	if err != nil {
+		return est, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var schema string
+	params.TableFilters, schema = s.unqualifiedTableFilters(params.TableFilters)
+	params.ColumnEnrichments = unqualifyEntries(params.ColumnEnrichments, schema)
+	filteredTables := filterTables(tables, params.TableFilters)
+
+	contextTokens := int64(len(params.AdditionalContext) / estimatedBytesPerToken)
+	describeTables := isEnrichmentRequested("description", params.Enrichments)
+	batched := s.config.BatchDescriptions && describeTables
+	describeColumns := params.AdditionalContext != "" || params.ContextDir != ""
+
+	addDescriptionCalls := func(calls, describedColumns int) {
+		est.DescriptionCalls += calls
+		est.InputTokens += int64(calls) * (estimatedPromptTokens + contextTokens)
+		est.OutputTokens += int64(describedColumns) * estimatedDescriptionOutputTokens
+	}
+
+	if describeTables {
+		addDescriptionCalls(len(filteredTables), len(filteredTables))
+	}
+
+	describedPerTable := make(map[string]int)
+	describedGroups := make(map[string]bool)
+	for _, table := range filteredTables {
+		if err := ctx.Err(); err != nil {
+			return est, err
+		}
+		columnInfos, err := s.listColumns(ctx, table)
+		if err != nil {
+			return est, fmt.Errorf("Table[%s] list columns: %w", table, err)
+		}
+		for _, ci := range filterColumns(table, columnInfos, params.TableFilters) {
+			enrichments, _ := columnEnrichments(table, ci.Name, params)
+			if ci.StatsUnsupported {
+				enrichments = withoutStatistics(enrichments)
+			}
+			if batched || describeColumns && isEnrichmentRequested("description", enrichments) {
+				key := descriptionGroupKey(ci.Name, ci.DataType)
+				if batched || !s.config.DedupeDescriptions || !describedGroups[key] {
+					describedPerTable[table]++
+				}
+				describedGroups[key] = true
+			}
+			if isEnrichmentRequested("foreign_keys", enrichments) {
+				est.ForeignKeyColumns++
+			}
+			if isEnrichmentRequested("examples", enrichments) {
+				est.PIICalls++
+				est.InputTokens += estimatedPromptTokens
+				est.OutputTokens += estimatedPIIOutputTokens
+			}
+		}
+	}
+	for _, described := range describedPerTable {
+		calls := described
+		if batched {
+			calls = (described + maxColumnsPerDescriptionBatch - 1) / maxColumnsPerDescriptionBatch
+		}
+		addDescriptionCalls(calls, described)
+	}
+	return est, nil
+}
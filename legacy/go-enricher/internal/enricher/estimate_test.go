@@ -0,0 +1,90 @@
+package enricher
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/genai"
+)
+
+func TestEstimateLLMUsage(t *testing.T) {
+	params := GenerateSQLParams{
+		TableFilters:      map[string][]string{"customers": {"email"}, "orders": {}},
+		ColumnEnrichments: map[string]map[string][]string{"customers": {"email": {"description"}}},
+		Enrichments:       map[string]bool{},
+		AdditionalContext: strings.Repeat("x", 400), // ~100 tokens per description prompt.
+	}
+	withEnrichments := func(enrichments map[string]bool, additionalContext string) GenerateSQLParams {
+		p := params
+		p.Enrichments = enrichments
+		p.AdditionalContext = additionalContext
+		return p
+	}
+
+	tests := []struct {
+		name          string
+		batch         bool
+		relationships bool
+		params        GenerateSQLParams
+		expected      LLMUsageEstimate
+	}{
+		{
+			name:   "per column",
+			params: params,
+			// 2 table and 4 column descriptions at 500 tokens each, 3 PII checks at 400.
+			expected: LLMUsageEstimate{DescriptionCalls: 6, PIICalls: 3, InputTokens: 4200, OutputTokens: 720, ForeignKeyColumns: 3},
+		},
+		{
+			name:   "batched",
+			batch:  true,
+			params: params,
+			// One description call per table for its columns.
+			expected: LLMUsageEstimate{DescriptionCalls: 4, PIICalls: 3, InputTokens: 3200, OutputTokens: 720, ForeignKeyColumns: 3},
+		},
+		{
+			name:          "column relationships",
+			relationships: true,
+			params:        params,
+			// Asked for in the table description calls.
+			expected: LLMUsageEstimate{DescriptionCalls: 6, PIICalls: 3, InputTokens: 4200, OutputTokens: 720, ForeignKeyColumns: 3},
+		},
+		{
+			name:     "without context columns are not described",
+			params:   withEnrichments(map[string]bool{}, ""),
+			expected: LLMUsageEstimate{DescriptionCalls: 2, PIICalls: 3, InputTokens: 2000, OutputTokens: 480, ForeignKeyColumns: 3},
+		},
+		{
+			name:   "only hinted columns use the LLM",
+			params: withEnrichments(map[string]bool{"null_count": true}, "docs"),
+			// customers.email asks for a description through its --tables hint.
+			expected: LLMUsageEstimate{DescriptionCalls: 1, InputTokens: 401, OutputTokens: 60},
+		},
+	}
+
+	newService := func(cfg Config) *Service {
+		mockDB := &MockDBAdapter{}
+		mockDB.On("ListTables").Return([]string{"customers", "orders", "audit_log"}, nil)
+		mockDB.On("ListColumns", "customers").Return([]database.ColumnInfo{{Name: "email", DataType: "text"}, {Name: "name", DataType: "text"}}, nil)
+		mockDB.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "id", DataType: "integer"}, {Name: "note", DataType: "text"}, {Name: "status", DataType: "text"}}, nil)
+		return NewService(mockDB, nil, cfg)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est, err := newService(Config{BatchDescriptions: tt.batch, DescribeColumnRelationships: tt.relationships}).EstimateLLMUsage(context.Background(), tt.params)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, est)
+		})
+	}
+
+	est, err := newService(Config{}).EstimateLLMUsage(context.Background(), params)
+	assert.NoError(t, err)
+	cost, ok := genai.EstimateCost("gemini-1.5-pro-002", est.InputTokens, est.OutputTokens)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.00885, cost, 1e-9)
+	_, ok = genai.EstimateCost("some-other-model", est.InputTokens, est.OutputTokens)
+	assert.False(t, ok)
+}
@@ -0,0 +1,150 @@
+package enricher
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ColumnExplanation records what a run did with a column, for --explain: which
+// enrichments made it into its comment, whether the LLM was asked about it, the
+// outcome of the PII check of its examples, and why requested enrichments were
+// left out. A skipped column had nothing added to its comment.
+type ColumnExplanation struct {
+	Table     string   `json:"table"`
+	Column    string   `json:"column"`
+	Applied   []string `json:"applied"`
+	LLMCalled bool     `json:"llm_called"` // A batched or shared description call counts.
+	PII       string   `json:"pii"`
+	Skipped   bool     `json:"skipped"`
+	Reasons   []string `json:"reasons,omitempty"`
+}
+
+// PII decisions reported in ColumnExplanation.PII.
+const (
+	piiNotChecked  = "not checked"
+	piiSynthesized = "PII detected or suspected; examples replaced with synthetic values"
+	piiKept        = "no PII found; original examples kept"
+	piiFailed      = "check failed; original examples kept"
+	piiNoMasking   = "not checked (--mask_pii=false)"
+)
+
+// explain starts the explanation of a column when Config.Explain is set. The
+// methods of a nil *ColumnExplanation do nothing, so callers need not check.
+func (s *Service) explain(table, column string) *ColumnExplanation {
+	if !s.config.Explain {
+		return nil
+	}
+	return &ColumnExplanation{Table: table, Column: column, Applied: []string{}}
+}
+
+func (e *ColumnExplanation) because(format string, args ...interface{}) {
+	if e != nil {
+		e.Reasons = append(e.Reasons, fmt.Sprintf(format, args...))
+	}
+}
+
+func (e *ColumnExplanation) skip(format string, args ...interface{}) {
+	if e != nil {
+		e.Skipped = true
+		e.because(format, args...)
+	}
+}
+
+func (e *ColumnExplanation) calledLLM() {
+	if e != nil {
+		e.LLMCalled = true
+	}
+}
+
+func (e *ColumnExplanation) decidePII(decision string) {
+	if e != nil {
+		e.PII = decision
+	}
+}
+
+// explainedEnrichments are the built-in enrichments in the order their parts appear
+// in a comment.
+var explainedEnrichments = []string{"data_type", "examples", "distinct_values", "null_count", "average", "json_keys", "description", "foreign_keys"}
+
+// finish records which of the column's enrichments produced metadata for its
+// comment, and skips the column when none did. includeType is set when every
+// comment starts with the data type (--comment-include-datatype).
+func (e *ColumnExplanation) finish(cm *ColumnMetadata, enrichments map[string]bool, includeType bool) {
+	if e == nil {
+		return
+	}
+	produced := map[string]bool{
+		"data_type":       enrichments["data_type"] || includeType, // Only added when listed explicitly, or for every column with includeType.
+		"examples":        len(cm.ExampleValues) > 0,
+		"distinct_values": cm.DistinctCount >= 0,
+		"null_count":      true,
+		"average":         enrichments["average"] && cm.Average != nil, // Only added when listed explicitly.
+		"json_keys":       len(cm.JSONKeys) > 0,
+		"description":     cm.Description != "",
+		"foreign_keys":    len(cm.ForeignKeys) > 0,
+	}
+	for _, name := range explainedEnrichments {
+		if produced[name] && (isEnrichmentRequested(name, enrichments) || name == "data_type") {
+			e.Applied = append(e.Applied, name)
+		}
+	}
+	custom := make([]string, 0, len(cm.Custom))
+	for name, value := range cm.Custom {
+		if value != "" && isEnrichmentRequested(name, enrichments) {
+			custom = append(custom, name)
+		}
+	}
+	sort.Strings(custom)
+	e.Applied = append(e.Applied, custom...)
+	if len(e.Applied) == 0 {
+		e.skip("no change: none of the requested enrichments produced metadata for it")
+	}
+	if e.PII == "" {
+		e.PII = piiNotChecked
+	}
+}
+
+// Explanations returns the explanations recorded for the columns of a snapshot, in
+// its order. It is empty unless the snapshot was collected with Config.Explain.
+func Explanations(snapshot *MetadataSnapshot) []*ColumnExplanation {
+	explanations := []*ColumnExplanation{}
+	for _, cm := range snapshot.Columns {
+		if cm.Explanation != nil {
+			explanations = append(explanations, cm.Explanation)
+		}
+	}
+	return explanations
+}
+
+func FormatExplanationsAsText(explanations []*ColumnExplanation) string {
+	if len(explanations) == 0 {
+		return "No columns were processed.\n"
+	}
+	var buffer bytes.Buffer
+	lastTable := ""
+	for _, e := range explanations {
+		if e.Table != lastTable {
+			if lastTable != "" {
+				buffer.WriteString("\n")
+			}
+			buffer.WriteString(fmt.Sprintf("--- Table: %s ---\n", e.Table))
+			lastTable = e.Table
+		}
+		status := "enriched with " + strings.Join(e.Applied, ", ")
+		if e.Skipped {
+			status = "skipped"
+		}
+		llm := "no"
+		if e.LLMCalled {
+			llm = "yes"
+		}
+		buffer.WriteString(fmt.Sprintf("  Column: %s: %s\n", e.Column, status))
+		buffer.WriteString(fmt.Sprintf("    LLM called: %s. PII: %s.\n", llm, e.PII))
+		for _, reason := range e.Reasons {
+			buffer.WriteString(fmt.Sprintf("    - %s\n", reason))
+		}
+	}
+	return buffer.String()
+}
@@ -0,0 +1,110 @@
+package enricher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+func TestCollectMetadataExplain(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	llm := &fakeLLMClient{
+		columnDescriptions: map[string]string{
+			"email":    "Customer email address",
+			"shipping": "Shipping address",
+			"status":   "Order status",
+		},
+		synthetic: map[string][]string{"email": {"user@example.org"}},
+	}
+	service := NewService(mockAdapter, llm, Config{MaskPII: true, SkipDescriptionPatterns: []string{"*_id"}, Explain: true})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "customer_id", DataType: "integer"},
+		{Name: "email", DataType: "text"},
+		{Name: "notes", DataType: "text"},
+		{Name: "region_id", DataType: "geometry", StatsUnsupported: true},
+		{Name: "shipping", DataType: "address (composite)", StatsUnsupported: true},
+		{Name: "status", DataType: "text"},
+	}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "customer_id").Return(map[string]interface{}{"ExampleValues": []string{"17", "42"}}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "email").Return(map[string]interface{}{"ExampleValues": []string{"jane@corp.com"}}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "notes").Return(map[string]interface{}{}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "status").Return(map[string]interface{}{"ExampleValues": []string{"open", "paid"}}, nil)
+
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+		Enrichments:       map[string]bool{"description": true, "examples": true},
+		AdditionalContext: "orders docs",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnExplanation{
+		{
+			Table: "orders", Column: "customer_id", Applied: []string{"examples"}, LLMCalled: true, PII: piiKept,
+			Reasons: []string{"no LLM description: name matches --skip-description-patterns"},
+		},
+		{
+			Table: "orders", Column: "email", Applied: []string{"examples", "description"}, LLMCalled: true, PII: piiSynthesized,
+		},
+		{
+			Table: "orders", Column: "notes", Applied: []string{}, LLMCalled: true, PII: piiNotChecked, Skipped: true,
+			Reasons: []string{"no change: none of the requested enrichments produced metadata for it"},
+		},
+		{
+			Table: "orders", Column: "region_id", Applied: []string{}, PII: piiNotChecked, Skipped: true,
+			Reasons: []string{
+				"type unsupported: values of type geometry cannot be sampled or counted",
+				"no LLM description: name matches --skip-description-patterns",
+				"none of the requested enrichments can add to its comment",
+			},
+		},
+		{
+			Table: "orders", Column: "shipping", Applied: []string{"description"}, LLMCalled: true, PII: piiNotChecked,
+			Reasons: []string{"type unsupported: values of type address (composite) cannot be sampled or counted"},
+		},
+		{
+			Table: "orders", Column: "status", Applied: []string{"examples", "description"}, LLMCalled: true, PII: piiKept,
+		},
+	}, Explanations(snapshot))
+
+	text := FormatExplanationsAsText(Explanations(snapshot))
+	assert.Contains(t, text, "--- Table: orders ---\n")
+	assert.Contains(t, text, "  Column: email: enriched with examples, description\n    LLM called: yes. PII: "+piiSynthesized+".\n")
+	assert.Contains(t, text, "  Column: notes: skipped\n    LLM called: yes. PII: not checked.\n    - no change:")
+}
+
+func TestCollectMetadataWithoutExplain(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "status", DataType: "text"}}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "status").Return(map[string]interface{}{"NullCount": int64(0)}, nil)
+
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{Enrichments: map[string]bool{"null_count": true}})
+
+	assert.NoError(t, err)
+	assert.Empty(t, Explanations(snapshot))
+}
+
+func TestCollectMetadataExplainCommentIncludeDataType(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{Explain: true, CommentIncludeDataType: true})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "status", DataType: "text"}}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "status").Return(map[string]interface{}{"NullCount": int64(0)}, nil)
+
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{Enrichments: map[string]bool{"null_count": true}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnExplanation{
+		{Table: "orders", Column: "status", Applied: []string{"data_type", "null_count"}, PII: piiNotChecked},
+	}, Explanations(snapshot))
+}
@@ -0,0 +1,43 @@
+package enricher
+
+import (
+	"strings"
+	"unicode"
+)
+
+// humanizeName turns a column name into a short description for --humanize-names:
+// created_at becomes "Created at" and userID "User id". Words are split at
+// underscores, hyphens, dots, spaces and camelCase boundaries; a run of capitals
+// such as HTTP stays one word.
+func humanizeName(name string) string {
+	runes := []rune(name)
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == '.' || unicode.IsSpace(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || nextIsLower {
+				flush()
+			}
+		}
+		word = append(word, unicode.ToLower(r))
+	}
+	flush()
+	if len(words) == 0 {
+		return ""
+	}
+	humanized := []rune(strings.Join(words, " "))
+	humanized[0] = unicode.ToUpper(humanized[0])
+	return string(humanized)
+}
@@ -0,0 +1,55 @@
+package enricher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHumanizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"created_at", "Created at"},
+		{"user_id", "User id"},
+		{"__order__total__", "Order total"},
+		{"shipping-address", "Shipping address"},
+		{"createdAt", "Created at"},
+		{"userID", "User id"},
+		{"HTTPStatusCode", "Http status code"},
+		{"Address2Line", "Address2 line"},
+		{"ID", "Id"},
+		{"_", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, humanizeName(tt.name))
+		})
+	}
+}
+
+func TestCollectMetadataHumanizesNames(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{HumanizeNames: true})
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("GetTableComment", "orders").Return("", nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "created_at", DataType: "timestamp"},
+		{Name: "customerId", DataType: "int"},
+	}, nil)
+
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+		Enrichments: map[string]bool{"description": true},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*ColumnMetadata{
+		{Table: "orders", Column: "created_at", DataType: "timestamp", Description: "Created at"},
+		{Table: "orders", Column: "customerId", DataType: "int", Description: "Customer id"},
+	}, snapshot.Columns)
+}
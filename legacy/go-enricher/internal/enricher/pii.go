@@ -0,0 +1,170 @@
+package enricher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+// PIIFinding describes a column that likely contains personally identifiable information.
+type PIIFinding struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Source string `json:"source"` // "column_name", "values" or "llm"
+	Reason string `json:"reason"`
+}
+
+type PIIReportParams struct {
+	TableFilters map[string][]string
+}
+
+var piiColumnNamePattern = regexp.MustCompile(`(?i)(^|_)(e?mail|email_address|phone|phone_number|mobile|ssn|social_security|passport|first_name|last_name|full_name|surname|birth_?date|dob|date_of_birth|address|street|zip_?code|postal_code|credit_card|card_number|iban|tax_id|national_id|ip_address)($|_)`)
+
+var piiValuePatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"email address", regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[A-Za-z]{2,}$`)},
+	{"US social security number", regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`)},
+	{"phone number", regexp.MustCompile(`^\+?\d{1,3}?[-. (]*\d{3}[-. )]*\d{3}[-. ]*\d{4}$`)},
+	{"credit card number", regexp.MustCompile(`^\d{4}[- ]?\d{4}[- ]?\d{4}[- ]?\d{4}$`)},
+}
+
+// detectPIIByRegex applies the regex pre-filter to a column name and its sample values.
+// It returns the finding source and reason, or an empty source if nothing matched.
+func detectPIIByRegex(columnName string, values []string) (source string, reason string) {
+	if piiColumnNamePattern.MatchString(columnName) {
+		return "column_name", fmt.Sprintf("column name '%s' matches a common PII pattern", columnName)
+	}
+	for _, p := range piiValuePatterns {
+		for _, v := range values {
+			if p.pattern.MatchString(strings.TrimSpace(v)) {
+				return "values", fmt.Sprintf("sample values look like a %s", p.name)
+			}
+		}
+	}
+	return "", ""
+}
+
+// GeneratePIIReport classifies the filtered columns as likely PII or not without
+// generating any comments. The regex pre-filter runs first; when an LLM client is
+// configured, columns it does not flag are checked with GenerateSyntheticExamples.
+func (s *Service) GeneratePIIReport(ctx context.Context, params PIIReportParams) ([]*PIIFinding, error) {
+	startTime := time.Now()
+	log.Println("INFO: Starting PII classification...")
+
+	tables, err := s.dbAdapter.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	params.TableFilters, _ = s.unqualifiedTableFilters(params.TableFilters)
+	filteredTables := filterTables(tables, params.TableFilters)
+	if len(filteredTables) == 0 {
+		log.Println(noTablesMessage(tables, "the PII report"))
+		return []*PIIFinding{}, nil
+	}
+
+	findings := []*PIIFinding{}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errorChannel := make(chan error, len(filteredTables)*5)
+	exampleOnly := map[string]bool{"examples": true}
+
+	for _, tableName := range filteredTables {
+		wg.Add(1)
+		go func(table string) {
+			defer wg.Done()
+			tableLogPrefix := fmt.Sprintf("Table[%s]", table)
+
+			columnInfos, listColErr := s.listColumns(ctx, table)
+			if listColErr != nil {
+				log.Printf("ERROR: %s Failed to list columns for PII report: %v", tableLogPrefix, listColErr)
+				errorChannel <- fmt.Errorf("%s list columns pii: %w", tableLogPrefix, listColErr)
+				return
+			}
+			filteredColumnInfos := filterColumns(table, columnInfos, params.TableFilters)
+
+			var colWg sync.WaitGroup
+			for _, colInfo := range filteredColumnInfos {
+				colWg.Add(1)
+				go func(ci database.ColumnInfo) {
+					defer colWg.Done()
+					colLogPrefix := fmt.Sprintf("Column[%s.%s]", table, ci.Name)
+
+					columnMetadata, colMetaErr := s.collectColumnDBMetadata(ctx, table, ci, exampleOnly)
+					if colMetaErr != nil {
+						log.Printf("ERROR: %s Failed to collect sample values: %v", colLogPrefix, colMetaErr)
+						errorChannel <- fmt.Errorf("%s collect sample values: %w", colLogPrefix, colMetaErr)
+						return
+					}
+
+					source, reason := detectPIIByRegex(ci.Name, columnMetadata.ExampleValues)
+					if source == "" && s.llmClient != nil && len(columnMetadata.ExampleValues) > 0 {
+						_, wasSynthesized, piiErr := s.llmClient.GenerateSyntheticExamples(ctx, ci.Name, table, ci.DataType, columnMetadata.ExampleValues, true)
+						if piiErr != nil {
+							log.Printf("WARN: %s Failed to classify column with LLM: %v", colLogPrefix, piiErr)
+						} else if wasSynthesized {
+							source, reason = "llm", "LLM classified the column and its sample values as likely PII"
+						}
+					}
+					if source == "" {
+						return
+					}
+					mu.Lock()
+					findings = append(findings, &PIIFinding{Table: table, Column: ci.Name, Source: source, Reason: reason})
+					mu.Unlock()
+				}(colInfo)
+			}
+			colWg.Wait()
+		}(tableName)
+	}
+
+	wg.Wait()
+	close(errorChannel)
+
+	var allErrors []error
+	for err := range errorChannel {
+		allErrors = append(allErrors, err)
+	}
+	if len(allErrors) > 0 {
+		return nil, &ErrPartialEnrichment{Operation: "PII classification", Errs: allErrors}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Table != findings[j].Table {
+			return findings[i].Table < findings[j].Table
+		}
+		return findings[i].Column < findings[j].Column
+	})
+
+	log.Printf("INFO: PII classification completed in %s. Flagged %d column(s).", time.Since(startTime), len(findings))
+	return findings, nil
+}
+
+func FormatPIIReportAsText(findings []*PIIFinding) string {
+	if len(findings) == 0 {
+		return "No likely PII columns found.\n"
+	}
+	var buffer bytes.Buffer
+	lastTable := ""
+	for _, f := range findings {
+		if f.Table != lastTable {
+			if lastTable != "" {
+				buffer.WriteString("\n")
+			}
+			buffer.WriteString(fmt.Sprintf("--- Table: %s ---\n", f.Table))
+			lastTable = f.Table
+		}
+		buffer.WriteString(fmt.Sprintf("  Column: %s [%s] %s\n", f.Column, f.Source, f.Reason))
+	}
+	return buffer.String()
+}
@@ -0,0 +1,55 @@
+package enricher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+func TestGeneratePIIReport(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"customers", "orders"}, nil)
+	mockAdapter.On("ListColumns", "customers").Return([]database.ColumnInfo{
+		{Name: "email", DataType: "text"},
+		{Name: "age", DataType: "int"},
+		{Name: "contact", DataType: "text"},
+	}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "customer_phone", DataType: "text"},
+		{Name: "status", DataType: "text"},
+	}, nil)
+	mockAdapter.On("GetColumnMetadata", "customers", "email").Return(map[string]interface{}{"ExampleValues": []string{"a@example.com"}}, nil)
+	mockAdapter.On("GetColumnMetadata", "customers", "age").Return(map[string]interface{}{"ExampleValues": []string{"31", "45"}}, nil)
+	mockAdapter.On("GetColumnMetadata", "customers", "contact").Return(map[string]interface{}{"ExampleValues": []string{"n/a", "jane.doe@example.org"}}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "customer_phone").Return(map[string]interface{}{"ExampleValues": []string{"x"}}, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "status").Return(map[string]interface{}{"ExampleValues": []string{"shipped", "pending"}}, nil)
+
+	findings, err := service.GeneratePIIReport(context.Background(), PIIReportParams{})
+
+	assert.NoError(t, err)
+	var flagged []string
+	for _, f := range findings {
+		flagged = append(flagged, f.Table+"."+f.Column+":"+f.Source)
+	}
+	assert.Equal(t, []string{
+		"customers.contact:values",
+		"customers.email:column_name",
+		"orders.customer_phone:column_name",
+	}, flagged)
+	mockAdapter.AssertExpectations(t)
+}
+
+func TestFormatPIIReportAsText(t *testing.T) {
+	assert.Equal(t, "No likely PII columns found.\n", FormatPIIReportAsText(nil))
+
+	text := FormatPIIReportAsText([]*PIIFinding{
+		{Table: "customers", Column: "email", Source: "column_name", Reason: "r1"},
+		{Table: "orders", Column: "phone", Source: "llm", Reason: "r2"},
+	})
+	assert.Equal(t, "--- Table: customers ---\n  Column: email [column_name] r1\n\n--- Table: orders ---\n  Column: phone [llm] r2\n", text)
+}
@@ -0,0 +1,92 @@
+package enricher
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+// Enrichment is a custom, computed enrichment (e.g. a data-quality score) whose
+// output is added to column comments. Implementations register themselves with
+// RegisterEnrichment, typically from an init function, and run when their name
+// is passed to --enrichments (or when --enrichments is empty).
+type Enrichment interface {
+	Name() string
+	Collect(ctx context.Context, db database.DBAdapter, table string, column string) (string, error)
+}
+
+// builtinEnrichments are the names handled by the enricher itself.
+var builtinEnrichments = map[string]bool{
+	"description":     true,
+	"examples":        true,
+	"distinct_values": true,
+	"null_count":      true,
+	"average":         true,
+	"json_keys":       true,
+	"foreign_keys":    true,
+	"data_type":       true,
+}
+
+var (
+	customEnrichments = make(map[string]Enrichment)
+	enrichmentsMu     sync.RWMutex
+)
+
+// RegisterEnrichment makes a custom enrichment available under its lower-cased name.
+func RegisterEnrichment(e Enrichment) {
+	name := strings.ToLower(e.Name())
+	if builtinEnrichments[name] {
+		log.Printf("WARN: Custom enrichment '%s' conflicts with a built-in enrichment and was not registered.", name)
+		return
+	}
+	enrichmentsMu.Lock()
+	defer enrichmentsMu.Unlock()
+	if _, exists := customEnrichments[name]; exists {
+		log.Printf("WARN: Custom enrichment '%s' is being overwritten.", name)
+	}
+	customEnrichments[name] = e
+}
+
+// requestedCustomEnrichments returns the registered enrichments to run, in name order.
+func requestedCustomEnrichments(enrichments map[string]bool) []Enrichment {
+	enrichmentsMu.RLock()
+	defer enrichmentsMu.RUnlock()
+	names := make([]string, 0, len(customEnrichments))
+	for name := range customEnrichments {
+		if isEnrichmentRequested(name, enrichments) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	requested := make([]Enrichment, len(names))
+	for i, name := range names {
+		requested[i] = customEnrichments[name]
+	}
+	return requested
+}
+
+// collectCustomEnrichments runs the requested custom enrichments for a column.
+// A failing enrichment is logged and left out of the comment.
+func (s *Service) collectCustomEnrichments(ctx context.Context, table string, column string, enrichments map[string]bool) map[string]string {
+	requested := requestedCustomEnrichments(enrichments)
+	if len(requested) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(requested))
+	for _, e := range requested {
+		name := strings.ToLower(e.Name())
+		value, err := e.Collect(ctx, s.dbAdapter, table, column)
+		if err != nil {
+			log.Printf("WARN: Column[%s.%s] Custom enrichment '%s' failed: %v", table, column, name, err)
+			continue
+		}
+		if value != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
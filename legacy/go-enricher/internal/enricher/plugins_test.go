@@ -0,0 +1,70 @@
+package enricher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+type fakeQualityEnrichment struct{}
+
+func (fakeQualityEnrichment) Name() string { return "quality_score" }
+
+func (fakeQualityEnrichment) Collect(ctx context.Context, db database.DBAdapter, table string, column string) (string, error) {
+	return table + "." + column + "=0.97", nil
+}
+
+func TestCustomEnrichmentRenderedInComment(t *testing.T) {
+	RegisterEnrichment(fakeQualityEnrichment{})
+	t.Cleanup(func() {
+		enrichmentsMu.Lock()
+		delete(customEnrichments, "quality_score")
+		enrichmentsMu.Unlock()
+	})
+
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+	enrichments := map[string]bool{"quality_score": true}
+
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "total", DataType: "int"}}, nil)
+	mockAdapter.On("GenerateTableCommentSQL", mock.Anything, mock.Anything).Return("", nil)
+	mockAdapter.On("GenerateCommentSQL", mock.MatchedBy(func(d *database.CommentData) bool {
+		return d.Custom["quality_score"] == "orders.total=0.97"
+	}), enrichments).Return("COMMENT total;", nil)
+
+	sqls, err := service.GenerateCommentSQLs(context.Background(), GenerateSQLParams{Enrichments: enrichments})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"COMMENT total;"}, sqls)
+	mockAdapter.AssertExpectations(t)
+
+	comment := database.GenerateMetadataCommentString(&database.CommentData{
+		TableName:  "orders",
+		ColumnName: "total",
+		Custom:     map[string]string{"quality_score": "orders.total=0.97"},
+	}, enrichments, "", "")
+	assert.Equal(t, "quality_score: orders.total=0.97", comment)
+}
+
+func TestRegisterEnrichmentRejectsBuiltinNames(t *testing.T) {
+	RegisterEnrichment(builtinNameEnrichment{})
+
+	enrichmentsMu.RLock()
+	defer enrichmentsMu.RUnlock()
+	_, registered := customEnrichments["examples"]
+	assert.False(t, registered)
+}
+
+type builtinNameEnrichment struct{}
+
+func (builtinNameEnrichment) Name() string { return "Examples" }
+
+func (builtinNameEnrichment) Collect(ctx context.Context, db database.DBAdapter, table string, column string) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,114 @@
+package enricher
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+// maxReferenceDescriptionLength caps the referenced table description quoted in a
+// foreign key, so a column comment doesn't repeat another table's full comment.
+const maxReferenceDescriptionLength = 80
+
+// tableDescriptions shares table descriptions between the concurrent table workers
+// of a run, so that a foreign key column can quote the description of the table it
+// references. Tables in the run publish their description as soon as it is known,
+// before their columns are processed; lookups of those tables wait for it. Tables
+// outside the run fall back to their existing comment.
+type tableDescriptions struct {
+	mu           sync.Mutex
+	ready        map[string]chan struct{}
+	once         map[string]*sync.Once
+	descriptions map[string]string
+}
+
+func newTableDescriptions(tables []string) *tableDescriptions {
+	d := &tableDescriptions{
+		ready:        make(map[string]chan struct{}, len(tables)),
+		once:         make(map[string]*sync.Once, len(tables)),
+		descriptions: make(map[string]string, len(tables)),
+	}
+	for _, table := range tables {
+		d.ready[table] = make(chan struct{})
+		d.once[table] = &sync.Once{}
+	}
+	return d
+}
+
+// publish records a table's description. Only the first call for a table has an
+// effect, so workers can also publish "" on their early-return paths.
+func (d *tableDescriptions) publish(table, description string) {
+	once, ok := d.once[table]
+	if !ok {
+		return
+	}
+	once.Do(func() {
+		d.mu.Lock()
+		d.descriptions[table] = description
+		d.mu.Unlock()
+		close(d.ready[table])
+	})
+}
+
+// lookup returns the description generated for a table in this run, waiting for
+// it if the table is part of the run but not yet described.
+func (d *tableDescriptions) lookup(ctx context.Context, table string) string {
+	ready, inRun := d.ready[table]
+	if !inRun {
+		return ""
+	}
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.descriptions[table]
+}
+
+// describeReferences fills in ReferencedTableDescription for each foreign key,
+// preferring the description generated in this run and falling back to the
+// referenced table's existing comment.
+func (s *Service) describeReferences(ctx context.Context, described *tableDescriptions, foreignKeys []database.ForeignKeyReference) {
+	for i := range foreignKeys {
+		table := foreignKeys[i].ReferencedTable
+		description := described.lookup(ctx, table)
+		if description == "" {
+			existing, err := s.dbAdapter.GetTableComment(ctx, table)
+			if err != nil {
+				log.Printf("WARN: Table[%s] Failed to read comment of referenced table: %v", table, err)
+			}
+			description = existingDescription(existing)
+		}
+		foreignKeys[i].ReferencedTableDescription = shortDescription(description)
+	}
+}
+
+// existingDescription picks the description out of an existing table comment: the
+// user's own text if there is any, otherwise what this tool wrote in <gemini> tags.
+func existingDescription(comment string) string {
+	user, metadata, _ := database.SplitComment(comment)
+	if user != "" {
+		return user
+	}
+	return metadata
+}
+
+// shortDescription trims a description to its first sentence and at most
+// maxReferenceDescriptionLength characters.
+func shortDescription(description string) string {
+	description = strings.TrimSpace(description)
+	if i := strings.Index(description, ". "); i != -1 {
+		description = description[:i]
+	}
+	description = strings.TrimSuffix(description, ".")
+	runes := []rune(description)
+	if len(runes) > maxReferenceDescriptionLength {
+		description = strings.TrimSpace(string(runes[:maxReferenceDescriptionLength-3])) + "..."
+	}
+	return description
+}
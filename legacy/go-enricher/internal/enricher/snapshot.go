@@ -0,0 +1,70 @@
+package enricher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// SnapshotVersion is the version of the snapshot format written by WriteSnapshot.
+// It is bumped whenever a change would make older snapshots generate different SQL.
+const SnapshotVersion = 1
+
+// MetadataSnapshot is everything the collection phase learned about a database:
+// the table and column metadata, including LLM descriptions and processed example
+// values, and the enrichments it was collected for. Generating SQL from a snapshot
+// needs neither the LLM nor metadata queries, so a persisted snapshot can be
+// regenerated with different settings.
+type MetadataSnapshot struct {
+	Version     int               `json:"version"`
+	Dialect     string            `json:"dialect,omitempty"`
+	Database    string            `json:"database,omitempty"`
+	Enrichments map[string]bool   `json:"enrichments,omitempty"` // Empty means every enrichment.
+	Tables      []*TableMetadata  `json:"tables"`
+	Columns     []*ColumnMetadata `json:"columns"`
+}
+
+// sort orders tables and columns by name, so that snapshots of the same database
+// are identical regardless of the order in which workers finished.
+func (snap *MetadataSnapshot) sort() {
+	sort.Slice(snap.Tables, func(i, j int) bool {
+		return snap.Tables[i].Table < snap.Tables[j].Table
+	})
+	sort.Slice(snap.Columns, func(i, j int) bool {
+		if snap.Columns[i].Table != snap.Columns[j].Table {
+			return snap.Columns[i].Table < snap.Columns[j].Table
+		}
+		return snap.Columns[i].Column < snap.Columns[j].Column
+	})
+}
+
+// WriteSnapshot writes a snapshot to path as indented JSON, creating the file with
+// the given permissions.
+func WriteSnapshot(path string, snapshot *MetadataSnapshot, mode os.FileMode) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), mode); err != nil {
+		return fmt.Errorf("failed to write metadata snapshot '%s': %w", path, err)
+	}
+	return nil
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot. Snapshots of an unknown
+// version are rejected rather than half understood.
+func ReadSnapshot(path string) (*MetadataSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata snapshot '%s': %w", path, err)
+	}
+	var snapshot MetadataSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata snapshot '%s': %w", path, err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("metadata snapshot '%s' has version %d, expected %d", path, snapshot.Version, SnapshotVersion)
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,97 @@
+package enricher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	enrichments := map[string]bool{"description": true, "examples": true, "foreign_keys": true}
+
+	collectAdapter := &MockDBAdapter{}
+	llm := &fakeLLMClient{
+		tableDescriptions:  map[string]string{"orders": "Customer orders"},
+		columnDescriptions: map[string]string{"id": "Order identifier", "user_id": "Ordering user"},
+	}
+	collector := NewService(collectAdapter, llm, Config{BatchDescriptions: true})
+
+	collectAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	collectAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "user_id", DataType: "int"},
+		{Name: "id", DataType: "int"},
+	}, nil)
+	collectAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	collectAdapter.On("GetColumnMetadata", "orders", "id").Return(map[string]interface{}{"ExampleValues": []string{"1", "2"}}, nil)
+	collectAdapter.On("GetColumnMetadata", "orders", "user_id").Return(map[string]interface{}{"ExampleValues": []string{"7"}}, nil)
+	collectAdapter.On("GetForeignKeys", "orders", "id").Return([]database.ForeignKeyReference(nil), nil)
+	collectAdapter.On("GetForeignKeys", "orders", "user_id").Return([]database.ForeignKeyReference{
+		{ReferencedTable: "users", ReferencedColumn: "id", ConstraintName: "fk_user"},
+	}, nil)
+	collectAdapter.On("GetTableComment", "users").Return("Registered users", nil)
+
+	snapshot, err := collector.CollectMetadata(context.Background(), GenerateSQLParams{Enrichments: enrichments})
+	assert.NoError(t, err)
+	for _, col := range snapshot.Columns {
+		assert.False(t, col.ProfiledAt.IsZero(), "column %s has no profiling time", col.Column)
+	}
+	profiledAt := snapshot.Columns[0].ProfiledAt
+	clearProfiledAt(snapshot.Columns)
+	snapshot.Columns[0].ProfiledAt = profiledAt
+	collectAdapter.AssertNotCalled(t, "GenerateCommentSQL", mock.Anything, mock.Anything)
+
+	expected := &MetadataSnapshot{
+		Version:     SnapshotVersion,
+		Enrichments: enrichments,
+		Tables:      []*TableMetadata{{Table: "orders", Description: "Customer orders"}},
+		Columns: []*ColumnMetadata{
+			{Table: "orders", Column: "id", DataType: "int", ExampleValues: []string{"1", "2"}, Description: "Order identifier", ProfiledAt: profiledAt},
+			{Table: "orders", Column: "user_id", DataType: "int", ExampleValues: []string{"7"}, Description: "Ordering user", ForeignKeys: []database.ForeignKeyReference{
+				{ReferencedTable: "users", ReferencedColumn: "id", ConstraintName: "fk_user", ReferencedTableDescription: "Registered users"},
+			}},
+		},
+	}
+	assert.Equal(t, expected, snapshot)
+
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	assert.NoError(t, WriteSnapshot(path, snapshot, 0644))
+	loaded, err := ReadSnapshot(path)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot, loaded)
+
+	// Generation only needs the statement builders: no metadata queries, no LLM.
+	generateAdapter := &MockDBAdapter{}
+	generator := NewService(generateAdapter, nil, Config{})
+	generateAdapter.On("GenerateTableCommentSQL", &database.TableCommentData{TableName: "orders", Description: "Customer orders"}, enrichments).
+		Return("COMMENT ON TABLE orders;", nil)
+	generateAdapter.On("GenerateCommentSQL", mock.MatchedBy(func(d *database.CommentData) bool {
+		return d.ColumnName == "id" && d.Description == "Order identifier" && len(d.ExampleValues) == 2
+	}), enrichments).Return("COMMENT ON COLUMN orders.id;", nil)
+	generateAdapter.On("GenerateCommentSQL", mock.MatchedBy(func(d *database.CommentData) bool {
+		return d.ColumnName == "user_id" && len(d.ForeignKeys) == 1 && d.ForeignKeys[0].ReferencedTableDescription == "Registered users"
+	}), enrichments).Return("COMMENT ON COLUMN orders.user_id;", nil)
+
+	sqls := generator.GenerateSQLFromSnapshot(loaded)
+
+	assert.Equal(t, []string{
+		"COMMENT ON TABLE orders;",
+		"COMMENT ON COLUMN orders.id;",
+		"COMMENT ON COLUMN orders.user_id;",
+	}, sqls)
+	generateAdapter.AssertExpectations(t)
+}
+
+func TestReadSnapshotRejectsUnknownVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version": 99, "tables": [], "columns": []}`), 0644))
+
+	_, err := ReadSnapshot(path)
+
+	assert.ErrorContains(t, err, "version 99")
+}
@@ -0,0 +1,268 @@
+package enricher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+// statsCacheVersion is the version of the file format written by StatsCache.Save.
+// Version 2 added foreign keys, whose columns may have no statistics.
+const statsCacheVersion = 2
+
+// StatsCache keeps the column statistics (example values, distinct and null counts,
+// foreign key match ratios) of earlier runs, together with the version of each table
+// they were collected at. When a table reports the same version again (see
+// database.TableVersioner), its statistics are served from the cache instead of
+// being queried. Tables whose dialect reports no version are always queried and
+// never cached.
+type StatsCache struct {
+	path string
+
+	mu   sync.Mutex
+	file statsCacheFile
+}
+
+type statsCacheFile struct {
+	Version int                          `json:"version"`
+	Key     string                       `json:"key"` // The database and settings the statistics were collected with.
+	Tables  map[string]*cachedTableStats `json:"tables"`
+}
+
+type cachedTableStats struct {
+	Version string                        `json:"version"`
+	Columns map[string]*cachedColumnStats `json:"columns"`
+}
+
+type cachedColumnStats struct {
+	ExampleValues []string  `json:"example_values,omitempty"`
+	DistinctCount *int64    `json:"distinct_count,omitempty"`
+	NullCount     *int64    `json:"null_count,omitempty"`
+	Average       *float64  `json:"average,omitempty"`
+	ProfiledAt    time.Time `json:"profiled_at,omitzero"` // Zero when only ForeignKeys are cached.
+	// ForeignKeys are the column's foreign keys with their match ratios.
+	ForeignKeys []cachedForeignKey `json:"foreign_keys,omitempty"`
+}
+
+// cachedForeignKey is a foreign key with the version its referenced table had when
+// the match ratio was checked, since the ratio also changes with that table.
+type cachedForeignKey struct {
+	database.ForeignKeyReference
+	ReferencedVersion string `json:"referenced_version"`
+}
+
+// LoadStatsCache reads the cache at path. A missing file, or one written for another
+// key (a different database or sample size), starts an empty cache.
+func LoadStatsCache(path, key string) (*StatsCache, error) {
+	cache := &StatsCache{
+		path: path,
+		file: statsCacheFile{Version: statsCacheVersion, Key: key, Tables: make(map[string]*cachedTableStats)},
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statistics cache '%s': %w", path, err)
+	}
+	var file statsCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse statistics cache '%s': %w", path, err)
+	}
+	switch {
+	case file.Version != statsCacheVersion:
+		log.Printf("WARN: Statistics cache '%s' has version %d, expected %d. Starting with an empty cache.", path, file.Version, statsCacheVersion)
+	case file.Key != key:
+		log.Printf("INFO: Statistics cache '%s' was written for %s. Starting with an empty cache.", path, file.Key)
+	case file.Tables != nil:
+		cache.file.Tables = file.Tables
+	}
+	return cache, nil
+}
+
+// Save writes the cache back to its file. It holds sampled column values, so it is
+// readable by the owner only.
+func (c *StatsCache) Save() error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.file, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode statistics cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write statistics cache '%s': %w", c.path, err)
+	}
+	return nil
+}
+
+// setTableVersion records the version a table has in this run and reports whether
+// its cached statistics are still valid. Statistics of a changed table are dropped.
+func (c *StatsCache) setTableVersion(table, version string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.file.Tables[table]
+	if ok && version != "" && cached.Version == version {
+		return true
+	}
+	if version == "" {
+		delete(c.file.Tables, table)
+	} else {
+		c.file.Tables[table] = &cachedTableStats{Version: version, Columns: make(map[string]*cachedColumnStats)}
+	}
+	return false
+}
+
+// lookup returns a column's cached statistics in the form GetColumnMetadata returns
+// them, and when they were queried. A nil cache has none.
+func (c *StatsCache) lookup(table, column string) (map[string]interface{}, time.Time, bool) {
+	if c == nil {
+		return nil, time.Time{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cachedTable, ok := c.file.Tables[table]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	cached, ok := cachedTable.Columns[column]
+	if !ok || cached.ProfiledAt.IsZero() {
+		return nil, time.Time{}, false
+	}
+	dbMetadata := map[string]interface{}{}
+	if cached.ExampleValues != nil {
+		dbMetadata["ExampleValues"] = cached.ExampleValues
+	}
+	if cached.DistinctCount != nil {
+		dbMetadata["DistinctCount"] = *cached.DistinctCount
+	}
+	if cached.NullCount != nil {
+		dbMetadata["NullCount"] = *cached.NullCount
+	}
+	if cached.Average != nil {
+		dbMetadata["Average"] = *cached.Average
+	}
+	return dbMetadata, cached.ProfiledAt, true
+}
+
+// store caches the statistics GetColumnMetadata returned for a column at profiledAt,
+// if its table has a version. A nil cache stores nothing.
+func (c *StatsCache) store(table, column string, dbMetadata map[string]interface{}, profiledAt time.Time) {
+	if c == nil {
+		return
+	}
+	cached := &cachedColumnStats{ProfiledAt: profiledAt}
+	if ev, ok := dbMetadata["ExampleValues"].([]string); ok {
+		cached.ExampleValues = ev
+	}
+	if dcRaw, ok := dbMetadata["DistinctCount"]; ok {
+		dc := safeConvertToInt64(dcRaw)
+		cached.DistinctCount = &dc
+	}
+	if ncRaw, ok := dbMetadata["NullCount"]; ok {
+		nc := safeConvertToInt64(ncRaw)
+		cached.NullCount = &nc
+	}
+	if avg, ok := dbMetadata["Average"].(float64); ok {
+		cached.Average = &avg
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cachedTable, ok := c.file.Tables[table]; ok {
+		if existing, ok := cachedTable.Columns[column]; ok {
+			cached.ForeignKeys = existing.ForeignKeys
+		}
+		cachedTable.Columns[column] = cached
+	}
+}
+
+// lookupForeignKeys returns a column's cached foreign keys. A nil cache has none.
+func (c *StatsCache) lookupForeignKeys(table, column string) ([]cachedForeignKey, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cachedTable, ok := c.file.Tables[table]
+	if !ok {
+		return nil, false
+	}
+	cached, ok := cachedTable.Columns[column]
+	if !ok || len(cached.ForeignKeys) == 0 {
+		return nil, false
+	}
+	return cached.ForeignKeys, true
+}
+
+// storeForeignKeys caches a column's foreign keys, if its table has a version. A nil
+// cache stores nothing.
+func (c *StatsCache) storeForeignKeys(table, column string, foreignKeys []cachedForeignKey) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cachedTable, ok := c.file.Tables[table]
+	if !ok {
+		return
+	}
+	cached, ok := cachedTable.Columns[column]
+	if !ok {
+		cached = &cachedColumnStats{}
+		cachedTable.Columns[column] = cached
+	}
+	cached.ForeignKeys = foreignKeys
+}
+
+// foreignKeys returns a column's foreign keys with their match ratios. They are served
+// from the statistics cache while the column's table and every referenced table keep
+// the version they had when the ratios were checked.
+func (s *Service) foreignKeys(ctx context.Context, table, column string) ([]database.ForeignKeyReference, error) {
+	if cached, ok := s.config.StatsCache.lookupForeignKeys(table, column); ok {
+		foreignKeys := make([]database.ForeignKeyReference, len(cached))
+		for i, fk := range cached {
+			if version, err := s.dbAdapter.TableVersion(ctx, fk.ReferencedTable); err != nil || version == "" || version != fk.ReferencedVersion {
+				foreignKeys = nil
+				break
+			}
+			foreignKeys[i] = fk.ForeignKeyReference
+		}
+		if foreignKeys != nil {
+			return foreignKeys, nil
+		}
+	}
+
+	foreignKeys, err := s.dbAdapter.GetForeignKeys(ctx, table, column)
+	if err != nil || s.config.StatsCache == nil || len(foreignKeys) == 0 {
+		return foreignKeys, err
+	}
+	cached := make([]cachedForeignKey, len(foreignKeys))
+	for i, fk := range foreignKeys {
+		version, err := s.dbAdapter.TableVersion(ctx, fk.ReferencedTable)
+		if err != nil || version == "" {
+			return foreignKeys, nil // The ratio could not be checked for staleness later.
+		}
+		cached[i] = cachedForeignKey{ForeignKeyReference: fk, ReferencedVersion: version}
+	}
+	s.config.StatsCache.storeForeignKeys(table, column, cached)
+	return foreignKeys, nil
+}
+
+// refreshTableVersion reads a table's version and checks it against the statistics
+// cache. A version that cannot be read is treated as unknown.
+func (s *Service) refreshTableVersion(ctx context.Context, table, logPrefix string) {
+	version, err := s.dbAdapter.TableVersion(ctx, table)
+	if err != nil {
+		log.Printf("WARN: %s Failed to read the table version: %v. Its statistics are collected again.", logPrefix, err)
+		version = ""
+	}
+	if s.config.StatsCache.setTableVersion(table, version) {
+		log.Printf("INFO: %s Unchanged since statistics were cached. Reusing them.", logPrefix)
+	}
+}
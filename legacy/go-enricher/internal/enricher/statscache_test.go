@@ -0,0 +1,107 @@
+package enricher
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+func newStatsCacheMock(version string) *MockDBAdapter {
+	mockAdapter := &MockDBAdapter{}
+	mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+	mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "status", DataType: "text"}}, nil)
+	mockAdapter.On("TableVersion", "orders").Return(version, nil)
+	mockAdapter.On("GetColumnMetadata", "orders", "status").Return(map[string]interface{}{
+		"ExampleValues": []string{"paid", "shipped"},
+		"DistinctCount": int64(2),
+		"NullCount":     int64(0),
+	}, nil)
+	return mockAdapter
+}
+
+func collectWithStatsCache(t *testing.T, mockAdapter *MockDBAdapter, path, key string) *ColumnMetadata {
+	t.Helper()
+	cache, err := LoadStatsCache(path, key)
+	assert.NoError(t, err)
+	service := NewService(mockAdapter, nil, Config{StatsCache: cache})
+	snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+		Enrichments: map[string]bool{"examples": true, "distinct_values": true, "null_count": true},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, cache.Save())
+	assert.Len(t, snapshot.Columns, 1)
+	return snapshot.Columns[0]
+}
+
+func TestStatsCacheServesUnchangedTables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	first := collectWithStatsCache(t, newStatsCacheMock("42:1:0:0"), path, "postgres://db/shop")
+
+	t.Run("unchanged table is served from cache", func(t *testing.T) {
+		mockAdapter := newStatsCacheMock("42:1:0:0")
+		cached := collectWithStatsCache(t, mockAdapter, path, "postgres://db/shop")
+		mockAdapter.AssertNotCalled(t, "GetColumnMetadata", "orders", "status")
+		assert.Equal(t, first, cached)
+		assert.Equal(t, []string{"paid", "shipped"}, cached.ExampleValues)
+		assert.Equal(t, int64(2), cached.DistinctCount)
+	})
+
+	t.Run("changed table is queried again", func(t *testing.T) {
+		mockAdapter := newStatsCacheMock("42:2:0:0")
+		collectWithStatsCache(t, mockAdapter, path, "postgres://db/shop")
+		mockAdapter.AssertCalled(t, "GetColumnMetadata", "orders", "status")
+	})
+
+	t.Run("another database starts empty", func(t *testing.T) {
+		mockAdapter := newStatsCacheMock("42:2:0:0")
+		collectWithStatsCache(t, mockAdapter, path, "postgres://db/other")
+		mockAdapter.AssertCalled(t, "GetColumnMetadata", "orders", "status")
+	})
+
+	t.Run("unknown version is never cached", func(t *testing.T) {
+		collectWithStatsCache(t, newStatsCacheMock(""), path, "postgres://db/shop")
+		mockAdapter := newStatsCacheMock("")
+		collectWithStatsCache(t, mockAdapter, path, "postgres://db/shop")
+		mockAdapter.AssertCalled(t, "GetColumnMetadata", "orders", "status")
+	})
+}
+
+func TestStatsCacheServesForeignKeyMatchRatios(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	ratio := 0.985
+	collect := func(usersVersion string) *MockDBAdapter {
+		mockAdapter := &MockDBAdapter{}
+		mockAdapter.On("ListTables").Return([]string{"orders"}, nil)
+		mockAdapter.On("GetAllColumnComments", "orders").Return(map[string]string{}, nil)
+		mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{{Name: "user_id", DataType: "int"}}, nil)
+		mockAdapter.On("TableVersion", "orders").Return("42:1:0:0", nil)
+		mockAdapter.On("TableVersion", "users").Return(usersVersion, nil)
+		mockAdapter.On("GetTableComment", "users").Return("", nil)
+		mockAdapter.On("GetForeignKeys", "orders", "user_id").Return([]database.ForeignKeyReference{
+			{ReferencedTable: "users", ReferencedColumn: "id", MatchRatio: &ratio},
+		}, nil)
+
+		cache, err := LoadStatsCache(path, "postgres://db/shop")
+		assert.NoError(t, err)
+		service := NewService(mockAdapter, nil, Config{StatsCache: cache})
+		snapshot, err := service.CollectMetadata(context.Background(), GenerateSQLParams{
+			Enrichments: map[string]bool{"foreign_keys": true},
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, cache.Save())
+		if assert.Len(t, snapshot.Columns, 1) && assert.Len(t, snapshot.Columns[0].ForeignKeys, 1) {
+			assert.Equal(t, ratio, *snapshot.Columns[0].ForeignKeys[0].MatchRatio)
+		}
+		return mockAdapter
+	}
+
+	collect("7:1:0:0").AssertCalled(t, "GetForeignKeys", "orders", "user_id")
+	collect("7:1:0:0").AssertNotCalled(t, "GetForeignKeys", "orders", "user_id")
+	// A change to the referenced table changes the ratio too.
+	collect("7:2:0:0").AssertCalled(t, "GetForeignKeys", "orders", "user_id")
+}
@@ -0,0 +1,82 @@
+package enricher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+)
+
+type ListTablesParams struct {
+	TableFilters map[string][]string
+	CountColumns bool // Also count the columns of each table that the filters select.
+}
+
+// TableListing is one table selected by the --tables filters.
+type TableListing struct {
+	Table       string `json:"table"`
+	ColumnCount *int   `json:"column_count,omitempty"` // Set only with ListTablesParams.CountColumns.
+}
+
+// ListTables returns the tables that the filters select, as the other commands would
+// process them. A table whose columns cannot be listed is reported without a count.
+func (s *Service) ListTables(ctx context.Context, params ListTablesParams) ([]TableListing, error) {
+	tables, err := s.dbAdapter.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	params.TableFilters, _ = s.unqualifiedTableFilters(params.TableFilters)
+	filteredTables := filterTables(tables, params.TableFilters)
+	if len(filteredTables) == 0 {
+		log.Println(noTablesMessage(tables, "listing"))
+		return []TableListing{}, nil
+	}
+
+	listings := make([]TableListing, 0, len(filteredTables))
+	for _, table := range filteredTables {
+		if err := ctx.Err(); err != nil {
+			return listings, err
+		}
+		listing := TableListing{Table: table}
+		if params.CountColumns {
+			columns, err := s.listColumns(ctx, table)
+			if err != nil {
+				log.Printf("WARN: Table[%s] Failed to list columns: %v", table, err)
+			} else {
+				count := len(filterColumns(table, columns, params.TableFilters))
+				listing.ColumnCount = &count
+			}
+		}
+		listings = append(listings, listing)
+	}
+	return listings, nil
+}
+
+// ColumnNames returns the names of a table's columns. They are listed once per
+// Service, so a run that goes on to process the table does not list them again.
+func (s *Service) ColumnNames(ctx context.Context, table string) ([]string, error) {
+	columns, err := s.listColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(columns))
+	for i, ci := range columns {
+		names[i] = ci.Name
+	}
+	return names, nil
+}
+
+// FormatTableListingAsText writes one table per line, followed by its column count
+// when it was counted.
+func FormatTableListingAsText(listings []TableListing) string {
+	var buffer bytes.Buffer
+	for _, listing := range listings {
+		buffer.WriteString(listing.Table)
+		if listing.ColumnCount != nil {
+			buffer.WriteString(fmt.Sprintf("\t%d column(s)", *listing.ColumnCount))
+		}
+		buffer.WriteString("\n")
+	}
+	return buffer.String()
+}
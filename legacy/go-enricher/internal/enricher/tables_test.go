@@ -0,0 +1,70 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+)
+
+func TestListTables(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListTables").Return([]string{"orders", "customers", "audit_log"}, nil)
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "int"},
+		{Name: "status", DataType: "text"},
+		{Name: "total", DataType: "int"},
+	}, nil)
+	mockAdapter.On("ListColumns", "customers").Return([]database.ColumnInfo(nil), errors.New("permission denied"))
+
+	t.Run("all tables", func(t *testing.T) {
+		listings, err := service.ListTables(context.Background(), ListTablesParams{})
+		assert.NoError(t, err)
+		assert.Equal(t, []TableListing{{Table: "orders"}, {Table: "customers"}, {Table: "audit_log"}}, listings)
+		assert.Equal(t, "orders\ncustomers\naudit_log\n", FormatTableListingAsText(listings))
+	})
+
+	t.Run("filtered with column counts", func(t *testing.T) {
+		listings, err := service.ListTables(context.Background(), ListTablesParams{
+			TableFilters: map[string][]string{"orders": {"id", "total"}, "customers": nil},
+			CountColumns: true,
+		})
+		assert.NoError(t, err)
+		two := 2
+		assert.Equal(t, []TableListing{{Table: "customers"}, {Table: "orders", ColumnCount: &two}}, listings)
+		assert.Equal(t, "customers\norders\t2 column(s)\n", FormatTableListingAsText(listings))
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		listings, err := service.ListTables(context.Background(), ListTablesParams{TableFilters: map[string][]string{"missing": nil}})
+		assert.NoError(t, err)
+		assert.Empty(t, listings)
+	})
+
+	mockAdapter.AssertNotCalled(t, "ListColumns", "audit_log")
+}
+
+func TestColumnNamesListsEachTableOnce(t *testing.T) {
+	mockAdapter := &MockDBAdapter{}
+	service := NewService(mockAdapter, nil, Config{})
+
+	mockAdapter.On("ListColumns", "orders").Return([]database.ColumnInfo{
+		{Name: "id", DataType: "int"},
+		{Name: "status", DataType: "text"},
+	}, nil).Once()
+
+	names, err := service.ColumnNames(context.Background(), "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "status"}, names)
+
+	// The run that follows the --interactive picker reuses the listing.
+	columns, err := service.listColumns(context.Background(), "orders")
+	assert.NoError(t, err)
+	assert.Len(t, columns, 2)
+	mockAdapter.AssertNumberOfCalls(t, "ListColumns", 1)
+}
@@ -2,21 +2,55 @@ package genai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
 	"time" // Added time package
 
 	"github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/googleapi" // Added to check for API errors
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-// geminiClient implements the LLMClient interface using the Google Gemini API.
+// geminiClient implements the LLMClient interface using Gemini models, reached
+// through the Gemini API or Vertex AI.
 type geminiClient struct {
-	client *genai.Client
-	cfg    Config
+	backend backend
+	cfg     Config
+}
+
+// backend is the service a geminiClient sends its requests to.
+type backend interface {
+	// generateContent sends prompt to model. A non-nil schema asks for a JSON response
+	// that follows it.
+	generateContent(ctx context.Context, model string, settings generationSettings, schema *genai.Schema, prompt string) (response, error)
+	// checkAccess verifies that the credentials can use model.
+	checkAccess(ctx context.Context, model string) error
+	close() error
+}
+
+// generationSettings are the sampling parameters of a request.
+type generationSettings struct {
+	temperature     float32
+	maxOutputTokens int32
+	topP            float32
+	topK            int32
+}
+
+var (
+	descriptionSettings       = generationSettings{temperature: 0.3, maxOutputTokens: 5000, topP: 0.9, topK: 40}
+	syntheticExamplesSettings = generationSettings{temperature: 0.5, maxOutputTokens: 500, topP: 0.9, topK: 40}
+)
+
+// response is a model response of either backend.
+type response interface {
+	// firstText returns the first part of the first candidate, which should be text.
+	firstText() (string, error)
 }
 
 // LLMClient defines the interface for interacting with a generative AI model.
@@ -24,34 +58,58 @@ type LLMClient interface {
 	// GenerateDescription generates a description for a database object (table or column).
 	GenerateDescription(ctx context.Context, objectType, objectName, parentName, knowledgeContext string) (string, error)
 
+	// GenerateColumnDescriptions generates descriptions for several columns of one table in a single call.
+	// Columns the context says nothing about are omitted from the returned map.
+	GenerateColumnDescriptions(ctx context.Context, tableName string, columnNames []string, knowledgeContext string) (map[string]string, error)
+
 	// GenerateSyntheticExamples analyzes original examples and potentially returns synthetic ones if PII is detected.
 	GenerateSyntheticExamples(ctx context.Context, columnName, tableName, dataType string, originalExamples []string, maskPII bool) (processedExamples []string, wasSynthesized bool, err error)
 
-	// IsAPIKeyValid checks if the configured API key is functional.
-	IsAPIKeyValid(ctx context.Context) error
+	// CheckAccess checks that the configured credentials can reach the model.
+	CheckAccess(ctx context.Context) error
 
 	// Close cleans up any resources used by the client.
 	Close() error
 }
 
+// Providers NewClient can reach Gemini models through.
+const (
+	ProviderGemini   = "gemini"   // The Gemini API, authenticated with Config.APIKey.
+	ProviderVertexAI = "vertexai" // Vertex AI, authenticated with application default credentials.
+)
+
 // Config holds configuration for the GenAI client.
 type Config struct {
-	APIKey         string
+	Provider       string // ProviderGemini (the default) or ProviderVertexAI
+	APIKey         string // Gemini API key, used by ProviderGemini
+	Project        string // Google Cloud project, used by ProviderVertexAI
+	Location       string // Google Cloud region such as "us-central1", used by ProviderVertexAI
 	Model          string
-	MaxRetries     int           // Number of retry attempts
+	MaxRetries     int           // Number of retry attempts; 0 uses the default of 3, a negative number disables retries
 	InitialBackoff time.Duration // Initial delay for backoff
 	MaxBackoff     time.Duration // Maximum delay for backoff
+	// StructuredOutput requests JSON responses that follow a response schema for
+	// descriptions and PII checks, falling back to tagged text if the model rejects it.
+	StructuredOutput bool
+	// DescriptionLanguage is the language descriptions are written in, such as "fr"
+	// or "Japanese". Empty leaves the choice to the model.
+	DescriptionLanguage string
 }
 
-// NewClient creates a new Gemini client.
+// NewClient creates a new Gemini client for the configured provider.
 func NewClient(ctx context.Context, cfg Config) (LLMClient, error) {
-	if cfg.APIKey == "" {
-		return nil, fmt.Errorf("cannot create Gemini client: API key is missing")
+	var b backend
+	var err error
+	switch strings.ToLower(cfg.Provider) {
+	case "", ProviderGemini:
+		b, err = newGeminiAPIBackend(ctx, cfg.APIKey)
+	case ProviderVertexAI:
+		b, err = newVertexBackend(ctx, cfg.Project, cfg.Location)
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: '%s'. Must be '%s' or '%s'", cfg.Provider, ProviderGemini, ProviderVertexAI)
 	}
-
-	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.APIKey))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+		return nil, err
 	}
 
 	if cfg.Model == "" {
@@ -62,6 +120,8 @@ func NewClient(ctx context.Context, cfg Config) (LLMClient, error) {
 	// Set default retry parameters if not provided
 	if cfg.MaxRetries == 0 {
 		cfg.MaxRetries = 3
+	} else if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
 	}
 	if cfg.InitialBackoff == 0 {
 		cfg.InitialBackoff = 2 * time.Second
@@ -71,26 +131,63 @@ func NewClient(ctx context.Context, cfg Config) (LLMClient, error) {
 	}
 
 	return &geminiClient{
-		client: client,
-		cfg:    cfg,
+		backend: b,
+		cfg:     cfg,
 	}, nil
 }
 
 // Close cleans up the underlying Gemini client.
 func (c *geminiClient) Close() error {
-	if c.client != nil {
-		return c.client.Close()
+	if c.backend != nil {
+		return c.backend.close()
 	}
 	return nil
 }
 
-// IsAPIKeyValid checks if the Gemini API key is valid by listing models.
-func (c *geminiClient) IsAPIKeyValid(ctx context.Context) error {
-	if c.client == nil {
-		return fmt.Errorf("gemini client not initialized (likely missing API key)")
+// CheckAccess checks that the configured credentials can reach the model.
+func (c *geminiClient) CheckAccess(ctx context.Context) error {
+	if c.backend == nil {
+		return fmt.Errorf("gemini client not initialized (likely missing credentials)")
+	}
+	return c.backend.checkAccess(ctx, c.cfg.Model)
+}
+
+// geminiAPIBackend reaches Gemini models through the Gemini API with an API key.
+type geminiAPIBackend struct {
+	client *genai.Client
+}
+
+func newGeminiAPIBackend(ctx context.Context, apiKey string) (*geminiAPIBackend, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("cannot create Gemini client: API key is missing")
+	}
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+	return &geminiAPIBackend{client: client}, nil
+}
+
+func (b *geminiAPIBackend) generateContent(ctx context.Context, model string, settings generationSettings, schema *genai.Schema, prompt string) (response, error) {
+	m := b.client.GenerativeModel(model)
+	m.SetTemperature(settings.temperature)
+	m.SetMaxOutputTokens(settings.maxOutputTokens)
+	m.SetTopP(settings.topP)
+	m.SetTopK(settings.topK)
+	if schema != nil {
+		m.ResponseMIMEType = "application/json"
+		m.ResponseSchema = schema
 	}
+	resp, err := m.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return nil, err
+	}
+	return geminiAPIResponse{resp}, nil
+}
 
-	modelIterator := c.client.ListModels(ctx)
+// checkAccess checks that the Gemini API key is valid by listing models.
+func (b *geminiAPIBackend) checkAccess(ctx context.Context, model string) error {
+	modelIterator := b.client.ListModels(ctx)
 	_, err := modelIterator.Next() // Attempt to list one model
 	if err != nil {
 		if st, ok := status.FromError(err); ok {
@@ -107,64 +204,158 @@ func (c *geminiClient) IsAPIKeyValid(ctx context.Context) error {
 	return nil
 }
 
-// generateWithRetry wraps the GenerateContent call with retry logic for rate limit errors.
-func (c *geminiClient) generateWithRetry(ctx context.Context, model *genai.GenerativeModel, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
-	var resp *genai.GenerateContentResponse
-	var err error
-	backoff := c.cfg.InitialBackoff
+func (b *geminiAPIBackend) close() error {
+	return b.client.Close()
+}
+
+// geminiAPIResponse is a response of the Gemini API.
+type geminiAPIResponse struct {
+	resp *genai.GenerateContentResponse
+}
+
+func (r geminiAPIResponse) firstText() (string, error) {
+	resp := r.resp
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		finishReason := "unknown"
+		safetyRatings := "none"
+		if resp != nil && len(resp.Candidates) > 0 {
+			finishReason = resp.Candidates[0].FinishReason.String()
+			if resp.Candidates[0].SafetyRatings != nil {
+				safetyRatings = fmt.Sprintf("%v", resp.Candidates[0].SafetyRatings)
+			}
+		}
+		return "", fmt.Errorf("empty or incomplete response from Gemini API. FinishReason: %s, SafetyRatings: %s", finishReason, safetyRatings)
+	}
+	part := resp.Candidates[0].Content.Parts[0]
+	text, ok := part.(genai.Text)
+	if !ok {
+		return "", fmt.Errorf("unexpected response part type: %T", part)
+	}
+	return string(text), nil
+}
 
-	for i := 0; i <= c.cfg.MaxRetries; i++ {
+// generateWithRetry wraps the generateContent call with retry logic for rate limit
+// and unavailable errors.
+func (c *geminiClient) generateWithRetry(ctx context.Context, settings generationSettings, schema *genai.Schema, prompt string) (response, error) {
+	return retryGemini(ctx, c.cfg, func() (response, error) {
+		return c.backend.generateContent(ctx, c.cfg.Model, settings, schema, prompt)
+	})
+}
+
+// retryGemini calls fn, and calls it again up to cfg.MaxRetries times while it fails
+// because of rate limits or because the service is temporarily unavailable. The wait
+// starts at cfg.InitialBackoff and doubles up to cfg.MaxBackoff. Other errors are
+// returned at once.
+func retryGemini[T any](ctx context.Context, cfg Config, fn func() (T, error)) (T, error) {
+	var zero T
+	backoff := cfg.InitialBackoff
+
+	for i := 0; ; i++ {
 		if i > 0 {
 			// Wait before retrying
 			sleepDuration := backoff
-			log.Printf("INFO: Retrying Gemini API call (attempt %d/%d after %s delay)...", i, c.cfg.MaxRetries, sleepDuration)
+			log.Printf("INFO: Retrying Gemini API call (attempt %d/%d after %s delay)...", i, cfg.MaxRetries, sleepDuration)
 			select {
 			case <-ctx.Done():
-				return nil, ctx.Err()
+				return zero, ctx.Err()
 			case <-time.After(sleepDuration):
 			}
 			// Exponential backoff
 			backoff *= 2
-			if backoff > c.cfg.MaxBackoff {
-				backoff = c.cfg.MaxBackoff
+			if backoff > cfg.MaxBackoff {
+				backoff = cfg.MaxBackoff
 			}
 		}
 
-		resp, err = model.GenerateContent(ctx, parts...)
+		result, err := fn()
 		if err == nil {
-			return resp, nil // Success
+			return result, nil // Success
 		}
 
-		// Check if the error is a rate limit error (429)
-		if apiErr, ok := err.(*googleapi.Error); ok && apiErr.Code == 429 {
-			log.Printf("WARN: Gemini API rate limit exceeded (attempt %d/%d): %v", i, c.cfg.MaxRetries, err)
-			if i == c.cfg.MaxRetries {
-				return nil, fmt.Errorf("Gemini API call failed after %d retries due to rate limits: %w", c.cfg.MaxRetries, err)
+		switch {
+		case IsRateLimitError(err):
+			log.Printf("WARN: Gemini API rate limit exceeded (attempt %d/%d): %v", i, cfg.MaxRetries, err)
+			if i >= cfg.MaxRetries {
+				return zero, fmt.Errorf("Gemini API call failed after %d retries due to rate limits: %w", cfg.MaxRetries, err)
 			}
-			// Continue to the next iteration to retry
-		} else if ctx.Err() == context.Canceled || ctx.Err() == context.DeadlineExceeded {
+		case IsUnavailableError(err):
+			log.Printf("WARN: Gemini API unavailable (attempt %d/%d): %v", i, cfg.MaxRetries, err)
+			if i >= cfg.MaxRetries {
+				return zero, fmt.Errorf("Gemini API call failed after %d retries because the service was unavailable: %w", cfg.MaxRetries, err)
+			}
+		case ctx.Err() == context.Canceled || ctx.Err() == context.DeadlineExceeded:
 			log.Printf("WARN: Context cancelled during Gemini API call: %v", ctx.Err())
-			return nil, ctx.Err()
-		} else {
+			return zero, ctx.Err()
+		default:
 			// Non-retryable error
-			return nil, fmt.Errorf("Gemini API call failed: %w", err)
+			return zero, fmt.Errorf("Gemini API call failed: %w", err)
+		}
+	}
+}
+
+// generateStructured calls the model, asking for JSON that follows schema when
+// StructuredOutput is set. A model that rejects the schema is asked again for plain
+// text, so structured reports which form the response is in.
+func (c *geminiClient) generateStructured(ctx context.Context, settings generationSettings, schema *genai.Schema, prompt string) (resp response, structured bool, err error) {
+	if c.cfg.StructuredOutput {
+		resp, err = c.generateWithRetry(ctx, settings, schema, prompt)
+		if err == nil || IsRateLimitError(err) || ctx.Err() != nil {
+			return resp, err == nil, err
 		}
+		log.Printf("WARN: Gemini model %s rejected the structured output request: %v. Retrying without a response schema.", c.cfg.Model, err)
 	}
-	return nil, err // Should only be reached if MaxRetries is somehow 0 or less initially
+	resp, err = c.generateWithRetry(ctx, settings, nil, prompt)
+	return resp, false, err
 }
 
 // GenerateDescription generates a description using the Gemini API.
 func (c *geminiClient) GenerateDescription(ctx context.Context, objectType, objectName, parentName, knowledgeContext string) (string, error) {
-	if c.client == nil {
+	if c.backend == nil {
 		return "", fmt.Errorf("gemini client not initialized")
 	}
 	if knowledgeContext == "" {
 		return "", nil
 	}
 
-	var targetDescription string
-	var prompt string
+	prompt, targetDescription, err := buildDescriptionPrompt(objectType, objectName, parentName, knowledgeContext, c.cfg.DescriptionLanguage)
+	if err != nil {
+		return "", err
+	}
+
+	// --- Call Gemini API ---
+	resp, structured, err := c.generateStructured(ctx, descriptionSettings, descriptionSchema, prompt)
+	if err != nil {
+		return "", err // Error from generateWithRetry
+	}
+
+	if structured {
+		rawText, _ := getFirstTextPart(resp)
+		if description, ok := parseDescriptionJSON(rawText); ok {
+			log.Printf("INFO: Generated description for %s using model %s.", targetDescription, c.cfg.Model)
+			return description, nil
+		}
+		log.Printf("WARN: Gemini response for %s is not the requested JSON. Falling back to <result> tags.", targetDescription)
+	}
+
+	description, err := extractTextBetweenTags(resp, "<result>", "</result>")
+	if err != nil {
+		// Log the raw response text if extraction fails, for debugging
+		rawText, _ := getFirstTextPart(resp)
+		log.Printf("WARN: Could not extract description from Gemini response for %s: %v. Raw response: '%s'", targetDescription, err, rawText)
+		return "", nil
+	}
+
+	log.Printf("INFO: Generated description for %s using model %s.", targetDescription, c.cfg.Model)
+	return description, nil
+}
+
+// ColumnRelationshipsPrefix starts the sentence that table_with_relationships
+// descriptions end with, listing the relationships between the table's columns.
+const ColumnRelationshipsPrefix = "Column relationships:"
 
+// buildDescriptionPrompt returns the prompt describing one table or column, and the
+// target it names for log messages.
+func buildDescriptionPrompt(objectType, objectName, parentName, knowledgeContext, language string) (prompt string, targetDescription string, err error) {
 	switch strings.ToLower(objectType) {
 	case "column":
 		targetDescription = fmt.Sprintf("Column Name: %s in Table: %s", objectName, parentName)
@@ -179,12 +370,12 @@ func (c *geminiClient) GenerateDescription(ctx context.Context, objectType, obje
 	1. Analyze the Knowledge Context carefully.
 	2. Determine if the context provides any relevant information SPECIFICALLY about the target column '%s' within the table '%s'.
 	3. If relevant information is found, generate a concise description (max 50 words) summarizing that information. Output ONLY the description text within <result></result> tags.
-	4. If NO relevant information about THIS SPECIFIC column/table combination is found in the context, output empty <result></result> tags. Do NOT invent descriptions or use general knowledge.
+	4. If NO relevant information about THIS SPECIFIC column/table combination is found in the context, output empty <result></result> tags. Do NOT invent descriptions or use general knowledge.%s
 
 	Target: %s
 
 	Begin analysis and provide description if applicable:
-	`, knowledgeContext, objectName, parentName, targetDescription)
+	`, knowledgeContext, objectName, parentName, languageInstruction(language), targetDescription)
 
 	case "table":
 		targetDescription = fmt.Sprintf("Table: %s", objectName)
@@ -199,44 +390,104 @@ func (c *geminiClient) GenerateDescription(ctx context.Context, objectType, obje
 	1. Analyze the Knowledge Context carefully.
 	2. Determine if the context provides any relevant information SPECIFICALLY about the target table '%s'.
 	3. If relevant information is found, generate a concise description (max 50 words) summarizing that information. Output ONLY the description text within <result></result> tags.
-	4. If NO relevant information about THIS SPECIFIC table is found in the context, output empty <result></result> tags. Do NOT invent descriptions or use general knowledge.
+	4. If NO relevant information about THIS SPECIFIC table is found in the context, output empty <result></result> tags. Do NOT invent descriptions or use general knowledge.%s
 
 	Target: %s
 
 	Begin analysis and provide description if applicable:
-	`, knowledgeContext, objectName, targetDescription)
+	`, knowledgeContext, objectName, languageInstruction(language), targetDescription)
+
+	case "table_with_relationships":
+		// The table prompt for --describe-column-relationships. The Knowledge Context ends
+		// with the table's columns, so the model can also name the ones that belong together.
+		targetDescription = fmt.Sprintf("Table: %s", objectName)
+		prompt = fmt.Sprintf(`
+	Your task is to generate a brief and concise description for a database table, followed by the notable relationships between its columns, based ONLY on the provided knowledge context.
+
+	********** Knowledge Context **********
+	%s
+	********** End Knowledge Context **********
+
+	**Instructions:**
+	1. Analyze the Knowledge Context carefully, including the list of columns of the table '%s'.
+	2. Determine if the context provides any relevant information SPECIFICALLY about the target table '%s'. If it does, generate a concise description (max 50 words) summarizing that information.
+	3. Look for meaning that only emerges from a combination of columns, for example 'start_date' and 'end_date' forming a validity range, 'amount' and 'currency' together forming a price, or 'latitude' and 'longitude' forming a location. If such relationships are found, summarize them concisely (max 40 words), naming the columns involved, in a final sentence that starts with exactly '%s'. Do NOT describe the columns one by one, relationships enforced by foreign keys, or relationships the context does not support.
+	4. Output ONLY the description followed by that sentence within <result></result> tags. If the context supports neither, output empty <result></result> tags. Do NOT invent descriptions or use general knowledge.%s
+
+	Target: %s
+
+	Begin analysis and provide description if applicable:
+	`, knowledgeContext, objectName, objectName, ColumnRelationshipsPrefix, languageInstruction(language), targetDescription)
 
 	default:
-		return "", fmt.Errorf("unsupported object type for description generation: %s", objectType)
+		return "", "", fmt.Errorf("unsupported object type for description generation: %s", objectType)
 	}
+	return prompt, targetDescription, nil
+}
 
-	// --- Call Gemini API ---
-	model := c.client.GenerativeModel(c.cfg.Model)
-	model.SetTemperature(0.3)
-	model.SetMaxOutputTokens(5000) // Keep the increased token limit
-	model.SetTopP(0.9)
-	model.SetTopK(40)
+// languageInstruction returns the prompt line asking for descriptions in language,
+// or nothing when no language was requested.
+func languageInstruction(language string) string {
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\tWrite every description in %s, whatever the language of the Knowledge Context. Keep tags and column names exactly as given.", language)
+}
+
+// GenerateColumnDescriptions generates descriptions for all given columns of a table with one Gemini call.
+func (c *geminiClient) GenerateColumnDescriptions(ctx context.Context, tableName string, columnNames []string, knowledgeContext string) (map[string]string, error) {
+	if c.backend == nil {
+		return nil, fmt.Errorf("gemini client not initialized")
+	}
+	if knowledgeContext == "" || len(columnNames) == 0 {
+		return map[string]string{}, nil
+	}
 
-	resp, err := c.generateWithRetry(ctx, model, genai.Text(prompt)) // Use retry helper
+	prompt := buildColumnDescriptionsPrompt(tableName, columnNames, knowledgeContext, c.cfg.DescriptionLanguage)
+
+	resp, err := c.generateWithRetry(ctx, descriptionSettings, nil, prompt)
 	if err != nil {
-		return "", err // Error from generateWithRetry
+		return nil, err
 	}
 
-	description, err := extractTextBetweenTags(resp, "<result>", "</result>")
+	block, err := extractTextBetweenTags(resp, "<result>", "</result>")
 	if err != nil {
-		// Log the raw response text if extraction fails, for debugging
 		rawText, _ := getFirstTextPart(resp)
-		log.Printf("WARN: Could not extract description from Gemini response for %s: %v. Raw response: '%s'", targetDescription, err, rawText)
-		return "", nil
+		return nil, fmt.Errorf("could not extract column descriptions for table %s: %w. Raw response: '%s'", tableName, err, rawText)
 	}
 
-	log.Printf("INFO: Generated description for %s using model %s.", targetDescription, c.cfg.Model)
-	return description, nil
+	descriptions := parseColumnDescriptions(block, columnNames)
+	log.Printf("INFO: Generated %d column description(s) for table %s in one call using model %s.", len(descriptions), tableName, c.cfg.Model)
+	return descriptions, nil
+}
+
+// buildColumnDescriptionsPrompt returns the prompt asking for descriptions of all the
+// given columns of a table in one response.
+func buildColumnDescriptionsPrompt(tableName string, columnNames []string, knowledgeContext, language string) string {
+	return fmt.Sprintf(`
+	Your task is to generate brief and concise descriptions for the columns of a database table based ONLY on the provided knowledge context.
+
+	********** Knowledge Context **********
+	%s
+	********** End Knowledge Context **********
+
+	**Instructions:**
+	1. Analyze the Knowledge Context carefully.
+	2. For each target column of the table '%s', determine if the context provides any relevant information SPECIFICALLY about that column.
+	3. For every column with relevant information, output one line of the form <column name="COLUMN_NAME">description</column>, using the column name exactly as listed, with a concise description (max 50 words).
+	4. Omit columns the context says nothing about. Do NOT invent descriptions or use general knowledge.
+	5. Wrap all <column> lines within <result></result> tags. Output empty <result></result> tags if no column is described.%s
+
+	Target Table: %s
+	Target Columns: %s
+
+	Begin analysis and provide descriptions if applicable:
+	`, knowledgeContext, tableName, languageInstruction(language), tableName, strings.Join(columnNames, ", "))
 }
 
 // GenerateSyntheticExamples generates synthetic examples if PII is detected.
 func (c *geminiClient) GenerateSyntheticExamples(ctx context.Context, columnName, tableName, dataType string, originalExamples []string, maskPII bool) (processedExamples []string, wasSynthesized bool, err error) {
-	if c.client == nil {
+	if c.backend == nil {
 		return originalExamples, false, fmt.Errorf("gemini client not initialized")
 	}
 	if len(originalExamples) == 0 {
@@ -248,38 +499,15 @@ func (c *geminiClient) GenerateSyntheticExamples(ctx context.Context, columnName
 		return originalExamples, false, nil
 	}
 
-	exampleValuesStr := strings.Join(originalExamples, ", ")
-
-	prompt := fmt.Sprintf(`
-	You are an expert in data privacy and database metadata. Analyze the following database column and its example values for Personally Identifiable Information (PII).
-
-	**Column Information:**
-	- Column Name: %s
-	- Table Name: %s
-	- Data Type: %s
-	- Original Example Values: [%s]
-
-	**Instructions:**
-	1. **Analyze for PII:** Based ONLY on the column name, data type, and example values, determine if this column is LIKELY to contain PII (e.g., names, emails, phones, addresses, specific IDs). Be conservative; if unsure, assume it's NOT PII.
-	2. **Decision & Output:**
-	- **If LIKELY PII:** Generate %d synthetic, plausible-looking example values that match the likely *pattern* and *data type* (%s) of the original data but are clearly fake. Output these values as a comma-separated list enclosed ONLY in <synthetic_examples>...</synthetic_examples> tags.
-	- **If NOT LIKELY PII (or unsure):** Output the tag <original_examples></original_examples> to indicate the original values should be used.
-
-	**Example Output (Synthetic):** <synthetic_examples>user1@example.com, user2@example.net, user3@example.org</synthetic_examples>
-	**Example Output (Original):** <original_examples></original_examples>
-
-	Provide your output based on the analysis:
-	`, columnName, tableName, dataType, exampleValuesStr, len(originalExamples), dataType) // Request same number of examples
+	prompt := buildSyntheticExamplesPrompt(columnName, tableName, dataType, originalExamples)
 
-	model := c.client.GenerativeModel(c.cfg.Model)
-	model.SetTemperature(0.5)
-	model.SetMaxOutputTokens(500)
-	model.SetTopP(0.9)
-	model.SetTopK(40)
-
-	resp, err := c.generateWithRetry(ctx, model, genai.Text(prompt)) // Use retry helper
+	resp, structured, err := c.generateStructured(ctx, syntheticExamplesSettings, syntheticExamplesSchema, prompt)
 	if err != nil {
 		log.Printf("WARN: Gemini API call for synthetic examples failed: %v. Returning original examples.", err)
+		if IsRateLimitError(err) {
+			// Reported so that a fallback model can be tried.
+			return originalExamples, false, err
+		}
 		return originalExamples, false, nil
 	}
 
@@ -289,6 +517,18 @@ func (c *geminiClient) GenerateSyntheticExamples(ctx context.Context, columnName
 		return originalExamples, false, nil
 	}
 
+	if structured {
+		if examples, isPII, ok := parseSyntheticExamplesJSON(fullResponseText); ok {
+			if isPII && len(examples) > 0 {
+				log.Printf("INFO: Gemini determined column '%s.%s' might be PII; generated %d synthetic examples.", tableName, columnName, len(examples))
+				return examples, true, nil
+			}
+			log.Printf("INFO: Gemini determined column '%s.%s' is likely NOT PII. Using original examples.", tableName, columnName)
+			return originalExamples, false, nil
+		}
+		log.Printf("WARN: Gemini response for '%s.%s' is not the requested JSON. Falling back to tags.", tableName, columnName)
+	}
+
 	syntheticContent, foundSynthetic := extractContentBetween(fullResponseText, "<synthetic_examples>", "</synthetic_examples>")
 	if foundSynthetic {
 		examples := parseCommaSeparated(syntheticContent)
@@ -311,29 +551,43 @@ func (c *geminiClient) GenerateSyntheticExamples(ctx context.Context, columnName
 	return originalExamples, false, nil
 }
 
+// buildSyntheticExamplesPrompt returns the prompt asking whether a column's example
+// values are PII and, if so, for synthetic replacements.
+func buildSyntheticExamplesPrompt(columnName, tableName, dataType string, originalExamples []string) string {
+	exampleValuesStr := strings.Join(originalExamples, ", ")
+
+	return fmt.Sprintf(`
+	You are an expert in data privacy and database metadata. Analyze the following database column and its example values for Personally Identifiable Information (PII).
+
+	**Column Information:**
+	- Column Name: %s
+	- Table Name: %s
+	- Data Type: %s
+	- Original Example Values: [%s]
+
+	**Instructions:**
+	1. **Analyze for PII:** Based ONLY on the column name, data type, and example values, determine if this column is LIKELY to contain PII (e.g., names, emails, phones, addresses, specific IDs). Be conservative; if unsure, assume it's NOT PII.
+	2. **Decision & Output:**
+	- **If LIKELY PII:** Generate %d synthetic, plausible-looking example values that match the likely *pattern* and *data type* (%s) of the original data but are clearly fake. Output these values as a comma-separated list enclosed ONLY in <synthetic_examples>...</synthetic_examples> tags.
+	- **If NOT LIKELY PII (or unsure):** Output the tag <original_examples></original_examples> to indicate the original values should be used.
+
+	**Example Output (Synthetic):** <synthetic_examples>user1@example.com, user2@example.net, user3@example.org</synthetic_examples>
+	**Example Output (Original):** <original_examples></original_examples>
+
+	Provide your output based on the analysis:
+	`, columnName, tableName, dataType, exampleValuesStr, len(originalExamples), dataType) // Request same number of examples
+}
+
 // getFirstTextPart extracts the first text part from a Gemini response.
-func getFirstTextPart(resp *genai.GenerateContentResponse) (string, error) {
-	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-		finishReason := "unknown"
-		safetyRatings := "none"
-		if resp != nil && len(resp.Candidates) > 0 {
-			finishReason = resp.Candidates[0].FinishReason.String()
-			if resp.Candidates[0].SafetyRatings != nil {
-				safetyRatings = fmt.Sprintf("%v", resp.Candidates[0].SafetyRatings)
-			}
-		}
-		return "", fmt.Errorf("empty or incomplete response from Gemini API. FinishReason: %s, SafetyRatings: %s", finishReason, safetyRatings)
-	}
-	part := resp.Candidates[0].Content.Parts[0]
-	text, ok := part.(genai.Text)
-	if !ok {
-		return "", fmt.Errorf("unexpected response part type: %T", part)
+func getFirstTextPart(resp response) (string, error) {
+	if resp == nil {
+		return "", fmt.Errorf("empty response from Gemini API")
 	}
-	return string(text), nil
+	return resp.firstText()
 }
 
 // extractTextBetweenTags extracts text between the first occurrence of startTag and endTag.
-func extractTextBetweenTags(resp *genai.GenerateContentResponse, startTag, endTag string) (string, error) {
+func extractTextBetweenTags(resp response, startTag, endTag string) (string, error) {
 	fullText, err := getFirstTextPart(resp)
 	if err != nil {
 		return "", fmt.Errorf("failed to get text part: %w", err)
@@ -346,18 +600,153 @@ func extractTextBetweenTags(resp *genai.GenerateContentResponse, startTag, endTa
 	return content, nil
 }
 
-// extractContentBetween extracts content between start and end tags from a string.
-func extractContentBetween(text, startTag, endTag string) (string, bool) {
-	startIndex := strings.Index(text, startTag)
-	if startIndex == -1 {
-		return "", false
+// Response schemas used with Config.StructuredOutput.
+var (
+	descriptionSchema = &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"description": {Type: genai.TypeString, Description: "The description, or an empty string if the context says nothing about the target."},
+		},
+		Required: []string{"description"},
+	}
+	syntheticExamplesSchema = &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"is_pii":             {Type: genai.TypeBoolean, Description: "Whether the column is likely to contain PII."},
+			"synthetic_examples": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}, Description: "Fake example values, only when is_pii is true."},
+		},
+		Required: []string{"is_pii"},
 	}
-	startIndex += len(startTag)
-	endIndex := strings.Index(text[startIndex:], endTag)
-	if endIndex == -1 {
+)
+
+// parseDescriptionJSON reads a response following descriptionSchema. Models sometimes
+// keep the <result> tags the prompt asks for inside the JSON value; they are removed.
+func parseDescriptionJSON(text string) (string, bool) {
+	var response struct {
+		Description *string `json:"description"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &response); err != nil || response.Description == nil {
 		return "", false
 	}
-	return strings.TrimSpace(text[startIndex : startIndex+endIndex]), true
+	description := strings.TrimSpace(*response.Description)
+	if inner, ok := extractContentBetween(description, "<result>", "</result>"); ok {
+		description = inner
+	}
+	return description, true
+}
+
+// parseSyntheticExamplesJSON reads a response following syntheticExamplesSchema.
+func parseSyntheticExamplesJSON(text string) (examples []string, isPII bool, ok bool) {
+	var response struct {
+		IsPII             *bool    `json:"is_pii"`
+		SyntheticExamples []string `json:"synthetic_examples"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &response); err != nil || response.IsPII == nil {
+		return nil, false, false
+	}
+	for _, example := range response.SyntheticExamples {
+		if trimmed := strings.TrimSpace(example); trimmed != "" {
+			examples = append(examples, trimmed)
+		}
+	}
+	return examples, *response.IsPII, true
+}
+
+// extractContentBetween extracts content between start and end tags from a string.
+// Model output is not always tidy, so each end tag is paired with the nearest start
+// tag before it: nested blocks yield the innermost content, and stray tags without a
+// partner are ignored. When there are several blocks, the last non-empty one wins,
+// since models tend to echo the prompt's empty example tags before answering.
+// found reports whether any complete pair exists.
+func extractContentBetween(text, startTag, endTag string) (content string, found bool) {
+	rest := text
+	for {
+		endIndex := strings.Index(rest, endTag)
+		if endIndex == -1 {
+			return content, found
+		}
+		if startIndex := strings.LastIndex(rest[:endIndex], startTag); startIndex != -1 {
+			inner := strings.TrimSpace(rest[startIndex+len(startTag) : endIndex])
+			if !found || inner != "" {
+				content = inner
+			}
+			found = true
+		}
+		rest = rest[endIndex+len(endTag):]
+	}
+}
+
+var (
+	columnDescriptionPattern     = regexp.MustCompile(`(?s)<column name="([^"]+)">(.*?)</column>`)
+	columnDescriptionLinePattern = regexp.MustCompile(`^\s*(?:[-*]\s*)?` + "`?([^:`]+?)`?" + `\s*:\s*(.+)$`)
+)
+
+// parseColumnDescriptions maps column names to descriptions from a block of
+// <column name="...">...</column> entries. Models do not always keep to that format,
+// so a JSON object of name to description and "name: description" lines are accepted
+// too. Unknown or empty entries are dropped; columns missing from the block are
+// simply absent from the result.
+func parseColumnDescriptions(block string, columnNames []string) map[string]string {
+	requested := make(map[string]bool, len(columnNames))
+	for _, name := range columnNames {
+		requested[name] = true
+	}
+	descriptions := make(map[string]string)
+	add := func(name, desc string) {
+		name, desc = strings.TrimSpace(name), strings.TrimSpace(desc)
+		if requested[name] && desc != "" {
+			descriptions[name] = desc
+		}
+	}
+
+	if matches := columnDescriptionPattern.FindAllStringSubmatch(block, -1); len(matches) > 0 {
+		for _, match := range matches {
+			add(match[1], match[2])
+		}
+		return descriptions
+	}
+
+	jsonBlock := strings.TrimSpace(block)
+	jsonBlock = strings.TrimPrefix(jsonBlock, "```json")
+	jsonBlock = strings.Trim(jsonBlock, "`\n ")
+	var object map[string]string
+	if err := json.Unmarshal([]byte(jsonBlock), &object); err == nil {
+		for name, desc := range object {
+			add(name, desc)
+		}
+		return descriptions
+	}
+
+	for _, line := range strings.Split(block, "\n") {
+		if match := columnDescriptionLinePattern.FindStringSubmatch(line); match != nil {
+			add(match[1], match[2])
+		}
+	}
+	return descriptions
+}
+
+// IsRateLimitError reports whether err is, or wraps, a Gemini API rate limit or quota
+// error (HTTP 429 or gRPC RESOURCE_EXHAUSTED), such as the one returned once
+// generateWithRetry runs out of retries.
+func IsRateLimitError(err error) bool {
+	return hasErrorCode(err, 429, codes.ResourceExhausted)
+}
+
+// IsUnavailableError reports whether err is a Gemini API error saying the service is
+// temporarily unavailable (HTTP 503 or gRPC UNAVAILABLE), which is worth retrying.
+func IsUnavailableError(err error) bool {
+	return hasErrorCode(err, 503, codes.Unavailable)
+}
+
+// hasErrorCode reports whether err wraps a Gemini API error with the given HTTP
+// status or gRPC code.
+func hasErrorCode(err error, httpCode int, grpcCode codes.Code) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == httpCode {
+		return true
+	}
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	return errors.As(err, &grpcErr) && grpcErr.GRPCStatus().Code() == grpcCode
 }
 
 // parseCommaSeparated parses a comma-separated string into a slice of trimmed strings.
@@ -0,0 +1,369 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseColumnDescriptions(t *testing.T) {
+	columns := []string{"id", "total", "note"}
+	tests := []struct {
+		name  string
+		block string
+		want  map[string]string
+	}{
+		{
+			name: "tags",
+			block: `
+<column name="id">Order identifier</column>
+<column name="total">
+  Order total in cents
+</column>
+<column name="unknown">Not requested</column>
+<column name="note"></column>`,
+			want: map[string]string{
+				"id":    "Order identifier",
+				"total": "Order total in cents",
+			},
+		},
+		{
+			name:  "json",
+			block: "```json\n{\"id\": \"Order identifier\", \"total\": \"Order total in cents\", \"unknown\": \"Not requested\"}\n```",
+			want: map[string]string{
+				"id":    "Order identifier",
+				"total": "Order total in cents",
+			},
+		},
+		{
+			name:  "lines",
+			block: "id: Order identifier\n- `total`: Order total in cents: tax included\nnote:\nunknown: Not requested",
+			want: map[string]string{
+				"id":    "Order identifier",
+				"total": "Order total in cents: tax included",
+			},
+		},
+		{
+			name:  "missing_columns",
+			block: `<column name="total">Order total in cents</column>`,
+			want:  map[string]string{"total": "Order total in cents"},
+		},
+		{
+			name:  "empty",
+			block: "",
+			want:  map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseColumnDescriptions(tt.block, columns)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseColumnDescriptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildColumnDescriptionsPrompt(t *testing.T) {
+	prompt := buildColumnDescriptionsPrompt("orders", []string{"id", "total"}, "Orders are placed in the web shop.", "")
+
+	for _, want := range []string{
+		"Orders are placed in the web shop.",
+		"Target Table: orders",
+		"Target Columns: id, total",
+		`<column name="COLUMN_NAME">description</column>`,
+		"<result></result>",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestIsRateLimitError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&googleapi.Error{Code: 429}, true},
+		{fmt.Errorf("Gemini API call failed after 3 retries due to rate limits: %w", &googleapi.Error{Code: 429}), true},
+		{status.Error(codes.ResourceExhausted, "quota exceeded"), true},
+		{fmt.Errorf("wrapped: %w", status.Error(codes.ResourceExhausted, "quota exceeded")), true},
+		{status.Error(codes.InvalidArgument, "bad request"), false},
+		{&googleapi.Error{Code: 500}, false},
+		{errors.New("boom"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsRateLimitError(tt.err); got != tt.want {
+			t.Errorf("IsRateLimitError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsUnavailableError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&googleapi.Error{Code: 503}, true},
+		{status.Error(codes.Unavailable, "try again later"), true},
+		{fmt.Errorf("wrapped: %w", status.Error(codes.Unavailable, "try again later")), true},
+		{&googleapi.Error{Code: 429}, false},
+		{status.Error(codes.ResourceExhausted, "quota exceeded"), false},
+		{errors.New("boom"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsUnavailableError(tt.err); got != tt.want {
+			t.Errorf("IsUnavailableError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryGemini(t *testing.T) {
+	cfg := Config{MaxRetries: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	unavailable := status.Error(codes.Unavailable, "try again later")
+	rateLimited := &googleapi.Error{Code: 429}
+	tests := []struct {
+		name      string
+		cfg       Config
+		errs      []error // Returned by the successive calls; later calls succeed.
+		wantCalls int
+		wantErr   func(error) bool
+	}{
+		{"succeeds at once", cfg, nil, 1, nil},
+		{"unavailable then succeeds", cfg, []error{unavailable, unavailable}, 3, nil},
+		{"rate limited then succeeds", cfg, []error{rateLimited}, 2, nil},
+		{"gives up after the retries", cfg, []error{rateLimited, rateLimited, rateLimited, rateLimited}, 4, IsRateLimitError},
+		{"no retries", Config{}, []error{unavailable}, 1, IsUnavailableError},
+		{"other errors are not retried", cfg, []error{status.Error(codes.InvalidArgument, "bad request")}, 1, func(err error) bool { return err != nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			got, err := retryGemini(context.Background(), tt.cfg, func() (string, error) {
+				calls++
+				if calls <= len(tt.errs) {
+					return "", tt.errs[calls-1]
+				}
+				return "ok", nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("retryGemini() made %d calls, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("retryGemini() error = %v, not the expected kind", err)
+				}
+				return
+			}
+			if err != nil || got != "ok" {
+				t.Errorf("retryGemini() = %q, %v, want \"ok\", nil", got, err)
+			}
+		})
+	}
+}
+
+func TestRetryGeminiHonoursContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := Config{MaxRetries: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+	_, err := retryGemini(ctx, cfg, func() (string, error) {
+		cancel()
+		return "", status.Error(codes.Unavailable, "try again later")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("retryGemini() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNewClientConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"unsupported provider", Config{Provider: "openai", APIKey: "key"}, "unsupported LLM provider"},
+		{"gemini without API key", Config{}, "API key is missing"},
+		{"vertexai without project", Config{Provider: ProviderVertexAI, Location: "us-central1"}, "project is missing"},
+		{"vertexai without location", Config{Provider: ProviderVertexAI, Project: "my-project"}, "location is missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient(context.Background(), tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewClient() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeBackend answers every request with text, and records the response schema of
+// each request. With rejectSchema set, requests with a schema fail.
+type fakeBackend struct {
+	text         string
+	rejectSchema bool
+	schemas      []*genai.Schema
+}
+
+type fakeResponse string
+
+func (r fakeResponse) firstText() (string, error) { return string(r), nil }
+
+func (b *fakeBackend) generateContent(ctx context.Context, model string, settings generationSettings, schema *genai.Schema, prompt string) (response, error) {
+	b.schemas = append(b.schemas, schema)
+	if schema != nil && b.rejectSchema {
+		return nil, status.Error(codes.InvalidArgument, "response schema not supported")
+	}
+	return fakeResponse(b.text), nil
+}
+
+func (b *fakeBackend) checkAccess(ctx context.Context, model string) error { return nil }
+
+func (b *fakeBackend) close() error { return nil }
+
+func TestGenerateDescriptionStructuredOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		backend     *fakeBackend
+		wantSchemas []*genai.Schema
+	}{
+		{"json", &fakeBackend{text: `{"description": "Orders placed online"}`}, []*genai.Schema{descriptionSchema}},
+		{"schema rejected", &fakeBackend{text: "<result>Orders placed online</result>", rejectSchema: true}, []*genai.Schema{descriptionSchema, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &geminiClient{backend: tt.backend, cfg: Config{Model: "test-model", StructuredOutput: true}}
+			got, err := client.GenerateDescription(context.Background(), "table", "orders", "", "Orders placed online.")
+			if err != nil || got != "Orders placed online" {
+				t.Errorf("GenerateDescription() = %q, %v, want \"Orders placed online\", nil", got, err)
+			}
+			if !reflect.DeepEqual(tt.backend.schemas, tt.wantSchemas) {
+				t.Errorf("GenerateDescription() sent schemas %v, want %v", tt.backend.schemas, tt.wantSchemas)
+			}
+		})
+	}
+}
+
+func TestParseDescriptionJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"description", `{"description": "Customer orders"}`, "Customer orders", true},
+		{"empty description", `{"description": ""}`, "", true},
+		{"tags kept inside the value", `{"description": "<result> Customer orders </result>"}`, "Customer orders", true},
+		{"surrounding whitespace", "\n {\"description\": \" Customer orders \"}\n", "Customer orders", true},
+		{"missing field", `{"summary": "Customer orders"}`, "", false},
+		{"tagged text", "<result>Customer orders</result>", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseDescriptionJSON(tt.text)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseDescriptionJSON(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseSyntheticExamplesJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantPII bool
+		wantOK  bool
+	}{
+		{"pii", `{"is_pii": true, "synthetic_examples": ["a@example.com", " ", "b@example.com "]}`, []string{"a@example.com", "b@example.com"}, true, true},
+		{"not pii", `{"is_pii": false}`, nil, false, true},
+		{"missing is_pii", `{"synthetic_examples": ["a@example.com"]}`, nil, false, false},
+		{"tagged text", "<original_examples></original_examples>", nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, isPII, ok := parseSyntheticExamplesJSON(tt.text)
+			if !reflect.DeepEqual(got, tt.want) || isPII != tt.wantPII || ok != tt.wantOK {
+				t.Errorf("parseSyntheticExamplesJSON(%q) = %v, %v, %v, want %v, %v, %v", tt.text, got, isPII, ok, tt.want, tt.wantPII, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestExtractContentBetween(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		want      string
+		wantFound bool
+	}{
+		{"single block", "Answer: <result> Customer orders </result>", "Customer orders", true},
+		{"no tags", "Customer orders", "", false},
+		{"empty block", "<result></result>", "", true},
+		{"last of several blocks", "<result>draft</result> then <result>Customer orders</result>", "Customer orders", true},
+		{"echoed empty example before the answer", "Output empty <result></result> tags if unsure. <result>Customer orders</result>", "Customer orders", true},
+		{"empty block after the answer", "<result>Customer orders</result> <result></result>", "Customer orders", true},
+		{"nested blocks", "<result>outer <result>Customer orders</result> tail</result>", "Customer orders", true},
+		{"unterminated start before a block", "<result>truncated <result>Customer orders</result>", "Customer orders", true},
+		{"stray end tag", "</result> <result>Customer orders</result> </result>", "Customer orders", true},
+		{"unterminated only", "<result>Customer orders", "", false},
+		{"end before start", "</result>Customer orders<result>", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := extractContentBetween(tt.text, "<result>", "</result>")
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("extractContentBetween(%q) = %q, %v, want %q, %v", tt.text, got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestDescriptionPromptsRequestLanguage(t *testing.T) {
+	columnPrompt, _, err := buildDescriptionPrompt("column", "email", "users", "Users sign up with an email address.", "fr")
+	if err != nil {
+		t.Fatalf("buildDescriptionPrompt() unexpected error: %v", err)
+	}
+	tablePrompt, _, err := buildDescriptionPrompt("table", "users", "", "Users sign up with an email address.", "fr")
+	if err != nil {
+		t.Fatalf("buildDescriptionPrompt() unexpected error: %v", err)
+	}
+	batchPrompt := buildColumnDescriptionsPrompt("users", []string{"id", "email"}, "Users sign up with an email address.", "fr")
+
+	for name, prompt := range map[string]string{"column": columnPrompt, "table": tablePrompt, "batch": batchPrompt} {
+		if !strings.Contains(prompt, "Write every description in fr") {
+			t.Errorf("%s prompt does not request the description language:\n%s", name, prompt)
+		}
+	}
+
+	unset, _, _ := buildDescriptionPrompt("column", "email", "users", "Users sign up with an email address.", "")
+	if strings.Contains(unset, "Write every description in") {
+		t.Errorf("prompt requests a language when none was set:\n%s", unset)
+	}
+}
+
+func TestBuildTableWithRelationshipsPrompt(t *testing.T) {
+	prompt, target, err := buildDescriptionPrompt("table_with_relationships", "bookings", "", "Bookings run from start_date to end_date.\n-- Columns of bookings --\nid (int), start_date (date), end_date (date)", "")
+	if err != nil {
+		t.Fatalf("buildDescriptionPrompt() unexpected error: %v", err)
+	}
+	if target != "Table: bookings" {
+		t.Errorf("buildDescriptionPrompt() target = %q", target)
+	}
+	for _, want := range []string{"description for a database table", "relationships between its columns", "list of columns of the table 'bookings'", "start_date (date), end_date (date)", "starts with exactly 'Column relationships:'", "<result></result>"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("table_with_relationships prompt does not contain %q:\n%s", want, prompt)
+		}
+	}
+}
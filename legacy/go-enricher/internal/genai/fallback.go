@@ -0,0 +1,80 @@
+package genai
+
+import (
+	"context"
+	"log"
+)
+
+// fallbackClient is an LLMClient that sends each call to a primary client and repeats
+// calls that fail on quota exhaustion with a fallback client, typically configured
+// with a cheaper model.
+type fallbackClient struct {
+	primary       LLMClient
+	primaryModel  string
+	fallback      LLMClient
+	fallbackModel string
+}
+
+// NewFallbackClient returns an LLMClient that uses fallback, running fallbackModel,
+// for calls on which primary, running primaryModel, reports a rate limit or quota error.
+func NewFallbackClient(primary LLMClient, primaryModel string, fallback LLMClient, fallbackModel string) LLMClient {
+	return &fallbackClient{primary: primary, primaryModel: primaryModel, fallback: fallback, fallbackModel: fallbackModel}
+}
+
+// useFallback reports whether a failed primary call should be repeated on the fallback.
+func (f *fallbackClient) useFallback(err error, target string) bool {
+	if !IsRateLimitError(err) {
+		return false
+	}
+	log.Printf("WARN: Model %s quota exhausted for %s: %v. Retrying with fallback model %s.", f.primaryModel, target, err, f.fallbackModel)
+	return true
+}
+
+func (f *fallbackClient) GenerateDescription(ctx context.Context, objectType, objectName, parentName, knowledgeContext string) (string, error) {
+	desc, err := f.primary.GenerateDescription(ctx, objectType, objectName, parentName, knowledgeContext)
+	target := objectType + " " + objectName
+	if parentName != "" {
+		target = objectType + " " + parentName + "." + objectName
+	}
+	if err == nil || !f.useFallback(err, target) {
+		return desc, err
+	}
+	desc, err = f.fallback.GenerateDescription(ctx, objectType, objectName, parentName, knowledgeContext)
+	if err == nil && desc != "" {
+		log.Printf("INFO: Description for %s produced by fallback model %s.", target, f.fallbackModel)
+	}
+	return desc, err
+}
+
+func (f *fallbackClient) GenerateColumnDescriptions(ctx context.Context, tableName string, columnNames []string, knowledgeContext string) (map[string]string, error) {
+	descs, err := f.primary.GenerateColumnDescriptions(ctx, tableName, columnNames, knowledgeContext)
+	target := "columns of table " + tableName
+	if err == nil || !f.useFallback(err, target) {
+		return descs, err
+	}
+	descs, err = f.fallback.GenerateColumnDescriptions(ctx, tableName, columnNames, knowledgeContext)
+	if err == nil {
+		log.Printf("INFO: %d description(s) for %s produced by fallback model %s.", len(descs), target, f.fallbackModel)
+	}
+	return descs, err
+}
+
+func (f *fallbackClient) GenerateSyntheticExamples(ctx context.Context, columnName, tableName, dataType string, originalExamples []string, maskPII bool) ([]string, bool, error) {
+	examples, synthesized, err := f.primary.GenerateSyntheticExamples(ctx, columnName, tableName, dataType, originalExamples, maskPII)
+	if err == nil || !f.useFallback(err, "PII check of column "+tableName+"."+columnName) {
+		return examples, synthesized, err
+	}
+	return f.fallback.GenerateSyntheticExamples(ctx, columnName, tableName, dataType, originalExamples, maskPII)
+}
+
+func (f *fallbackClient) CheckAccess(ctx context.Context) error {
+	return f.primary.CheckAccess(ctx)
+}
+
+func (f *fallbackClient) Close() error {
+	primaryErr := f.primary.Close()
+	if err := f.fallback.Close(); err != nil {
+		return err
+	}
+	return primaryErr
+}
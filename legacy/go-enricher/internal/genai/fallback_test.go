@@ -0,0 +1,96 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeModelClient answers with a fixed description, or fails every call with err.
+type fakeModelClient struct {
+	LLMClient
+	description string
+	err         error
+	calls       int
+}
+
+func (f *fakeModelClient) GenerateDescription(ctx context.Context, objectType, objectName, parentName, knowledgeContext string) (string, error) {
+	f.calls++
+	return f.description, f.err
+}
+
+func (f *fakeModelClient) GenerateColumnDescriptions(ctx context.Context, tableName string, columnNames []string, knowledgeContext string) (map[string]string, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return map[string]string{columnNames[0]: f.description}, nil
+}
+
+func (f *fakeModelClient) GenerateSyntheticExamples(ctx context.Context, columnName, tableName, dataType string, originalExamples []string, maskPII bool) ([]string, bool, error) {
+	f.calls++
+	if f.err != nil {
+		return originalExamples, false, f.err
+	}
+	return []string{"fake@example.com"}, true, nil
+}
+
+func TestFallbackClient(t *testing.T) {
+	quotaErrors := map[string]error{
+		"http 429":           fmt.Errorf("Gemini API call failed after 3 retries due to rate limits: %w", &googleapi.Error{Code: 429}),
+		"resource exhausted": status.Error(codes.ResourceExhausted, "quota exceeded"),
+	}
+	for name, quotaErr := range quotaErrors {
+		t.Run(name, func(t *testing.T) {
+			primary := &fakeModelClient{err: quotaErr}
+			fallback := &fakeModelClient{description: "Customer orders"}
+			client := NewFallbackClient(primary, "gemini-pro", fallback, "gemini-flash")
+			ctx := context.Background()
+
+			desc, err := client.GenerateDescription(ctx, "table", "orders", "", "context")
+			if err != nil || desc != "Customer orders" {
+				t.Errorf("GenerateDescription() = %q, %v, want the fallback's description", desc, err)
+			}
+			descs, err := client.GenerateColumnDescriptions(ctx, "orders", []string{"id"}, "context")
+			if err != nil || !reflect.DeepEqual(descs, map[string]string{"id": "Customer orders"}) {
+				t.Errorf("GenerateColumnDescriptions() = %v, %v, want the fallback's descriptions", descs, err)
+			}
+			examples, synthesized, err := client.GenerateSyntheticExamples(ctx, "email", "users", "text", []string{"a@b.c"}, true)
+			if err != nil || !synthesized || !reflect.DeepEqual(examples, []string{"fake@example.com"}) {
+				t.Errorf("GenerateSyntheticExamples() = %v, %v, %v, want the fallback's examples", examples, synthesized, err)
+			}
+			if primary.calls != 3 || fallback.calls != 3 {
+				t.Errorf("calls: primary %d, fallback %d, want 3 each", primary.calls, fallback.calls)
+			}
+		})
+	}
+}
+
+func TestFallbackClientKeepsPrimaryResults(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		primary := &fakeModelClient{description: "From primary"}
+		fallback := &fakeModelClient{description: "From fallback"}
+		client := NewFallbackClient(primary, "gemini-pro", fallback, "gemini-flash")
+
+		desc, err := client.GenerateDescription(context.Background(), "column", "total", "orders", "context")
+		if err != nil || desc != "From primary" || fallback.calls != 0 {
+			t.Errorf("GenerateDescription() = %q, %v with %d fallback calls, want the primary's description", desc, err, fallback.calls)
+		}
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		primary := &fakeModelClient{err: errors.New("invalid argument")}
+		fallback := &fakeModelClient{description: "From fallback"}
+		client := NewFallbackClient(primary, "gemini-pro", fallback, "gemini-flash")
+
+		if _, err := client.GenerateDescription(context.Background(), "column", "total", "orders", "context"); err == nil || fallback.calls != 0 {
+			t.Errorf("GenerateDescription() error = %v with %d fallback calls, want the primary's error", err, fallback.calls)
+		}
+	})
+}
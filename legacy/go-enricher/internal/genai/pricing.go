@@ -0,0 +1,34 @@
+package genai
+
+import "strings"
+
+// modelPrice is the list price of a model in US dollars per million tokens.
+type modelPrice struct {
+	input  float64
+	output float64
+}
+
+// modelPrices holds list prices for the Gemini models the tool is commonly run with,
+// keyed by model name prefix. They only feed rough estimates and may be out of date.
+var modelPrices = []struct {
+	prefix string
+	price  modelPrice
+}{
+	{"gemini-1.5-flash-8b", modelPrice{input: 0.0375, output: 0.15}},
+	{"gemini-1.5-flash", modelPrice{input: 0.075, output: 0.30}},
+	{"gemini-1.5-pro", modelPrice{input: 1.25, output: 5.00}},
+	{"gemini-2.0-flash-lite", modelPrice{input: 0.075, output: 0.30}},
+	{"gemini-2.0-flash", modelPrice{input: 0.10, output: 0.40}},
+}
+
+// EstimateCost returns the approximate price in US dollars of sending inputTokens and
+// receiving outputTokens with model. ok is false if the model's price is not known.
+func EstimateCost(model string, inputTokens, outputTokens int64) (cost float64, ok bool) {
+	model = strings.TrimPrefix(strings.ToLower(model), "models/")
+	for _, m := range modelPrices {
+		if strings.HasPrefix(model, m.prefix) {
+			return (float64(inputTokens)*m.price.input + float64(outputTokens)*m.price.output) / 1e6, true
+		}
+	}
+	return 0, false
+}
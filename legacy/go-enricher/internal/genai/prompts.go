@@ -0,0 +1,82 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// promptPrinter is an LLMClient that writes each fully rendered prompt before the call
+// is passed on. Prompts are only written for calls the Gemini client would send, so the
+// output matches what the API would receive.
+type promptPrinter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	next     LLMClient // nil means no API calls are made.
+	language string    // Config.DescriptionLanguage of the client the prompts are for.
+}
+
+// NewPromptPrinter returns an LLMClient that writes every prompt to w and then passes
+// the call to next. With a nil next the API is never called: descriptions come back
+// empty and example values are kept as they are. language is the description language
+// next was configured with, so the printed prompts match the ones it sends.
+func NewPromptPrinter(w io.Writer, next LLMClient, language string) LLMClient {
+	return &promptPrinter{w: w, next: next, language: language}
+}
+
+func (p *promptPrinter) print(kind, target, prompt string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "===== %s prompt for %s =====\n%s\n\n", kind, target, strings.TrimSpace(prompt))
+}
+
+func (p *promptPrinter) GenerateDescription(ctx context.Context, objectType, objectName, parentName, knowledgeContext string) (string, error) {
+	if knowledgeContext != "" {
+		prompt, target, err := buildDescriptionPrompt(objectType, objectName, parentName, knowledgeContext, p.language)
+		if err != nil {
+			return "", err
+		}
+		p.print("description", target, prompt)
+	}
+	if p.next == nil {
+		return "", nil
+	}
+	return p.next.GenerateDescription(ctx, objectType, objectName, parentName, knowledgeContext)
+}
+
+func (p *promptPrinter) GenerateColumnDescriptions(ctx context.Context, tableName string, columnNames []string, knowledgeContext string) (map[string]string, error) {
+	if knowledgeContext != "" && len(columnNames) > 0 {
+		p.print("column descriptions", "Table: "+tableName, buildColumnDescriptionsPrompt(tableName, columnNames, knowledgeContext, p.language))
+	}
+	if p.next == nil {
+		return map[string]string{}, nil
+	}
+	return p.next.GenerateColumnDescriptions(ctx, tableName, columnNames, knowledgeContext)
+}
+
+func (p *promptPrinter) GenerateSyntheticExamples(ctx context.Context, columnName, tableName, dataType string, originalExamples []string, maskPII bool) ([]string, bool, error) {
+	if maskPII && len(originalExamples) > 0 {
+		target := fmt.Sprintf("Column Name: %s in Table: %s", columnName, tableName)
+		p.print("PII", target, buildSyntheticExamplesPrompt(columnName, tableName, dataType, originalExamples))
+	}
+	if p.next == nil {
+		return originalExamples, false, nil
+	}
+	return p.next.GenerateSyntheticExamples(ctx, columnName, tableName, dataType, originalExamples, maskPII)
+}
+
+func (p *promptPrinter) CheckAccess(ctx context.Context) error {
+	if p.next == nil {
+		return nil
+	}
+	return p.next.CheckAccess(ctx)
+}
+
+func (p *promptPrinter) Close() error {
+	if p.next == nil {
+		return nil
+	}
+	return p.next.Close()
+}
@@ -0,0 +1,83 @@
+package genai
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPromptPrinterWithoutClient(t *testing.T) {
+	var buf bytes.Buffer
+	client := NewPromptPrinter(&buf, nil, "")
+	ctx := context.Background()
+
+	desc, err := client.GenerateDescription(ctx, "table", "orders", "", "Orders placed in the web shop.")
+	if err != nil || desc != "" {
+		t.Errorf("GenerateDescription() = %q, %v, want empty description", desc, err)
+	}
+	if _, err := client.GenerateDescription(ctx, "column", "total", "orders", "Totals are in cents."); err != nil {
+		t.Fatalf("GenerateDescription() unexpected error: %v", err)
+	}
+	descs, err := client.GenerateColumnDescriptions(ctx, "orders", []string{"id", "total"}, "Totals are in cents.")
+	if err != nil || len(descs) != 0 {
+		t.Errorf("GenerateColumnDescriptions() = %v, %v, want no descriptions", descs, err)
+	}
+	examples, synthesized, err := client.GenerateSyntheticExamples(ctx, "email", "users", "text", []string{"a@example.com"}, true)
+	if err != nil || synthesized || !reflect.DeepEqual(examples, []string{"a@example.com"}) {
+		t.Errorf("GenerateSyntheticExamples() = %v, %v, %v, want the original examples", examples, synthesized, err)
+	}
+
+	// Calls the Gemini client would not send print nothing.
+	client.GenerateDescription(ctx, "column", "note", "orders", "")
+	client.GenerateSyntheticExamples(ctx, "email", "users", "text", []string{"a@example.com"}, false)
+	if _, err := client.GenerateDescription(ctx, "view", "v", "", "context"); err == nil {
+		t.Error("GenerateDescription() with an unsupported object type expected an error")
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"===== description prompt for Table: orders =====",
+		"Orders placed in the web shop.",
+		"===== description prompt for Column Name: total in Table: orders =====",
+		"===== column descriptions prompt for Table: orders =====",
+		"Target Columns: id, total",
+		"===== PII prompt for Column Name: email in Table: users =====",
+		"Original Example Values: [a@example.com]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printed prompts do not contain %q:\n%s", want, out)
+		}
+	}
+	if got := strings.Count(out, "====="); got != 8 {
+		t.Errorf("printed %d prompt headers, want 4:\n%s", got/2, out)
+	}
+}
+
+type stubLLMClient struct {
+	LLMClient
+	calls int
+}
+
+func (s *stubLLMClient) GenerateDescription(ctx context.Context, objectType, objectName, parentName, knowledgeContext string) (string, error) {
+	s.calls++
+	return "Customer orders", nil
+}
+
+func TestPromptPrinterPassesCallsOn(t *testing.T) {
+	var buf bytes.Buffer
+	next := &stubLLMClient{}
+	client := NewPromptPrinter(&buf, next, "")
+
+	desc, err := client.GenerateDescription(context.Background(), "table", "orders", "", "Orders placed in the web shop.")
+	if err != nil || desc != "Customer orders" {
+		t.Errorf("GenerateDescription() = %q, %v, want the wrapped client's description", desc, err)
+	}
+	if next.calls != 1 {
+		t.Errorf("wrapped client called %d times, want 1", next.calls)
+	}
+	if !strings.Contains(buf.String(), "Table: orders") {
+		t.Errorf("prompt was not printed:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,59 @@
+package genai
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitedClient is an LLMClient that spaces out the calls it passes on, however
+// many goroutines share it. Calls the Gemini client answers without a request, such as
+// descriptions without knowledge context, do not wait for a turn.
+type rateLimitedClient struct {
+	next    LLMClient
+	limiter *rate.Limiter
+}
+
+// NewRateLimitedClient returns an LLMClient that sends at most requestsPerSecond calls
+// per second to next, with no bursts. Calls wait for their turn or until ctx is done.
+func NewRateLimitedClient(next LLMClient, requestsPerSecond float64) LLMClient {
+	return &rateLimitedClient{next: next, limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), 1)}
+}
+
+func (r *rateLimitedClient) GenerateDescription(ctx context.Context, objectType, objectName, parentName, knowledgeContext string) (string, error) {
+	if knowledgeContext == "" {
+		return r.next.GenerateDescription(ctx, objectType, objectName, parentName, knowledgeContext)
+	}
+	if err := r.limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+	return r.next.GenerateDescription(ctx, objectType, objectName, parentName, knowledgeContext)
+}
+
+func (r *rateLimitedClient) GenerateColumnDescriptions(ctx context.Context, tableName string, columnNames []string, knowledgeContext string) (map[string]string, error) {
+	if knowledgeContext == "" || len(columnNames) == 0 {
+		return r.next.GenerateColumnDescriptions(ctx, tableName, columnNames, knowledgeContext)
+	}
+	if err := r.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.GenerateColumnDescriptions(ctx, tableName, columnNames, knowledgeContext)
+}
+
+func (r *rateLimitedClient) GenerateSyntheticExamples(ctx context.Context, columnName, tableName, dataType string, originalExamples []string, maskPII bool) ([]string, bool, error) {
+	if !maskPII || len(originalExamples) == 0 {
+		return r.next.GenerateSyntheticExamples(ctx, columnName, tableName, dataType, originalExamples, maskPII)
+	}
+	if err := r.limiter.Wait(ctx); err != nil {
+		return originalExamples, false, err
+	}
+	return r.next.GenerateSyntheticExamples(ctx, columnName, tableName, dataType, originalExamples, maskPII)
+}
+
+func (r *rateLimitedClient) CheckAccess(ctx context.Context) error {
+	return r.next.CheckAccess(ctx)
+}
+
+func (r *rateLimitedClient) Close() error {
+	return r.next.Close()
+}
@@ -0,0 +1,79 @@
+package genai
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// timingClient records when each call reaches it.
+type timingClient struct {
+	LLMClient
+	mu    sync.Mutex
+	calls []time.Time
+}
+
+func (c *timingClient) GenerateDescription(ctx context.Context, objectType, objectName, parentName, knowledgeContext string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, time.Now())
+	return "", nil
+}
+
+func TestRateLimitedClientSpacesConcurrentCalls(t *testing.T) {
+	const rps = 50
+	const numCalls = 6
+	next := &timingClient{}
+	client := NewRateLimitedClient(next, rps)
+
+	var wg sync.WaitGroup
+	for i := 0; i < numCalls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.GenerateDescription(context.Background(), "table", "orders", "", "context")
+		}()
+	}
+	wg.Wait()
+
+	if len(next.calls) != numCalls {
+		t.Fatalf("got %d calls, want %d", len(next.calls), numCalls)
+	}
+	sort.Slice(next.calls, func(i, j int) bool { return next.calls[i].Before(next.calls[j]) })
+	interval := time.Second / rps
+	// Allow some scheduling slack on each gap, but not a second call in the same slot.
+	for i := 1; i < numCalls; i++ {
+		if gap := next.calls[i].Sub(next.calls[i-1]); gap < interval*3/4 {
+			t.Errorf("calls %d and %d were %s apart, want about %s", i-1, i, gap, interval)
+		}
+	}
+}
+
+func TestRateLimitedClientSkipsCallsWithoutRequests(t *testing.T) {
+	next := &timingClient{}
+	client := NewRateLimitedClient(next, 1)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		client.GenerateDescription(context.Background(), "column", "note", "orders", "")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("calls without knowledge context took %s, want no waiting", elapsed)
+	}
+}
+
+func TestRateLimitedClientHonoursContext(t *testing.T) {
+	client := NewRateLimitedClient(&timingClient{}, 0.1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The first call takes the only token; the next would wait ten seconds.
+	if _, err := client.GenerateDescription(ctx, "table", "orders", "", "context"); err != nil {
+		t.Fatalf("first call unexpected error: %v", err)
+	}
+	if _, err := client.GenerateDescription(ctx, "table", "orders", "", "context"); err == nil {
+		t.Error("second call expected an error once the context deadline cannot be met")
+	}
+}
@@ -0,0 +1,124 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+
+	vertexai "cloud.google.com/go/vertexai/genai"
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// vertexBackend reaches Gemini models through Vertex AI, authenticating with
+// application default credentials.
+type vertexBackend struct {
+	client *vertexai.Client
+}
+
+func newVertexBackend(ctx context.Context, project, location string) (*vertexBackend, error) {
+	if project == "" {
+		return nil, fmt.Errorf("cannot create Vertex AI client: project is missing")
+	}
+	if location == "" {
+		return nil, fmt.Errorf("cannot create Vertex AI client: location is missing")
+	}
+	client, err := vertexai.NewClient(ctx, project, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vertex AI client: %w", err)
+	}
+	return &vertexBackend{client: client}, nil
+}
+
+func (b *vertexBackend) generateContent(ctx context.Context, model string, settings generationSettings, schema *genai.Schema, prompt string) (response, error) {
+	m := b.client.GenerativeModel(model)
+	m.SetTemperature(settings.temperature)
+	m.SetMaxOutputTokens(settings.maxOutputTokens)
+	m.SetTopP(settings.topP)
+	m.SetTopK(settings.topK)
+	if schema != nil {
+		m.ResponseMIMEType = "application/json"
+		m.ResponseSchema = toVertexSchema(schema)
+	}
+	resp, err := m.GenerateContent(ctx, vertexai.Text(prompt))
+	if err != nil {
+		return nil, err
+	}
+	return vertexResponse{resp}, nil
+}
+
+// checkAccess checks that the application default credentials can use model by
+// counting the tokens of a short text, which is not billed. Vertex AI has no call
+// listing the Gemini models.
+func (b *vertexBackend) checkAccess(ctx context.Context, model string) error {
+	_, err := b.client.GenerativeModel(model).CountTokens(ctx, vertexai.Text("ping"))
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			switch st.Code() {
+			case codes.Unauthenticated, codes.PermissionDenied:
+				return fmt.Errorf("invalid application default credentials or insufficient Vertex AI permissions: %w", err)
+			case codes.NotFound:
+				return fmt.Errorf("model %s not found in the Vertex AI project and location: %w", model, err)
+			}
+		}
+		if IsRateLimitError(err) {
+			return fmt.Errorf("failed to verify Vertex AI access due to rate limiting: %w", err)
+		}
+		return fmt.Errorf("failed to verify Vertex AI access by counting tokens: %w", err)
+	}
+	return nil
+}
+
+func (b *vertexBackend) close() error {
+	return b.client.Close()
+}
+
+// vertexResponse is a response of Vertex AI.
+type vertexResponse struct {
+	resp *vertexai.GenerateContentResponse
+}
+
+func (r vertexResponse) firstText() (string, error) {
+	resp := r.resp
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		finishReason := "unknown"
+		safetyRatings := "none"
+		if resp != nil && len(resp.Candidates) > 0 {
+			finishReason = resp.Candidates[0].FinishReason.String()
+			if resp.Candidates[0].SafetyRatings != nil {
+				safetyRatings = fmt.Sprintf("%v", resp.Candidates[0].SafetyRatings)
+			}
+		}
+		return "", fmt.Errorf("empty or incomplete response from Vertex AI. FinishReason: %s, SafetyRatings: %s", finishReason, safetyRatings)
+	}
+	part := resp.Candidates[0].Content.Parts[0]
+	text, ok := part.(vertexai.Text)
+	if !ok {
+		return "", fmt.Errorf("unexpected response part type: %T", part)
+	}
+	return string(text), nil
+}
+
+// toVertexSchema converts a response schema to the Vertex AI SDK's type. Both SDKs
+// number the types as the API does, so they convert directly.
+func toVertexSchema(schema *genai.Schema) *vertexai.Schema {
+	if schema == nil {
+		return nil
+	}
+	converted := &vertexai.Schema{
+		Type:        vertexai.Type(schema.Type),
+		Format:      schema.Format,
+		Description: schema.Description,
+		Nullable:    schema.Nullable,
+		Enum:        schema.Enum,
+		Items:       toVertexSchema(schema.Items),
+		Required:    schema.Required,
+	}
+	if len(schema.Properties) > 0 {
+		converted.Properties = make(map[string]*vertexai.Schema, len(schema.Properties))
+		for name, property := range schema.Properties {
+			converted.Properties[name] = toVertexSchema(property)
+		}
+	}
+	return converted
+}
@@ -0,0 +1,25 @@
+package genai
+
+import (
+	"reflect"
+	"testing"
+
+	vertexai "cloud.google.com/go/vertexai/genai"
+)
+
+func TestToVertexSchema(t *testing.T) {
+	want := &vertexai.Schema{
+		Type: vertexai.TypeObject,
+		Properties: map[string]*vertexai.Schema{
+			"is_pii":             {Type: vertexai.TypeBoolean, Description: "Whether the column is likely to contain PII."},
+			"synthetic_examples": {Type: vertexai.TypeArray, Items: &vertexai.Schema{Type: vertexai.TypeString}, Description: "Fake example values, only when is_pii is true."},
+		},
+		Required: []string{"is_pii"},
+	}
+	if got := toVertexSchema(syntheticExamplesSchema); !reflect.DeepEqual(got, want) {
+		t.Errorf("toVertexSchema() = %+v, want %+v", got, want)
+	}
+	if got := toVertexSchema(nil); got != nil {
+		t.Errorf("toVertexSchema(nil) = %+v, want nil", got)
+	}
+}
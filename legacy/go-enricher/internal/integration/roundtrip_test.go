@@ -0,0 +1,214 @@
+//go:build integration
+
+// Package integration runs the enricher against real databases started in throwaway
+// containers with testcontainers-go. The tests are built only with the integration
+// tag and need Docker:
+//
+//	make integration-test
+//
+// or go test -tags integration ./internal/integration/. They are skipped when no
+// Docker daemon is reachable.
+package integration
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/config"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/database"
+	_ "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/mysql"
+	_ "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/postgres"
+	_ "github.com/GoogleCloudPlatform/db-context-enrichment/internal/database/sqlserver"
+	"github.com/GoogleCloudPlatform/db-context-enrichment/internal/enricher"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mssql"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+const table = "enricher_it_orders"
+
+// dialectSetup starts a dialect's container and creates the test table with two
+// rows and a user-written comment on its status column.
+type dialectSetup struct {
+	dialect  string
+	port     string
+	user     string
+	password string
+	database string
+	start    func(ctx context.Context) (testcontainers.Container, error)
+	setup    []string
+}
+
+var dialects = []dialectSetup{
+	{
+		dialect: "postgres", port: "5432/tcp", user: "postgres", password: "enricher-it", database: "enricher",
+		start: func(ctx context.Context) (testcontainers.Container, error) {
+			return postgres.Run(ctx, "postgres:16",
+				postgres.WithDatabase("enricher"),
+				postgres.WithUsername("postgres"),
+				postgres.WithPassword("enricher-it"),
+				postgres.BasicWaitStrategies(),
+			)
+		},
+		setup: []string{
+			`DROP TABLE IF EXISTS enricher_it_orders`,
+			`CREATE TABLE enricher_it_orders (id INT PRIMARY KEY, status VARCHAR(20))`,
+			`INSERT INTO enricher_it_orders VALUES (1, 'paid'), (2, 'shipped')`,
+			`COMMENT ON COLUMN enricher_it_orders.status IS 'Order state'`,
+		},
+	},
+	{
+		dialect: "mysql", port: "3306/tcp", user: "root", password: "enricher-it", database: "enricher",
+		start: func(ctx context.Context) (testcontainers.Container, error) {
+			return mysql.Run(ctx, "mysql:8.4",
+				mysql.WithDatabase("enricher"),
+				mysql.WithUsername("root"),
+				mysql.WithPassword("enricher-it"),
+			)
+		},
+		setup: []string{
+			"DROP TABLE IF EXISTS enricher_it_orders",
+			"CREATE TABLE enricher_it_orders (id INT PRIMARY KEY, status VARCHAR(20) COMMENT 'Order state')",
+			"INSERT INTO enricher_it_orders VALUES (1, 'paid'), (2, 'shipped')",
+		},
+	},
+	{
+		dialect: "sqlserver", port: "1433/tcp", user: "sa", password: "Enricher-it-1", database: "master",
+		start: func(ctx context.Context) (testcontainers.Container, error) {
+			return mssql.Run(ctx, "mcr.microsoft.com/mssql/server:2022-latest",
+				mssql.WithAcceptEULA(),
+				mssql.WithPassword("Enricher-it-1"),
+			)
+		},
+		setup: []string{
+			"DROP TABLE IF EXISTS dbo.enricher_it_orders",
+			"CREATE TABLE dbo.enricher_it_orders (id INT PRIMARY KEY, status NVARCHAR(20))",
+			"INSERT INTO dbo.enricher_it_orders VALUES (1, N'paid'), (2, N'shipped')",
+			"EXEC sp_addextendedproperty @name=N'MS_Description', @value=N'Order state', @level0type=N'SCHEMA', @level0name=N'dbo', @level1type=N'TABLE', @level1name=N'enricher_it_orders', @level2type=N'COLUMN', @level2name=N'status'",
+		},
+	},
+}
+
+// connect starts the dialect's container and opens its database. The container is
+// removed when the test finishes.
+func connect(t *testing.T, d dialectSetup) *database.DB {
+	t.Helper()
+	ctx := context.Background()
+	ctr, err := d.start(ctx)
+	testcontainers.CleanupContainer(t, ctr)
+	if err != nil {
+		t.Fatalf("starting the %s container failed: %v", d.dialect, err)
+	}
+	host, err := ctr.Host(ctx)
+	if err != nil {
+		t.Fatalf("container host: %v", err)
+	}
+	mapped, err := ctr.MappedPort(ctx, d.port)
+	if err != nil {
+		t.Fatalf("container port %s: %v", d.port, err)
+	}
+	port, err := strconv.Atoi(mapped.Port())
+	if err != nil {
+		t.Fatalf("invalid container port %q: %v", mapped.Port(), err)
+	}
+	cfg := config.DatabaseConfig{
+		Dialect:            d.dialect,
+		Host:               host,
+		Port:               port,
+		User:               d.user,
+		Password:           d.password,
+		DBName:             d.database,
+		UpdateExistingMode: "append",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid configuration: %v", err)
+	}
+	db, err := database.New(cfg)
+	if err != nil {
+		t.Fatalf("database.New() error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// columnComments reads the table's comments the way get-comments does.
+func columnComments(t *testing.T, svc *enricher.Service, filters map[string][]string) map[string]string {
+	t.Helper()
+	comments, err := svc.GetComments(context.Background(), enricher.GetCommentsParams{TableFilters: filters})
+	if err != nil {
+		t.Fatalf("GetComments() error: %v", err)
+	}
+	byColumn := make(map[string]string)
+	for _, c := range comments {
+		byColumn[c.Column] = strings.TrimSpace(c.Comment)
+	}
+	return byColumn
+}
+
+// TestCommentRoundTrip runs add-comments, get-comments and delete-comments against each
+// dialect and checks that the <gemini> block is added next to the user's comment and
+// removed again without touching it.
+func TestCommentRoundTrip(t *testing.T) {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	for _, d := range dialects {
+		t.Run(d.dialect, func(t *testing.T) {
+			ctx := context.Background()
+			db := connect(t, d)
+			for _, stmt := range d.setup {
+				if _, err := db.Pool.ExecContext(ctx, stmt); err != nil {
+					t.Fatalf("setup %q failed: %v", stmt, err)
+				}
+			}
+
+			svc := enricher.NewService(db, nil, enricher.Config{})
+			filters := map[string][]string{table: {}}
+
+			// add-comments
+			sqls, err := svc.GenerateCommentSQLs(ctx, enricher.GenerateSQLParams{
+				TableFilters: filters,
+				Enrichments:  map[string]bool{"examples": true, "distinct_values": true, "null_count": true},
+			})
+			if err != nil {
+				t.Fatalf("GenerateCommentSQLs() error: %v", err)
+			}
+			if err := db.ExecuteSQLStatements(ctx, sqls); err != nil {
+				t.Fatalf("applying the add-comments SQL failed: %v\n%s", err, strings.Join(sqls, "\n"))
+			}
+
+			// get-comments
+			added := columnComments(t, svc, filters)
+			status := added["status"]
+			if !strings.HasPrefix(status, "Order state") {
+				t.Errorf("status comment = %q, want the user's text kept in front", status)
+			}
+			_, metadata, ok := database.SplitComment(status)
+			if !ok || !strings.Contains(metadata, "Distinct: 2") || !strings.Contains(metadata, "paid") {
+				t.Errorf("status comment = %q, want a <gemini> block with its statistics", status)
+			}
+			if _, metadata, _ := database.SplitComment(added["id"]); !strings.Contains(metadata, "Distinct: 2") {
+				t.Errorf("id comment = %q, want a <gemini> block with its statistics", added["id"])
+			}
+
+			// delete-comments
+			sqls, err = svc.GenerateDeleteCommentSQLs(ctx, enricher.GenerateDeleteSQLParams{TableFilters: filters})
+			if err != nil {
+				t.Fatalf("GenerateDeleteCommentSQLs() error: %v", err)
+			}
+			if err := db.ExecuteSQLStatements(ctx, sqls); err != nil {
+				t.Fatalf("applying the delete-comments SQL failed: %v\n%s", err, strings.Join(sqls, "\n"))
+			}
+
+			deleted := columnComments(t, svc, filters)
+			if deleted["status"] != "Order state" {
+				t.Errorf("status comment after delete = %q, want %q", deleted["status"], "Order state")
+			}
+			if comment, ok := deleted["id"]; ok {
+				t.Errorf("id comment after delete = %q, want none", comment)
+			}
+		})
+	}
+}
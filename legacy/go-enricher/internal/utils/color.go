@@ -0,0 +1,59 @@
+/*
+ * Copyright 2025 Google LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package utils
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// ANSI escape sequences used for terminal output.
+const (
+	ColorReset  = "\x1b[0m"
+	ColorBold   = "\x1b[1m"
+	ColorRed    = "\x1b[31m"
+	ColorGreen  = "\x1b[32m"
+	ColorYellow = "\x1b[33m"
+	ColorCyan   = "\x1b[36m"
+)
+
+var colorEnabled atomic.Bool
+
+func init() {
+	colorEnabled.Store(os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout))
+}
+
+// isTerminal reports whether f is attached to a character device such as a TTY.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// SetColorEnabled overrides terminal detection, e.g. for --no-color.
+func SetColorEnabled(enabled bool) {
+	colorEnabled.Store(enabled)
+}
+
+// Colorize wraps text in the given ANSI color when color output is enabled.
+func Colorize(color, text string) string {
+	if !colorEnabled.Load() || text == "" {
+		return text
+	}
+	return color + text + ColorReset
+}
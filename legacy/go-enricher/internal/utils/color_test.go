@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConfirmActionNoColor(t *testing.T) {
+	SetColorEnabled(false)
+	defer SetColorEnabled(false)
+
+	var out bytes.Buffer
+	if !confirmAction(strings.NewReader("yes\n"), &out, "apply 2 statements") {
+		t.Errorf("confirmAction() = false, want true for 'yes'")
+	}
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Errorf("output contains ANSI escape codes with color disabled: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "apply 2 statements") {
+		t.Errorf("output missing action description: %q", out.String())
+	}
+}
+
+func TestConfirmActionColor(t *testing.T) {
+	SetColorEnabled(true)
+	defer SetColorEnabled(false)
+
+	var out bytes.Buffer
+	if confirmAction(strings.NewReader("no\n"), &out, "apply 2 statements") {
+		t.Errorf("confirmAction() = true, want false for 'no'")
+	}
+	if !strings.Contains(out.String(), ColorBold+"apply 2 statements"+ColorReset) {
+		t.Errorf("output missing colored action description: %q", out.String())
+	}
+}
+
+func TestColorizeDisabled(t *testing.T) {
+	SetColorEnabled(false)
+	if got := Colorize(ColorRed, "error"); got != "error" {
+		t.Errorf("Colorize() with color disabled = %q, want %q", got, "error")
+	}
+}
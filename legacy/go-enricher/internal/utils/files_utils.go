@@ -17,13 +17,41 @@ package utils
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"unicode"
 )
 
+// StdioPath is the file path that stands for standard input or output, as in
+// --in_file - or --out_file -.
+const StdioPath = "-"
+
+// ReadInputFile reads the file at path, or standard input if path is StdioPath, so
+// that generated SQL can be piped between commands.
+func ReadInputFile(path string) ([]byte, error) {
+	return readInputFile(path, os.Stdin)
+}
+
+func readInputFile(path string, stdin io.Reader) ([]byte, error) {
+	if path != StdioPath {
+		return os.ReadFile(path)
+	}
+	content, err := io.ReadAll(stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read standard input: %w", err)
+	}
+	return content, nil
+}
+
 func ReadSQLStatementsFromFile(filePath string) ([]string, error) {
-	content, err := os.ReadFile(filePath)
+	content, err := ReadInputFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
@@ -40,7 +68,8 @@ func ReadSQLStatementsFromFile(filePath string) ([]string, error) {
 }
 
 // ReadContextFiles reads the content of the specified context files and combines them into a single string.
-func ReadContextFiles(filePaths string) (string, error) {
+// With sanitize, likely secrets are redacted from each file (see SanitizeContext).
+func ReadContextFiles(filePaths string, sanitize bool) (string, error) {
 	if filePaths == "" {
 		return "", nil // No context files provided
 	}
@@ -54,11 +83,106 @@ func ReadContextFiles(filePaths string) (string, error) {
 			return "", fmt.Errorf("failed to read context file '%s': %w", path, err)
 		}
 		combinedContext.WriteString("\n-- Context from file: " + path + " --\n")
-		combinedContext.WriteString(string(content))
+		combinedContext.WriteString(sanitizeContextFile(path, string(content), sanitize))
 	}
 	return combinedContext.String(), nil
 }
 
+// ScopedContextFilePath returns the --context-dir file holding context for a table
+// (<dir>/<table>.md) or, when column is set, for one of its columns (<dir>/<table>.<column>.md).
+func ScopedContextFilePath(dir, table, column string) string {
+	name := table
+	if column != "" {
+		name += "." + column
+	}
+	return filepath.Join(dir, name+".md")
+}
+
+// ReadScopedContextFile reads the --context-dir file for a table or column, formatted
+// like ReadContextFiles. A missing file or an empty dir yields an empty context.
+func ReadScopedContextFile(dir, table, column string, sanitize bool) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	path := ScopedContextFilePath(dir, table, column)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read context file '%s': %w", path, err)
+	}
+	return "\n-- Context from file: " + path + " --\n" + sanitizeContextFile(path, string(content), sanitize), nil
+}
+
+// sanitizeContextFile redacts likely secrets from a context file's content when
+// sanitize is set, logging how many were found.
+func sanitizeContextFile(path, content string, sanitize bool) string {
+	if !sanitize {
+		return content
+	}
+	content, redacted := SanitizeContext(content)
+	if redacted > 0 {
+		log.Printf("WARN: Redacted %d likely secret(s) from context file '%s' (--sanitize-context).", redacted, path)
+	}
+	return content
+}
+
+// ReadGlossaryFile reads a business glossary mapping terms to definitions, either as
+// a JSON object or as "term: definition" lines where blank lines and lines starting
+// with # are ignored. Terms are keyed by NormalizeGlossaryTerm.
+func ReadGlossaryFile(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glossary file '%s': %w", path, err)
+	}
+
+	entries := make(map[string]string)
+	if trimmed := strings.TrimSpace(string(content)); strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal([]byte(trimmed), &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse glossary file '%s' as JSON: %w", path, err)
+		}
+	} else {
+		for i, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			term, definition, ok := strings.Cut(line, ":")
+			if !ok || strings.TrimSpace(term) == "" {
+				return nil, fmt.Errorf("invalid glossary entry on line %d of '%s': expected 'term: definition'", i+1, path)
+			}
+			entries[term] = definition
+		}
+	}
+
+	glossary := make(map[string]string, len(entries))
+	for term, definition := range entries {
+		key := NormalizeGlossaryTerm(term)
+		definition = strings.TrimSpace(definition)
+		if key == "" || definition == "" {
+			continue
+		}
+		glossary[key] = definition
+	}
+	return glossary, nil
+}
+
+// NormalizeGlossaryTerm reduces a term or column name to lower-case letters and
+// digits, so "created_at", "CreatedAt" and "Created At" all match.
+func NormalizeGlossaryTerm(term string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(term) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
 func GetDefaultOutputFilePath(dbName, commandName string) string {
 	switch commandName {
 	case "get-comments":
@@ -69,19 +193,37 @@ func GetDefaultOutputFilePath(dbName, commandName string) string {
 }
 
 func ConfirmAction(actionDescription string) bool {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("\n-------------------------------------------------------------\n")
-	fmt.Printf("Generated %s:\n", actionDescription)
-	fmt.Print("Do you want to apply these changes to the database? (yes/no): ")
+	return confirmAction(os.Stdin, os.Stdout, actionDescription)
+}
+
+func confirmAction(in io.Reader, out io.Writer, actionDescription string) bool {
+	reader := bufio.NewReader(in)
+	fmt.Fprintf(out, "\n%s\n", Colorize(ColorCyan, "-------------------------------------------------------------"))
+	fmt.Fprintf(out, "Generated %s:\n", Colorize(ColorBold, actionDescription))
+	fmt.Fprint(out, Colorize(ColorYellow, "Do you want to apply these changes to the database? (yes/no): "))
 	text, _ := reader.ReadString('\n')
 	action := strings.TrimSpace(strings.ToLower(text))
 	return action == "yes" || action == "y"
 }
 
+// ParseTablesFlag parses --tables into the columns to include per table, where nil
+// means all columns. Table names may be schema-qualified (schema.table) and are
+// kept as given. Per-column enrichment hints are dropped; see
+// ParseTablesFlagWithEnrichments.
 func ParseTablesFlag(tablesFlag string) (map[string][]string, error) {
+	tableColumns, _, err := ParseTablesFlagWithEnrichments(tablesFlag)
+	return tableColumns, err
+}
+
+// ParseTablesFlagWithEnrichments parses --tables like ParseTablesFlag and also returns
+// the per-column enrichment hints, keyed by table entry and column. Columns are
+// separated by ',' or ';' and may name their enrichments after a ':', joined with '+',
+// e.g. "orders[amount:examples+null_count;status:description]".
+func ParseTablesFlagWithEnrichments(tablesFlag string) (map[string][]string, map[string]map[string][]string, error) {
 	tableColumns := make(map[string][]string)
+	columnEnrichments := make(map[string]map[string][]string)
 	if tablesFlag == "" {
-		return tableColumns, nil
+		return tableColumns, columnEnrichments, nil
 	}
 
 	// strip any whitespace
@@ -98,26 +240,75 @@ func ParseTablesFlag(tablesFlag string) (map[string][]string, error) {
 		if bracketStart != -1 {
 			bracketEnd := strings.Index(part, "]")
 			if bracketEnd == -1 {
-				return nil, fmt.Errorf("missing closing bracket in: %s", part)
+				return nil, nil, fmt.Errorf("missing closing bracket in: %s", part)
 			}
 
 			tableName := strings.TrimSpace(part[:bracketStart])
+			if err := validateQualifiedTable(tableName); err != nil {
+				return nil, nil, err
+			}
 			columnsStr := strings.TrimSpace(part[bracketStart+1 : bracketEnd])
 
-			// Split columns by comma and trim spaces
-			columns := strings.Split(columnsStr, ",")
+			// Split columns by comma or semicolon and trim spaces
+			columns := strings.Split(strings.ReplaceAll(columnsStr, ";", ","), ",")
 			var trimmedColumns []string
 			for _, col := range columns {
-				trimmedColumns = append(trimmedColumns, strings.TrimSpace(col))
+				col = strings.TrimSpace(col)
+				name, hints, hasHints := strings.Cut(col, ":")
+				if hasHints {
+					enrichments, err := parseEnrichmentHints(name, hints)
+					if err != nil {
+						return nil, nil, err
+					}
+					if columnEnrichments[tableName] == nil {
+						columnEnrichments[tableName] = make(map[string][]string)
+					}
+					columnEnrichments[tableName][name] = enrichments
+				}
+				trimmedColumns = append(trimmedColumns, name)
 			}
 			tableColumns[tableName] = trimmedColumns
 		} else {
 			// No columns specified, just table name
+			if err := validateQualifiedTable(part); err != nil {
+				return nil, nil, err
+			}
 			tableColumns[part] = nil
 		}
 	}
 
-	return tableColumns, nil
+	return tableColumns, columnEnrichments, nil
+}
+
+func parseEnrichmentHints(column, hints string) ([]string, error) {
+	if column == "" {
+		return nil, fmt.Errorf("missing column name before enrichments '%s'", hints)
+	}
+	var enrichments []string
+	for _, hint := range strings.Split(hints, "+") {
+		if hint == "" {
+			return nil, fmt.Errorf("empty enrichment for column '%s': expected 'column:enrichment+enrichment'", column)
+		}
+		enrichments = append(enrichments, strings.ToLower(hint))
+	}
+	return enrichments, nil
+}
+
+// SplitQualifiedTable splits a --tables entry of the form schema.table into its
+// schema and table name. An unqualified name has an empty schema.
+func SplitQualifiedTable(name string) (schema, table string) {
+	if i := strings.Index(name, "."); i != -1 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+func validateQualifiedTable(name string) error {
+	schema, table := SplitQualifiedTable(name)
+	if table == "" || strings.Contains(table, ".") || (schema == "" && strings.Contains(name, ".")) {
+		return fmt.Errorf("invalid table name '%s': expected 'table' or 'schema.table'", name)
+	}
+	return nil
 }
 
 // SplitOutsideBrackets Helper function to split string by commas that are not within brackets
@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScopedContextFilePath(t *testing.T) {
+	tests := []struct {
+		table, column string
+		expected      string
+	}{
+		{"orders", "", filepath.Join("docs", "orders.md")},
+		{"orders", "total", filepath.Join("docs", "orders.total.md")},
+	}
+	for _, tt := range tests {
+		if got := ScopedContextFilePath("docs", tt.table, tt.column); got != tt.expected {
+			t.Errorf("ScopedContextFilePath(%q, %q) = %q, want %q", tt.table, tt.column, got, tt.expected)
+		}
+	}
+}
+
+func TestReadScopedContextFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "orders.md"), []byte("Customer orders."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "orders.total.md"), []byte("Total in cents."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		dir           string
+		table, column string
+		expected      string
+	}{
+		{"table file", dir, "orders", "", "Customer orders."},
+		{"column file", dir, "orders", "total", "Total in cents."},
+		{"missing column file", dir, "orders", "status", ""},
+		{"missing table file", dir, "customers", "", ""},
+		{"no dir", "", "orders", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadScopedContextFile(tt.dir, tt.table, tt.column, false)
+			if err != nil {
+				t.Fatalf("ReadScopedContextFile() unexpected error: %v", err)
+			}
+			if tt.expected == "" {
+				if got != "" {
+					t.Errorf("ReadScopedContextFile() = %q, want empty", got)
+				}
+				return
+			}
+			if !strings.HasSuffix(got, tt.expected) || !strings.Contains(got, ScopedContextFilePath(tt.dir, tt.table, tt.column)) {
+				t.Errorf("ReadScopedContextFile() = %q, want content %q with file header", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadInputFile(t *testing.T) {
+	sql := "COMMENT ON TABLE \"orders\" IS 'Customer orders';\n"
+
+	got, err := readInputFile(StdioPath, strings.NewReader(sql))
+	if err != nil {
+		t.Fatalf("readInputFile(stdin) unexpected error: %v", err)
+	}
+	if string(got) != sql {
+		t.Errorf("readInputFile(stdin) = %q, want %q", got, sql)
+	}
+
+	path := filepath.Join(t.TempDir(), "comments.sql")
+	if err := os.WriteFile(path, []byte(sql), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = readInputFile(path, strings.NewReader("ignored"))
+	if err != nil {
+		t.Fatalf("readInputFile(file) unexpected error: %v", err)
+	}
+	if string(got) != sql {
+		t.Errorf("readInputFile(file) = %q, want %q", got, sql)
+	}
+}
+
+func TestReadGlossaryFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		expected    map[string]string
+		expectedErr string
+	}{
+		{
+			name: "term lines",
+			path: write("glossary.txt", "# Business glossary\nCreated At: When the record was created, in UTC.\n\nSKU: Stock keeping unit: the product code.\nNotes:\n"),
+			expected: map[string]string{
+				"createdat": "When the record was created, in UTC.",
+				"sku":       "Stock keeping unit: the product code.",
+			},
+		},
+		{
+			name:     "json object",
+			path:     write("glossary.json", `{"customer_id": "Identifier of the customer placing the order."}`),
+			expected: map[string]string{"customerid": "Identifier of the customer placing the order."},
+		},
+		{name: "no file", path: "", expected: nil},
+		{name: "line without term", path: write("bad.txt", "created_at\n"), expectedErr: "line 1"},
+		{name: "missing file", path: filepath.Join(dir, "missing.txt"), expectedErr: "failed to read glossary file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadGlossaryFile(tt.path)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("ReadGlossaryFile() error = %v, want error containing %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadGlossaryFile() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ReadGlossaryFile() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeGlossaryTerm(t *testing.T) {
+	for _, term := range []string{"created_at", "CreatedAt", "Created At", "created-at"} {
+		if got := NormalizeGlossaryTerm(term); got != "createdat" {
+			t.Errorf("NormalizeGlossaryTerm(%q) = %q, want %q", term, got, "createdat")
+		}
+	}
+}
+
+func TestParseTablesFlagSchemaQualified(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		expected    map[string][]string
+		expectedErr string
+	}{
+		{"bare table with columns", "orders[id,total]", map[string][]string{"orders": {"id", "total"}}, ""},
+		{"schema-qualified table with columns", "analytics.orders[id]", map[string][]string{"analytics.orders": {"id"}}, ""},
+		{"mixed", "analytics.orders,users[id]", map[string][]string{"analytics.orders": nil, "users": {"id"}}, ""},
+		{"empty schema", ".orders[id]", nil, "invalid table name '.orders'"},
+		{"too many parts", "db.analytics.orders", nil, "invalid table name 'db.analytics.orders'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTablesFlag(tt.raw)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("ParseTablesFlag(%q) error = %v, want error containing %q", tt.raw, err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTablesFlag(%q) unexpected error: %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseTablesFlag(%q) = %v, want %v", tt.raw, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTablesFlagWithEnrichments(t *testing.T) {
+	tests := []struct {
+		name                string
+		raw                 string
+		expectedColumns     map[string][]string
+		expectedEnrichments map[string]map[string][]string
+		expectedErr         string
+	}{
+		{
+			name:                "no hints",
+			raw:                 "orders[id,total],users",
+			expectedColumns:     map[string][]string{"orders": {"id", "total"}, "users": nil},
+			expectedEnrichments: map[string]map[string][]string{},
+		},
+		{
+			name:            "hints separated by semicolons",
+			raw:             "orders[amount:examples+Null_Count;status:description]",
+			expectedColumns: map[string][]string{"orders": {"amount", "status"}},
+			expectedEnrichments: map[string]map[string][]string{
+				"orders": {"amount": {"examples", "null_count"}, "status": {"description"}},
+			},
+		},
+		{
+			name:            "hinted and plain columns mixed",
+			raw:             "analytics.orders[id, amount:examples],users[email:description]",
+			expectedColumns: map[string][]string{"analytics.orders": {"id", "amount"}, "users": {"email"}},
+			expectedEnrichments: map[string]map[string][]string{
+				"analytics.orders": {"amount": {"examples"}},
+				"users":            {"email": {"description"}},
+			},
+		},
+		{name: "empty enrichment list", raw: "orders[amount:]", expectedErr: "empty enrichment for column 'amount'"},
+		{name: "empty enrichment between", raw: "orders[amount:examples++description]", expectedErr: "empty enrichment for column 'amount'"},
+		{name: "missing column name", raw: "orders[:examples]", expectedErr: "missing column name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, enrichments, err := ParseTablesFlagWithEnrichments(tt.raw)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("ParseTablesFlagWithEnrichments(%q) error = %v, want error containing %q", tt.raw, err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTablesFlagWithEnrichments(%q) unexpected error: %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(columns, tt.expectedColumns) {
+				t.Errorf("ParseTablesFlagWithEnrichments(%q) columns = %v, want %v", tt.raw, columns, tt.expectedColumns)
+			}
+			if !reflect.DeepEqual(enrichments, tt.expectedEnrichments) {
+				t.Errorf("ParseTablesFlagWithEnrichments(%q) enrichments = %v, want %v", tt.raw, enrichments, tt.expectedEnrichments)
+			}
+
+			// ParseTablesFlag returns the same columns without the hints.
+			plain, err := ParseTablesFlag(tt.raw)
+			if err != nil || !reflect.DeepEqual(plain, tt.expectedColumns) {
+				t.Errorf("ParseTablesFlag(%q) = %v, %v, want %v", tt.raw, plain, err, tt.expectedColumns)
+			}
+		})
+	}
+}
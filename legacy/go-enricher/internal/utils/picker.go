@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PickTables lets the user choose the tables and columns to process from stdin, for
+// --interactive. It returns filters in the form of ParseTablesFlag, where a nil
+// column list means all columns of the table.
+func PickTables(tables []string, listColumns func(table string) ([]string, error)) (map[string][]string, error) {
+	return pickTables(os.Stdin, os.Stdout, tables, listColumns)
+}
+
+func pickTables(in io.Reader, out io.Writer, tables []string, listColumns func(table string) ([]string, error)) (map[string][]string, error) {
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no tables to pick from")
+	}
+	reader := bufio.NewReader(in)
+
+	fmt.Fprintf(out, "\n%s\n", Colorize(ColorCyan, "-------------------------------------------------------------"))
+	fmt.Fprintln(out, Colorize(ColorBold, "Tables:"))
+	printChoices(out, tables)
+	selectedTables, err := promptSelection(reader, out, "Select tables by number or name, e.g. '1,3-4' ('all' for every table): ", tables, false)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := make(map[string][]string, len(selectedTables))
+	for _, table := range selectedTables {
+		columns, err := listColumns(table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list columns of '%s': %w", table, err)
+		}
+		if len(columns) == 0 {
+			filters[table] = nil
+			continue
+		}
+		fmt.Fprintf(out, "\n%s\n", Colorize(ColorBold, fmt.Sprintf("Columns of %s:", table)))
+		printChoices(out, columns)
+		selectedColumns, err := promptSelection(reader, out, fmt.Sprintf("Select columns of %s (empty or 'all' for every column): ", table), columns, true)
+		if err != nil {
+			return nil, err
+		}
+		if len(selectedColumns) == len(columns) {
+			selectedColumns = nil
+		}
+		filters[table] = selectedColumns
+	}
+	return filters, nil
+}
+
+func printChoices(out io.Writer, choices []string) {
+	for i, choice := range choices {
+		fmt.Fprintf(out, "  %3d) %s\n", i+1, choice)
+	}
+}
+
+// promptSelection asks until the answer is a valid selection of choices. An empty
+// answer selects every choice if emptyMeansAll is set. Input ending before a valid
+// answer is an error.
+func promptSelection(reader *bufio.Reader, out io.Writer, prompt string, choices []string, emptyMeansAll bool) ([]string, error) {
+	for {
+		fmt.Fprint(out, Colorize(ColorYellow, prompt))
+		text, readErr := reader.ReadString('\n')
+		answer := strings.TrimSpace(text)
+		if answer != "" || emptyMeansAll {
+			selected, err := parseSelection(answer, choices)
+			if err == nil {
+				return selected, nil
+			}
+			fmt.Fprintln(out, Colorize(ColorRed, err.Error()))
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("selection aborted: no valid answer before end of input")
+		}
+	}
+}
+
+// parseSelection resolves a comma- or space-separated list of 1-based numbers, ranges
+// like "2-4" and names into the selected choices, in the order of choices. "all" and
+// an empty answer select every choice.
+func parseSelection(answer string, choices []string) ([]string, error) {
+	if answer == "" || strings.EqualFold(answer, "all") {
+		return choices, nil
+	}
+	index := make(map[string]int, len(choices))
+	for i, choice := range choices {
+		index[choice] = i
+	}
+	picked := make([]bool, len(choices))
+	for _, token := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if i, ok := index[token]; ok {
+			picked[i] = true
+			continue
+		}
+		first, last, isRange := strings.Cut(token, "-")
+		if !isRange {
+			last = first
+		}
+		from, errFrom := strconv.Atoi(first)
+		to, errTo := strconv.Atoi(last)
+		if errFrom != nil || errTo != nil {
+			return nil, fmt.Errorf("'%s' is not a number, range or name from the list", token)
+		}
+		if from < 1 || to > len(choices) || from > to {
+			return nil, fmt.Errorf("'%s' is out of range 1-%d", token, len(choices))
+		}
+		for i := from - 1; i < to; i++ {
+			picked[i] = true
+		}
+	}
+	var selected []string
+	for i, ok := range picked {
+		if ok {
+			selected = append(selected, choices[i])
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("nothing selected")
+	}
+	return selected, nil
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPickTables(t *testing.T) {
+	tables := []string{"customers", "orders", "payments", "shipments"}
+	columns := map[string][]string{
+		"customers": {"id", "email", "name"},
+		"orders":    {"id", "customer_id", "status", "total"},
+		"payments":  {"id", "amount"},
+		"shipments": {},
+	}
+	listColumns := func(table string) ([]string, error) { return columns[table], nil }
+
+	tests := []struct {
+		name        string
+		input       string
+		expected    map[string][]string
+		expectedErr string
+	}{
+		{
+			name:     "numbers, ranges and names",
+			input:    "1,3-4\n2 name\nall\n",
+			expected: map[string][]string{"customers": {"email", "name"}, "payments": nil, "shipments": nil},
+		},
+		{
+			name:     "empty column answer selects all",
+			input:    "orders\n\n",
+			expected: map[string][]string{"orders": nil},
+		},
+		{
+			name:     "invalid answers are asked again",
+			input:    "\n9\nbogus\n2\n4,3\n",
+			expected: map[string][]string{"orders": {"status", "total"}},
+		},
+		{
+			name:        "input ends before an answer",
+			input:       "7\n",
+			expectedErr: "selection aborted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := pickTables(strings.NewReader(tt.input), &out, tables, listColumns)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("pickTables() error = %v, want error containing %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pickTables() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("pickTables() = %v, want %v", got, tt.expected)
+			}
+			if !strings.Contains(out.String(), "  2) orders") {
+				t.Errorf("pickTables() output does not list the tables:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestPickTablesColumnListingError(t *testing.T) {
+	var out bytes.Buffer
+	_, err := pickTables(strings.NewReader("1\n"), &out, []string{"orders"}, func(string) ([]string, error) {
+		return nil, errors.New("permission denied")
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to list columns of 'orders'") {
+		t.Errorf("pickTables() error = %v, want column listing error", err)
+	}
+}
@@ -16,10 +16,14 @@
 package main
 
 import (
+	"os"
+
 	"github.com/GoogleCloudPlatform/db-context-enrichment/cmd"
 )
 
 func main() {
-	// Execute the root command
-	cmd.Execute()
+	// Execute the root command, exiting with a code that tells failures apart
+	if err := cmd.Execute(); err != nil {
+		os.Exit(cmd.ExitCode(err))
+	}
 }